@@ -5,18 +5,24 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/pkg/errors"
 )
 
 type ABIFinder struct {
 	ContractMap   ContractMap
 	ContractStore *ContractStore
+	// Resolver is used to find ABI of contracts, whose calls don't match any ABI from the store (e.g. third-party ones)
+	Resolver ABIResolver
+	// Proxies is used to decode calls to EIP-1967 proxies and EIP-2535 diamonds with ABIs of their implementations and facets
+	Proxies *ProxyDetector
 }
 
 type ABIFinderResult struct {
 	ABI            abi.ABI
 	Method         *abi.Method
 	DuplicateCount int
+	// ProxyAddress is set, if the called contract is a proxy and the method was found in the ABI of its Implementation
+	ProxyAddress   string
+	Implementation common.Address
 	contractName   string
 }
 
@@ -35,7 +41,14 @@ func NewABIFinder(contractMap ContractMap, contractStore *ContractStore) ABIFind
 // If the contract address is known, it will use the ABI instance that is known to be at the address.
 // If the contract address is not known, it will iterate over all known ABIs and check if any of them
 // has a method with the given signature. If there are duplicates we will use the first ABI that matched.
+// If none matches and Resolver is set, ABI of the contract is resolved (e.g. downloaded from explorer) and added to the store.
+// If the method isn't in the ABI of the contract and Proxies is set, the contract is checked for being an EIP-1967 proxy,
+// whose implementation has the method, or an EIP-2535 diamond, whose facet has it.
 func (a *ABIFinder) FindABIByMethod(address string, signature []byte) (ABIFinderResult, error) {
+	return a.findABIByMethod(address, signature, true)
+}
+
+func (a *ABIFinder) findABIByMethod(address string, signature []byte, followProxies bool) (ABIFinderResult, error) {
 	result := ABIFinderResult{}
 	stringSignature := common.Bytes2Hex(signature)
 
@@ -44,9 +57,19 @@ func (a *ABIFinder) FindABIByMethod(address string, signature []byte) (ABIFinder
 	// that it's correct. If it's not we will stop and return an error
 	if a.ContractMap.IsKnownAddress(address) {
 		contractName := a.ContractMap.GetContractName(address)
-		abiInstanceCandidate, ok := a.ContractStore.ABIs[contractName+".abi"]
+		abiInstanceCandidate, ok := a.ContractStore.GetABI(contractName)
+		if _, err := abiInstanceCandidate.MethodById(signature); (!ok || err != nil) && followProxies {
+			if proxied, isProxy := a.findProxiedABIByMethod(address, signature); isProxy {
+				proxied.contractName = contractName
+				return proxied, nil
+			}
+		}
+		if !ok {
+			// contract map might come from previous run, which downloaded the ABI
+			contractName, abiInstanceCandidate, ok = a.resolveABI(address)
+		}
 		if !ok {
-			err := errors.New(ErrNoAbiFound)
+			err := ErrNoAbiFound
 			L.Err(err).
 				Str("Contract", contractName).
 				Str("Address", address).
@@ -62,7 +85,7 @@ func (a *ABIFinder) FindABIByMethod(address string, signature []byte) (ABIFinder
 			// won't have it. In this case we should just continue and try to find the method in other ABIs.
 			// In that case we should update our mapping, as now we came across a method that's (hopefully)
 			// unique to contract B.
-			for correctedContractName, correctedAbi := range a.ContractStore.ABIs {
+			for correctedContractName, correctedAbi := range a.ContractStore.ListABIs() {
 				correctedMethod, abiErr := correctedAbi.MethodById(signature)
 				if abiErr == nil {
 					L.Debug().
@@ -93,7 +116,7 @@ func (a *ABIFinder) FindABIByMethod(address string, signature []byte) (ABIFinder
 		}
 
 		result.Method = methodCandidate
-		result.ABI = abiInstanceCandidate
+		result.ABI = *abiInstanceCandidate
 		result.contractName = contractName
 		result.DuplicateCount = 0 // we know the exact contract, so the duplicates here do not matter
 
@@ -105,7 +128,12 @@ func (a *ABIFinder) FindABIByMethod(address string, signature []byte) (ABIFinder
 		// In any case this should happen only when we did not deploy the contract via Seth (as otherwise we
 		// know the address of the contract and can map it to the correct ABI instance).
 		// If there are duplicates we will use the first ABI that matched.
-		for abiName, abiInstanceCandidate := range a.ContractStore.ABIs {
+		if followProxies {
+			if proxied, isProxy := a.findProxiedABIByMethod(address, signature); isProxy {
+				return proxied, nil
+			}
+		}
+		for abiName, abiInstanceCandidate := range a.ContractStore.ListABIs() {
 			methodCandidate, err := abiInstanceCandidate.MethodById(signature)
 			if err != nil {
 				L.Trace().
@@ -126,7 +154,15 @@ func (a *ABIFinder) FindABIByMethod(address string, signature []byte) (ABIFinder
 		}
 
 		if result.Method == nil {
-			return ABIFinderResult{}, errors.New(ErrNoABIMethod)
+			if name, resolvedABI, ok := a.resolveABI(address); ok {
+				if method, err := resolvedABI.MethodById(signature); err == nil {
+					result.ABI = *resolvedABI
+					result.Method = method
+					result.contractName = name
+					return result, nil
+				}
+			}
+			return ABIFinderResult{}, ErrNoABIMethod
 		}
 	}
 
@@ -135,7 +171,7 @@ func (a *ABIFinder) FindABIByMethod(address string, signature []byte) (ABIFinder
 
 func (a *ABIFinder) getDuplicateCount(signature []byte) int {
 	count := 0
-	for _, abiInstance := range a.ContractStore.ABIs {
+	for _, abiInstance := range a.ContractStore.ListABIs() {
 		_, err := abiInstance.MethodById(signature)
 		if err == nil {
 			count++
@@ -0,0 +1,146 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/pkg/errors"
+)
+
+// accessListKey is the context key of access list requested with transaction options
+type accessListKey struct{}
+
+// accessListRequest is either an explicit access list or a request to generate one with eth_createAccessList
+type accessListRequest struct {
+	list types.AccessList
+	auto bool
+}
+
+// WithAccessList attaches EIP-2930 access list to the transaction. Legacy transactions are sent as access list (type 1)
+// transactions and dynamic fee transactions keep their type.
+func WithAccessList(accessList types.AccessList) TransactOpt {
+	return func(o *bind.TransactOpts) {
+		o.Context = context.WithValue(contextOrBackground(o.Context), accessListKey{}, accessListRequest{list: accessList})
+	}
+}
+
+// WithAutoAccessList generates access list with eth_createAccessList right before the transaction is signed and attaches it
+// to the transaction. It's enabled for all transactions with network's 'auto_access_list' option.
+func WithAutoAccessList() TransactOpt {
+	return func(o *bind.TransactOpts) {
+		o.Context = context.WithValue(contextOrBackground(o.Context), accessListKey{}, accessListRequest{auto: true})
+	}
+}
+
+// CreateAccessList calls eth_createAccessList for the message and returns access list and gas used with it
+func (m *Client) CreateAccessList(ctx context.Context, msg ethereum.CallMsg) (types.AccessList, uint64, error) {
+	var result struct {
+		AccessList types.AccessList `json:"accessList"`
+		Error      string           `json:"error,omitempty"`
+		GasUsed    hexutil.Uint64   `json:"gasUsed"`
+	}
+	if err := m.Client.Client().CallContext(ctx, &result, "eth_createAccessList", toCallArg(msg), "pending"); err != nil {
+		return nil, 0, errors.Wrap(err, "failed to create access list")
+	}
+	if result.Error != "" {
+		return nil, 0, errors.Errorf("failed to create access list, execution failed: %s", result.Error)
+	}
+	if result.AccessList == nil {
+		result.AccessList = types.AccessList{}
+	}
+	return result.AccessList, uint64(result.GasUsed), nil
+}
+
+// attachAccessList wraps signer of transaction options, so that requested access list is added to the transaction before
+// it's signed. It's a no-op, if no access list was requested.
+func (m *Client) attachAccessList(opts *bind.TransactOpts) {
+	if opts.Signer == nil {
+		return
+	}
+	request, ok := contextOrBackground(opts.Context).Value(accessListKey{}).(accessListRequest)
+	if !ok && !m.Cfg.Network.AutoAccessList {
+		return
+	}
+	if !ok {
+		request = accessListRequest{auto: true}
+	}
+
+	sign := opts.Signer
+	opts.Signer = func(from common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		accessList := request.list
+		if request.auto {
+			ctx, cancel := context.WithTimeout(contextOrBackground(opts.Context), m.Cfg.Network.TxnTimeout.Duration())
+			defer cancel()
+			created, _, err := m.CreateAccessList(ctx, ethereum.CallMsg{
+				From:  from,
+				To:    tx.To(),
+				Gas:   tx.Gas(),
+				Value: tx.Value(),
+				Data:  tx.Data(),
+			})
+			if err != nil {
+				// transaction can still be sent without it, if it reverts we want to see why
+				m.logger().Warn().Err(err).Msg("Failed to create access list, sending transaction without it")
+				return sign(from, tx)
+			}
+			accessList = created
+		}
+		m.logger().Debug().
+			Int("Addresses", len(accessList)).
+			Int("Storage keys", accessList.StorageKeys()).
+			Msg("Attaching access list to transaction")
+
+		return sign(from, withAccessList(tx, big.NewInt(m.ChainID), accessList))
+	}
+}
+
+// withAccessList returns unsigned copy of the transaction with access list. Gas limit is increased by intrinsic gas of
+// the access list, so that gas limit estimated without it is still enough.
+func withAccessList(tx *types.Transaction, chainID *big.Int, accessList types.AccessList) *types.Transaction {
+	gas := tx.Gas() + accessListIntrinsicGas(accessList)
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    chainID,
+			Nonce:      tx.Nonce(),
+			GasPrice:   tx.GasPrice(),
+			Gas:        gas,
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: accessList,
+		})
+	case types.DynamicFeeTxType:
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    chainID,
+			Nonce:      tx.Nonce(),
+			GasTipCap:  tx.GasTipCap(),
+			GasFeeCap:  tx.GasFeeCap(),
+			Gas:        gas,
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: accessList,
+		})
+	default:
+		L.Warn().Uint8("Type", tx.Type()).Msg("Access list can't be attached to transaction of this type")
+		return tx
+	}
+}
+
+func accessListIntrinsicGas(accessList types.AccessList) uint64 {
+	return uint64(len(accessList))*params.TxAccessListAddressGas + uint64(accessList.StorageKeys())*params.TxAccessListStorageKeyGas
+}
+
+func contextOrBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
@@ -0,0 +1,68 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func sentTransaction(t *testing.T, c *seth.Client, hash string) *types.Transaction {
+	tx, _, err := c.Client.TransactionByHash(context.Background(), common.HexToHash(hash))
+	require.NoError(t, err, "failed to get transaction")
+	return tx
+}
+
+func requireInAccessList(t *testing.T, tx *types.Transaction, address common.Address) {
+	for _, tuple := range tx.AccessList() {
+		if tuple.Address == address {
+			return
+		}
+	}
+	t.Fatalf("address %s should be in access list, got: %v", address.Hex(), tx.AccessList())
+}
+
+func TestAPIAccessList(t *testing.T) {
+	c := newClient(t)
+
+	t.Run("explicit access list", func(t *testing.T) {
+		accessList := types.AccessList{{Address: TestEnv.DebugSubContractAddress, StorageKeys: []common.Hash{}}}
+		decoded, err := c.Decode(TestEnv.DebugContract.Trace(c.NewTXOpts(seth.WithAccessList(accessList)), big.NewInt(1), big.NewInt(2)))
+		require.NoError(t, err, "failed to send transaction with access list")
+
+		tx := sentTransaction(t, c, decoded.Hash)
+		if c.Cfg.Network.EIP1559DynamicFees {
+			require.Equal(t, uint8(types.DynamicFeeTxType), tx.Type(), "dynamic fee transaction should keep its type")
+		} else {
+			require.Equal(t, uint8(types.AccessListTxType), tx.Type(), "legacy transaction should be sent as access list transaction")
+		}
+		require.Equal(t, accessList, tx.AccessList(), "access list should be attached")
+	})
+
+	t.Run("generated access list", func(t *testing.T) {
+		decoded, err := c.Decode(TestEnv.DebugContract.Trace(c.NewTXOpts(seth.WithAutoAccessList()), big.NewInt(1), big.NewInt(2)))
+		require.NoError(t, err, "failed to send transaction with generated access list")
+
+		requireInAccessList(t, sentTransaction(t, c, decoded.Hash), TestEnv.DebugSubContractAddress)
+	})
+
+	t.Run("access list generated for all transactions", func(t *testing.T) {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Network.AutoAccessList = true
+		auto, err := seth.NewClientWithConfig(cfg)
+		require.NoError(t, err, "failed to initalise seth")
+
+		decoded, err := auto.Decode(TestEnv.DebugContract.Trace(auto.NewTXOpts(), big.NewInt(1), big.NewInt(2)))
+		require.NoError(t, err, "failed to send transaction")
+		requireInAccessList(t, sentTransaction(t, auto, decoded.Hash), TestEnv.DebugSubContractAddress)
+
+		_, err = auto.Decode(TestEnv.DebugContract.AlwaysRevertsRequire(auto.NewTXOpts(seth.WithGasLimit(1_000_000))))
+		require.Error(t, err, "transaction should revert")
+		require.Contains(t, err.Error(), "always revert error", "reverted transaction should be sent without access list")
+	})
+}
@@ -0,0 +1,171 @@
+package seth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrReadChecksumManifest     = errors.New("failed to read artifact checksum manifest")
+	ErrInvalidChecksumManifest  = errors.New("invalid line in artifact checksum manifest")
+	ErrArtifactNotInManifest    = errors.New("artifact is not listed in checksum manifest")
+	ErrArtifactChecksumMismatch = errors.New("checksum of artifact doesn't match the manifest")
+	ErrUnverifiedBytecode       = errors.New("bytecode doesn't match any verified artifact")
+)
+
+// LoadChecksumManifest reads SHA-256 checksums of artifacts from a file in the format produced by `sha256sum`
+// (`<hex checksum>  <file name>` per line, lines starting with '#' are ignored). Only base names of files are used, so
+// the manifest can be generated in any directory. Returns map of file name to lowercase hex checksum.
+func LoadChecksumManifest(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, wrapError(err, ErrReadChecksumManifest)
+	}
+	defer func() { _ = f.Close() }()
+
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, errors.Wrapf(ErrInvalidChecksumManifest, "line %d: '%s'", lineNum, line)
+		}
+		sum := strings.ToLower(fields[0])
+		if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != sha256.Size {
+			return nil, errors.Wrapf(ErrInvalidChecksumManifest, "line %d: '%s'", lineNum, line)
+		}
+		// sha256sum prefixes file names with '*' in binary mode
+		checksums[filepath.Base(strings.TrimPrefix(fields[1], "*"))] = sum
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, wrapError(err, ErrReadChecksumManifest)
+	}
+
+	return checksums, nil
+}
+
+// VerifyChecksums checks that every ABI, BIN and artifact file loaded from disk is listed in the manifest and that its
+// checksum matches. Afterwards integrity mode is enabled and only bytecode of verified BIN files can be deployed (see VerifyBIN).
+func (c *ContractStore) VerifyChecksums(manifest map[string]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(c.fileChecksums))
+	for name := range c.fileChecksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		expected, ok := manifest[name]
+		if !ok {
+			return errors.Wrapf(ErrArtifactNotInManifest, "%s", name)
+		}
+		if got := c.fileChecksums[name]; got != expected {
+			return errors.Wrapf(ErrArtifactChecksumMismatch, "%s, expected: %s, got: %s", name, expected, got)
+		}
+	}
+
+	c.verifiedBINs = make(map[string]string)
+	for name, bin := range c.BINs {
+		if c.loadedFromFile(name) {
+			c.verifiedBINs[name] = checksum(bin)
+		}
+	}
+	for name, bin := range c.unlinkedBINs {
+		if c.loadedFromFile(name) {
+			c.verifiedBINs[name] = checksum([]byte(bin.Hex))
+		}
+	}
+	L.Info().Int("Artifacts", len(names)).Msg("Verified checksums of contract artifacts")
+
+	return nil
+}
+
+// loadedFromFile returns true if bytecode was loaded from BIN file or artifact, whose checksum is known
+func (c *ContractStore) loadedFromFile(binName string) bool {
+	file, ok := c.artifactBINs[binName]
+	if !ok {
+		file = binName
+	}
+	_, ok = c.fileChecksums[file]
+	return ok
+}
+
+// VerifyBIN returns an error if integrity mode is enabled and bytecode isn't the same as the one loaded from verified
+// BIN file with given name. Bytecode added with AddBIN() is never verified.
+func (c *ContractStore) VerifyBIN(name string, bin []byte) error {
+	name = strings.TrimSuffix(name, ".abi")
+	if !strings.HasSuffix(name, ".bin") {
+		name = name + ".bin"
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.verifiedBINs == nil {
+		return nil
+	}
+	if expected, ok := c.verifiedBINs[name]; !ok || expected != checksum(bin) {
+		return errors.Wrapf(ErrUnverifiedBytecode, "contract: %s", strings.TrimSuffix(name, ".bin"))
+	}
+
+	return nil
+}
+
+// VerifyUnlinkedBIN returns an error if integrity mode is enabled and unlinked bytecode isn't the same as the one loaded
+// from verified BIN file with given name. Bytecode linked from verified one can be deployed.
+func (c *ContractStore) VerifyUnlinkedBIN(name string, bin *UnlinkedBytecode) error {
+	return c.VerifyBIN(name, []byte(bin.Hex))
+}
+
+// IntegrityModeEnabled returns true if artifacts were verified against checksum manifest
+func (c *ContractStore) IntegrityModeEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.verifiedBINs != nil
+}
+
+// newContractStore creates contract store from directories set in config and verifies its artifacts, if checksum
+// manifest is set
+func newContractStore(cfg *Config) (*ContractStore, error) {
+	artifactDirs := make([]string, 0, len(cfg.ArtifactDirs))
+	for _, dir := range cfg.ArtifactDirs {
+		artifactDirs = append(artifactDirs, filepath.Join(cfg.ConfigDir, dir))
+	}
+	cs, err := NewContractStore(filepath.Join(cfg.ConfigDir, cfg.ABIDir), filepath.Join(cfg.ConfigDir, cfg.BINDir), artifactDirs...)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ArtifactChecksums == "" {
+		return cs, nil
+	}
+	manifest, err := LoadChecksumManifest(filepath.Join(cfg.ConfigDir, cfg.ArtifactChecksums))
+	if err != nil {
+		return nil, err
+	}
+	if err := cs.VerifyChecksums(manifest); err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,111 @@
+package seth_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+// writeChecksumManifest writes manifest in sha256sum format for all files in dirs, except skipped ones
+func writeChecksumManifest(t *testing.T, path string, skip string, dirs ...string) {
+	var lines []string
+	for _, dir := range dirs {
+		files, err := os.ReadDir(dir)
+		require.NoError(t, err, "failed to read dir")
+		for _, f := range files {
+			if f.Name() == skip {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, f.Name()))
+			require.NoError(t, err, "failed to read file")
+			sum := sha256.Sum256(data)
+			lines = append(lines, fmt.Sprintf("%s  %s", hex.EncodeToString(sum[:]), filepath.Join(dir, f.Name())))
+		}
+	}
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0600), "failed to write manifest")
+}
+
+func TestSmokeContractStoreChecksums(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("all artifacts match", func(t *testing.T) {
+		manifestPath := filepath.Join(dir, "all.sha256")
+		writeChecksumManifest(t, manifestPath, "", "./contracts/abi", "./contracts/bin")
+
+		manifest, err := seth.LoadChecksumManifest(manifestPath)
+		require.NoError(t, err, "failed to load manifest")
+		cs, err := seth.NewContractStore("./contracts/abi", "./contracts/bin")
+		require.NoError(t, err, "failed to create contract store")
+		require.NoError(t, cs.VerifyChecksums(manifest), "artifacts should be verified")
+		require.True(t, cs.IntegrityModeEnabled(), "integrity mode should be enabled")
+
+		bin, ok := cs.GetBIN("NetworkDebugContract")
+		require.True(t, ok, "BIN should be loaded")
+		require.NoError(t, cs.VerifyBIN("NetworkDebugContract", bin), "loaded bytecode should be verified")
+		require.EqualError(t, cs.VerifyBIN("NetworkDebugContract", append(bin, 0x0)),
+			"contract: NetworkDebugContract: bytecode doesn't match any verified artifact")
+		require.Error(t, cs.VerifyBIN("Unknown", bin), "bytecode not loaded from disk should not be verified")
+	})
+
+	t.Run("artifact missing in manifest", func(t *testing.T) {
+		manifestPath := filepath.Join(dir, "partial.sha256")
+		writeChecksumManifest(t, manifestPath, "NetworkDebugContract.bin", "./contracts/abi", "./contracts/bin")
+
+		manifest, err := seth.LoadChecksumManifest(manifestPath)
+		require.NoError(t, err, "failed to load manifest")
+		cs, err := seth.NewContractStore("./contracts/abi", "./contracts/bin")
+		require.NoError(t, err, "failed to create contract store")
+		require.EqualError(t, cs.VerifyChecksums(manifest), "NetworkDebugContract.bin: artifact is not listed in checksum manifest")
+		require.False(t, cs.IntegrityModeEnabled(), "integrity mode should not be enabled")
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		manifestPath := filepath.Join(dir, "all.sha256")
+		writeChecksumManifest(t, manifestPath, "", "./contracts/abi", "./contracts/bin")
+		manifest, err := seth.LoadChecksumManifest(manifestPath)
+		require.NoError(t, err, "failed to load manifest")
+		manifest["NetworkDebugContract.abi"] = strings.Repeat("0", 64)
+
+		cs, err := seth.NewContractStore("./contracts/abi", "./contracts/bin")
+		require.NoError(t, err, "failed to create contract store")
+		err = cs.VerifyChecksums(manifest)
+		require.ErrorIs(t, err, seth.ErrArtifactChecksumMismatch, "tampered artifact should not be verified")
+		require.Contains(t, err.Error(), "NetworkDebugContract.abi, expected: "+strings.Repeat("0", 64), "incorrect error")
+	})
+
+	t.Run("invalid manifest", func(t *testing.T) {
+		manifestPath := filepath.Join(dir, "invalid.sha256")
+		require.NoError(t, os.WriteFile(manifestPath, []byte("# comment\nabcd  Contract.bin\n"), 0600), "failed to write manifest")
+		_, err := seth.LoadChecksumManifest(manifestPath)
+		require.EqualError(t, err, "line 2: 'abcd  Contract.bin': invalid line in artifact checksum manifest")
+	})
+}
+
+func TestAPIDeployUnverifiedBytecode(t *testing.T) {
+	c := newClient(t)
+
+	manifestPath := filepath.Join(t.TempDir(), "all.sha256")
+	writeChecksumManifest(t, manifestPath, "", "./contracts/abi", "./contracts/bin")
+	manifest, err := seth.LoadChecksumManifest(manifestPath)
+	require.NoError(t, err, "failed to load manifest")
+	cs, err := seth.NewContractStore("./contracts/abi", "./contracts/bin")
+	require.NoError(t, err, "failed to create contract store")
+	require.NoError(t, cs.VerifyChecksums(manifest), "artifacts should be verified")
+	c.ContractStore = cs
+
+	bin, _ := cs.GetBIN("NetworkDebugSubContract")
+	cs.AddBIN("NetworkDebugSubContract", append(bin, 0x0))
+	_, err = c.DeployContractFromContractStore(c.NewTXOpts(), "NetworkDebugSubContract")
+	require.EqualError(t, err, "contract: NetworkDebugSubContract: bytecode doesn't match any verified artifact")
+
+	cs.AddBIN("NetworkDebugSubContract", bin)
+	_, err = c.DeployContractFromContractStore(c.NewTXOpts(), "NetworkDebugSubContract")
+	require.NoError(t, err, "verified bytecode should be deployed")
+}
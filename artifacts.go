@@ -0,0 +1,264 @@
+package seth
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	ArtifactFormatFoundry = "foundry"
+	ArtifactFormatHardhat = "hardhat"
+
+	hardhatArtifactFormatPrefix = "hh-sol-artifact"
+)
+
+var (
+	ErrUnknownArtifactFormat = errors.New("no Foundry or Hardhat artifacts found")
+	ErrParseArtifact         = errors.New("failed to parse artifact")
+)
+
+// ContractArtifact describes Foundry or Hardhat artifact, from which contract's ABI and bytecode were loaded
+type ContractArtifact struct {
+	Name            string
+	SourceName      string
+	Format          string
+	Path            string
+	CompilerVersion string
+}
+
+// artifactLinkReferences are link references of Foundry and Hardhat artifacts: source file -> library -> positions
+type artifactLinkReferences map[string]map[string][]struct {
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+// artifactBytecode is bytecode of an artifact, Hardhat saves it as a hex string and Foundry as an object with link references
+type artifactBytecode struct {
+	Object         string
+	LinkReferences artifactLinkReferences
+}
+
+func (b *artifactBytecode) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		return json.Unmarshal(data, &b.Object)
+	}
+	var v struct {
+		Object         string                 `json:"object"`
+		LinkReferences artifactLinkReferences `json:"linkReferences"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	b.Object, b.LinkReferences = v.Object, v.LinkReferences
+	return nil
+}
+
+// artifactFile contains fields of both Foundry and Hardhat artifacts, that are loaded into the contract store
+type artifactFile struct {
+	Format         string                 `json:"_format"`
+	ContractName   string                 `json:"contractName"`
+	SourceName     string                 `json:"sourceName"`
+	ABI            json.RawMessage        `json:"abi"`
+	Bytecode       *artifactBytecode      `json:"bytecode"`
+	LinkReferences artifactLinkReferences `json:"linkReferences"`
+	// Metadata is solc metadata saved by Foundry, older versions save it as a string
+	Metadata json.RawMessage `json:"metadata"`
+}
+
+type artifactMetadata struct {
+	Compiler struct {
+		Version string `json:"version"`
+	} `json:"compiler"`
+	Settings struct {
+		CompilationTarget map[string]string `json:"compilationTarget"`
+	} `json:"settings"`
+}
+
+func (a *artifactFile) format() string {
+	switch {
+	case strings.HasPrefix(a.Format, hardhatArtifactFormatPrefix):
+		return ArtifactFormatHardhat
+	case a.ABI != nil && a.Bytecode != nil && a.Format == "":
+		return ArtifactFormatFoundry
+	default:
+		return ""
+	}
+}
+
+// DetectArtifactFormat returns format of artifacts in the directory, either ArtifactFormatFoundry (`out` directory with
+// `<File>.sol/<Contract>.json` artifacts) or ArtifactFormatHardhat (`artifacts` directory with `hh-sol-artifact-1` ones)
+func DetectArtifactFormat(dir string) (string, error) {
+	return detectArtifactFormat(osFS{}, dir)
+}
+
+func detectArtifactFormat(fsys fs.FS, dir string) (string, error) {
+	var format string
+	err := walkArtifacts(fsys, dir, func(_ string, _ []byte, artifact *artifactFile) error {
+		format = artifact.format()
+		return fs.SkipAll
+	})
+	if err != nil {
+		return "", err
+	}
+	if format == "" {
+		return "", errors.Wrapf(ErrUnknownArtifactFormat, "directory: %s", dir)
+	}
+	return format, nil
+}
+
+// walkArtifacts calls fn for each JSON file in the directory tree, that is a Foundry or Hardhat artifact. Files that aren't
+// artifacts (e.g. Hardhat's debug files and build info, Foundry's cache) are skipped.
+func walkArtifacts(fsys fs.FS, dir string, fn func(file string, data []byte, artifact *artifactFile) error) error {
+	root := path.Clean(dir)
+	return fs.WalkDir(fsys, root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if file != root && d.Name() == "build-info" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".json") || strings.HasSuffix(d.Name(), ".dbg.json") {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return errors.Wrapf(wrapError(err, ErrParseArtifact), "file: %s", file)
+		}
+		var artifact artifactFile
+		if err := json.Unmarshal(data, &artifact); err != nil || artifact.format() == "" {
+			L.Trace().Str("File", file).Msg("Skipping JSON file, which isn't a contract artifact")
+			return nil
+		}
+		return fn(file, data, &artifact)
+	})
+}
+
+// loadArtifacts loads ABIs and bytecodes of all Foundry or Hardhat artifacts found in the directory
+func (c *ContractStore) loadArtifacts(fsys fs.FS, dir string) error {
+	format, err := detectArtifactFormat(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	var loaded int
+	err = walkArtifacts(fsys, dir, func(file string, data []byte, artifact *artifactFile) error {
+		if artifact.format() != format {
+			return nil
+		}
+		contract := artifact.contract(file, format)
+		a, err := abi.JSON(bytes.NewReader(artifact.ABI))
+		if err != nil {
+			return errors.Wrapf(wrapError(err, ErrParseArtifact), "file: %s", file)
+		}
+
+		abiName, binName := contract.Name+".abi", contract.Name+".bin"
+		if existing, ok := c.artifacts[contract.Name]; ok {
+			L.Warn().
+				Str("Contract", contract.Name).
+				Str("Loaded", existing.Path).
+				Str("Skipped", file).
+				Msg("Contract with the same name was already loaded from another artifact, skipping it")
+			return nil
+		}
+		c.ABIs[abiName] = a
+		c.artifacts[contract.Name] = contract
+		c.fileChecksums[path.Base(file)] = checksum(data)
+
+		if bin := strings.TrimPrefix(artifact.Bytecode.Object, "0x"); bin != "" {
+			if IsUnlinkedBytecode(bin) {
+				c.unlinkedBINs[binName] = artifact.unlinkedBytecode(bin)
+			} else {
+				c.BINs[binName] = common.FromHex(bin)
+			}
+			c.artifactBINs[binName] = path.Base(file)
+		}
+		L.Debug().Str("File", file).Str("Format", format).Str("Contract", contract.Name).Msg("Artifact loaded")
+		loaded++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	L.Debug().Str("Dir", dir).Str("Format", format).Int("Contracts", loaded).Msg("Loaded contract artifacts")
+	return nil
+}
+
+func (a *artifactFile) contract(file, format string) *ContractArtifact {
+	contract := &ContractArtifact{
+		Name:       a.ContractName,
+		SourceName: a.SourceName,
+		Format:     format,
+		Path:       file,
+	}
+	var metadata artifactMetadata
+	if raw := a.Metadata; len(raw) > 0 {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			raw = []byte(s)
+		}
+		if err := json.Unmarshal(raw, &metadata); err != nil {
+			L.Debug().Err(err).Str("File", file).Msg("Failed to parse artifact metadata")
+		}
+		contract.CompilerVersion = metadata.Compiler.Version
+		for source, name := range metadata.Settings.CompilationTarget {
+			contract.SourceName, contract.Name = source, name
+		}
+	}
+	if contract.Name == "" {
+		// Foundry names artifacts "<Contract>.json" or "<Contract>.<compiler version>.json", if more than one compiler was used
+		contract.Name = strings.SplitN(strings.TrimSuffix(path.Base(file), ".json"), ".", 2)[0]
+	}
+	return contract
+}
+
+// unlinkedBytecode returns bytecode with link references keyed by placeholders of libraries
+func (a *artifactFile) unlinkedBytecode(bin string) *UnlinkedBytecode {
+	references := a.LinkReferences
+	if a.Bytecode.LinkReferences != nil {
+		references = a.Bytecode.LinkReferences
+	}
+	unlinked := &UnlinkedBytecode{Hex: bin, LinkReferences: make(map[string]string)}
+	for source, libraries := range references {
+		for library := range libraries {
+			fullyQualifiedName := source + ":" + library
+			unlinked.LinkReferences[libraryPlaceholder(fullyQualifiedName, "__$")] = fullyQualifiedName
+		}
+	}
+	return unlinked
+}
+
+// GetArtifact returns Foundry or Hardhat artifact, from which the contract was loaded
+func (c *ContractStore) GetArtifact(name string) (*ContractArtifact, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	artifact, ok := c.artifacts[strings.TrimSuffix(strings.TrimSuffix(name, ".abi"), ".bin")]
+	return artifact, ok
+}
+
+// ListArtifacts returns all Foundry and Hardhat artifacts loaded into the store sorted by contract name
+func (c *ContractStore) ListArtifacts() []*ContractArtifact {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	artifacts := make([]*ContractArtifact, 0, len(c.artifacts))
+	for _, a := range c.artifacts {
+		artifacts = append(artifacts, a)
+	}
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].Name < artifacts[j].Name
+	})
+	return artifacts
+}
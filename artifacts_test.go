@@ -0,0 +1,130 @@
+package seth_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+const consumerFQN = "contracts/Consumer.sol:Consumer"
+
+func writeArtifact(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700), "failed to create artifact dir")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600), "failed to write artifact")
+}
+
+func newFoundryOutDir(t *testing.T) string {
+	dir := t.TempDir()
+	writeArtifact(t, filepath.Join(dir, "Lib.sol", "Lib.json"), fmt.Sprintf(`{
+		"abi": [],
+		"bytecode": {"object": "0x%s", "linkReferences": {}},
+		"deployedBytecode": {"object": "0x00"},
+		"metadata": {"compiler": {"version": "0.8.19+commit.7dd6d404"}, "settings": {"compilationTarget": {"contracts/Lib.sol": "Lib"}}}
+	}`, libraryBytecode))
+	writeArtifact(t, filepath.Join(dir, "Consumer.sol", "Consumer.json"), fmt.Sprintf(`{
+		"abi": [{"type": "function", "name": "foo", "stateMutability": "view", "inputs": [], "outputs": []}],
+		"bytecode": {"object": "0x%s", "linkReferences": {"contracts/Lib.sol": {"Lib": [{"start": 12, "length": 20}]}}},
+		"deployedBytecode": {"object": "0x00"},
+		"metadata": {"compiler": {"version": "0.8.19+commit.7dd6d404"}, "settings": {"compilationTarget": {"%s": "Consumer"}}}
+	}`, fmt.Sprintf(consumerBytecodeTemplate, libraryHashPlaceholder(libraryFQN)), strings.Split(consumerFQN, ":")[0]))
+	// interfaces have no bytecode
+	writeArtifact(t, filepath.Join(dir, "IConsumer.sol", "IConsumer.json"), `{"abi": [], "bytecode": {"object": "0x"}}`)
+	// cache and other JSON files are skipped
+	writeArtifact(t, filepath.Join(dir, "cache", "solidity-files-cache.json"), `{"_format": "ethers-rs-sol-cache-3", "files": {}}`)
+	return dir
+}
+
+func newHardhatArtifactsDir(t *testing.T) string {
+	dir := t.TempDir()
+	writeArtifact(t, filepath.Join(dir, "contracts", "Lib.sol", "Lib.json"), fmt.Sprintf(`{
+		"_format": "hh-sol-artifact-1",
+		"contractName": "Lib",
+		"sourceName": "contracts/Lib.sol",
+		"abi": [],
+		"bytecode": "0x%s",
+		"deployedBytecode": "0x00",
+		"linkReferences": {},
+		"deployedLinkReferences": {}
+	}`, libraryBytecode))
+	writeArtifact(t, filepath.Join(dir, "contracts", "Consumer.sol", "Consumer.json"), fmt.Sprintf(`{
+		"_format": "hh-sol-artifact-1",
+		"contractName": "Consumer",
+		"sourceName": "contracts/Consumer.sol",
+		"abi": [],
+		"bytecode": "0x%s",
+		"deployedBytecode": "0x00",
+		"linkReferences": {"contracts/Lib.sol": {"Lib": [{"start": 12, "length": 20}]}},
+		"deployedLinkReferences": {}
+	}`, fmt.Sprintf(consumerBytecodeTemplate, libraryHashPlaceholder(libraryFQN))))
+	writeArtifact(t, filepath.Join(dir, "contracts", "Lib.sol", "Lib.dbg.json"), `{"_format": "hh-sol-dbg-1", "buildInfo": "../../build-info/1.json"}`)
+	writeArtifact(t, filepath.Join(dir, "build-info", "1.json"), `{"_format": "hh-sol-build-info-1", "output": {"contracts": {}}}`)
+	return dir
+}
+
+func TestContractStoreArtifacts(t *testing.T) {
+	foundryDir, hardhatDir := newFoundryOutDir(t), newHardhatArtifactsDir(t)
+
+	format, err := seth.DetectArtifactFormat(foundryDir)
+	require.NoError(t, err, "failed to detect format")
+	require.Equal(t, seth.ArtifactFormatFoundry, format, "incorrect format")
+	format, err = seth.DetectArtifactFormat(hardhatDir)
+	require.NoError(t, err, "failed to detect format")
+	require.Equal(t, seth.ArtifactFormatHardhat, format, "incorrect format")
+	_, err = seth.DetectArtifactFormat("./contracts/abi")
+	require.ErrorIs(t, err, seth.ErrUnknownArtifactFormat, "ABI dir has no artifacts")
+
+	for _, dir := range []string{foundryDir, hardhatDir} {
+		cs, err := seth.NewContractStore("", "", dir)
+		require.NoError(t, err, "failed to create contract store")
+
+		_, ok := cs.GetABI("Consumer")
+		require.True(t, ok, "ABI should be loaded from artifact")
+		lib, ok := cs.GetBIN("Lib")
+		require.True(t, ok, "bytecode should be loaded from artifact")
+		require.Equal(t, common.FromHex(libraryBytecode), lib, "incorrect bytecode")
+		unlinked, ok := cs.GetUnlinkedBIN("Consumer")
+		require.True(t, ok, "unlinked bytecode should be loaded from artifact")
+		require.Equal(t, libraryFQN, unlinked.LibraryName(unlinked.Placeholders()[0]), "library name should be read from link references")
+
+		artifact, ok := cs.GetArtifact("Consumer")
+		require.True(t, ok, "artifact should be recorded")
+		require.Equal(t, strings.Split(consumerFQN, ":")[0], artifact.SourceName, "incorrect source name")
+	}
+
+	cs, err := seth.NewContractStore("", "", foundryDir)
+	require.NoError(t, err, "failed to create contract store")
+	_, ok := cs.GetABI("IConsumer")
+	require.True(t, ok, "ABI of interface should be loaded")
+	_, ok = cs.GetBIN("IConsumer")
+	require.False(t, ok, "interface should have no bytecode")
+	artifact, _ := cs.GetArtifact("Lib")
+	require.Equal(t, "0.8.19+commit.7dd6d404", artifact.CompilerVersion, "compiler version should be read from metadata")
+	require.Len(t, cs.ListArtifacts(), 3, "only artifacts should be loaded")
+
+	// both formats can be loaded together with ABI and BIN files
+	cs, err = seth.NewContractStore("./contracts/abi", "./contracts/bin", foundryDir, hardhatDir)
+	require.NoError(t, err, "failed to create contract store")
+	_, ok = cs.GetABI("NetworkDebugContract")
+	require.True(t, ok, "ABI files should still be loaded")
+}
+
+func TestAPIDeployContractFromArtifacts(t *testing.T) {
+	c := newClient(t)
+	cs, err := seth.NewContractStore("", "", newHardhatArtifactsDir(t))
+	require.NoError(t, err, "failed to create contract store")
+	c.ContractStore = cs
+
+	consumer, err := c.DeployContractFromContractStore(c.NewTXOpts(), "Consumer")
+	require.NoError(t, err, "failed to deploy contract from artifact")
+	library := c.ContractAddressToNameMap.GetContractAddress("Lib")
+	code, err := c.Client.CodeAt(context.Background(), consumer.Address, nil)
+	require.NoError(t, err, "failed to get code")
+	require.Contains(t, common.Bytes2Hex(code), strings.ToLower(library[2:]), "library deployed from artifact should be linked")
+}
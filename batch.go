@@ -0,0 +1,121 @@
+package seth
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxBatchSize is the maximum number of calls sent in a single batch request, larger batches are split, since most
+	// RPC providers limit size of batches
+	MaxBatchSize = 100
+)
+
+var (
+	ErrBatchCall = errors.New("batch RPC call failed")
+)
+
+// BatchElem is a single call of a batch request, its result is unmarshalled into Result and error of the call (e.g.
+// revert) is set to Error
+type BatchElem = rpc.BatchElem
+
+// BatchCall sends all calls to the node in as few requests as possible, see BatchCallCtx
+func (m *Client) BatchCall(b []BatchElem) error {
+	return m.BatchCallCtx(context.Background(), b)
+}
+
+// BatchCallCtx sends all calls to the node in as few requests as possible (batches larger than MaxBatchSize are split).
+// Returned error means that request itself failed, errors of single calls are set to their Error field.
+func (m *Client) BatchCallCtx(ctx context.Context, b []BatchElem) error {
+	if len(b) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.Cfg.Network.TxnTimeout.Duration())
+	defer cancel()
+
+	m.logger().Debug().Int("Calls", len(b)).Msg("Sending batch RPC request")
+	for start := 0; start < len(b); start += MaxBatchSize {
+		end := start + MaxBatchSize
+		if end > len(b) {
+			end = len(b)
+		}
+		if err := m.Client.Client().BatchCallContext(ctx, b[start:end]); err != nil {
+			return wrapError(err, ErrBatchCall)
+		}
+	}
+	return nil
+}
+
+// batchResultOrNotFound returns error of the call or ethereum.NotFound, if node returned null
+func batchResultOrNotFound(elem BatchElem) error {
+	if elem.Error != nil {
+		return elem.Error
+	}
+	if raw, ok := elem.Result.(*json.RawMessage); ok && (len(*raw) == 0 || string(*raw) == "null") {
+		return ethereum.NotFound
+	}
+	return nil
+}
+
+// toBlockNumArg formats block number the same way as ethclient does, nil means the latest block
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	if number.Sign() >= 0 {
+		return hexutil.EncodeBig(number)
+	}
+	return rpc.BlockNumber(number.Int64()).String()
+}
+
+// headersByNumber fetches headers of the blocks in a single batch, missing headers are nil and failed calls are returned as errors by index
+func (m *Client) headersByNumber(ctx context.Context, numbers []*big.Int) ([]*types.Header, map[int]error, error) {
+	raw := make([]json.RawMessage, len(numbers))
+	batch := make([]BatchElem, len(numbers))
+	for i, bn := range numbers {
+		batch[i] = BatchElem{Method: "eth_getBlockByNumber", Args: []interface{}{toBlockNumArg(bn), false}, Result: &raw[i]}
+	}
+	if err := m.BatchCallCtx(ctx, batch); err != nil {
+		return nil, nil, err
+	}
+	headers := make([]*types.Header, len(numbers))
+	errs := make(map[int]error)
+	for i := range batch {
+		if err := batchResultOrNotFound(batch[i]); err != nil {
+			errs[i] = err
+			continue
+		}
+		header := &types.Header{}
+		if err := json.Unmarshal(raw[i], header); err != nil {
+			errs[i] = err
+			continue
+		}
+		headers[i] = header
+	}
+	return headers, errs, nil
+}
+
+// transactionReceiptElem returns batch call of the receipt, which is unmarshalled into receipt
+func transactionReceiptElem(txHash common.Hash, receipt *json.RawMessage) BatchElem {
+	return BatchElem{Method: "eth_getTransactionReceipt", Args: []interface{}{txHash}, Result: receipt}
+}
+
+// decodeReceipt returns receipt fetched in a batch or ethereum.NotFound, if it isn't available yet
+func decodeReceipt(elem BatchElem, raw json.RawMessage) (*types.Receipt, error) {
+	if err := batchResultOrNotFound(elem); err != nil {
+		return nil, err
+	}
+	receipt := &types.Receipt{}
+	if err := json.Unmarshal(raw, receipt); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
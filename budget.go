@@ -0,0 +1,228 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	BudgetScopeKey = "key"
+	BudgetScopeRun = "run"
+)
+
+// ErrBudgetExceeded is returned (wrapped in BudgetExceededError) when a transaction wasn't signed, because it could exceed
+// the spending budget. Use errors.Is(err, ErrBudgetExceeded) to check for it.
+var ErrBudgetExceeded = errors.New("spending budget exceeded")
+
+// BudgetCfg limits how much can be spent (gas fees and value) by each key and by all keys together during the lifetime of
+// the client. Amounts accept the same formats as other amounts, e.g. "0.5 ether". Unset amount means no limit.
+type BudgetCfg struct {
+	PerKey *Amount `toml:"per_key"`
+	PerRun *Amount `toml:"per_run"`
+}
+
+// BudgetExceededError describes the transaction, which would exceed the budget
+type BudgetExceededError struct {
+	// Scope is either BudgetScopeKey or BudgetScopeRun
+	Scope   string
+	Address common.Address
+	Budget  *big.Int
+	// Spent is the amount already spent by the key (or all keys for BudgetScopeRun) including maximum cost of transactions,
+	// which were signed, but aren't mined yet
+	Spent *big.Int
+	// Cost is the maximum cost of the transaction (gas limit * fee cap + value + L1 data fee on OP-stack chains)
+	Cost *big.Int
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%s: transaction from %s costing up to %s would exceed %s budget of %s, already spent %s",
+		ErrBudgetExceeded, e.Address.Hex(), FormatWei(e.Cost), e.Scope, FormatWei(e.Budget), FormatWei(e.Spent))
+}
+
+func (e *BudgetExceededError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// budgetSigner refuses to sign transactions, whose maximum cost (including L1 data fee on OP-stack chains) added to what was already spent and reserved would exceed
+// the budget. Maximum cost of each signed transaction is reserved until its receipt arrives (and it's counted as spent) or
+// it fails to be sent, so that concurrently signed transactions can't exceed the budget together. Reservations are kept per
+// nonce, transaction signed again with the same nonce (e.g. replacement with bumped fees) replaces the previous one.
+type budgetSigner struct {
+	Signer
+	budget   *BudgetCfg
+	spending *SpendingTracker
+	// l1Fees is nil, if network isn't an OP-stack chain
+	l1Fees   *L1FeeOracle
+	mu       *sync.Mutex
+	reserved map[common.Address]map[uint64]*budgetReservation
+}
+
+// budgetReservation is maximum cost reserved by transaction, hash is empty until transaction is signed
+type budgetReservation struct {
+	cost *big.Int
+	hash common.Hash
+}
+
+func newBudgetSigner(signer Signer, budget *BudgetCfg, spending *SpendingTracker, l1Fees *L1FeeOracle) *budgetSigner {
+	return &budgetSigner{
+		Signer:   signer,
+		budget:   budget,
+		spending: spending,
+		l1Fees:   l1Fees,
+		mu:       &sync.Mutex{},
+		reserved: make(map[common.Address]map[uint64]*budgetReservation),
+	}
+}
+
+func (s *budgetSigner) SignTx(ctx context.Context, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	if err := s.reserve(address, tx.Nonce(), s.cost(ctx, tx)); err != nil {
+		L.Error().
+			Err(err).
+			Msg("Refusing to sign transaction")
+		return nil, err
+	}
+	signedTx, err := s.Signer.SignTx(ctx, address, tx)
+	if err != nil {
+		s.release(address, tx.Nonce())
+		return nil, err
+	}
+	s.signed(address, signedTx)
+	return signedTx, nil
+}
+
+// cost returns maximum cost of the transaction, on OP-stack chains L1 data fee estimated at the latest block is added
+func (s *budgetSigner) cost(ctx context.Context, tx *types.Transaction) *big.Int {
+	cost := tx.Cost()
+	if s.l1Fees == nil {
+		return cost
+	}
+	l1Fee, err := s.l1Fees.TransactionL1Fee(ctx, tx, nil)
+	if err != nil {
+		L.Warn().
+			Err(err).
+			Msg("Failed to estimate L1 data fee of transaction, budget won't include it")
+		return cost
+	}
+	return cost.Add(cost, l1Fee)
+}
+
+// reserve checks, that the cost fits the budget together with what was spent and reserved by other transactions, and
+// reserves it
+func (s *budgetSigner) reserve(address common.Address, nonce uint64, cost *big.Int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keyReserved, runReserved := big.NewInt(0), big.NewInt(0)
+	for a, nonces := range s.reserved {
+		for n, r := range nonces {
+			if a == address && n == nonce {
+				continue
+			}
+			runReserved.Add(runReserved, r.cost)
+			if a == address {
+				keyReserved.Add(keyReserved, r.cost)
+			}
+		}
+	}
+	if err := s.budget.check(s.spending, address, cost, keyReserved, runReserved); err != nil {
+		return err
+	}
+	if _, ok := s.reserved[address]; !ok {
+		s.reserved[address] = make(map[uint64]*budgetReservation)
+	}
+	s.reserved[address][nonce] = &budgetReservation{cost: cost}
+	return nil
+}
+
+// signed records hash of the signed transaction in its reservation
+func (s *budgetSigner) signed(address common.Address, tx *types.Transaction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.reserved[address][tx.Nonce()]; ok {
+		r.hash = tx.Hash()
+	}
+}
+
+// signedReservations returns hashes of signed transactions, which reserved budget, by address and nonce
+func (s *budgetSigner) signedReservations() map[common.Address]map[uint64]common.Hash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hashes := make(map[common.Address]map[uint64]common.Hash)
+	for address, nonces := range s.reserved {
+		for nonce, r := range nonces {
+			if r.hash == (common.Hash{}) {
+				continue
+			}
+			if hashes[address] == nil {
+				hashes[address] = make(map[uint64]common.Hash)
+			}
+			hashes[address][nonce] = r.hash
+		}
+	}
+	return hashes
+}
+
+// release removes reservation of transaction with given nonce
+func (s *budgetSigner) release(address common.Address, nonce uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.reserved[address], nonce)
+}
+
+// releaseSigned removes reservation of transaction with given nonce, if it's still the one of the transaction with given hash
+func (s *budgetSigner) releaseSigned(address common.Address, nonce uint64, hash common.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.reserved[address][nonce]; ok && r.hash == hash {
+		delete(s.reserved[address], nonce)
+	}
+}
+
+// check returns error if the cost added to what was spent and reserved exceeds per key or per run budget
+func (b *BudgetCfg) check(spending *SpendingTracker, address common.Address, cost, keyReserved, runReserved *big.Int) error {
+	if b.PerKey != nil {
+		spent := new(big.Int).Add(spending.Spent(address), keyReserved)
+		if new(big.Int).Add(spent, cost).Cmp(b.PerKey.Wei()) > 0 {
+			return &BudgetExceededError{Scope: BudgetScopeKey, Address: address, Budget: b.PerKey.Wei(), Spent: spent, Cost: cost}
+		}
+	}
+	if b.PerRun != nil {
+		spent := new(big.Int).Add(spending.TotalSpent(), runReserved)
+		if new(big.Int).Add(spent, cost).Cmp(b.PerRun.Wei()) > 0 {
+			return &BudgetExceededError{Scope: BudgetScopeRun, Address: address, Budget: b.PerRun.Wei(), Spent: spent, Cost: cost}
+		}
+	}
+	return nil
+}
+
+// releaseBudget releases budget reserved by the transaction, when it's mined (and counted as spent) or couldn't be sent
+func (m *Client) releaseBudget(tx *types.Transaction) {
+	if m.budget == nil || tx == nil {
+		return
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return
+	}
+	m.budget.release(from, tx.Nonce())
+}
+
+// releaseUnsentBudget releases budget reserved by signed transactions, that node doesn't know, because their sending
+// failed. Reservations of transactions, that aren't signed yet, are kept.
+func (m *Client) releaseUnsentBudget(ctx context.Context) {
+	if m.budget == nil {
+		return
+	}
+	for address, nonces := range m.budget.signedReservations() {
+		for nonce, hash := range nonces {
+			if !m.transactionKnown(ctx, hash) {
+				m.budget.releaseSigned(address, nonce, hash)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package seth_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIBudget(t *testing.T) {
+	value := seth.EtherToWei(big.NewFloat(0.03))
+
+	t.Run("per key", func(t *testing.T) {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Budget = &seth.BudgetCfg{PerKey: amountOf("0.05 ether")}
+		c, err := seth.NewClientWithConfig(cfg)
+		require.NoError(t, err, "failed to initalise seth")
+
+		_, err = c.Decode(TestEnv.DebugContract.Pay(c.NewTXOpts(seth.WithValue(value))))
+		require.NoError(t, err, "transaction within budget should be sent")
+		spent := c.Spending.Spent(c.Addresses[0])
+
+		_, err = c.Decode(TestEnv.DebugContract.Pay(c.NewTXOpts(seth.WithValue(value))))
+		require.ErrorIs(t, err, seth.ErrBudgetExceeded, "transaction over budget should be refused")
+		var budgetErr *seth.BudgetExceededError
+		require.True(t, errors.As(err, &budgetErr), "error should describe exceeded budget")
+		require.Equal(t, seth.BudgetScopeKey, budgetErr.Scope, "incorrect budget scope")
+		require.Equal(t, c.Addresses[0], budgetErr.Address, "incorrect address")
+		require.Equal(t, spent.String(), budgetErr.Spent.String(), "incorrect amount spent")
+		require.Equal(t, spent.String(), c.Spending.Spent(c.Addresses[0]).String(), "refused transaction shouldn't be sent")
+
+		err = c.TransferETHFromKey(context.Background(), 0, c.Addresses[0].Hex(), value, nil)
+		require.ErrorIs(t, err, seth.ErrBudgetExceeded, "transfer over budget should be refused")
+	})
+
+	t.Run("per run", func(t *testing.T) {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Budget = &seth.BudgetCfg{PerRun: amountOf("0.05 ether")}
+		c, err := seth.NewClientWithConfig(cfg)
+		require.NoError(t, err, "failed to initalise seth")
+
+		_, err = c.Decode(TestEnv.DebugContract.Pay(c.NewTXOpts(seth.WithValue(value))))
+		require.NoError(t, err, "transaction within budget should be sent")
+		_, err = c.Decode(TestEnv.DebugContract.Pay(c.NewTXOpts(seth.WithValue(value))))
+		var budgetErr *seth.BudgetExceededError
+		require.True(t, errors.As(err, &budgetErr), "transaction over budget should be refused")
+		require.Equal(t, seth.BudgetScopeRun, budgetErr.Scope, "incorrect budget scope")
+	})
+
+	t.Run("rejected transaction", func(t *testing.T) {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Budget = &seth.BudgetCfg{PerKey: amountOf("0.05 ether")}
+		c, err := seth.NewClientWithConfig(cfg)
+		require.NoError(t, err, "failed to initalise seth")
+
+		// transaction is signed, but node rejects it, so its reservation has to be released
+		_, err = c.Decode(TestEnv.DebugContract.Pay(c.NewTXOpts(seth.WithValue(value), seth.WithGasLimit(1))))
+		require.Error(t, err, "transaction with too low gas limit should fail")
+		require.NotErrorIs(t, err, seth.ErrBudgetExceeded, "transaction within budget should be signed")
+
+		_, err = c.Decode(TestEnv.DebugContract.Pay(c.NewTXOpts(seth.WithValue(value))))
+		require.NoError(t, err, "budget of rejected transaction should be released")
+	})
+
+	t.Run("concurrent signing", func(t *testing.T) {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Budget = &seth.BudgetCfg{PerKey: amountOf("0.05 ether")}
+		c, err := seth.NewClientWithConfig(cfg)
+		require.NoError(t, err, "failed to initalise seth")
+
+		// transactions are only signed, so nothing is spent and each one has to be counted by its reservation
+		var signed, refused atomic.Int64
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			nonce := uint64(1<<40 + i)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := c.Signer.SignTx(context.Background(), c.Addresses[0], types.NewTx(&types.LegacyTx{
+					Nonce:    nonce,
+					To:       &c.Addresses[0],
+					Value:    seth.EtherToWei(big.NewFloat(0.01)),
+					Gas:      21_000,
+					GasPrice: big.NewInt(1),
+				}))
+				if errors.Is(err, seth.ErrBudgetExceeded) {
+					refused.Add(1)
+					return
+				}
+				require.NoError(t, err, "failed to sign transaction")
+				signed.Add(1)
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, int64(4), signed.Load(), "only transactions within budget should be signed")
+		require.Equal(t, int64(6), refused.Load(), "transactions over budget should be refused")
+	})
+}
+
+func TestConfigBudgetValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	require.Error(t, toml.Unmarshal([]byte("[budget]\nper_key = \"a lot\""), cfg), "invalid amount should be rejected")
+
+	cfg.Budget = &seth.BudgetCfg{PerKey: amountOf("1 ether"), PerRun: amountOf("10 ether")}
+	require.NoError(t, seth.ValidateConfig(cfg), "valid budget should be accepted")
+}
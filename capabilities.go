@@ -0,0 +1,252 @@
+package seth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	CapabilitiesCacheFilePattern = "capabilities_%d"
+
+	rpcMethodNotFoundCode = -32601
+)
+
+// NodeCapabilities describes optional features supported by the node (or RPC provider), they are detected by probing the node
+// and can be cached per chain (see `capabilities_cache_dir`), so that probing isn't repeated on each client creation
+type NodeCapabilities struct {
+	ChainID    int64     `json:"chain_id"`
+	URLHash    string    `json:"url_hash"`
+	DetectedAt time.Time `json:"detected_at"`
+	DebugAPI   bool      `json:"debug_api"`
+	TxPoolAPI  bool      `json:"txpool_api"`
+	EIP1559    bool      `json:"eip_1559"`
+	FeeHistory bool      `json:"fee_history"`
+	// MaxPriorityFee is true if the node suggests priority fee with eth_maxPriorityFeePerGas
+	MaxPriorityFee bool `json:"max_priority_fee"`
+	TraceAPI       bool `json:"trace_api"`
+	WebSocket      bool `json:"websocket"`
+	// Subscriptions is true if the connection supports eth_subscribe notifications
+	Subscriptions bool `json:"subscriptions"`
+	// ArchiveState is true if the node serves state of old blocks, which non-archive nodes prune
+	ArchiveState bool `json:"archive_state"`
+}
+
+// DetectNodeCapabilities probes the node for optional features. Methods are called with dummy arguments, only "method not found"
+// kind of errors mean that the feature isn't supported.
+func DetectNodeCapabilities(ctx context.Context, rpcClient *rpc.Client, url string, chainID int64) *NodeCapabilities {
+	caps := &NodeCapabilities{
+		ChainID:    chainID,
+		URLHash:    hashURL(url),
+		DetectedAt: time.Now(),
+		WebSocket:  strings.HasPrefix(url, "ws://") || strings.HasPrefix(url, "wss://"),
+	}
+
+	caps.DebugAPI = probeRPCMethod(ctx, rpcClient, "debug_traceTransaction", common.Hash{})
+	caps.TxPoolAPI = probeRPCMethod(ctx, rpcClient, "txpool_status")
+	caps.TraceAPI = probeRPCMethod(ctx, rpcClient, "trace_transaction", common.Hash{})
+	caps.FeeHistory = probeRPCMethod(ctx, rpcClient, "eth_feeHistory", hexutil.Uint64(1), "latest", []float64{})
+	caps.MaxPriorityFee = probeRPCMethod(ctx, rpcClient, "eth_maxPriorityFeePerGas")
+	caps.Subscriptions = rpcClient.SupportsSubscriptions()
+	caps.ArchiveState = probeHistoricalState(ctx, rpcClient)
+
+	var header struct {
+		BaseFee *hexutil.Big `json:"baseFeePerGas"`
+	}
+	if err := rpcClient.CallContext(ctx, &header, "eth_getBlockByNumber", "latest", false); err != nil {
+		L.Debug().Err(err).Msg("Failed to get latest block, while detecting EIP-1559 support")
+	}
+	caps.EIP1559 = header.BaseFee != nil && caps.MaxPriorityFee
+
+	L.Debug().Interface("Capabilities", caps).Msg("Detected node capabilities")
+
+	return caps
+}
+
+// probeRPCMethod returns false only if the node doesn't know the method, any other error means it's supported
+func probeRPCMethod(ctx context.Context, rpcClient *rpc.Client, method string, params ...interface{}) bool {
+	var result interface{}
+	err := rpcClient.CallContext(ctx, &result, method, params...)
+	if err == nil {
+		return true
+	}
+	L.Trace().Err(err).Str("Method", method).Msg("Capability probe returned an error")
+	return !isMethodUnavailableErr(err)
+}
+
+// probeHistoricalState returns true if the node serves state of the first block after genesis, any error means it doesn't
+func probeHistoricalState(ctx context.Context, rpcClient *rpc.Client) bool {
+	var balance hexutil.Big
+	err := rpcClient.CallContext(ctx, &balance, "eth_getBalance", common.Address{}, hexutil.Uint64(1))
+	if err != nil {
+		L.Trace().Err(err).Msg("Historical state probe returned an error")
+		return false
+	}
+	return true
+}
+
+func isMethodUnavailableErr(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == rpcMethodNotFoundCode {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"does not exist", "not available", "method not found", "not supported", "unsupported method"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashURL is used to detect that cached capabilities belong to a different RPC node, URLs often contain secrets, so they aren't saved
+func hashURL(url string) string {
+	h := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(h[:])
+}
+
+func capabilitiesCacheName(chainID int64) string {
+	return fmt.Sprintf(CapabilitiesCacheFilePattern, chainID)
+}
+
+// loadCachedCapabilities returns cached capabilities, if they exist, belong to the same RPC URL and haven't expired
+func loadCachedCapabilities(cfg *Config, url string, chainID int64) (*NodeCapabilities, bool) {
+	path := filepath.Join(cfg.CapabilitiesCacheDir, capabilitiesCacheName(chainID)+".json")
+	if _, err := os.Stat(path); err != nil {
+		return nil, false
+	}
+	caps := &NodeCapabilities{}
+	if err := OpenJsonFileAsStruct(path, caps); err != nil {
+		L.Warn().Err(err).Str("Path", path).Msg("Failed to read node capabilities cache, capabilities will be detected again")
+		return nil, false
+	}
+	if caps.ChainID != chainID || caps.URLHash != hashURL(url) {
+		L.Debug().Str("Path", path).Msg("Cached node capabilities belong to a different RPC node, they will be detected again")
+		return nil, false
+	}
+	if cfg.CapabilitiesCacheTTL != nil && cfg.CapabilitiesCacheTTL.Duration() > 0 && time.Since(caps.DetectedAt) > cfg.CapabilitiesCacheTTL.Duration() {
+		L.Debug().Str("Path", path).Msg("Cached node capabilities expired, they will be detected again")
+		return nil, false
+	}
+	return caps, true
+}
+
+func saveCapabilities(cfg *Config, caps *NodeCapabilities) {
+	path, err := saveAsJson(caps, cfg.CapabilitiesCacheDir, capabilitiesCacheName(caps.ChainID))
+	if err != nil {
+		L.Warn().Err(err).Msg("Failed to save node capabilities cache")
+		return
+	}
+	L.Debug().Str("Path", path).Msg("Saved node capabilities cache")
+}
+
+// Capabilities returns features supported by the node. They're detected (or read from cache, see 'capabilities_cache_dir')
+// on the first call, unless it was already done, when the client was created (see 'detect_capabilities').
+func (m *Client) Capabilities() NodeCapabilities {
+	m.capabilitiesMu.Lock()
+	defer m.capabilitiesMu.Unlock()
+	if m.nodeCapabilities == nil {
+		m.loadNodeCapabilities()
+	}
+	return *m.nodeCapabilities
+}
+
+// loadNodeCapabilities reads node capabilities from cache or detects and caches them, it has to be called with the lock held
+func (m *Client) loadNodeCapabilities() {
+	if m.Cfg.CapabilitiesCacheDir != "" {
+		if caps, ok := loadCachedCapabilities(m.Cfg, m.URL, m.ChainID); ok {
+			m.logger().Debug().Str("Detected at", caps.DetectedAt.String()).Msg("Using cached node capabilities")
+			m.nodeCapabilities = caps
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
+	defer cancel()
+	m.nodeCapabilities = DetectNodeCapabilities(ctx, m.Client.Client(), m.URL, m.ChainID)
+	if m.Cfg.CapabilitiesCacheDir != "" {
+		saveCapabilities(m.Cfg, m.nodeCapabilities)
+	}
+}
+
+// applyNodeCapabilities disables configured features that the node doesn't support and selects tracer backend
+func (m *Client) applyNodeCapabilities() {
+	caps := m.Capabilities()
+	if m.Cfg.tracingEnabled() && !caps.DebugAPI && !caps.TraceAPI {
+		m.logger().Warn().Msg("Neither debug nor trace API is available on the node. Disabling tracing")
+		m.Cfg.disableTracing()
+	}
+	m.selectTracerBackend()
+	if m.Cfg.Network.EIP1559DynamicFees && !caps.EIP1559 {
+		m.logger().Warn().Msg("EIP1559 fees are not supported by the network. Switching to Legacy fees. Remember to update your config!")
+		m.Cfg.Network.EIP1559DynamicFees = false
+	}
+}
+
+// selectTracerBackend makes tracer use trace API, if capabilities were detected and the node doesn't support debug API
+func (m *Client) selectTracerBackend() {
+	m.capabilitiesMu.Lock()
+	caps := m.nodeCapabilities
+	m.capabilitiesMu.Unlock()
+	if m.Tracer == nil || caps == nil || caps.DebugAPI || !caps.TraceAPI {
+		return
+	}
+	if m.Tracer.Backend() != TracerBackend_TraceAPI {
+		m.logger().Warn().Msg("Debug API is not available on the node, tracing with trace API. Only call traces will be available")
+		m.Tracer.SetBackend(TracerBackend_TraceAPI)
+	}
+}
+
+// handleTracingError updates node capabilities after tracing failed, switches tracer to trace API, if the node supports only
+// that one, or disables tracing, if the node supports neither. It returns true, if tracing should be retried.
+func (m *Client) handleTracingError(err error) bool {
+	if m.Tracer.Backend() == TracerBackend_TraceAPI {
+		if isMethodUnavailableErr(err) {
+			m.markCapabilityUnsupported(func(caps *NodeCapabilities) {
+				caps.TraceAPI = false
+			})
+			m.logger().Warn().Err(err).Msg("Trace API is not available on the node. Disabling tracing")
+			m.Cfg.disableTracing()
+		}
+		return false
+	}
+	if isMethodUnavailableErr(err) {
+		m.markCapabilityUnsupported(func(caps *NodeCapabilities) {
+			caps.DebugAPI = false
+		})
+	}
+	caps := m.Capabilities()
+	if caps.DebugAPI {
+		return false
+	}
+	if caps.TraceAPI {
+		m.selectTracerBackend()
+		return true
+	}
+	m.logger().Warn().Err(err).Msg("Debug API is either disabled or not available on the node. Disabling tracing")
+	m.Cfg.disableTracing()
+	return false
+}
+
+// markCapabilityUnsupported updates detected capabilities (and their cache), when a feature turned out to be unsupported at runtime
+func (m *Client) markCapabilityUnsupported(update func(caps *NodeCapabilities)) {
+	m.capabilitiesMu.Lock()
+	defer m.capabilitiesMu.Unlock()
+	if m.nodeCapabilities == nil {
+		return
+	}
+	update(m.nodeCapabilities)
+	if m.Cfg.CapabilitiesCacheDir != "" {
+		saveCapabilities(m.Cfg, m.nodeCapabilities)
+	}
+}
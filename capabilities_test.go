@@ -0,0 +1,123 @@
+package seth_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPINodeCapabilitiesCache(t *testing.T) {
+	cacheDir := "capabilities_test_cache"
+	t.Cleanup(func() {
+		_ = os.RemoveAll(cacheDir)
+	})
+
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.CapabilitiesCacheDir = cacheDir
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+
+	cachePath := filepath.Join(cacheDir, fmt.Sprintf(seth.CapabilitiesCacheFilePattern, c.ChainID)+".json")
+	caps := &seth.NodeCapabilities{}
+	require.NoError(t, seth.OpenJsonFileAsStruct(cachePath, caps), "capabilities should be cached")
+	require.Equal(t, c.ChainID, caps.ChainID, "cached capabilities should belong to the chain")
+	require.True(t, caps.DebugAPI, "Geth should support debug API")
+	require.True(t, caps.TxPoolAPI, "Geth should support txpool API")
+	require.True(t, caps.EIP1559, "Geth should support EIP-1559")
+	require.True(t, caps.FeeHistory, "Geth should support fee history")
+	require.False(t, caps.TraceAPI, "Geth should not support trace API")
+
+	// cached capabilities are used instead of probing the node again
+	caps.DebugAPI = false
+	b, err := json.Marshal(caps)
+	require.NoError(t, err, "failed to marshal capabilities")
+	require.NoError(t, os.WriteFile(cachePath, b, 0600), "failed to update capabilities cache")
+
+	cfg, err = seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.CapabilitiesCacheDir = cacheDir
+	cfg.TracingLevel = seth.TracingLevel_All
+
+	c, err = seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	require.Equal(t, seth.TracingLevel_None, c.Cfg.TracingLevel, "tracing should be disabled, because cached capabilities have no debug API")
+}
+
+func TestAPINodeCapabilities(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.DetectCapabilities = true
+	// node supports EIP-1559, so fees shouldn't be switched to Legacy ones
+	cfg.Network.EIP1559DynamicFees = true
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+
+	caps := c.Capabilities()
+	require.Equal(t, c.ChainID, caps.ChainID, "capabilities should belong to the chain")
+	require.True(t, caps.DebugAPI, "Geth should support debug API")
+	require.True(t, caps.EIP1559, "Geth should support EIP-1559")
+	require.True(t, caps.MaxPriorityFee, "Geth should support eth_maxPriorityFeePerGas")
+	require.False(t, caps.TraceAPI, "Geth should not support trace API")
+	require.Equal(t, caps.WebSocket, caps.Subscriptions, "subscriptions should be supported over websocket")
+	require.True(t, c.Cfg.Network.EIP1559DynamicFees, "EIP-1559 fees should stay enabled")
+	if c.Tracer != nil {
+		require.Equal(t, seth.TracerBackend_Debug, c.Tracer.Backend(), "debug API should be used for tracing")
+	}
+}
+
+func TestTraceTraceAPIBackend(t *testing.T) {
+	// frames of a call, which made a sub-call and a reverted static call inside it, in the order returned by trace_transaction
+	traces := `[
+		{"action": {"callType": "call", "from": "0x1111111111111111111111111111111111111111", "to": "0x2222222222222222222222222222222222222222", "gas": "0x1000", "input": "0xaabbccdd", "value": "0x5"}, "result": {"gasUsed": "0x500", "output": "0x01"}, "traceAddress": [], "type": "call"},
+		{"action": {"callType": "delegatecall", "from": "0x2222222222222222222222222222222222222222", "to": "0x3333333333333333333333333333333333333333", "gas": "0x800", "input": "0x11223344", "value": "0x0"}, "result": {"gasUsed": "0x100", "output": "0x"}, "traceAddress": [0], "type": "call"},
+		{"action": {"callType": "staticcall", "from": "0x3333333333333333333333333333333333333333", "to": "0x4444444444444444444444444444444444444444", "gas": "0x400", "input": "0x55667788", "value": "0x0"}, "error": "Reverted", "traceAddress": [0, 0], "type": "call"},
+		{"action": {"from": "0x2222222222222222222222222222222222222222", "gas": "0x300", "init": "0x6080", "value": "0x0"}, "result": {"gasUsed": "0x200", "address": "0x5555555555555555555555555555555555555555", "code": "0x"}, "traceAddress": [1], "type": "create"}
+	]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req), "failed to decode request")
+		require.Equal(t, "trace_transaction", req.Method, "only trace API should be used")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"jsonrpc": "2.0", "id": %s, "result": %s}`, req.ID, traces)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	tracer, err := seth.NewTracer(server.URL, nil, nil, cfg, seth.NewEmptyContractMap(), nil)
+	require.NoError(t, err, "failed to create tracer")
+	tracer.SetBackend(seth.TracerBackend_TraceAPI)
+
+	txHash := "0x0000000000000000000000000000000000000000000000000000000000000001"
+	require.NoError(t, tracer.TraceGethTX(txHash), "failed to trace transaction")
+	trace, ok := tracer.GetTrace(txHash)
+	require.True(t, ok, "trace should be stored")
+	require.Nil(t, trace.FourByte, "4byte tracer isn't supported by trace API")
+
+	root := trace.CallTrace
+	require.Equal(t, "CALL", root.Type, "incorrect type of the top-level call")
+	require.Equal(t, "0x2222222222222222222222222222222222222222", root.To, "incorrect target of the top-level call")
+	require.Equal(t, "0x500", root.GasUsed, "incorrect gas used by the top-level call")
+	require.Equal(t, "0x01", root.Output, "incorrect output of the top-level call")
+	require.Len(t, root.Calls, 2, "top-level call should have two sub-calls")
+	require.Equal(t, "DELEGATECALL", root.Calls[0].Type, "incorrect type of the first sub-call")
+	require.Len(t, root.Calls[0].Calls, 1, "first sub-call should have a nested call")
+	require.Equal(t, "STATICCALL", root.Calls[0].Calls[0].Type, "incorrect type of the nested call")
+	require.Equal(t, "execution reverted", root.Calls[0].Calls[0].Error, "revert should be reported like geth does")
+	require.Equal(t, "CREATE", root.Calls[1].Type, "incorrect type of the second sub-call")
+	require.Equal(t, "0x5555555555555555555555555555555555555555", root.Calls[1].To, "created contract should be the target")
+	require.Equal(t, "0x6080", root.Calls[1].Input, "init code should be the input")
+}
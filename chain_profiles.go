@@ -0,0 +1,191 @@
+package seth
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ChainProfile holds defaults of a well-known chain, they are applied to network's settings, which aren't set in the
+// config, when network's 'chain_id' matches ChainID
+type ChainProfile struct {
+	Name               string
+	ChainID            int64
+	EIP1559DynamicFees bool
+	// BlockTime is the average time between blocks, it's used as receipt polling interval
+	BlockTime time.Duration
+	// GasPriceEstimationEnabled, GasPriceEstimationBlocks and GasPriceEstimationTxPriority configure automatic gas
+	// estimation, gas prices are used as fallback
+	GasPriceEstimationEnabled    bool
+	GasPriceEstimationBlocks     uint64
+	GasPriceEstimationTxPriority string
+	GasPrice                     Amount
+	GasFeeCap                    Amount
+	GasTipCap                    Amount
+	// FinalityDepth is the number of blocks after which a block is considered final, it's used as reorg monitor's depth
+	FinalityDepth uint64
+	// ExplorerURL is the Etherscan-compatible API of chain's block explorer
+	ExplorerURL string
+	// OPStack is true for OP-stack chains, which charge L1 data fee
+	OPStack bool
+}
+
+var chainProfiles = map[int64]ChainProfile{}
+
+func init() {
+	for _, p := range []ChainProfile{
+		publicChainProfile("Mainnet", 1, 12*time.Second, true, "30 gwei", "1 gwei", 64, "https://api.etherscan.io/api"),
+		publicChainProfile("Sepolia", 11155111, 12*time.Second, true, "10 gwei", "1 gwei", 64, "https://api-sepolia.etherscan.io/api"),
+		publicChainProfile("Arbitrum", 42161, 250*time.Millisecond, true, "0.1 gwei", "0.01 gwei", 20, "https://api.arbiscan.io/api"),
+		publicChainProfile("ArbitrumSepolia", 421614, 250*time.Millisecond, true, "0.1 gwei", "0.01 gwei", 20, "https://api-sepolia.arbiscan.io/api"),
+		opStack(publicChainProfile("Optimism", 10, 2*time.Second, true, "0.1 gwei", "0.001 gwei", 20, "https://api-optimistic.etherscan.io/api")),
+		opStack(publicChainProfile("OptimismSepolia", 11155420, 2*time.Second, true, "0.1 gwei", "0.001 gwei", 20, "https://api-sepolia-optimistic.etherscan.io/api")),
+		opStack(publicChainProfile("Base", 8453, 2*time.Second, true, "0.1 gwei", "0.001 gwei", 20, "https://api.basescan.org/api")),
+		opStack(publicChainProfile("BaseSepolia", 84532, 2*time.Second, true, "0.1 gwei", "0.001 gwei", 20, "https://api-sepolia.basescan.org/api")),
+		publicChainProfile("Polygon", 137, 2*time.Second, true, "200 gwei", "30 gwei", 128, "https://api.polygonscan.com/api"),
+		publicChainProfile("PolygonAmoy", 80002, 2*time.Second, true, "100 gwei", "30 gwei", 128, "https://api-amoy.polygonscan.com/api"),
+		publicChainProfile("BSC", 56, 3*time.Second, false, "3 gwei", "", 15, "https://api.bscscan.com/api"),
+		publicChainProfile("BSCTestnet", 97, 3*time.Second, false, "10 gwei", "", 15, "https://api-testnet.bscscan.com/api"),
+		publicChainProfile("Avalanche", 43114, 2*time.Second, true, "50 gwei", "2 gwei", 1, "https://api.routescan.io/v2/network/mainnet/evm/43114/etherscan/api"),
+		publicChainProfile("Fuji", 43113, 2*time.Second, true, "50 gwei", "2 gwei", 1, "https://api.routescan.io/v2/network/testnet/evm/43113/etherscan/api"),
+		{
+			Name:      GETH,
+			ChainID:   1337,
+			BlockTime: time.Second,
+			GasPrice:  MustParseAmount("1 gwei"),
+			GasFeeCap: MustParseAmount("10 gwei"),
+			GasTipCap: MustParseAmount("3 gwei"),
+		},
+		{
+			Name:      ANVIL,
+			ChainID:   31337,
+			BlockTime: time.Second,
+			GasPrice:  MustParseAmount("1 gwei"),
+			GasFeeCap: MustParseAmount("1 gwei"),
+			GasTipCap: MustParseAmount("1 gwei"),
+		},
+	} {
+		chainProfiles[p.ChainID] = p
+	}
+}
+
+// publicChainProfile returns profile of a public chain with gas estimation from last 100 blocks. Fee cap is also used as
+// legacy gas price, chains without dynamic fees have no tip cap.
+func publicChainProfile(name string, chainID int64, blockTime time.Duration, eip1559 bool, gasFeeCap, gasTipCap string, finalityDepth uint64, explorerURL string) ChainProfile {
+	p := ChainProfile{
+		Name:                         name,
+		ChainID:                      chainID,
+		EIP1559DynamicFees:           eip1559,
+		BlockTime:                    blockTime,
+		GasPriceEstimationEnabled:    true,
+		GasPriceEstimationBlocks:     100,
+		GasPriceEstimationTxPriority: Priority_Standard,
+		GasPrice:                     MustParseAmount(gasFeeCap),
+		FinalityDepth:                finalityDepth,
+		ExplorerURL:                  explorerURL,
+	}
+	if eip1559 {
+		p.GasFeeCap = MustParseAmount(gasFeeCap)
+		p.GasTipCap = MustParseAmount(gasTipCap)
+	}
+	return p
+}
+
+// opStack marks profile of OP-stack chain
+func opStack(p ChainProfile) ChainProfile {
+	p.OPStack = true
+	return p
+}
+
+// KnownChainProfile returns profile of a well-known chain
+func KnownChainProfile(chainID int64) (ChainProfile, bool) {
+	p, ok := chainProfiles[chainID]
+	return p, ok
+}
+
+// KnownChainProfiles returns profiles of all well-known chains sorted by chain ID
+func KnownChainProfiles() []ChainProfile {
+	profiles := make([]ChainProfile, 0, len(chainProfiles))
+	for _, p := range chainProfiles {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].ChainID < profiles[j].ChainID
+	})
+	return profiles
+}
+
+// applyChainProfile applies profile of the chain with network's 'chain_id' to settings, which aren't set. Fees are applied
+// only if none of them is set and gas estimation only if none of its settings is set, so that profile's fees are never
+// mixed with configured ones, but configured fees are used as fallback of profile's gas estimation. Explorer URL is set
+// only if [networks.explorer] table is present, because querying explorer is opt-in.
+func applyChainProfile(cfg *Config) {
+	if cfg.Network == nil || cfg.Network.ChainID == "" || cfg.chainProfileApplied {
+		return
+	}
+	chainID, err := strconv.ParseInt(cfg.Network.ChainID, 10, 64)
+	if err != nil {
+		return
+	}
+	p, ok := KnownChainProfile(chainID)
+	if !ok {
+		return
+	}
+	applyProfile(cfg, p, !hasFeeSettings(cfg.Network), !hasGasEstimationSettings(cfg.Network))
+}
+
+// applyProfile applies the profile to settings, which aren't set. Fees and gas estimation settings are applied only if
+// applyFees and applyGasEstimation are true, callers decide whether they were set, because unset values can't be told
+// apart from zero ones.
+func applyProfile(cfg *Config, p ChainProfile, applyFees, applyGasEstimation bool) {
+	cfg.chainProfileApplied = true
+	n := cfg.Network
+	if n.Name == "" {
+		n.Name = p.Name
+	}
+	if applyFees {
+		n.EIP1559DynamicFees = p.EIP1559DynamicFees
+		n.GasPrice = p.GasPrice.Wei().Int64()
+		n.GasFeeCap = p.GasFeeCap.Wei().Int64()
+		n.GasTipCap = p.GasTipCap.Wei().Int64()
+	}
+	if applyGasEstimation {
+		n.GasPriceEstimationEnabled = p.GasPriceEstimationEnabled
+		n.GasPriceEstimationBlocks = p.GasPriceEstimationBlocks
+		n.GasPriceEstimationTxPriority = p.GasPriceEstimationTxPriority
+	}
+	if n.TxnTimeout == nil {
+		n.TxnTimeout = MustMakeDuration(DefaultTransactionTimeout)
+	}
+	if n.TransferGasFee == 0 {
+		n.TransferGasFee = DefaultTransferGasFee
+	}
+	if n.ReceiptPollingInterval == nil && p.BlockTime > 0 {
+		n.ReceiptPollingInterval = MustMakeDuration(p.BlockTime)
+	}
+	if p.OPStack {
+		n.OPStack = true
+	}
+	if n.Explorer != nil && n.Explorer.URL == "" {
+		n.Explorer.URL = p.ExplorerURL
+	}
+	if cfg.ReorgMonitor != nil && cfg.ReorgMonitor.Depth == 0 {
+		cfg.ReorgMonitor.Depth = p.FinalityDepth
+	}
+	L.Debug().
+		Str("Network", n.Name).
+		Int64("ChainID", p.ChainID).
+		Str("Profile", p.Name).
+		Msg("Applied defaults of known chain")
+}
+
+// hasFeeSettings returns true if dynamic fees or any of network's gas prices is set
+func hasFeeSettings(n *Network) bool {
+	return n.EIP1559DynamicFees || n.GasPrice != 0 || n.GasFeeCap != 0 || n.GasTipCap != 0 ||
+		n.GasPriceAmount != "" || n.GasFeeCapAmount != "" || n.GasTipCapAmount != ""
+}
+
+// hasGasEstimationSettings returns true if any of network's gas estimation settings is set
+func hasGasEstimationSettings(n *Network) bool {
+	return n.GasPriceEstimationEnabled || n.GasPriceEstimationBlocks != 0 || n.GasPriceEstimationTxPriority != ""
+}
@@ -0,0 +1,71 @@
+package seth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigChainProfiles(t *testing.T) {
+	for _, chainID := range []int64{1, 11155111, 42161, 10, 8453, 137, 56, 43114} {
+		p, ok := seth.KnownChainProfile(chainID)
+		require.True(t, ok, "profile of chain %d should exist", chainID)
+		require.Equal(t, chainID, p.ChainID, "chain ID should match")
+		require.NotEmpty(t, p.ExplorerURL, "explorer of chain %d should be set", chainID)
+	}
+
+	cfg := &seth.Config{
+		ReorgMonitor: &seth.ReorgMonitorCfg{},
+		Network: &seth.Network{
+			ChainID:  "8453",
+			URLs:     []string{"https://base.example.com"},
+			Explorer: &seth.ExplorerCfg{APIKey: "key"},
+		},
+	}
+	require.NoError(t, seth.ValidateConfig(cfg), "minimal config should be valid")
+	require.Equal(t, "Base", cfg.Network.Name, "name of the chain should be used")
+	require.True(t, cfg.Network.EIP1559DynamicFees, "dynamic fees should be enabled")
+	require.NotZero(t, cfg.Network.GasFeeCap, "fallback fee cap should be set")
+	require.True(t, cfg.Network.GasPriceEstimationEnabled, "gas estimation should be enabled")
+	require.Equal(t, uint64(100), cfg.Network.GasPriceEstimationBlocks, "gas estimation blocks should be set")
+	require.Equal(t, seth.DefaultTransactionTimeout, cfg.Network.TxnTimeout.Duration(), "transaction timeout should be set")
+	require.Equal(t, int64(seth.DefaultTransferGasFee), cfg.Network.TransferGasFee, "transfer gas fee should be set")
+	require.Equal(t, 2*time.Second, cfg.Network.ReceiptPollingInterval.Duration(), "block time should be used as polling interval")
+	require.Equal(t, "https://api.basescan.org/api", cfg.Network.Explorer.URL, "explorer URL should be set")
+	require.True(t, cfg.Network.OPStack, "L1 data fee of OP-stack chain should be enabled")
+	require.Equal(t, uint64(20), cfg.ReorgMonitor.Depth, "finality depth should be used as reorg monitor depth")
+
+	cfg = &seth.Config{
+		Network: &seth.Network{
+			Name:       "MyBSC",
+			ChainID:    "56",
+			GasPrice:   5_000_000_000,
+			TxnTimeout: seth.MustMakeDuration(time.Minute),
+		},
+	}
+	require.NoError(t, seth.ValidateConfig(cfg), "config should be valid")
+	require.Equal(t, "MyBSC", cfg.Network.Name, "configured name should be kept")
+	require.Equal(t, int64(5_000_000_000), cfg.Network.GasPrice, "configured gas price should be kept")
+	require.False(t, cfg.Network.EIP1559DynamicFees, "fees of the profile shouldn't be mixed with configured ones")
+	require.True(t, cfg.Network.GasPriceEstimationEnabled, "gas estimation of the profile should be used")
+	require.Equal(t, time.Minute, cfg.Network.TxnTimeout.Duration(), "configured timeout should be kept")
+	require.Nil(t, cfg.Network.Explorer, "explorer should be opt-in")
+
+	cfg = &seth.Config{
+		Network: &seth.Network{
+			ChainID:                   "11155111",
+			GasPriceEstimationEnabled: true,
+			GasPriceEstimationBlocks:  10,
+		},
+	}
+	require.NoError(t, seth.ValidateConfig(cfg), "config should be valid")
+	require.Equal(t, uint64(10), cfg.Network.GasPriceEstimationBlocks, "configured gas estimation should be kept")
+	require.True(t, cfg.Network.EIP1559DynamicFees, "dynamic fees of the profile should be used")
+	require.Equal(t, int64(10_000_000_000), cfg.Network.GasFeeCap, "fee cap of the profile should be used as fallback")
+
+	cfg = &seth.Config{Network: &seth.Network{Name: "Private", ChainID: "424242"}}
+	require.NoError(t, seth.ValidateConfig(cfg), "config should be valid")
+	require.Nil(t, cfg.Network.TxnTimeout, "unknown chain shouldn't have defaults")
+}
@@ -6,9 +6,9 @@ import (
 	verr "errors"
 	"fmt"
 	"math/big"
-	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/avast/retry-go"
@@ -17,27 +17,37 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
-	"golang.org/x/sync/errgroup"
 )
 
 const (
-	ErrEmptyConfigPath      = "toml config path is empty, set SETH_CONFIG_PATH"
-	ErrCreateABIStore       = "failed to create ABI store"
-	ErrReadingKeys          = "failed to read keys"
-	ErrCreateNonceManager   = "failed to create nonce manager"
-	ErrCreateTracer         = "failed to create tracer"
-	ErrReadContractMap      = "failed to read deployed contract map"
-	ErrNoKeyLoaded          = "failed to load private key"
-	ErrRpcHealthCheckFailed = "RPC health check failed ¯\\_(ツ)_/¯"
-
 	ContractMapFilePattern          = "deployed_contracts_%s_%s.toml"
 	RevertedTransactionsFilePattern = "reverted_transactions_%s_%s.json"
 )
 
+var (
+	ErrEmptyConfigPath                    = errors.New("toml config path is empty, set SETH_CONFIG_PATH")
+	ErrCreateABIStore                     = errors.New("failed to create ABI store")
+	ErrReadingKeys                        = errors.New("failed to read keys")
+	ErrCreateNonceManager                 = errors.New("failed to create nonce manager")
+	ErrInitLogging                        = errors.New("failed to initialise logging")
+	ErrLocalNonceAllocationWithProtection = errors.New("local_nonce_allocation can't be used together with pending_nonce_protection_enabled, since it's meant to have multiple pending transactions per key")
+	ErrCreateTracer                       = errors.New("failed to create tracer")
+	ErrCreateKeyCoordinator               = errors.New("failed to create key coordinator")
+	ErrReadContractMap                    = errors.New("failed to read deployed contract map")
+	ErrNoKeyLoaded                        = errors.New("failed to load private key")
+	ErrReturnFundsOnClose                 = errors.New("failed to return funds of ephemeral keys on close")
+	ErrInvalidChainID                     = errors.New("network's 'chain_id' must be a positive integer")
+	ErrChainIDMismatch                    = errors.New("configured chain ID doesn't match the node")
+	ErrNoRPCURL                           = errors.New("at least one url should be present in config in 'secret_urls = []' or 'endpoints', unless connection is provided with WithEthClient or WithRPCClient")
+)
+
 var (
 	// Amount of funds that will be left on the root key, when splitting funds between ephemeral addresses
 	ZeroInt64 int64 = 0
@@ -49,46 +59,102 @@ var (
 
 // Client is a vanilla go-ethereum client with enhanced debug logging
 type Client struct {
-	Cfg                      *Config
-	Client                   *ethclient.Client
-	Addresses                []common.Address
-	PrivateKeys              []*ecdsa.PrivateKey
-	ChainID                  int64
-	URL                      string
-	Context                  context.Context
-	CancelFunc               context.CancelFunc
+	Cfg         *Config
+	Client      *ethclient.Client
+	Addresses   []common.Address
+	PrivateKeys []*ecdsa.PrivateKey
+	// Signer signs transactions of Addresses, by default with PrivateKeys and with remote signer, if it's configured
+	Signer     Signer
+	Logger     Logger
+	ChainID    int64
+	URL        string
+	Context    context.Context
+	CancelFunc context.CancelFunc
+	// Deprecated: use ErrorCollector, which scopes errors and is safe for concurrent use. Errors contains all errors
+	// collected by Seth, until they are drained with DrainErrors.
 	Errors                   []error
+	ErrorCollector           *ErrorCollector
 	ContractStore            *ContractStore
 	NonceManager             *NonceManager
+	KeyCoordinator           KeyCoordinator
 	Tracer                   *Tracer
 	ContractAddressToNameMap ContractMap
 	ABIFinder                *ABIFinder
 	HeaderCache              *LFUHeaderCache
+	FundsFlow                *FundsFlow
+	Spending                 *SpendingTracker
+	RunManifest              *RunManifest
+	GasSpikeBreaker          *GasSpikeBreaker
+	ReorgMonitor             *ReorgMonitor
+	L1FeeOracle              *L1FeeOracle
+	RPCHealthMonitor         *RPCHealthMonitor
+	ReadCache                *ReadCache
+	Paymaster                *PaymasterClient
+	Subscriptions            *SubscriptionManager
+	TraceWriter              *TraceWriter
+	lazyFunding              *lazyFunding
+	// budget reserves cost of signed transactions, it's nil if there's no budget
+	budget       *budgetSigner
+	sendAttempts *sendAttempts
+	// fundingMu is held shared by funding transfers from the root key, while they take nonce and are sent, and exclusively,
+	// when root key's nonce is reconciled after a failed one
+	fundingMu        sync.RWMutex
+	rpcFailover      *failoverTransport
+	externalClient   bool
+	rpcRecording     rpcRecordingTransport
+	nodeCapabilities *NodeCapabilities
+	capabilitiesMu   sync.Mutex
+	devNodeOnce      sync.Once
+	devNode          *DevNode
+	devNodeErr       error
+	topUpMu          sync.Mutex
+	closeOnce        sync.Once
+	closeErr         error
+	// stopTopUp stops background top up and waits for the one in progress, it's nil if there's no background top up
+	stopTopUp func()
 }
 
-// NewClientWithConfig creates a new seth client with all deps setup from config
-func NewClientWithConfig(cfg *Config) (*Client, error) {
-	initDefaultLogging()
+// NewClientWithConfig creates a new seth client with all deps setup from config, options are applied after the defaults
+func NewClientWithConfig(cfg *Config, opts ...ClientOpt) (*Client, error) {
+	err := initLogging(cfg.Log)
+	if err != nil {
+		return nil, wrapError(err, ErrInitLogging)
+	}
 
-	err := ValidateConfig(cfg)
+	err = ValidateConfig(cfg)
 	if err != nil {
 		return nil, err
 	}
+	logEffectiveConfig(cfg)
 
 	L.Debug().Msgf("Using tracing level: %s", cfg.TracingLevel)
 
 	cfg.setEphemeralAddrs()
-	cs, err := NewContractStore(filepath.Join(cfg.ConfigDir, cfg.ABIDir), filepath.Join(cfg.ConfigDir, cfg.BINDir))
-	if err != nil {
-		return nil, errors.Wrap(err, ErrCreateABIStore)
+	cs := cfg.contractStore
+	if cs == nil {
+		cs, err = newContractStore(cfg)
+		if err != nil {
+			return nil, wrapError(err, ErrCreateABIStore)
+		}
 	}
 	if cfg.ephemeral {
 		// we don't care about any other keys, only the root key
 		// you should not use ephemeral mode with more than 1 key
-		if len(cfg.Network.PrivateKeys) > 1 {
+		rootKeys := 1
+		if cfg.Network.RemoteSigner != nil {
+			// root key is the first address of remote signer
+			rootKeys = 0
+			cfg.Network.RemoteSigner.Addresses = cfg.Network.RemoteSigner.Addresses[:1]
+			cfg.Network.KMSKeys = nil
+		} else if len(cfg.Network.KMSKeys) > 0 {
+			// root key is the first KMS key
+			rootKeys = 0
+			cfg.Network.KMSKeys = cfg.Network.KMSKeys[:1]
+		}
+		if len(cfg.Network.PrivateKeys) > rootKeys {
 			L.Warn().Msg("Ephemeral mode is enabled, but more than 1 key is loaded. Only the first key will be used")
 		}
-		cfg.Network.PrivateKeys = cfg.Network.PrivateKeys[:1]
+		cfg.Network.PrivateKeys = cfg.Network.PrivateKeys[:rootKeys]
 		pkeys, err := NewEphemeralKeys(*cfg.EphemeralAddrs)
 		if err != nil {
 			return nil, err
@@ -101,11 +167,11 @@ func NewClientWithConfig(cfg *Config) (*Client, error) {
 	}
 	addrs, pkeys, err := cfg.ParseKeys()
 	if err != nil {
-		return nil, errors.Wrap(err, ErrReadingKeys)
+		return nil, wrapError(err, ErrReadingKeys)
 	}
 	nm, err := NewNonceManager(cfg, addrs, pkeys)
 	if err != nil {
-		return nil, errors.Wrap(err, ErrCreateNonceManager)
+		return nil, wrapError(err, ErrCreateNonceManager)
 	}
 
 	if !cfg.IsSimulatedNetwork() && cfg.SaveDeployedContractsMap && cfg.ContractMapFile == "" {
@@ -119,34 +185,36 @@ func NewClientWithConfig(cfg *Config) (*Client, error) {
 	if !cfg.IsSimulatedNetwork() {
 		contractAddressToNameMap.addressMap, err = LoadDeployedContracts(cfg.ContractMapFile)
 		if err != nil {
-			return nil, errors.Wrap(err, ErrReadContractMap)
+			return nil, wrapError(err, ErrReadContractMap)
 		}
 	} else {
 		L.Debug().Msg("Simulated network, contract map won't be read from file")
 	}
 
 	abiFinder := NewABIFinder(contractAddressToNameMap, cs)
-	if len(cfg.Network.URLs) == 0 {
-		return nil, fmt.Errorf("at least one url should be present in config in 'secret_urls = []'")
-	}
-	tr, err := NewTracer(cfg.Network.URLs[0], cs, &abiFinder, cfg, contractAddressToNameMap, addrs)
-	if err != nil {
-		return nil, errors.Wrap(err, ErrCreateTracer)
-	}
+	// tracer connects in NewClientRaw, after options configuring RPC connections are applied
+	tr := newTracer(nil, cs, &abiFinder, cfg, contractAddressToNameMap, addrs)
 
 	return NewClientRaw(
 		cfg,
 		addrs,
 		pkeys,
-		WithContractStore(cs),
-		WithNonceManager(nm),
-		WithTracer(tr),
-		WithContractMap(contractAddressToNameMap),
-		WithABIFinder(&abiFinder),
+		append([]ClientOpt{
+			WithContractStore(cs),
+			WithNonceManager(nm),
+			WithTracer(tr),
+			WithContractMap(contractAddressToNameMap),
+			WithABIFinder(&abiFinder),
+		}, opts...)...,
 	)
 }
 
 func ValidateConfig(cfg *Config) error {
+	if err := resolveAmounts(cfg); err != nil {
+		return err
+	}
+	applyChainProfile(cfg)
+
 	if cfg.Network.GasPriceEstimationEnabled {
 		if cfg.Network.GasPriceEstimationBlocks == 0 {
 			return errors.New("when automating gas estimation is enabled blocks must be greater than 0. fix it or disable gas estimation")
@@ -168,6 +236,103 @@ func ValidateConfig(cfg *Config) error {
 
 	}
 
+	if err := validateLogCfg(cfg.Log); err != nil {
+		return err
+	}
+	if err := validateContractReuseCfg(cfg.ContractReuse); err != nil {
+		return err
+	}
+	if err := validateSubscriptionsCfg(cfg.Subscriptions); err != nil {
+		return err
+	}
+	if err := validateGasSpikeBreaker(cfg.GasSpikeBreaker); err != nil {
+		return err
+	}
+	if err := validateReorgMonitorCfg(cfg.ReorgMonitor); err != nil {
+		return err
+	}
+	if err := validateRPCHealthMonitorCfg(cfg.RPCHealthMonitor); err != nil {
+		return err
+	}
+	if err := validateTopUpCfg(cfg.TopUp); err != nil {
+		return err
+	}
+	if err := validateEphemeralFundingCfg(cfg.EphemeralFunding); err != nil {
+		return err
+	}
+	if err := validateKeyCoordinationCfg(cfg); err != nil {
+		return err
+	}
+	if err := validateTraceWriterCfg(cfg.TraceWriter); err != nil {
+		return err
+	}
+	if err := validateTraceOutputCfg(cfg.TraceOutput); err != nil {
+		return err
+	}
+	if err := validateDecodedOutputCfg(cfg.DecodedOutput); err != nil {
+		return err
+	}
+	if err := validateSignatureDatabaseCfg(cfg.SignatureDatabase); err != nil {
+		return err
+	}
+	if err := validateRPCHealthCheck(cfg.RPCHealthCheck); err != nil {
+		return err
+	}
+	if err := validatePaymasterCfg(cfg.Network.Paymaster); err != nil {
+		return err
+	}
+	if err := validateEndpoints(cfg.Network); err != nil {
+		return err
+	}
+	if err := validateRateLimits(cfg.Network); err != nil {
+		return err
+	}
+	if err := validateRPCAuth(cfg.Network); err != nil {
+		return err
+	}
+	if err := validateRPCRecordingCfg(cfg.RPCRecording); err != nil {
+		return err
+	}
+	if urls := cfg.Network.RPCURLs(); cfg.RPCRecording != nil && len(urls) > 0 && !isHTTPURL(urls[0]) {
+		return errors.New("'rpc_recording' records only HTTP RPC calls, set HTTP URL of the network")
+	}
+	if err := validateReadCacheCfg(cfg.ReadCache); err != nil {
+		return err
+	}
+	if urls := cfg.Network.RPCURLs(); cfg.ReadCache != nil && len(urls) > 0 && !isHTTPURL(urls[0]) {
+		return errors.New("'read_cache' caches only HTTP RPC calls, set HTTP URL of the network")
+	}
+	if err := validateTokenFunding(cfg.Network); err != nil {
+		return err
+	}
+	if err := validateRemoteSignerCfg(cfg.Network); err != nil {
+		return err
+	}
+	if err := validateKMSKeys(cfg.Network); err != nil {
+		return err
+	}
+
+	if err := validateExplorerCfg(cfg.Network); err != nil {
+		return err
+	}
+	if err := validateFinality(cfg.Network); err != nil {
+		return err
+	}
+	if cfg.Network.ChainID != "" {
+		if id, err := strconv.ParseInt(cfg.Network.ChainID, 10, 64); err != nil || id <= 0 {
+			return errors.Wrapf(ErrInvalidChainID, "chain ID: '%s'", cfg.Network.ChainID)
+		}
+	}
+
+	if cfg.Network.ReceiptPollingJitter < 0 || cfg.Network.ReceiptPollingJitter >= 1 {
+		return errors.New("receipt polling jitter must be greater than or equal to 0 and less than 1")
+	}
+
+	if cfg.Network.ReceiptPollingInterval != nil && cfg.Network.ReceiptPollingMaxInterval != nil &&
+		cfg.Network.ReceiptPollingMaxInterval.Duration() < cfg.Network.ReceiptPollingInterval.Duration() {
+		return errors.New("receipt polling max interval must be greater than or equal to receipt polling interval")
+	}
+
 	if cfg.Network.GasLimit != 0 {
 		L.Warn().
 			Msg("Gas limit is set, this will override the gas limit set by the network. This option should be used **ONLY** if node is incapable of estimating gas limit itself, which happens only with very old versions")
@@ -187,20 +352,49 @@ func ValidateConfig(cfg *Config) error {
 		return errors.New("tracing level must be one of: NONE, REVERTED, ALL")
 	}
 
+	if err := validateTracingLevelOverrides(cfg); err != nil {
+		return err
+	}
+
+	if err := validateTraceOpts(cfg.TraceOpts); err != nil {
+		return err
+	}
+
 	if cfg.KeyFileSource != "" && cfg.EphemeralAddrs != nil && *cfg.EphemeralAddrs != 0 {
 		return fmt.Errorf("KeyFileSource is set to '%s' and ephemeral addresses are enabled, please disable ephemeral addresses or the keyfile usage. You cannot use both modes at the same time", cfg.KeyFileSource)
 	}
 
 	switch cfg.KeyFileSource {
-	case "", KeyFileSourceFile, KeyFileSourceBase64EnvVar:
+	case "", KeyFileSourceFile, KeyFileSourceBase64EnvVar, KeyFileSourceKeystore, KeyFileSourceMnemonic:
 	default:
-		return fmt.Errorf("KeyFileSource must be either empty (disabled) or one of: '%s', '%s'", KeyFileSourceFile, KeyFileSourceBase64EnvVar)
+		return fmt.Errorf("KeyFileSource must be either empty (disabled) or one of: '%s', '%s', '%s', '%s'", KeyFileSourceFile, KeyFileSourceBase64EnvVar, KeyFileSourceKeystore, KeyFileSourceMnemonic)
 	}
 
 	if cfg.KeyFileSource == KeyFileSourceFile && cfg.KeyFilePath == "" {
 		return fmt.Errorf("KeyFileSource is set to 'file' but the path to the key file is not set")
 	}
 
+	if cfg.KeyFileSource == KeyFileSourceKeystore && cfg.KeyFilePath == "" {
+		return fmt.Errorf("KeyFileSource is set to 'keystore' but the path to the keystore directory is not set")
+	}
+
+	if cfg.KeyFileSource == KeyFileSourceMnemonic {
+		if cfg.MnemonicDerivationPath == "" {
+			cfg.MnemonicDerivationPath = DefaultMnemonicDerivationPath
+		}
+		if _, err := expandDerivationPath(cfg.MnemonicDerivationPath); err != nil {
+			return err
+		}
+	}
+
+	if cfg.NonceManager != nil && cfg.NonceManager.LocalNonceAllocation && cfg.PendingNonceProtectionEnabled {
+		return ErrLocalNonceAllocationWithProtection
+	}
+
+	if err := validateTransactionTemplates(cfg.TransactionTemplates); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -220,40 +414,105 @@ func NewClientRaw(
 	pkeys []*ecdsa.PrivateKey,
 	opts ...ClientOpt,
 ) (*Client, error) {
-	if len(cfg.Network.URLs) == 0 {
-		return nil, errors.New("no RPC URL provided")
+	urls := cfg.Network.RPCURLs()
+	c := &Client{
+		Cfg:            cfg,
+		Addresses:      addrs,
+		PrivateKeys:    pkeys,
+		Spending:       NewSpendingTracker(),
+		ErrorCollector: NewErrorCollector(),
+		sendAttempts:   newSendAttempts(),
 	}
-	if len(cfg.Network.URLs) > 1 {
-		L.Warn().Msg("Multiple RPC URLs provided, only the first one will be used")
+	if len(urls) > 0 {
+		c.URL = urls[0]
 	}
-
-	client, err := ethclient.Dial(cfg.Network.URLs[0])
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to '%s' due to: %w", cfg.Network.URLs[0], err)
+	// options are applied before connecting, so that they can configure RPC connections or provide an existing one
+	for _, o := range opts {
+		o(c)
 	}
 
-	chainId, err := client.ChainID(context.Background())
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get chain ID")
+	if c.Client == nil {
+		if len(urls) == 0 {
+			return nil, ErrNoRPCURL
+		}
+		if cfg.RPCRecording != nil && cfg.Network.rpcRecording == nil {
+			recording, err := newRPCRecordingTransport(cfg.RPCRecording, cfg.Network.rpcClientTransport())
+			if err != nil {
+				return nil, err
+			}
+			cfg.Network.rpcRecording = recording
+			c.rpcRecording = recording
+		}
+		if cfg.ReadCache != nil && cfg.Network.readCache == nil {
+			cfg.Network.readCache = NewReadCache(*cfg.ReadCache)
+		}
+		c.ReadCache = cfg.Network.readCache
+		rpcClient, failover, err := dialRPC(context.Background(), cfg.Network)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to '%s' due to: %w", urls[0], err)
+		}
+		c.Client = ethclient.NewClient(rpcClient)
+		c.rpcFailover = failover
+	} else {
+		c.externalClient = true
 	}
-	cfg.Network.ChainID = chainId.String()
-	cID, err := strconv.Atoi(cfg.Network.ChainID)
+
+	// chain ID is resolved only once, all transactions are signed with this value
+	var err error
+	c.ChainID, err = resolveChainID(context.Background(), cfg.Network, c.Client)
 	if err != nil {
 		return nil, err
 	}
+	chainId := big.NewInt(c.ChainID)
 	ctx, cancel := context.WithCancel(context.Background())
-	c := &Client{
-		Cfg:         cfg,
-		Client:      client,
-		Addresses:   addrs,
-		PrivateKeys: pkeys,
-		URL:         cfg.Network.URLs[0],
-		ChainID:     int64(cID),
-		Context:     ctx,
-		CancelFunc:  cancel,
+	c.Context = ctx
+	c.CancelFunc = cancel
+	if c.Tracer != nil && c.Tracer.rpcClient == nil {
+		if err := c.connectTracer(c.Tracer); err != nil {
+			return nil, err
+		}
 	}
-	for _, o := range opts {
-		o(c)
+
+	if cfg.KeyCoordination != nil && c.KeyCoordinator == nil {
+		coordinator, err := NewKeyCoordinator(cfg.KeyCoordination, c.ChainID)
+		if err != nil {
+			return nil, wrapError(err, ErrCreateKeyCoordinator)
+		}
+		c.KeyCoordinator = coordinator
+	}
+
+	if c.Signer == nil {
+		signer := &keySigner{local: NewPrivateKeySigner(chainId, pkeys...)}
+		if len(cfg.kmsKeys) > 0 {
+			signer.kms = &KMSSigner{keys: cfg.kmsKeys, signer: types.LatestSignerForChainID(chainId)}
+		}
+		if cfg.Network.RemoteSigner != nil {
+			remote, err := NewRemoteSigner(cfg.Network.RemoteSigner, chainId)
+			if err != nil {
+				return nil, err
+			}
+			signer.remote = remote
+		}
+		c.Signer = signer
+	}
+
+	if cfg.Network.OPStack && c.L1FeeOracle == nil {
+		c.L1FeeOracle = NewL1FeeOracle(c.Client)
+	}
+	if cfg.Budget != nil {
+		c.budget = newBudgetSigner(c.Signer, cfg.Budget, c.Spending, c.L1FeeOracle)
+		c.Signer = c.budget
+	}
+	if c.NonceManager != nil && c.NonceManager.Journal == nil && cfg.NonceManager != nil && cfg.NonceManager.JournalPath != "" {
+		journal, err := OpenFileNonceJournal(cfg.NonceManager.JournalPath)
+		if err != nil {
+			return nil, err
+		}
+		c.NonceManager.Journal = journal
+	}
+	if c.NonceManager != nil {
+		// journal can also be set later, so signer is wrapped even if it's not set yet
+		c.Signer = &journalSigner{Signer: c.Signer, nonceManager: c.NonceManager}
 	}
 
 	if c.ContractAddressToNameMap.addressMap == nil {
@@ -261,30 +520,72 @@ func NewClientRaw(
 		if !cfg.IsSimulatedNetwork() {
 			c.ContractAddressToNameMap.addressMap, err = LoadDeployedContracts(cfg.ContractMapFile)
 			if err != nil {
-				return nil, errors.Wrap(err, ErrReadContractMap)
+				return nil, wrapError(err, ErrReadContractMap)
 			}
 			if len(c.ContractAddressToNameMap.addressMap) > 0 {
-				L.Info().
+				c.logger().Info().
 					Int("Size", len(c.ContractAddressToNameMap.addressMap)).
 					Str("File name", cfg.ContractMapFile).
 					Msg("No contract map provided, read it from file")
 			} else {
-				L.Info().
+				c.logger().Info().
 					Msg("No contract map provided and no file found, created new one")
 			}
 		} else {
-			L.Debug().Msg("Simulated network, contract map won't be read from file")
-			L.Info().
+			c.logger().Debug().Msg("Simulated network, contract map won't be read from file")
+			c.logger().Info().
 				Msg("No contract map provided and no file found, created new one")
 		}
 	} else {
-		L.Info().
+		c.logger().Info().
 			Int("Size", len(c.ContractAddressToNameMap.addressMap)).
 			Msg("Contract map was provided")
 	}
+	if cfg.Network.Paymaster != nil && c.Paymaster == nil {
+		c.Paymaster, err = newPaymasterClient(ctx, cfg.Network.Paymaster, c.ChainID, c.Client)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// subscriptions aren't recorded, so they're not used, when RPC calls are recorded or replayed
+	if wsURLs := cfg.Network.SubscriptionURLs(); len(wsURLs) > 0 && c.Subscriptions == nil && (cfg.Subscriptions == nil || !cfg.Subscriptions.Disabled) && cfg.RPCRecording == nil {
+		subsCfg := SubscriptionsCfg{}
+		if cfg.Subscriptions != nil {
+			subsCfg = *cfg.Subscriptions
+		}
+		c.Subscriptions, err = NewSubscriptionManagerWithFailover(ctx, wsURLs, subsCfg)
+		if err != nil {
+			return nil, err
+		}
+		c.Subscriptions.dialOptions = cfg.Network.rpcHeaderOptions()
+	}
+	if cfg.GasSpikeBreaker != nil && c.GasSpikeBreaker == nil {
+		c.GasSpikeBreaker = NewGasSpikeBreaker(*cfg.GasSpikeBreaker, c.latestBaseFee)
+	}
+	if cfg.ReorgMonitor != nil && c.ReorgMonitor == nil {
+		c.ReorgMonitor = NewReorgMonitor(*cfg.ReorgMonitor, c.Client)
+		c.ReorgMonitor.Start(c.Context, c.Subscriptions, cfg.Network.ReceiptPollingDelay(0))
+	}
+	if cfg.RPCHealthMonitor != nil && c.RPCHealthMonitor == nil {
+		c.RPCHealthMonitor = NewRPCHealthMonitor(*cfg.RPCHealthMonitor)
+	}
+	if c.RPCHealthMonitor != nil {
+		var selector endpointSelector
+		if c.rpcFailover != nil {
+			selector = c.rpcFailover
+		}
+		c.RPCHealthMonitor.start(c.Context, urls, selector, cfg.Network.rpcDialOptions)
+		if c.Tracer != nil && c.Tracer.failover != nil {
+			c.RPCHealthMonitor.addSelector(c.Tracer.failover)
+		}
+	}
+	if cfg.DetectCapabilities || cfg.CapabilitiesCacheDir != "" {
+		c.applyNodeCapabilities()
+	}
+
 	if c.NonceManager != nil {
 		c.NonceManager.Client = c
-		if len(c.Cfg.Network.PrivateKeys) > 0 {
+		if len(c.Addresses) > 0 {
 			if err := c.NonceManager.UpdateNonces(); err != nil {
 				return nil, err
 			}
@@ -292,8 +593,12 @@ func NewClientRaw(
 	}
 
 	if cfg.CheckRpcHealthOnStart {
-		if c.NonceManager == nil {
-			L.Warn().Msg("Nonce manager is not set, RPC health check will be skipped. Client will most probably fail on first transaction")
+		if cfg.rpcHealthCheckMode() == RPCHealthCheckMode_ReadOnly {
+			if err := c.checkRPCHealthReadOnly(); err != nil {
+				return nil, err
+			}
+		} else if c.NonceManager == nil {
+			c.logger().Warn().Msg("Nonce manager is not set, RPC health check will be skipped. Client will most probably fail on first transaction")
 		} else {
 			if err := c.checkRPCHealth(); err != nil {
 				return nil, err
@@ -303,46 +608,39 @@ func NewClientRaw(
 
 	cfg.setEphemeralAddrs()
 
-	L.Info().
+	c.logger().Info().
 		Str("NetworkName", cfg.Network.Name).
 		Interface("Addresses", addrs).
-		Str("RPC", cfg.Network.URLs[0]).
+		Str("RPC", c.URL).
 		Str("ChainID", cfg.Network.ChainID).
 		Int64("Ephemeral keys", *cfg.EphemeralAddrs).
 		Msg("Created new client")
 
 	if cfg.ephemeral {
-		gasPrice, err := c.GetSuggestedLegacyFees(context.Background(), Priority_Standard)
-		if err != nil {
-			gasPrice = big.NewInt(c.Cfg.Network.GasPrice)
+		if err := c.checkTokenBalances(context.Background(), *cfg.EphemeralAddrs); err != nil {
+			return nil, err
 		}
+		fees := c.SuggestedTransferFees(context.Background())
 
-		bd, err := c.CalculateSubKeyFunding(*cfg.EphemeralAddrs, gasPrice.Int64(), *cfg.RootKeyFundsBuffer)
+		bd, err := c.calculateSubKeyFunding(*cfg.EphemeralAddrs, fees.MaxGasPrice(), cfg.rootKeyFundsBuffer())
 		if err != nil {
 			return nil, err
 		}
-		L.Warn().Msg("Ephemeral mode, all funds will be lost!")
+		c.logger().Warn().Msg("Ephemeral mode, all funds will be lost!")
 
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-		eg, egCtx := errgroup.WithContext(ctx)
-		// root key is element 0 in ephemeral
-		for _, addr := range c.Addresses[1:] {
-			addr := addr
-			eg.Go(func() error {
-				return c.TransferETHFromKey(egCtx, 0, addr.Hex(), bd.AddrFunding, gasPrice)
-			})
-		}
-		if err := eg.Wait(); err != nil {
+		if err := c.fundEphemeralKeys(context.Background(), bd.AddrFunding, fees); err != nil {
 			return nil, err
 		}
 	}
+	if cfg.TopUp != nil && cfg.TopUp.Interval != nil {
+		c.startTopUp(c.Context, cfg.TopUp.Interval.Duration())
+	}
 
-	if c.Cfg.TracingLevel != TracingLevel_None && c.Tracer == nil {
+	if c.Cfg.tracingEnabled() && c.Tracer == nil {
 		if c.ContractStore == nil {
-			cs, err := NewContractStore(filepath.Join(cfg.ConfigDir, cfg.ABIDir), filepath.Join(cfg.ConfigDir, cfg.BINDir))
+			cs, err := newContractStore(cfg)
 			if err != nil {
-				return nil, errors.Wrap(err, ErrCreateABIStore)
+				return nil, wrapError(err, ErrCreateABIStore)
 			}
 			c.ContractStore = cs
 		}
@@ -350,24 +648,67 @@ func NewClientRaw(
 			abiFinder := NewABIFinder(c.ContractAddressToNameMap, c.ContractStore)
 			c.ABIFinder = &abiFinder
 		}
-		tr, err := NewTracer(cfg.Network.URLs[0], c.ContractStore, c.ABIFinder, cfg, c.ContractAddressToNameMap, addrs)
-		if err != nil {
-			return nil, errors.Wrap(err, ErrCreateTracer)
+		tr := newTracer(nil, c.ContractStore, c.ABIFinder, cfg, c.ContractAddressToNameMap, addrs)
+		if err := c.connectTracer(tr); err != nil {
+			return nil, err
 		}
 
 		c.Tracer = tr
+		c.selectTracerBackend()
+		if c.RPCHealthMonitor != nil && tr.failover != nil {
+			c.RPCHealthMonitor.addSelector(tr.failover)
+		}
+	}
+
+	if c.ABIFinder != nil && c.ABIFinder.Resolver == nil && c.Cfg.Network.Explorer != nil {
+		c.ABIFinder.Resolver = NewExplorerABIResolver(c.Cfg.Network.Explorer, c.ChainID)
+	}
+
+	if c.ABIFinder != nil && c.ABIFinder.Proxies == nil && c.Client != nil {
+		c.ABIFinder.Proxies = NewProxyDetector(c.Client)
+	}
+
+	if c.Cfg.TrackFundsFlow && c.FundsFlow == nil {
+		c.FundsFlow = NewFundsFlow(c.ContractAddressToNameMap, c.Addresses)
+	}
+
+	if c.Cfg.RunManifest && c.RunManifest == nil {
+		c.RunManifest = NewRunManifest(c.ContractAddressToNameMap)
+		if logPath := LogFilePath(); logPath != "" {
+			c.RunManifest.AddArtifact(logPath)
+		}
 	}
 
-	now := time.Now().Format("2006-01-02-15-04-05")
-	c.Cfg.RevertedTransactionsFile = fmt.Sprintf(RevertedTransactionsFilePattern, c.Cfg.Network.Name, now)
+	if c.Cfg.TraceToJson && c.TraceWriter == nil {
+		c.TraceWriter = NewTraceWriter(c.Cfg.TraceWriter, c.recordManifestArtifact)
+	}
+
+	c.Cfg.resolveTraceOutput(time.Now())
+	if err := c.PruneTraces(); err != nil {
+		c.logger().Warn().Err(err).Msg("Failed to remove old trace files")
+	}
 
 	if c.Cfg.Network.GasPriceEstimationEnabled {
-		L.Debug().Msg("Gas estimation is enabled")
-		L.Debug().Msg("Initialising LFU block header cache")
+		c.logger().Debug().Msg("Gas estimation is enabled")
+		c.logger().Debug().Msg("Initialising LFU block header cache")
 		c.HeaderCache = NewLFUBlockCache(c.Cfg.Network.GasPriceEstimationBlocks)
+		if c.Subscriptions != nil {
+			// headers of new blocks are cached as they arrive, so that they don't have to be fetched during estimation
+			c.Subscriptions.OnHead(func(h *types.Header) {
+				_ = c.HeaderCache.Set(h)
+			})
+		}
+		if c.ReorgMonitor != nil {
+			// headers of replaced blocks mustn't be used for estimation
+			c.ReorgMonitor.OnReorg(func(e ReorgEvent) {
+				for number := e.CommonAncestor + 1; number <= e.CommonAncestor+e.Depth; number++ {
+					c.HeaderCache.Remove(int64(number))
+				}
+			})
+		}
 
 		if c.Cfg.Network.EIP1559DynamicFees {
-			L.Debug().Msg("Checking if EIP-1559 is supported by the network")
+			c.logger().Debug().Msg("Checking if EIP-1559 is supported by the network")
 			c.CalculateGasEstimations(GasEstimationRequest{
 				GasEstimationEnabled: true,
 				FallbackGasPrice:     c.Cfg.Network.GasPrice,
@@ -381,23 +722,20 @@ func NewClientRaw(
 	return c, nil
 }
 
-func (m *Client) checkRPCHealth() error {
-	L.Info().Str("RPC node", m.URL).Msg("---------------- !!!!! ----------------> Checking RPC health")
-	ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
-	defer cancel()
-
-	gasPrice, err := m.GetSuggestedLegacyFees(context.Background(), Priority_Standard)
+// resolveChainID fetches chain ID from the node and checks it against the one configured for the network, if it isn't
+// configured, the fetched one is stored in the network config
+func resolveChainID(ctx context.Context, n *Network, client *ethclient.Client) (int64, error) {
+	chainID, err := client.ChainID(ctx)
 	if err != nil {
-		gasPrice = big.NewInt(m.Cfg.Network.GasPrice)
+		return 0, errors.Wrap(err, "failed to get chain ID")
 	}
-
-	err = m.TransferETHFromKey(ctx, 0, m.Addresses[0].Hex(), big.NewInt(10_000), gasPrice)
-	if err != nil {
-		return errors.Wrap(err, ErrRpcHealthCheckFailed)
+	if n.ChainID != "" {
+		if expected, ok := new(big.Int).SetString(n.ChainID, 10); !ok || expected.Cmp(chainID) != 0 {
+			return 0, errors.Wrapf(ErrChainIDMismatch, "network: '%s', configured chain ID: %s, node chain ID: %s", n.Name, n.ChainID, chainID.String())
+		}
 	}
-
-	L.Info().Msg("RPC health check passed <---------------- !!!!! ----------------")
-	return nil
+	n.ChainID = chainID.String()
+	return chainID.Int64(), nil
 }
 
 // Decode waits for transaction to be minted, then decodes transaction inputs, outputs, logs and events and
@@ -406,47 +744,87 @@ func (m *Client) checkRPCHealth() error {
 // If transaction was reverted the error return will be revert error, not decoding error (that one if any will be logged).
 // It means it can return both error and decoded transaction!
 func (m *Client) Decode(tx *types.Transaction, txErr error) (*DecodedTransaction, error) {
-	if len(m.Errors) > 0 {
-		return nil, verr.Join(m.Errors...)
+	return m.DecodeCtx(context.Background(), tx, txErr)
+}
+
+// DecodeCtx is the same as Decode, but waiting for the receipt, getting revert reason and tracing can be cancelled with the
+// context
+func (m *Client) DecodeCtx(ctx context.Context, tx *types.Transaction, txErr error) (*DecodedTransaction, error) {
+	if tx != nil {
+		if from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err == nil {
+			if m.deferredNonceAllocation() {
+				m.NonceManager.markSent(from, tx.Nonce())
+			}
+			if errs := m.ErrorCollector.Drain(KeyErrorScope(from), GlobalErrorScope); len(errs) > 0 {
+				return nil, verr.Join(errs...)
+			}
+		}
 	}
 	if txErr != nil {
+		if m.RunManifest != nil {
+			m.RunManifest.AddSendError()
+		}
+		if !errors.Is(txErr, ErrTransactOptsWithError) {
+			// transaction might have been signed and not sent, its nonce would leave a gap and its budget stay reserved
+			if m.deferredNonceAllocation() {
+				m.releaseUnsentNonces(ctx)
+			}
+			m.releaseUnsentBudget(ctx)
+		}
+		if errors.Is(txErr, ErrTransactOptsWithError) {
+			return nil, txErr
+		}
 		//try to decode revert reason
-		reason, decodingErr := m.DecodeCustomABIErr(txErr)
-
-		if decodingErr == nil {
-			return nil, errors.Wrap(txErr, reason)
+		revertErr, decodingErr := m.DecodeCustomRevertError(txErr)
+		if decodingErr == nil && revertErr != nil {
+			return nil, revertErr
 		}
 
-		L.Trace().
+		m.logger().Trace().
 			Msg("Skipping decoding, transaction submission failed. Nothing to decode")
 		return nil, txErr
 	}
 
 	if tx == nil {
-		L.Trace().
+		m.logger().Trace().
 			Msg("Skipping decoding, because transaction is nil. Nothing to decode")
 		return nil, nil
 	}
 
-	l := L.With().Str("Transaction", tx.Hash().Hex()).Logger()
-	receipt, err := m.WaitMined(context.Background(), l, m.Client, tx)
+	l := m.logger().With().Str("Transaction", tx.Hash().Hex()).Logger()
+	startedAt := time.Now()
+	receipt, err := m.WaitMined(ctx, l, m.Client, tx)
+	m.recordManifestTransaction(tx, receipt, err, startedAt)
 	if err != nil {
-		L.Trace().
+		m.logger().Trace().
 			Err(err).
 			Msg("Skipping decoding, because transaction was not minted. Nothing to decode")
 		return nil, err
 	}
 
+	if m.FundsFlow != nil {
+		// deferred, so that internal transfers from the trace are already available
+		defer m.recordFundsFlow(tx, receipt)
+	}
+
 	var revertErr error
+	var revertReason *RevertReason
 	if receipt.Status == 0 {
-		revertErr = m.callAndGetRevertReason(tx, receipt)
+		revertReason, revertErr = m.callAndGetRevertReason(ctx, tx, receipt)
 	}
 
 	decoded, decodeErr := m.decodeTransaction(l, tx, receipt)
+	if decoded != nil {
+		decoded.RevertReason = revertReason
+		decoded.L1Fee = m.l1Fee(ctx, tx, receipt)
+	}
+	if receipt.Status == 0 {
+		revertErr = m.attachRevertLocation(l, decoded, tx, revertErr)
+	}
 
-	if decodeErr != nil && errors.Is(decodeErr, errors.New(ErrNoABIMethod)) {
+	if decodeErr != nil && errors.Is(decodeErr, ErrNoABIMethod) {
 		if m.Cfg.TraceToJson {
-			L.Trace().
+			m.logger().Trace().
 				Err(decodeErr).
 				Msg("Failed to decode transaction. Saving transaction data hash as JSON")
 
@@ -457,70 +835,57 @@ func (m *Client) Decode(tx *types.Transaction, txErr error) (*DecodedTransaction
 					Str("TXHash", tx.Hash().Hex()).
 					Msg("Failed to save reverted transaction hash to file")
 			} else {
+				m.recordManifestArtifact(m.Cfg.RevertedTransactionsFile)
 				l.Trace().
 					Str("TXHash", tx.Hash().Hex()).
 					Msg("Saved reverted transaction to file")
 			}
 		}
-		return decoded, revertErr
+		// tracer can still decode the transaction with signatures from 4byte database
 	}
 
-	if m.Cfg.TracingLevel == TracingLevel_None {
-		L.Trace().
+	tracingLevel := m.tracingLevelForTx(tx, receipt)
+	if tracingLevel == TracingLevel_None || m.Tracer == nil {
+		m.logger().Trace().
 			Str("Transaction Hash", tx.Hash().Hex()).
 			Msg("Tracing level is NONE, skipping decoding")
 		return decoded, revertErr
 	}
 
-	if m.Cfg.TracingLevel == TracingLevel_All || (m.Cfg.TracingLevel == TracingLevel_Reverted && revertErr != nil) {
-		traceErr := m.Tracer.TraceGethTX(decoded.Hash)
+	if tracingLevel == TracingLevel_All || (tracingLevel == TracingLevel_Reverted && revertErr != nil) {
+		traceErr := m.Tracer.TraceGethTXCtx(ctx, decoded.Hash)
+		if traceErr != nil && m.handleTracingError(traceErr) {
+			traceErr = m.Tracer.TraceGethTXCtx(ctx, decoded.Hash)
+		}
 		if traceErr != nil {
 			if m.Cfg.TraceToJson {
-				L.Trace().
+				m.logger().Trace().
 					Err(traceErr).
 					Msg("Failed to trace call, but decoding was successful. Saving decoded data as JSON")
 
-				path, saveErr := saveAsJson(decoded, "traces", decoded.Hash)
-				if saveErr != nil {
-					L.Warn().
-						Err(saveErr).
-						Msg("Failed to save decoded call as JSON")
-				} else {
-					L.Trace().
-						Str("Path", path).
-						Str("Tx hash", decoded.Hash).
-						Msg("Saved decoded transaction data to JSON")
-				}
-			}
-
-			if strings.Contains(traceErr.Error(), "debug_traceTransaction does not exist") {
-				L.Warn().
-					Err(err).
-					Msg("Debug API is either disabled or not available on the node. Disabling tracing")
-
-				m.Cfg.TracingLevel = TracingLevel_None
+				m.saveTraceAsJson(decoded, decoded.Hash, revertErr != nil)
 			}
 
 			return decoded, revertErr
 		}
 
 		if m.Cfg.TraceToJson {
-			path, saveErr := saveAsJson(m.Tracer.DecodedCalls[decoded.Hash], "traces", decoded.Hash)
-			if saveErr != nil {
-				L.Warn().
-					Err(saveErr).
-					Msg("Failed to save decoded call as JSON")
+			decodedCalls, _ := m.Tracer.GetDecodedCalls(decoded.Hash)
+			m.saveTraceAsJson(decodedCalls, decoded.Hash, revertErr != nil)
+		}
+
+		if m.Cfg.TraceToHTML {
+			name := m.Cfg.traceFileName(decoded.Hash, revertErr != nil)
+			if path, err := m.Tracer.saveHTMLReport(decoded.Hash, m.Cfg.TraceOutputDir(), name); err != nil {
+				m.logger().Warn().Err(err).Str("Tx hash", decoded.Hash).Msg("Failed to save HTML trace report")
 			} else {
-				L.Trace().
-					Str("Path", path).
-					Str("Tx hash", decoded.Hash).
-					Msg("Saved decoded call data to JSON")
+				m.recordManifestArtifact(path)
 			}
 		}
 	} else {
-		L.Trace().
+		m.logger().Trace().
 			Str("Transaction Hash", tx.Hash().Hex()).
-			Str("Tracing level", m.Cfg.TracingLevel).
+			Str("Tracing level", tracingLevel).
 			Bool("Was reverted?", revertErr != nil).
 			Msg("Transaction doesn't match tracing level, skipping decoding")
 	}
@@ -528,67 +893,26 @@ func (m *Client) Decode(tx *types.Transaction, txErr error) (*DecodedTransaction
 	return decoded, revertErr
 }
 
+// TransferETHFromKey sends value from given key and waits for the transfer to be mined. If network has dynamic fees
+// enabled, transfer is sent as EIP-1559 transaction with gasPrice used as fee cap. Nil gasPrice means fees from network config.
 func (m *Client) TransferETHFromKey(ctx context.Context, fromKeyNum int, to string, value *big.Int, gasPrice *big.Int) error {
-	if fromKeyNum > len(m.PrivateKeys) || fromKeyNum > len(m.Addresses) {
-		return errors.Wrap(errors.New(ErrNoKeyLoaded), fmt.Sprintf("requested key: %d", fromKeyNum))
-	}
-	toAddr := common.HexToAddress(to)
-	chainID, err := m.Client.NetworkID(context.Background())
-	if err != nil {
-		return errors.Wrap(err, "failed to get network ID")
-	}
-
-	var gasLimit int64
-	gasLimitRaw, err := m.EstimateGasLimitForFundTransfer(m.Addresses[fromKeyNum], common.HexToAddress(to), value)
-	if err != nil {
-		gasLimit = m.Cfg.Network.TransferGasFee
-	} else {
-		gasLimit = int64(gasLimitRaw)
-	}
-
-	if gasPrice == nil {
-		gasPrice = big.NewInt(m.Cfg.Network.GasPrice)
-	}
-
-	rawTx := &types.LegacyTx{
-		Nonce:    m.NonceManager.NextNonce(m.Addresses[fromKeyNum]).Uint64(),
-		To:       &toAddr,
-		Value:    value,
-		Gas:      uint64(gasLimit),
-		GasPrice: gasPrice,
-	}
-	L.Debug().Interface("TransferTx", rawTx).Send()
-	signedTx, err := types.SignNewTx(m.PrivateKeys[fromKeyNum], types.NewEIP155Signer(chainID), rawTx)
-	if err != nil {
-		return errors.Wrap(err, "failed to sign tx")
-	}
-
-	ctx, cancel := context.WithTimeout(ctx, m.Cfg.Network.TxnTimeout.Duration())
-	defer cancel()
-	err = m.Client.SendTransaction(ctx, signedTx)
-	if err != nil {
-		return errors.Wrap(err, "failed to send transaction")
-	}
-	l := L.With().Str("Transaction", signedTx.Hash().Hex()).Logger()
-	l.Info().
-		Int("FromKeyNum", fromKeyNum).
-		Str("To", to).
-		Interface("Value", value).
-		Msg("Send ETH")
-	_, err = m.WaitMined(ctx, l, m.Client, signedTx)
-	if err != nil {
-		return err
-	}
-	return err
+	return m.TransferETHFromKeyWithFees(ctx, fromKeyNum, to, value, m.transferFeesFromGasPrice(gasPrice))
 }
 
-// WaitMined the same as bind.WaitMined, awaits transaction receipt until timeout
+// WaitMined the same as bind.WaitMined, awaits transaction receipt until timeout. If network has confirmations or finality
+// configured, it also waits until transaction's block is deep enough in the chain (see WaitMinedWithConfirmations)
 func (m *Client) WaitMined(ctx context.Context, l zerolog.Logger, b bind.DeployBackend, tx *types.Transaction) (*types.Receipt, error) {
-	queryTicker := time.NewTicker(time.Second)
-	defer queryTicker.Stop()
-	ctx, cancel := context.WithTimeout(ctx, m.Cfg.Network.TxnTimeout.Duration())
-	defer cancel()
-	for {
+	return m.WaitMinedWithConfirmations(ctx, l, b, tx, m.Cfg.Network.Confirmations, m.Cfg.Network.Finality)
+}
+
+// waitForReceipt polls for transaction receipt until it's available or context is done
+func (m *Client) waitForReceipt(ctx context.Context, l zerolog.Logger, b bind.DeployBackend, tx *types.Transaction) (*types.Receipt, error) {
+	for attempt := 0; ; attempt++ {
+		// when subscribed to new heads receipt is checked as soon as new block arrives, polling is only a fallback
+		var newHead <-chan struct{}
+		if m.Subscriptions != nil {
+			newHead = m.Subscriptions.NextHead()
+		}
 		receipt, err := b.TransactionReceipt(ctx, tx.Hash())
 		if err == nil {
 			l.Info().
@@ -607,13 +931,88 @@ func (m *Client) WaitMined(ctx context.Context, l zerolog.Logger, b bind.DeployB
 				Str("TX", tx.Hash().String()).
 				Msg("Failed to get receipt")
 		}
+		queryTimer := time.NewTimer(m.Cfg.Network.ReceiptPollingDelay(attempt))
 		select {
 		case <-ctx.Done():
+			queryTimer.Stop()
 			l.Error().Err(err).Msg("Transaction context is done")
 			return nil, ctx.Err()
-		case <-queryTicker.C:
+		case <-queryTimer.C:
+		case <-newHead:
+			queryTimer.Stop()
+		}
+	}
+}
+
+// Close releases client's resources. If 'return_funds_on_close' is enabled, it first returns funds of ephemeral keys to
+// the root key. Then it logs spending report, saves queued traces and run manifest (if it's enabled), cancels client's
+// context, which stops background goroutines (reorg monitor, subscriptions), and closes all RPC connections. Only the first
+// call has any effect, following ones return the same error.
+func (m *Client) Close() error {
+	m.closeOnce.Do(func() {
+		m.closeErr = m.close()
+	})
+	return m.closeErr
+}
+
+func (m *Client) close() error {
+	var errs []error
+	// otherwise keys could be topped up again after their funds are returned
+	if m.stopTopUp != nil {
+		m.stopTopUp()
+	}
+	if m.Cfg.ReturnFundsOnClose && m.Cfg.ephemeral && len(m.Addresses) > 1 {
+		m.logger().Info().
+			Int("Keys", len(m.Addresses)-1).
+			Msg("Returning funds of ephemeral keys to the root key")
+		if err := ReturnFunds(m, m.Addresses[0].Hex()); err != nil {
+			errs = append(errs, wrapError(err, ErrReturnFundsOnClose))
+		}
+	}
+	m.logSpendingReport()
+	if m.TraceWriter != nil {
+		m.TraceWriter.Close()
+	}
+	if err := m.PruneTraces(); err != nil {
+		m.logger().Warn().Err(err).Msg("Failed to remove old trace files")
+	}
+	if m.RunManifest != nil {
+		if _, err := m.SaveRunManifest(); err != nil {
+			errs = append(errs, err)
 		}
 	}
+	if m.CancelFunc != nil {
+		m.CancelFunc()
+	}
+	if m.Subscriptions != nil {
+		m.Subscriptions.Close()
+	}
+	if m.Tracer != nil {
+		m.Tracer.Close()
+	}
+	if m.Paymaster != nil {
+		m.Paymaster.Close()
+	}
+	if m.KeyCoordinator != nil {
+		if err := m.KeyCoordinator.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if m.NonceManager != nil && m.NonceManager.Journal != nil {
+		if err := m.NonceManager.Journal.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// connection provided with WithEthClient or WithRPCClient is closed by its owner
+	if m.Client != nil && !m.externalClient {
+		m.Client.Close()
+	}
+	if m.rpcRecording != nil {
+		if err := m.rpcRecording.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return verr.Join(errs...)
 }
 
 /* ClientOpts client functional options */
@@ -642,6 +1041,20 @@ func WithABIFinder(abiFinder *ABIFinder) ClientOpt {
 	}
 }
 
+// WithSigner Signer functional option
+func WithSigner(signer Signer) ClientOpt {
+	return func(c *Client) {
+		c.Signer = signer
+	}
+}
+
+// WithLogger Logger functional option, client logs with given logger instead of the global one
+func WithLogger(logger Logger) ClientOpt {
+	return func(c *Client) {
+		c.Logger = logger
+	}
+}
+
 // WithNonceManager NonceManager functional option
 func WithNonceManager(nm *NonceManager) ClientOpt {
 	return func(c *Client) {
@@ -649,6 +1062,48 @@ func WithNonceManager(nm *NonceManager) ClientOpt {
 	}
 }
 
+// WithKeyCoordinator sets coordinator of keys shared with other processes, it overrides 'key_coordination' config
+func WithKeyCoordinator(coordinator KeyCoordinator) ClientOpt {
+	return func(c *Client) {
+		c.KeyCoordinator = coordinator
+	}
+}
+
+// WithRPCHealthMonitor RPCHealthMonitor functional option, use it to register hooks before the first check
+func WithRPCHealthMonitor(monitor *RPCHealthMonitor) ClientOpt {
+	return func(c *Client) {
+		c.RPCHealthMonitor = monitor
+	}
+}
+
+// WithEthClient makes client use already connected client (e.g. the one of the application under test) instead of
+// connecting to network's URLs, which can then be omitted. Tracer uses the same connection. It isn't closed by Close().
+func WithEthClient(client *ethclient.Client) ClientOpt {
+	return func(c *Client) {
+		c.Client = client
+	}
+}
+
+// WithRPCClient makes client use already connected RPC client the same way as WithEthClient
+func WithRPCClient(client *rpc.Client) ClientOpt {
+	return func(c *Client) {
+		c.Client = ethclient.NewClient(client)
+	}
+}
+
+// connectTracer connects the tracer to network's URLs or makes it use connection provided with WithEthClient or WithRPCClient
+func (m *Client) connectTracer(t *Tracer) error {
+	if m.externalClient {
+		t.rpcClient = m.Client.Client()
+		t.sharedConnection = true
+		return nil
+	}
+	if err := t.connect(m.Cfg.Network); err != nil {
+		return wrapError(err, ErrCreateTracer)
+	}
+	return nil
+}
+
 // WithTracer Tracer functional option
 func WithTracer(t *Tracer) ClientOpt {
 	return func(c *Client) {
@@ -756,9 +1211,18 @@ type ContextErrorKey struct{}
 // NewTXOpts returns a new transaction options wrapper,
 // Sets gas price/fee tip/cap and gas limit either based on TOML config or estimations.
 func (m *Client) NewTXOpts(o ...TransactOpt) *bind.TransactOpts {
-	opts, nonce, estimations := m.getProposedTransactionOptions(0)
+	return m.NewTXOptsCtx(context.Background(), o...)
+}
+
+// NewTXOptsCtx is the same as NewTXOpts, but fetching nonce and gas estimations can be cancelled with the context, which is
+// also set as options' context, so that it's used when sending the transaction
+func (m *Client) NewTXOptsCtx(ctx context.Context, o ...TransactOpt) *bind.TransactOpts {
+	opts, nonce, estimations := m.getProposedTransactionOptions(ctx, 0)
 	m.configureTransactionOpts(opts, nonce.PendingNonce, estimations, o...)
-	L.Debug().
+	if m.deferredNonceAllocation() {
+		m.attachNonceAllocation(opts, nonce.PendingNonce)
+	}
+	m.logger().Debug().
 		Interface("Nonce", opts.Nonce).
 		Interface("Value", opts.Value).
 		Interface("GasPrice", opts.GasPrice).
@@ -766,37 +1230,46 @@ func (m *Client) NewTXOpts(o ...TransactOpt) *bind.TransactOpts {
 		Interface("GasTipCap", opts.GasTipCap).
 		Uint64("GasLimit", opts.GasLimit).
 		Msg("New transaction options")
-	return opts
+	return m.guardTransactOpts(opts)
 }
 
 // NewTXKeyOpts returns a new transaction options wrapper,
 // sets opts.GasPrice and opts.GasLimit from seth.toml or override with options
 func (m *Client) NewTXKeyOpts(keyNum int, o ...TransactOpt) *bind.TransactOpts {
+	return m.NewTXKeyOptsCtx(context.Background(), keyNum, o...)
+}
+
+// NewTXKeyOptsCtx is the same as NewTXKeyOpts, but fetching nonce and gas estimations can be cancelled with the context, which
+// is also set as options' context, so that it's used when sending the transaction
+func (m *Client) NewTXKeyOptsCtx(ctx context.Context, keyNum int, o ...TransactOpt) *bind.TransactOpts {
 	if keyNum > len(m.Addresses) || keyNum < 0 {
 		errText := fmt.Sprintf("keyNum is out of range. Expected %d-%d. Got: %d", 0, len(m.Addresses)-1, keyNum)
+		err := errors.New(errText)
 		if keyNum == TimeoutKeyNum {
 			errText += " (this is a probably because, we didn't manage to find any synced key before timeout)"
+			err = errors.Wrap(ErrKeySync, errText)
 		}
-
-		err := errors.New(errText)
-		m.Errors = append(m.Errors, err)
+		m.addError(GlobalErrorScope, err)
 		opts := &bind.TransactOpts{}
 
 		// can't return nil, otherwise RPC wrapper will panic and we might lose funds on testnets/mainnets, that's why
 		// error is passed in Context here to avoid panic, whoever is using Seth should make sure that there is no error
 		// present in Context before using *bind.TransactOpts
-		opts.Context = context.WithValue(context.Background(), ContextErrorKey{}, err)
+		opts.Context = context.WithValue(ctx, ContextErrorKey{}, err)
 
-		return opts
+		return m.guardTransactOpts(opts)
 	}
-	L.Debug().
+	m.logger().Debug().
 		Interface("KeyNum", keyNum).
 		Interface("Address", m.Addresses[keyNum]).
 		Msg("Estimating transaction")
-	opts, nonceStatus, estimations := m.getProposedTransactionOptions(keyNum)
+	opts, nonceStatus, estimations := m.getProposedTransactionOptions(ctx, keyNum)
 
 	m.configureTransactionOpts(opts, nonceStatus.PendingNonce, estimations, o...)
-	L.Debug().
+	if m.deferredNonceAllocation() {
+		m.attachNonceAllocation(opts, nonceStatus.PendingNonce)
+	}
+	m.logger().Debug().
 		Interface("KeyNum", keyNum).
 		Interface("Nonce", opts.Nonce).
 		Interface("Value", opts.Value).
@@ -805,7 +1278,12 @@ func (m *Client) NewTXKeyOpts(keyNum int, o ...TransactOpt) *bind.TransactOpts {
 		Interface("GasTipCap", opts.GasTipCap).
 		Uint64("GasLimit", opts.GasLimit).
 		Msg("New transaction options")
-	return opts
+	return m.guardTransactOpts(opts)
+}
+
+// localNonceAllocationEnabled returns true if nonces should be allocated from nonce manager's local counter
+func (m *Client) localNonceAllocationEnabled() bool {
+	return m.NonceManager != nil && m.Cfg.NonceManager != nil && m.Cfg.NonceManager.LocalNonceAllocation
 }
 
 // AnySyncedKey returns the first synced key
@@ -824,38 +1302,168 @@ type NonceStatus struct {
 	PendingNonce uint64
 }
 
-func (m *Client) getNonceStatus(keyNum int) (NonceStatus, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
+func (m *Client) getNonceStatus(ctx context.Context, keyNum int) (NonceStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.Cfg.Network.TxnTimeout.Duration())
 	defer cancel()
-	pendingNonce, err := m.Client.PendingNonceAt(ctx, m.Addresses[keyNum])
-	if err != nil {
-		L.Error().Err(err).Msg("Failed to get pending nonce")
+	// both nonces are fetched in a single batch request
+	var pendingNonce, lastNonce hexutil.Uint64
+	batch := []BatchElem{
+		{Method: "eth_getTransactionCount", Args: []interface{}{m.Addresses[keyNum], "pending"}, Result: &pendingNonce},
+		{Method: "eth_getTransactionCount", Args: []interface{}{m.Addresses[keyNum], "latest"}, Result: &lastNonce},
+	}
+	if err := m.BatchCallCtx(ctx, batch); err != nil {
+		m.logger().Error().Err(err).Msg("Failed to get nonces")
 		return NonceStatus{}, err
 	}
-
-	lastNonce, err := m.Client.NonceAt(ctx, m.Addresses[keyNum], nil)
-	if err != nil {
+	if err := batch[0].Error; err != nil {
+		m.logger().Error().Err(err).Msg("Failed to get pending nonce")
+		return NonceStatus{}, err
+	}
+	if err := batch[1].Error; err != nil {
 		return NonceStatus{}, err
 	}
 
 	return NonceStatus{
-		LastNonce:    lastNonce,
-		PendingNonce: pendingNonce,
+		LastNonce:    uint64(lastNonce),
+		PendingNonce: uint64(pendingNonce),
 	}, nil
 }
 
+// deferredNonceAllocation returns true if nonces are allocated, when transactions are signed, see attachNonceAllocation
+func (m *Client) deferredNonceAllocation() bool {
+	return m.NonceManager != nil && (m.localNonceAllocationEnabled() || m.KeyCoordinator != nil)
+}
+
+// attachNonceAllocation wraps signer of transaction options, so that nonce is allocated from local counter (or the counter
+// shared with other processes) only when transaction is signed and options' nonce is just a placeholder replaced in the
+// signed transaction. That way transactions, that fail before they are signed (e.g. their gas estimation reverts), don't
+// allocate any nonce and nonces of transactions, that fail to be signed, are released at once. Transactions with
+// nonce other than the placeholder (e.g. set with WithNonce) are signed as they are.
+func (m *Client) attachNonceAllocation(opts *bind.TransactOpts, placeholder uint64) {
+	if opts.Signer == nil {
+		return
+	}
+	opts.Context = context.WithValue(contextOrBackground(opts.Context), noncePlaceholderKey{}, placeholder)
+	sign := opts.Signer
+	opts.Signer = func(from common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		if tx.Nonce() != placeholder {
+			return sign(from, tx)
+		}
+		nonce, err := m.allocateNonce(contextOrBackground(opts.Context), from)
+		if err != nil {
+			return nil, err
+		}
+		if nonce != tx.Nonce() {
+			tx = replacementTx(tx, nonce, 0)
+		}
+		signed, err := sign(from, tx)
+		if err != nil {
+			m.NonceManager.releaseNonce(unsentTransaction{address: from, nonce: nonce})
+			return nil, err
+		}
+		m.NonceManager.markSigned(from, signed)
+		return signed, nil
+	}
+}
+
+// noncePlaceholderKey is a context key of the nonce placeholder of transaction options, see attachNonceAllocation
+type noncePlaceholderKey struct{}
+
+// hasNoncePlaceholder returns true if nonce of transaction options is a placeholder replaced, when transaction is signed
+func hasNoncePlaceholder(opts *bind.TransactOpts) bool {
+	placeholder, ok := contextOrBackground(opts.Context).Value(noncePlaceholderKey{}).(uint64)
+	return ok && opts.Nonce != nil && opts.Nonce.Uint64() == placeholder
+}
+
+// allocateNonce allocates the lowest nonce released by transaction of the address, that wasn't sent, or the next one from
+// local counter (or the counter shared with other processes, with pending nonce as its lower bound)
+func (m *Client) allocateNonce(ctx context.Context, address common.Address) (uint64, error) {
+	for {
+		released, ok := m.NonceManager.takeReleasedNonce(address)
+		if !ok {
+			break
+		}
+		// released transaction might have still been sent, if its sending was in flight, when it was released
+		if released.hash == (common.Hash{}) || !m.transactionKnown(ctx, released.hash) {
+			return released.nonce, nil
+		}
+	}
+	if m.KeyCoordinator == nil {
+		return m.NonceManager.AllocateNonce(address), nil
+	}
+	pendingNonce, err := m.Client.PendingNonceAt(ctx, address)
+	if err != nil {
+		return 0, wrapError(err, ErrNonce)
+	}
+	nonce, err := m.KeyCoordinator.AllocateNonce(ctx, address, pendingNonce)
+	if err != nil {
+		return 0, err
+	}
+	m.NonceManager.markAllocated(address)
+	return nonce, nil
+}
+
+// transactionKnown returns true if node knows the transaction, i.e. it's pending or mined. If node can't be queried
+// it's assumed it does, so that nonce isn't allocated twice.
+func (m *Client) transactionKnown(ctx context.Context, hash common.Hash) bool {
+	_, _, err := m.Client.TransactionByHash(ctx, hash)
+	if err != nil && !errors.Is(err, ethereum.NotFound) {
+		m.logger().Warn().Err(err).Str("Transaction", hash.Hex()).Msg("Failed to check if transaction was sent")
+	}
+	return !errors.Is(err, ethereum.NotFound)
+}
+
+// releaseUnsentNonces releases nonces of signed transactions, that node doesn't know, because their sending failed, so
+// that they are allocated again and don't leave a gap. Nonces of transactions, that aren't signed yet, are never released,
+// since other goroutines might be about to send them.
+func (m *Client) releaseUnsentNonces(ctx context.Context) {
+	for _, tx := range m.NonceManager.signedTransactions() {
+		if m.transactionKnown(ctx, tx.hash) {
+			m.NonceManager.markSent(tx.address, tx.nonce)
+			continue
+		}
+		m.logger().Debug().
+			Str("Address", tx.address.Hex()).
+			Uint64("Nonce", tx.nonce).
+			Msg("Releasing nonce of transaction, that wasn't sent")
+		m.NonceManager.releaseNonce(tx)
+	}
+}
+
 // getProposedTransactionOptions gets all the tx info that network proposed
-func (m *Client) getProposedTransactionOptions(keyNum int) (*bind.TransactOpts, NonceStatus, GasEstimations) {
-	nonceStatus, err := m.getNonceStatus(keyNum)
+func (m *Client) getProposedTransactionOptions(ctx context.Context, keyNum int) (*bind.TransactOpts, NonceStatus, GasEstimations) {
+	var nonceStatus NonceStatus
+	var err error
+	// wait before allocating the nonce, so that it isn't lost if breaker stays tripped for too long
+	if err = m.waitForGasSpikeBreaker(ctx); err != nil {
+		m.addError(m.keyErrorScope(keyNum), err)
+		// can't return nil, otherwise RPC wrapper will panic
+		errCtx := context.WithValue(ctx, ContextErrorKey{}, err)
+
+		return &bind.TransactOpts{Context: errCtx}, NonceStatus{}, GasEstimations{}
+	}
+	if err = m.ensureKeyFunded(ctx, keyNum); err != nil {
+		m.addError(m.keyErrorScope(keyNum), err)
+		// can't return nil, otherwise RPC wrapper will panic
+		errCtx := context.WithValue(ctx, ContextErrorKey{}, err)
+
+		return &bind.TransactOpts{Context: errCtx}, NonceStatus{}, GasEstimations{}
+	}
+	if m.localNonceAllocationEnabled() && m.KeyCoordinator == nil {
+		// it's only a placeholder, nonce is allocated when transaction is signed
+		nonceStatus.PendingNonce = m.NonceManager.peekNonce(m.Addresses[keyNum])
+	} else {
+		nonceStatus, err = m.getNonceStatus(ctx, keyNum)
+	}
 	if err != nil {
-		m.Errors = append(m.Errors, err)
+		m.addError(m.keyErrorScope(keyNum), err)
 		// can't return nil, otherwise RPC wrapper will panic
-		ctx := context.WithValue(context.Background(), ContextErrorKey{}, err)
+		errCtx := context.WithValue(ctx, ContextErrorKey{}, err)
 
-		return &bind.TransactOpts{Context: ctx}, NonceStatus{}, GasEstimations{}
+		return &bind.TransactOpts{Context: errCtx}, NonceStatus{}, GasEstimations{}
 	}
 
-	var ctx context.Context
+	var errCtx context.Context
 
 	if m.Cfg.PendingNonceProtectionEnabled {
 		if nonceStatus.PendingNonce > nonceStatus.LastNonce {
@@ -867,38 +1475,38 @@ This issue is caused by one of two things:
 2. You have stuck transaction(s). Speed them up by sending replacement transactions with higher gas price before continuing, otherwise future transactions most probably will also get stuck.
 `
 			err := fmt.Errorf(errMsg, keyNum, nonceStatus.PendingNonce-nonceStatus.LastNonce)
-			m.Errors = append(m.Errors, err)
+			m.addError(m.keyErrorScope(keyNum), err)
 			// can't return nil, otherwise RPC wrapper will panic and we might lose funds on testnets/mainnets, that's why
 			// error is passed in Context here to avoid panic, whoever is using Seth should make sure that there is no error
 			// present in Context before using *bind.TransactOpts
-			ctx = context.WithValue(context.Background(), ContextErrorKey{}, err)
+			errCtx = context.WithValue(ctx, ContextErrorKey{}, err)
 		}
-		L.Debug().
+		m.logger().Debug().
 			Msg("Pending nonce protection is enabled. Nonce status is OK")
 	}
 
-	estimations := m.CalculateGasEstimations(m.NewDefaultGasEstimationRequest())
+	estimations := m.CalculateGasEstimationsCtx(ctx, m.NewDefaultGasEstimationRequest())
 
-	L.Debug().
+	m.logger().Debug().
 		Interface("KeyNum", keyNum).
 		Uint64("Nonce", nonceStatus.PendingNonce).
 		Interface("GasEstimations", estimations).
 		Msg("Proposed transaction options")
 
-	opts, err := bind.NewKeyedTransactorWithChainID(m.PrivateKeys[keyNum], big.NewInt(m.ChainID))
+	opts, err := m.newSigningTransactor(ctx, keyNum)
 	if err != nil {
 		err = errors.Wrapf(err, "failed to create transactor for key %d", keyNum)
-		m.Errors = append(m.Errors, err)
+		m.addError(m.keyErrorScope(keyNum), err)
 		// can't return nil, otherwise RPC wrapper will panic and we might lose funds on testnets/mainnets, that's why
 		// error is passed in Context here to avoid panic, whoever is using Seth should make sure that there is no error
 		// present in Context before using *bind.TransactOpts
-		ctx := context.WithValue(context.Background(), ContextErrorKey{}, err)
+		errCtx := context.WithValue(ctx, ContextErrorKey{}, err)
 
-		return &bind.TransactOpts{Context: ctx}, NonceStatus{}, GasEstimations{}
+		return &bind.TransactOpts{Context: errCtx}, NonceStatus{}, GasEstimations{}
 	}
 
-	if ctx != nil {
-		opts.Context = ctx
+	if errCtx != nil {
+		opts.Context = errCtx
 	}
 
 	return opts, nonceStatus, estimations
@@ -926,6 +1534,11 @@ func (m *Client) NewDefaultGasEstimationRequest() GasEstimationRequest {
 // CalculateGasEstimations calculates gas estimations (price, tip/cap) or uses hardcoded values if estimation is disabled,
 // estimation errors or network is a simulated one.
 func (m *Client) CalculateGasEstimations(request GasEstimationRequest) GasEstimations {
+	return m.CalculateGasEstimationsCtx(context.Background(), request)
+}
+
+// CalculateGasEstimationsCtx is the same as CalculateGasEstimations, but estimation can be cancelled with the context
+func (m *Client) CalculateGasEstimationsCtx(ctx context.Context, request GasEstimationRequest) GasEstimations {
 	estimations := GasEstimations{}
 
 	if m.Cfg.IsSimulatedNetwork() || !request.GasEstimationEnabled {
@@ -936,12 +1549,12 @@ func (m *Client) CalculateGasEstimations(request GasEstimationRequest) GasEstima
 		return estimations
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
+	ctx, cancel := context.WithTimeout(ctx, m.Cfg.Network.TxnTimeout.Duration())
 	defer cancel()
 
 	var disableEstimationsIfNeeded = func(err error) {
-		if strings.Contains(err.Error(), ZeroGasSuggestedErr) {
-			L.Warn().Msg("Received incorrect gas estimations. Disabling them and reverting to hardcoded values. Remember to update your config!")
+		if errors.Is(err, ZeroGasSuggestedErr) {
+			m.logger().Warn().Msg("Received incorrect gas estimations. Disabling them and reverting to hardcoded values. Remember to update your config!")
 			m.Cfg.Network.GasPriceEstimationEnabled = false
 		}
 	}
@@ -950,7 +1563,7 @@ func (m *Client) CalculateGasEstimations(request GasEstimationRequest) GasEstima
 		gasPrice, err := m.GetSuggestedLegacyFees(ctx, request.Priority)
 		if err != nil {
 			disableEstimationsIfNeeded(err)
-			L.Warn().Err(err).Msg("Failed to get suggested Legacy fees. Using hardcoded values")
+			m.logger().Warn().Err(err).Msg("Failed to get suggested Legacy fees. Using hardcoded values")
 			estimations.GasPrice = big.NewInt(request.FallbackGasPrice)
 		} else {
 			estimations.GasPrice = gasPrice
@@ -960,16 +1573,21 @@ func (m *Client) CalculateGasEstimations(request GasEstimationRequest) GasEstima
 	if m.Cfg.Network.EIP1559DynamicFees {
 		maxFee, priorityFee, err := m.GetSuggestedEIP1559Fees(ctx, request.Priority)
 		if err != nil {
-			L.Warn().Err(err).Msg("Failed to get suggested EIP1559 fees. Using hardcoded values")
+			m.logger().Warn().Err(err).Msg("Failed to get suggested EIP1559 fees. Using hardcoded values")
 			estimations.GasFeeCap = big.NewInt(request.FallbackGasFeeCap)
 			estimations.GasTipCap = big.NewInt(request.FallbackGasTipCap)
 
 			disableEstimationsIfNeeded(err)
 
-			if strings.Contains(err.Error(), "method eth_maxPriorityFeePerGas") || strings.Contains(err.Error(), "method eth_maxFeePerGas") || strings.Contains(err.Error(), "method eth_feeHistory") || strings.Contains(err.Error(), "expected input list for types.txdata") {
-				L.Warn().Msg("EIP1559 fees are not supported by the network. Switching to Legacy fees. Remember to update your config!")
+			if isMethodUnavailableErr(err) {
+				m.markCapabilityUnsupported(func(caps *NodeCapabilities) {
+					caps.EIP1559 = false
+				})
+			}
+			if !m.Capabilities().EIP1559 {
+				m.logger().Warn().Msg("EIP1559 fees are not supported by the network. Switching to Legacy fees. Remember to update your config!")
 				if m.Cfg.Network.GasPrice == 0 {
-					L.Warn().Msg("Gas price is 0. If Legacy estimations fail, there will no fallback price and transactions will start fail. Set gas price in config and disable EIP1559DynamicFees")
+					m.logger().Warn().Msg("Gas price is 0. If Legacy estimations fail, there will no fallback price and transactions will start fail. Set gas price in config and disable EIP1559DynamicFees")
 				}
 				m.Cfg.Network.EIP1559DynamicFees = false
 				calculateLegacyFees()
@@ -995,7 +1613,7 @@ func (m *Client) EstimateGasLimitForFundTransfer(from, to common.Address, amount
 		Value: amount,
 	})
 	if err != nil {
-		L.Warn().Err(err).Msg("Failed to estimate gas for fund transfer.")
+		m.logger().Warn().Err(err).Msg("Failed to estimate gas for fund transfer.")
 		return 0, errors.Wrapf(err, "failed to estimate gas for fund transfer")
 	}
 	return gasLimit, nil
@@ -1020,6 +1638,8 @@ func (m *Client) configureTransactionOpts(
 	for _, f := range o {
 		f(opts)
 	}
+	m.attachAccessList(opts)
+	m.attachSimulation(opts)
 	return opts
 }
 
@@ -1052,21 +1672,41 @@ func (cl *ContractLoader[T]) LoadContract(name string, address common.Address, a
 // available at the address, so that when the method returns it's safe to interact with it. It also saves the contract address and ABI name
 // to the contract map, so that we can use that, when tracing transactions. It is suggested to use name identical to the name of the contract Solidity file.
 func (m *Client) DeployContract(auth *bind.TransactOpts, name string, abi abi.ABI, bytecode []byte, params ...interface{}) (DeploymentData, error) {
-	L.Info().
+	return m.DeployContractCtx(context.Background(), auth, name, abi, bytecode, params...)
+}
+
+// DeployContractCtx is the same as DeployContract, but waiting for the deployment can be cancelled with the context
+func (m *Client) DeployContractCtx(ctx context.Context, auth *bind.TransactOpts, name string, abi abi.ABI, bytecode []byte, params ...interface{}) (DeploymentData, error) {
+	m.logger().Info().
 		Msgf("Started deploying %s contract", name)
 
-	if auth.Context != nil {
-		if err, ok := auth.Context.Value(ContextErrorKey{}).(error); ok {
-			return DeploymentData{}, errors.Wrapf(err, "aborted contract deployment for %s, because context passed in transaction options had an error set", name)
+	if m.ContractStore != nil {
+		if err := m.ContractStore.VerifyBIN(name, bytecode); err != nil {
+			return DeploymentData{}, err
 		}
 	}
 
-	address, tx, contract, err := bind.DeployContract(auth, abi, bytecode, m.Client, params...)
+	return m.deployVerifiedContract(ctx, auth, name, abi, bytecode, params...)
+}
+
+// deployVerifiedContract deploys bytecode, that was already verified against checksum manifest
+func (m *Client) deployVerifiedContract(ctx context.Context, auth *bind.TransactOpts, name string, abi abi.ABI, bytecode []byte, params ...interface{}) (DeploymentData, error) {
+	if err := m.checkTransactOpts(auth); err != nil {
+		return DeploymentData{}, errors.Wrapf(err, "aborted contract deployment for %s", name)
+	}
+
+	if address, ok := m.findReusableContract(auth, name, bytecode); ok {
+		return m.reuseContract(name, abi, address), nil
+	}
+
+	startedAt := time.Now()
+	address, tx, contract, err := m.deployContract(auth, abi, bytecode, params...)
 	if err != nil {
+		m.onDeploymentSendError()
 		return DeploymentData{}, wrapErrInMessageWithASuggestion(err)
 	}
 
-	L.Info().
+	m.logger().Info().
 		Str("Address", address.Hex()).
 		Str("TXHash", tx.Hash().Hex()).
 		Msgf("Waiting for %s contract deployment to finish", name)
@@ -1080,13 +1720,13 @@ func (m *Client) DeployContract(auth *bind.TransactOpts, name string, abi abi.AB
 	// I had this one failing sometimes, when transaction has been minted, but contract cannot be found yet at address
 	if err := retry.Do(
 		func() error {
-			ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
-			_, err := bind.WaitDeployed(ctx, m.Client, tx)
+			waitCtx, cancel := context.WithTimeout(ctx, m.Cfg.Network.TxnTimeout.Duration())
+			_, err := bind.WaitDeployed(waitCtx, m.Client, tx)
 			cancel()
 
 			// let's make sure that deployment transaction was successful, before retrying
 			if err != nil {
-				receipt, mineErr := bind.WaitMined(context.Background(), m.Client, tx)
+				receipt, mineErr := bind.WaitMined(ctx, m.Client, tx)
 				if mineErr != nil {
 					return mineErr
 				}
@@ -1098,50 +1738,107 @@ func (m *Client) DeployContract(auth *bind.TransactOpts, name string, abi abi.AB
 
 			return err
 		}, retry.OnRetry(func(i uint, _ error) {
-			L.Debug().Uint("Attempt", i).Msg("Waiting for contract to be deployed")
+			m.logger().Debug().Uint("Attempt", i).Msg("Waiting for contract to be deployed")
 		}),
 		retry.DelayType(retry.FixedDelay),
 		retry.Attempts(10),
 		retry.Delay(time.Duration(1)*time.Second),
 		retry.RetryIf(func(err error) bool {
-			return strings.Contains(strings.ToLower(err.Error()), "no contract code at given address") ||
-				strings.Contains(strings.ToLower(err.Error()), "no contract code after deployment")
+			return errors.Is(err, bind.ErrNoCode) || errors.Is(err, bind.ErrNoCodeAfterDeploy)
 		}),
 	); err != nil {
 		// do not pass the error here, because it's not transaction submission error
-		_, _ = m.Decode(tx, nil)
+		_, _ = m.DecodeCtx(ctx, tx, nil)
 		return DeploymentData{}, wrapErrInMessageWithASuggestion(err)
 	}
 
-	L.Info().
+	m.logger().Info().
 		Str("Address", address.Hex()).
 		Str("TXHash", tx.Hash().Hex()).
 		Msgf("Deployed %s contract", name)
 
+	if m.RunManifest != nil || m.Spending != nil {
+		receipt, receiptErr := m.Client.TransactionReceipt(ctx, tx.Hash())
+		m.recordManifestTransaction(tx, receipt, receiptErr, startedAt)
+		if receiptErr == nil {
+			m.recordSpending(tx, receipt)
+		}
+	}
+
+	m.saveDeployedContract(name, address)
+
+	return DeploymentData{Address: address, Transaction: tx, BoundContract: contract}, nil
+}
+
+// deployContract sends deployment transaction. If send retries are enabled, it's sent with SendTransaction(), so that nonce
+// errors are retried, in which case contract's address is derived from the nonce, that was actually used.
+func (m *Client) deployContract(auth *bind.TransactOpts, abi abi.ABI, bytecode []byte, params ...interface{}) (common.Address, *types.Transaction, *bind.BoundContract, error) {
+	if m.sendRetries() == 0 || auth.NoSend {
+		return bind.DeployContract(auth, abi, bytecode, m.Client, params...)
+	}
+	noSendAuth := *auth
+	noSendAuth.NoSend = true
+	_, tx, _, err := bind.DeployContract(&noSendAuth, abi, bytecode, m.Client, params...)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	ctx := auth.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if tx, err = m.SendTransaction(ctx, tx); err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	address := crypto.CreateAddress(auth.From, tx.Nonce())
+	return address, tx, bind.NewBoundContract(address, abi, m.Client, m.Client, m.Client), nil
+}
+
+// onDeploymentSendError releases nonce and budget of deployment transaction and records send error, when it couldn't be sent
+func (m *Client) onDeploymentSendError() {
+	if m.deferredNonceAllocation() {
+		m.releaseUnsentNonces(context.Background())
+	}
+	m.releaseUnsentBudget(context.Background())
+	if m.RunManifest != nil {
+		m.RunManifest.AddSendError()
+	}
+}
+
+// saveDeployedContract saves address of deployed contract to contract map file, if it's enabled
+func (m *Client) saveDeployedContract(name string, address common.Address) {
 	if !m.Cfg.ShoulSaveDeployedContractMap() {
-		return DeploymentData{Address: address, Transaction: tx, BoundContract: contract}, nil
+		return
 	}
 
 	if err := SaveDeployedContract(m.Cfg.ContractMapFile, name, address.Hex()); err != nil {
-		L.Warn().
+		m.logger().Warn().
 			Err(err).
 			Msg("Failed to save deployed contract address to file")
+	} else {
+		m.recordManifestArtifact(m.Cfg.ContractMapFile)
 	}
-
-	return DeploymentData{Address: address, Transaction: tx, BoundContract: contract}, nil
 }
 
 type DeploymentData struct {
 	Address       common.Address
 	Transaction   *types.Transaction
 	BoundContract *bind.BoundContract
+	// Reused is true if contract deployed in previous run was reused, Transaction is nil then
+	Reused bool
 }
 
 // DeployContractFromContractStore deploys contract from Seth's Contract Store, waits for transaction to be minted and contract really
 // available at the address, so that when the method returns it's safe to interact with it. It also saves the contract address and ABI name
 // to the contract map, so that we can use that, when tracing transactions. Name by which you refer the contract should be the same as the
-// name of ABI file (you can omit the .abi suffix).
+// name of ABI file (you can omit the .abi suffix). If its BIN file has library placeholders, libraries are linked with addresses set
+// with WithLibraries() or deployed from the Contract Store first.
 func (m *Client) DeployContractFromContractStore(auth *bind.TransactOpts, name string, params ...interface{}) (DeploymentData, error) {
+	return m.DeployContractFromContractStoreCtx(context.Background(), auth, name, params...)
+}
+
+// DeployContractFromContractStoreCtx is the same as DeployContractFromContractStore, but waiting for deployments can be
+// cancelled with the context
+func (m *Client) DeployContractFromContractStoreCtx(ctx context.Context, auth *bind.TransactOpts, name string, params ...interface{}) (DeploymentData, error) {
 	if m.ContractStore == nil {
 		return DeploymentData{}, errors.New("ABIStore is nil")
 	}
@@ -1149,17 +1846,20 @@ func (m *Client) DeployContractFromContractStore(auth *bind.TransactOpts, name s
 	name = strings.TrimSuffix(name, ".abi")
 	name = strings.TrimSuffix(name, ".bin")
 
-	abi, ok := m.ContractStore.ABIs[name+".abi"]
+	abi, ok := m.ContractStore.GetABI(name)
 	if !ok {
 		return DeploymentData{}, errors.New("ABI not found")
 	}
 
-	bytecode, ok := m.ContractStore.BINs[name+".bin"]
+	bytecode, ok := m.ContractStore.GetBIN(name)
 	if !ok {
+		if unlinked, ok := m.ContractStore.GetUnlinkedBIN(name); ok {
+			return m.deployLinkedContract(ctx, auth, name, unlinked, params...)
+		}
 		return DeploymentData{}, errors.New("BIN not found")
 	}
 
-	data, err := m.DeployContract(auth, name, abi, bytecode, params...)
+	data, err := m.DeployContractCtx(ctx, auth, name, *abi, bytecode, params...)
 	if err != nil {
 		return DeploymentData{}, err
 	}
@@ -1168,7 +1868,11 @@ func (m *Client) DeployContractFromContractStore(auth *bind.TransactOpts, name s
 }
 
 func (m *Client) SaveDecodedCallsAsJson(dirname string) error {
-	return m.Tracer.SaveDecodedCallsAsJson(dirname)
+	if err := m.Tracer.SaveDecodedCallsAsJson(dirname); err != nil {
+		return err
+	}
+	m.recordManifestArtifact(dirname)
+	return nil
 }
 
 type TransactionLog struct {
@@ -1194,7 +1898,7 @@ func (m *Client) decodeContractLogs(l zerolog.Logger, logs []types.Log, a abi.AB
 				l.Trace().Str("Name", evSpec.RawName).Str("Signature", evSpec.Sig).Msg("Unpacking event")
 				eventsMap, topicsMap, err := decodeEventFromLog(l, a, evSpec, d)
 				if err != nil {
-					return nil, errors.Wrap(err, ErrDecodeLog)
+					return nil, wrapError(err, ErrDecodeLog)
 				}
 				parsedEvent := decodedLogFromMaps(&DecodedTransactionLog{}, eventsMap, topicsMap)
 				if decodedTransactionLog, ok := parsedEvent.(*DecodedTransactionLog); ok {
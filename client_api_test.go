@@ -34,9 +34,9 @@ func TestAPI(t *testing.T) {
 	bn, err := c.Client.BlockNumber(context.Background())
 	require.NoError(t, err)
 	weiValue := big.NewInt(1)
-	overridenGasPrice := big.NewInt(c.Cfg.Network.GasPrice + 1)
-	overridenGasFeeCap := big.NewInt(c.Cfg.Network.GasFeeCap + 1)
-	overridenGasTipCap := big.NewInt(c.Cfg.Network.GasTipCap + 1)
+	overridenGasPrice := new(big.Int).Add(big.NewInt(c.Cfg.Network.GasPrice), big.NewInt(1))
+	overridenGasFeeCap := new(big.Int).Add(big.NewInt(c.Cfg.Network.GasFeeCap), big.NewInt(1))
+	overridenGasTipCap := new(big.Int).Add(big.NewInt(c.Cfg.Network.GasTipCap), big.NewInt(1))
 	overridenGasLimit := uint64(c.Cfg.Network.GasLimit) + 1
 
 	tests := []test{
@@ -140,6 +140,39 @@ func TestAPINonces(t *testing.T) {
 	}
 }
 
+func TestAPILocalNonceAllocation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.NonceManager.LocalNonceAllocation = true
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+
+	pnonce, err := c.Client.PendingNonceAt(context.Background(), c.Addresses[0])
+	require.NoError(t, err)
+
+	// all transactions are in flight at the same time
+	var txs []*types.Transaction
+	for i := 0; i < 3; i++ {
+		tx, err := TestEnv.DebugContract.AddCounter(c.NewTXOpts(), big.NewInt(88), big.NewInt(1))
+		require.NoError(t, err, "failed to send transaction")
+		require.Equal(t, pnonce+uint64(i), tx.Nonce(), "nonce should be allocated from local counter")
+		txs = append(txs, tx)
+	}
+	for _, tx := range txs {
+		_, err = c.Decode(tx, nil)
+		require.NoError(t, err, "transaction should be mined")
+	}
+
+	// transaction that was never sent should not leave a nonce gap
+	_, err = c.Decode(TestEnv.DebugContract.Set(c.NewTXOpts(seth.WithGasLimit(1)), big.NewInt(1)))
+	require.Error(t, err, "transaction with too low gas limit should fail")
+
+	decoded, err := c.Decode(TestEnv.DebugContract.Set(c.NewTXOpts(), big.NewInt(1)))
+	require.NoError(t, err, "transaction after reconciliation should be mined")
+	require.Equal(t, pnonce+3, decoded.Transaction.Nonce(), "nonce should be reconciled with pending nonce")
+}
+
 func TestAPISeqErrors(t *testing.T) {
 	c := newClientWithEphemeralAddresses(t)
 
@@ -159,7 +192,7 @@ func TestAPISeqErrors(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			c.Errors = append(c.Errors, errors.New("previous call error"))
+			c.ErrorCollector.Add(seth.KeyErrorScope(c.Addresses[0]), errors.New("previous call error"))
 			_, err := c.Decode(
 				TestEnv.DebugContract.Set(c.NewTXOpts(), big.NewInt(1)),
 			)
@@ -168,6 +201,70 @@ func TestAPISeqErrors(t *testing.T) {
 	}
 }
 
+func TestAPIContext(t *testing.T) {
+	c := newClient(t)
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "caller")
+	opts := c.NewTXKeyOptsCtx(ctx, 0)
+	require.NoError(t, seth.CheckTransactOpts(opts), "options should be valid")
+	require.Equal(t, "caller", opts.Context.Value(ctxKey{}), "caller's context should be set in options")
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if c.Cfg.NonceManager == nil || !c.Cfg.NonceManager.LocalNonceAllocation {
+		require.Error(t, seth.CheckTransactOpts(c.NewTXOptsCtx(cancelled)), "nonce shouldn't be fetched with cancelled context")
+	}
+
+	// transaction is signed, but never sent, so it would be awaited until transaction timeout
+	tx, err := c.Signer.SignTx(context.Background(), c.Addresses[0], types.NewTx(&types.LegacyTx{
+		Nonce:    1 << 40,
+		To:       &c.Addresses[0],
+		Gas:      21_000,
+		GasPrice: big.NewInt(1),
+	}))
+	require.NoError(t, err, "failed to sign transaction")
+	timeout, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	startedAt := time.Now()
+	_, err = c.DecodeCtx(timeout, tx, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded, "waiting should be cancelled by caller's context")
+	require.Less(t, time.Since(startedAt), c.Cfg.Network.TxnTimeout.Duration(), "waiting shouldn't last until transaction timeout")
+}
+
+func TestAPIClose(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	var two int64 = 2
+	cfg.EphemeralAddrs = &two
+	cfg.ReturnFundsOnClose = true
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	require.Len(t, c.Addresses, 3, "expected root and 2 ephemeral keys")
+
+	// other client is used to check balances after the closed one is disconnected
+	checker := newClient(t)
+	for _, address := range c.Addresses[1:] {
+		balance, err := checker.Client.BalanceAt(context.Background(), address, nil)
+		require.NoError(t, err, "failed to get balance")
+		require.Equal(t, 1, balance.Sign(), "ephemeral key should be funded")
+	}
+
+	require.NoError(t, c.Close(), "failed to close client")
+	require.ErrorIs(t, c.Context.Err(), context.Canceled, "client's context should be cancelled")
+	require.NoError(t, c.Close(), "closing client again should be a no-op")
+
+	maxFee := new(big.Int).Mul(big.NewInt(cfg.Network.GasPrice), big.NewInt(cfg.Network.TransferGasFee))
+	for _, address := range c.Addresses[1:] {
+		balance, err := checker.Client.BalanceAt(context.Background(), address, nil)
+		require.NoError(t, err, "failed to get balance")
+		require.True(t, balance.Cmp(maxFee) <= 0, "funds of ephemeral key %s should be returned, but it has %s", address.Hex(), balance)
+	}
+
+	_, err = c.Client.BlockNumber(context.Background())
+	require.Error(t, err, "RPC connection should be closed")
+}
+
 func TestAPIConfig(t *testing.T) {
 	cfg, err := seth.ReadConfig()
 	require.NoError(t, err)
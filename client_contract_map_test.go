@@ -2,7 +2,10 @@ package seth_test
 
 import (
 	"crypto/ecdsa"
+	"fmt"
+	"math/big"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -141,7 +144,7 @@ func TestContractMapNewClientIsNotCreatedWhenCorruptedContractMapFileExists(t *t
 	cfg.ContractMapFile = file.Name()
 	newClient, err := seth.NewClientRaw(cfg, addresses, pks)
 	require.Error(t, err, "succeeded in creation of new client")
-	require.Contains(t, err.Error(), seth.ErrReadContractMap, "expected error reading invalid toml")
+	require.ErrorIs(t, err, seth.ErrReadContractMap, "expected error reading invalid toml")
 	require.Nil(t, newClient, "expected new client to be nil")
 }
 
@@ -160,6 +163,41 @@ func TestContractMapNewClientIsNotCreatedWhenCorruptedContractMapFileExists_Inva
 	cfg.ContractMapFile = file.Name()
 	newClient, err := seth.NewClientRaw(cfg, addresses, pks)
 	require.Error(t, err, "succeeded in creation of new client")
-	require.Contains(t, err.Error(), seth.ErrReadContractMap, "expected error reading invalid contract address")
+	require.ErrorIs(t, err, seth.ErrReadContractMap, "expected error reading invalid contract address")
 	require.Nil(t, newClient, "expected new client to be nil")
 }
+
+func TestContractMapConcurrentAccess(t *testing.T) {
+	cm := seth.NewEmptyContractMap()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			contracts := make(map[string]string)
+			for j := 0; j < 10; j++ {
+				contracts[common.BigToAddress(big.NewInt(int64(i*100+j))).Hex()] = fmt.Sprintf("Contract_%d.abi", j)
+			}
+			cm.AddContracts(contracts)
+			cm.AddContract(common.BigToAddress(big.NewInt(int64(10_000+i))).Hex(), "Single")
+		}(i)
+		go func() {
+			defer wg.Done()
+			cm.Range(func(address, name string) bool {
+				require.Equal(t, name, cm.GetContractName(address), "incorrect contract name")
+				return true
+			})
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 110, cm.Size(), "incorrect number of contracts")
+	addr := common.BigToAddress(big.NewInt(305)).Hex()
+	require.Equal(t, "Contract_5", cm.GetContractName(addr), "name should be saved without .abi suffix")
+
+	snapshot := cm.Snapshot()
+	cm.RemoveContract(addr)
+	require.False(t, cm.IsKnownAddress(addr), "removed contract should not be known")
+	require.Len(t, snapshot, 110, "snapshot should not change, when map is modified")
+}
@@ -4,6 +4,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/smartcontractkit/seth"
 	"github.com/stretchr/testify/require"
 )
@@ -61,6 +62,90 @@ func TestSmokeDebugReverts(t *testing.T) {
 	}
 }
 
+func TestAPIDecodeRevertReason(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+
+	type test struct {
+		name     string
+		method   string
+		reason   string
+		contract string
+		params   map[string]interface{}
+		message  string
+	}
+
+	tests := []test{
+		{
+			name:     "revert with require",
+			method:   "alwaysRevertsRequire",
+			reason:   seth.RevertReasonError,
+			contract: "NetworkDebugContract",
+			params:   map[string]interface{}{"message": "always revert error"},
+			message:  "always revert error",
+		},
+		{
+			name:     "revert with assert(panic)",
+			method:   "alwaysRevertsAssert",
+			reason:   seth.RevertReasonPanic,
+			contract: "NetworkDebugContract",
+			params:   map[string]interface{}{"code": big.NewInt(1)},
+			message:  "panic code: 0x1",
+		},
+		{
+			name:     "revert with a custom err",
+			method:   "alwaysRevertsCustomError",
+			reason:   "CustomErr",
+			contract: "NetworkDebugContract",
+			params:   map[string]interface{}{"available": big.NewInt(12), "required": big.NewInt(21)},
+			message:  "error type: CustomErr, error values: [12 21]",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// gas limit is set, so that transaction is mined instead of failing on gas estimation
+			decoded, err := c.Decode(TestEnv.DebugContractRaw.Transact(c.NewTXOpts(seth.WithGasLimit(1_000_000)), tc.method))
+			require.Error(t, err, "transaction should revert")
+			require.NotNil(t, decoded, "reverted transaction should be decoded")
+			require.NotNil(t, decoded.RevertReason, "revert reason should be decoded")
+
+			reason := decoded.RevertReason
+			require.Equal(t, tc.reason, reason.Name, "incorrect error name")
+			require.Equal(t, tc.contract, reason.Contract, "incorrect contract")
+			require.Equal(t, TestEnv.DebugContractAddress.Hex(), reason.Address, "incorrect address")
+			require.Equal(t, tc.message, reason.Message, "incorrect message")
+			require.Len(t, reason.Params, len(tc.params), "incorrect number of params")
+			for name, expected := range tc.params {
+				value, ok := reason.Param(name)
+				require.True(t, ok, "param %s not found", name)
+				require.Equal(t, expected, value, "incorrect value of param %s", name)
+			}
+			require.Equal(t, tc.reason != seth.RevertReasonError && tc.reason != seth.RevertReasonPanic, reason.IsCustomError(), "incorrect custom error flag")
+
+			require.ErrorIs(t, err, seth.ErrTransactionReverted, "error should match reverted transaction")
+			var revertErr *seth.RevertError
+			require.ErrorAs(t, err, &revertErr, "error should be a revert error")
+			require.Equal(t, tc.message, revertErr.Reason, "incorrect reason of revert error")
+			if reason.IsCustomError() {
+				require.Equal(t, tc.reason, revertErr.CustomErrorName, "incorrect custom error name")
+				require.Equal(t, tc.contract, revertErr.Contract, "incorrect contract of custom error")
+				require.Equal(t, []interface{}{big.NewInt(12), big.NewInt(21)}, revertErr.Args, "incorrect custom error args")
+				for name, expected := range tc.params {
+					value, ok := revertErr.Arg(name)
+					require.True(t, ok, "arg %s not found", name)
+					require.Equal(t, expected, value, "incorrect value of arg %s", name)
+				}
+			} else {
+				require.Empty(t, revertErr.CustomErrorName, "built-in error shouldn't have custom error name")
+			}
+		})
+	}
+
+	decoded, err := c.Decode(TestEnv.DebugContract.Set(c.NewTXOpts(), big.NewInt(1)))
+	require.NoError(t, err, "transaction should not revert")
+	require.Nil(t, decoded.RevertReason, "successful transaction should have no revert reason")
+}
+
 func TestSmokeDebugData(t *testing.T) {
 	c := newClient(t)
 	c.Cfg.TracingLevel = seth.TracingLevel_All
@@ -233,3 +318,52 @@ func TestSmokeDebugData(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIDecodeCustomRevertErrorOnSend(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+
+	// without gas limit transaction reverts already during gas estimation, so it's never sent
+	_, err := c.Decode(TestEnv.DebugContractRaw.Transact(c.NewTXOpts(seth.WithGasLimit(0)), "alwaysRevertsCustomError"))
+	require.Error(t, err, "transaction should revert")
+	require.ErrorIs(t, err, seth.ErrTransactionReverted, "error should match reverted transaction")
+	var revertErr *seth.RevertError
+	require.ErrorAs(t, err, &revertErr, "error should be a revert error")
+	require.Equal(t, "CustomErr", revertErr.CustomErrorName, "incorrect custom error name")
+	require.Equal(t, "NetworkDebugContract", revertErr.Contract, "incorrect contract of custom error")
+	available, ok := revertErr.Arg("available")
+	require.True(t, ok, "arg available not found")
+	require.Equal(t, big.NewInt(12), available, "incorrect value of arg available")
+	required, ok := revertErr.Arg("required")
+	require.True(t, ok, "arg required not found")
+	require.Equal(t, big.NewInt(21), required, "incorrect value of arg required")
+}
+
+func TestAPIDecodeCalldata(t *testing.T) {
+	c := newClient(t)
+	debugABI, ok := c.ContractStore.GetABI("NetworkDebugContract")
+	require.True(t, ok, "debug contract ABI should be in the store")
+
+	data, err := debugABI.Pack("emitInputs", big.NewInt(7), "seven")
+	require.NoError(t, err, "failed to pack calldata")
+	decoded, err := c.DecodeCalldata(data)
+	require.NoError(t, err, "failed to decode calldata")
+	require.Equal(t, "NetworkDebugContract", decoded.Contract, "incorrect contract")
+	require.Equal(t, "emitInputs(uint256,string)", decoded.Method, "incorrect method")
+	require.Equal(t, map[string]interface{}{"inputVal1": big.NewInt(7), "inputVal2": "seven"}, decoded.Input, "incorrect inputs")
+
+	// payload of a call forwarding another call
+	inner, err := debugABI.Pack("set", big.NewInt(1))
+	require.NoError(t, err, "failed to pack calldata")
+	data, err = debugABI.Pack("onTokenTransfer", TestEnv.DebugContractAddress, big.NewInt(2), inner)
+	require.NoError(t, err, "failed to pack calldata")
+	decoded, err = c.DecodeCalldataHex(hexutil.Encode(data))
+	require.NoError(t, err, "failed to decode hex calldata")
+	require.Equal(t, "onTokenTransfer(address,uint256,bytes)", decoded.Method, "incorrect method")
+	require.Contains(t, decoded.NestedCalls, "data", "nested calldata should be decoded")
+	require.Equal(t, "set(int256)", decoded.NestedCalls["data"].Method, "incorrect nested method")
+
+	_, err = c.DecodeCalldata([]byte{0xde, 0xad, 0xbe, 0xef})
+	require.ErrorIs(t, err, seth.ErrNoABIMethod, "unknown selector shouldn't be decoded")
+	_, err = c.DecodeCalldataHex("0xzz")
+	require.Error(t, err, "invalid hex shouldn't be decoded")
+}
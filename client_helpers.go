@@ -2,8 +2,9 @@ package seth
 
 import (
 	"crypto/ecdsa"
-	"errors"
+
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
 )
 
 // MustGetRootKeyAddress returns the root key address from the client configuration. If no addresses are found, it panics.
@@ -39,5 +40,8 @@ func (m *Client) GetRootPrivateKey() (*ecdsa.PrivateKey, error) {
 	if len(m.PrivateKeys) == 0 {
 		return nil, errors.New("no private keys found in the client configuration")
 	}
+	if m.PrivateKeys[0] == nil {
+		return nil, errors.Wrapf(ErrNoPrivateKey, "key: %d", 0)
+	}
 	return m.PrivateKeys[0], nil
 }
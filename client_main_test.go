@@ -1,9 +1,14 @@
 package seth_test
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"math/big"
+	"net/http"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -64,6 +69,16 @@ func newClientWithEphemeralAddresses(t *testing.T) *seth.Client {
 	return c
 }
 
+// limitEphemeralFunds makes ephemeral keys share only given amount of root key's balance (including fees of funding
+// transfers), so that tests don't depend on how much funds previous tests left to the root key
+func limitEphemeralFunds(t *testing.T, cfg *seth.Config, funds *big.Int) {
+	balance, err := TestEnv.Client.Client.BalanceAt(context.Background(), TestEnv.Client.Addresses[0], nil)
+	require.NoError(t, err, "failed to get root key balance")
+	require.True(t, balance.Cmp(funds) > 0, "root key should have more than %s", seth.FormatWei(funds))
+	cfg.RootKeyFundsBuffer = nil
+	cfg.RootKeyFundsBufferAmount = &seth.EtherAmount{Amount: seth.NewAmount(new(big.Int).Sub(balance, funds))}
+}
+
 func newClientWithKeyfile(t *testing.T, keyFilePath string) *seth.Client {
 	cfg, err := seth.ReadConfig()
 	require.NoError(t, err, "failed to read config")
@@ -77,6 +92,30 @@ func newClientWithKeyfile(t *testing.T, keyFilePath string) *seth.Client {
 	return c
 }
 
+// nodeHTTPURL returns HTTP RPC URL of the node, which client is connected to over websocket, as Geth serves HTTP on the port
+// just below websocket one. Test is skipped, if client doesn't use websocket or HTTP endpoint isn't available.
+func nodeHTTPURL(t *testing.T, c *seth.Client) string {
+	if !strings.HasPrefix(c.URL, "ws://") {
+		t.Skip("test requires a websocket RPC URL")
+	}
+	httpURL := strings.Replace(strings.Replace(c.URL, "ws://", "http://", 1), ":8546", ":8545", 1)
+	resp, err := http.Post(httpURL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`))
+	if err != nil {
+		t.Skipf("HTTP RPC endpoint %s is not available", httpURL)
+	}
+	_ = resp.Body.Close()
+	return httpURL
+}
+
+// forwardRPCRequest sends JSON-RPC request to the node and copies its response, it's used by proxies, which alter only some requests
+func forwardRPCRequest(t *testing.T, w http.ResponseWriter, target string, body []byte) {
+	resp, err := http.Post(target, "application/json", bytes.NewReader(body))
+	require.NoError(t, err, "failed to forward request")
+	defer resp.Body.Close()
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = io.Copy(w, resp.Body)
+}
+
 func TestDeploymentLinkTokenFromGethWrapperExample(t *testing.T) {
 	c, err := seth.NewClient()
 	require.NoError(t, err, "failed to initalise seth")
@@ -161,7 +200,7 @@ func NewDebugContractSetup() (
 
 	nm, err := seth.NewNonceManager(cfg, addrs, pkeys)
 	if err != nil {
-		return nil, nil, common.Address{}, common.Address{}, nil, errors.Wrap(err, seth.ErrCreateNonceManager)
+		return nil, nil, common.Address{}, common.Address{}, nil, fmt.Errorf("%w: %w", seth.ErrCreateNonceManager, err)
 	}
 
 	c, err := seth.NewClientRaw(cfg, addrs, pkeys, seth.WithContractStore(cs), seth.WithTracer(tracer), seth.WithNonceManager(nm))
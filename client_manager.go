@@ -0,0 +1,128 @@
+package seth
+
+import (
+	verr "errors"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNoNetworks     = errors.New("no networks are configured, add [[networks]] to the config")
+	ErrUnknownNetwork = errors.New("network isn't configured")
+)
+
+// ClientManager creates clients of networks from the same config, so that cross-chain tests don't need multiple configs.
+// Client of each network is created on the first use and cached. All clients share Contract Store and keyfile settings,
+// networks without their own keys use keys of the selected network (cfg.Network), so the same addresses are used on all
+// chains. Contract maps, nonces and tracers are separate for each network.
+type ClientManager struct {
+	cfg           *Config
+	opts          []ClientOpt
+	contractStore *ContractStore
+	mu            sync.Mutex
+	clients       map[string]*Client
+}
+
+// NewClientManager creates manager of clients of all networks from the config, options are applied to every client
+func NewClientManager(cfg *Config, opts ...ClientOpt) (*ClientManager, error) {
+	if len(cfg.Networks) == 0 && cfg.Network == nil {
+		return nil, ErrNoNetworks
+	}
+	cs, err := newContractStore(cfg)
+	if err != nil {
+		return nil, wrapError(err, ErrCreateABIStore)
+	}
+	return &ClientManager{
+		cfg:           cfg,
+		opts:          opts,
+		contractStore: cs,
+		clients:       make(map[string]*Client),
+	}, nil
+}
+
+// Networks returns names of networks, which clients can be created for
+func (m *ClientManager) Networks() []string {
+	names := make([]string, 0, len(m.cfg.Networks)+1)
+	seen := make(map[string]struct{})
+	for _, n := range append([]*Network{m.cfg.Network}, m.cfg.Networks...) {
+		if n == nil {
+			continue
+		}
+		if _, ok := seen[n.Name]; ok {
+			continue
+		}
+		seen[n.Name] = struct{}{}
+		names = append(names, n.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Client returns client of the network with given name, it's created on the first call. Clients are created one at a time.
+func (m *ClientManager) Client(networkName string) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.clients[networkName]; ok {
+		return c, nil
+	}
+
+	network := m.network(networkName)
+	if network == nil {
+		return nil, errors.Wrapf(ErrUnknownNetwork, "network: '%s', available networks: %v", networkName, m.Networks())
+	}
+	c, err := NewClientWithConfig(m.networkConfig(network), m.opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create client of network '%s'", networkName)
+	}
+	m.clients[networkName] = c
+	return c, nil
+}
+
+// Close closes all created clients
+func (m *ClientManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var errs []error
+	for name, c := range m.clients {
+		if err := c.Close(); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to close client of network '%s'", name))
+		}
+	}
+	return verr.Join(errs...)
+}
+
+// network returns network with given name, the selected network takes precedence, because env var overrides and
+// root private key were applied to it
+func (m *ClientManager) network(name string) *Network {
+	if m.cfg.Network != nil && m.cfg.Network.Name == name {
+		return m.cfg.Network
+	}
+	for _, n := range m.cfg.Networks {
+		if n != nil && n.Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// networkConfig returns copy of the config with given network selected, client creation modifies both the config and
+// the network, so they are copied
+func (m *ClientManager) networkConfig(network *Network) *Config {
+	cfg := *m.cfg
+	n := *network
+	if len(n.PrivateKeys) == 0 && n.RemoteSigner == nil && len(n.KMSKeys) == 0 && m.cfg.Network != nil {
+		n.PrivateKeys = m.cfg.Network.PrivateKeys
+		n.RemoteSigner = m.cfg.Network.RemoteSigner
+		n.KMSKeys = m.cfg.Network.KMSKeys
+	}
+	n.PrivateKeys = append([]string(nil), n.PrivateKeys...)
+	if m.cfg.EphemeralAddrs != nil {
+		ephemeralAddrs := *m.cfg.EphemeralAddrs
+		cfg.EphemeralAddrs = &ephemeralAddrs
+	}
+	cfg.Network = &n
+	cfg.contractStore = m.contractStore
+	return &cfg
+}
@@ -0,0 +1,57 @@
+package seth_test
+
+import (
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIClientManager(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	mgr, err := seth.NewClientManager(cfg)
+	require.NoError(t, err, "failed to create client manager")
+	defer func() {
+		require.NoError(t, mgr.Close(), "failed to close clients")
+	}()
+	require.Contains(t, mgr.Networks(), cfg.Network.Name, "selected network should be available")
+
+	c, err := mgr.Client(cfg.Network.Name)
+	require.NoError(t, err, "failed to create client")
+	require.Equal(t, cfg.Network.Name, c.Cfg.Network.Name, "client of requested network should be returned")
+
+	cached, err := mgr.Client(cfg.Network.Name)
+	require.NoError(t, err, "failed to get client")
+	require.Same(t, c, cached, "client should be cached")
+
+	_, err = mgr.Client("NoSuchNetwork")
+	require.Error(t, err, "unknown network should be rejected")
+}
+
+func TestAPIClientManagerSharedContractStore(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	// the same node under another name, which has no keys of its own
+	other := *cfg.Network
+	other.Name = "Other"
+	other.PrivateKeys = nil
+	cfg.Networks = append(cfg.Networks, &other)
+
+	mgr, err := seth.NewClientManager(cfg)
+	require.NoError(t, err, "failed to create client manager")
+	defer func() {
+		require.NoError(t, mgr.Close(), "failed to close clients")
+	}()
+
+	c1, err := mgr.Client(cfg.Network.Name)
+	require.NoError(t, err, "failed to create client")
+	c2, err := mgr.Client("Other")
+	require.NoError(t, err, "failed to create client")
+
+	require.Same(t, c1.ContractStore, c2.ContractStore, "contract store should be shared")
+	require.Equal(t, c1.Addresses[0], c2.Addresses[0], "keys of the selected network should be used")
+	require.NotSame(t, c1.NonceManager, c2.NonceManager, "nonce managers should be separate")
+}
@@ -1,9 +1,12 @@
 package seth_test
 
 import (
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/common"
 	link_token "github.com/smartcontractkit/seth/contracts/bind/link"
 	"testing"
+	"time"
 
 	"github.com/smartcontractkit/seth"
 	"github.com/stretchr/testify/require"
@@ -39,7 +42,7 @@ func TestRPCHealtCheckEnabled_Node_Unhealthy(t *testing.T) {
 
 	_, err = seth.NewClientWithConfig(cfg)
 	require.Error(t, err, "expected error when connecting to unhealthy node")
-	require.Contains(t, err.Error(), seth.ErrRpcHealthCheckFailed, "expected error message when connecting to dead node")
+	require.ErrorIs(t, err, seth.ErrRpcHealthCheckFailed, "expected error message when connecting to dead node")
 }
 
 func TestRPCHealtCheckDisabled_Node_Unhealthy(t *testing.T) {
@@ -56,6 +59,146 @@ func TestRPCHealtCheckDisabled_Node_Unhealthy(t *testing.T) {
 	require.NoError(t, err, "expected health check to be skipped")
 }
 
+func TestRPCHealtCheckReadOnly_Node_OK(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	newPks, err := seth.NewEphemeralKeys(1)
+	require.NoError(t, err, "failed to create ephemeral keys")
+
+	cfg.CheckRpcHealthOnStart = true
+	// key without funds can't pass transactional health check, but it's fine for read-only one
+	cfg.Network.PrivateKeys = []string{newPks[0]}
+	cfg.RPCHealthCheck = &seth.RPCHealthCheckCfg{
+		Mode:                    seth.RPCHealthCheckMode_ReadOnly,
+		BlockProgressionTimeout: &seth.Duration{D: 30 * time.Second},
+		CallTo:                  TestEnv.LinkTokenContract.Address().Hex(),
+		CallData:                "0x8da5cb5b", // owner()
+		Timeout:                 &seth.Duration{D: 10 * time.Second},
+		ChainID:                 1337,
+		MaxBlockAge:             &seth.Duration{D: time.Hour},
+		MaxGasPrice:             seth.MustParseAmount("1000 gwei"),
+	}
+
+	_, err = seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "read-only health check should pass")
+}
+
+func TestRPCHealtCheckReadOnly_Call_Fails(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	cfg.CheckRpcHealthOnStart = true
+	cfg.RPCHealthCheck = &seth.RPCHealthCheckCfg{
+		Mode:     seth.RPCHealthCheckMode_ReadOnly,
+		CallTo:   TestEnv.LinkTokenContract.Address().Hex(),
+		CallData: "0xdeadbeef",
+	}
+
+	_, err = seth.NewClientWithConfig(cfg)
+	require.Error(t, err, "expected error when health check call reverts")
+	require.ErrorIs(t, err, seth.ErrRpcHealthCheckFailed, "expected health check error")
+}
+
+func TestRPCHealtCheckReadOnly_Checks_Fail(t *testing.T) {
+	testCases := []struct {
+		name        string
+		hcCfg       *seth.RPCHealthCheckCfg
+		reason      error
+		expectedErr string
+	}{
+		{
+			name:        "unexpected chain ID",
+			hcCfg:       &seth.RPCHealthCheckCfg{ChainID: 1},
+			reason:      seth.ErrRpcHealthCheckChainID,
+			expectedErr: "expected: 1",
+		},
+		{
+			name:        "stale latest block",
+			hcCfg:       &seth.RPCHealthCheckCfg{MaxBlockAge: &seth.Duration{D: time.Nanosecond}},
+			reason:      seth.ErrRpcHealthCheckStaleBlock,
+			expectedErr: "max age: 1ns",
+		},
+		{
+			name:        "too high gas price",
+			hcCfg:       &seth.RPCHealthCheckCfg{MaxGasPrice: seth.MustParseAmount("1 wei")},
+			reason:      seth.ErrRpcHealthCheckGasPrice,
+			expectedErr: "max gas price: 1 wei",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := seth.ReadConfig()
+			require.NoError(t, err, "failed to read config")
+
+			cfg.CheckRpcHealthOnStart = true
+			tc.hcCfg.Mode = seth.RPCHealthCheckMode_ReadOnly
+			tc.hcCfg.Timeout = &seth.Duration{D: 5 * time.Second}
+			cfg.RPCHealthCheck = tc.hcCfg
+
+			_, err = seth.NewClientWithConfig(cfg)
+			require.ErrorIs(t, err, seth.ErrRpcHealthCheckFailed, "expected health check error")
+			require.ErrorIs(t, err, tc.reason, "incorrect reason of failed health check")
+			require.ErrorContains(t, err, tc.expectedErr, "error should contain details of failed health check")
+		})
+	}
+}
+
+func TestRPCHealtCheckInvalidMode(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	cfg.RPCHealthCheck = &seth.RPCHealthCheckCfg{Mode: "free"}
+
+	err = seth.ValidateConfig(cfg)
+	require.Error(t, err, "expected error for invalid mode")
+	require.Contains(t, err.Error(), "RPC health check mode must be either", "expected invalid mode error")
+}
+
+func TestConfigChainID(t *testing.T) {
+	t.Run("resolved from node", func(t *testing.T) {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Network.ChainID = ""
+
+		c, err := seth.NewClientWithConfig(cfg)
+		require.NoError(t, err, "failed to initalise seth")
+		require.NotZero(t, c.ChainID, "chain ID should be fetched from the node")
+		require.Equal(t, fmt.Sprint(c.ChainID), cfg.Network.ChainID, "chain ID should be stored in the network config")
+	})
+
+	t.Run("matching", func(t *testing.T) {
+		c := newClient(t)
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Network.ChainID = fmt.Sprint(c.ChainID)
+
+		_, err = seth.NewClientWithConfig(cfg)
+		require.NoError(t, err, "configured chain ID matches the node")
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Network.ChainID = "999999999"
+
+		_, err = seth.NewClientWithConfig(cfg)
+		require.ErrorIs(t, err, seth.ErrChainIDMismatch, "expected error when node returns different chain ID")
+		require.Contains(t, err.Error(), "configured chain ID: 999999999", "error should contain configured chain ID")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Network.ChainID = "mainnet"
+
+		err = seth.ValidateConfig(cfg)
+		require.Error(t, err, "expected error for invalid chain ID")
+		require.Contains(t, err.Error(), "'chain_id' must be a positive integer", "expected invalid chain ID error")
+	})
+}
+
 func TestContractLoader(t *testing.T) {
 	c, err := seth.NewClient()
 	require.NoError(t, err, "failed to initalise seth")
@@ -1,18 +1,24 @@
 package seth_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/smartcontractkit/seth/contracts/bind/link_token_interface"
 	"io"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/barkimedes/go-deepcopy"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/smartcontractkit/seth"
@@ -1561,6 +1567,7 @@ func TestTraceContractTracingSaveToJson(t *testing.T) {
 	t.Cleanup(func() {
 		_ = os.Remove(fileName)
 	})
+	require.NoError(t, c.FlushTraces(context.Background()), "failed to flush traces")
 
 	expectedCall := &seth.DecodedCall{
 		FromAddress: strings.ToLower(c.Addresses[0].Hex()),
@@ -1592,6 +1599,141 @@ func TestTraceContractTracingSaveToJson(t *testing.T) {
 	require.EqualValues(t, expectedCall, &readCall[0], "decoded call does not match one read from file")
 }
 
+func TestTraceInternalTransfers(t *testing.T) {
+	// call trace of a transaction that forwards value to an EOA, creates a contract with value, tries to send
+	// value in a call that reverts and finally selfdestructs the created contract
+	rawTrace := `{
+		"from": "0x1111111111111111111111111111111111111111",
+		"to": "0x2222222222222222222222222222222222222222",
+		"type": "CALL",
+		"value": "0x64",
+		"calls": [
+			{"from": "0x2222222222222222222222222222222222222222", "to": "0x3333333333333333333333333333333333333333", "type": "CALL", "value": "0xa"},
+			{"from": "0x2222222222222222222222222222222222222222", "to": "0x4444444444444444444444444444444444444444", "type": "DELEGATECALL", "value": "0x64"},
+			{"from": "0x2222222222222222222222222222222222222222", "to": "0x5555555555555555555555555555555555555555", "type": "CREATE", "value": "0x14",
+				"calls": [
+					{"from": "0x5555555555555555555555555555555555555555", "to": "0x3333333333333333333333333333333333333333", "type": "CALL", "value": "0x5"}
+				]
+			},
+			{"from": "0x2222222222222222222222222222222222222222", "to": "0x6666666666666666666666666666666666666666", "type": "CALL", "value": "0x1", "error": "execution reverted"},
+			{"from": "0x5555555555555555555555555555555555555555", "to": "0x1111111111111111111111111111111111111111", "type": "SELFDESTRUCT", "value": "0xf"},
+			{"from": "0x2222222222222222222222222222222222222222", "to": "0x3333333333333333333333333333333333333333", "type": "STATICCALL"}
+		]
+	}`
+
+	var callTrace seth.TXCallTraceOutput
+	err := json.Unmarshal([]byte(rawTrace), &callTrace)
+	require.NoError(t, err, "failed to unmarshal call trace")
+
+	transfers, err := callTrace.InternalTransfers()
+	require.NoError(t, err, "failed to get internal transfers")
+
+	expected := []seth.InternalTransfer{
+		{Type: "CALL", FromAddress: "0x2222222222222222222222222222222222222222", ToAddress: "0x3333333333333333333333333333333333333333", Amount: big.NewInt(10), Depth: 1},
+		{Type: "CREATE", FromAddress: "0x2222222222222222222222222222222222222222", ToAddress: "0x5555555555555555555555555555555555555555", Amount: big.NewInt(20), Depth: 1},
+		{Type: "CALL", FromAddress: "0x5555555555555555555555555555555555555555", ToAddress: "0x3333333333333333333333333333333333333333", Amount: big.NewInt(5), Depth: 2},
+		{Type: "SELFDESTRUCT", FromAddress: "0x5555555555555555555555555555555555555555", ToAddress: "0x1111111111111111111111111111111111111111", Amount: big.NewInt(15), Depth: 1},
+	}
+	require.Equal(t, expected, transfers, "internal transfers do not match")
+
+	balanceChanges, err := callTrace.BalanceChanges()
+	require.NoError(t, err, "failed to get balance changes")
+	expectedChanges := []seth.BalanceChange{
+		{Address: "0x1111111111111111111111111111111111111111", Sent: big.NewInt(100), Received: big.NewInt(15), Change: big.NewInt(-85)},
+		{Address: "0x2222222222222222222222222222222222222222", Sent: big.NewInt(30), Received: big.NewInt(100), Change: big.NewInt(70)},
+		{Address: "0x3333333333333333333333333333333333333333", Sent: big.NewInt(0), Received: big.NewInt(15), Change: big.NewInt(15)},
+		{Address: "0x5555555555555555555555555555555555555555", Sent: big.NewInt(20), Received: big.NewInt(20), Change: big.NewInt(0)},
+	}
+	require.Len(t, balanceChanges, len(expectedChanges), "balance changes do not match")
+	for i, expectedChange := range expectedChanges {
+		require.Equal(t, expectedChange.Address, balanceChanges[i].Address, "address does not match")
+		require.Equal(t, expectedChange.Sent.String(), balanceChanges[i].Sent.String(), "sent value does not match")
+		require.Equal(t, expectedChange.Received.String(), balanceChanges[i].Received.String(), "received value does not match")
+		require.Equal(t, expectedChange.Change.String(), balanceChanges[i].Change.String(), "balance change does not match")
+	}
+
+	callTrace.Error = "execution reverted"
+	transfers, err = callTrace.InternalTransfers()
+	require.NoError(t, err, "failed to get internal transfers")
+	require.Empty(t, transfers, "reverted transaction should have no internal transfers")
+	balanceChanges, err = callTrace.BalanceChanges()
+	require.NoError(t, err, "failed to get balance changes")
+	require.Empty(t, balanceChanges, "reverted transaction should have no balance changes")
+}
+
+func TestTraceRevertChain(t *testing.T) {
+	// 0x11 calls 0x22, which calls 0x33, that reverts; 0x22 bubbles the revert up and 0x11 reverts with its own error,
+	// earlier 0x11 called 0x44, which reverted, but 0x11 caught it and continued
+	rawTrace := `{
+		"from": "0x0000000000000000000000000000000000000001",
+		"to": "0x1111111111111111111111111111111111111111",
+		"type": "CALL",
+		"input": "0xaaaaaaaa",
+		"error": "execution reverted",
+		"output": "0x02",
+		"calls": [
+			{"from": "0x1111111111111111111111111111111111111111", "to": "0x4444444444444444444444444444444444444444", "type": "CALL", "input": "0xdddddddd", "error": "execution reverted", "output": "0x04"},
+			{"from": "0x1111111111111111111111111111111111111111", "to": "0x2222222222222222222222222222222222222222", "type": "CALL", "input": "0xbbbbbbbb", "error": "execution reverted", "output": "0x01",
+				"calls": [
+					{"from": "0x2222222222222222222222222222222222222222", "to": "0x3333333333333333333333333333333333333333", "type": "STATICCALL", "input": "0xcccccccc", "error": "execution reverted", "output": "0x01", "revertReason": "boom"}
+				]
+			}
+		]
+	}`
+
+	var callTrace seth.TXCallTraceOutput
+	err := json.Unmarshal([]byte(rawTrace), &callTrace)
+	require.NoError(t, err, "failed to unmarshal call trace")
+
+	chain := callTrace.RevertChain()
+	require.NotNil(t, chain, "revert chain should be found")
+	require.Len(t, chain.Propagation, 3, "expected 3 frames in propagation chain")
+
+	origin := chain.Propagation[0]
+	require.Equal(t, *chain.Origin, origin, "origin should be the first frame in propagation chain")
+	require.Equal(t, seth.RevertFrameOrigin, origin.Kind, "incorrect kind of origin frame")
+	require.Equal(t, "0x3333333333333333333333333333333333333333", origin.ToAddress, "incorrect origin")
+	require.Equal(t, "cccccccc", origin.Method, "incorrect origin method")
+	require.Equal(t, "STATICCALL", origin.Type, "incorrect origin call type")
+	require.Equal(t, 2, origin.Depth, "incorrect origin depth")
+	require.Equal(t, "boom", origin.Reason, "incorrect origin reason")
+
+	require.Equal(t, seth.RevertFramePropagated, chain.Propagation[1].Kind, "revert should be propagated by 0x22")
+	require.Equal(t, "0x2222222222222222222222222222222222222222", chain.Propagation[1].ToAddress, "incorrect propagating frame")
+	require.Equal(t, seth.RevertFrameRewrapped, chain.Propagation[2].Kind, "revert should be rewrapped by 0x11")
+	require.Equal(t, 0, chain.Propagation[2].Depth, "top-level call should have depth 0")
+
+	require.Len(t, chain.Swallowed, 1, "expected 1 swallowed revert")
+	require.Equal(t, seth.RevertFrameSwallowed, chain.Swallowed[0].Kind, "incorrect kind of swallowed frame")
+	require.Equal(t, "0x4444444444444444444444444444444444444444", chain.Swallowed[0].ToAddress, "incorrect swallowed call")
+	require.Equal(t, "0x1111111111111111111111111111111111111111", chain.Swallowed[0].FromAddress, "incorrect swallowing caller")
+
+	// no reverts at all
+	callTrace = seth.TXCallTraceOutput{Call: seth.Call{Input: "0xaaaaaaaa"}}
+	require.Nil(t, callTrace.RevertChain(), "there should be no revert chain")
+}
+
+func TestTraceRevertChainInSubContract(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	SkipAnvil(t, c)
+
+	c.Cfg.TracingLevel = seth.TracingLevel_Reverted
+
+	tx, txErr := TestEnv.DebugContract.CallRevertFunctionInSubContract(c.NewTXOpts(), big.NewInt(1001), big.NewInt(2))
+	require.NoError(t, txErr, "transaction should have been sent")
+	_, decodeErr := c.Decode(tx, txErr)
+	require.Error(t, decodeErr, "transaction should have reverted")
+
+	chain, ok := c.Tracer.RevertChains[tx.Hash().Hex()]
+	require.True(t, ok, "revert chain should be decoded")
+	require.Len(t, chain.Propagation, 2, "revert should originate in sub contract and propagate to main contract")
+	require.Equal(t, "NetworkDebugSubContract", chain.Origin.To, "revert should originate in sub contract")
+	require.Equal(t, "error type: CustomErr, error values: [1001 2]", chain.Origin.Reason, "incorrect revert reason")
+	require.Equal(t, seth.RevertFramePropagated, chain.Propagation[1].Kind, "main contract should propagate the revert")
+	require.Equal(t, "NetworkDebugContract", chain.Propagation[1].To, "main contract should be the last frame")
+	require.Empty(t, chain.Swallowed, "no revert should be swallowed")
+}
+
 func removeGasDataFromDecodedCalls(decodedCall map[string][]*seth.DecodedCall) {
 	for _, decodedCalls := range decodedCall {
 		for _, call := range decodedCalls {
@@ -1600,3 +1742,414 @@ func removeGasDataFromDecodedCalls(decodedCall map[string][]*seth.DecodedCall) {
 		}
 	}
 }
+
+func TestTraceTracingLevelOverrides(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	SkipAnvil(t, c)
+
+	c.Cfg.TracingLevel = seth.TracingLevel_None
+	c.Cfg.TracingLevelOverrides = map[string]string{"NetworkDebugContract": "all"}
+	require.NoError(t, seth.ValidateConfig(c.Cfg), "config should be valid")
+
+	tx, err := c.Decode(TestEnv.DebugContract.Trace(c.NewTXOpts(), big.NewInt(2), big.NewInt(4)))
+	require.NoError(t, err, FailedToDecode)
+	require.Contains(t, c.Tracer.DecodedCalls, tx.Hash, "transaction to contract with overridden level should be traced")
+
+	tx, err = c.Decode(TestEnv.LinkTokenContract.Approve(c.NewTXOpts(), c.Addresses[0], big.NewInt(1)))
+	require.NoError(t, err, FailedToDecode)
+	require.NotContains(t, c.Tracer.DecodedCalls, tx.Hash, "transaction to other contract should not be traced")
+
+	// address override takes precedence over the name one
+	c.Cfg.TracingLevelOverrides[TestEnv.DebugContractAddress.Hex()] = seth.TracingLevel_None
+	require.NoError(t, seth.ValidateConfig(c.Cfg), "config should be valid")
+	require.Equal(t, seth.TracingLevel_None, c.Cfg.TracingLevelFor(TestEnv.DebugContractAddress.Hex(), "NetworkDebugContract"), "address override should take precedence")
+	tx, err = c.Decode(TestEnv.DebugContract.Trace(c.NewTXOpts(), big.NewInt(2), big.NewInt(4)))
+	require.NoError(t, err, FailedToDecode)
+	require.NotContains(t, c.Tracer.DecodedCalls, tx.Hash, "transaction to contract with overridden address should not be traced")
+
+	c.Cfg.TracingLevelOverrides["NetworkDebugContract"] = "verbose"
+	require.ErrorIs(t, seth.ValidateConfig(c.Cfg), seth.ErrTracingLevelOverride, "override level should be validated")
+}
+
+func TestTraceContractTracingUnknownAbiWithSignatureDatabase(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	SkipAnvil(t, c)
+
+	c.Cfg.TracingLevel = seth.TracingLevel_All
+
+	// simulate missing ABI
+	delete(c.ContractAddressToNameMap.GetContractMap(), strings.ToLower(TestEnv.DebugContractAddress.Hex()))
+	delete(c.ContractStore.ABIs, "NetworkDebugContract.abi")
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		require.Equal(t, "0x30985bcc", r.URL.Query().Get("hex_signature"), "incorrect selector")
+		_, _ = fmt.Fprint(w, `{"results": [{"id": 2, "text_signature": "traceDifferent(int256,int256)"}, {"id": 1, "text_signature": "notMatching(uint256)"}]}`)
+	}))
+	t.Cleanup(server.Close)
+	cacheFile := filepath.Join(t.TempDir(), "signatures.json")
+	c.Tracer.SignatureDatabase = seth.NewSignatureDatabase(&seth.SignatureDatabaseCfg{URL: server.URL, CacheFile: cacheFile})
+
+	tx, txErr := c.Decode(TestEnv.DebugContract.TraceDifferent(c.NewTXOpts(), big.NewInt(2), big.NewInt(4)))
+	require.NoError(t, txErr, FailedToDecode)
+
+	call := c.Tracer.DecodedCalls[tx.Hash][0]
+	require.Equal(t, "traceDifferent(int256,int256)", call.Method, "method should be decoded with signature from database")
+	require.Equal(t, map[string]interface{}{"arg0": big.NewInt(2), "arg1": big.NewInt(4)}, call.Input, "inputs should be decoded")
+	require.Equal(t, seth.CommentSignatureFromDatabase+"; other candidates: notMatching(uint256)", call.Comment, "incorrect comment")
+
+	// signatures are cached and can be used offline
+	offline := seth.NewSignatureDatabase(&seth.SignatureDatabaseCfg{URL: server.URL, CacheFile: cacheFile, Offline: true})
+	signatures, err := offline.Lookup(context.Background(), common.FromHex("0x30985bcc"))
+	require.NoError(t, err, "failed to look up signature")
+	require.Equal(t, []string{"notMatching(uint256)", "traceDifferent(int256,int256)"}, signatures, "signatures should be read from cache ordered by id")
+	signatures, err = offline.Lookup(context.Background(), common.FromHex("0xdeadbeef"))
+	require.NoError(t, err, "offline lookup should not fail")
+	require.Empty(t, signatures, "unknown selector should not be queried offline")
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests), "database should be queried once")
+}
+
+// deployEIP1967Proxy deploys minimal proxy, which keeps implementation's address in EIP-1967 slot and delegates all calls to it
+func deployEIP1967Proxy(t *testing.T, c *seth.Client, name string, implementation common.Address) common.Address {
+	slot := strings.TrimPrefix(seth.EIP1967ImplementationSlot.Hex(), "0x")
+	runtime := "366000600037600060003660007f" + slot + "545af43d600060003e603e573d6000fd5b3d6000f3"
+	constructor := "73" + common.Bytes2Hex(implementation.Bytes()) + "7f" + slot + "55" + "6043806042600039" + "6000f3"
+	data, err := c.DeployContract(c.NewTXOpts(), name, abi.ABI{}, common.FromHex(constructor+runtime))
+	require.NoError(t, err, "failed to deploy proxy")
+	return data.Address
+}
+
+func TestTraceProxyCallDecodedWithImplementationABI(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	SkipAnvil(t, c)
+
+	c.Cfg.TracingLevel = seth.TracingLevel_All
+
+	proxyAddress := deployEIP1967Proxy(t, c, "DebugContractProxy", TestEnv.DebugContractAddress)
+	proxied, err := network_debug_contract.NewNetworkDebugContract(proxyAddress, c.Client)
+	require.NoError(t, err, "failed to bind proxy")
+
+	tx, txErr := c.Decode(proxied.SetStatus(c.NewTXOpts(), 1))
+	require.NoError(t, txErr, FailedToDecode)
+	require.Equal(t, "setStatus(uint8)", tx.Method, "transaction should be decoded with implementation's ABI")
+
+	calls := c.Tracer.DecodedCalls[tx.Hash]
+	require.GreaterOrEqual(t, len(calls), 2, "proxy call and delegatecall should be traced")
+	for _, call := range calls[:2] {
+		require.Equal(t, "setStatus(uint8)", call.Method, "call should be decoded with implementation's ABI")
+		require.Equal(t, map[string]interface{}{"status": uint8(1)}, call.Input, "incorrect input")
+		require.Equal(t, "DebugContractProxy", call.To, "call should be attributed to the proxy")
+		require.Equal(t, "NetworkDebugContract", call.Implementation, "implementation should be set")
+	}
+	require.Equal(t, strings.ToLower(TestEnv.DebugContractAddress.Hex()), calls[1].ToAddress, "delegatecall should keep implementation's address")
+	require.NotEmpty(t, calls[1].Events, "events emitted in proxy's context should be decoded")
+	require.NotEmpty(t, tx.Events, "events emitted in proxy's context should be decoded")
+	require.Equal(t, "DebugContractProxy", c.ContractAddressToNameMap.GetContractName(proxyAddress.Hex()), "proxy shouldn't be mapped to implementation")
+}
+
+func TestTraceTraceOpts(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	SkipAnvil(t, c)
+
+	tx, err := c.Decode(TestEnv.DebugContract.Trace(c.NewTXOpts(), big.NewInt(2), big.NewInt(4)))
+	require.NoError(t, err, FailedToDecode)
+
+	t.Run("defaults", func(t *testing.T) {
+		require.NoError(t, c.Tracer.TraceGethTX(tx.Hash), FailedToTrace)
+		trace, ok := c.Tracer.GetTrace(tx.Hash)
+		require.True(t, ok, "trace should be stored")
+		require.NotEmpty(t, trace.FourByte, "4byte tracer should be used by default")
+		require.NotEmpty(t, trace.OpCodesTrace, "opcodes tracer should be used by default")
+		require.Nil(t, trace.PrestateTrace, "prestate tracer shouldn't be used by default")
+		require.NotEmpty(t, trace.CallTrace.Calls, "sub-calls should be traced")
+		require.NotEmpty(t, trace.CallTrace.Logs, "logs should be traced")
+	})
+
+	t.Run("prestate and custom tracers", func(t *testing.T) {
+		opts := seth.TraceOpts{
+			Tracers:  []string{seth.TracerType_Prestate},
+			JSTracer: "{steps: 0, step: function() { this.steps++ }, fault: function() {}, result: function() { return this.steps }}",
+			Timeout:  seth.MustMakeDuration(10 * time.Second),
+		}
+		require.NoError(t, c.Tracer.TraceGethTX(tx.Hash, opts), FailedToTrace)
+		trace, ok := c.Tracer.GetTrace(tx.Hash)
+		require.True(t, ok, "trace should be stored")
+		require.Nil(t, trace.FourByte, "4byte tracer shouldn't be used")
+		require.Nil(t, trace.OpCodesTrace, "opcodes tracer shouldn't be used")
+		require.NotNil(t, trace.CallTrace, "call tracer should always be used")
+		require.Contains(t, trace.PrestateTrace, strings.ToLower(TestEnv.DebugContractAddress.Hex()), "called contract should be in pre-state")
+		require.Contains(t, trace.PrestateTrace, strings.ToLower(c.Addresses[0].Hex()), "sender should be in pre-state")
+		var steps int
+		require.NoError(t, json.Unmarshal(trace.CustomTrace, &steps), "failed to parse custom trace")
+		require.Greater(t, steps, 0, "custom tracer should count executed opcodes")
+		require.Len(t, c.Tracer.DecodedCalls[tx.Hash], 2, "trace should still be decoded")
+	})
+
+	t.Run("call tracer config", func(t *testing.T) {
+		withLog := false
+		c.Cfg.TraceOpts = &seth.TraceOpts{Tracers: []string{seth.TracerType_Call}, OnlyTopCall: true, WithLog: &withLog}
+		defer func() {
+			c.Cfg.TraceOpts = nil
+		}()
+		require.NoError(t, c.Tracer.TraceGethTX(tx.Hash), FailedToTrace)
+		trace, ok := c.Tracer.GetTrace(tx.Hash)
+		require.True(t, ok, "trace should be stored")
+		require.Empty(t, trace.CallTrace.Calls, "sub-calls shouldn't be traced")
+		require.Empty(t, trace.CallTrace.Logs, "logs shouldn't be traced")
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		cfg := deepcopy.MustAnything(c.Cfg).(*seth.Config)
+		cfg.TraceOpts = &seth.TraceOpts{Tracers: []string{"flatCallTracer"}}
+		require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrTracerType, "tracer should be validated")
+		cfg.TraceOpts = &seth.TraceOpts{Timeout: seth.MustMakeDuration(0)}
+		require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrTracerTimeout, "timeout should be validated")
+	})
+}
+
+func TestTraceStateDiff(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	SkipAnvil(t, c)
+
+	c.Cfg.TracingLevel = seth.TracingLevel_All
+	c.Cfg.TraceOpts = &seth.TraceOpts{Tracers: []string{seth.TracerType_Call}, StateDiff: true}
+
+	value := big.NewInt(time.Now().UnixNano())
+	tx, err := c.Decode(TestEnv.DebugContract.Set(c.NewTXOpts(), value))
+	require.NoError(t, err, FailedToDecode)
+	require.NotEmpty(t, c.Tracer.DecodedCalls[tx.Hash], "transaction should be traced")
+
+	stateDiff := c.Tracer.DecodedCalls[tx.Hash][0].StateDiff
+	diffs := make(map[string]seth.AccountStateDiff)
+	for _, d := range stateDiff {
+		diffs[d.Address] = d
+	}
+
+	sender, ok := diffs[strings.ToLower(c.Addresses[0].Hex())]
+	require.True(t, ok, "sender should be in state diff")
+	require.Equal(t, "you", sender.Name, "sender should be named")
+	require.Equal(t, tx.Transaction.Nonce()+1, sender.NonceAfter, "sender's nonce should be increased")
+	require.Equal(t, 1, sender.BalanceBefore.Cmp(sender.BalanceAfter), "sender should pay for gas")
+
+	contract, ok := diffs[strings.ToLower(TestEnv.DebugContractAddress.Hex())]
+	require.True(t, ok, "called contract should be in state diff")
+	require.Equal(t, "NetworkDebugContract", contract.Name, "contract should be named")
+	require.Nil(t, contract.BalanceBefore, "contract's balance shouldn't change")
+	require.NotEmpty(t, contract.Storage, "contract's storage should change")
+	stored := make([]string, 0, len(contract.Storage))
+	for _, s := range contract.Storage {
+		stored = append(stored, s.After)
+	}
+	require.Contains(t, stored, common.BigToHash(value).Hex(), "new value should be stored")
+}
+
+func TestTraceHTMLReport(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	SkipAnvil(t, c)
+
+	c.Cfg.TracingLevel = seth.TracingLevel_Reverted
+	c.Cfg.TraceToHTML = true
+
+	tx, txErr := TestEnv.DebugContract.CallRevertFunctionInSubContract(c.NewTXOpts(), big.NewInt(1001), big.NewInt(2))
+	require.NoError(t, txErr, "transaction should have been sent")
+	_, decodeErr := c.Decode(tx, txErr)
+	require.Error(t, decodeErr, "transaction should have reverted")
+
+	fileName := fmt.Sprintf("%s/%s.html", seth.TracesDir, tx.Hash().Hex())
+	t.Cleanup(func() {
+		_ = os.Remove(fileName)
+	})
+	report, err := os.ReadFile(fileName)
+	require.NoError(t, err, "expected HTML report to exist")
+	require.Contains(t, string(report), "NetworkDebugSubContract</b>.alwaysRevertsCustomError(uint256,uint256)", "sub-call should be in the report")
+	require.Contains(t, string(report), "error type: CustomErr, error values: [1001 2]", "revert reason should be in the report")
+
+	tree, err := c.Tracer.DecodeCallTree(tx.Hash().Hex())
+	require.NoError(t, err, "failed to decode call tree")
+	require.Equal(t, "NetworkDebugContract", tree.To, "main call should be the root")
+	require.Equal(t, "callRevertFunctionInSubContract(uint256,uint256)", tree.Method, "main call should be decoded")
+	require.NotEmpty(t, tree.Error, "main call should revert")
+	require.Len(t, tree.Calls, 1, "main call should have one sub-call")
+	require.Equal(t, 1, tree.Calls[0].Depth, "sub-call should be one level deeper")
+	require.Equal(t, "NetworkDebugSubContract", tree.Calls[0].To, "sub-call should go to sub contract")
+	require.Equal(t, map[string]interface{}{"x": big.NewInt(1001), "y": big.NewInt(2)}, tree.Calls[0].Input, "sub-call's inputs should be decoded")
+	require.NotZero(t, tree.Calls[0].GasUsed, "sub-call's gas should be set")
+
+	_, err = c.Tracer.SaveHTMLReport("0x1234", t.TempDir())
+	require.ErrorIs(t, err, seth.ErrNoTrace, "transaction without trace should be rejected")
+}
+
+func TestTraceCompareTraces(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	SkipAnvil(t, c)
+
+	c.Cfg.TracingLevel = seth.TracingLevel_All
+
+	decodeTree := func(tx *seth.DecodedTransaction) *seth.CallTreeFrame {
+		tree, err := c.Tracer.DecodeCallTree(tx.Hash)
+		require.NoError(t, err, "failed to decode call tree")
+		return tree
+	}
+
+	first, err := c.Decode(TestEnv.DebugContract.Trace(c.NewTXOpts(), big.NewInt(2), big.NewInt(4)))
+	require.NoError(t, err, FailedToDecode)
+	same, err := c.Decode(TestEnv.DebugContract.Trace(c.NewTXOpts(), big.NewInt(2), big.NewInt(4)))
+	require.NoError(t, err, FailedToDecode)
+	other, err := c.Decode(TestEnv.DebugContract.Trace(c.NewTXOpts(), big.NewInt(3), big.NewInt(5)))
+	require.NoError(t, err, FailedToDecode)
+
+	require.Empty(t, seth.CompareTraces(decodeTree(first), decodeTree(same)), "same calls should have no differences")
+
+	diffs := seth.CompareTraces(decodeTree(first), decodeTree(other))
+	kinds := make(map[string][]string)
+	for _, d := range diffs {
+		kinds[d.Kind] = append(kinds[d.Kind], d.Field)
+	}
+	require.Equal(t, []string{"x", "y", "x", "y"}, kinds[seth.TraceDiff_Argument], "arguments of main call and sub-call should differ")
+	require.Contains(t, kinds[seth.TraceDiff_Output], "0", "output should differ")
+	require.NotEmpty(t, kinds[seth.TraceDiff_Event], "events should differ")
+	require.NotContains(t, kinds, seth.TraceDiff_Target, "targets shouldn't differ")
+	require.NotContains(t, kinds, seth.TraceDiff_MissingCall, "calls should be aligned")
+	require.Contains(t, diffs[0].String(), "NetworkDebugContract.trace(int256,int256): argument 'x' differs: 2 != 3", "difference should be readable")
+
+	// sub-call missing in the second trace
+	treeA, treeB := decodeTree(first), decodeTree(same)
+	treeB.Calls = nil
+	diffs = seth.CompareTraces(treeA, treeB)
+	require.Len(t, diffs, 1, "only missing call should be reported")
+	require.Equal(t, seth.TraceDiff_MissingCall, diffs[0].Kind, "sub-call should be missing")
+	require.Equal(t, "NetworkDebugSubContract.trace(int256,int256)", diffs[0].A, "missing call should be named")
+}
+
+func TestTraceAssertions(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	SkipAnvil(t, c)
+
+	c.Cfg.TracingLevel = seth.TracingLevel_All
+	tx, err := c.Decode(TestEnv.DebugContract.Trace(c.NewTXOpts(), big.NewInt(2), big.NewInt(4)))
+	require.NoError(t, err, FailedToDecode)
+
+	trace := c.Tracer.AssertTrace(tx.Hash)
+	require.NoError(t, trace.ExpectCall("NetworkDebugContract", "trace").WithArg(0, big.NewInt(2)).WithArg(1, 4).Times(1))
+	require.NoError(t, trace.ExpectCall("NetworkDebugContract", "trace(int256,int256)").WithNamedArg("y", 4).WithEvent("TwoIndexEvent").Times(1))
+	require.NoError(t, trace.ExpectCall("NetworkDebugSubContract", "trace").AtLeast(1))
+	require.NoError(t, trace.ExpectCall(TestEnv.DebugContractAddress.Hex(), "trace").Times(1))
+	require.NoError(t, trace.ExpectCall("NetworkDebugContract", "set").Never())
+	require.Len(t, trace.ExpectCall("NetworkDebugSubContract", "trace").Calls(), 1, "sub-call should be returned")
+
+	err = trace.ExpectCall("NetworkDebugContract", "trace").WithArg(0, 3).Times(1)
+	require.ErrorContains(t, err, "expected NetworkDebugContract.trace with arg 0 = 3 to be called exactly 1 times", "mismatched argument should be reported")
+	require.ErrorContains(t, err, "called 0 times (1 times regardless of conditions)", "calls regardless of conditions should be counted")
+	require.Error(t, trace.ExpectCall("NetworkDebugSubContract", "trace").Never(), "made call should be reported")
+
+	require.ErrorIs(t, c.Tracer.AssertTrace("0x1234").ExpectCall("NetworkDebugContract", "trace").Times(1), seth.ErrNoTrace, "transaction without trace should be reported")
+}
+
+func TestTraceRetentionAndFlush(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	SkipAnvil(t, c)
+
+	c.Cfg.TracingLevel = seth.TracingLevel_All
+	c.Cfg.TraceRetention = 2
+
+	hashes := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		tx, err := c.Decode(TestEnv.DebugContract.Trace(c.NewTXOpts(), big.NewInt(int64(i)), big.NewInt(4)))
+		require.NoError(t, err, FailedToDecode)
+		hashes = append(hashes, tx.Hash)
+	}
+
+	_, ok := c.Tracer.GetDecodedCalls(hashes[0])
+	require.False(t, ok, "oldest transaction should be evicted")
+	_, ok = c.Tracer.GetTrace(hashes[0])
+	require.False(t, ok, "trace of oldest transaction should be evicted")
+	require.Len(t, c.Tracer.DecodedCalls, 2, "only retained transactions should be kept")
+
+	// tracing transactions concurrently doesn't race and keeps the limit
+	var wg sync.WaitGroup
+	for _, hash := range hashes {
+		wg.Add(1)
+		go func(hash string) {
+			defer wg.Done()
+			require.NoError(t, c.Tracer.TraceGethTX(hash), FailedToTrace)
+		}(hash)
+	}
+	wg.Wait()
+	require.Len(t, c.Tracer.DecodedCalls, 2, "only retained transactions should be kept")
+
+	dir := t.TempDir()
+	require.NoError(t, c.Tracer.FlushTo(dir), "failed to flush decoded calls")
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err, "failed to read flushed traces")
+	require.Len(t, files, 2, "retained transactions should be flushed")
+	require.Empty(t, c.Tracer.DecodedCalls, "flushed transactions should be removed from memory")
+
+	require.NoError(t, c.Tracer.TraceGethTX(hashes[0]), FailedToTrace)
+	_, ok = c.Tracer.GetDecodedCalls(hashes[0])
+	require.True(t, ok, "transaction should be traced again")
+	c.Tracer.Reset()
+	_, ok = c.Tracer.GetTrace(hashes[0])
+	require.False(t, ok, "reset should remove all traces")
+}
+
+func TestTraceOutputLayoutAndRetention(t *testing.T) {
+	dir := t.TempDir()
+	// a file left by a previous run, which is too old to be kept
+	oldFile := filepath.Join(dir, "old_run", "0x1234.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(oldFile), os.ModePerm))
+	require.NoError(t, os.WriteFile(oldFile, []byte("[]"), 0600))
+	oldTime := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(oldFile, oldTime, oldTime))
+
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.TracingLevel = seth.TracingLevel_All
+	cfg.TraceToJson = true
+	cfg.TraceToHTML = true
+	cfg.TraceOutput = &seth.TraceOutputCfg{
+		Dir:                      dir,
+		RunSubdir:                "{network}_run",
+		FileName:                 "{status}/{tx_hash}",
+		RevertedTransactionsFile: "{dir}/reverted.json",
+		MaxAge:                   &seth.Duration{D: time.Hour},
+	}
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	require.NoFileExists(t, oldFile, "file older than 'max_age' should be removed, when client is created")
+	require.NoDirExists(t, filepath.Dir(oldFile), "empty directory should be removed")
+
+	runDir := filepath.Join(dir, cfg.Network.Name+"_run")
+	require.Equal(t, runDir, c.Cfg.TraceOutputDir(), "run subdirectory should be resolved")
+	require.Equal(t, filepath.Join(runDir, "reverted.json"), c.Cfg.RevertedTransactionsFile, "reverted transactions file should be in run directory")
+
+	tx, err := c.Decode(TestEnv.DebugContract.Trace(c.NewTXOpts(), big.NewInt(1), big.NewInt(2)))
+	require.NoError(t, err, FailedToDecode)
+	revertedTx, txErr := TestEnv.DebugContract.AlwaysRevertsCustomError(c.NewTXOpts())
+	require.NoError(t, txErr, "transaction should have been sent")
+	_, err = c.Decode(revertedTx, txErr)
+	require.Error(t, err, "transaction should have reverted")
+	require.NoError(t, c.FlushTraces(context.Background()), "failed to flush traces")
+
+	require.FileExists(t, filepath.Join(runDir, "success", tx.Hash+".json"), "trace should be saved with file name template")
+	require.FileExists(t, filepath.Join(runDir, "success", tx.Hash+".html"), "HTML report should be saved with file name template")
+	require.FileExists(t, filepath.Join(runDir, "reverted", revertedTx.Hash().Hex()+".json"), "trace of reverted transaction should be saved with its status")
+
+	c.Cfg.TraceOutput.MaxFiles = 1
+	require.NoError(t, c.PruneTraces(), "failed to prune traces")
+	count := 0
+	require.NoError(t, filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			count++
+		}
+		return err
+	}))
+	require.Equal(t, 1, count, "only the newest file should be kept")
+	require.DirExists(t, runDir, "directory of the current run should be kept")
+
+	cfg.TraceOutput = &seth.TraceOutputCfg{FileName: "{hash}"}
+	_, err = seth.NewClientWithConfig(cfg)
+	require.ErrorIs(t, err, seth.ErrTraceOutputPlaceholder, "unknown placeholder should be rejected")
+	require.ErrorContains(t, err, "placeholder: '{hash}'", "error should contain the placeholder")
+}
@@ -5,19 +5,23 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/pelletier/go-toml/v2"
 	"github.com/pkg/errors"
 	"github.com/smartcontractkit/seth"
 	"github.com/urfave/cli/v2"
 )
 
-const (
-	ErrNoNetwork    = "no network specified, use -n flag. Ex.: 'seth -n Geth keys update' or -u and -c flags. Ex.: 'seth -u http://localhost:8545 -c 1337 keys update'"
-	ErrNo1PassVault = "1Password vault name or id is required, when running without '--local' flag. Set it as %s env var"
+const ()
+
+var (
+	ErrNoNetwork    = errors.New("no network specified, use -n flag. Ex.: 'seth -n Geth keys update' or -u and -c flags. Ex.: 'seth -u http://localhost:8545 -c 1337 keys update'")
+	ErrNo1PassVault = errors.New("1Password vault name or id is required, when running without '--local' flag")
 )
 
 var C *seth.Client
@@ -36,7 +40,11 @@ func RunCLI(args []string) error {
 			networkName := cCtx.String("networkName")
 			url := cCtx.String("url")
 			if networkName == "" && url == "" {
-				return errors.New(ErrNoNetwork)
+				// comparing traces saved to files doesn't need a network
+				if cCtx.Args().First() == "trace" && cCtx.Args().Get(1) == "diff" {
+					return nil
+				}
+				return ErrNoNetwork
 			}
 			if networkName != "" {
 				_ = os.Setenv(seth.NETWORK_ENV_VAR, networkName)
@@ -63,7 +71,7 @@ func RunCLI(args []string) error {
 					if err != nil {
 						return err
 					}
-				case "gas", "stats":
+				case "gas", "stats", "estimate", "watch":
 					var cfg *seth.Config
 					var pk string
 					_, pk, err = seth.NewAddress()
@@ -76,6 +84,16 @@ func RunCLI(args []string) error {
 						return err
 					}
 
+					cfg, err = seth.ReadConfig()
+					if err != nil {
+						return err
+					}
+					C, err = seth.NewClientWithConfig(cfg)
+					if err != nil {
+						return err
+					}
+				case "send", "run", "report", "replay", "deploy":
+					var cfg *seth.Config
 					cfg, err = seth.ReadConfig()
 					if err != nil {
 						return err
@@ -179,6 +197,321 @@ func RunCLI(args []string) error {
 					return err
 				},
 			},
+			{
+				Name:        "estimate",
+				HelpName:    "estimate",
+				Aliases:     []string{"e"},
+				Description: "estimate gas and cost of a contract method call without sending any transaction",
+				ArgsUsage:   "--contract ${contract name} --sig ${method signature} --args ${argument} [--address ${contract address}] [--from ${sender address}]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "contract", Aliases: []string{"c"}},
+					&cli.StringFlag{Name: "sig", Aliases: []string{"s"}},
+					&cli.StringSliceFlag{Name: "args", Aliases: []string{"a"}},
+					&cli.StringFlag{Name: "address"},
+					&cli.StringFlag{Name: "from", Aliases: []string{"f"}},
+				},
+				Action: func(cCtx *cli.Context) error {
+					contractName := cCtx.String("contract")
+					sig := cCtx.String("sig")
+					if contractName == "" || sig == "" {
+						return errors.New("both contract name and method signature are required, ex.: --contract NetworkDebugContract --sig \"set(uint256)\" --args 1")
+					}
+
+					address := cCtx.String("address")
+					if address == "" {
+						address = C.ContractAddressToNameMap.GetContractAddress(contractName)
+						if address == seth.UNKNOWN {
+							return fmt.Errorf("contract %s not found in the contract map, use --address flag to set its address", contractName)
+						}
+					}
+					if !common.IsHexAddress(address) {
+						return fmt.Errorf("invalid contract address: %s", address)
+					}
+
+					from := C.Addresses[0]
+					if cCtx.String("from") != "" {
+						if !common.IsHexAddress(cCtx.String("from")) {
+							return fmt.Errorf("invalid sender address: %s", cCtx.String("from"))
+						}
+						from = common.HexToAddress(cCtx.String("from"))
+					}
+
+					ctx, cancel := context.WithTimeout(context.Background(), C.Cfg.Network.TxnTimeout.Duration())
+					defer cancel()
+					estimation, err := C.EstimateContractCallCost(ctx, from, common.HexToAddress(address), contractName, sig, cCtx.StringSlice("args"))
+					if err != nil {
+						return err
+					}
+
+					seth.L.Info().
+						Str("Contract", contractName).
+						Str("Address", address).
+						Str("Method", estimation.Method).
+						Uint64("Gas limit", estimation.GasLimit).
+						Str("L1 fee (wei/ether)", seth.FormatWei(estimation.L1Fee)).
+						Msg("Estimated gas")
+					for _, c := range estimation.Costs {
+						seth.L.Info().
+							Str("Priority", c.Priority).
+							Str("Gas price (wei)", c.GasPrice.String()).
+							Str("Cost (wei/ether)", seth.FormatWei(c.Cost)).
+							Msg("Estimated cost")
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:        "send",
+				HelpName:    "send",
+				Description: "send a transaction defined by named template from the config, using the root key",
+				ArgsUsage:   "--template ${template name} [--args ${argument}] [--value ${value in wei}] [--to ${contract address}]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "template", Aliases: []string{"t"}},
+					&cli.StringSliceFlag{Name: "args", Aliases: []string{"a"}},
+					&cli.StringFlag{Name: "value", Aliases: []string{"v"}},
+					&cli.StringFlag{Name: "to"},
+				},
+				Action: func(cCtx *cli.Context) error {
+					name := cCtx.String("template")
+					if name == "" {
+						return errors.New("template name is required, ex.: --template mint")
+					}
+
+					var overrides []seth.TemplateOverride
+					if cCtx.IsSet("args") {
+						overrides = append(overrides, seth.WithTemplateArgs(cCtx.StringSlice("args")...))
+					}
+					if cCtx.String("value") != "" {
+						value, err := seth.ParseAmount(cCtx.String("value"))
+						if err != nil {
+							return err
+						}
+						overrides = append(overrides, seth.WithTemplateValue(value))
+					}
+					if cCtx.String("to") != "" {
+						overrides = append(overrides, seth.WithTemplateTo(cCtx.String("to")))
+					}
+
+					decoded, err := C.Decode(C.FromTemplate(name, overrides...))
+					if err != nil {
+						return err
+					}
+
+					seth.L.Info().
+						Str("Template", name).
+						Str("Transaction", decoded.Hash).
+						Msg("Transaction sent")
+
+					return nil
+				},
+			},
+			{
+				Name:        "run",
+				HelpName:    "run",
+				Description: "run a scenario (deploy/send/call/wait_for_event/assert steps) from TOML or JSON file, using the root key",
+				ArgsUsage:   "[--report ${report dir}] ${scenario file}",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "report", Aliases: []string{"r"}, Value: seth.ScenarioReportDir},
+				},
+				Action: func(cCtx *cli.Context) error {
+					if cCtx.Args().Len() == 0 {
+						return errors.New("scenario file is required, ex.: seth -n Geth run scenario.toml")
+					}
+					scenario, err := seth.LoadScenario(cCtx.Args().First())
+					if err != nil {
+						return err
+					}
+
+					report, runErr := C.RunScenario(context.Background(), scenario)
+					for _, step := range report.Steps {
+						seth.L.Info().
+							Str("Step", step.Name).
+							Str("Type", step.Type).
+							Str("Status", step.Status).
+							Str("Duration", step.Duration).
+							Str("Error", step.Error).
+							Msg("Scenario step")
+					}
+
+					path, err := seth.SaveScenarioReport(report, cCtx.String("report"))
+					if err != nil {
+						return err
+					}
+					seth.L.Info().
+						Str("Scenario", report.Name).
+						Bool("Passed", report.Passed).
+						Str("Report", path).
+						Msg("Scenario finished")
+
+					return runErr
+				},
+			},
+			{
+				Name:        "deploy",
+				HelpName:    "deploy",
+				Description: "deploys contracts of a deployment manifest (TOML or JSON), skipping ones deployed by previous runs",
+				ArgsUsage:   "${deployment manifest file}",
+				Action: func(cCtx *cli.Context) error {
+					if cCtx.Args().Len() == 0 {
+						return errors.New("deployment manifest file is required, ex.: seth -n Geth deploy deployments.toml")
+					}
+					manifest, err := seth.LoadDeploymentManifest(cCtx.Args().First())
+					if err != nil {
+						return err
+					}
+
+					state, runErr := C.RunDeployments(context.Background(), manifest)
+					if state != nil {
+						for _, c := range state.Contracts {
+							seth.L.Info().
+								Str("Deployment", c.Name).
+								Str("Contract", c.Contract).
+								Str("Address", c.Address).
+								Str("Status", c.Status).
+								Msg("Deployment")
+						}
+					}
+					if runErr != nil {
+						return runErr
+					}
+					seth.L.Info().
+						Str("Manifest", manifest.Name).
+						Str("State", seth.DeploymentStatePath(manifest.Name, C.Cfg.Network.Name)).
+						Msg("Deployments finished")
+
+					return nil
+				},
+			},
+			{
+				Name:        "replay",
+				HelpName:    "replay",
+				Description: "replays deployments and transactions recorded in a run manifest against selected network, remapping senders, nonces and contract addresses",
+				ArgsUsage:   "[--report ${report dir}] ${run manifest file}",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "report", Aliases: []string{"r"}, Value: seth.ReplayReportDir},
+				},
+				Action: func(cCtx *cli.Context) error {
+					if cCtx.Args().Len() == 0 {
+						return errors.New("run manifest file is required, ex.: seth -n Geth replay run_manifests/run_manifest.json")
+					}
+					manifest, err := seth.LoadRunManifest(cCtx.Args().First())
+					if err != nil {
+						return err
+					}
+
+					report, err := C.Replay(context.Background(), manifest)
+					if err != nil {
+						return err
+					}
+
+					path, err := seth.SaveReplayReport(report, cCtx.String("report"))
+					if err != nil {
+						return err
+					}
+					seth.L.Info().
+						Int("Transactions", len(report.Transactions)).
+						Int("Mismatches", report.Mismatches).
+						Str("Report", path).
+						Msg("Replay finished")
+
+					if report.Mismatches > 0 {
+						return fmt.Errorf("%d replayed transactions have different status than recorded ones", report.Mismatches)
+					}
+					return nil
+				},
+			},
+			{
+				Name:        "report",
+				HelpName:    "report",
+				Description: "reports about activity of managed addresses",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "spend",
+						HelpName:    "spend",
+						Description: "sums fees and value of all transactions sent from managed addresses in a block range and reconciles them with run manifests",
+						ArgsUsage:   "--from-block ${block number} [--to-block ${block number}] [--manifests ${run manifests dir}] [--report ${report dir}]",
+						Flags: []cli.Flag{
+							&cli.Uint64Flag{Name: "from-block", Aliases: []string{"b"}, Required: true},
+							&cli.Uint64Flag{Name: "to-block", Aliases: []string{"e"}},
+							&cli.StringFlag{Name: "manifests", Aliases: []string{"m"}, Value: seth.RunManifestDir},
+							&cli.StringFlag{Name: "report", Aliases: []string{"r"}, Value: seth.SpendReportDir},
+						},
+						Action: func(cCtx *cli.Context) error {
+							ctx := context.Background()
+							toBlock := cCtx.Uint64("to-block")
+							if toBlock == 0 {
+								latest, err := C.Client.BlockNumber(ctx)
+								if err != nil {
+									return err
+								}
+								toBlock = latest
+							}
+
+							manifests, err := seth.LoadRunManifests(cCtx.String("manifests"))
+							if err != nil {
+								return err
+							}
+							report, err := C.SpendReport(ctx, cCtx.Uint64("from-block"), toBlock, manifests)
+							if err != nil {
+								return err
+							}
+
+							path, err := seth.SaveSpendReport(report, cCtx.String("report"))
+							if err != nil {
+								return err
+							}
+							for _, hash := range report.UntrackedTransactions {
+								seth.L.Warn().Str("Transaction", hash).Msg("Transaction not found in any run manifest")
+							}
+							for _, hash := range report.MissingTransactions {
+								seth.L.Warn().Str("Transaction", hash).Msg("Transaction from run manifest not found on chain")
+							}
+							seth.L.Info().
+								Uint64("From block", report.FromBlock).
+								Uint64("To block", report.ToBlock).
+								Int("Transactions", len(report.Transactions)).
+								Str("Total fees (ether)", report.TotalFeesEther).
+								Str("Total value (ether)", report.TotalValueEther).
+								Str("Untracked fees (ether)", report.UntrackedFeesEther).
+								Str("Report", path).
+								Msg("Spend report")
+
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:        "watch",
+				HelpName:    "watch",
+				Description: "stream transactions and decoded events involving an address in real time, until interrupted",
+				ArgsUsage:   "-a ${address} [--from_block ${block number}] [--duration ${duration}] [--no_color]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "address", Aliases: []string{"a"}},
+					&cli.Uint64Flag{Name: "from_block", Aliases: []string{"b"}},
+					&cli.DurationFlag{Name: "duration", Aliases: []string{"d"}},
+					&cli.BoolFlag{Name: "no_color"},
+				},
+				Action: func(cCtx *cli.Context) error {
+					address := cCtx.String("address")
+					if !common.IsHexAddress(address) {
+						return fmt.Errorf("valid address is required, ex.: -a 0x5FbDB2315678afecb367f032d93F642f64180aa3, got: '%s'", address)
+					}
+
+					ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+					defer cancel()
+					if cCtx.Duration("duration") > 0 {
+						ctx, cancel = context.WithTimeout(ctx, cCtx.Duration("duration"))
+						defer cancel()
+					}
+
+					colors := !cCtx.Bool("no_color")
+					return C.WatchAddress(ctx, common.HexToAddress(address), cCtx.Uint64("from_block"), func(activity seth.WatchActivity) {
+						fmt.Println(formatWatchActivity(activity, colors))
+					})
+				},
+			},
 			{
 				Name:        "keys",
 				HelpName:    "keys",
@@ -199,7 +532,7 @@ func RunCLI(args []string) error {
 							localKeyfile := cCtx.Bool("local")
 							vaultId := os.Getenv(seth.ONE_PASS_VAULT_ENV_VAR)
 							if !localKeyfile && vaultId == "" {
-								return fmt.Errorf(ErrNo1PassVault, seth.ONE_PASS_VAULT_ENV_VAR)
+								return errors.Wrapf(ErrNo1PassVault, "set it as %s env var", seth.ONE_PASS_VAULT_ENV_VAR)
 							}
 							return seth.UpdateKeyFileBalances(C, &seth.FundKeyFileCmdOpts{LocalKeyfile: localKeyfile, VaultId: vaultId})
 						},
@@ -221,7 +554,7 @@ func RunCLI(args []string) error {
 							localKeyfile := cCtx.Bool("local")
 							vaultId := os.Getenv(seth.ONE_PASS_VAULT_ENV_VAR)
 							if !localKeyfile && vaultId == "" {
-								return fmt.Errorf(ErrNo1PassVault, seth.ONE_PASS_VAULT_ENV_VAR)
+								return errors.Wrapf(ErrNo1PassVault, "set it as %s env var", seth.ONE_PASS_VAULT_ENV_VAR)
 							}
 							opts := &seth.FundKeyFileCmdOpts{Addrs: addresses, RootKeyBuffer: rootKeyBuffer, LocalKeyfile: localKeyfile, VaultId: vaultId}
 							return seth.UpdateAndSplitFunds(C, opts)
@@ -241,11 +574,29 @@ func RunCLI(args []string) error {
 							localKeyfile := cCtx.Bool("local")
 							vaultId := os.Getenv(seth.ONE_PASS_VAULT_ENV_VAR)
 							if !localKeyfile && vaultId == "" {
-								return fmt.Errorf(ErrNo1PassVault, seth.ONE_PASS_VAULT_ENV_VAR)
+								return errors.Wrapf(ErrNo1PassVault, "set it as %s env var", seth.ONE_PASS_VAULT_ENV_VAR)
 							}
 							return seth.ReturnFundsFromKeyFileAndUpdateIt(C, cCtx.String("address"), &seth.FundKeyFileCmdOpts{LocalKeyfile: localKeyfile, VaultId: vaultId})
 						},
 					},
+					{
+						Name:        "rebalance",
+						HelpName:    "rebalance",
+						Aliases:     []string{"rb"},
+						Description: "evens out balances of all the keys from keyfile.toml by moving funds from the richest keys to the poorest ones",
+						ArgsUsage:   "seth keys rebalance",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{Name: "local", Aliases: []string{"l"}},
+						},
+						Action: func(cCtx *cli.Context) error {
+							localKeyfile := cCtx.Bool("local")
+							vaultId := os.Getenv(seth.ONE_PASS_VAULT_ENV_VAR)
+							if !localKeyfile && vaultId == "" {
+								return errors.Wrapf(ErrNo1PassVault, "set it as %s env var", seth.ONE_PASS_VAULT_ENV_VAR)
+							}
+							return seth.RebalanceKeyFileAndUpdateIt(C, &seth.FundKeyFileCmdOpts{LocalKeyfile: localKeyfile, VaultId: vaultId})
+						},
+					},
 					{
 						Name:        "remove",
 						Aliases:     []string{"rm"},
@@ -258,7 +609,7 @@ func RunCLI(args []string) error {
 							localKeyfile := cCtx.Bool("local")
 							vaultId := os.Getenv(seth.ONE_PASS_VAULT_ENV_VAR)
 							if !localKeyfile && vaultId == "" {
-								return fmt.Errorf(ErrNo1PassVault, seth.ONE_PASS_VAULT_ENV_VAR)
+								return errors.Wrapf(ErrNo1PassVault, "set it as %s env var", seth.ONE_PASS_VAULT_ENV_VAR)
 							}
 
 							if localKeyfile {
@@ -278,6 +629,37 @@ func RunCLI(args []string) error {
 				Flags: []cli.Flag{
 					&cli.StringFlag{Name: "file", Aliases: []string{"f"}},
 				},
+				Subcommands: []*cli.Command{
+					{
+						Name:        "diff",
+						HelpName:    "diff",
+						Aliases:     []string{"d"},
+						Description: "compare two decoded traces saved as JSON (decoded calls saved with 'trace_to_json' or call trees)",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "file", Aliases: []string{"f"}, Required: true},
+							&cli.StringFlag{Name: "file2", Aliases: []string{"f2"}, Required: true},
+						},
+						Action: func(cCtx *cli.Context) error {
+							traceA, err := seth.ReadCallTree(cCtx.String("file"))
+							if err != nil {
+								return err
+							}
+							traceB, err := seth.ReadCallTree(cCtx.String("file2"))
+							if err != nil {
+								return err
+							}
+							diffs := seth.CompareTraces(traceA, traceB)
+							if len(diffs) == 0 {
+								fmt.Println("Traces are identical")
+								return nil
+							}
+							for _, d := range diffs {
+								fmt.Println(d.String())
+							}
+							return nil
+						},
+					},
+				},
 				Action: func(cCtx *cli.Context) error {
 					file := cCtx.String("file")
 					var transactions []string
@@ -290,16 +672,16 @@ func RunCLI(args []string) error {
 
 					cfgPath := os.Getenv(seth.CONFIG_FILE_ENV_VAR)
 					if cfgPath == "" {
-						return errors.New(seth.ErrEmptyConfigPath)
+						return seth.ErrEmptyConfigPath
 					}
 					var cfg *seth.Config
 					d, err := os.ReadFile(cfgPath)
 					if err != nil {
-						return errors.Wrap(err, seth.ErrReadSethConfig)
+						return errors.Wrap(err, seth.ErrReadSethConfig.Error())
 					}
 					err = toml.Unmarshal(d, &cfg)
 					if err != nil {
-						return errors.Wrap(err, seth.ErrUnmarshalSethConfig)
+						return errors.Wrap(err, seth.ErrUnmarshalSethConfig.Error())
 					}
 					absPath, err := filepath.Abs(cfgPath)
 					if err != nil {
@@ -331,6 +713,7 @@ func RunCLI(args []string) error {
 								cfg.Network = n
 								cfg.Network.Name = snet
 								cfg.Network.URLs = []string{url}
+								cfg.Network.Endpoints = nil
 								break
 							}
 						}
@@ -338,20 +721,10 @@ func RunCLI(args []string) error {
 						if cfg.Network == nil {
 							return fmt.Errorf("default network not defined in the TOML file")
 						}
+					}
 
-						client, err := ethclient.Dial(cfg.Network.URLs[0])
-						if err != nil {
-							return fmt.Errorf("failed to connect to '%s' due to: %w", cfg.Network.URLs[0], err)
-						}
-						defer client.Close()
-
-						if cfg.Network.Name == seth.DefaultNetworkName {
-							chainId, err := client.ChainID(context.Background())
-							if err != nil {
-								return errors.Wrap(err, "failed to get chain ID")
-							}
-							cfg.Network.ChainID = chainId.String()
-						}
+					if _, err := seth.ApplyEnvOverrides(cfg); err != nil {
+						return err
 					}
 
 					zero := int64(0)
@@ -383,3 +756,63 @@ func RunCLI(args []string) error {
 	}
 	return app.Run(args)
 }
+
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorCyan   = "\x1b[36m"
+	colorGray   = "\x1b[90m"
+)
+
+// formatWatchActivity formats transaction or event as a single line, optionally colorized
+func formatWatchActivity(activity seth.WatchActivity, colors bool) string {
+	paint := func(color, text string) string {
+		if !colors {
+			return text
+		}
+		return color + text + colorReset
+	}
+
+	var sb strings.Builder
+	sb.WriteString(paint(colorGray, fmt.Sprintf("[block %d]", activity.BlockNumber)))
+	sb.WriteString(" ")
+
+	contract := ""
+	if activity.Contract != "" {
+		contract = fmt.Sprintf(" (%s)", activity.Contract)
+	}
+
+	switch activity.Type {
+	case seth.WatchActivity_Transaction:
+		status := paint(colorGreen, "TX")
+		if activity.Status == 0 {
+			status = paint(colorRed, "TX REVERTED")
+		}
+		sb.WriteString(fmt.Sprintf("%s %s %s -> %s%s", status, activity.TxHash, activity.From, activity.To, contract))
+		if activity.Method != "" {
+			sb.WriteString(" " + paint(colorCyan, activity.Method))
+		}
+		if activity.Value != nil && activity.Value.Sign() > 0 {
+			sb.WriteString(" value: " + seth.FormatWei(activity.Value))
+		}
+	case seth.WatchActivity_Event:
+		sb.WriteString(fmt.Sprintf("%s %s%s", paint(colorYellow, "EVENT"), activity.Event.Address.Hex(), contract))
+		if activity.Event.Signature == "" {
+			sb.WriteString(fmt.Sprintf(" topics: %v", activity.Event.Topics))
+			break
+		}
+		sb.WriteString(" " + paint(colorCyan, activity.Event.Signature))
+		keys := make([]string, 0, len(activity.Event.EventData))
+		for k := range activity.Event.EventData {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sb.WriteString(fmt.Sprintf(" %s=%v", k, activity.Event.EventData[k]))
+		}
+	}
+
+	return sb.String()
+}
@@ -1,9 +1,13 @@
 package seth
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"encoding/base64"
 	"fmt"
+	"math/big"
+	"math/rand"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,17 +15,12 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/pelletier/go-toml/v2"
 	"github.com/pkg/errors"
 )
 
 const (
-	ErrReadSethConfig         = "failed to read TOML config for seth"
-	ErrReadKeyFileConfig      = "failed to read TOML keyfile config"
-	ErrUnmarshalSethConfig    = "failed to unmarshal TOML config for seth"
-	ErrUnmarshalKeyFileConfig = "failed to unmarshal TOML keyfile config for seth"
-	ErrEmptyRootPrivateKey    = "no private keys were set, set %s=..."
-
 	GETH  = "Geth"
 	ANVIL = "Anvil"
 
@@ -36,6 +35,17 @@ const (
 	ONE_PASS_VAULT_ENV_VAR = "SETH_ONE_PASS_VAULT"
 
 	DefaultNetworkName = "Default"
+
+	DefaultReceiptPollingInterval              = time.Second
+	DefaultReceiptPollingMaxIntervalMultiplier = 10
+)
+
+var (
+	ErrReadSethConfig         = errors.New("failed to read TOML config for seth")
+	ErrReadKeyFileConfig      = errors.New("failed to read TOML keyfile config")
+	ErrUnmarshalSethConfig    = errors.New("failed to unmarshal TOML config for seth")
+	ErrUnmarshalKeyFileConfig = errors.New("failed to unmarshal TOML keyfile config for seth")
+	ErrEmptyRootPrivateKey    = errors.New("no private keys were set")
 )
 
 type KeyFileSource string
@@ -43,32 +53,83 @@ type KeyFileSource string
 const (
 	KeyFileSourceBase64EnvVar KeyFileSource = "base64_env"
 	KeyFileSourceFile         KeyFileSource = "file"
+	// KeyFileSourceKeystore reads encrypted geth keystore files from directory set in 'keyfile_path'
+	KeyFileSourceKeystore KeyFileSource = "keystore"
+	// KeyFileSourceMnemonic derives keys from BIP-39 mnemonic set in SETH_MNEMONIC environment variable
+	KeyFileSourceMnemonic KeyFileSource = "mnemonic"
 )
 
 type Config struct {
 	// internal fields
 	RevertedTransactionsFile string
 	ephemeral                bool
+	// traceOutputRoot and traceOutputDir are resolved root directory of traces and directory of traces of this run
+	traceOutputRoot    string
+	traceOutputDir     string
+	traceOutputStarted time.Time
+	// kmsKeys are KMS keys read by ParseKeys
+	kmsKeys map[common.Address]kmsKey
+	// contractStore is shared by clients of ClientManager, when it's set it's used instead of loading a new one
+	contractStore *ContractStore
+	// chainProfileApplied is true if profile of well-known chain was already applied, e.g. by ConfigBuilder, so that
+	// settings it left unset on purpose aren't overridden by ValidateConfig
+	chainProfileApplied bool
 
 	// external fields
-	KeyFileSource                 KeyFileSource     `toml:"keyfile_source"`
-	KeyFilePath                   string            `toml:"keyfile_path"`
-	EphemeralAddrs                *int64            `toml:"ephemeral_addresses_number"`
-	RootKeyFundsBuffer            *int64            `toml:"root_key_funds_buffer"`
-	ABIDir                        string            `toml:"abi_dir"`
-	BINDir                        string            `toml:"bin_dir"`
-	ContractMapFile               string            `toml:"contract_map_file"`
-	SaveDeployedContractsMap      bool              `toml:"save_deployed_contracts_map"`
-	Network                       *Network          `toml:"network"`
-	Networks                      []*Network        `toml:"networks"`
-	NonceManager                  *NonceManagerCfg  `toml:"nonce_manager"`
-	TracingLevel                  string            `toml:"tracing_level"`
-	TraceToJson                   bool              `toml:"trace_to_json"`
-	PendingNonceProtectionEnabled bool              `toml:"pending_nonce_protection_enabled"`
-	ConfigDir                     string            `toml:"abs_path"`
-	ExperimentsEnabled            []string          `toml:"experiments_enabled"`
-	CheckRpcHealthOnStart         bool              `toml:"check_rpc_health_on_start"`
-	BlockStatsConfig              *BlockStatsConfig `toml:"block_stats"`
+	KeyFileSource                 KeyFileSource          `toml:"keyfile_source"`
+	KeyFilePath                   string                 `toml:"keyfile_path"`
+	KeystorePasswordFile          string                 `toml:"keystore_password_file"`
+	MnemonicDerivationPath        string                 `toml:"mnemonic_derivation_path"`
+	EphemeralAddrs                *int64                 `toml:"ephemeral_addresses_number"`
+	RootKeyFundsBuffer            *int64                 `toml:"root_key_funds_buffer"`
+	EphemeralFunding              *EphemeralFundingCfg   `toml:"ephemeral_funding"`
+	ABIDir                        string                 `toml:"abi_dir"`
+	BINDir                        string                 `toml:"bin_dir"`
+	ArtifactDirs                  []string               `toml:"artifact_dirs"`
+	ArtifactChecksums             string                 `toml:"artifact_checksums"`
+	ContractMapFile               string                 `toml:"contract_map_file"`
+	SaveDeployedContractsMap      bool                   `toml:"save_deployed_contracts_map"`
+	ContractReuse                 *ContractReuseCfg      `toml:"contract_reuse"`
+	Network                       *Network               `toml:"network"`
+	Networks                      []*Network             `toml:"networks"`
+	NonceManager                  *NonceManagerCfg       `toml:"nonce_manager"`
+	TracingLevel                  string                 `toml:"tracing_level"`
+	TracingLevelOverrides         map[string]string      `toml:"tracing_level_overrides"`
+	TraceToJson                   bool                   `toml:"trace_to_json"`
+	TraceToHTML                   bool                   `toml:"trace_to_html"`
+	TraceRetention                int                    `toml:"trace_retention"`
+	TraceWriter                   *TraceWriterCfg        `toml:"trace_writer"`
+	TraceOutput                   *TraceOutputCfg        `toml:"trace_output"`
+	TraceOpts                     *TraceOpts             `toml:"tracer"`
+	DecodedOutput                 *DecodedOutputCfg      `toml:"decoded_output"`
+	SignatureDatabase             *SignatureDatabaseCfg  `toml:"signature_database"`
+	TraceInternalTransfers        bool                   `toml:"trace_internal_transfers"`
+	TrackFundsFlow                bool                   `toml:"track_funds_flow"`
+	RunManifest                   bool                   `toml:"run_manifest"`
+	PendingNonceProtectionEnabled bool                   `toml:"pending_nonce_protection_enabled"`
+	StrictTransactOpts            bool                   `toml:"strict_transact_opts"`
+	ReturnFundsOnClose            bool                   `toml:"return_funds_on_close"`
+	ConfigDir                     string                 `toml:"abs_path"`
+	ExperimentsEnabled            []string               `toml:"experiments_enabled"`
+	CheckRpcHealthOnStart         bool                   `toml:"check_rpc_health_on_start"`
+	RPCHealthCheck                *RPCHealthCheckCfg     `toml:"rpc_health_check"`
+	BlockStatsConfig              *BlockStatsConfig      `toml:"block_stats"`
+	TransactionTemplates          []*TransactionTemplate `toml:"transaction_templates"`
+	DetectCapabilities            bool                   `toml:"detect_capabilities"`
+	CapabilitiesCacheDir          string                 `toml:"capabilities_cache_dir"`
+	CapabilitiesCacheTTL          *Duration              `toml:"capabilities_cache_ttl"`
+	GasSpikeBreaker               *GasSpikeBreakerCfg    `toml:"gas_spike_breaker"`
+	ReorgMonitor                  *ReorgMonitorCfg       `toml:"reorg_monitor"`
+	RPCHealthMonitor              *RPCHealthMonitorCfg   `toml:"rpc_health_monitor"`
+	RPCRecording                  *RPCRecordingCfg       `toml:"rpc_recording"`
+	ReadCache                     *ReadCacheCfg          `toml:"read_cache"`
+	Budget                        *BudgetCfg             `toml:"budget"`
+	TopUp                         *TopUpCfg              `toml:"top_up"`
+	KeyCoordination               *KeyCoordinationCfg    `toml:"key_coordination"`
+	Log                           *LogCfg                `toml:"log"`
+	Subscriptions                 *SubscriptionsCfg      `toml:"subscriptions"`
+	// RootKeyFundsBufferAmount is the same as RootKeyFundsBuffer, but it also accepts amounts with unit, e.g. "0.5 ether"
+	RootKeyFundsBufferAmount *EtherAmount `toml:"root_key_funds_buffer_amount"`
 }
 
 type NonceManagerCfg struct {
@@ -76,41 +137,120 @@ type NonceManagerCfg struct {
 	KeySyncTimeout      *Duration `toml:"key_sync_timeout"`
 	KeySyncRetries      uint      `toml:"key_sync_retries"`
 	KeySyncRetryDelay   *Duration `toml:"key_sync_retry_delay"`
+	// LocalNonceAllocation makes NewTXOpts/NewTXKeyOpts allocate nonces from local counter instead of querying pending nonce
+	LocalNonceAllocation bool `toml:"local_nonce_allocation"`
+	// JournalPath is the path of the file, where nonces and hashes of all signed transactions are persisted
+	JournalPath string `toml:"journal_path"`
+	// SendRetries is the number of times transaction rejected with 'nonce too low' or 'replacement transaction underpriced'
+	// is resent with resynced nonce or bumped fees, 0 disables it
+	SendRetries uint `toml:"send_retries"`
 }
 
 type Network struct {
-	Name                         string    `toml:"name"`
-	URLs                         []string  `toml:"urls_secret"`
-	EIP1559DynamicFees           bool      `toml:"eip_1559_dynamic_fees"`
-	GasPrice                     int64     `toml:"gas_price"`
-	GasFeeCap                    int64     `toml:"gas_fee_cap"`
-	GasTipCap                    int64     `toml:"gas_tip_cap"`
-	GasLimit                     uint64    `toml:"gas_limit"`
-	TxnTimeout                   *Duration `toml:"transaction_timeout"`
-	TransferGasFee               int64     `toml:"transfer_gas_fee"`
-	PrivateKeys                  []string  `toml:"private_keys_secret"`
-	GasPriceEstimationEnabled    bool      `toml:"gas_price_estimation_enabled"`
-	GasPriceEstimationBlocks     uint64    `toml:"gas_price_estimation_blocks"`
-	GasPriceEstimationTxPriority string    `toml:"gas_price_estimation_tx_priority"`
-
-	// derivative vars
-	ChainID string
+	Name                         string          `toml:"name"`
+	URLs                         []string        `toml:"urls_secret"`
+	Endpoints                    []*Endpoint     `toml:"endpoints"`
+	EIP1559DynamicFees           bool            `toml:"eip_1559_dynamic_fees"`
+	GasPrice                     int64           `toml:"gas_price"`
+	GasFeeCap                    int64           `toml:"gas_fee_cap"`
+	GasTipCap                    int64           `toml:"gas_tip_cap"`
+	GasLimit                     uint64          `toml:"gas_limit"`
+	TxnTimeout                   *Duration       `toml:"transaction_timeout"`
+	TransferGasFee               int64           `toml:"transfer_gas_fee"`
+	PrivateKeys                  []string        `toml:"private_keys_secret"`
+	GasPriceEstimationEnabled    bool            `toml:"gas_price_estimation_enabled"`
+	GasPriceEstimationBlocks     uint64          `toml:"gas_price_estimation_blocks"`
+	GasPriceEstimationTxPriority string          `toml:"gas_price_estimation_tx_priority"`
+	ReceiptPollingInterval       *Duration       `toml:"receipt_polling_interval"`
+	ReceiptPollingBackoff        bool            `toml:"receipt_polling_backoff"`
+	ReceiptPollingMaxInterval    *Duration       `toml:"receipt_polling_max_interval"`
+	ReceiptPollingJitter         float64         `toml:"receipt_polling_jitter"`
+	Confirmations                uint64          `toml:"confirmations"`
+	Finality                     string          `toml:"finality"`
+	Paymaster                    *PaymasterCfg   `toml:"paymaster"`
+	AutoAccessList               bool            `toml:"auto_access_list"`
+	MulticallAddress             string          `toml:"multicall_address"`
+	Create2Factory               string          `toml:"create2_factory"`
+	EphemeralTokens              []*TokenFunding `toml:"ephemeral_tokens"`
+	Explorer                     *ExplorerCfg    `toml:"explorer"`
+	// RateLimit limits rate of HTTP RPC requests to each of network's URLs, endpoints can override it
+	RateLimit *RateLimitCfg `toml:"rate_limit"`
+	// HTTPHeaders are set on every RPC request and websocket handshake, e.g. API key header of the provider
+	HTTPHeaders map[string]string `toml:"http_headers_secret"`
+	// BasicAuth are credentials of RPC nodes sent in Authorization header
+	BasicAuth *BasicAuthCfg `toml:"basic_auth"`
+	// BearerToken is sent in Authorization header of every RPC request and websocket handshake
+	BearerToken string `toml:"bearer_token_secret"`
+	// RemoteSigner signs transactions of its addresses, which are used as the first keys (root key being the first one)
+	RemoteSigner *RemoteSignerCfg `toml:"remote_signer"`
+	// KMSKeys are keys stored in AWS or GCP KMS, they are used after remote signer's addresses
+	KMSKeys []*KMSKeyCfg `toml:"kms_keys"`
+	// ChainID is the expected chain ID of the network, client creation fails if node returns another one. If it's not set,
+	// chain ID returned by the node is stored here, when client is created
+	ChainID string `toml:"chain_id"`
+	// GasPriceAmount, GasFeeCapAmount and GasTipCapAmount are the same as GasPrice, GasFeeCap and GasTipCap, but they also
+	// accept amounts with unit, e.g. "3 gwei". They are copied to the int64 fields, when config is validated.
+	GasPriceAmount  Amount `toml:"gas_price_amount"`
+	GasFeeCapAmount Amount `toml:"gas_fee_cap_amount"`
+	GasTipCapAmount Amount `toml:"gas_tip_cap_amount"`
+	// OPStack adds L1 data fee queried from GasPriceOracle predeploy to costs of transactions on OP-stack chains (Optimism, Base)
+	OPStack bool `toml:"op_stack"`
+
+	// rateLimiter is shared by all connections to network's nodes, it's created on the first connection
+	rateLimiter *rateLimitTransport
+	// httpClient and rpcHeaders are set with WithHTTPClient and WithRPCHeaders
+	httpClient *http.Client
+	rpcHeaders func(h http.Header) error
+	// rpcRecording records or replays HTTP RPC requests, when 'rpc_recording' is set
+	rpcRecording rpcRecordingTransport
+	// readCache caches results of read-only HTTP RPC calls, when 'read_cache' is set
+	readCache *ReadCache
+}
+
+// ReceiptPollingDelay returns how long WaitMined should wait before polling for transaction receipt again after given number
+// of unsuccessful attempts (starting from 0). If backoff is enabled polling interval is doubled after each attempt, until it reaches
+// max interval. If jitter is set, the delay is randomly adjusted by up to +/- jitter fraction of it.
+func (n *Network) ReceiptPollingDelay(attempt int) time.Duration {
+	interval := DefaultReceiptPollingInterval
+	if n.ReceiptPollingInterval != nil && n.ReceiptPollingInterval.Duration() > 0 {
+		interval = n.ReceiptPollingInterval.Duration()
+	}
+
+	if n.ReceiptPollingBackoff {
+		maxInterval := interval * DefaultReceiptPollingMaxIntervalMultiplier
+		if n.ReceiptPollingMaxInterval != nil && n.ReceiptPollingMaxInterval.Duration() > 0 {
+			maxInterval = n.ReceiptPollingMaxInterval.Duration()
+		}
+		for i := 0; i < attempt && interval < maxInterval; i++ {
+			interval *= 2
+		}
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+
+	if n.ReceiptPollingJitter > 0 {
+		maxJitter := float64(interval) * n.ReceiptPollingJitter
+		interval += time.Duration(rand.Float64()*2*maxJitter - maxJitter)
+	}
+
+	return interval
 }
 
 // ReadConfig reads the TOML config file from location specified by env var "SETH_CONFIG_PATH" and returns a Config struct
 func ReadConfig() (*Config, error) {
 	cfgPath := os.Getenv(CONFIG_FILE_ENV_VAR)
 	if cfgPath == "" {
-		return nil, errors.New(ErrEmptyConfigPath)
+		return nil, ErrEmptyConfigPath
 	}
 	var cfg *Config
 	d, err := os.ReadFile(cfgPath)
 	if err != nil {
-		return nil, errors.Wrap(err, ErrReadSethConfig)
+		return nil, wrapError(err, ErrReadSethConfig)
 	}
 	err = toml.Unmarshal(d, &cfg)
 	if err != nil {
-		return nil, errors.Wrap(err, ErrUnmarshalSethConfig)
+		return nil, wrapError(err, ErrUnmarshalSethConfig)
 	}
 	absPath, err := filepath.Abs(cfgPath)
 	if err != nil {
@@ -141,6 +281,7 @@ func ReadConfig() (*Config, error) {
 				cfg.Network = n
 				cfg.Network.Name = snet
 				cfg.Network.URLs = []string{url}
+				cfg.Network.Endpoints = nil
 
 				if snet == "" {
 					L.Warn().Msg("No network name provided, using default network")
@@ -156,9 +297,17 @@ func ReadConfig() (*Config, error) {
 		}
 	}
 
+	if _, err := ApplyEnvOverrides(cfg); err != nil {
+		return nil, err
+	}
+
 	rootPrivateKey := os.Getenv(ROOT_PRIVATE_KEY_ENV_VAR)
-	if rootPrivateKey == "" {
-		return nil, errors.Errorf(ErrEmptyRootPrivateKey, ROOT_PRIVATE_KEY_ENV_VAR)
+	if rootPrivateKey == "" && cfg.Network.RemoteSigner != nil && len(cfg.Network.RemoteSigner.Addresses) > 0 {
+		L.Debug().Msg("Root private key not set, root key is signed by remote signer")
+	} else if rootPrivateKey == "" && len(cfg.Network.KMSKeys) > 0 {
+		L.Debug().Msg("Root private key not set, root key is stored in KMS")
+	} else if rootPrivateKey == "" {
+		return nil, errors.Wrapf(ErrEmptyRootPrivateKey, "set %s=...", ROOT_PRIVATE_KEY_ENV_VAR)
 	} else {
 		cfg.Network.PrivateKeys = append(cfg.Network.PrivateKeys, rootPrivateKey)
 	}
@@ -166,10 +315,31 @@ func ReadConfig() (*Config, error) {
 	return cfg, nil
 }
 
-// ParseKeys parses private keys from the config
+// ParseKeys parses private keys from the config. If remote signer is configured, its addresses come first, then
+// addresses of KMS keys (read from KMS) follow. Private keys of both are nil.
 func (c *Config) ParseKeys() ([]common.Address, []*ecdsa.PrivateKey, error) {
 	addresses := make([]common.Address, 0)
 	privKeys := make([]*ecdsa.PrivateKey, 0)
+	if c.Network.RemoteSigner != nil {
+		for _, a := range c.Network.RemoteSigner.Addresses {
+			addresses = append(addresses, common.HexToAddress(a))
+			privKeys = append(privKeys, nil)
+		}
+	}
+	c.kmsKeys = make(map[common.Address]kmsKey)
+	for _, keyCfg := range c.Network.KMSKeys {
+		client, err := NewKMSClient(context.Background(), keyCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		address, key, err := newKMSKey(context.Background(), client)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.kmsKeys[address] = key
+		addresses = append(addresses, address)
+		privKeys = append(privKeys, nil)
+	}
 	for _, k := range c.Network.PrivateKeys {
 		privateKey, err := crypto.HexToECDSA(k)
 		if err != nil {
@@ -210,6 +380,15 @@ func readKeyFileConfig(cfg *Config) error {
 		return nil
 	}
 
+	if cfg.KeyFileSource == KeyFileSourceKeystore || cfg.KeyFileSource == KeyFileSourceMnemonic {
+		keys, err := readKeystoreOrMnemonicKeys(cfg)
+		if err != nil {
+			return wrapError(err, ErrReadKeyFileConfig)
+		}
+		cfg.Network.PrivateKeys = append(cfg.Network.PrivateKeys, keys...)
+		return nil
+	}
+
 	var err error
 	var kf *KeyFile
 	var kfd []byte
@@ -221,7 +400,7 @@ func readKeyFileConfig(cfg *Config) error {
 		}
 		kfd, err = os.ReadFile(cfg.KeyFilePath)
 		if err != nil {
-			return errors.Wrap(err, ErrReadKeyFileConfig)
+			return wrapError(err, ErrReadKeyFileConfig)
 		}
 		L.Debug().Msgf("Found keyfile file '%s' found", cfg.KeyFilePath)
 	} else {
@@ -238,10 +417,10 @@ func readKeyFileConfig(cfg *Config) error {
 
 	err = toml.Unmarshal(kfd, &kf)
 	if err != nil {
-		return errors.Wrap(err, ErrUnmarshalKeyFileConfig)
+		return wrapError(err, ErrUnmarshalKeyFileConfig)
 	}
 	if kf == nil {
-		return errors.New(ErrEmptyKeyFile)
+		return ErrEmptyKeyFile
 	}
 	for _, pk := range kf.Keys {
 		cfg.Network.PrivateKeys = append(cfg.Network.PrivateKeys, pk.PrivateKey)
@@ -266,6 +445,51 @@ func (c *Config) setEphemeralAddrs() {
 	}
 }
 
+// rootKeyFundsBuffer returns the amount of wei left on the root key in ephemeral mode
+func (c *Config) rootKeyFundsBuffer() *big.Int {
+	if c.RootKeyFundsBufferAmount != nil {
+		return c.RootKeyFundsBufferAmount.Wei()
+	}
+	if c.RootKeyFundsBuffer == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Mul(big.NewInt(*c.RootKeyFundsBuffer), big.NewInt(params.Ether))
+}
+
+// resolveAmounts copies network's gas prices set with unit to their int64 fields and checks that amounts set in both ways
+// are the same
+func resolveAmounts(cfg *Config) error {
+	if cfg.RootKeyFundsBufferAmount != nil && cfg.RootKeyFundsBuffer != nil && *cfg.RootKeyFundsBuffer != 0 &&
+		cfg.RootKeyFundsBufferAmount.Wei().Cmp(new(big.Int).Mul(big.NewInt(*cfg.RootKeyFundsBuffer), big.NewInt(params.Ether))) != 0 {
+		return errors.New("'root_key_funds_buffer' and 'root_key_funds_buffer_amount' are set to different amounts, set only one of them")
+	}
+	if cfg.Network == nil {
+		return nil
+	}
+	for _, f := range []struct {
+		name   string
+		amount Amount
+		value  *int64
+	}{
+		{"gas_price", cfg.Network.GasPriceAmount, &cfg.Network.GasPrice},
+		{"gas_fee_cap", cfg.Network.GasFeeCapAmount, &cfg.Network.GasFeeCap},
+		{"gas_tip_cap", cfg.Network.GasTipCapAmount, &cfg.Network.GasTipCap},
+	} {
+		if f.amount == "" {
+			continue
+		}
+		wei := f.amount.Wei()
+		if !wei.IsInt64() {
+			return errors.Errorf("'%s_amount' %s is too high, it must fit into int64", f.name, f.amount)
+		}
+		if *f.value != 0 && *f.value != wei.Int64() {
+			return errors.Errorf("'%s' and '%s_amount' are set to different amounts, set only one of them", f.name, f.name)
+		}
+		*f.value = wei.Int64()
+	}
+	return nil
+}
+
 const (
 	Experiment_SlowFundsReturn    = "slow_funds_return"
 	Experiment_Eip1559FeeEqualier = "eip_1559_fee_equalizer"
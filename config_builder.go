@@ -0,0 +1,220 @@
+package seth
+
+import (
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultTransactionTimeout = 5 * time.Minute
+	DefaultTransferGasFee     = 21_000
+)
+
+var (
+	ErrBuilderNoURLs = errors.New("no RPC URLs were set, set them with WithRPCURLs(...)")
+	ErrBuilderNoKeys = errors.New("no private keys were set, set them with WithPrivateKeys(...)")
+)
+
+// ConfigBuilder builds Config in code, so that Seth can be used without TOML config file and SETH_* environment variables.
+// Builder starts with the same defaults as the example config (tracing of reverted transactions, nonce manager settings,
+// 1 gwei gas price, 5 minutes transaction timeout) and WithChainID applies profile of well-known chains (see
+// KnownChainProfile) instead. Build validates the config, so it can be passed to NewClientWithConfig:
+//
+//	cfg, err := seth.NewConfigBuilder().
+//		WithRPCURLs("wss://sepolia.example.com").
+//		WithChainID(11155111).
+//		WithPrivateKeys(pk).
+//		Build()
+type ConfigBuilder struct {
+	cfg     *Config
+	chainID int64
+	// estimationSet is true if gas estimation settings were set explicitly, they aren't overridden by chain profile
+	estimationSet bool
+	errs          []error
+}
+
+// NewConfigBuilder returns builder of config with default settings
+func NewConfigBuilder() *ConfigBuilder {
+	return &ConfigBuilder{
+		cfg: &Config{
+			TracingLevel: TracingLevel_Reverted,
+			NonceManager: &NonceManagerCfg{
+				KeySyncRateLimitSec: 10,
+				KeySyncTimeout:      MustMakeDuration(20 * time.Second),
+				KeySyncRetryDelay:   MustMakeDuration(time.Second),
+				KeySyncRetries:      10,
+			},
+			Network: &Network{
+				TxnTimeout:     MustMakeDuration(DefaultTransactionTimeout),
+				TransferGasFee: DefaultTransferGasFee,
+			},
+		},
+	}
+}
+
+// WithNetworkName sets name of the network, by default it's the name of well-known chain or "Default"
+func (b *ConfigBuilder) WithNetworkName(name string) *ConfigBuilder {
+	b.cfg.Network.Name = name
+	return b
+}
+
+// WithRPCURLs sets RPC URLs of the network, the first one is used, the others are used as fallbacks
+func (b *ConfigBuilder) WithRPCURLs(urls ...string) *ConfigBuilder {
+	b.cfg.Network.URLs = urls
+	return b
+}
+
+// WithChainID sets expected chain ID of the network and applies profile of well-known chains to settings, which weren't
+// set explicitly
+func (b *ConfigBuilder) WithChainID(chainID int64) *ConfigBuilder {
+	if chainID <= 0 {
+		b.errs = append(b.errs, errors.Wrapf(ErrInvalidChainID, "chain ID: '%s'", strconv.FormatInt(chainID, 10)))
+		return b
+	}
+	b.chainID = chainID
+	b.cfg.Network.ChainID = strconv.FormatInt(chainID, 10)
+	return b
+}
+
+// WithPrivateKeys sets private keys (hex without 0x prefix), the first one is the root key
+func (b *ConfigBuilder) WithPrivateKeys(privateKeys ...string) *ConfigBuilder {
+	b.cfg.Network.PrivateKeys = privateKeys
+	return b
+}
+
+// WithEphemeralAddresses enables ephemeral mode with given number of keys funded from the root key, buffer is the amount
+// of wei left on the root key
+func (b *ConfigBuilder) WithEphemeralAddresses(addrs int64, rootKeyFundsBuffer *big.Int) *ConfigBuilder {
+	buffer := NewEtherAmount(rootKeyFundsBuffer)
+	b.cfg.EphemeralAddrs = &addrs
+	b.cfg.RootKeyFundsBufferAmount = &buffer
+	return b
+}
+
+// WithLegacyGasPrice sets gas price of legacy transactions and disables dynamic fees
+func (b *ConfigBuilder) WithLegacyGasPrice(gasPrice *big.Int) *ConfigBuilder {
+	b.cfg.Network.EIP1559DynamicFees = false
+	b.cfg.Network.GasPrice = b.gasPrice("gas price", gasPrice)
+	return b
+}
+
+// WithDynamicFees enables dynamic fee (EIP-1559) transactions with given fee cap and tip cap
+func (b *ConfigBuilder) WithDynamicFees(gasFeeCap, gasTipCap *big.Int) *ConfigBuilder {
+	b.cfg.Network.EIP1559DynamicFees = true
+	b.cfg.Network.GasFeeCap = b.gasPrice("gas fee cap", gasFeeCap)
+	b.cfg.Network.GasTipCap = b.gasPrice("gas tip cap", gasTipCap)
+	return b
+}
+
+// gasPrice returns gas price in wei as int64, gas prices, which don't fit into int64, fail Build
+func (b *ConfigBuilder) gasPrice(name string, wei *big.Int) int64 {
+	if wei == nil {
+		return 0
+	}
+	if !wei.IsInt64() {
+		b.errs = append(b.errs, errors.Errorf("%s %s is too high, it must fit into int64", name, FormatWei(wei)))
+		return 0
+	}
+	return wei.Int64()
+}
+
+// WithGasPriceEstimations enables or disables estimation of gas prices from given number of last blocks and sets
+// priority of transactions. Gas prices set with WithLegacyGasPrice and WithDynamicFees are used as fallback.
+func (b *ConfigBuilder) WithGasPriceEstimations(enabled bool, blocks uint64, priority string) *ConfigBuilder {
+	b.cfg.Network.GasPriceEstimationEnabled = enabled
+	b.cfg.Network.GasPriceEstimationBlocks = blocks
+	b.cfg.Network.GasPriceEstimationTxPriority = priority
+	b.estimationSet = true
+	return b
+}
+
+// WithGasLimit sets gas limit of all transactions, it should be set only if node can't estimate it
+func (b *ConfigBuilder) WithGasLimit(gasLimit uint64) *ConfigBuilder {
+	b.cfg.Network.GasLimit = gasLimit
+	return b
+}
+
+// WithTransactionTimeout sets how long to wait for transactions to be mined
+func (b *ConfigBuilder) WithTransactionTimeout(timeout time.Duration) *ConfigBuilder {
+	d, err := MakeDuration(timeout)
+	if err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	b.cfg.Network.TxnTimeout = &d
+	return b
+}
+
+// WithTracing sets tracing level (one of TracingLevel_*) and whether traces are saved as JSON
+func (b *ConfigBuilder) WithTracing(level string, traceToJson bool) *ConfigBuilder {
+	b.cfg.TracingLevel = level
+	b.cfg.TraceToJson = traceToJson
+	return b
+}
+
+// WithContractDirs sets directories with ABI and BIN files loaded into Contract Store
+func (b *ConfigBuilder) WithContractDirs(abiDir, binDir string) *ConfigBuilder {
+	b.cfg.ABIDir = abiDir
+	b.cfg.BINDir = binDir
+	return b
+}
+
+// WithNonceManager overrides default nonce manager settings
+func (b *ConfigBuilder) WithNonceManager(cfg *NonceManagerCfg) *ConfigBuilder {
+	b.cfg.NonceManager = cfg
+	return b
+}
+
+// WithPendingNonceProtection enables or disables pending nonce protection
+func (b *ConfigBuilder) WithPendingNonceProtection(enabled bool) *ConfigBuilder {
+	b.cfg.PendingNonceProtectionEnabled = enabled
+	return b
+}
+
+// WithConfig applies a function, which modifies the config, for settings without a dedicated builder method
+func (b *ConfigBuilder) WithConfig(modify func(cfg *Config)) *ConfigBuilder {
+	modify(b.cfg)
+	return b
+}
+
+// Build returns validated config, builder shouldn't be used after it's called
+func (b *ConfigBuilder) Build() (*Config, error) {
+	if len(b.errs) > 0 {
+		return nil, b.errs[0]
+	}
+	b.applyDefaults()
+
+	if len(b.cfg.Network.RPCURLs()) == 0 {
+		return nil, ErrBuilderNoURLs
+	}
+	if len(b.cfg.Network.PrivateKeys) == 0 && b.cfg.Network.RemoteSigner == nil && len(b.cfg.Network.KMSKeys) == 0 {
+		return nil, ErrBuilderNoKeys
+	}
+	if err := ValidateConfig(b.cfg); err != nil {
+		return nil, err
+	}
+	L.Trace().Interface("Config", b.cfg).Msg("Built seth config")
+	return b.cfg, nil
+}
+
+// applyDefaults applies profile of well-known chain or, for other chains, builder's default name and gas prices to settings,
+// which weren't set explicitly. Fees and gas estimation of the profile are applied independently, so explicit fees are
+// used as fallback of profile's gas estimation.
+func (b *ConfigBuilder) applyDefaults() {
+	n := b.cfg.Network
+	if p, ok := KnownChainProfile(b.chainID); ok {
+		applyProfile(b.cfg, p, !hasFeeSettings(n), !b.estimationSet)
+		return
+	}
+	if n.Name == "" {
+		n.Name = DefaultNetworkName
+	}
+	if !n.EIP1559DynamicFees && n.GasPrice == 0 && n.GasPriceAmount == "" {
+		n.GasPrice = 1_000_000_000
+		n.GasFeeCap = 1_000_000_000
+		n.GasTipCap = 1_000_000_000
+	}
+}
@@ -0,0 +1,87 @@
+package seth_test
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigBuilder(t *testing.T) {
+	pk := "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+
+	cfg, err := seth.NewConfigBuilder().
+		WithRPCURLs("ws://localhost:8545").
+		WithChainID(11155111).
+		WithPrivateKeys(pk).
+		WithTransactionTimeout(time.Minute).
+		Build()
+	require.NoError(t, err, "failed to build config")
+	require.Equal(t, "Sepolia", cfg.Network.Name, "name of well-known chain should be used")
+	require.Equal(t, "11155111", cfg.Network.ChainID, "chain ID should be set")
+	require.True(t, cfg.Network.EIP1559DynamicFees, "dynamic fees of well-known chain should be enabled")
+	require.True(t, cfg.Network.GasPriceEstimationEnabled, "gas estimation of well-known chain should be enabled")
+	require.Equal(t, int64(10_000_000_000), cfg.Network.GasFeeCap, "fee cap of well-known chain should be used")
+	require.Equal(t, int64(1_000_000_000), cfg.Network.GasTipCap, "tip cap of well-known chain should be used")
+	require.Equal(t, time.Minute, cfg.Network.TxnTimeout.Duration(), "transaction timeout should be set")
+	require.Equal(t, seth.TracingLevel_Reverted, cfg.TracingLevel, "default tracing level should be used")
+	require.NotNil(t, cfg.NonceManager, "default nonce manager settings should be used")
+
+	cfg, err = seth.NewConfigBuilder().
+		WithRPCURLs("ws://localhost:8545").
+		WithChainID(11155111).
+		WithNetworkName("MySepolia").
+		WithPrivateKeys(pk).
+		WithLegacyGasPrice(big.NewInt(5_000_000_000)).
+		Build()
+	require.NoError(t, err, "failed to build config")
+	require.Equal(t, "MySepolia", cfg.Network.Name, "explicit name should be kept")
+	require.False(t, cfg.Network.EIP1559DynamicFees, "explicit legacy fees should be kept")
+	require.Equal(t, int64(5_000_000_000), cfg.Network.GasPrice, "explicit gas price should be kept")
+	require.True(t, cfg.Network.GasPriceEstimationEnabled, "gas estimation of well-known chain should use explicit gas price as fallback")
+
+	cfg, err = seth.NewConfigBuilder().
+		WithRPCURLs("ws://localhost:8545").
+		WithChainID(1).
+		WithPrivateKeys(pk).
+		WithGasPriceEstimations(false, 0, "").
+		Build()
+	require.NoError(t, err, "failed to build config")
+	require.False(t, cfg.Network.GasPriceEstimationEnabled, "explicitly disabled gas estimation should be kept")
+	require.True(t, cfg.Network.EIP1559DynamicFees, "dynamic fees of well-known chain should be enabled")
+	require.Equal(t, int64(30_000_000_000), cfg.Network.GasFeeCap, "fee cap of well-known chain should be used")
+
+	_, err = seth.NewConfigBuilder().WithPrivateKeys(pk).Build()
+	require.ErrorIs(t, err, seth.ErrBuilderNoURLs, "config without URLs should be rejected")
+
+	_, err = seth.NewConfigBuilder().WithRPCURLs("ws://localhost:8545").Build()
+	require.ErrorIs(t, err, seth.ErrBuilderNoKeys, "config without keys should be rejected")
+
+	_, err = seth.NewConfigBuilder().WithRPCURLs("ws://localhost:8545").WithPrivateKeys(pk).WithChainID(-1).Build()
+	require.Error(t, err, "negative chain ID should be rejected")
+
+	_, err = seth.NewConfigBuilder().WithRPCURLs("ws://localhost:8545").WithPrivateKeys(pk).WithTracing("verbose", false).Build()
+	require.Error(t, err, "invalid tracing level should be rejected")
+}
+
+func TestAPIConfigBuilder(t *testing.T) {
+	fileCfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	cfg, err := seth.NewConfigBuilder().
+		WithNetworkName(fileCfg.Network.Name).
+		WithRPCURLs(fileCfg.Network.URLs...).
+		WithPrivateKeys(fileCfg.Network.PrivateKeys...).
+		WithContractDirs(fileCfg.ABIDir, fileCfg.BINDir).
+		WithConfig(func(cfg *seth.Config) {
+			cfg.ConfigDir = fileCfg.ConfigDir
+		}).
+		Build()
+	require.NoError(t, err, "failed to build config")
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to create client from built config")
+	require.NotZero(t, c.ChainID, "chain ID should be resolved")
+}
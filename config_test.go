@@ -5,6 +5,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestValidateConfigKeyfile(t *testing.T) {
@@ -134,3 +135,73 @@ func TestDecodeKeyfileFromBase64(t *testing.T) {
 	require.Equal(t, 11, len(c.Addresses), "expected 10 addresses")
 	require.Equal(t, 11, len(c.PrivateKeys), "expected 10 private keys")
 }
+
+func TestConfigReceiptPollingDelay(t *testing.T) {
+	mustDuration := func(d time.Duration) *seth.Duration {
+		duration, err := seth.MakeDuration(d)
+		require.NoError(t, err, "failed to make duration")
+		return &duration
+	}
+
+	type tc struct {
+		name     string
+		network  seth.Network
+		attempt  int
+		expected time.Duration
+	}
+
+	tcs := []tc{
+		{
+			name:     "default interval",
+			network:  seth.Network{},
+			attempt:  5,
+			expected: seth.DefaultReceiptPollingInterval,
+		},
+		{
+			name:     "custom interval without backoff",
+			network:  seth.Network{ReceiptPollingInterval: mustDuration(200 * time.Millisecond)},
+			attempt:  5,
+			expected: 200 * time.Millisecond,
+		},
+		{
+			name:     "backoff",
+			network:  seth.Network{ReceiptPollingInterval: mustDuration(200 * time.Millisecond), ReceiptPollingBackoff: true},
+			attempt:  3,
+			expected: 1600 * time.Millisecond,
+		},
+		{
+			name:     "backoff capped by default max interval",
+			network:  seth.Network{ReceiptPollingInterval: mustDuration(200 * time.Millisecond), ReceiptPollingBackoff: true},
+			attempt:  10,
+			expected: 2 * time.Second,
+		},
+		{
+			name:     "backoff capped by custom max interval",
+			network:  seth.Network{ReceiptPollingInterval: mustDuration(time.Second), ReceiptPollingBackoff: true, ReceiptPollingMaxInterval: mustDuration(12 * time.Second)},
+			attempt:  10,
+			expected: 12 * time.Second,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.network.ReceiptPollingDelay(tc.attempt), "incorrect receipt polling delay")
+		})
+	}
+
+	t.Run("jitter", func(t *testing.T) {
+		network := seth.Network{ReceiptPollingInterval: mustDuration(time.Second), ReceiptPollingJitter: 0.2}
+		for i := 0; i < 100; i++ {
+			delay := network.ReceiptPollingDelay(0)
+			require.GreaterOrEqual(t, delay, 800*time.Millisecond, "delay should not be lower than interval minus jitter")
+			require.LessOrEqual(t, delay, 1200*time.Millisecond, "delay should not be greater than interval plus jitter")
+		}
+	})
+
+	t.Run("invalid jitter", func(t *testing.T) {
+		cfg := seth.Config{Network: &seth.Network{ReceiptPollingJitter: 1.5}}
+		err := seth.ValidateConfig(&cfg)
+		require.Error(t, err, "expected validation error")
+		require.Equal(t, "receipt polling jitter must be greater than or equal to 0 and less than 1", err.Error(), "incorrect error message")
+	})
+}
@@ -0,0 +1,228 @@
+package seth
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// FinalitySafe makes WaitMined wait until transaction's block is at or behind the 'safe' block
+	FinalitySafe = "safe"
+	// FinalityFinalized makes WaitMined wait until transaction's block is at or behind the 'finalized' block
+	FinalityFinalized = "finalized"
+)
+
+var (
+	ErrInvalidFinality         = errors.New("invalid finality")
+	ErrFinalityTagNotSupported = errors.New("node doesn't support finality tag")
+	ErrWaitConfirmations       = errors.New("transaction didn't reach required confirmations")
+)
+
+func validateFinality(n *Network) error {
+	switch n.Finality {
+	case "", FinalitySafe, FinalityFinalized:
+		return nil
+	default:
+		return errors.Wrapf(ErrInvalidFinality, "finality must be either empty, '%s' or '%s', got '%s'", FinalitySafe, FinalityFinalized, n.Finality)
+	}
+}
+
+// WaitMinedWithConfirmations awaits transaction receipt and then waits until transaction's block has given number of
+// confirmations (block itself being the first one, so 0 and 1 mean that receipt is enough) and, if finality is set, until
+// it's at or behind 'safe' or 'finalized' block. Before returning, the receipt is fetched again and if a reorg removed the
+// transaction in the meantime, it waits for the transaction to be mined again. Whole wait is limited by transaction timeout.
+func (m *Client) WaitMinedWithConfirmations(ctx context.Context, l zerolog.Logger, b bind.DeployBackend, tx *types.Transaction, confirmations uint64, finality string) (*types.Receipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.Cfg.Network.TxnTimeout.Duration())
+	defer cancel()
+
+	receipt, err := m.waitForReceipt(ctx, l, b, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; confirmations > 1 || finality != ""; attempt++ {
+		var newHead <-chan struct{}
+		if m.Subscriptions != nil {
+			newHead = m.Subscriptions.NextHead()
+		}
+		confirmed, current, err, statusErr := m.confirmationStatus(ctx, b, tx.Hash(), receipt.BlockNumber.Uint64(), confirmations, finality)
+		if statusErr != nil {
+			return nil, statusErr
+		}
+		if confirmed {
+			if err == nil && current.BlockHash == receipt.BlockHash {
+				break
+			}
+			switch {
+			case errors.Is(err, ethereum.NotFound):
+				l.Warn().
+					Str("TX", tx.Hash().String()).
+					Msg("Transaction was removed by a chain reorg, waiting for it to be mined again")
+				if receipt, err = m.waitForReceipt(ctx, l, b, tx); err != nil {
+					return nil, err
+				}
+				attempt = -1
+				continue
+			case err == nil:
+				l.Warn().
+					Str("TX", tx.Hash().String()).
+					Int64("BlockNumber", current.BlockNumber.Int64()).
+					Msg("Transaction was moved to another block by a chain reorg")
+				receipt = current
+				attempt = -1
+				continue
+			default:
+				l.Warn().
+					Err(err).
+					Str("TX", tx.Hash().String()).
+					Msg("Failed to get receipt")
+			}
+		}
+
+		queryTimer := time.NewTimer(m.Cfg.Network.ReceiptPollingDelay(attempt))
+		select {
+		case <-ctx.Done():
+			queryTimer.Stop()
+			return nil, errors.Wrapf(wrapError(ctx.Err(), ErrWaitConfirmations), "transaction: %s, block: %d", tx.Hash().Hex(), receipt.BlockNumber.Uint64())
+		case <-queryTimer.C:
+		case <-newHead:
+			queryTimer.Stop()
+		}
+	}
+
+	if confirmations > 1 || finality != "" {
+		l.Info().
+			Int64("BlockNumber", receipt.BlockNumber.Int64()).
+			Uint64("Confirmations", confirmations).
+			Str("Finality", finality).
+			Str("TX", tx.Hash().String()).
+			Msg("Transaction confirmed")
+	}
+	if m.ReorgMonitor != nil {
+		m.ReorgMonitor.TrackTransaction(receipt)
+	}
+	m.recordSpending(tx, receipt)
+	m.sendAttempts.forget(tx)
+	return receipt, nil
+}
+
+// confirmationStatus returns true if the block has given number of confirmations and is at or behind the finality tag
+// and, if it is, also current receipt of the transaction or error of fetching it. If the backend is client's own
+// connection, latest block number, finality header and receipt are fetched in a single batch request.
+func (m *Client) confirmationStatus(ctx context.Context, b bind.DeployBackend, txHash common.Hash, blockNumber, confirmations uint64, finality string) (confirmed bool, receipt *types.Receipt, receiptErr error, err error) {
+	if ec, ok := b.(*ethclient.Client); !ok || ec != m.Client {
+		confirmed, err = m.hasConfirmations(ctx, blockNumber, confirmations, finality, nil)
+		if !confirmed || err != nil {
+			return confirmed, nil, nil, err
+		}
+		receipt, receiptErr = b.TransactionReceipt(ctx, txHash)
+		return true, receipt, receiptErr, nil
+	}
+
+	var latest hexutil.Uint64
+	var header, rawReceipt json.RawMessage
+	var batch []BatchElem
+	needLatest := confirmations > 1 && m.latestSubscribedHead() == nil
+	if needLatest {
+		batch = append(batch, BatchElem{Method: "eth_blockNumber", Result: &latest})
+	}
+	if finality != "" {
+		batch = append(batch, BatchElem{Method: "eth_getBlockByNumber", Args: []interface{}{finality, false}, Result: &header})
+	}
+	batch = append(batch, transactionReceiptElem(txHash, &rawReceipt))
+	if batchErr := m.BatchCallCtx(ctx, batch); batchErr != nil {
+		m.logger().Debug().Err(batchErr).Msg("Failed to get confirmation status")
+		return false, nil, nil, nil
+	}
+
+	fetched := &fetchedConfirmationData{}
+	if needLatest {
+		fetched.latest, fetched.latestErr = uint64(latest), batch[0].Error
+		batch = batch[1:]
+	}
+	if finality != "" {
+		if fetched.finalityErr = batchResultOrNotFound(batch[0]); fetched.finalityErr == nil {
+			fetched.finalityHeader = &types.Header{}
+			fetched.finalityErr = json.Unmarshal(header, fetched.finalityHeader)
+		}
+		batch = batch[1:]
+	}
+	confirmed, err = m.hasConfirmations(ctx, blockNumber, confirmations, finality, fetched)
+	if !confirmed || err != nil {
+		return confirmed, nil, nil, err
+	}
+	receipt, receiptErr = decodeReceipt(batch[0], rawReceipt)
+	return true, receipt, receiptErr, nil
+}
+
+// fetchedConfirmationData is latest block number and finality header fetched in a batch
+type fetchedConfirmationData struct {
+	latest         uint64
+	latestErr      error
+	finalityHeader *types.Header
+	finalityErr    error
+}
+
+// hasConfirmations returns true if the block has given number of confirmations and is at or behind the finality tag,
+// latest block number and finality header are fetched, unless they were already fetched in a batch
+func (m *Client) hasConfirmations(ctx context.Context, blockNumber, confirmations uint64, finality string, fetched *fetchedConfirmationData) (bool, error) {
+	if confirmations > 1 {
+		var latest uint64
+		if head := m.latestSubscribedHead(); head != nil {
+			latest = head.Number.Uint64()
+		} else {
+			var err error
+			if fetched != nil {
+				latest, err = fetched.latest, fetched.latestErr
+			} else {
+				latest, err = m.Client.BlockNumber(ctx)
+			}
+			if err != nil {
+				m.logger().Debug().Err(err).Msg("Failed to get latest block number")
+				return false, nil
+			}
+		}
+		if latest+1 < blockNumber+confirmations {
+			return false, nil
+		}
+	}
+
+	if finality != "" {
+		var header *types.Header
+		var err error
+		if fetched != nil {
+			header, err = fetched.finalityHeader, fetched.finalityErr
+		} else {
+			tag := rpc.SafeBlockNumber
+			if finality == FinalityFinalized {
+				tag = rpc.FinalizedBlockNumber
+			}
+			header, err = m.Client.HeaderByNumber(ctx, big.NewInt(int64(tag)))
+		}
+		if err != nil {
+			if errors.Is(err, ethereum.NotFound) || strings.Contains(err.Error(), "not found") {
+				return false, errors.Wrapf(wrapError(err, ErrFinalityTagNotSupported), "block tag: '%s'", finality)
+			}
+			m.logger().Debug().Err(err).Str("Finality", finality).Msg("Failed to get block header")
+			return false, nil
+		}
+		if header.Number.Uint64() < blockNumber {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
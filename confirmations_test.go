@@ -0,0 +1,98 @@
+package seth_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+// newFinalityProxy forwards requests to the node, which doesn't support 'finalized' tag, and answers queries of finalized
+// block with the block, which is lag blocks behind the latest one
+func newFinalityProxy(t *testing.T, target string, lag uint64) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err, "failed to read request")
+		// the tag can be sent in a single request or in a batch
+		if bytes.Contains(body, []byte(`"eth_getBlockByNumber"`)) && bytes.Contains(body, []byte(`["finalized"`)) {
+			resp, err := http.Post(target, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`))
+			require.NoError(t, err, "failed to get block number")
+			var latest struct {
+				Result hexutil.Uint64 `json:"result"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&latest), "failed to decode block number")
+			_ = resp.Body.Close()
+			finalized := uint64(0)
+			if uint64(latest.Result) > lag {
+				finalized = uint64(latest.Result) - lag
+			}
+			body = bytes.Replace(body, []byte(`["finalized"`), []byte(fmt.Sprintf(`["%s"`, hexutil.EncodeUint64(finalized))), 1)
+		}
+		forwardRPCRequest(t, w, target, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAPIWaitMinedWithConfirmations(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.Network.Confirmations = 3
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to create client")
+
+	decoded, err := c.Decode(TestEnv.DebugContract.AddCounter(c.NewTXOpts(), big.NewInt(0), big.NewInt(1)))
+	require.NoError(t, err, "transaction should be mined")
+	latest, err := c.Client.BlockNumber(context.Background())
+	require.NoError(t, err, "failed to get block number")
+	require.GreaterOrEqual(t, latest, decoded.Receipt.BlockNumber.Uint64()+2, "transaction should have 3 confirmations")
+}
+
+func TestAPIWaitMinedWithFinality(t *testing.T) {
+	c := newClient(t)
+	httpURL := nodeHTTPURL(t, c)
+
+	t.Run("finalized", func(t *testing.T) {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Network.URLs = []string{newFinalityProxy(t, httpURL, 2).URL}
+		cfg.Network.Finality = seth.FinalityFinalized
+		client, err := seth.NewClientWithConfig(cfg)
+		require.NoError(t, err, "failed to create client")
+
+		decoded, err := client.Decode(TestEnv.DebugContract.AddCounter(client.NewTXOpts(), big.NewInt(0), big.NewInt(1)))
+		require.NoError(t, err, "transaction should be finalized")
+		latest, err := client.Client.BlockNumber(context.Background())
+		require.NoError(t, err, "failed to get block number")
+		require.GreaterOrEqual(t, latest, decoded.Receipt.BlockNumber.Uint64()+2, "transaction's block should be finalized")
+	})
+
+	t.Run("tag not supported", func(t *testing.T) {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Network.Finality = seth.FinalityFinalized
+		client, err := seth.NewClientWithConfig(cfg)
+		require.NoError(t, err, "failed to create client")
+
+		_, err = client.Decode(TestEnv.DebugContract.AddCounter(client.NewTXOpts(), big.NewInt(0), big.NewInt(1)))
+		require.Error(t, err, "waiting should fail")
+		require.ErrorIs(t, err, seth.ErrFinalityTagNotSupported, "incorrect error")
+	})
+}
+
+func TestConfigFinalityValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.Network.Finality = "latest"
+	require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrInvalidFinality, "invalid finality should be rejected")
+}
@@ -0,0 +1,278 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultEventSubscriptionBufferSize is the number of decoded events buffered for the handler, when it's full
+	// fetching of new logs is paused until the handler catches up
+	DefaultEventSubscriptionBufferSize = 64
+	// eventPollingReorgDepth is the number of latest polled blocks, whose hashes are remembered to detect reorgs
+	eventPollingReorgDepth = 128
+)
+
+var (
+	ErrUnknownContractEvent = errors.New("event not found in ABI of contract")
+	ErrNoContractAddresses  = errors.New("no address of contract found in contract map")
+)
+
+// EventSubscription delivers decoded events of a contract to the handler, until it's unsubscribed or its context is done
+type EventSubscription struct {
+	// Polling is true if node is polled for logs instead of using websocket subscription
+	Polling bool
+	cancel  context.CancelFunc
+	err     chan error
+	done    chan struct{}
+	once    *sync.Once
+}
+
+// Unsubscribe stops the subscription and waits until the handler returns
+func (s *EventSubscription) Unsubscribe() {
+	s.once.Do(s.cancel)
+	<-s.done
+}
+
+// Err returns a channel, that receives an error if subscription fails permanently (e.g. it couldn't be resubscribed
+// within configured number of attempts)
+func (s *EventSubscription) Err() <-chan error {
+	return s.err
+}
+
+// SubscribeContractEvents calls the handler with every event of given name (or signature) emitted by all contracts with
+// the name from contract map, decoded with contract's ABI from contract store. Only events emitted after the subscription
+// starts are delivered. Logs are received with websocket subscription, which is resubscribed after connection drops
+// (backfilling missed logs), or by polling the node, if client isn't connected over websocket. When a reorg removes
+// blocks with delivered events, they are delivered again with Removed flag set.
+// Events are delivered in order to a single handler goroutine through a buffered channel, so a slow handler pauses
+// fetching of new logs instead of events being dropped.
+func (m *Client) SubscribeContractEvents(ctx context.Context, contractName, eventName string, handler func(DecodedTransactionLog)) (*EventSubscription, error) {
+	contractABI, event, addresses, err := m.contractEventFilter(contractName, eventName)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(contractName, ".abi")
+
+	q := ethereum.FilterQuery{Addresses: addresses, Topics: [][]common.Hash{{event.ID}}}
+	ctx, cancel := context.WithCancel(ctx)
+	sub := &EventSubscription{cancel: cancel, err: make(chan error, 1), done: make(chan struct{}), once: &sync.Once{}}
+	logs := make(chan types.Log)
+
+	var managed *ManagedSubscription
+	if m.Subscriptions != nil {
+		var err error
+		managed, err = m.Subscriptions.SubscribeLogs(ctx, q, logs)
+		if err != nil {
+			m.logger().Debug().Err(err).Msg("Failed to subscribe to logs, falling back to polling")
+			managed = nil
+		}
+	}
+	if managed == nil {
+		latest, err := m.Client.BlockNumber(ctx)
+		if err != nil {
+			cancel()
+			return nil, errors.Wrap(err, "failed to get latest block number")
+		}
+		sub.Polling = true
+		go m.pollLogs(ctx, q, latest+1, logs)
+	} else {
+		go func() {
+			select {
+			case <-ctx.Done():
+			case err := <-managed.Err():
+				sub.err <- err
+				cancel()
+			}
+			managed.Unsubscribe()
+		}()
+	}
+
+	events := make(chan DecodedTransactionLog, DefaultEventSubscriptionBufferSize)
+	go func() {
+		defer close(events)
+		for {
+			var lo types.Log
+			select {
+			case <-ctx.Done():
+				return
+			case lo = <-logs:
+			}
+			decoded, err := m.decodeContractLogs(m.logger().With().Logger(), []types.Log{lo}, *contractABI)
+			if err != nil || len(decoded) == 0 {
+				m.logger().Warn().Err(err).Str("TxHash", lo.TxHash.Hex()).Uint("Index", lo.Index).Msg("Failed to decode contract event")
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case events <- decoded[0]:
+			}
+		}
+	}()
+	go func() {
+		defer close(sub.done)
+		for event := range events {
+			if ctx.Err() != nil {
+				return
+			}
+			handler(event)
+		}
+	}()
+
+	m.logger().Info().
+		Str("Contract", name).
+		Str("Event", event.Sig).
+		Int("Addresses", len(addresses)).
+		Bool("Polling", sub.Polling).
+		Msg("Subscribed to contract events")
+
+	return sub, nil
+}
+
+// contractEventFilter returns ABI of the contract, its event with the name (or signature) and all addresses of the contract
+// from contract map
+func (m *Client) contractEventFilter(contractName, eventName string) (*abi.ABI, abi.Event, []common.Address, error) {
+	contractABI, ok := m.ContractStore.GetABI(contractName)
+	if !ok {
+		return nil, abi.Event{}, nil, errors.Wrapf(ErrNoABIForContract, "contract: %s", contractName)
+	}
+	event, ok := findEvent(*contractABI, eventName)
+	if !ok {
+		return nil, abi.Event{}, nil, errors.Wrapf(ErrUnknownContractEvent, "event: '%s', contract: '%s'", eventName, contractName)
+	}
+	name := strings.TrimSuffix(contractName, ".abi")
+	var addresses []common.Address
+	m.ContractAddressToNameMap.Range(func(address, contract string) bool {
+		if contract == name {
+			addresses = append(addresses, common.HexToAddress(address))
+		}
+		return true
+	})
+	if len(addresses) == 0 {
+		return nil, abi.Event{}, nil, errors.Wrapf(ErrNoContractAddresses, "contract: '%s'", name)
+	}
+	return contractABI, event, addresses, nil
+}
+
+// findEvent finds event in the ABI by its name or signature
+func findEvent(a abi.ABI, name string) (abi.Event, bool) {
+	for _, event := range a.Events {
+		if event.Name == name || event.RawName == name || event.Sig == name {
+			return event, true
+		}
+	}
+	return abi.Event{}, false
+}
+
+// polledBlock is a block, whose logs were delivered by pollLogs
+type polledBlock struct {
+	number uint64
+	hash   common.Hash
+	logs   []types.Log
+}
+
+// pollLogs polls the node for logs matching the query starting with the block until context is done. Hashes of latest
+// polled blocks are remembered and, if any of them changes, logs from reorged blocks are sent again as removed and
+// blocks after the common ancestor are polled again.
+func (m *Client) pollLogs(ctx context.Context, q ethereum.FilterQuery, from uint64, ch chan<- types.Log) {
+	var blocks []*polledBlock
+	next := from
+	send := func(lo types.Log) bool {
+		select {
+		case ch <- lo:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		if ancestor, reorged := m.findReorgAncestor(ctx, blocks); reorged {
+			m.logger().Warn().Uint64("Common ancestor", ancestor).Msg("Chain reorg detected, while polling logs")
+			for len(blocks) > 0 && blocks[len(blocks)-1].number > ancestor {
+				removed := blocks[len(blocks)-1]
+				blocks = blocks[:len(blocks)-1]
+				for i := len(removed.logs) - 1; i >= 0; i-- {
+					lo := removed.logs[i]
+					lo.Removed = true
+					if !send(lo) {
+						return
+					}
+				}
+			}
+			next = ancestor + 1
+		}
+
+		latest, err := m.Client.HeaderByNumber(ctx, nil)
+		if err == nil && latest.Number.Uint64() >= next {
+			q.FromBlock = new(big.Int).SetUint64(next)
+			q.ToBlock = latest.Number
+			var logs []types.Log
+			logs, err = m.Client.FilterLogs(ctx, q)
+			if err == nil {
+				byBlock := make(map[uint64]*polledBlock)
+				for _, lo := range logs {
+					block, ok := byBlock[lo.BlockNumber]
+					if !ok {
+						block = &polledBlock{number: lo.BlockNumber, hash: lo.BlockHash}
+						byBlock[lo.BlockNumber] = block
+						blocks = append(blocks, block)
+					}
+					block.logs = append(block.logs, lo)
+					if !send(lo) {
+						return
+					}
+				}
+				if _, ok := byBlock[latest.Number.Uint64()]; !ok {
+					blocks = append(blocks, &polledBlock{number: latest.Number.Uint64(), hash: latest.Hash()})
+				}
+				// keep only blocks, that can still be reorged
+				for len(blocks) > 0 && blocks[0].number+eventPollingReorgDepth < latest.Number.Uint64() {
+					blocks = blocks[1:]
+				}
+				next = latest.Number.Uint64() + 1
+			}
+		}
+		if err != nil && ctx.Err() == nil {
+			m.logger().Debug().Err(err).Msg("Failed to poll logs")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.Cfg.Network.ReceiptPollingDelay(0)):
+		}
+	}
+}
+
+// findReorgAncestor checks if the latest polled block is still canonical and if it isn't, it returns number of the latest
+// polled block, that still is
+func (m *Client) findReorgAncestor(ctx context.Context, blocks []*polledBlock) (uint64, bool) {
+	if len(blocks) == 0 {
+		return 0, false
+	}
+	for i := len(blocks) - 1; i >= 0; i-- {
+		header, err := m.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(blocks[i].number))
+		if err != nil {
+			// can't tell, try again with the next poll
+			return 0, false
+		}
+		if header.Hash() == blocks[i].hash {
+			return blocks[i].number, i != len(blocks)-1
+		}
+	}
+	if blocks[0].number == 0 {
+		return 0, true
+	}
+	return blocks[0].number - 1, true
+}
@@ -0,0 +1,76 @@
+package seth_test
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPISubscribeContractEvents(t *testing.T) {
+	for _, polling := range []bool{false, true} {
+		t.Run(fmt.Sprintf("polling %v", polling), func(t *testing.T) {
+			cfg, err := seth.ReadConfig()
+			require.NoError(t, err, "failed to read config")
+			if polling {
+				cfg.Subscriptions = &seth.SubscriptionsCfg{Disabled: true}
+			}
+			c, err := seth.NewClientWithConfig(cfg)
+			require.NoError(t, err, "failed to create client")
+			c.ContractAddressToNameMap.AddContract(TestEnv.DebugContractAddress.Hex(), "NetworkDebugContract")
+
+			events := make(chan seth.DecodedTransactionLog, 10)
+			sub, err := c.SubscribeContractEvents(context.Background(), "NetworkDebugContract", "OneIndexEvent", func(event seth.DecodedTransactionLog) {
+				// slow handler shouldn't cause events to be dropped
+				time.Sleep(200 * time.Millisecond)
+				events <- event
+			})
+			require.NoError(t, err, "failed to subscribe to events")
+			defer sub.Unsubscribe()
+			require.Equal(t, polling || !seth.IsWebsocketURL(c.URL), sub.Polling, "incorrect subscription mode")
+
+			var hashes []string
+			for i := 0; i < 3; i++ {
+				// other events of the contract aren't delivered
+				_, err = c.Decode(TestEnv.DebugContract.EmitNoIndexEvent(c.NewTXOpts()))
+				require.NoError(t, err, "failed to emit event")
+				decoded, err := c.Decode(TestEnv.DebugContract.EmitOneIndexEvent(c.NewTXOpts()))
+				require.NoError(t, err, "failed to emit event")
+				hashes = append(hashes, decoded.Hash)
+			}
+
+			for _, hash := range hashes {
+				select {
+				case event := <-events:
+					require.Equal(t, hash, event.TXHash, "events should be delivered in order")
+					require.Equal(t, "OneIndexEvent(uint256)", event.Signature, "incorrect event")
+					require.Equal(t, big.NewInt(83), event.EventData["a"], "incorrect event data")
+					require.Equal(t, TestEnv.DebugContractAddress, event.Address, "incorrect address")
+					require.False(t, event.Removed, "event should not be removed")
+				case <-time.After(30 * time.Second):
+					t.Fatal("event wasn't delivered")
+				}
+			}
+			sub.Unsubscribe()
+			require.Empty(t, events, "each event should be delivered once")
+		})
+	}
+}
+
+func TestAPISubscribeContractEventsErrors(t *testing.T) {
+	c := newClient(t)
+
+	_, err := c.SubscribeContractEvents(context.Background(), "Unknown", "OneIndexEvent", func(seth.DecodedTransactionLog) {})
+	require.ErrorIs(t, err, seth.ErrNoABIForContract, "unknown contract should be rejected")
+
+	_, err = c.SubscribeContractEvents(context.Background(), "NetworkDebugContract", "Unknown", func(seth.DecodedTransactionLog) {})
+	require.ErrorIs(t, err, seth.ErrUnknownContractEvent, "unknown event should be rejected")
+
+	c.ContractAddressToNameMap = seth.NewEmptyContractMap()
+	_, err = c.SubscribeContractEvents(context.Background(), "NetworkDebugContract", "OneIndexEvent", func(seth.DecodedTransactionLog) {})
+	require.ErrorIs(t, err, seth.ErrNoContractAddresses, "contract without address should be rejected")
+}
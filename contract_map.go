@@ -10,6 +10,8 @@ import (
 	"github.com/pelletier/go-toml/v2"
 )
 
+// ContractMap maps contract addresses to names of their ABIs. It's safe for concurrent use, with exception of the map
+// returned by GetContractMap().
 type ContractMap struct {
 	mu         *sync.RWMutex
 	addressMap map[string]string
@@ -29,19 +31,20 @@ func NewContractMap(contracts map[string]string) ContractMap {
 	}
 }
 
+// GetContractMap returns the underlying map, it's not safe to iterate over it while other goroutines add contracts, use Snapshot() instead
 func (c ContractMap) GetContractMap() map[string]string {
 	return c.addressMap
 }
 
 func (c ContractMap) IsKnownAddress(addr string) bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.addressMap[strings.ToLower(addr)] != ""
 }
 
 func (c ContractMap) GetContractName(addr string) string {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.addressMap[strings.ToLower(addr)]
 }
 
@@ -50,8 +53,8 @@ func (c ContractMap) GetContractAddress(addr string) string {
 		return UNKNOWN
 	}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	for k, v := range c.addressMap {
 		if v == addr {
 			return k
@@ -71,7 +74,63 @@ func (c ContractMap) AddContract(addr, name string) {
 	c.addressMap[strings.ToLower(addr)] = name
 }
 
+// AddContracts adds all address -> name mappings at once
+func (c ContractMap) AddContracts(contracts map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for addr, name := range contracts {
+		if addr == UNKNOWN {
+			continue
+		}
+		c.addressMap[strings.ToLower(addr)] = strings.TrimSuffix(name, ".abi")
+	}
+}
+
+// RemoveContract removes contract with given address from the map
+func (c ContractMap) RemoveContract(addr string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.addressMap, strings.ToLower(addr))
+}
+
+// Snapshot returns a copy of the map, that can be iterated over while other goroutines add contracts
+func (c ContractMap) Snapshot() map[string]string {
+	snapshot := make(map[string]string)
+	if c.mu == nil {
+		return snapshot
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for k, v := range c.addressMap {
+		snapshot[k] = v
+	}
+	return snapshot
+}
+
+// Restore replaces content of the map with the snapshot (see Snapshot), all copies of the map see the change
+func (c ContractMap) Restore(snapshot map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.addressMap {
+		delete(c.addressMap, k)
+	}
+	for k, v := range snapshot {
+		c.addressMap[k] = v
+	}
+}
+
+// Range calls fn for each address and contract name until it returns false. It iterates over a copy of the map, so fn can modify it.
+func (c ContractMap) Range(fn func(address, name string) bool) {
+	for address, name := range c.Snapshot() {
+		if !fn(address, name) {
+			return
+		}
+	}
+}
+
 func (c ContractMap) Size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return len(c.addressMap)
 }
 
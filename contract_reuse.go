@@ -0,0 +1,166 @@
+package seth
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ContractReuse_AlwaysDeploy deploys a new instance of the contract every time (default)
+	ContractReuse_AlwaysDeploy = "always_deploy"
+	// ContractReuse_ReuseIfExists reuses contract from the contract map, if its code is compatible with the bytecode
+	ContractReuse_ReuseIfExists = "reuse_if_exists"
+)
+
+var (
+	ErrInvalidContractReusePolicy = errors.New("invalid contract reuse policy")
+)
+
+// ContractReuseCfg configures whether contracts deployed in previous runs (and saved to the contract map) are reused
+// instead of being deployed again
+type ContractReuseCfg struct {
+	// Default policy, either "always_deploy" (default) or "reuse_if_exists"
+	Default string `toml:"default"`
+	// Contracts overrides default policy per contract name
+	Contracts map[string]string `toml:"contracts"`
+}
+
+// Policy returns reuse policy of the contract
+func (c *ContractReuseCfg) Policy(name string) string {
+	if c == nil {
+		return ContractReuse_AlwaysDeploy
+	}
+	if policy, ok := c.Contracts[strings.TrimSuffix(name, ".abi")]; ok {
+		return policy
+	}
+	if c.Default != "" {
+		return c.Default
+	}
+	return ContractReuse_AlwaysDeploy
+}
+
+func validateContractReuseCfg(cfg *ContractReuseCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	validate := func(name, policy string) error {
+		if policy != ContractReuse_AlwaysDeploy && policy != ContractReuse_ReuseIfExists {
+			return errors.Wrapf(ErrInvalidContractReusePolicy, "policy '%s' for '%s', valid ones are: %s, %s", policy, name, ContractReuse_AlwaysDeploy, ContractReuse_ReuseIfExists)
+		}
+		return nil
+	}
+	if cfg.Default != "" {
+		if err := validate("default", cfg.Default); err != nil {
+			return err
+		}
+	}
+	for name, policy := range cfg.Contracts {
+		if err := validate(name, policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contractReuseKey is the context key of reuse policy set with transaction options
+type contractReuseKey struct{}
+
+// WithSkipIfDeployed overrides contract reuse policy of a single deployment. If skip is true, DeployContract returns
+// contract from the contract map, whose on-chain code is compatible with the bytecode, instead of deploying it again.
+// If it's false, contract is always deployed.
+func WithSkipIfDeployed(skip bool) TransactOpt {
+	return func(o *bind.TransactOpts) {
+		policy := ContractReuse_AlwaysDeploy
+		if skip {
+			policy = ContractReuse_ReuseIfExists
+		}
+		o.Context = context.WithValue(contextOrBackground(o.Context), contractReuseKey{}, policy)
+	}
+}
+
+// contractReusePolicy returns reuse policy of the contract set with transaction options or in config
+func (m *Client) contractReusePolicy(auth *bind.TransactOpts, name string) string {
+	if policy, ok := contextOrBackground(auth.Context).Value(contractReuseKey{}).(string); ok {
+		return policy
+	}
+	return m.Cfg.ContractReuse.Policy(name)
+}
+
+// findReusableContract returns address of a contract with given name from the contract map, whose code is compatible
+// with the bytecode, if reuse is enabled for the contract. Constructor parameters can't be verified.
+func (m *Client) findReusableContract(auth *bind.TransactOpts, name string, bytecode []byte) (common.Address, bool) {
+	if m.contractReusePolicy(auth, name) != ContractReuse_ReuseIfExists {
+		return common.Address{}, false
+	}
+
+	var candidates []string
+	m.ContractAddressToNameMap.Range(func(address, contractName string) bool {
+		if strings.TrimSuffix(contractName, ".abi") == strings.TrimSuffix(name, ".abi") {
+			candidates = append(candidates, address)
+		}
+		return true
+	})
+	sort.Strings(candidates)
+
+	for _, candidate := range candidates {
+		address := common.HexToAddress(candidate)
+		ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
+		code, err := m.Client.CodeAt(ctx, address, nil)
+		cancel()
+		if err != nil {
+			m.logger().Debug().Err(err).Str("Address", address.Hex()).Msg("Failed to get code of contract, that could be reused")
+			continue
+		}
+		if !IsRuntimeCodeCompatible(bytecode, code) {
+			m.logger().Debug().Str("Address", address.Hex()).Msgf("Code of %s contract isn't compatible with its bytecode, it won't be reused", name)
+			continue
+		}
+		return address, true
+	}
+
+	return common.Address{}, false
+}
+
+// reuseContract returns deployment data of already deployed contract
+func (m *Client) reuseContract(name string, contractABI abi.ABI, address common.Address) DeploymentData {
+	m.logger().Info().
+		Str("Address", address.Hex()).
+		Msgf("Reusing already deployed %s contract", name)
+
+	if _, ok := m.ContractStore.GetABI(name); !ok {
+		m.ContractStore.AddABI(name, contractABI)
+	}
+
+	return DeploymentData{
+		Address:       address,
+		BoundContract: bind.NewBoundContract(address, contractABI, m.Client, m.Client, m.Client),
+		Reused:        true,
+	}
+}
+
+// IsRuntimeCodeCompatible returns true if code deployed on-chain could have been created with the creation bytecode.
+// Runtime code is embedded at the end of creation bytecode, where immutable variables are zero placeholders, so they
+// are ignored. Empty code is never compatible.
+func IsRuntimeCodeCompatible(creationBytecode, runtimeCode []byte) bool {
+	if len(runtimeCode) == 0 || len(creationBytecode) < len(runtimeCode) {
+		return false
+	}
+	if bytes.Contains(creationBytecode, runtimeCode) {
+		return true
+	}
+
+	embedded := creationBytecode[len(creationBytecode)-len(runtimeCode):]
+	for i := range runtimeCode {
+		if embedded[i] != runtimeCode[i] && embedded[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package seth_test
+
+import (
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIContractReuse(t *testing.T) {
+	c := newClient(t)
+	c.Cfg.ContractReuse = &seth.ContractReuseCfg{
+		Default:   seth.ContractReuse_AlwaysDeploy,
+		Contracts: map[string]string{"NetworkDebugSubContract": seth.ContractReuse_ReuseIfExists},
+	}
+
+	first, err := c.DeployContractFromContractStore(c.NewTXOpts(), "NetworkDebugSubContract")
+	require.NoError(t, err, "failed to deploy contract")
+
+	t.Run("compatible contract is reused", func(t *testing.T) {
+		data, err := c.DeployContractFromContractStore(c.NewTXOpts(), "NetworkDebugSubContract")
+		require.NoError(t, err, "failed to reuse contract")
+		require.True(t, data.Reused, "contract should be reused")
+		require.Nil(t, data.Transaction, "no transaction should be sent")
+		require.Equal(t, first.Address, data.Address, "contract from contract map should be reused")
+		require.NotNil(t, data.BoundContract, "reused contract should be bound")
+	})
+
+	t.Run("contract with always_deploy policy is deployed", func(t *testing.T) {
+		data, err := c.DeployContractFromContractStore(c.NewTXOpts(), "NetworkDebugContract", first.Address)
+		require.NoError(t, err, "failed to deploy contract")
+		require.False(t, data.Reused, "contract should not be reused")
+
+		again, err := c.DeployContractFromContractStore(c.NewTXOpts(), "NetworkDebugContract", first.Address)
+		require.NoError(t, err, "failed to deploy contract")
+		require.False(t, again.Reused, "contract should not be reused")
+		require.NotEqual(t, data.Address, again.Address, "new contract should be deployed")
+	})
+
+	t.Run("policy is overridden with transaction options", func(t *testing.T) {
+		data, err := c.DeployContractFromContractStore(c.NewTXOpts(seth.WithSkipIfDeployed(false)), "NetworkDebugSubContract")
+		require.NoError(t, err, "failed to deploy contract")
+		require.False(t, data.Reused, "contract should be deployed")
+		require.NotEqual(t, first.Address, data.Address, "new contract should be deployed")
+
+		_, err = c.DeployContractFromContractStore(c.NewTXOpts(), "NetworkDebugContract", first.Address)
+		require.NoError(t, err, "failed to deploy contract")
+		skipped, err := c.DeployContractFromContractStore(c.NewTXOpts(seth.WithSkipIfDeployed(true)), "NetworkDebugContract", first.Address)
+		require.NoError(t, err, "failed to reuse contract")
+		require.True(t, skipped.Reused, "contract should be reused")
+		require.Nil(t, skipped.Transaction, "no transaction should be sent")
+		require.Equal(t, "NetworkDebugContract", c.ContractAddressToNameMap.GetContractName(skipped.Address.Hex()), "contract from contract map should be reused")
+	})
+
+	t.Run("contract with incompatible code is not reused", func(t *testing.T) {
+		fresh := newClient(t)
+		fresh.Cfg.ContractReuse = &seth.ContractReuseCfg{Default: seth.ContractReuse_ReuseIfExists}
+		fresh.ContractAddressToNameMap.AddContract(TestEnv.DebugContractAddress.Hex(), "NetworkDebugSubContract")
+
+		data, err := fresh.DeployContractFromContractStore(fresh.NewTXOpts(), "NetworkDebugSubContract")
+		require.NoError(t, err, "failed to deploy contract")
+		require.False(t, data.Reused, "contract should not be reused")
+		require.NotEqual(t, TestEnv.DebugContractAddress, data.Address, "new contract should be deployed")
+	})
+}
+
+func TestUtilRuntimeCodeCompatibility(t *testing.T) {
+	runtime := []byte{0x60, 0x80, 0x7f, 0x11, 0x22, 0x00, 0x01}
+	creation := append([]byte{0x60, 0x80, 0x60, 0x40}, runtime...)
+	require.True(t, seth.IsRuntimeCodeCompatible(creation, runtime), "embedded runtime code should be compatible")
+
+	// immutables are zero placeholders in creation bytecode
+	withImmutable := append([]byte{0x60, 0x80, 0x60, 0x40}, 0x60, 0x80, 0x7f, 0x00, 0x00, 0x00, 0x01)
+	require.True(t, seth.IsRuntimeCodeCompatible(withImmutable, runtime), "code with immutables should be compatible")
+
+	require.False(t, seth.IsRuntimeCodeCompatible(creation, []byte{0x60, 0x81}), "different code should not be compatible")
+	require.False(t, seth.IsRuntimeCodeCompatible(creation, nil), "empty code should not be compatible")
+}
+
+func TestConfigContractReuseValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.ContractReuse = &seth.ContractReuseCfg{Contracts: map[string]string{"LinkToken": "sometimes"}}
+	require.EqualError(t, seth.ValidateConfig(cfg), "policy 'sometimes' for 'LinkToken', valid ones are: always_deploy, reuse_if_exists: invalid contract reuse policy")
+}
@@ -1,7 +1,10 @@
 package seth
 
 import (
+	"bytes"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -11,17 +14,30 @@ import (
 	"github.com/pkg/errors"
 )
 
-const (
-	ErrOpenABIFile = "failed to open ABI file"
-	ErrParseABI    = "failed to parse ABI file"
-	ErrOpenBINFile = "failed to open BIN file"
+var (
+	ErrOpenABIFile = errors.New("failed to open ABI file")
+	ErrParseABI    = errors.New("failed to parse ABI file")
+	ErrOpenBINFile = errors.New("failed to open BIN file")
 )
 
-// ContractStore contains all ABIs that are used in decoding. It might also contain contract bytecode for deployment
+// ContractStore contains all ABIs that are used in decoding. It might also contain contract bytecode for deployment.
+// It's safe for concurrent use, as long as ABIs and BINs are accessed only via its methods.
 type ContractStore struct {
 	ABIs ABIStore
 	BINs map[string][]byte
 	mu   *sync.RWMutex
+	// fileChecksums are SHA-256 checksums of ABI and BIN files loaded from disk
+	fileChecksums map[string]string
+	// verifiedBINs are checksums of bytecodes from BIN files verified against checksum manifest, nil if integrity mode is disabled
+	verifiedBINs map[string]string
+	// sourceMaps are runtime source maps keyed by contract name
+	sourceMaps map[string]*SourceMap
+	// unlinkedBINs are bytecodes with library placeholders, they are not available with GetBIN()
+	unlinkedBINs map[string]*UnlinkedBytecode
+	// artifacts are Foundry and Hardhat artifacts keyed by contract name
+	artifacts map[string]*ContractArtifact
+	// artifactBINs map bytecodes loaded from artifacts to artifact file names
+	artifactBINs map[string]string
 }
 
 type ABIStore map[string]abi.ABI
@@ -31,8 +47,8 @@ func (c *ContractStore) GetABI(name string) (*abi.ABI, bool) {
 		name = name + ".abi"
 	}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
 	abi, ok := c.ABIs[name]
 	return &abi, ok
@@ -54,8 +70,8 @@ func (c *ContractStore) GetBIN(name string) ([]byte, bool) {
 		name = name + ".bin"
 	}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
 	bin, ok := c.BINs[name]
 	return bin, ok
@@ -72,12 +88,141 @@ func (c *ContractStore) AddBIN(name string, bin []byte) {
 	c.BINs[name] = bin
 }
 
-// NewContractStore creates a new Contract store
-func NewContractStore(abiPath, binPath string) (*ContractStore, error) {
-	cs := &ContractStore{ABIs: make(ABIStore), BINs: make(map[string][]byte), mu: &sync.RWMutex{}}
+// GetSourceMap returns runtime source map of the contract, if it was loaded
+// GetUnlinkedBIN returns bytecode with library placeholders
+func (c *ContractStore) GetUnlinkedBIN(name string) (*UnlinkedBytecode, bool) {
+	if !strings.HasSuffix(name, ".bin") {
+		name = name + ".bin"
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	bin, ok := c.unlinkedBINs[name]
+	return bin, ok
+}
+
+// AddUnlinkedBIN adds bytecode with library placeholders, which is linked during deployment
+func (c *ContractStore) AddUnlinkedBIN(name string, bin *UnlinkedBytecode) {
+	if !strings.HasSuffix(name, ".bin") {
+		name = name + ".bin"
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.unlinkedBINs == nil {
+		c.unlinkedBINs = make(map[string]*UnlinkedBytecode)
+	}
+	c.unlinkedBINs[name] = bin
+}
+
+func (c *ContractStore) GetSourceMap(name string) (*SourceMap, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	sm, ok := c.sourceMaps[strings.TrimSuffix(name, ".abi")]
+	return sm, ok
+}
+
+// AddSourceMap adds runtime source map of the contract
+func (c *ContractStore) AddSourceMap(name string, sm *SourceMap) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sourceMaps == nil {
+		c.sourceMaps = make(map[string]*SourceMap)
+	}
+	c.sourceMaps[strings.TrimSuffix(name, ".abi")] = sm
+}
+
+// HasSourceMaps returns true if any source map was loaded
+func (c *ContractStore) HasSourceMaps() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.sourceMaps) > 0
+}
+
+// AddABIs adds all ABIs at once, names without ".abi" suffix get it appended
+func (c *ContractStore) AddABIs(abis map[string]abi.ABI) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, a := range abis {
+		if !strings.HasSuffix(name, ".abi") {
+			name = name + ".abi"
+		}
+		c.ABIs[name] = a
+	}
+}
+
+// AddBINs adds all bytecodes at once, names without ".bin" suffix get it appended
+func (c *ContractStore) AddBINs(bins map[string][]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, bin := range bins {
+		if !strings.HasSuffix(name, ".bin") {
+			name = name + ".bin"
+		}
+		c.BINs[name] = bin
+	}
+}
+
+// RemoveABI removes ABI with given name
+func (c *ContractStore) RemoveABI(name string) {
+	if !strings.HasSuffix(name, ".abi") {
+		name = name + ".abi"
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.ABIs, name)
+}
+
+// ListABIs returns a copy of all ABIs, that can be iterated over while other goroutines modify the store
+func (c *ContractStore) ListABIs() ABIStore {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	abis := make(ABIStore, len(c.ABIs))
+	for name, a := range c.ABIs {
+		abis[name] = a
+	}
+	return abis
+}
+
+// RangeABIs calls fn for each ABI until it returns false. It iterates over a copy of the store, so fn can modify it.
+func (c *ContractStore) RangeABIs(fn func(name string, a abi.ABI) bool) {
+	for name, a := range c.ListABIs() {
+		if !fn(name, a) {
+			return
+		}
+	}
+}
+
+// NewContractStore creates a new Contract store. ABIs and bytecodes are loaded from '.abi' and '.bin' files and from
+// Foundry or Hardhat artifacts found in artifactDirs, whose format is detected automatically.
+func NewContractStore(abiPath, binPath string, artifactDirs ...string) (*ContractStore, error) {
+	return NewContractStoreFromFS(osFS{}, abiPath, binPath, artifactDirs...)
+}
+
+// NewContractStoreFromFS creates a new Contract store from files in the filesystem (e.g. embed.FS), so that ABIs and BINs
+// can be embedded in the test binary. Paths are slash-separated and relative to the root of the filesystem, files are
+// loaded the same way as by NewContractStore().
+func NewContractStoreFromFS(fsys fs.FS, abiPath, binPath string, artifactDirs ...string) (*ContractStore, error) {
+	cs := &ContractStore{ABIs: make(ABIStore), BINs: make(map[string][]byte), mu: &sync.RWMutex{}, fileChecksums: make(map[string]string), sourceMaps: make(map[string]*SourceMap), unlinkedBINs: make(map[string]*UnlinkedBytecode), artifacts: make(map[string]*ContractArtifact), artifactBINs: make(map[string]string)}
+
+	for _, dir := range artifactDirs {
+		if err := cs.loadArtifacts(fsys, dir); err != nil {
+			return nil, err
+		}
+	}
 
 	if abiPath != "" {
-		files, err := os.ReadDir(abiPath)
+		files, err := fs.ReadDir(fsys, abiPath)
 		if err != nil {
 			return nil, err
 		}
@@ -85,15 +230,16 @@ func NewContractStore(abiPath, binPath string) (*ContractStore, error) {
 		for _, f := range files {
 			if strings.HasSuffix(f.Name(), ".abi") {
 				L.Debug().Str("File", f.Name()).Msg("ABI file loaded")
-				ff, err := os.Open(filepath.Join(abiPath, f.Name()))
+				data, err := fs.ReadFile(fsys, path.Join(abiPath, f.Name()))
 				if err != nil {
-					return nil, errors.Wrap(err, ErrOpenABIFile)
+					return nil, wrapError(err, ErrOpenABIFile)
 				}
-				a, err := abi.JSON(ff)
+				a, err := abi.JSON(bytes.NewReader(data))
 				if err != nil {
-					return nil, errors.Wrap(err, ErrParseABI)
+					return nil, wrapError(err, ErrParseABI)
 				}
 				cs.ABIs[f.Name()] = a
+				cs.fileChecksums[f.Name()] = checksum(data)
 				foundABI = true
 			}
 		}
@@ -104,7 +250,7 @@ func NewContractStore(abiPath, binPath string) (*ContractStore, error) {
 	}
 
 	if binPath != "" {
-		files, err := os.ReadDir(binPath)
+		files, err := fs.ReadDir(fsys, binPath)
 		if err != nil {
 			return nil, err
 		}
@@ -112,13 +258,33 @@ func NewContractStore(abiPath, binPath string) (*ContractStore, error) {
 		for _, f := range files {
 			if strings.HasSuffix(f.Name(), ".bin") {
 				L.Debug().Str("File", f.Name()).Msg("BIN file loaded")
-				bin, err := os.ReadFile(filepath.Join(binPath, f.Name()))
+				bin, err := fs.ReadFile(fsys, path.Join(binPath, f.Name()))
 				if err != nil {
-					return nil, errors.Wrap(err, ErrOpenBINFile)
+					return nil, wrapError(err, ErrOpenBINFile)
+				}
+				if IsUnlinkedBytecode(string(bin)) {
+					unlinked, err := ParseUnlinkedBytecode(string(bin))
+					if err != nil {
+						return nil, errors.Wrapf(err, "failed to parse BIN file %s", f.Name())
+					}
+					cs.unlinkedBINs[f.Name()] = unlinked
+				} else {
+					cs.BINs[f.Name()] = common.FromHex(string(bin))
 				}
-				cs.BINs[f.Name()] = common.FromHex(string(bin))
+				cs.fileChecksums[f.Name()] = checksum(bin)
+				delete(cs.artifactBINs, f.Name())
 				foundBIN = true
 			}
+			if strings.HasSuffix(f.Name(), SourceMapFileSuffix) {
+				sourceMaps, err := loadSourceMaps(fsys, path.Join(binPath, f.Name()))
+				if err != nil {
+					return nil, err
+				}
+				for name, sm := range sourceMaps {
+					L.Debug().Str("File", f.Name()).Str("Contract", name).Msg("Source map loaded")
+					cs.sourceMaps[name] = sm
+				}
+			}
 		}
 		if !foundBIN {
 			L.Warn().Msg("No BIN files found")
@@ -128,3 +294,23 @@ func NewContractStore(abiPath, binPath string) (*ContractStore, error) {
 
 	return cs, nil
 }
+
+// osFS is a filesystem reading files from disk with paths used as they are (absolute or relative to working directory),
+// unlike os.DirFS(), which doesn't allow paths outside its root
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(filepath.FromSlash(name))
+}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(filepath.FromSlash(name))
+}
+
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.FromSlash(name))
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(filepath.FromSlash(name))
+}
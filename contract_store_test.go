@@ -1,13 +1,21 @@
 package seth_test
 
 import (
+	"embed"
+	"fmt"
+	"sync"
 	"testing"
+	"testing/fstest"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/pkg/errors"
 	"github.com/smartcontractkit/seth"
 	"github.com/stretchr/testify/require"
 )
 
+//go:embed contracts/abi contracts/bin
+var embeddedContracts embed.FS
+
 func TestSmokeContractABIStore(t *testing.T) {
 
 	type test struct {
@@ -101,3 +109,69 @@ func TestSmokeContractBINStore(t *testing.T) {
 		})
 	}
 }
+
+func TestSmokeContractStoreConcurrentAccess(t *testing.T) {
+	cs, err := seth.NewContractStore("./contracts/abi", "./contracts/bin")
+	require.NoError(t, err, "failed to create contract store")
+	initial := cs.ListABIs()
+	require.NotEmpty(t, initial, "ABIs should not be empty")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			abis := make(map[string]abi.ABI)
+			bins := make(map[string][]byte)
+			for j := 0; j < 10; j++ {
+				name := fmt.Sprintf("Contract_%d_%d", i, j)
+				abis[name] = abi.ABI{}
+				bins[name] = []byte{byte(j)}
+			}
+			cs.AddABIs(abis)
+			cs.AddBINs(bins)
+			cs.AddABI(fmt.Sprintf("Single_%d", i), abi.ABI{})
+		}(i)
+		go func() {
+			defer wg.Done()
+			cs.RangeABIs(func(name string, _ abi.ABI) bool {
+				_, ok := cs.GetABI(name)
+				require.True(t, ok, "ABI from range should be in the store")
+				return true
+			})
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, cs.ListABIs(), len(initial)+110, "incorrect number of ABIs")
+	_, ok := cs.GetABI("Contract_3_7.abi")
+	require.True(t, ok, "ABI added in bulk should be found with suffix")
+	bin, ok := cs.GetBIN("Contract_3_7")
+	require.True(t, ok, "BIN added in bulk should be found")
+	require.Equal(t, []byte{7}, bin, "incorrect BIN")
+
+	cs.RemoveABI("Contract_3_7")
+	_, ok = cs.GetABI("Contract_3_7")
+	require.False(t, ok, "removed ABI should not be found")
+}
+
+func TestSmokeContractStoreFromFS(t *testing.T) {
+	cs, err := seth.NewContractStoreFromFS(embeddedContracts, "contracts/abi", "contracts/bin")
+	require.NoError(t, err, "failed to create contract store from embedded files")
+	_, ok := cs.GetABI("NetworkDebugContract")
+	require.True(t, ok, "embedded ABI should be loaded")
+	_, ok = cs.GetBIN("NetworkDebugContract")
+	require.True(t, ok, "embedded BIN should be loaded")
+
+	fsys := fstest.MapFS{
+		"out/Lib.sol/Lib.json": &fstest.MapFile{Data: []byte(`{"abi": [], "bytecode": {"object": "0x6001"}}`)},
+	}
+	cs, err = seth.NewContractStoreFromFS(fsys, "", "", "out")
+	require.NoError(t, err, "failed to create contract store from artifacts")
+	bin, ok := cs.GetBIN("Lib")
+	require.True(t, ok, "artifact should be loaded")
+	require.Equal(t, []byte{0x60, 0x01}, bin, "incorrect bytecode")
+
+	_, err = seth.NewContractStoreFromFS(fsys, "abi", "")
+	require.Error(t, err, "missing directory should be reported")
+}
@@ -0,0 +1,164 @@
+package seth
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Create2FactoryAddress is the address of deterministic deployment proxy (https://github.com/Arachnid/deterministic-deployment-proxy)
+	// deployed on most of the chains. It deploys init code passed after 32-byte salt with CREATE2 and returns address of the contract.
+	Create2FactoryAddress = "0x4e59b44847b379578588920cA78FbF26c0B4956C"
+	// Create2FactoryBytecode is creation bytecode of deterministic deployment proxy, it can be deployed with DeployCreate2Factory()
+	// on networks, which don't have it
+	Create2FactoryBytecode = "0x604580600e600039806000f350fe7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe03601600081602082378035828234f58015156039578182fd5b8082525050506014600cf3"
+)
+
+var (
+	ErrNoCreate2Factory       = errors.New("no CREATE2 factory deployed")
+	ErrCreate2AddressOccupied = errors.New("CREATE2 address is already occupied by a contract with different code")
+)
+
+// Create2Salt returns salt derived from a human-readable string (keccak256 of it)
+func Create2Salt(s string) [32]byte {
+	return crypto.Keccak256Hash([]byte(s))
+}
+
+// PredictCreate2Address returns address of the contract deployed by the factory with salt and init code (bytecode followed
+// by ABI encoded constructor parameters)
+func PredictCreate2Address(factory common.Address, salt [32]byte, initCode []byte) common.Address {
+	return crypto.CreateAddress2(factory, salt, crypto.Keccak256(initCode))
+}
+
+// Create2Factory returns address of CREATE2 factory used by the client, either network's 'create2_factory' or canonical one
+func (m *Client) Create2Factory() common.Address {
+	if m.Cfg.Network.Create2Factory != "" {
+		return common.HexToAddress(m.Cfg.Network.Create2Factory)
+	}
+	return common.HexToAddress(Create2FactoryAddress)
+}
+
+// PredictContractAddress returns address, at which DeployContractWithSalt() would deploy the contract with given salt and
+// constructor parameters
+func (m *Client) PredictContractAddress(contractABI abi.ABI, bytecode []byte, salt [32]byte, params ...interface{}) (common.Address, error) {
+	initCode, err := create2InitCode(contractABI, bytecode, params...)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return PredictCreate2Address(m.Create2Factory(), salt, initCode), nil
+}
+
+// DeployCreate2Factory deploys deterministic deployment proxy with a regular transaction. Its address isn't the canonical one,
+// so it has to be set as network's 'create2_factory' to be used by DeployContractWithSalt().
+func (m *Client) DeployCreate2Factory(auth *bind.TransactOpts) (DeploymentData, error) {
+	return m.DeployContract(auth, "Create2Factory", abi.ABI{}, common.FromHex(Create2FactoryBytecode))
+}
+
+// DeployContractWithSalt deploys contract with CREATE2 factory, so that its address depends only on factory address, salt,
+// bytecode and constructor parameters, and not on deployer's nonce. If a contract compatible with the bytecode is already
+// deployed at that address (e.g. by a previous run), it's returned instead with Reused set. Otherwise, it works just like
+// DeployContract().
+func (m *Client) DeployContractWithSalt(auth *bind.TransactOpts, name string, contractABI abi.ABI, bytecode []byte, salt [32]byte, params ...interface{}) (DeploymentData, error) {
+	m.logger().Info().
+		Msgf("Started deploying %s contract with CREATE2", name)
+
+	if m.ContractStore != nil {
+		if err := m.ContractStore.VerifyBIN(name, bytecode); err != nil {
+			return DeploymentData{}, err
+		}
+	}
+
+	if err := m.checkTransactOpts(auth); err != nil {
+		return DeploymentData{}, errors.Wrapf(err, "aborted contract deployment for %s", name)
+	}
+
+	initCode, err := create2InitCode(contractABI, bytecode, params...)
+	if err != nil {
+		return DeploymentData{}, err
+	}
+	factory := m.Create2Factory()
+	address := PredictCreate2Address(factory, salt, initCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
+	defer cancel()
+
+	code, err := m.Client.CodeAt(ctx, address, nil)
+	if err != nil {
+		return DeploymentData{}, err
+	}
+	if len(code) > 0 {
+		if !IsRuntimeCodeCompatible(bytecode, code) {
+			return DeploymentData{}, errors.Wrapf(ErrCreate2AddressOccupied, "address: %s, contract: %s, use another salt", address.Hex(), name)
+		}
+		m.ContractAddressToNameMap.AddContract(address.Hex(), name)
+		return m.reuseContract(name, contractABI, address), nil
+	}
+
+	factoryCode, err := m.Client.CodeAt(ctx, factory, nil)
+	if err != nil {
+		return DeploymentData{}, err
+	}
+	if len(factoryCode) == 0 {
+		return DeploymentData{}, errors.Wrapf(ErrNoCreate2Factory, "factory: %s, deploy it with DeployCreate2Factory() and set network's 'create2_factory' to its address", factory.Hex())
+	}
+
+	calldata := append(salt[:], initCode...)
+	tx, err := bind.NewBoundContract(factory, abi.ABI{}, m.Client, m.Client, m.Client).RawTransact(auth, calldata)
+	if err != nil {
+		m.onDeploymentSendError()
+		return DeploymentData{}, wrapErrInMessageWithASuggestion(err)
+	}
+
+	m.logger().Info().
+		Str("Address", address.Hex()).
+		Str("TXHash", tx.Hash().Hex()).
+		Msgf("Waiting for %s contract deployment to finish", name)
+
+	m.ContractAddressToNameMap.AddContract(address.Hex(), name)
+
+	if _, ok := m.ContractStore.GetABI(name); !ok {
+		m.ContractStore.AddABI(name, contractABI)
+	}
+
+	// transaction is recorded in run manifest by Decode(), but factories other than the canonical one might not revert,
+	// when creation fails, so code has to be checked too
+	if _, err := m.Decode(tx, nil); err != nil {
+		return DeploymentData{}, err
+	}
+	checkCtx, checkCancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
+	defer checkCancel()
+	code, err = m.Client.CodeAt(checkCtx, address, nil)
+	if err != nil {
+		return DeploymentData{}, err
+	}
+	if len(code) == 0 {
+		return DeploymentData{}, errors.Errorf("no contract code at %s after deployment of %s contract with CREATE2", address.Hex(), name)
+	}
+
+	m.logger().Info().
+		Str("Address", address.Hex()).
+		Str("TXHash", tx.Hash().Hex()).
+		Msgf("Deployed %s contract with CREATE2", name)
+
+	m.saveDeployedContract(name, address)
+
+	return DeploymentData{
+		Address:       address,
+		Transaction:   tx,
+		BoundContract: bind.NewBoundContract(address, contractABI, m.Client, m.Client, m.Client),
+	}, nil
+}
+
+// create2InitCode returns bytecode followed by ABI encoded constructor parameters
+func create2InitCode(contractABI abi.ABI, bytecode []byte, params ...interface{}) ([]byte, error) {
+	args, err := contractABI.Pack("", params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to pack constructor parameters")
+	}
+	return append(append([]byte{}, bytecode...), args...), nil
+}
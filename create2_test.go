@@ -0,0 +1,67 @@
+package seth_test
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIDeployContractWithSalt(t *testing.T) {
+	c := newClient(t)
+	factory, err := c.DeployCreate2Factory(c.NewTXOpts())
+	require.NoError(t, err, "failed to deploy CREATE2 factory")
+	c.Cfg.Network.Create2Factory = factory.Address.Hex()
+
+	subABI, ok := c.ContractStore.GetABI("NetworkDebugSubContract")
+	require.True(t, ok, "ABI should be loaded")
+	subBIN, ok := c.ContractStore.GetBIN("NetworkDebugSubContract")
+	require.True(t, ok, "BIN should be loaded")
+	salt := seth.Create2Salt("seth")
+
+	predicted, err := c.PredictContractAddress(*subABI, subBIN, salt)
+	require.NoError(t, err, "failed to predict address")
+
+	t.Run("deployed at predicted address", func(t *testing.T) {
+		data, err := c.DeployContractWithSalt(c.NewTXOpts(), "NetworkDebugSubContract", *subABI, subBIN, salt)
+		require.NoError(t, err, "failed to deploy contract")
+		require.False(t, data.Reused, "contract should be deployed")
+		require.NotNil(t, data.Transaction, "deployment transaction should be returned")
+		require.Equal(t, predicted, data.Address, "contract should be deployed at predicted address")
+		require.Equal(t, "NetworkDebugSubContract", c.ContractAddressToNameMap.GetContractName(data.Address.Hex()), "contract should be added to contract map")
+
+		code, err := c.Client.CodeAt(c.Context, data.Address, nil)
+		require.NoError(t, err, "failed to get code")
+		require.True(t, seth.IsRuntimeCodeCompatible(subBIN, code), "deployed code should match bytecode")
+	})
+
+	t.Run("contract deployed with the same salt is reused", func(t *testing.T) {
+		data, err := c.DeployContractWithSalt(c.NewTXOpts(), "NetworkDebugSubContract", *subABI, subBIN, salt)
+		require.NoError(t, err, "failed to reuse contract")
+		require.True(t, data.Reused, "contract should be reused")
+		require.Nil(t, data.Transaction, "no transaction should be sent")
+		require.Equal(t, predicted, data.Address, "contract at predicted address should be reused")
+	})
+
+	t.Run("different salt gives different address", func(t *testing.T) {
+		other := seth.Create2Salt("seth-2")
+		address, err := c.PredictContractAddress(*subABI, subBIN, other)
+		require.NoError(t, err, "failed to predict address")
+		require.NotEqual(t, predicted, address, "address should depend on salt")
+		require.Equal(t, address, seth.PredictCreate2Address(factory.Address, other, subBIN), "contract without constructor parameters should have bytecode as init code")
+	})
+
+	t.Run("factory is not deployed", func(t *testing.T) {
+		missing := newClient(t)
+		missing.Cfg.Network.Create2Factory = common.HexToAddress("0x1").Hex()
+		_, err := missing.DeployContractWithSalt(missing.NewTXOpts(), "NetworkDebugSubContract", *subABI, subBIN, salt)
+		require.ErrorIs(t, err, seth.ErrNoCreate2Factory)
+	})
+}
+
+func TestUtilPredictCreate2Address(t *testing.T) {
+	// example 1 from EIP-1014
+	address := seth.PredictCreate2Address(common.Address{}, [32]byte{}, common.FromHex("0x00"))
+	require.Equal(t, common.HexToAddress("0x4D1A2e2bB4F88F0250f26Ffff098B0b30B26BF38"), address, "incorrect CREATE2 address")
+}
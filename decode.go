@@ -1,16 +1,18 @@
 package seth
 
 import (
-	"bytes"
 	"context"
 	"encoding/hex"
 	"fmt"
 	"math/big"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/pkg/errors"
@@ -18,17 +20,19 @@ import (
 )
 
 const (
-	ErrDecodeInput          = "failed to decode transaction input"
-	ErrDecodeOutput         = "failed to decode transaction output"
-	ErrDecodeLog            = "failed to decode log"
-	ErrDecodedLogNonIndexed = "failed to decode non-indexed log data"
-	ErrDecodeILogIndexed    = "failed to decode indexed log data"
-	ErrNoTxData             = "no tx data or it's less than 4 bytes"
-	ErrRPCJSONCastError     = "failed to cast CallMsg error as rpc.DataError"
-
 	WarnNoContractStore = "ContractStore is nil, use seth.NewContractStore(...) to decode transactions"
 )
 
+var (
+	ErrDecodeInput          = errors.New("failed to decode transaction input")
+	ErrDecodeOutput         = errors.New("failed to decode transaction output")
+	ErrDecodeLog            = errors.New("failed to decode log")
+	ErrDecodedLogNonIndexed = errors.New("failed to decode non-indexed log data")
+	ErrDecodeILogIndexed    = errors.New("failed to decode indexed log data")
+	ErrNoTxData             = errors.New("no tx data or it's less than 4 bytes")
+	ErrRPCJSONCastError     = errors.New("failed to cast CallMsg error as rpc.DataError")
+)
+
 // DecodedTransaction decoded transaction
 type DecodedTransaction struct {
 	CommonData
@@ -38,6 +42,12 @@ type DecodedTransaction struct {
 	Transaction *types.Transaction      `json:"transaction,omitempty"`
 	Receipt     *types.Receipt          `json:"receipt,omitempty"`
 	Events      []DecodedTransactionLog `json:"events,omitempty"`
+	// RevertReason is set only for reverted transactions, whose revert data could be decoded
+	RevertReason *RevertReason `json:"revert_reason,omitempty"`
+	// RevertLocation is set only for reverted transactions, if reverting contract has a source map
+	RevertLocation *SourceLocation `json:"revert_location,omitempty"`
+	// L1Fee is L1 data fee paid by the transaction on OP-stack chains (see 'op_stack' network setting)
+	L1Fee *big.Int `json:"l1_fee,omitempty"`
 }
 
 type CommonData struct {
@@ -45,6 +55,8 @@ type CommonData struct {
 	Method    string                 `json:"method"`
 	Input     map[string]interface{} `json:"input,omitempty"`
 	Output    map[string]interface{} `json:"output,omitempty"`
+	// NestedCalls are calls encoded in bytes arguments of the call, that could be decoded, keyed by argument path
+	NestedCalls map[string]*NestedCall `json:"nested_calls,omitempty"`
 }
 
 // DecodedCall decoded call
@@ -56,9 +68,19 @@ type DecodedCall struct {
 	To          string             `json:"to,omitempty"`
 	Events      []DecodedCommonLog `json:"events,omitempty"`
 	Comment     string             `json:"comment,omitempty"`
-	Value       int64              `json:"value,omitempty"`
-	GasLimit    uint64             `json:"gas_limit,omitempty"`
-	GasUsed     uint64             `json:"gas_used,omitempty"`
+	// Implementation is the name of proxy's implementation (or diamond's facet), whose ABI was used to decode the call,
+	// while To is the proxy
+	Implementation string `json:"implementation,omitempty"`
+	Value          int64  `json:"value,omitempty"`
+	GasLimit       uint64 `json:"gas_limit,omitempty"`
+	GasUsed        uint64 `json:"gas_used,omitempty"`
+	// InternalTransfers are set only on the main call and only if `trace_internal_transfers` is enabled
+	InternalTransfers []InternalTransfer `json:"internal_transfers,omitempty"`
+	// BalanceChanges are net changes of native balances made by the transaction, they are set only on the main call and
+	// only if `trace_internal_transfers` is enabled
+	BalanceChanges []BalanceChange `json:"balance_changes,omitempty"`
+	// StateDiff is set only on the main call and only if the transaction was traced with `state_diff` enabled
+	StateDiff []AccountStateDiff `json:"state_diff,omitempty"`
 }
 
 type DecodedCommonLog struct {
@@ -127,17 +149,17 @@ func (m *Client) decodeTransaction(l zerolog.Logger, tx *types.Transaction, rece
 	}
 	// if there is no tx data we have no inputs/outputs/logs
 	if len(txData) == 0 || len(txData) < 4 {
-		l.Err(errors.New(ErrNoTxData)).Send()
+		l.Err(ErrNoTxData).Send()
 		return defaultTxn, nil
 	}
 	if m.ContractStore == nil {
-		L.Warn().Msg(WarnNoContractStore)
+		m.logger().Warn().Msg(WarnNoContractStore)
 		return defaultTxn, nil
 	}
 
 	sig := txData[:4]
 	if m.ABIFinder == nil {
-		L.Err(errors.New("ABIFInder is nil")).Msg("ABIFinder is required for transaction decoding")
+		m.logger().Err(errors.New("ABIFInder is nil")).Msg("ABIFinder is required for transaction decoding")
 		return defaultTxn, nil
 	}
 
@@ -155,7 +177,7 @@ func (m *Client) decodeTransaction(l zerolog.Logger, tx *types.Transaction, rece
 
 	txInput, err = decodeTxInputs(l, txData, abiResult.Method)
 	if err != nil {
-		return defaultTxn, errors.Wrap(err, ErrDecodeInput)
+		return defaultTxn, wrapError(err, ErrDecodeInput)
 	}
 
 	if receipt != nil {
@@ -171,9 +193,10 @@ func (m *Client) decodeTransaction(l zerolog.Logger, tx *types.Transaction, rece
 	}
 	ptx := &DecodedTransaction{
 		CommonData: CommonData{
-			Signature: common.Bytes2Hex(abiResult.Method.ID),
-			Method:    abiResult.Method.Sig,
-			Input:     txInput,
+			Signature:   common.Bytes2Hex(abiResult.Method.ID),
+			Method:      abiResult.Method.Sig,
+			Input:       txInput,
+			NestedCalls: m.ABIFinder.DecodeNestedCalls(abiResult.Method, txInput),
 		},
 		Index:       receipt.TransactionIndex,
 		Receipt:     receipt,
@@ -187,59 +210,124 @@ func (m *Client) decodeTransaction(l zerolog.Logger, tx *types.Transaction, rece
 	return ptx, nil
 }
 
+// DecodedCalldata is calldata decoded without an on-chain transaction
+type DecodedCalldata struct {
+	CommonData
+	// Contract is the name of ABI used to decode the calldata
+	Contract string `json:"contract"`
+}
+
+// DecodeCalldata decodes calldata (selector followed by ABI-encoded arguments) with the first ABI from the contract store,
+// in the order of their names, that has a method with its selector. Calldata nested in bytes arguments (e.g. of multisigs
+// or governance proposals) is decoded as well. It doesn't need a transaction, so it can be used to inspect payloads before
+// they are sent.
+func (m *Client) DecodeCalldata(data []byte) (*DecodedCalldata, error) {
+	if len(data) < 4 {
+		return nil, ErrNoTxData
+	}
+	if m.ContractStore == nil {
+		return nil, errors.New(WarnNoContractStore)
+	}
+	abis := m.ContractStore.ListABIs()
+	names := make([]string, 0, len(abis))
+	for name := range abis {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var decodeErr error
+	for _, name := range names {
+		contractABI := abis[name]
+		method, err := contractABI.MethodById(data[:4])
+		if err != nil {
+			continue
+		}
+		input, err := decodeTxInputs(L, data, method)
+		if err != nil {
+			// other ABI could have a method with the same selector, but different arguments
+			decodeErr = wrapError(err, ErrDecodeInput)
+			continue
+		}
+		decoded := &DecodedCalldata{
+			CommonData: CommonData{
+				Signature: common.Bytes2Hex(method.ID),
+				Method:    method.Sig,
+				Input:     input,
+			},
+			Contract: strings.TrimSuffix(name, ".abi"),
+		}
+		if m.ABIFinder != nil {
+			decoded.NestedCalls = m.ABIFinder.DecodeNestedCalls(method, input)
+		}
+		return decoded, nil
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return nil, errors.Wrapf(ErrNoABIMethod, "selector %s", hexutil.Encode(data[:4]))
+}
+
+// DecodeCalldataHex is the same as DecodeCalldata, but accepts hex-encoded calldata with or without 0x prefix
+func (m *Client) DecodeCalldataHex(data string) (*DecodedCalldata, error) {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(data, "0x"), "0X"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode hex calldata")
+	}
+	return m.DecodeCalldata(decoded)
+}
+
 // printDecodedTXData prints decoded txn data
 func (m *Client) printDecodedTXData(l zerolog.Logger, ptx *DecodedTransaction) {
 	l.Debug().Str("Method signature", ptx.Signature).Send()
 	l.Debug().Str("Method name", ptx.Method).Send()
 	if ptx.Input != nil {
-		l.Debug().Interface("Inputs", ptx.Input).Send()
+		l.Debug().Interface("Inputs", m.Cfg.DecodedOutput.Limit(ptx.Input)).Send()
+	}
+	for arg, nc := range ptx.NestedCalls {
+		l.Debug().Str("Argument", arg).Str("Contract", nc.Contract).Str("Method", nc.Method).Interface("Inputs", m.Cfg.DecodedOutput.Limit(nc.Input)).Msg("Nested call")
 	}
 	if ptx.Output != nil {
-		l.Debug().Interface("Outputs", ptx.Output).Send()
+		l.Debug().Interface("Outputs", m.Cfg.DecodedOutput.Limit(ptx.Output)).Send()
 	}
 	for _, e := range ptx.Events {
 		l.Debug().
 			Str("Signature", e.Signature).
-			Interface("Log", e.EventData).Send()
+			Interface("Log", m.Cfg.DecodedOutput.Limit(e.EventData)).Send()
 	}
 }
 
-// DecodeCustomABIErr decodes typed Solidity errors
+// DecodeCustomABIErr decodes typed Solidity errors, it returns message of the custom error, see DecodeCustomRevertError()
 func (m *Client) DecodeCustomABIErr(txErr error) (string, error) {
-	cerr, ok := txErr.(rpc.DataError)
-	if !ok {
-		return "", errors.New(ErrRPCJSONCastError)
+	revertErr, err := m.DecodeCustomRevertError(txErr)
+	if err != nil || revertErr == nil {
+		return "", err
+	}
+	return revertErr.Reason, nil
+}
+
+// DecodeCustomRevertError decodes typed Solidity error from error returned by the node (e.g. by eth_call or gas estimation)
+// and returns it as RevertError with name of the error, its decoded arguments and the contract, whose ABI declares it. The
+// original error is wrapped with the message of the custom error. Returns nil, if error doesn't contain custom error data
+// or it doesn't match any ABI in the contract store.
+func (m *Client) DecodeCustomRevertError(txErr error) (*RevertError, error) {
+	if _, ok := txErr.(rpc.DataError); !ok {
+		return nil, ErrRPCJSONCastError
 	}
 	if m.ContractStore == nil {
-		L.Warn().Msg(WarnNoContractStore)
-		return "", nil
-	}
-	if cerr.ErrorData() != nil {
-		L.Trace().Msg("Decoding custom ABI error from tx")
-		for _, a := range m.ContractStore.ABIs {
-			for k, abiError := range a.Errors {
-				data, err := hex.DecodeString(cerr.ErrorData().(string)[2:])
-				if err != nil {
-					return "", err
-				}
-				if len(data) < 4 {
-					return "", err
-				}
-				if bytes.Equal(data[:4], abiError.ID.Bytes()[:4]) {
-					// Found a matching error
-					v, err := abiError.Unpack(data)
-					if err != nil {
-						return "", err
-					}
-					L.Trace().Interface("Error", k).Interface("Args", v).Msg("Revert Reason")
-					return fmt.Sprintf("error type: %s, error values: %v", k, v), nil
-				}
-			}
-		}
-	} else {
-		L.Warn().Msg("No error data in tx")
+		m.logger().Warn().Msg(WarnNoContractStore)
+		return nil, nil
+	}
+	data, ok := revertDataFromErr(txErr)
+	if !ok {
+		m.logger().Warn().Msg("No error data in tx")
+		return nil, nil
+	}
+	m.logger().Trace().Msg("Decoding custom ABI error from tx")
+	if reason := decodeRevertReason(m.ContractStore, data); reason != nil && reason.IsCustomError() {
+		m.logger().Trace().Interface("Error", reason.Name).Interface("Args", reason.Params).Msg("Revert Reason")
+		return newRevertError(reason, errors.Wrap(txErr, reason.Message)), nil
 	}
-	return "", nil
+	return nil, nil
 }
 
 // CallMsgFromTx creates ethereum.CallMsg from tx, used in simulated calls
@@ -247,28 +335,30 @@ func (m *Client) CallMsgFromTx(tx *types.Transaction) (ethereum.CallMsg, error)
 	signer := types.LatestSignerForChainID(tx.ChainId())
 	sender, err := types.Sender(signer, tx)
 	if err != nil {
-		L.Warn().Err(err).Msg("Failed to get sender from tx")
+		m.logger().Warn().Err(err).Msg("Failed to get sender from tx")
 		return ethereum.CallMsg{}, err
 	}
 
-	if tx.Type() == types.LegacyTxType {
+	if tx.Type() == types.LegacyTxType || tx.Type() == types.AccessListTxType {
 		return ethereum.CallMsg{
-			From:     sender,
-			To:       tx.To(),
-			Gas:      tx.Gas(),
-			GasPrice: tx.GasPrice(),
-			Value:    tx.Value(),
-			Data:     tx.Data(),
+			From:       sender,
+			To:         tx.To(),
+			Gas:        tx.Gas(),
+			GasPrice:   tx.GasPrice(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
 		}, nil
 	}
 	return ethereum.CallMsg{
-		From:      sender,
-		To:        tx.To(),
-		Gas:       tx.Gas(),
-		GasFeeCap: tx.GasFeeCap(),
-		GasTipCap: tx.GasTipCap(),
-		Value:     tx.Value(),
-		Data:      tx.Data(),
+		From:       sender,
+		To:         tx.To(),
+		Gas:        tx.Gas(),
+		GasFeeCap:  tx.GasFeeCap(),
+		GasTipCap:  tx.GasTipCap(),
+		Value:      tx.Value(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
 	}, nil
 }
 
@@ -286,9 +376,10 @@ func (m *Client) DownloadContractAndGetPragma(address common.Address, block *big
 	return pragma, nil
 }
 
-// callAndGetRevertReason executes transaction locally and gets revert reason
-func (m *Client) callAndGetRevertReason(tx *types.Transaction, rc *types.Receipt) error {
-	L.Trace().Msg("Decoding revert error")
+// callAndGetRevertReason executes transaction locally and gets revert reason, both as RevertError and, if revert data could
+// be decoded, as structured revert reason
+func (m *Client) callAndGetRevertReason(ctx context.Context, tx *types.Transaction, rc *types.Receipt) (*RevertReason, error) {
+	m.logger().Trace().Msg("Decoding revert error")
 	// bind should support custom errors decoding soon, not yet merged
 	// https://github.com/ethereum/go-ethereum/issues/26823
 	// there are 2 types of possible errors, plain old assert/revert string
@@ -296,49 +387,64 @@ func (m *Client) callAndGetRevertReason(tx *types.Transaction, rc *types.Receipt
 	// if there is no match we print the error from CallMsg call
 	msg, err := m.CallMsgFromTx(tx)
 	if err != nil {
-		L.Warn().Err(err).Msg("Failed to get call msg from tx. We won't be able to decode revert reason.")
-		return nil
+		m.logger().Warn().Err(err).Msg("Failed to get call msg from tx. We won't be able to decode revert reason.")
+		return nil, nil
 	}
-	_, plainStringErr := m.Client.CallContract(context.Background(), msg, rc.BlockNumber)
+	_, plainStringErr := m.Client.CallContract(ctx, msg, rc.BlockNumber)
 
-	decodedABIErrString, err := m.DecodeCustomABIErr(plainStringErr)
-	if err != nil {
-		return err
+	var revertReason *RevertReason
+	if data, ok := revertDataFromErr(plainStringErr); ok {
+		var calledContract string
+		if tx.To() != nil && m.ContractAddressToNameMap.IsKnownAddress(tx.To().Hex()) {
+			calledContract = m.ContractAddressToNameMap.GetContractName(tx.To().Hex())
+		}
+		revertReason = decodeRevertReasonOf(m.ContractStore, data, calledContract)
+		if revertReason != nil && tx.To() != nil {
+			revertReason.Address = tx.To().Hex()
+			if revertReason.Contract == "" {
+				revertReason.Contract = calledContract
+			}
+		}
+	}
+
+	if _, ok := plainStringErr.(rpc.DataError); !ok {
+		return revertReason, newRevertError(revertReason, ErrRPCJSONCastError)
 	}
-	if decodedABIErrString != "" {
-		return errors.New(decodedABIErrString)
+	if revertReason != nil && revertReason.IsCustomError() {
+		m.logger().Trace().Interface("Error", revertReason.Name).Interface("Args", revertReason.Params).Msg("Revert Reason")
+		return revertReason, newRevertError(revertReason, errors.New(revertReason.Message))
 	}
 
 	if plainStringErr != nil {
-		L.Warn().Msg("Failed to decode revert reason")
+		m.logger().Warn().Msg("Failed to decode revert reason")
 
 		if plainStringErr.Error() == "execution reverted" && tx != nil && rc != nil {
 			if tx.To() != nil {
 				pragma, err := m.DownloadContractAndGetPragma(*tx.To(), rc.BlockNumber)
 				if err == nil {
 					if DoesPragmaSupportCustomRevert(pragma) {
-						L.Warn().Str("Pragma", fmt.Sprint(pragma)).Msg("Custom revert reason is supported by pragma, but we could not decode it. This might be a bug in Seth. Please contact the Test Tooling team.")
+						m.logger().Warn().Str("Pragma", fmt.Sprint(pragma)).Msg("Custom revert reason is supported by pragma, but we could not decode it. This might be a bug in Seth. Please contact the Test Tooling team.")
 					} else {
-						L.Info().Str("Pragma", fmt.Sprint(pragma)).Msg("Custom revert reason is not supported by pragma version (must be >= 0.8.4). There's nothing more we can do to get custom revert reason.")
+						m.logger().Info().Str("Pragma", fmt.Sprint(pragma)).Msg("Custom revert reason is not supported by pragma version (must be >= 0.8.4). There's nothing more we can do to get custom revert reason.")
 					}
 				} else {
-					L.Warn().Err(err).Msg("Failed to decode pragma version. Contract either uses very old version or was compiled without metadata. We won't be able to decode revert reason.")
+					m.logger().Warn().Err(err).Msg("Failed to decode pragma version. Contract either uses very old version or was compiled without metadata. We won't be able to decode revert reason.")
 				}
 			} else {
-				L.Warn().Msg("Transaction has no recipient address. Most likely it's a contract creation transaction. We don't support decoding revert reasons for contract creation transactions yet.")
+				m.logger().Warn().Msg("Transaction has no recipient address. Most likely it's a contract creation transaction. We don't support decoding revert reasons for contract creation transactions yet.")
 			}
 		}
 
-		return plainStringErr
+		return revertReason, newRevertError(revertReason, plainStringErr)
 	}
-	return nil
+	return revertReason, nil
 }
 
 // decodeTxInputs decoded tx inputs
 func decodeTxInputs(l zerolog.Logger, txData []byte, method *abi.Method) (map[string]interface{}, error) {
 	l.Trace().Msg("Parsing tx inputs")
 	if (len(txData)) < 4 {
-		return nil, errors.New(ErrNoTxData)
+		return nil, ErrNoTxData
 	}
 
 	inputMap := make(map[string]interface{})
@@ -374,7 +480,7 @@ func decodeTxOutputs(l zerolog.Logger, payload []byte, method *abi.Method) (map[
 	} else {
 		err := method.Outputs.UnpackIntoMap(outputMap, payload)
 		if err != nil {
-			return nil, errors.Wrap(err, ErrDecodeOutput)
+			return nil, wrapError(err, ErrDecodeOutput)
 		}
 	}
 	l.Trace().Interface("Outputs", outputMap).Msg("Transaction outputs")
@@ -399,7 +505,7 @@ func decodeEventFromLog(
 	if len(lo.GetData()) != 0 {
 		err := a.UnpackIntoMap(eventsMap, eventABISpec.Name, lo.GetData())
 		if err != nil {
-			return nil, nil, errors.Wrap(err, ErrDecodedLogNonIndexed)
+			return nil, nil, wrapError(err, ErrDecodedLogNonIndexed)
 		}
 		l.Trace().Interface("Non-indexed", eventsMap).Send()
 	}
@@ -424,7 +530,7 @@ func decodeEventFromLog(
 		l.Trace().Interface("Indexed", indexed).Send()
 		err := abi.ParseTopicsIntoMap(topicsMap, indexed, indexedTopics)
 		if err != nil {
-			return nil, nil, errors.Wrap(err, ErrDecodeILogIndexed)
+			return nil, nil, wrapError(err, ErrDecodeILogIndexed)
 		}
 		l.Trace().Interface("Indexed", topicsMap).Send()
 	}
@@ -0,0 +1,208 @@
+package seth
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DecodedDataDir is the directory, where full values truncated in decoded output are saved, if 'keep_full_data' is enabled
+	DecodedDataDir = "decoded_data"
+	// RedactedValue replaces values of redacted fields in decoded output
+	RedactedValue = "<redacted>"
+)
+
+var (
+	ErrDecodedOutputLimit = errors.New("decoded output limit must be greater than or equal to 0")
+)
+
+// DecodedOutputCfg limits size of decoded inputs, outputs, events and call traces, that are logged or saved as JSON, so
+// that transactions carrying large calldata don't produce megabyte-scale log lines. Zero value of each limit means no limit.
+type DecodedOutputCfg struct {
+	// MaxValueLength is the maximum length of a string value, byte arrays are converted to hex strings before they are checked
+	MaxValueLength int `toml:"max_value_length"`
+	// MaxElements is the maximum number of elements of an array or a slice
+	MaxElements int `toml:"max_elements"`
+	// MaxDepth is the maximum nesting level of structs, maps and arrays
+	MaxDepth int `toml:"max_depth"`
+	// Redact are names of arguments, event fields or struct fields (case-insensitive), whose values are never printed
+	Redact []string `toml:"redact"`
+	// KeepFullData saves each truncated value to a separate JSON file in 'decoded_data' directory and adds its path to the marker
+	KeepFullData bool `toml:"keep_full_data"`
+}
+
+func validateDecodedOutputCfg(cfg *DecodedOutputCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.MaxValueLength < 0 {
+		return errors.Wrapf(ErrDecodedOutputLimit, "field: '%s'", "max_value_length")
+	}
+	if cfg.MaxElements < 0 {
+		return errors.Wrapf(ErrDecodedOutputLimit, "field: '%s'", "max_elements")
+	}
+	if cfg.MaxDepth < 0 {
+		return errors.Wrapf(ErrDecodedOutputLimit, "field: '%s'", "max_depth")
+	}
+	return nil
+}
+
+// Limit returns copy of the value with long strings and byte arrays truncated, long arrays cut, too deeply nested values
+// replaced with a marker and redacted fields replaced with RedactedValue. Structs are converted to maps keyed by JSON
+// names of their fields. If config is nil value is returned as it is.
+func (c *DecodedOutputCfg) Limit(v interface{}) interface{} {
+	if c == nil || v == nil {
+		return v
+	}
+	return c.limit(reflect.ValueOf(v), 0)
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func (c *DecodedOutputCfg) limit(v reflect.Value, depth int) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil
+	}
+
+	// types with own representation (e.g. addresses, hashes, big ints) are kept, unless they are too long
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		if s, ok := marshalledString(v.Interface()); ok && c.MaxValueLength > 0 && len(s) > c.MaxValueLength {
+			return c.truncateString(s)
+		}
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return c.limit(v.Elem(), depth)
+	case reflect.String:
+		return c.truncateString(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return c.truncateString(hexutil.Encode(b))
+		}
+		if c.MaxDepth > 0 && depth >= c.MaxDepth {
+			return c.marker(v.Interface(), fmt.Sprintf("<%d elements nested too deep>", v.Len()))
+		}
+		n := v.Len()
+		if c.MaxElements > 0 && n > c.MaxElements {
+			n = c.MaxElements
+		}
+		limited := make([]interface{}, 0, n+1)
+		for i := 0; i < n; i++ {
+			limited = append(limited, c.limit(v.Index(i), depth+1))
+		}
+		if n < v.Len() {
+			limited = append(limited, c.marker(v.Interface(), fmt.Sprintf("<%d more elements>", v.Len()-n)))
+		}
+		return limited
+	case reflect.Map:
+		if c.MaxDepth > 0 && depth >= c.MaxDepth {
+			return c.marker(v.Interface(), fmt.Sprintf("<%d fields nested too deep>", v.Len()))
+		}
+		limited := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			limited[key] = c.limitField(key, iter.Value(), depth)
+		}
+		return limited
+	case reflect.Struct:
+		if c.MaxDepth > 0 && depth >= c.MaxDepth {
+			return c.marker(v.Interface(), fmt.Sprintf("<%d fields nested too deep>", v.NumField()))
+		}
+		limited := make(map[string]interface{}, v.NumField())
+		c.limitStructFields(v, depth, limited)
+		return limited
+	default:
+		return v.Interface()
+	}
+}
+
+// limitStructFields adds struct fields to the map using their JSON names, fields of embedded structs are added directly
+func (c *DecodedOutputCfg) limitStructFields(v reflect.Value, depth int, limited map[string]interface{}) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			c.limitStructFields(v.Field(i), depth, limited)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(opts, "omitempty") && v.Field(i).IsZero() {
+			continue
+		}
+		limited[name] = c.limitField(name, v.Field(i), depth)
+	}
+}
+
+func (c *DecodedOutputCfg) limitField(name string, v reflect.Value, depth int) interface{} {
+	for _, redacted := range c.Redact {
+		if strings.EqualFold(redacted, name) {
+			return RedactedValue
+		}
+	}
+	return c.limit(v, depth+1)
+}
+
+func (c *DecodedOutputCfg) truncateString(s string) string {
+	if c.MaxValueLength == 0 || len(s) <= c.MaxValueLength {
+		return s
+	}
+	return s[:c.MaxValueLength] + c.marker(s, fmt.Sprintf("...<truncated %d characters>", len(s)-c.MaxValueLength))
+}
+
+// marker returns description of truncated data, extended with path of the file with full value, if it's kept
+func (c *DecodedOutputCfg) marker(full interface{}, description string) string {
+	if !c.KeepFullData {
+		return description
+	}
+	data, err := json.MarshalIndent(full, "", "   ")
+	if err != nil {
+		L.Warn().Err(err).Msg("Failed to marshal truncated decoded value")
+		return description
+	}
+	// values are saved under their hash, so the same value is saved only once
+	path, err := writeJsonFile(data, DecodedDataDir, common.Bytes2Hex(crypto.Keccak256(data)[:8]), false)
+	if err != nil {
+		L.Warn().Err(err).Msg("Failed to save truncated decoded value")
+		return description
+	}
+	return strings.TrimSuffix(description, ">") + ", full value in " + path + ">"
+}
+
+func marshalledString(v interface{}) (string, bool) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	var s string
+	if json.Unmarshal(data, &s) != nil {
+		return "", false
+	}
+	return s, true
+}
@@ -0,0 +1,63 @@
+package seth_test
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUtilDecodedOutputLimit(t *testing.T) {
+	address := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	input := map[string]interface{}{
+		"data":     make([]byte, 100),
+		"amount":   big.NewInt(100),
+		"to":       address,
+		"ids":      []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4)},
+		"password": "secret",
+		"nested": struct {
+			Inner struct {
+				Value string `json:"value"`
+			} `json:"inner"`
+		}{},
+	}
+
+	var nilCfg *seth.DecodedOutputCfg
+	require.Equal(t, input, nilCfg.Limit(input), "value should not be changed without config")
+
+	cfg := &seth.DecodedOutputCfg{MaxValueLength: 10, MaxElements: 2, MaxDepth: 2, Redact: []string{"Password"}}
+	limited, ok := cfg.Limit(input).(map[string]interface{})
+	require.True(t, ok, "map should be returned")
+	require.Equal(t, "0x00000000...<truncated 192 characters>", limited["data"], "byte array should be truncated hex")
+	require.Equal(t, big.NewInt(100), limited["amount"], "big int should be kept")
+	require.Equal(t, "0x5fbdb231...<truncated 32 characters>", limited["to"], "long address should be truncated")
+	require.Equal(t, []interface{}{big.NewInt(1), big.NewInt(2), "<2 more elements>"}, limited["ids"], "array should be cut")
+	require.Equal(t, seth.RedactedValue, limited["password"], "field should be redacted")
+	require.Equal(t, map[string]interface{}{"inner": "<1 fields nested too deep>"}, limited["nested"], "deeply nested struct should be replaced")
+
+	// full values can be kept in separate files
+	cfg = &seth.DecodedOutputCfg{MaxValueLength: 10, KeepFullData: true}
+	truncated, ok := cfg.Limit(strings.Repeat("a", 20)).(string)
+	require.True(t, ok, "string should be returned")
+	require.True(t, strings.HasPrefix(truncated, "aaaaaaaaaa...<truncated 10 characters, full value in "), "path of file with full value should be added")
+	path := strings.TrimSuffix(truncated[strings.Index(truncated, "full value in ")+len("full value in "):], ">")
+	t.Cleanup(func() {
+		_ = os.Remove(path)
+	})
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "full value should be saved")
+	require.Equal(t, fmt.Sprintf("%q", strings.Repeat("a", 20)), string(data), "incorrect full value")
+}
+
+func TestConfigDecodedOutputValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	cfg.DecodedOutput = &seth.DecodedOutputCfg{MaxElements: -1}
+	require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrDecodedOutputLimit, "limits should be validated")
+}
@@ -0,0 +1,351 @@
+package seth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DeploymentStateDir is the directory (relative to working directory), in which deployment states are saved
+	DeploymentStateDir = "deployments"
+
+	DeploymentStatusDeployed = "deployed"
+	DeploymentStatusExisting = "existing"
+)
+
+var (
+	ErrDuplicateDeployment          = errors.New("deployment is defined more than once")
+	ErrUnknownDeploymentDependency  = errors.New("deployment depends on one, which isn't deployed before it")
+	ErrUnknownDeploymentVar         = errors.New("unknown variable in deployment")
+	ErrDeploymentManifestExtension  = errors.New("unsupported deployment manifest extension")
+	ErrDeploymentStateOtherChain    = errors.New("deployment state was saved for another chain")
+	ErrDeploymentStateOtherManifest = errors.New("deployment state was saved for another manifest")
+)
+
+// Deployment is a single contract deployment of a deployment manifest. Args (constructor arguments) can reference
+// results of previous deployments with ${name.key}, where key is `address`, `tx_hash` or `block`. Such deployments are
+// dependencies of this one, others can be added with DependsOn.
+type Deployment struct {
+	Name      string   `toml:"name" json:"name"`
+	Contract  string   `toml:"contract" json:"contract"`
+	Args      []string `toml:"args" json:"args,omitempty"`
+	DependsOn []string `toml:"depends_on" json:"depends_on,omitempty"`
+	Value     Amount   `toml:"value" json:"value,omitempty"`
+	KeyNum    int      `toml:"key_num" json:"key_num,omitempty"`
+	GasLimit  uint64   `toml:"gas_limit" json:"gas_limit,omitempty"`
+}
+
+// DeploymentManifest is a named, ordered list of contracts to deploy
+type DeploymentManifest struct {
+	Name        string       `toml:"name" json:"name"`
+	Deployments []Deployment `toml:"deployments" json:"deployments"`
+}
+
+// DeployedContract is a record of deployed contract, Args are constructor arguments after variable substitution
+type DeployedContract struct {
+	Name       string    `json:"name"`
+	Contract   string    `json:"contract"`
+	Address    string    `json:"address"`
+	TxHash     string    `json:"tx_hash,omitempty"`
+	Block      uint64    `json:"block,omitempty"`
+	Args       []string  `json:"args,omitempty"`
+	Deployer   string    `json:"deployer"`
+	DeployedAt time.Time `json:"deployed_at"`
+	// Status is DeploymentStatusDeployed, if contract was deployed by the last run, or DeploymentStatusExisting, if it was
+	// deployed by one of previous runs
+	Status string `json:"status"`
+}
+
+// DeploymentState is saved after each deployment, so that following runs of the same manifest on the same network deploy
+// only contracts, which are missing or whose arguments or dependencies changed
+type DeploymentState struct {
+	Manifest  string             `json:"manifest"`
+	Network   string             `json:"network"`
+	ChainID   int64              `json:"chain_id"`
+	UpdatedAt time.Time          `json:"updated_at"`
+	Contracts []DeployedContract `json:"contracts"`
+}
+
+// Get returns record of the deployment with given name
+func (s *DeploymentState) Get(name string) (DeployedContract, bool) {
+	for _, c := range s.Contracts {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return DeployedContract{}, false
+}
+
+func (s *DeploymentState) set(deployed DeployedContract) {
+	for i, c := range s.Contracts {
+		if c.Name == deployed.Name {
+			s.Contracts[i] = deployed
+			return
+		}
+	}
+	s.Contracts = append(s.Contracts, deployed)
+}
+
+// LoadDeploymentManifest reads deployment manifest from TOML or JSON file
+func LoadDeploymentManifest(path string) (*DeploymentManifest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read deployment manifest %s", path)
+	}
+
+	dm := &DeploymentManifest{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".toml":
+		err = toml.Unmarshal(b, dm)
+	case ".json":
+		err = json.Unmarshal(b, dm)
+	default:
+		return nil, errors.Wrapf(ErrDeploymentManifestExtension, "extension '%s', use .toml or .json", ext)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal deployment manifest %s", path)
+	}
+	if dm.Name == "" {
+		dm.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	return dm, dm.Validate()
+}
+
+// Validate checks that all deployments have unique names and contracts and depend only on deployments defined before them
+func (dm *DeploymentManifest) Validate() error {
+	if dm.Name == "" {
+		return errors.New("deployment manifest has no name")
+	}
+	names := make(map[string]struct{})
+	for i, d := range dm.Deployments {
+		if d.Name == "" {
+			return fmt.Errorf("deployment %d has no name", i)
+		}
+		if _, ok := names[d.Name]; ok {
+			return errors.Wrapf(ErrDuplicateDeployment, "deployment: '%s'", d.Name)
+		}
+		if d.Contract == "" {
+			return fmt.Errorf("deployment '%s' requires contract", d.Name)
+		}
+		for _, dep := range d.dependencies() {
+			if _, ok := names[dep]; !ok {
+				return errors.Wrapf(ErrUnknownDeploymentDependency, "deployment: '%s', dependency: '%s'", d.Name, dep)
+			}
+		}
+		names[d.Name] = struct{}{}
+	}
+	return nil
+}
+
+// dependencies returns names of deployments listed in DependsOn or referenced by variables
+func (d Deployment) dependencies() []string {
+	deps := append([]string{}, d.DependsOn...)
+	for _, s := range d.Args {
+		for _, match := range scenarioVarRegexp.FindAllStringSubmatch(s, -1) {
+			name, _, _ := strings.Cut(match[1], ".")
+			deps = append(deps, name)
+		}
+	}
+	return deps
+}
+
+// DeploymentStatePath returns path (relative to working directory) of the state of the manifest deployed to the network
+func DeploymentStatePath(manifestName, network string) string {
+	return filepath.Join(DeploymentStateDir, deploymentStateName(manifestName, network)+".json")
+}
+
+func deploymentStateName(manifestName, network string) string {
+	return fmt.Sprintf("%s_%s", manifestName, network)
+}
+
+// LoadDeploymentState reads deployment state saved by RunDeployments()
+func LoadDeploymentState(path string) (*DeploymentState, error) {
+	state := &DeploymentState{}
+	if err := OpenJsonFileAsStruct(path, state); err != nil {
+		return nil, errors.Wrapf(err, "failed to read deployment state %s", path)
+	}
+	return state, nil
+}
+
+// RunDeployments deploys contracts of the manifest in order. Results of each run are saved in DeploymentStateDir after every
+// deployment, so when a run fails, the next one resumes from the failed deployment: contracts recorded in the state, which
+// still exist on chain and were deployed with the same arguments, are not deployed again, unless one of their dependencies
+// was. All contracts are added to the contract map. Returned state contains all deployments made so far, also on error.
+func (m *Client) RunDeployments(ctx context.Context, dm *DeploymentManifest) (*DeploymentState, error) {
+	if err := dm.Validate(); err != nil {
+		return nil, err
+	}
+
+	state, err := m.loadOrNewDeploymentState(dm.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string)
+	deployedNow := make(map[string]bool)
+	for _, d := range dm.Deployments {
+		if err := ctx.Err(); err != nil {
+			return state, err
+		}
+
+		deployed, err := m.runDeployment(ctx, d, state, vars, deployedNow)
+		if err != nil {
+			return state, errors.Wrapf(err, "deployment '%s' failed", d.Name)
+		}
+		deployedNow[d.Name] = deployed.Status == DeploymentStatusDeployed
+		vars[d.Name+".address"] = deployed.Address
+		vars[d.Name+".tx_hash"] = deployed.TxHash
+		vars[d.Name+".block"] = fmt.Sprint(deployed.Block)
+
+		state.set(deployed)
+		state.UpdatedAt = time.Now()
+		if _, err := saveAsJson(state, DeploymentStateDir, deploymentStateName(dm.Name, m.Cfg.Network.Name)); err != nil {
+			return state, errors.Wrap(err, "failed to save deployment state")
+		}
+	}
+
+	return state, nil
+}
+
+func (m *Client) loadOrNewDeploymentState(manifestName string) (*DeploymentState, error) {
+	path := DeploymentStatePath(manifestName, m.Cfg.Network.Name)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return &DeploymentState{
+			Manifest:  manifestName,
+			Network:   m.Cfg.Network.Name,
+			ChainID:   m.ChainID,
+			Contracts: []DeployedContract{},
+		}, nil
+	}
+
+	state, err := LoadDeploymentState(path)
+	if err != nil {
+		return nil, err
+	}
+	if state.ChainID != m.ChainID {
+		return nil, errors.Wrapf(ErrDeploymentStateOtherChain, "state: %s, saved for chain: %d, connected to chain: %d", path, state.ChainID, m.ChainID)
+	}
+	if state.Manifest != manifestName {
+		return nil, errors.Wrapf(ErrDeploymentStateOtherManifest, "state: %s, saved for manifest: '%s'", path, state.Manifest)
+	}
+	for i := range state.Contracts {
+		state.Contracts[i].Status = DeploymentStatusExisting
+	}
+	return state, nil
+}
+
+func (m *Client) runDeployment(ctx context.Context, d Deployment, state *DeploymentState, vars map[string]string, deployedNow map[string]bool) (DeployedContract, error) {
+	var err error
+	if d, err = substituteDeploymentVars(d, vars); err != nil {
+		return DeployedContract{}, err
+	}
+
+	contractAbi, ok := m.ContractStore.GetABI(d.Contract)
+	if !ok {
+		return DeployedContract{}, errors.Wrapf(ErrNoABIForContract, "contract: %s", d.Contract)
+	}
+	bytecode, ok := m.ContractStore.GetBIN(d.Contract)
+	if !ok {
+		return DeployedContract{}, fmt.Errorf("BIN for contract %s not found", d.Contract)
+	}
+
+	if recorded, ok := state.Get(d.Name); ok && m.isDeploymentUpToDate(ctx, d, recorded, bytecode, deployedNow) {
+		m.logger().Info().
+			Str("Deployment", d.Name).
+			Str("Address", recorded.Address).
+			Msgf("%s contract is already deployed, skipping", d.Contract)
+		m.ContractAddressToNameMap.AddContract(recorded.Address, d.Contract)
+		recorded.Status = DeploymentStatusExisting
+		return recorded, nil
+	}
+
+	params, err := ParseMethodArgs(contractAbi.Constructor, d.Args)
+	if err != nil {
+		return DeployedContract{}, errors.Wrap(err, "failed to parse constructor arguments")
+	}
+	txOpts, err := scenarioTransactOpts(ScenarioStep{Name: d.Name, Value: d.Value, GasLimit: d.GasLimit})
+	if err != nil {
+		return DeployedContract{}, err
+	}
+
+	auth := m.NewTXKeyOpts(d.KeyNum, txOpts...)
+	data, err := m.DeployContract(auth, d.Contract, *contractAbi, bytecode, params...)
+	if err != nil {
+		return DeployedContract{}, err
+	}
+
+	deployed := DeployedContract{
+		Name:       d.Name,
+		Contract:   d.Contract,
+		Address:    data.Address.Hex(),
+		Args:       d.Args,
+		Deployer:   auth.From.Hex(),
+		DeployedAt: time.Now(),
+		Status:     DeploymentStatusDeployed,
+	}
+	if data.Transaction != nil {
+		deployed.TxHash = data.Transaction.Hash().Hex()
+		receipt, err := m.Client.TransactionReceipt(ctx, data.Transaction.Hash())
+		if err != nil {
+			return DeployedContract{}, errors.Wrap(err, "failed to get deployment receipt")
+		}
+		deployed.Block = receipt.BlockNumber.Uint64()
+	}
+
+	return deployed, nil
+}
+
+// isDeploymentUpToDate checks that recorded deployment has the same contract and arguments, none of its dependencies was
+// deployed in this run and its code is still on chain (the chain might have been reset)
+func (m *Client) isDeploymentUpToDate(ctx context.Context, d Deployment, recorded DeployedContract, bytecode []byte, deployedNow map[string]bool) bool {
+	if recorded.Contract != d.Contract || strings.Join(recorded.Args, "\x00") != strings.Join(d.Args, "\x00") {
+		return false
+	}
+	for _, dep := range d.DependsOn {
+		if deployedNow[dep] {
+			return false
+		}
+	}
+
+	code, err := m.Client.CodeAt(ctx, common.HexToAddress(recorded.Address), nil)
+	if err != nil {
+		m.logger().Warn().Err(err).Str("Deployment", d.Name).Msg("Failed to check code of recorded deployment, deploying it again")
+		return false
+	}
+	return IsRuntimeCodeCompatible(bytecode, code)
+}
+
+// substituteDeploymentVars replaces all ${name.key} references with results of previous deployments
+func substituteDeploymentVars(d Deployment, vars map[string]string) (Deployment, error) {
+	var err error
+	substitute := func(s string) string {
+		return scenarioVarRegexp.ReplaceAllStringFunc(s, func(match string) string {
+			key := scenarioVarRegexp.FindStringSubmatch(match)[1]
+			v, ok := vars[key]
+			if !ok && err == nil {
+				err = errors.Wrapf(ErrUnknownDeploymentVar, "variable: '%s', deployment: '%s'", key, d.Name)
+			}
+			return v
+		})
+	}
+
+	if d.Args != nil {
+		args := make([]string, len(d.Args))
+		for i, a := range d.Args {
+			args[i] = substitute(a)
+		}
+		d.Args = args
+	}
+
+	return d, err
+}
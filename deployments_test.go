@@ -0,0 +1,130 @@
+package seth_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/seth"
+	sethcmd "github.com/smartcontractkit/seth/cmd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIRunDeployments(t *testing.T) {
+	c := newClient(t)
+
+	manifest := &seth.DeploymentManifest{
+		Name: fmt.Sprintf("debug_%d", time.Now().UnixNano()),
+		Deployments: []seth.Deployment{
+			{Name: "sub", Contract: "NetworkDebugSubContract"},
+			{Name: "debug", Contract: "NetworkDebugContract", Args: []string{"${sub.address}"}},
+		},
+	}
+	t.Cleanup(func() {
+		_ = os.Remove(seth.DeploymentStatePath(manifest.Name, c.Cfg.Network.Name))
+	})
+
+	state, err := c.RunDeployments(context.Background(), manifest)
+	require.NoError(t, err, "deployments should succeed")
+	require.Len(t, state.Contracts, 2, "both contracts should be recorded")
+	sub, _ := state.Get("sub")
+	debug, _ := state.Get("debug")
+	require.Equal(t, seth.DeploymentStatusDeployed, sub.Status, "sub contract should be deployed")
+	require.Equal(t, seth.DeploymentStatusDeployed, debug.Status, "debug contract should be deployed")
+	require.Equal(t, []string{sub.Address}, debug.Args, "constructor arguments should be recorded after substitution")
+	require.NotEmpty(t, debug.TxHash, "transaction hash should be recorded")
+	require.NotZero(t, debug.Block, "block should be recorded")
+	require.Equal(t, "NetworkDebugContract", c.ContractAddressToNameMap.GetContractName(debug.Address), "contract should be added to contract map")
+
+	saved, err := seth.LoadDeploymentState(seth.DeploymentStatePath(manifest.Name, c.Cfg.Network.Name))
+	require.NoError(t, err, "state should be saved")
+	require.Len(t, saved.Contracts, 2, "both contracts should be saved")
+
+	// the last deployment fails, previous ones are kept
+	manifest.Deployments = append(manifest.Deployments, seth.Deployment{Name: "broken", Contract: "NetworkDebugContract", Args: []string{"not an address"}})
+	state, err = c.RunDeployments(context.Background(), manifest)
+	require.Error(t, err, "broken deployment should fail")
+	require.Len(t, state.Contracts, 2, "failed deployment should not be recorded")
+
+	// resumed run deploys only the fixed deployment
+	manifest.Deployments[2].Args = []string{"${sub.address}"}
+	state, err = c.RunDeployments(context.Background(), manifest)
+	require.NoError(t, err, "resumed deployments should succeed")
+	require.Len(t, state.Contracts, 3, "all contracts should be recorded")
+	for _, name := range []string{"sub", "debug"} {
+		resumed, _ := state.Get(name)
+		original, _ := saved.Get(name)
+		require.Equal(t, seth.DeploymentStatusExisting, resumed.Status, "%s contract should not be deployed again", name)
+		require.Equal(t, original.Address, resumed.Address, "%s contract address should not change", name)
+	}
+	broken, _ := state.Get("broken")
+	require.Equal(t, seth.DeploymentStatusDeployed, broken.Status, "fixed contract should be deployed")
+
+	// contracts are deployed again, when their arguments or dependencies change
+	manifest.Deployments = append(manifest.Deployments, seth.Deployment{Name: "dependent", Contract: "NetworkDebugSubContract", DependsOn: []string{"broken"}})
+	_, err = c.RunDeployments(context.Background(), manifest)
+	require.NoError(t, err, "deployments should succeed")
+	manifest.Deployments[2].Args = []string{"${debug.address}"}
+	state, err = c.RunDeployments(context.Background(), manifest)
+	require.NoError(t, err, "deployments should succeed")
+	for name, status := range map[string]string{
+		"sub":       seth.DeploymentStatusExisting,
+		"debug":     seth.DeploymentStatusExisting,
+		"broken":    seth.DeploymentStatusDeployed,
+		"dependent": seth.DeploymentStatusDeployed,
+	} {
+		deployed, _ := state.Get(name)
+		require.Equal(t, status, deployed.Status, "incorrect status of %s contract", name)
+	}
+}
+
+func TestAPIDeploymentManifestValidation(t *testing.T) {
+	manifest := &seth.DeploymentManifest{
+		Name: "invalid",
+		Deployments: []seth.Deployment{
+			{Name: "debug", Contract: "NetworkDebugContract", Args: []string{"${sub.address}"}},
+			{Name: "sub", Contract: "NetworkDebugSubContract"},
+		},
+	}
+	require.ErrorIs(t, manifest.Validate(), seth.ErrUnknownDeploymentDependency, "dependency should be deployed before")
+
+	manifest.Deployments[0] = seth.Deployment{Name: "sub", Contract: "NetworkDebugSubContract"}
+	require.ErrorIs(t, manifest.Validate(), seth.ErrDuplicateDeployment, "names should be unique")
+
+	manifest.Deployments[1] = seth.Deployment{Name: "debug", Contract: "NetworkDebugContract", DependsOn: []string{"unknown"}}
+	require.ErrorIs(t, manifest.Validate(), seth.ErrUnknownDeploymentDependency, "dependency should exist")
+}
+
+func TestCLIDeploy(t *testing.T) {
+	dir := t.TempDir()
+	manifestName := fmt.Sprintf("cli_deployments_%d", time.Now().UnixNano())
+	manifestFile := filepath.Join(dir, manifestName+".toml")
+	err := os.WriteFile(manifestFile, []byte(`
+[[deployments]]
+name = "sub"
+contract = "NetworkDebugSubContract"
+
+[[deployments]]
+name = "debug"
+contract = "NetworkDebugContract"
+args = ["${sub.address}"]
+`), 0600)
+	require.NoError(t, err, "failed to write deployment manifest")
+
+	network := os.Getenv(seth.NETWORK_ENV_VAR)
+	statePath := seth.DeploymentStatePath(manifestName, network)
+	t.Cleanup(func() {
+		_ = os.Remove(statePath)
+	})
+
+	err = sethcmd.RunCLI([]string{"seth", "-n", network, "deploy", manifestFile})
+	require.NoError(t, err, "deployments should succeed")
+
+	state, err := seth.LoadDeploymentState(statePath)
+	require.NoError(t, err, "state should be saved")
+	require.Equal(t, manifestName, state.Manifest, "manifest name should default to file name")
+	require.Len(t, state.Contracts, 2, "both contracts should be recorded")
+}
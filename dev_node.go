@@ -0,0 +1,165 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+const (
+	DevNodeAnvil   = "anvil"
+	DevNodeHardhat = "hardhat"
+)
+
+var (
+	ErrNotSimulatedNetwork = errors.New("network isn't a simulated one (Anvil or Hardhat)")
+	ErrDevNodeCall         = errors.New("dev node call failed")
+	ErrDevNodeRevert       = errors.New("failed to revert to snapshot")
+)
+
+// DetectDevNode returns kind of the simulated node (DevNodeAnvil or DevNodeHardhat) based on its web3_clientVersion or empty
+// string, if it's a real node
+func DetectDevNode(ctx context.Context, rpcClient *rpc.Client) (string, string, error) {
+	var version string
+	if err := rpcClient.CallContext(ctx, &version, "web3_clientVersion"); err != nil {
+		return "", "", errors.Wrapf(wrapError(err, ErrDevNodeCall), "method: %s", "web3_clientVersion")
+	}
+	lower := strings.ToLower(version)
+	switch {
+	case strings.HasPrefix(lower, "anvil"):
+		return DevNodeAnvil, version, nil
+	case strings.HasPrefix(lower, "hardhatnetwork"):
+		return DevNodeHardhat, version, nil
+	default:
+		return "", version, nil
+	}
+}
+
+// IsSimulatedNetwork returns true if client is connected to Anvil or Hardhat node, which supports cheat codes (see DevNode)
+func (m *Client) IsSimulatedNetwork() bool {
+	_, err := m.DevNode()
+	return err == nil
+}
+
+// DevNode returns helper exposing cheat codes of the simulated network or an error, if client isn't connected to one.
+// Kind of the node is detected once per client.
+func (m *Client) DevNode() (*DevNode, error) {
+	m.devNodeOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
+		defer cancel()
+		m.devNode, m.devNodeErr = NewDevNode(ctx, m.Client.Client())
+	})
+	return m.devNode, m.devNodeErr
+}
+
+// DevNode exposes cheat codes of Anvil and Hardhat nodes: snapshots, balance manipulation, account impersonation, time travel
+// and mining control. It can only be created for simulated networks, so that cheat codes are never sent to real ones.
+type DevNode struct {
+	// Kind is either DevNodeAnvil or DevNodeHardhat
+	Kind      string
+	rpcClient *rpc.Client
+}
+
+// NewDevNode creates a new cheat codes helper, it returns an error if the node isn't Anvil or Hardhat
+func NewDevNode(ctx context.Context, rpcClient *rpc.Client) (*DevNode, error) {
+	kind, version, err := DetectDevNode(ctx, rpcClient)
+	if err != nil {
+		return nil, err
+	}
+	if kind == "" {
+		return nil, errors.Wrapf(ErrNotSimulatedNetwork, "node version: '%s'", version)
+	}
+	L.Debug().Str("Kind", kind).Str("Version", version).Msg("Connected to simulated network")
+	return &DevNode{Kind: kind, rpcClient: rpcClient}, nil
+}
+
+// call calls the method, methods with "node_" prefix are called with node specific prefix ("anvil_" or "hardhat_")
+func (d *DevNode) call(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if strings.HasPrefix(method, "node_") {
+		method = d.Kind + strings.TrimPrefix(method, "node")
+	}
+	if err := d.rpcClient.CallContext(ctx, result, method, args...); err != nil {
+		return errors.Wrapf(wrapError(err, ErrDevNodeCall), "method: %s", method)
+	}
+	return nil
+}
+
+// Snapshot snapshots the state of the chain and returns snapshot's ID, which can be passed to Revert
+func (d *DevNode) Snapshot(ctx context.Context) (string, error) {
+	var id string
+	if err := d.call(ctx, &id, "evm_snapshot"); err != nil {
+		return "", err
+	}
+	L.Debug().Str("ID", id).Msg("Created chain snapshot")
+	return id, nil
+}
+
+// Revert reverts the state of the chain to the snapshot. Snapshot can be reverted to only once, snapshots taken after it
+// are removed too.
+func (d *DevNode) Revert(ctx context.Context, id string) error {
+	var reverted bool
+	if err := d.call(ctx, &reverted, "evm_revert", id); err != nil {
+		return err
+	}
+	if !reverted {
+		return errors.Wrapf(ErrDevNodeRevert, "snapshot %s doesn't exist or was already reverted", id)
+	}
+	L.Debug().Str("ID", id).Msg("Reverted chain to snapshot")
+	return nil
+}
+
+// SetBalance sets balance of the address
+func (d *DevNode) SetBalance(ctx context.Context, address common.Address, balance *big.Int) error {
+	return d.call(ctx, nil, "node_setBalance", address, (*hexutil.Big)(balance))
+}
+
+// ImpersonateAccount makes node accept unsigned transactions from the address (see SendAs), e.g. to move funds of a whale
+func (d *DevNode) ImpersonateAccount(ctx context.Context, address common.Address) error {
+	return d.call(ctx, nil, "node_impersonateAccount", address)
+}
+
+// StopImpersonatingAccount stops impersonation of the address
+func (d *DevNode) StopImpersonatingAccount(ctx context.Context, address common.Address) error {
+	return d.call(ctx, nil, "node_stopImpersonatingAccount", address)
+}
+
+// SendAs sends unsigned transaction from impersonated account with eth_sendTransaction and returns its hash, gas limit
+// is estimated by the node, if it's not set
+func (d *DevNode) SendAs(ctx context.Context, msg ethereum.CallMsg) (common.Hash, error) {
+	var hash common.Hash
+	if err := d.call(ctx, &hash, "eth_sendTransaction", toCallArg(msg)); err != nil {
+		return common.Hash{}, err
+	}
+	return hash, nil
+}
+
+// IncreaseTime moves timestamp of the next block forward, it's applied when next block is mined
+func (d *DevNode) IncreaseTime(ctx context.Context, duration time.Duration) error {
+	return d.call(ctx, nil, "evm_increaseTime", int64(duration.Seconds()))
+}
+
+// Mine mines given number of blocks
+func (d *DevNode) Mine(ctx context.Context, blocks uint64) error {
+	if blocks <= 1 {
+		return d.call(ctx, nil, "evm_mine")
+	}
+	return d.call(ctx, nil, "node_mine", hexutil.Uint64(blocks))
+}
+
+// SetAutomine enables or disables mining of a new block for every transaction, when it's disabled transactions stay
+// in mempool until blocks are mined with Mine or with interval mining
+func (d *DevNode) SetAutomine(ctx context.Context, enabled bool) error {
+	return d.call(ctx, nil, "evm_setAutomine", enabled)
+}
+
+// SetIntervalMining mines a new block every interval, 0 disables interval mining
+func (d *DevNode) SetIntervalMining(ctx context.Context, interval time.Duration) error {
+	return d.call(ctx, nil, "evm_setIntervalMining", interval.Milliseconds())
+}
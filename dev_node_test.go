@@ -0,0 +1,148 @@
+package seth_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+type devNodeCall struct {
+	Method string
+	Params []interface{}
+}
+
+// newFakeDevNode starts JSON-RPC server, which pretends to be a node with given version and records called cheat codes
+func newFakeDevNode(t *testing.T, version string) (*rpc.Client, func() []devNodeCall) {
+	var mu sync.Mutex
+	var calls []devNodeCall
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []interface{}   `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req), "failed to decode request")
+		var result interface{}
+		switch req.Method {
+		case "web3_clientVersion":
+			result = version
+		case "evm_snapshot":
+			result = "0x1"
+		case "evm_revert":
+			result = req.Params[0] == "0x1"
+		case "eth_sendTransaction":
+			result = common.HexToHash("0x01")
+		}
+		if req.Method != "web3_clientVersion" {
+			mu.Lock()
+			calls = append(calls, devNodeCall{Method: req.Method, Params: req.Params})
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	t.Cleanup(server.Close)
+	client, err := rpc.Dial(server.URL)
+	require.NoError(t, err, "failed to connect to fake node")
+	t.Cleanup(client.Close)
+	return client, func() []devNodeCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func TestUtilDevNode(t *testing.T) {
+	ctx := context.Background()
+	address := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+
+	for version, prefix := range map[string]string{
+		"anvil/v0.2.0": "anvil",
+		"HardhatNetwork/2.22.0/@ethereumjs/vm/7.0.0": "hardhat",
+	} {
+		t.Run(prefix, func(t *testing.T) {
+			client, calls := newFakeDevNode(t, version)
+			node, err := seth.NewDevNode(ctx, client)
+			require.NoError(t, err, "failed to create dev node")
+			require.Equal(t, prefix, node.Kind, "incorrect node kind")
+
+			id, err := node.Snapshot(ctx)
+			require.NoError(t, err, "failed to snapshot")
+			require.Equal(t, "0x1", id, "incorrect snapshot ID")
+			require.NoError(t, node.SetBalance(ctx, address, big.NewInt(1000)), "failed to set balance")
+			require.NoError(t, node.ImpersonateAccount(ctx, address), "failed to impersonate account")
+			hash, err := node.SendAs(ctx, ethereum.CallMsg{From: address, To: &address, Value: big.NewInt(1)})
+			require.NoError(t, err, "failed to send transaction")
+			require.Equal(t, common.HexToHash("0x01"), hash, "incorrect transaction hash")
+			require.NoError(t, node.StopImpersonatingAccount(ctx, address), "failed to stop impersonating account")
+			require.NoError(t, node.IncreaseTime(ctx, time.Hour), "failed to increase time")
+			require.NoError(t, node.Mine(ctx, 1), "failed to mine block")
+			require.NoError(t, node.Mine(ctx, 5), "failed to mine blocks")
+			require.NoError(t, node.SetAutomine(ctx, false), "failed to disable automine")
+			require.NoError(t, node.SetIntervalMining(ctx, 2*time.Second), "failed to set interval mining")
+			require.NoError(t, node.Revert(ctx, id), "failed to revert")
+			require.ErrorIs(t, node.Revert(ctx, "0x2"), seth.ErrDevNodeRevert, "unknown snapshot should fail")
+
+			require.Equal(t, []devNodeCall{
+				{Method: "evm_snapshot"},
+				{Method: prefix + "_setBalance", Params: []interface{}{address.Hex(), "0x3e8"}},
+				{Method: prefix + "_impersonateAccount", Params: []interface{}{address.Hex()}},
+				{Method: "eth_sendTransaction", Params: []interface{}{map[string]interface{}{"from": address.Hex(), "to": address.Hex(), "value": "0x1"}}},
+				{Method: prefix + "_stopImpersonatingAccount", Params: []interface{}{address.Hex()}},
+				{Method: "evm_increaseTime", Params: []interface{}{float64(3600)}},
+				{Method: "evm_mine"},
+				{Method: prefix + "_mine", Params: []interface{}{"0x5"}},
+				{Method: "evm_setAutomine", Params: []interface{}{false}},
+				{Method: "evm_setIntervalMining", Params: []interface{}{float64(2000)}},
+				{Method: "evm_revert", Params: []interface{}{"0x1"}},
+				{Method: "evm_revert", Params: []interface{}{"0x2"}},
+			}, normalizeDevNodeCalls(calls()), "incorrect cheat codes called")
+		})
+	}
+
+	client, _ := newFakeDevNode(t, "Geth/v1.13.8-stable/linux-amd64/go1.21.6")
+	_, err := seth.NewDevNode(ctx, client)
+	require.ErrorIs(t, err, seth.ErrNotSimulatedNetwork, "real node should be rejected")
+}
+
+// normalizeDevNodeCalls makes addresses checksummed, so that they can be compared regardless of encoding
+func normalizeDevNodeCalls(calls []devNodeCall) []devNodeCall {
+	for i := range calls {
+		for j, p := range calls[i].Params {
+			switch v := p.(type) {
+			case string:
+				if common.IsHexAddress(v) && len(v) == 42 {
+					calls[i].Params[j] = common.HexToAddress(v).Hex()
+				}
+			case map[string]interface{}:
+				for k, s := range v {
+					if str, ok := s.(string); ok && common.IsHexAddress(str) && len(str) == 42 {
+						v[k] = common.HexToAddress(str).Hex()
+					}
+				}
+			}
+		}
+	}
+	return calls
+}
+
+func TestAPIDevNodeNotSimulatedNetwork(t *testing.T) {
+	c := newClient(t)
+	if c.Cfg.Network.Name == "Anvil" {
+		t.Skip("test requires a real node")
+	}
+	require.False(t, c.IsSimulatedNetwork(), "Geth isn't a simulated network")
+	_, err := c.DevNode()
+	require.Error(t, err, "cheat codes shouldn't be available")
+}
@@ -0,0 +1,92 @@
+package seth
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	diamondLoupeABIJSON = `[
+		{"type":"function","name":"facets","stateMutability":"view","inputs":[],"outputs":[{"name":"facets_","type":"tuple[]","components":[{"name":"facetAddress","type":"address"},{"name":"functionSelectors","type":"bytes4[]"}]}]}
+	]`
+)
+
+var (
+	ErrDiamondFacets = errors.New("failed to read facets of diamond")
+)
+
+var diamondLoupeABI = mustParseABI(diamondLoupeABIJSON)
+
+// DiamondFacet is a facet of EIP-2535 diamond with selectors of functions, that the diamond delegates to it
+type DiamondFacet struct {
+	FacetAddress      common.Address `abi:"facetAddress"`
+	FunctionSelectors [][4]byte      `abi:"functionSelectors"`
+}
+
+// Facets returns facets of the diamond read with its loupe facets() function
+func (p *ProxyDetector) Facets(ctx context.Context, diamond common.Address) ([]DiamondFacet, error) {
+	data, err := diamondLoupeABI.Pack("facets")
+	if err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrDiamondFacets), "diamond: %s", diamond.Hex())
+	}
+	output, err := p.backend.CallContract(ctx, ethereum.CallMsg{To: &diamond, Data: data}, nil)
+	if err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrDiamondFacets), "diamond: %s", diamond.Hex())
+	}
+	var facets []DiamondFacet
+	if err := diamondLoupeABI.UnpackIntoInterface(&facets, "facets", output); err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrDiamondFacets), "diamond: %s", diamond.Hex())
+	}
+	return facets, nil
+}
+
+// Facet returns address of the facet, to which the diamond delegates calls of the function with the selector, or zero
+// address, if the contract isn't a diamond or has no such function. Selectors of each diamond are cached and read again
+// only when a selector is missing, so that diamond cuts made after the first call are picked up.
+func (p *ProxyDetector) Facet(ctx context.Context, diamond common.Address, selector []byte) (common.Address, error) {
+	if len(selector) != 4 {
+		return common.Address{}, nil
+	}
+	var key [4]byte
+	copy(key[:], selector)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, notDiamond := p.notDiamonds[diamond]; notDiamond {
+		return common.Address{}, nil
+	}
+	if facet, ok := p.facets[diamond][key]; ok {
+		return facet, nil
+	}
+
+	facets, err := p.Facets(ctx, diamond)
+	if err != nil || len(facets) == 0 {
+		// contracts without loupe functions revert or return data, that can't be unpacked
+		L.Trace().Err(err).Str("Address", diamond.Hex()).Msg("Contract isn't a diamond")
+		p.notDiamonds[diamond] = struct{}{}
+		return common.Address{}, nil
+	}
+	selectors := make(map[[4]byte]common.Address)
+	for _, facet := range facets {
+		for _, s := range facet.FunctionSelectors {
+			selectors[s] = facet.FacetAddress
+		}
+	}
+	p.facets[diamond] = selectors
+
+	return selectors[key], nil
+}
+
+// Target returns address of the contract, whose code is executed, when the function with the selector is called on the
+// proxy: EIP-1967 implementation or diamond's facet. Zero address is returned, if the contract isn't a proxy.
+func (p *ProxyDetector) Target(ctx context.Context, proxy common.Address, selector []byte) (common.Address, error) {
+	implementation, err := p.Implementation(ctx, proxy)
+	if err != nil || implementation != (common.Address{}) {
+		return implementation, err
+	}
+	return p.Facet(ctx, proxy, selector)
+}
@@ -0,0 +1,86 @@
+package seth_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+var diamondLoupeABI, _ = abi.JSON(strings.NewReader(`[{"type":"function","name":"facets","stateMutability":"view","inputs":[],"outputs":[{"name":"facets_","type":"tuple[]","components":[{"name":"facetAddress","type":"address"},{"name":"functionSelectors","type":"bytes4[]"}]}]}]`))
+
+func selector(signature string) [4]byte {
+	var s [4]byte
+	copy(s[:], crypto.Keccak256([]byte(signature))[:4])
+	return s
+}
+
+func TestUtilDiamondFacets(t *testing.T) {
+	diamond := common.HexToAddress("0x01")
+	facet := common.HexToAddress("0x02")
+	otherFacet := common.HexToAddress("0x03")
+	notDiamond := common.HexToAddress("0x04")
+
+	backend := &proxyBackendMock{
+		facets: map[common.Address][]seth.DiamondFacet{
+			diamond: {{FacetAddress: facet, FunctionSelectors: [][4]byte{selector("set(int256)")}}},
+		},
+	}
+	detector := seth.NewProxyDetector(backend)
+
+	set := selector("set(int256)")
+	found, err := detector.Facet(context.Background(), diamond, set[:])
+	require.NoError(t, err, "failed to find facet")
+	require.Equal(t, facet, found, "incorrect facet")
+	found, err = detector.Target(context.Background(), diamond, set[:])
+	require.NoError(t, err, "failed to find target")
+	require.Equal(t, facet, found, "facet should be the target of diamond's call")
+	require.Equal(t, 1, backend.calls, "facets should be cached")
+
+	// facets are read again after diamond cut adds a new function
+	get := selector("get()")
+	backend.facets[diamond] = append(backend.facets[diamond], seth.DiamondFacet{FacetAddress: otherFacet, FunctionSelectors: [][4]byte{get}})
+	found, err = detector.Facet(context.Background(), diamond, get[:])
+	require.NoError(t, err, "failed to find facet")
+	require.Equal(t, otherFacet, found, "facet added by diamond cut should be found")
+
+	for i := 0; i < 2; i++ {
+		found, err = detector.Facet(context.Background(), notDiamond, set[:])
+		require.NoError(t, err, "contract without loupe functions should not return an error")
+		require.Equal(t, common.Address{}, found, "contract isn't a diamond")
+	}
+	require.Equal(t, 3, backend.calls, "contract, which isn't a diamond, should be checked only once")
+}
+
+func TestContractStoreDiamondFacetABI(t *testing.T) {
+	cs, err := seth.NewContractStore("./contracts/abi", "")
+	require.NoError(t, err, "failed to create contract store")
+	cs.AddABI("Diamond", abi.ABI{})
+
+	diamond := common.HexToAddress("0x01")
+	facet := common.HexToAddress("0x02")
+	contractMap := seth.NewEmptyContractMap()
+	contractMap.AddContract(diamond.Hex(), "Diamond")
+	contractMap.AddContract(facet.Hex(), "NetworkDebugSubContract")
+
+	traceOneInt := selector("traceOneInt(int256)")
+	finder := seth.NewABIFinder(contractMap, cs)
+	finder.Proxies = seth.NewProxyDetector(&proxyBackendMock{
+		facets: map[common.Address][]seth.DiamondFacet{
+			diamond: {{FacetAddress: facet, FunctionSelectors: [][4]byte{traceOneInt}}},
+		},
+	})
+
+	result, err := finder.FindABIByMethod(diamond.Hex(), traceOneInt[:])
+	require.NoError(t, err, "method should be found in facet's ABI")
+	require.Equal(t, "traceOneInt(int256)", result.Method.Sig, "incorrect method")
+	require.Equal(t, "Diamond", result.ContractName(), "call should be attributed to the diamond")
+	require.Equal(t, diamond.Hex(), result.ProxyAddress, "proxy address should be set")
+	require.Equal(t, facet, result.Implementation, "facet should be the implementation")
+	require.Equal(t, "Diamond", contractMap.GetContractName(diamond.Hex()), "diamond shouldn't be mapped to facet")
+}
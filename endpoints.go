@@ -0,0 +1,183 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrEndpointWithoutURL = errors.New("endpoint has neither 'http_url_secret' nor 'ws_url_secret'")
+	ErrEndpointHTTPURL    = errors.New("'http_url_secret' of endpoint must be an http:// or https:// URL")
+	ErrEndpointWSURL      = errors.New("'ws_url_secret' of endpoint must be a ws:// or wss:// URL")
+)
+
+// Endpoint is a pair of HTTP and websocket URLs of the same RPC node. HTTP URL is used for regular and heavy calls (e.g. traces
+// or eth_getLogs) and websocket one for subscriptions. Either of them can be omitted.
+type Endpoint struct {
+	HTTP string `toml:"http_url_secret"`
+	WS   string `toml:"ws_url_secret"`
+	// RateLimit limits rate of requests sent to HTTP URL, it overrides network's 'rate_limit'
+	RateLimit *RateLimitCfg `toml:"rate_limit"`
+}
+
+// RPCURLs returns URLs used for RPC calls in the order of failover: HTTP URLs of endpoints (or their websocket URLs, if
+// none of them has HTTP one), if endpoints are set, otherwise 'urls_secret'
+func (n *Network) RPCURLs() []string {
+	if len(n.Endpoints) == 0 {
+		return n.URLs
+	}
+	var urls []string
+	for _, e := range n.Endpoints {
+		if e.HTTP != "" {
+			urls = append(urls, e.HTTP)
+		}
+	}
+	if len(urls) > 0 {
+		return urls
+	}
+	return n.SubscriptionURLs()
+}
+
+// SubscriptionURLs returns websocket URLs used for subscriptions in the order of failover: websocket URLs of endpoints,
+// if endpoints are set, otherwise the first of 'urls_secret', if it's a websocket one
+func (n *Network) SubscriptionURLs() []string {
+	var urls []string
+	if len(n.Endpoints) == 0 {
+		if len(n.URLs) > 0 && IsWebsocketURL(n.URLs[0]) {
+			urls = append(urls, n.URLs[0])
+		}
+		return urls
+	}
+	for _, e := range n.Endpoints {
+		if e.WS != "" {
+			urls = append(urls, e.WS)
+		}
+	}
+	return urls
+}
+
+func validateEndpoints(n *Network) error {
+	for i, e := range n.Endpoints {
+		if e == nil || (e.HTTP == "" && e.WS == "") {
+			return errors.Wrapf(ErrEndpointWithoutURL, "endpoint: %d, network: '%s'", i, n.Name)
+		}
+		if e.HTTP != "" && !isHTTPURL(e.HTTP) {
+			return errors.Wrapf(ErrEndpointHTTPURL, "endpoint: %d, network: '%s'", i, n.Name)
+		}
+		if e.WS != "" && !IsWebsocketURL(e.WS) {
+			return errors.Wrapf(ErrEndpointWSURL, "endpoint: %d, network: '%s'", i, n.Name)
+		}
+	}
+	return nil
+}
+
+func isHTTPURL(u string) bool {
+	u = strings.ToLower(u)
+	return strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://")
+}
+
+// dialRPC connects to the first of network's RPC URLs. If there are more HTTP URLs, requests that fail with connection
+// error or server error are retried with the next ones and the first one that succeeds is used for following requests.
+// Failover transport is returned only in that case.
+func dialRPC(ctx context.Context, n *Network) (*rpc.Client, *failoverTransport, error) {
+	urls := n.RPCURLs()
+	if len(urls) == 0 {
+		return nil, nil, errors.New("no RPC URL provided")
+	}
+	if len(urls) == 1 || !isHTTPURL(urls[0]) {
+		if len(urls) > 1 {
+			L.Warn().Msg("Multiple RPC URLs provided, only the first one will be used")
+		}
+		c, err := rpc.DialOptions(ctx, urls[0], n.rpcDialOptions(urls[0])...)
+		return c, nil, err
+	}
+
+	transport, err := newFailoverTransport(urls, n.rpcHTTPTransport())
+	if err != nil {
+		return nil, nil, err
+	}
+	opts := append(n.rpcHeaderOptions(), rpc.WithHTTPClient(n.rpcHTTPClient(transport)))
+	c, err := rpc.DialOptions(ctx, urls[0], opts...)
+	return c, transport, err
+}
+
+// failoverTransport sends each request to the current URL and fails over to the next ones, when it fails or it's still
+// rate limited after retries
+type failoverTransport struct {
+	urls    []*url.URL
+	current atomic.Int32
+	base    http.RoundTripper
+}
+
+func newFailoverTransport(urls []string, base http.RoundTripper) (*failoverTransport, error) {
+	t := &failoverTransport{base: base}
+	for i, raw := range urls {
+		if !isHTTPURL(raw) {
+			return nil, fmt.Errorf("HTTP failover requires all URLs to be HTTP ones, URL %d isn't", i)
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse RPC URL %d", i)
+		}
+		t.urls = append(t.urls, u)
+	}
+	return t, nil
+}
+
+// selected returns index of the URL, to which requests are sent first
+func (t *failoverTransport) selected() int {
+	return int(t.current.Load())
+}
+
+// selectEndpoint makes requests go to the URL with the index first
+func (t *failoverTransport) selectEndpoint(idx int) {
+	if idx >= 0 && idx < len(t.urls) {
+		t.current.Store(int32(idx))
+	}
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := int(t.current.Load())
+	var lastErr error
+	for i := 0; i < len(t.urls); i++ {
+		idx := (start + i) % len(t.urls)
+		r := req.Clone(req.Context())
+		u := *t.urls[idx]
+		r.URL = &u
+		r.Host = t.urls[idx].Host
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+			if idx != start && t.current.CompareAndSwap(int32(start), int32(idx)) {
+				L.Warn().
+					Int("Endpoint", idx).
+					Err(lastErr).
+					Msg("HTTP RPC endpoint failed, switched to the next one")
+			}
+			return resp, nil
+		}
+		if err == nil {
+			_ = resp.Body.Close()
+			err = fmt.Errorf("HTTP RPC endpoint %d returned %s", idx, resp.Status)
+		}
+		lastErr = err
+		if req.Context().Err() != nil {
+			return nil, req.Context().Err()
+		}
+	}
+	return nil, errors.Wrapf(lastErr, "all %d HTTP RPC endpoints failed", len(t.urls))
+}
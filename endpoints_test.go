@@ -0,0 +1,235 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIEndpointsFailover(t *testing.T) {
+	c := newClient(t)
+	httpURL := nodeHTTPURL(t, c)
+	wsURL := c.URL
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(failing.Close)
+
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.Network.Endpoints = []*seth.Endpoint{
+		{HTTP: failing.URL, WS: "ws://127.0.0.1:1"},
+		{HTTP: httpURL, WS: wsURL},
+	}
+	err = seth.ValidateConfig(cfg)
+	require.NoError(t, err, "endpoints should be valid")
+
+	client, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "client should fail over to the second HTTP endpoint")
+	require.Equal(t, failing.URL, client.URL, "URL should be the first HTTP URL")
+	require.NotNil(t, client.Subscriptions, "subscriptions should use websocket URLs")
+
+	t.Run("HTTP calls fail over", func(t *testing.T) {
+		_, err := client.Decode(TestEnv.DebugContract.AddCounter(client.NewTXOpts(), big.NewInt(1), big.NewInt(1)))
+		require.NoError(t, err, "failed to send transaction")
+		_, err = client.Client.BlockNumber(context.Background())
+		require.NoError(t, err, "failed to get block number")
+	})
+
+	t.Run("subscriptions fail over", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		heads := make(chan *types.Header, 1)
+		sub, err := client.Subscriptions.SubscribeNewHeads(ctx, heads)
+		require.NoError(t, err, "failed to subscribe to second websocket endpoint")
+		defer sub.Unsubscribe()
+		require.True(t, sub.Connected(), "subscription should be connected")
+	})
+}
+
+func TestConfigEndpoints(t *testing.T) {
+	network := &seth.Network{
+		Name: "test",
+		Endpoints: []*seth.Endpoint{
+			{HTTP: "http://node-1:8545", WS: "ws://node-1:8546"},
+			{WS: "wss://node-2"},
+			{HTTP: "https://node-3"},
+		},
+	}
+	require.Equal(t, []string{"http://node-1:8545", "https://node-3"}, network.RPCURLs(), "HTTP URLs should be used for RPC calls")
+	require.Equal(t, []string{"ws://node-1:8546", "wss://node-2"}, network.SubscriptionURLs(), "websocket URLs should be used for subscriptions")
+
+	wsOnly := &seth.Network{Endpoints: []*seth.Endpoint{{WS: "ws://node-1"}}}
+	require.Equal(t, []string{"ws://node-1"}, wsOnly.RPCURLs(), "websocket URLs should be used, if there are no HTTP ones")
+
+	legacy := &seth.Network{URLs: []string{"http://node-1"}}
+	require.Equal(t, []string{"http://node-1"}, legacy.RPCURLs(), "'urls_secret' should be used without endpoints")
+	require.Empty(t, legacy.SubscriptionURLs(), "HTTP URL can't be used for subscriptions")
+
+	for i, tc := range []struct {
+		endpoint seth.Endpoint
+		err      error
+	}{
+		{seth.Endpoint{}, seth.ErrEndpointWithoutURL},
+		{seth.Endpoint{HTTP: "ws://node"}, seth.ErrEndpointHTTPURL},
+		{seth.Endpoint{WS: "http://node"}, seth.ErrEndpointWSURL},
+	} {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Network.Name = "test"
+		endpoint := tc.endpoint
+		cfg.Network.Endpoints = []*seth.Endpoint{&endpoint}
+		require.ErrorIs(t, seth.ValidateConfig(cfg), tc.err, "case %d", i)
+	}
+}
+
+func TestAPIRPCHealthMonitor(t *testing.T) {
+	c := newClient(t)
+	if !strings.HasPrefix(c.URL, "ws://") {
+		t.Skip("test requires a websocket RPC URL")
+	}
+	httpURL := strings.Replace(strings.Replace(c.URL, "ws://", "http://", 1), ":8546", ":8545", 1)
+	if _, err := http.Post(httpURL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`)); err != nil {
+		t.Skipf("HTTP RPC endpoint %s is not available", httpURL)
+	}
+	target, err := url.Parse(httpURL)
+	require.NoError(t, err, "failed to parse HTTP URL")
+
+	// first endpoint proxies requests to geth, until it's broken
+	var broken atomic.Bool
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if broken.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(flaky.Close)
+
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.Network.URLs = nil
+	cfg.Network.Endpoints = []*seth.Endpoint{{HTTP: flaky.URL}, {HTTP: httpURL}}
+	cfg.RPCHealthMonitor = &seth.RPCHealthMonitorCfg{Interval: seth.MustMakeDuration(100 * time.Millisecond)}
+
+	degraded := make(chan seth.EndpointHealth, 10)
+	recovered := make(chan seth.EndpointHealth, 10)
+	monitor := seth.NewRPCHealthMonitor(*cfg.RPCHealthMonitor)
+	monitor.OnDegraded(func(e seth.EndpointHealth) { degraded <- e })
+	monitor.OnRecovered(func(e seth.EndpointHealth) { recovered <- e })
+
+	client, err := seth.NewClientWithConfig(cfg, seth.WithRPCHealthMonitor(monitor))
+	require.NoError(t, err, "failed to create client")
+
+	health := client.RPCHealth()
+	require.True(t, health.Healthy, "current endpoint should be healthy")
+	require.Equal(t, 0, health.Current, "first endpoint should be used")
+	require.Len(t, health.Endpoints, 2, "both endpoints should be checked")
+	for _, e := range health.Endpoints {
+		require.True(t, e.Healthy, "endpoint %d should be healthy", e.Index)
+		require.NotZero(t, e.BlockNumber, "endpoint %d should return block number", e.Index)
+		require.Equal(t, uint64(0), e.BlockLag, "endpoints of the same node shouldn't lag")
+	}
+
+	broken.Store(true)
+	select {
+	case e := <-degraded:
+		require.Equal(t, 0, e.Index, "first endpoint should be degraded")
+		require.Contains(t, e.Error, "502", "error should be returned")
+	case <-time.After(5 * time.Second):
+		t.Fatal("first endpoint wasn't reported as degraded")
+	}
+	health = client.RPCHealth()
+	require.Equal(t, 1, health.Current, "calls should be sent to the healthy endpoint")
+	require.True(t, health.Healthy, "current endpoint should be healthy")
+	_, err = client.Client.BlockNumber(context.Background())
+	require.NoError(t, err, "failed to get block number")
+
+	broken.Store(false)
+	select {
+	case e := <-recovered:
+		require.Equal(t, 0, e.Index, "first endpoint should recover")
+	case <-time.After(5 * time.Second):
+		t.Fatal("first endpoint wasn't reported as recovered")
+	}
+	require.Equal(t, 0, client.RPCHealth().Current, "calls should go back to the preferred endpoint")
+	require.NoError(t, client.Close(), "failed to close client")
+}
+
+func TestConfigRPCHealthMonitor(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.RPCHealthMonitor = &seth.RPCHealthMonitorCfg{FailureThreshold: -1}
+	require.Error(t, seth.ValidateConfig(cfg), "negative failure threshold should be rejected")
+
+	require.Equal(t, seth.RPCHealth{}, (&seth.Client{}).RPCHealth(), "health should be empty without monitor")
+}
+
+func TestAPIExistingConnection(t *testing.T) {
+	c := newClient(t)
+
+	newConfig := func(t *testing.T) *seth.Config {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Network.URLs = nil
+		cfg.Network.Endpoints = nil
+		return cfg
+	}
+
+	t.Run("client fails without URL and connection", func(t *testing.T) {
+		_, err := seth.NewClientWithConfig(newConfig(t))
+		require.Error(t, err, "client shouldn't be created without RPC URL")
+		require.Contains(t, err.Error(), "WithEthClient", "error should mention connection options")
+	})
+
+	t.Run("client uses existing ethclient", func(t *testing.T) {
+		ec, err := ethclient.Dial(c.URL)
+		require.NoError(t, err, "failed to connect to node")
+		defer ec.Close()
+
+		cfg := newConfig(t)
+		cfg.TracingLevel = seth.TracingLevel_All
+		client, err := seth.NewClientWithConfig(cfg, seth.WithEthClient(ec))
+		require.NoError(t, err, "failed to create client with existing connection")
+		require.Same(t, ec, client.Client, "existing connection should be used")
+		require.Equal(t, c.ChainID, client.ChainID, "chain ID should be fetched with existing connection")
+
+		decoded, err := client.Decode(TestEnv.DebugContract.AddCounter(client.NewTXOpts(), big.NewInt(1), big.NewInt(1)))
+		require.NoError(t, err, "failed to send transaction")
+		_, ok := client.Tracer.GetTrace(decoded.Hash)
+		require.True(t, ok, "transaction should be traced with existing connection")
+
+		require.NoError(t, client.Close(), "failed to close client")
+		_, err = ec.BlockNumber(context.Background())
+		require.NoError(t, err, "existing connection shouldn't be closed by the client")
+	})
+
+	t.Run("client uses existing rpc client", func(t *testing.T) {
+		rc, err := rpc.Dial(c.URL)
+		require.NoError(t, err, "failed to connect to node")
+		defer rc.Close()
+
+		client, err := seth.NewClientWithConfig(newConfig(t), seth.WithRPCClient(rc))
+		require.NoError(t, err, "failed to create client with existing connection")
+		require.Same(t, rc, client.Client.Client(), "existing connection should be used")
+		require.NoError(t, client.Close(), "failed to close client")
+
+		var block string
+		require.NoError(t, rc.Call(&block, "eth_blockNumber"), "existing connection shouldn't be closed by the client")
+	})
+}
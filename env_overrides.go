@@ -0,0 +1,219 @@
+package seth
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// EnvOverridePrefix is the prefix of environment variables overriding config fields
+	EnvOverridePrefix = "SETH_"
+)
+
+var (
+	ErrEnvOverride = errors.New("invalid value of environment variable")
+)
+
+// reservedEnvVars are environment variables with their own meaning, they never override config fields
+var reservedEnvVars = map[string]struct{}{
+	CONFIG_FILE_ENV_VAR:       {},
+	KEYFILE_BASE64_ENV_VAR:    {},
+	KEYFILE_PATH_ENV_VAR:      {},
+	ROOT_PRIVATE_KEY_ENV_VAR:  {},
+	NETWORK_ENV_VAR:           {},
+	URL_ENV_VAR:               {},
+	ONE_PASS_VAULT_ENV_VAR:    {},
+	LogLevelEnvVar:            {},
+	KEYSTORE_PASSWORD_ENV_VAR: {},
+	MNEMONIC_ENV_VAR:          {},
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// ApplyEnvOverrides overrides config fields with values of environment variables. Name of the variable is "SETH_" followed
+// by upper-cased TOML keys of the field joined with "_", e.g. SETH_TRACING_LEVEL for 'tracing_level',
+// SETH_NONCE_MANAGER_KEY_SYNC_TIMEOUT for 'key_sync_timeout' of [nonce_manager] and SETH_NETWORK_GAS_PRICE for 'gas_price'
+// of the selected network. Values are parsed the same way as in TOML (durations like "10s", amounts like "3 gwei"), lists
+// are comma-separated and maps are comma-separated "key=value" pairs. Lists of tables (e.g. [[networks]]) can't be
+// overridden and variables with their own meaning (e.g. SETH_NETWORK or SETH_KEYFILE_PATH) are never used as overrides.
+// Returns names of applied variables.
+func ApplyEnvOverrides(cfg *Config) ([]string, error) {
+	applied := make([]string, 0)
+	if _, err := applyEnvOverrides(reflect.ValueOf(cfg).Elem(), strings.TrimSuffix(EnvOverridePrefix, "_"), &applied); err != nil {
+		return nil, err
+	}
+	sort.Strings(applied)
+	for _, name := range applied {
+		L.Debug().Str("Variable", name).Msg("Config field overridden with environment variable")
+	}
+	return applied, nil
+}
+
+// applyEnvOverrides overrides fields of the struct, returns true if any was overridden
+func applyEnvOverrides(v reflect.Value, prefix string, applied *[]string) (bool, error) {
+	overridden := false
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("toml"), ",")[0]
+		if !field.IsExported() || tag == "" || tag == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(tag)
+		fv := v.Field(i)
+
+		if isEnvOverrideStruct(field.Type) {
+			ok, err := applyEnvOverridesToStruct(fv, name, applied)
+			if err != nil {
+				return false, err
+			}
+			overridden = overridden || ok
+			continue
+		}
+
+		if _, reserved := reservedEnvVars[name]; reserved {
+			continue
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		supported, err := setFromEnv(fv, value)
+		if err != nil {
+			return false, errors.Wrapf(wrapError(err, ErrEnvOverride), "variable: %s", name)
+		}
+		if supported {
+			*applied = append(*applied, name)
+			overridden = true
+		}
+	}
+	return overridden, nil
+}
+
+// applyEnvOverridesToStruct overrides fields of nested struct, nil pointer is set only if any of its fields was overridden
+func applyEnvOverridesToStruct(fv reflect.Value, prefix string, applied *[]string) (bool, error) {
+	if fv.Kind() != reflect.Ptr {
+		return applyEnvOverrides(fv, prefix, applied)
+	}
+	if !fv.IsNil() {
+		return applyEnvOverrides(fv.Elem(), prefix, applied)
+	}
+	nv := reflect.New(fv.Type().Elem())
+	ok, err := applyEnvOverrides(nv.Elem(), prefix, applied)
+	if err != nil || !ok {
+		return false, err
+	}
+	fv.Set(nv)
+	return true, nil
+}
+
+// isEnvOverrideStruct returns true for config tables, whose fields are overridden one by one
+func isEnvOverrideStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// setFromEnv sets field to the value of environment variable, returns false if field's type can't be overridden
+func setFromEnv(fv reflect.Value, value string) (bool, error) {
+	if fv.Kind() == reflect.Ptr {
+		nv := reflect.New(fv.Type().Elem())
+		supported, err := setFromEnv(nv.Elem(), value)
+		if err != nil || !supported {
+			return supported, err
+		}
+		fv.Set(nv)
+		return true, nil
+	}
+	if reflect.PointerTo(fv.Type()).Implements(textUnmarshalerType) {
+		return true, fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return true, err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.ReplaceAll(value, "_", ""), 10, fv.Type().Bits())
+		if err != nil {
+			return true, err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.ReplaceAll(value, "_", ""), 10, fv.Type().Bits())
+		if err != nil {
+			return true, err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return true, err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return false, nil
+		}
+		items := splitEnvList(value)
+		s := reflect.MakeSlice(fv.Type(), len(items), len(items))
+		for i, item := range items {
+			s.Index(i).SetString(item)
+		}
+		fv.Set(s)
+	case reflect.Map:
+		if fv.Type().Key().Kind() != reflect.String || fv.Type().Elem().Kind() != reflect.String {
+			return false, nil
+		}
+		m := reflect.MakeMap(fv.Type())
+		for _, pair := range splitEnvList(value) {
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				return true, fmt.Errorf("expected comma-separated key=value pairs, got '%s'", pair)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(k)).Convert(fv.Type().Key()), reflect.ValueOf(strings.TrimSpace(v)).Convert(fv.Type().Elem()))
+		}
+		fv.Set(m)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// splitEnvList splits comma-separated list, empty value is an empty list
+func splitEnvList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// logEffectiveConfig logs config with secrets redacted at debug level
+func logEffectiveConfig(cfg *Config) {
+	if L.GetLevel() > zerolog.DebugLevel {
+		return
+	}
+	snapshot, err := redactedConfigSnapshot(cfg)
+	if err != nil {
+		L.Debug().Err(err).Msg("Failed to dump effective seth config")
+		return
+	}
+	L.Debug().Interface("Config", snapshot).Msg("Effective seth config")
+}
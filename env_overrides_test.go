@@ -0,0 +1,56 @@
+package seth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigEnvOverrides(t *testing.T) {
+	cfg := &seth.Config{
+		TracingLevel: "reverted",
+		Network:      &seth.Network{Name: "Geth", URLs: []string{"ws://localhost:8546"}},
+	}
+
+	t.Setenv("SETH_TRACING_LEVEL", "all")
+	t.Setenv("SETH_NETWORK_GAS_PRICE_AMOUNT", "3 gwei")
+	t.Setenv("SETH_NETWORK_EIP_1559_DYNAMIC_FEES", "true")
+	t.Setenv("SETH_NETWORK_TRANSACTION_TIMEOUT", "2m")
+	t.Setenv("SETH_NETWORK_URLS_SECRET", "ws://a:8546, ws://b:8546")
+	t.Setenv("SETH_NETWORK_HTTP_HEADERS_SECRET", "X-Api-Key=secret")
+	t.Setenv("SETH_EPHEMERAL_ADDRESSES_NUMBER", "5")
+	t.Setenv("SETH_NONCE_MANAGER_KEY_SYNC_RETRIES", "3")
+	// variables with their own meaning aren't overrides
+	t.Setenv(seth.KEYFILE_PATH_ENV_VAR, "other_keyfile.toml")
+
+	applied, err := seth.ApplyEnvOverrides(cfg)
+	require.NoError(t, err, "failed to apply overrides")
+	require.Equal(t, []string{
+		"SETH_EPHEMERAL_ADDRESSES_NUMBER",
+		"SETH_NETWORK_EIP_1559_DYNAMIC_FEES",
+		"SETH_NETWORK_GAS_PRICE_AMOUNT",
+		"SETH_NETWORK_HTTP_HEADERS_SECRET",
+		"SETH_NETWORK_TRANSACTION_TIMEOUT",
+		"SETH_NETWORK_URLS_SECRET",
+		"SETH_NONCE_MANAGER_KEY_SYNC_RETRIES",
+		"SETH_TRACING_LEVEL",
+	}, applied, "applied overrides should match")
+
+	require.Equal(t, "all", cfg.TracingLevel, "tracing level should be overridden")
+	require.Equal(t, seth.MustParseAmount("3 gwei"), cfg.Network.GasPriceAmount, "gas price should be overridden")
+	require.True(t, cfg.Network.EIP1559DynamicFees, "dynamic fees should be overridden")
+	require.Equal(t, 2*time.Minute, cfg.Network.TxnTimeout.Duration(), "transaction timeout should be overridden")
+	require.Equal(t, []string{"ws://a:8546", "ws://b:8546"}, cfg.Network.URLs, "URLs should be overridden")
+	require.Equal(t, map[string]string{"X-Api-Key": "secret"}, cfg.Network.HTTPHeaders, "headers should be overridden")
+	require.Equal(t, int64(5), *cfg.EphemeralAddrs, "number of ephemeral addresses should be overridden")
+	require.NotNil(t, cfg.NonceManager, "missing table should be created")
+	require.Equal(t, uint(3), cfg.NonceManager.KeySyncRetries, "nonce manager setting should be overridden")
+	require.Empty(t, cfg.KeyFilePath, "reserved variable shouldn't override keyfile path")
+	require.Nil(t, cfg.GasSpikeBreaker, "tables without overrides shouldn't be created")
+
+	t.Setenv("SETH_NETWORK_GAS_PRICE_AMOUNT", "-1")
+	_, err = seth.ApplyEnvOverrides(cfg)
+	require.ErrorContains(t, err, "SETH_NETWORK_GAS_PRICE_AMOUNT", "invalid value should be rejected")
+}
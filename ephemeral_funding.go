@@ -0,0 +1,237 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	DefaultEphemeralFundingBatchSize  = 20
+	DefaultEphemeralFundingRetries    = 3
+	DefaultEphemeralFundingRetryDelay = time.Second
+)
+
+var (
+	ErrEphemeralFundingBatchSize = errors.New("'batch_size' of ephemeral funding must be greater than or equal to 0")
+	ErrEphemeralFundingDelay     = errors.New("'retry_delay' of ephemeral funding must be positive")
+	ErrFundEphemeralKey          = errors.New("failed to fund ephemeral key")
+)
+
+// EphemeralFundingCfg configures how ephemeral keys are funded from the root key
+type EphemeralFundingCfg struct {
+	// BatchSize is the maximum number of funding transfers sent at the same time, 0 means default [default: 20]
+	BatchSize int `toml:"batch_size"`
+	// Retries is the number of times failed funding transfer is retried [default: 3]
+	Retries *uint `toml:"retries"`
+	// RetryDelay is the delay before the first retry, it doubles with each next one [default: 1s]
+	RetryDelay *Duration `toml:"retry_delay"`
+	// Lazy makes keys funded on their first use (when transaction options are created for them or they send funds)
+	// instead of all at once, when client is created
+	Lazy bool `toml:"lazy"`
+}
+
+func validateEphemeralFundingCfg(cfg *EphemeralFundingCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.BatchSize < 0 {
+		return ErrEphemeralFundingBatchSize
+	}
+	if cfg.RetryDelay != nil && cfg.RetryDelay.Duration() <= 0 {
+		return ErrEphemeralFundingDelay
+	}
+	return nil
+}
+
+func (c *EphemeralFundingCfg) batchSize() int {
+	if c == nil || c.BatchSize == 0 {
+		return DefaultEphemeralFundingBatchSize
+	}
+	return c.BatchSize
+}
+
+func (c *EphemeralFundingCfg) retries() uint {
+	if c == nil || c.Retries == nil {
+		return DefaultEphemeralFundingRetries
+	}
+	return *c.Retries
+}
+
+func (c *EphemeralFundingCfg) retryDelay() time.Duration {
+	if c == nil || c.RetryDelay == nil {
+		return DefaultEphemeralFundingRetryDelay
+	}
+	return c.RetryDelay.Duration()
+}
+
+func (c *EphemeralFundingCfg) lazy() bool {
+	return c != nil && c.Lazy
+}
+
+// lazyFunding keeps track of ephemeral keys funded on their first use
+type lazyFunding struct {
+	amount *big.Int
+	mu     sync.Mutex
+	keys   map[int]*sync.Mutex
+	funded map[int]bool
+}
+
+// keyLock returns lock of the key, which is held while the key is being funded
+func (f *lazyFunding) keyLock(keyNum int) *sync.Mutex {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.keys[keyNum]; !ok {
+		f.keys[keyNum] = &sync.Mutex{}
+	}
+	return f.keys[keyNum]
+}
+
+// fundEphemeralKeys sends amount (and network's 'ephemeral_tokens') from the root key to all other keys, at most 'batch_size'
+// transfers are sent at the same time and failed ones are retried. If funding is lazy, amount is only remembered and keys are
+// funded by ensureKeyFunded.
+func (m *Client) fundEphemeralKeys(ctx context.Context, amount *big.Int, fees TransferFees) error {
+	fundingCfg := m.Cfg.EphemeralFunding
+	if fundingCfg.lazy() {
+		m.lazyFunding = &lazyFunding{amount: amount, keys: make(map[int]*sync.Mutex), funded: make(map[int]bool)}
+		m.logger().Info().
+			Int("Keys", len(m.Addresses)-1).
+			Str("Amount", FormatWei(amount)).
+			Msg("Ephemeral keys will be funded on their first use")
+		return nil
+	}
+
+	total := len(m.Addresses) - 1
+	var funded atomic.Int64
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(fundingCfg.batchSize())
+	// root key is element 0 in ephemeral
+	for keyNum := 1; keyNum < len(m.Addresses); keyNum++ {
+		keyNum := keyNum
+		eg.Go(func() error {
+			if err := m.fundEphemeralKey(egCtx, keyNum, amount, fees); err != nil {
+				return err
+			}
+			if done := funded.Add(1); done%int64(fundingCfg.batchSize()) == 0 || done == int64(total) {
+				m.logger().Info().
+					Int64("Funded", done).
+					Int("Total", total).
+					Msg("Funding ephemeral keys")
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	return m.fundKeysWithTokens(ctx, m.Addresses[1:])
+}
+
+// fundEphemeralKey sends amount from the root key to the key, failed transfer is retried with exponential backoff, unless
+// the key already received the funds (e.g. when waiting for the transfer timed out, but it was mined after all)
+func (m *Client) fundEphemeralKey(ctx context.Context, keyNum int, amount *big.Int, fees TransferFees) error {
+	fundingCfg := m.Cfg.EphemeralFunding
+	attempt := 0
+	err := retry.Do(
+		func() error {
+			attempt++
+			if attempt > 1 {
+				balance, err := m.Client.BalanceAt(ctx, m.Addresses[keyNum], nil)
+				if err == nil && balance.Cmp(amount) >= 0 {
+					return nil
+				}
+				// failed transfer could have left a gap in root key's nonces
+				if err := m.reconcileFundingNonce(ctx); err != nil {
+					return err
+				}
+			}
+			return m.sendFundingTransfer(ctx, keyNum, amount, fees)
+		},
+		retry.Context(ctx),
+		retry.Attempts(fundingCfg.retries()+1),
+		retry.Delay(fundingCfg.retryDelay()),
+		retry.DelayType(retry.BackOffDelay),
+		retry.LastErrorOnly(true),
+		retry.OnRetry(func(i uint, err error) {
+			m.logger().Warn().
+				Err(err).
+				Int("KeyNum", keyNum).
+				Uint("Attempt", i+1).
+				Msg("Failed to fund ephemeral key, retrying")
+		}),
+	)
+	return errors.Wrapf(wrapError(err, ErrFundEphemeralKey), "key: %d", keyNum)
+}
+
+// sendFundingTransfer sends amount from the root key to the key and waits for it to be mined. Root key's nonce is taken and
+// the transfer is sent under shared funding lock, so that its nonce is never reconciled, while it's not sent yet.
+func (m *Client) sendFundingTransfer(ctx context.Context, keyNum int, amount *big.Int, fees TransferFees) error {
+	if err := m.waitForGasSpikeBreaker(ctx); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.Cfg.Network.TxnTimeout.Duration())
+	defer cancel()
+	m.fundingMu.RLock()
+	tx, err := m.sendETHFromKey(ctx, 0, m.Addresses[keyNum], amount, fees)
+	m.fundingMu.RUnlock()
+	if err != nil {
+		return err
+	}
+	l := m.logger().With().Str("Transaction", tx.Hash().Hex()).Logger()
+	l.Debug().
+		Int("KeyNum", keyNum).
+		Str("Amount", FormatWei(amount)).
+		Msg("Sent funds to ephemeral key")
+	_, err = m.WaitMined(ctx, l, m.Client, tx)
+	return err
+}
+
+// reconcileFundingNonce reconciles root key's nonce with exclusive funding lock, so that no other funding transfer holds
+// a nonce, which isn't sent yet, and it's not allocated twice
+func (m *Client) reconcileFundingNonce(ctx context.Context) error {
+	m.fundingMu.Lock()
+	defer m.fundingMu.Unlock()
+	return m.NonceManager.ReconcileNonce(ctx, m.Addresses[0])
+}
+
+// ensureKeyFunded funds ephemeral key (with native funds and network's 'ephemeral_tokens') on its first use, if funding is
+// lazy. Root key and keys, that were already funded, are skipped.
+func (m *Client) ensureKeyFunded(ctx context.Context, keyNum int) error {
+	f := m.lazyFunding
+	if f == nil || keyNum <= 0 || keyNum >= len(m.Addresses) {
+		return nil
+	}
+	lock := f.keyLock(keyNum)
+	lock.Lock()
+	defer lock.Unlock()
+	f.mu.Lock()
+	funded := f.funded[keyNum]
+	f.mu.Unlock()
+	if funded {
+		return nil
+	}
+
+	m.logger().Info().
+		Int("KeyNum", keyNum).
+		Str("Address", m.Addresses[keyNum].Hex()).
+		Str("Amount", FormatWei(f.amount)).
+		Msg("Funding ephemeral key on its first use")
+	if err := m.fundEphemeralKey(ctx, keyNum, f.amount, m.SuggestedTransferFees(ctx)); err != nil {
+		return err
+	}
+	if err := m.fundKeysWithTokens(ctx, []common.Address{m.Addresses[keyNum]}); err != nil {
+		return errors.Wrapf(wrapError(err, ErrFundEphemeralKey), "key: %d", keyNum)
+	}
+
+	f.mu.Lock()
+	f.funded[keyNum] = true
+	f.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,85 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigEphemeralFundingValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	cfg.EphemeralFunding = &seth.EphemeralFundingCfg{BatchSize: -1}
+	require.Error(t, seth.ValidateConfig(cfg), "negative batch size should be rejected")
+
+	zero, err := seth.MakeDuration(0)
+	require.NoError(t, err, "failed to make duration")
+	cfg.EphemeralFunding = &seth.EphemeralFundingCfg{RetryDelay: &zero}
+	require.Error(t, seth.ValidateConfig(cfg), "zero retry delay should be rejected")
+
+	delay, err := seth.MakeDuration(500 * time.Millisecond)
+	require.NoError(t, err, "failed to make duration")
+	cfg.EphemeralFunding = &seth.EphemeralFundingCfg{BatchSize: 5, RetryDelay: &delay, Lazy: true}
+	require.NoError(t, seth.ValidateConfig(cfg), "valid ephemeral funding should be accepted")
+}
+
+func TestAPIEphemeralFundingBatches(t *testing.T) {
+	_ = os.Unsetenv(seth.KEYFILE_PATH_ENV_VAR)
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	var ten int64 = 10
+	cfg.EphemeralAddrs = &ten
+	cfg.EphemeralFunding = &seth.EphemeralFundingCfg{BatchSize: 3}
+	limitEphemeralFunds(t, cfg, seth.EtherToWei(big.NewFloat(1)))
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	t.Cleanup(func() {
+		_ = seth.ReturnFunds(c, c.Addresses[0].Hex())
+	})
+
+	for keyNum := 1; keyNum < len(c.Addresses); keyNum++ {
+		balance, err := c.Client.BalanceAt(context.Background(), c.Addresses[keyNum], nil)
+		require.NoError(t, err, "failed to get balance")
+		require.Equal(t, 1, balance.Sign(), "key %d should be funded", keyNum)
+	}
+}
+
+func TestAPIEphemeralFundingLazy(t *testing.T) {
+	_ = os.Unsetenv(seth.KEYFILE_PATH_ENV_VAR)
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	var three int64 = 3
+	cfg.EphemeralAddrs = &three
+	cfg.EphemeralFunding = &seth.EphemeralFundingCfg{Lazy: true}
+	limitEphemeralFunds(t, cfg, seth.EtherToWei(big.NewFloat(1)))
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	t.Cleanup(func() {
+		_ = seth.ReturnFunds(c, c.Addresses[0].Hex())
+	})
+
+	balance, err := c.Client.BalanceAt(context.Background(), c.Addresses[1], nil)
+	require.NoError(t, err, "failed to get balance")
+	require.Zero(t, balance.Sign(), "key shouldn't be funded before its first use")
+
+	opts := c.NewTXKeyOpts(1)
+	require.Nil(t, opts.Context.Value(seth.ContextErrorKey{}), "transaction options shouldn't have an error")
+
+	balance, err = c.Client.BalanceAt(context.Background(), c.Addresses[1], nil)
+	require.NoError(t, err, "failed to get balance")
+	require.Equal(t, 1, balance.Sign(), "key should be funded on its first use")
+
+	balance, err = c.Client.BalanceAt(context.Background(), c.Addresses[2], nil)
+	require.NoError(t, err, "failed to get balance")
+	require.Zero(t, balance.Sign(), "unused key shouldn't be funded")
+}
@@ -0,0 +1,202 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// erc20TransferGasLimit is the gas limit of a single token transfer, which is reserved from root key's balance, when
+	// splitting funds between keys
+	erc20TransferGasLimit = 100_000
+
+	erc20ABIJSON = `[
+		{"type":"function","name":"transfer","stateMutability":"nonpayable","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+		{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]}
+	]`
+)
+
+var (
+	ErrTokenFundingAddress      = errors.New("'token' of ephemeral token must be a valid address")
+	ErrTokenFundingAmount       = errors.New("'amount' of ephemeral token must be a positive amount")
+	ErrInsufficientTokenBalance = errors.New("root key has insufficient token balance to fund ephemeral addresses")
+	ErrTokenTransferFailed      = errors.New("failed to transfer token")
+)
+
+var erc20ABI = mustParseABI(erc20ABIJSON)
+
+// TokenFunding is an ERC-20 token sent from the root key to every ephemeral address or keyfile key together with native
+// funds and returned together with them by ReturnFunds(). Amount is in the smallest units of the token and accepts the same
+// formats as other amounts, so for tokens with 18 decimals (e.g. LINK) "10 ether" means 10 tokens.
+type TokenFunding struct {
+	Token  string `toml:"token"`
+	Amount Amount `toml:"amount"`
+}
+
+func validateTokenFunding(n *Network) error {
+	for i, t := range n.EphemeralTokens {
+		if t == nil || !common.IsHexAddress(t.Token) {
+			return errors.Wrapf(ErrTokenFundingAddress, "ephemeral token: %d, network: '%s'", i, n.Name)
+		}
+		if t.Amount.IsZero() {
+			return errors.Wrapf(ErrTokenFundingAmount, "ephemeral token: %d, network: '%s'", i, n.Name)
+		}
+	}
+	return nil
+}
+
+// estimateL1TokenTransferFee returns L1 data fee of a transfer of the first ephemeral token, which is paid on OP-stack chains
+func (m *Client) estimateL1TokenTransferFee(to common.Address) (*big.Int, error) {
+	if m.L1FeeOracle == nil || len(m.Cfg.Network.EphemeralTokens) == 0 {
+		return big.NewInt(0), nil
+	}
+	token := common.HexToAddress(m.Cfg.Network.EphemeralTokens[0].Token)
+	data, err := erc20ABI.Pack("transfer", to, m.Cfg.Network.EphemeralTokens[0].Amount.Wei())
+	if err != nil {
+		return nil, err
+	}
+	return m.estimateL1Fee(context.Background(), &token, nil, data, erc20TransferGasLimit)
+}
+
+// ERC20BalanceOf returns token balance of the address
+func (m *Client) ERC20BalanceOf(ctx context.Context, token, address common.Address) (*big.Int, error) {
+	data, err := erc20ABI.Pack("balanceOf", address)
+	if err != nil {
+		return nil, err
+	}
+	out, err := m.Client.CallContract(ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get balance of token %s", token.Hex())
+	}
+	unpacked, err := erc20ABI.Unpack("balanceOf", out)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unpack balance of token %s", token.Hex())
+	}
+	return unpacked[0].(*big.Int), nil
+}
+
+// TransferERC20FromKey transfers amount of the token from the key to the address and waits for the transaction to be mined.
+// Like TransferETHFromKey() it takes the nonce from the nonce manager, so that many transfers from the same key can be sent
+// concurrently.
+func (m *Client) TransferERC20FromKey(ctx context.Context, fromKeyNum int, token, to common.Address, amount *big.Int) error {
+	if fromKeyNum >= len(m.PrivateKeys) || fromKeyNum >= len(m.Addresses) {
+		return errors.Wrap(ErrNoKeyLoaded, fmt.Sprintf("requested key: %d", fromKeyNum))
+	}
+	if err := m.waitForGasSpikeBreaker(ctx); err != nil {
+		return err
+	}
+	data, err := erc20ABI.Pack("transfer", to, amount)
+	if err != nil {
+		return err
+	}
+
+	// pending nonce isn't queried, because it would change while other transfers are sent
+	opts, err := m.newSigningTransactor(ctx, fromKeyNum)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create transactor for key %d", fromKeyNum)
+	}
+	from := m.Addresses[fromKeyNum]
+	m.configureTransactionOpts(opts, m.NonceManager.NextNonce(from).Uint64(), m.CalculateGasEstimationsCtx(ctx, m.NewDefaultGasEstimationRequest()))
+	tx, err := bind.NewBoundContract(token, erc20ABI, m.Client, m.Client, m.Client).RawTransact(opts, data)
+	if err != nil {
+		if reconcileErr := m.NonceManager.ReconcileNonce(context.Background(), from); reconcileErr != nil {
+			m.logger().Warn().Err(reconcileErr).Msg("Failed to reconcile nonce after failed token transfer")
+		}
+		return errors.Wrapf(wrapError(err, ErrTokenTransferFailed), "amount: %s, token: %s, receiver: %s", amount.String(), token.Hex(), to.Hex())
+	}
+
+	m.logger().Info().
+		Int("FromKeyNum", fromKeyNum).
+		Str("Token", token.Hex()).
+		Str("To", to.Hex()).
+		Str("Amount", amount.String()).
+		Str("Transaction", tx.Hash().Hex()).
+		Msg("Send ERC-20 tokens")
+
+	if _, err := m.Decode(tx, nil); err != nil {
+		return errors.Wrapf(wrapError(err, ErrTokenTransferFailed), "amount: %s, token: %s, receiver: %s", amount.String(), token.Hex(), to.Hex())
+	}
+	return nil
+}
+
+// checkTokenBalances checks that root key has enough of each of network's 'ephemeral_tokens' to fund given number of keys,
+// so that funding fails before any funds are sent
+func (m *Client) checkTokenBalances(ctx context.Context, receivers int64) error {
+	for _, t := range m.Cfg.Network.EphemeralTokens {
+		token := common.HexToAddress(t.Token)
+		balance, err := m.ERC20BalanceOf(ctx, token, m.Addresses[0])
+		if err != nil {
+			return err
+		}
+		needed := new(big.Int).Mul(t.Amount.Wei(), big.NewInt(receivers))
+		if balance.Cmp(needed) < 0 {
+			return errors.Wrapf(ErrInsufficientTokenBalance, "balance: %s, token: %s, needed: %s, ephemeral addresses: %d", balance.String(), token.Hex(), needed.String(), receivers)
+		}
+	}
+	return nil
+}
+
+// fundKeysWithTokens sends network's 'ephemeral_tokens' from the root key to all given addresses
+func (m *Client) fundKeysWithTokens(ctx context.Context, addresses []common.Address) error {
+	eg, egCtx := errgroup.WithContext(ctx)
+	for _, t := range m.Cfg.Network.EphemeralTokens {
+		amount := t.Amount.Wei()
+		token := common.HexToAddress(t.Token)
+		for _, addr := range addresses {
+			addr := addr
+			eg.Go(func() error {
+				return m.TransferERC20FromKey(egCtx, 0, token, addr, amount)
+			})
+		}
+	}
+	return eg.Wait()
+}
+
+// returnTokens sends the whole balance of each of network's 'ephemeral_tokens' from the key to the address. Keys without
+// any tokens are skipped.
+func (m *Client) returnTokens(ctx context.Context, fromKeyNum int, to common.Address) error {
+	for _, t := range m.Cfg.Network.EphemeralTokens {
+		token := common.HexToAddress(t.Token)
+		balance, err := m.ERC20BalanceOf(ctx, token, m.Addresses[fromKeyNum])
+		if err != nil {
+			return err
+		}
+		if balance.Sign() == 0 {
+			continue
+		}
+		m.logger().Info().
+			Str("Key", m.Addresses[fromKeyNum].Hex()).
+			Str("Token", token.Hex()).
+			Str("Balance", balance.String()).
+			Msg("Returning tokens")
+		if err := m.TransferERC20FromKey(ctx, fromKeyNum, token, to, balance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tokenBalances returns balances of network's 'ephemeral_tokens' of the address keyed by token address or nil, if there are
+// no tokens configured
+func (m *Client) tokenBalances(ctx context.Context, address common.Address) (map[string]string, error) {
+	if len(m.Cfg.Network.EphemeralTokens) == 0 {
+		return nil, nil
+	}
+	balances := make(map[string]string, len(m.Cfg.Network.EphemeralTokens))
+	for _, t := range m.Cfg.Network.EphemeralTokens {
+		token := common.HexToAddress(t.Token)
+		balance, err := m.ERC20BalanceOf(ctx, token, address)
+		if err != nil {
+			return nil, err
+		}
+		balances[token.Hex()] = balance.String()
+	}
+	return balances, nil
+}
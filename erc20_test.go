@@ -0,0 +1,115 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIEphemeralTokenFunding(t *testing.T) {
+	_ = os.Unsetenv(seth.KEYFILE_PATH_ENV_VAR)
+	root := newClient(t)
+	token := TestEnv.LinkTokenContract.Address()
+
+	_, err := root.Decode(TestEnv.LinkTokenContract.GrantMintRole(root.NewTXOpts(), root.Addresses[0]))
+	require.NoError(t, err, "failed to grant mint role")
+	_, err = root.Decode(TestEnv.LinkTokenContract.Mint(root.NewTXOpts(), root.Addresses[0], big.NewInt(100)))
+	require.NoError(t, err, "failed to mint tokens")
+
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	var three int64 = 3
+	cfg.EphemeralAddrs = &three
+	limitEphemeralFunds(t, cfg, seth.EtherToWei(big.NewFloat(1)))
+	cfg.Network.EphemeralTokens = []*seth.TokenFunding{{Token: token.Hex(), Amount: seth.NewAmount(big.NewInt(5))}}
+	require.NoError(t, seth.ValidateConfig(cfg), "config should be valid")
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	t.Cleanup(func() {
+		_ = seth.ReturnFunds(c, c.Addresses[0].Hex())
+	})
+
+	for _, addr := range c.Addresses[1:] {
+		balance, err := c.ERC20BalanceOf(context.Background(), token, addr)
+		require.NoError(t, err, "failed to get token balance")
+		require.Equal(t, int64(5), balance.Int64(), "ephemeral address should receive tokens")
+	}
+
+	rootBalance, err := c.ERC20BalanceOf(context.Background(), token, c.Addresses[0])
+	require.NoError(t, err, "failed to get token balance")
+	cfg.Network.EphemeralTokens[0].Amount = seth.NewAmount(new(big.Int).Add(rootBalance, big.NewInt(1)))
+	limitEphemeralFunds(t, cfg, seth.EtherToWei(big.NewFloat(1)))
+	_, err = seth.NewClientWithConfig(cfg)
+	require.Error(t, err, "client should not be created without enough tokens")
+	needed := new(big.Int).Mul(new(big.Int).Add(rootBalance, big.NewInt(1)), big.NewInt(3))
+	require.ErrorIs(t, err, seth.ErrInsufficientTokenBalance, "incorrect error")
+	require.ErrorContains(t, err, needed.String(), "error should contain amount needed")
+}
+
+func TestConfigEphemeralTokenFundingValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	cfg.Network.EphemeralTokens = []*seth.TokenFunding{{Token: "not an address", Amount: seth.NewAmount(big.NewInt(1))}}
+	require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrTokenFundingAddress, "token address should be validated")
+
+	cfg.Network.EphemeralTokens = []*seth.TokenFunding{{Token: TestEnv.LinkTokenContract.Address().Hex(), Amount: seth.NewAmount(big.NewInt(0))}}
+	require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrTokenFundingAmount, "amount should be validated")
+}
+
+func TestAPIKeyfileTokenFundingAndReturn(t *testing.T) {
+	keyFilePath := "keyfile_test_tokens.toml"
+	_ = os.Remove(keyFilePath)
+	t.Cleanup(func() {
+		_ = os.Remove(keyFilePath)
+	})
+	minter := newClient(t)
+	token := TestEnv.LinkTokenContract.Address()
+
+	_, err := minter.Decode(TestEnv.LinkTokenContract.GrantMintRole(minter.NewTXOpts(), minter.Addresses[0]))
+	require.NoError(t, err, "failed to grant mint role")
+	_, err = minter.Decode(TestEnv.LinkTokenContract.Mint(minter.NewTXOpts(), minter.Addresses[0], big.NewInt(100)))
+	require.NoError(t, err, "failed to mint tokens")
+
+	// new client, so that its nonce manager starts after minting transactions
+	c := newClient(t)
+	rootBalance, err := c.ERC20BalanceOf(context.Background(), token, c.Addresses[0])
+	require.NoError(t, err, "failed to get token balance")
+
+	c.Cfg.KeyFilePath = keyFilePath
+	c.Cfg.Network.EphemeralTokens = []*seth.TokenFunding{{Token: token.Hex(), Amount: seth.NewAmount(big.NewInt(7))}}
+	// keys share only one or two ether of root key's balance, no matter how much previous tests left to it
+	balance, err := c.Client.BalanceAt(context.Background(), c.Addresses[0], nil)
+	require.NoError(t, err, "failed to get root key balance")
+	buffer := new(big.Int).Div(balance, big.NewInt(params.Ether)).Int64() - 1
+	require.Positive(t, buffer, "root key should have at least 2 ether")
+	opts := &seth.FundKeyFileCmdOpts{Addrs: 2, RootKeyBuffer: buffer, LocalKeyfile: true}
+	require.NoError(t, seth.UpdateAndSplitFunds(c, opts), "failed to fund keyfile")
+
+	kf, _, err := c.CreateOrUnmarshalKeyFile(opts)
+	require.NoError(t, err, "failed to read keyfile")
+	require.Len(t, kf.Keys, 2, "incorrect number of keys")
+	for _, kfd := range kf.Keys {
+		require.Equal(t, map[string]string{token.Hex(): "7"}, kfd.TokenFunds, "keyfile token balance is incorrect")
+		balance, err := c.ERC20BalanceOf(context.Background(), token, common.HexToAddress(kfd.Address))
+		require.NoError(t, err, "failed to get token balance")
+		require.Equal(t, int64(7), balance.Int64(), "key should receive tokens")
+	}
+
+	require.NoError(t, seth.ReturnFundsFromKeyFileAndUpdateIt(c, c.Addresses[0].Hex(), opts), "failed to return funds")
+	kf, _, err = c.CreateOrUnmarshalKeyFile(opts)
+	require.NoError(t, err, "failed to read keyfile")
+	for _, kfd := range kf.Keys {
+		require.Equal(t, map[string]string{token.Hex(): "0"}, kfd.TokenFunds, "tokens should be returned")
+	}
+	returned, err := c.ERC20BalanceOf(context.Background(), token, c.Addresses[0])
+	require.NoError(t, err, "failed to get token balance")
+	require.Equal(t, rootBalance.String(), returned.String(), "root key should get all tokens back")
+}
@@ -0,0 +1,131 @@
+package seth
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// GlobalErrorScope is the scope of collected errors, which aren't related to any key, e.g. failure to find a synced key
+const GlobalErrorScope = "global"
+
+// KeyErrorScope returns scope of collected errors related to the key, e.g. failure to create transaction options for it
+func KeyErrorScope(address common.Address) string {
+	return address.Hex()
+}
+
+// keyErrorScope returns scope of errors related to the key or global scope, if there's no such key
+func (m *Client) keyErrorScope(keyNum int) string {
+	if keyNum < 0 || keyNum >= len(m.Addresses) {
+		return GlobalErrorScope
+	}
+	return KeyErrorScope(m.Addresses[keyNum])
+}
+
+// ErrorCollector collects errors, that happened in calls, which can't return them (e.g. creation of transaction options or
+// background key syncing). Errors are grouped by scope: key scope (see KeyErrorScope), GlobalErrorScope or any custom scope,
+// e.g. per operation. It's safe for concurrent use.
+type ErrorCollector struct {
+	mu     *sync.Mutex
+	errors map[string][]error
+}
+
+// NewErrorCollector creates a new empty error collector
+func NewErrorCollector() *ErrorCollector {
+	return &ErrorCollector{
+		mu:     &sync.Mutex{},
+		errors: make(map[string][]error),
+	}
+}
+
+// Add adds the error to the scope, nil errors are ignored
+func (e *ErrorCollector) Add(scope string, err error) {
+	if err == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errors[scope] = append(e.errors[scope], err)
+}
+
+// Has returns true if there are any errors in given scopes or in any scope, if none is given
+func (e *ErrorCollector) Has(scopes ...string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(scopes) == 0 {
+		return len(e.errors) > 0
+	}
+	for _, scope := range scopes {
+		if len(e.errors[scope]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Drain returns and removes errors of given scopes or of all scopes, if none is given. Errors of each scope are returned in
+// the order they were added, scopes are ordered by name, when all are drained.
+func (e *ErrorCollector) Drain(scopes ...string) []error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(scopes) == 0 {
+		for scope := range e.errors {
+			scopes = append(scopes, scope)
+		}
+		sort.Strings(scopes)
+	}
+	var drained []error
+	for _, scope := range scopes {
+		drained = append(drained, e.errors[scope]...)
+		delete(e.errors, scope)
+	}
+	return drained
+}
+
+// discard removes the first occurrence of the error from any scope, e.g. when it was already returned to the caller
+func (e *ErrorCollector) discard(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for scope, errs := range e.errors {
+		for i, collected := range errs {
+			if collected != err {
+				continue
+			}
+			if len(errs) == 1 {
+				delete(e.errors, scope)
+			} else {
+				e.errors[scope] = append(errs[:i:i], errs[i+1:]...)
+			}
+			return
+		}
+	}
+}
+
+// errorCollectorKey is a context key of the collector, which collected error set in transaction options' context
+type errorCollectorKey struct{}
+
+// addError adds the error to the scope of client's error collector and to deprecated Errors
+func (m *Client) addError(scope string, err error) {
+	if err == nil {
+		return
+	}
+	m.ErrorCollector.Add(scope, err)
+	m.ErrorCollector.mu.Lock()
+	defer m.ErrorCollector.mu.Unlock()
+	m.Errors = append(m.Errors, err)
+}
+
+// HasErrors returns true if client collected any errors, that weren't drained yet
+func (m *Client) HasErrors() bool {
+	return m.ErrorCollector.Has()
+}
+
+// DrainErrors returns and removes all errors collected by the client
+func (m *Client) DrainErrors() []error {
+	errs := m.ErrorCollector.Drain()
+	m.ErrorCollector.mu.Lock()
+	defer m.ErrorCollector.mu.Unlock()
+	m.Errors = nil
+	return errs
+}
@@ -0,0 +1,61 @@
+package seth_test
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUtilErrorCollector(t *testing.T) {
+	collector := seth.NewErrorCollector()
+	require.False(t, collector.Has(), "new collector should be empty")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			collector.Add(fmt.Sprintf("scope-%d", i%2), fmt.Errorf("error %d", i))
+		}(i)
+	}
+	wg.Wait()
+	collector.Add(seth.GlobalErrorScope, nil)
+
+	require.True(t, collector.Has(), "collector should have errors")
+	require.True(t, collector.Has("scope-0", "unknown"), "scope should have errors")
+	require.False(t, collector.Has(seth.GlobalErrorScope), "nil error shouldn't be added")
+	require.Len(t, collector.Drain("scope-0"), 5, "only errors of the scope should be drained")
+	require.False(t, collector.Has("scope-0"), "drained scope should be empty")
+	require.Len(t, collector.Drain(), 5, "remaining errors should be drained")
+	require.False(t, collector.Has(), "collector should be empty")
+}
+
+func TestAPIScopedErrors(t *testing.T) {
+	c := newClient(t)
+	other := seth.KeyErrorScope(c.Addresses[0]) + "-other"
+	c.ErrorCollector.Add(other, errors.New("other goroutine's error"))
+	c.ErrorCollector.Add(seth.KeyErrorScope(c.Addresses[0]), errors.New("previous call error"))
+
+	_, err := c.Decode(TestEnv.DebugContract.Set(c.NewTXOpts(), big.NewInt(1)))
+	require.EqualError(t, err, "previous call error", "error of transaction's key should be returned")
+
+	_, err = c.Decode(TestEnv.DebugContract.Set(c.NewTXOpts(), big.NewInt(1)))
+	require.NoError(t, err, "error should be returned only once and errors of other scopes should be ignored")
+
+	c.ErrorCollector.Add(seth.GlobalErrorScope, errors.New("background error"))
+	_, err = c.Decode(TestEnv.DebugContract.Set(c.NewTXOpts(), big.NewInt(1)))
+	require.EqualError(t, err, "background error", "global error should be returned")
+
+	_, err = c.Decode(TestEnv.DebugContract.Set(c.NewTXKeyOpts(len(c.Addresses)+1), big.NewInt(1)))
+	require.ErrorIs(t, err, seth.ErrTransactOptsWithError, "error of transaction options should be returned")
+	_, err = c.Decode(TestEnv.DebugContract.Set(c.NewTXOpts(), big.NewInt(1)))
+	require.NoError(t, err, "error of transaction options shouldn't be returned again")
+	require.True(t, c.HasErrors(), "other scope's error should be kept")
+	require.Equal(t, []error{errors.New("other goroutine's error")}, c.DrainErrors(), "incorrect drained errors")
+	require.False(t, c.HasErrors(), "all errors should be drained")
+}
@@ -0,0 +1,116 @@
+package seth
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Sentinel errors, which can be matched with errors.Is(), no matter how many times they were wrapped. Details of the
+// failure are added with errors.Wrapf() and its cause with wrapError()
+var (
+	// ErrRpcHealthCheckFailed is returned, when RPC node doesn't pass the health check
+	ErrRpcHealthCheckFailed = errors.New("RPC health check failed ¯\\_(ツ)_/¯")
+	// ErrNoABIMethod is returned, when no ABI in the contract store has a method with transaction's selector
+	ErrNoABIMethod = errors.New("no ABI method found")
+	// ErrNoKeyAvailable is returned by TryAcquireKey(), when all keys are leased
+	ErrNoKeyAvailable = errors.New("no key is available, all keys are leased")
+	// ErrRetryTimeout is returned by RetryTxAndDecode(), when all attempts to send the transaction failed
+	ErrRetryTimeout = errors.New("retry timeout")
+	// ErrTransactOptsWithError is returned, when transaction options with an error set in their context are used
+	ErrTransactOptsWithError = errors.New("transaction options had an error set, they can't be used to send transactions")
+	// ErrTransactionReverted is matched by every RevertError
+	ErrTransactionReverted = errors.New("transaction reverted")
+	// ErrNothingToSweep is returned by SweepKey(), when key's balance doesn't cover the transfer fee
+	ErrNothingToSweep = errors.New("nothing to sweep")
+)
+
+// sentinelError wraps the cause with a sentinel error, so that both of them match with errors.Is(), its message is
+// "<sentinel>: <cause>"
+type sentinelError struct {
+	sentinel error
+	cause    error
+}
+
+// wrapError wraps err with the sentinel error, nil error isn't wrapped
+func wrapError(err, sentinel error) error {
+	if err == nil {
+		return nil
+	}
+	return &sentinelError{sentinel: sentinel, cause: err}
+}
+
+func (e *sentinelError) Error() string {
+	return e.sentinel.Error() + ": " + e.cause.Error()
+}
+
+func (e *sentinelError) Cause() error {
+	return e.cause
+}
+
+func (e *sentinelError) Unwrap() error {
+	return e.cause
+}
+
+func (e *sentinelError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+// RevertError is returned by Decode() for reverted transactions. Reason is the decoded revert message (or the error returned
+// by the node, if it couldn't be decoded), CustomErrorName, Args and Params are set only for custom Solidity errors and
+// Contract is the name of the contract, whose ABI declares the custom error. It matches ErrTransactionReverted and wraps
+// the original error, so its message stays the same.
+type RevertError struct {
+	Reason          string
+	CustomErrorName string
+	// Args are decoded values of custom error's arguments in the order of declaration
+	Args []interface{}
+	// Params are decoded arguments of custom error with their names and types
+	Params   []RevertReasonParam
+	Contract string
+	err      error
+}
+
+// newRevertError creates revert error from decoded revert reason, which can be nil, and the error of the replayed call
+func newRevertError(reason *RevertReason, err error) *RevertError {
+	revertErr := &RevertError{err: err}
+	if reason == nil {
+		revertErr.Reason = err.Error()
+		return revertErr
+	}
+	revertErr.Reason = reason.Message
+	if reason.IsCustomError() {
+		revertErr.CustomErrorName = reason.Name
+		revertErr.Contract = reason.Contract
+		revertErr.Params = reason.Params
+		for _, p := range reason.Params {
+			revertErr.Args = append(revertErr.Args, p.Value)
+		}
+	}
+	return revertErr
+}
+
+// Arg returns decoded value of custom error's argument with given name
+func (e *RevertError) Arg(name string) (interface{}, bool) {
+	for _, p := range e.Params {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (e *RevertError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("%s: %s", ErrTransactionReverted, e.Reason)
+	}
+	return e.err.Error()
+}
+
+func (e *RevertError) Unwrap() error {
+	return e.err
+}
+
+func (e *RevertError) Is(target error) bool {
+	return target == ErrTransactionReverted
+}
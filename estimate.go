@@ -0,0 +1,242 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNoABIForContract      = errors.New("no ABI found for contract in contract store")
+	ErrNoMethodInABI         = errors.New("method not found in ABI of contract")
+	ErrAmbiguousMethod       = errors.New("method is overloaded, use its full signature")
+	ErrMethodArgsCount       = errors.New("incorrect number of method arguments")
+	ErrUnsupportedMethodArg  = errors.New("unsupported argument type, only basic types (int, uint, bool, address, string, bytes) are supported")
+	ErrInvalidMethodArgValue = errors.New("invalid value of method argument")
+)
+
+// PriorityCost is the expected cost of a transaction sent with given priority
+type PriorityCost struct {
+	Priority string
+	// GasPrice is the gas price for legacy transactions or the max fee cap for EIP-1559 transactions
+	GasPrice *big.Int
+	// Cost is the maximum cost of the transaction in wei (gas limit * gas price + L1 fee)
+	Cost *big.Int
+}
+
+// CallCostEstimation holds the estimated gas limit of a contract call and its expected cost at each priority
+type CallCostEstimation struct {
+	Method   string
+	GasLimit uint64
+	// L1Fee is the L1 data fee included in costs on OP-stack chains, it's 0 on other chains
+	L1Fee *big.Int
+	Costs []PriorityCost
+}
+
+// EstimateContractCallCost ABI-encodes the call to contract method identified by its signature (e.g. "foo(uint256)") or name,
+// runs eth_estimateGas for it and estimates the fees for each transaction priority. Arguments are passed as strings and converted
+// to ABI types of the method. ABI of the contract has to be present in the ContractStore. It doesn't send any transactions.
+func (m *Client) EstimateContractCallCost(ctx context.Context, from, to common.Address, contractName, methodSig string, args []string) (*CallCostEstimation, error) {
+	if m.ContractStore == nil {
+		return nil, errors.New("ABIStore is nil")
+	}
+	contractAbi, ok := m.ContractStore.GetABI(contractName)
+	if !ok {
+		return nil, errors.Wrapf(ErrNoABIForContract, "contract: %s", contractName)
+	}
+
+	method, err := findMethodBySignature(contractAbi, contractName, methodSig)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedArgs, err := ParseMethodArgs(method, args)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := contractAbi.Pack(method.Name, parsedArgs...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to ABI-encode call to %s", method.Sig)
+	}
+
+	gasLimit, err := m.Client.EstimateGas(ctx, ethereum.CallMsg{
+		From: from,
+		To:   &to,
+		Data: data,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to estimate gas for %s", method.Sig)
+	}
+
+	l1Fee, err := m.estimateL1Fee(ctx, &to, nil, data, gasLimit)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to estimate L1 fee for %s", method.Sig)
+	}
+
+	estimation := &CallCostEstimation{
+		Method:   method.Sig,
+		GasLimit: gasLimit,
+		L1Fee:    l1Fee,
+	}
+
+	for _, priority := range []string{Priority_Slow, Priority_Standard, Priority_Fast} {
+		gasPrice := m.suggestedGasPriceForEstimation(ctx, priority)
+		cost := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gasLimit))
+		estimation.Costs = append(estimation.Costs, PriorityCost{
+			Priority: priority,
+			GasPrice: gasPrice,
+			Cost:     cost.Add(cost, l1Fee),
+		})
+	}
+
+	return estimation, nil
+}
+
+// suggestedGasPriceForEstimation returns the suggested gas price (or max fee cap for EIP-1559 networks) for given priority,
+// falling back to values from the network config if suggestion fails
+func (m *Client) suggestedGasPriceForEstimation(ctx context.Context, priority string) *big.Int {
+	if m.Cfg.Network.EIP1559DynamicFees {
+		feeCap, _, err := m.GetSuggestedEIP1559Fees(ctx, priority)
+		if err != nil {
+			m.logger().Warn().Err(err).Str("Priority", priority).Msg("Failed to get suggested EIP-1559 fees, using fee cap from config")
+			return big.NewInt(m.Cfg.Network.GasFeeCap)
+		}
+		return feeCap
+	}
+
+	gasPrice, err := m.GetSuggestedLegacyFees(ctx, priority)
+	if err != nil {
+		m.logger().Warn().Err(err).Str("Priority", priority).Msg("Failed to get suggested legacy fees, using gas price from config")
+		return big.NewInt(m.Cfg.Network.GasPrice)
+	}
+	return gasPrice
+}
+
+// findMethodBySignature finds method by its full signature (e.g. "foo(uint256)") or, for convenience, by its name. Name of
+// an overloaded method is ambiguous, so it's an error.
+func findMethodBySignature(contractAbi *abi.ABI, contractName, methodSig string) (abi.Method, error) {
+	methodSig = strings.ReplaceAll(methodSig, " ", "")
+	var byName []abi.Method
+	for _, method := range contractAbi.Methods {
+		if method.Sig == methodSig {
+			return method, nil
+		}
+		if method.RawName == methodSig {
+			byName = append(byName, method)
+		}
+	}
+	switch len(byName) {
+	case 0:
+		return abi.Method{}, errors.Wrapf(ErrNoMethodInABI, "method: %s, contract: %s", methodSig, contractName)
+	case 1:
+		return byName[0], nil
+	default:
+		sigs := make([]string, 0, len(byName))
+		for _, method := range byName {
+			sigs = append(sigs, method.Sig)
+		}
+		sort.Strings(sigs)
+		return abi.Method{}, errors.Wrapf(ErrAmbiguousMethod, "method: %s, contract: %s, full signatures: %s", methodSig, contractName, strings.Join(sigs, ", "))
+	}
+}
+
+// ParseMethodArgs converts string arguments to Go types expected by the ABI encoder for given method. Only basic
+// types are supported: integers (decimal or 0x-prefixed hex), bools, addresses, strings, dynamic and fixed-size bytes (hex).
+func ParseMethodArgs(method abi.Method, args []string) ([]interface{}, error) {
+	if len(method.Inputs) != len(args) {
+		return nil, errors.Wrapf(ErrMethodArgsCount, "method %s expects %d arguments, but %d were given", method.Sig, len(method.Inputs), len(args))
+	}
+
+	parsed := make([]interface{}, 0, len(args))
+	for i, input := range method.Inputs {
+		v, err := parseMethodArg(input.Type, args[i])
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, v)
+	}
+
+	return parsed, nil
+}
+
+func parseMethodArg(t abi.Type, value string) (interface{}, error) {
+	invalidValueErr := errors.Wrapf(ErrInvalidMethodArgValue, "value '%s' for argument of type %s", value, t.String())
+
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		n, ok := new(big.Int).SetString(value, 0)
+		if !ok {
+			return nil, invalidValueErr
+		}
+		if t.Size > 64 {
+			if !fitsIntType(t, n) {
+				return nil, invalidValueErr
+			}
+			return n, nil
+		}
+		v := reflect.New(t.GetType()).Elem()
+		if t.T == abi.IntTy {
+			if !n.IsInt64() || v.OverflowInt(n.Int64()) {
+				return nil, invalidValueErr
+			}
+			v.SetInt(n.Int64())
+		} else {
+			if !n.IsUint64() || v.OverflowUint(n.Uint64()) {
+				return nil, invalidValueErr
+			}
+			v.SetUint(n.Uint64())
+		}
+		return v.Interface(), nil
+	case abi.BoolTy:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, invalidValueErr
+		}
+		return b, nil
+	case abi.AddressTy:
+		if !common.IsHexAddress(value) {
+			return nil, invalidValueErr
+		}
+		return common.HexToAddress(value), nil
+	case abi.StringTy:
+		return value, nil
+	case abi.BytesTy:
+		b, err := hexutil.Decode(value)
+		if err != nil {
+			return nil, invalidValueErr
+		}
+		return b, nil
+	case abi.FixedBytesTy:
+		b, err := hexutil.Decode(value)
+		if err != nil || len(b) > t.Size {
+			return nil, invalidValueErr
+		}
+		v := reflect.New(t.GetType()).Elem()
+		reflect.Copy(v, reflect.ValueOf(b))
+		return v.Interface(), nil
+	default:
+		return nil, errors.Wrapf(ErrUnsupportedMethodArg, "type: %s", t.String())
+	}
+}
+
+// fitsIntType returns true if the number is within the range of int or uint ABI type
+func fitsIntType(t abi.Type, n *big.Int) bool {
+	if t.T == abi.UintTy {
+		return n.Sign() >= 0 && n.BitLen() <= t.Size
+	}
+	// signed range is [-2^(size-1), 2^(size-1)-1]
+	if n.Sign() < 0 {
+		return new(big.Int).Sub(new(big.Int).Neg(n), big.NewInt(1)).BitLen() < t.Size
+	}
+	return n.BitLen() < t.Size
+}
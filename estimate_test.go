@@ -0,0 +1,100 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/smartcontractkit/seth"
+	sethcmd "github.com/smartcontractkit/seth/cmd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIEstimateContractCallCost(t *testing.T) {
+	c := newClient(t)
+
+	estimation, err := c.EstimateContractCallCost(context.Background(), c.Addresses[0], TestEnv.DebugContractAddress, "NetworkDebugContract", "addCounter(int256, int256)", []string{"1", "0x02"})
+	require.NoError(t, err, "failed to estimate call cost")
+	require.Equal(t, "addCounter(int256,int256)", estimation.Method, "incorrect method")
+	require.Greater(t, estimation.GasLimit, uint64(21_000), "gas limit should be greater than intrinsic gas")
+	require.Len(t, estimation.Costs, 3, "there should be a cost for each priority")
+
+	for _, cost := range estimation.Costs {
+		require.Equal(t, new(big.Int).Mul(cost.GasPrice, new(big.Int).SetUint64(estimation.GasLimit)), cost.Cost, "incorrect cost for priority %s", cost.Priority)
+	}
+
+	_, err = c.EstimateContractCallCost(context.Background(), c.Addresses[0], TestEnv.DebugContractAddress, "NetworkDebugContract", "noSuchMethod(uint256)", []string{"1"})
+	require.Error(t, err, "should fail for unknown method")
+	require.ErrorIs(t, err, seth.ErrNoMethodInABI, "incorrect error")
+
+	_, err = c.EstimateContractCallCost(context.Background(), c.Addresses[0], TestEnv.DebugContractAddress, "NetworkDebugContract", "set", []string{"1", "2"})
+	require.Error(t, err, "should fail for incorrect number of arguments")
+	require.ErrorIs(t, err, seth.ErrMethodArgsCount, "incorrect error")
+
+	_, err = c.EstimateContractCallCost(context.Background(), c.Addresses[0], TestEnv.DebugContractAddress, "NetworkDebugContract", "processNestedData", []string{"1"})
+	require.Error(t, err, "should fail for name of overloaded method")
+	require.Contains(t, err.Error(), "overloaded", "incorrect error")
+}
+
+func TestUtilParseMethodArgs(t *testing.T) {
+	newType := func(s string) abi.Type {
+		typ, err := abi.NewType(s, "", nil)
+		require.NoError(t, err, "failed to create ABI type")
+		return typ
+	}
+
+	type tc struct {
+		name     string
+		abiType  string
+		value    string
+		expected interface{}
+		err      error
+	}
+
+	tcs := []tc{
+		{name: "uint256", abiType: "uint256", value: "100", expected: big.NewInt(100)},
+		{name: "int256 negative", abiType: "int256", value: "-100", expected: big.NewInt(-100)},
+		{name: "uint8 hex", abiType: "uint8", value: "0xff", expected: uint8(255)},
+		{name: "uint8 overflow", abiType: "uint8", value: "256", err: seth.ErrInvalidMethodArgValue},
+		{name: "uint256 negative", abiType: "uint256", value: "-1", err: seth.ErrInvalidMethodArgValue},
+		{name: "uint128 overflow", abiType: "uint128", value: "0x100000000000000000000000000000000", err: seth.ErrInvalidMethodArgValue},
+		{name: "int128 min", abiType: "int128", value: "-0x80000000000000000000000000000000", expected: new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 127))},
+		{name: "int128 underflow", abiType: "int128", value: "-0x80000000000000000000000000000001", err: seth.ErrInvalidMethodArgValue},
+		{name: "int128 overflow", abiType: "int128", value: "0x80000000000000000000000000000000", err: seth.ErrInvalidMethodArgValue},
+		{name: "int64", abiType: "int64", value: "-5", expected: int64(-5)},
+		{name: "bool", abiType: "bool", value: "true", expected: true},
+		{name: "address", abiType: "address", value: "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266", expected: common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")},
+		{name: "invalid address", abiType: "address", value: "0x123", err: seth.ErrInvalidMethodArgValue},
+		{name: "string", abiType: "string", value: "hello", expected: "hello"},
+		{name: "bytes", abiType: "bytes", value: "0x0102", expected: []byte{1, 2}},
+		{name: "bytes2", abiType: "bytes2", value: "0x0102", expected: [2]byte{1, 2}},
+		{name: "array", abiType: "uint256[]", value: "1", err: seth.ErrUnsupportedMethodArg},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			method := abi.NewMethod("foo", "foo", abi.Function, "nonpayable", false, false, abi.Arguments{{Type: newType(tc.abiType)}}, nil)
+			parsed, err := seth.ParseMethodArgs(method, []string{tc.value})
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err, "incorrect error")
+				return
+			}
+			require.NoError(t, err, "failed to parse method args")
+			require.Equal(t, []interface{}{tc.expected}, parsed, "incorrect parsed value")
+		})
+	}
+}
+
+func TestCLIEstimate(t *testing.T) {
+	// estimate command uses a random root key, restore the original one afterwards
+	rootKey := os.Getenv(seth.ROOT_PRIVATE_KEY_ENV_VAR)
+	t.Cleanup(func() {
+		_ = os.Setenv(seth.ROOT_PRIVATE_KEY_ENV_VAR, rootKey)
+	})
+
+	err := sethcmd.RunCLI([]string{"seth", "-n", os.Getenv(seth.NETWORK_ENV_VAR), "estimate", "--contract", "NetworkDebugContract", "--sig", "set(int256)", "--args", "1", "--address", TestEnv.DebugContractAddress.Hex()})
+	require.NoError(t, err, "failed to estimate call cost")
+}
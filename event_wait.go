@@ -0,0 +1,78 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrEventWaitTimeout = errors.New("event was not emitted before timeout")
+)
+
+// BloomMayContain returns true if logs bloom may contain logs emitted by the address with all given topics. False positives are
+// possible, false negatives are not, so blocks for which it returns false can be safely skipped.
+func BloomMayContain(bloom types.Bloom, address common.Address, topics ...common.Hash) bool {
+	if !types.BloomLookup(bloom, address) {
+		return false
+	}
+	for _, topic := range topics {
+		if !types.BloomLookup(bloom, topic) {
+			return false
+		}
+	}
+	return true
+}
+
+// WaitForEvent waits until a log with given topic (event ID) is emitted by the address in any block starting from fromBlock and
+// returns the first one found. Blocks are checked one by one as the chain progresses and `eth_getLogs` is called only for blocks,
+// whose header logs bloom may contain the log, which drastically reduces number of RPC calls during long waits on quiet chains.
+func (m *Client) WaitForEvent(ctx context.Context, address common.Address, topic common.Hash, fromBlock uint64) (*types.Log, error) {
+	next := fromBlock
+	for {
+		latest, err := m.Client.BlockNumber(ctx)
+		if err != nil {
+			m.logger().Debug().Err(err).Msg("Failed to get latest block number, while waiting for event")
+		}
+
+		for ; err == nil && next <= latest; next++ {
+			header, headerErr := m.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(next))
+			if headerErr != nil {
+				m.logger().Debug().Err(headerErr).Uint64("Block", next).Msg("Failed to get block header, while waiting for event")
+				break
+			}
+			if !BloomMayContain(header.Bloom, address, topic) {
+				continue
+			}
+
+			logs, logsErr := m.Client.FilterLogs(ctx, ethereum.FilterQuery{
+				BlockHash: ptr(header.Hash()),
+				Addresses: []common.Address{address},
+				Topics:    [][]common.Hash{{topic}},
+			})
+			if logsErr != nil {
+				m.logger().Debug().Err(logsErr).Uint64("Block", next).Msg("Failed to get logs, while waiting for event")
+				break
+			}
+			if len(logs) > 0 {
+				return &logs[0], nil
+			}
+			m.logger().Trace().Uint64("Block", next).Msg("Logs bloom false positive, no matching logs in block")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(wrapError(ctx.Err(), ErrEventWaitTimeout), "topic: %s, emitter: %s", topic.Hex(), address.Hex())
+		case <-time.After(m.Cfg.Network.ReceiptPollingDelay(0)):
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
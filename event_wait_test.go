@@ -0,0 +1,54 @@
+package seth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUtilBloomMayContain(t *testing.T) {
+	address := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	topic := crypto.Keccak256Hash([]byte("OneIndexEvent(uint256)"))
+	otherTopic := crypto.Keccak256Hash([]byte("TwoIndexEvent(uint256,address)"))
+
+	var bloom types.Bloom
+	bloom.Add(address.Bytes())
+	bloom.Add(topic.Bytes())
+
+	require.True(t, seth.BloomMayContain(bloom, address, topic), "bloom should contain address and topic")
+	require.True(t, seth.BloomMayContain(bloom, address), "bloom should contain address")
+	require.False(t, seth.BloomMayContain(bloom, address, otherTopic), "bloom should not contain other topic")
+	require.False(t, seth.BloomMayContain(bloom, common.HexToAddress("0x1"), topic), "bloom should not contain other address")
+	require.False(t, seth.BloomMayContain(types.Bloom{}, address, topic), "empty bloom should not contain anything")
+}
+
+func TestAPIWaitForEvent(t *testing.T) {
+	c := newClient(t)
+
+	fromBlock, err := c.Client.BlockNumber(context.Background())
+	require.NoError(t, err, "failed to get block number")
+
+	debugAbi, ok := c.ContractStore.GetABI("NetworkDebugContract")
+	require.True(t, ok, "ABI not found")
+	topic := debugAbi.Events["OneIndexEvent"].ID
+
+	decoded, err := c.Decode(TestEnv.DebugContract.EmitOneIndexEvent(c.NewTXOpts()))
+	require.NoError(t, err, "failed to emit event")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	log, err := c.WaitForEvent(ctx, TestEnv.DebugContractAddress, topic, fromBlock)
+	require.NoError(t, err, "event should be found")
+	require.Equal(t, decoded.Hash, log.TxHash.Hex(), "event should be emitted by the sent transaction")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err = c.WaitForEvent(ctx, TestEnv.DebugContractAddress, debugAbi.Events["CallbackEvent"].ID, fromBlock)
+	require.Error(t, err, "wait for event that was not emitted should time out")
+}
@@ -0,0 +1,268 @@
+package seth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultExplorerTimeout = 10 * time.Second
+	// DefaultExplorerCacheDir is the directory, where ABIs downloaded from explorers are cached, keyed by chain ID and address
+	DefaultExplorerCacheDir = "explorer_abis"
+)
+
+var (
+	ErrExplorerURL          = errors.New("explorer 'url' must be a valid http(s) URL")
+	ErrExplorerRequest      = errors.New("failed to query explorer for ABI")
+	ErrExplorerResponse     = errors.New("explorer returned error for ABI")
+	ErrContractNotVerified  = errors.New("contract is not verified on the explorer")
+	ErrParseExplorerABI     = errors.New("failed to parse ABI returned by explorer")
+	ErrExplorerNotAvailable = errors.New("ABI is unavailable, explorer query failed before")
+)
+
+// ExplorerCfg configures Etherscan-compatible block explorer API, which is used to download verified ABIs of contracts,
+// whose calls can't be decoded with ABIs from the contract store
+type ExplorerCfg struct {
+	// URL is the API endpoint, e.g. "https://api.etherscan.io/api"
+	URL string `toml:"url"`
+	// APIKey is sent as 'apikey' query param
+	APIKey  string    `toml:"api_key_secret"`
+	Timeout *Duration `toml:"timeout"`
+	// CacheDir is the directory, where downloaded ABIs are saved, so that each contract is queried only once; caching is
+	// disabled, if it's set to "-"
+	CacheDir string `toml:"cache_dir"`
+}
+
+func validateExplorerCfg(n *Network) error {
+	if n.Explorer == nil {
+		return nil
+	}
+	u, err := url.Parse(n.Explorer.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Wrapf(ErrExplorerURL, "network: '%s'", n.Name)
+	}
+	if n.Explorer.Timeout == nil {
+		n.Explorer.Timeout = MustMakeDuration(DefaultExplorerTimeout)
+	}
+	if n.Explorer.CacheDir == "" {
+		n.Explorer.CacheDir = DefaultExplorerCacheDir
+	}
+	return nil
+}
+
+// ABIResolver finds ABI of a contract, when it isn't in the contract store. Name is used to add the ABI to the store.
+type ABIResolver interface {
+	ResolveABI(ctx context.Context, address common.Address) (name string, contractABI *abi.ABI, err error)
+}
+
+// ExplorerABIResolver downloads verified ABIs from Etherscan-compatible explorer API. Addresses, for which the query failed,
+// are not queried again, so that traces of unverified contracts don't hit the API (and its rate limit) for every call.
+type ExplorerABIResolver struct {
+	cfg     *ExplorerCfg
+	chainID int64
+	client  *http.Client
+	mu      sync.Mutex
+	failed  map[common.Address]error
+}
+
+// NewExplorerABIResolver creates ABI resolver for the explorer of the chain
+func NewExplorerABIResolver(cfg *ExplorerCfg, chainID int64) *ExplorerABIResolver {
+	timeout := DefaultExplorerTimeout
+	if cfg.Timeout != nil {
+		timeout = cfg.Timeout.Duration()
+	}
+	return &ExplorerABIResolver{
+		cfg:     cfg,
+		chainID: chainID,
+		client:  &http.Client{Timeout: timeout},
+		failed:  make(map[common.Address]error),
+	}
+}
+
+// explorerABI is saved in the cache directory
+type explorerABI struct {
+	Name string          `json:"name"`
+	ABI  json.RawMessage `json:"abi"`
+}
+
+// ResolveABI returns name and ABI of the verified contract, from the cache if it was downloaded before
+func (r *ExplorerABIResolver) ResolveABI(ctx context.Context, address common.Address) (string, *abi.ABI, error) {
+	// requests are serialised, as explorers' rate limits are low and the same address is often resolved concurrently
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.failed[address]; err != nil {
+		return "", nil, errors.Wrapf(wrapError(err, ErrExplorerNotAvailable), "contract: %s", address.Hex())
+	}
+
+	cached, err := r.readCache(address)
+	if err != nil {
+		L.Debug().Err(err).Str("Address", address.Hex()).Msg("Failed to read cached explorer ABI")
+	}
+	if cached == nil {
+		cached, err = r.download(ctx, address)
+		if err != nil {
+			r.failed[address] = err
+			return "", nil, err
+		}
+		r.writeCache(address, cached)
+	}
+
+	contractABI, err := abi.JSON(strings.NewReader(string(cached.ABI)))
+	if err != nil {
+		err = errors.Wrapf(wrapError(err, ErrParseExplorerABI), "contract: %s", address.Hex())
+		r.failed[address] = err
+		return "", nil, err
+	}
+	return cached.Name, &contractABI, nil
+}
+
+func (r *ExplorerABIResolver) download(ctx context.Context, address common.Address) (*explorerABI, error) {
+	query := url.Values{}
+	query.Set("module", "contract")
+	query.Set("action", "getsourcecode")
+	query.Set("address", address.Hex())
+	if r.cfg.APIKey != "" {
+		query.Set("apikey", r.cfg.APIKey)
+	}
+	u, err := url.Parse(r.cfg.URL)
+	if err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrExplorerRequest), "contract: %s", address.Hex())
+	}
+	for k, v := range u.Query() {
+		query[k] = v
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrExplorerRequest), "contract: %s", address.Hex())
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		// error contains the URL with API key
+		return nil, errors.Wrapf(wrapError(errors.New(redactURLQuery(err.Error(), r.cfg.APIKey)), ErrExplorerRequest), "contract: %s", address.Hex())
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrapf(ErrExplorerResponse, "contract: %s, error: %s", address.Hex(), resp.Status)
+	}
+
+	var body struct {
+		Status  string          `json:"status"`
+		Message string          `json:"message"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrExplorerRequest), "contract: %s", address.Hex())
+	}
+	var results []struct {
+		ABI          string `json:"ABI"`
+		ContractName string `json:"ContractName"`
+	}
+	if body.Status != "1" || json.Unmarshal(body.Result, &results) != nil || len(results) == 0 {
+		// on errors result is a string with description
+		var reason string
+		_ = json.Unmarshal(body.Result, &reason)
+		return nil, errors.Wrapf(ErrExplorerResponse, "contract: %s, error: %s", address.Hex(), strings.TrimSpace(body.Message+" "+reason))
+	}
+	if results[0].ContractName == "" || !strings.HasPrefix(strings.TrimSpace(results[0].ABI), "[") {
+		return nil, errors.Wrapf(ErrContractNotVerified, "contract: %s", address.Hex())
+	}
+
+	L.Info().
+		Str("Address", address.Hex()).
+		Str("Contract", results[0].ContractName).
+		Msg("Downloaded verified ABI from explorer")
+
+	return &explorerABI{Name: results[0].ContractName, ABI: json.RawMessage(results[0].ABI)}, nil
+}
+
+func (r *ExplorerABIResolver) cachePath(address common.Address) string {
+	if r.cfg.CacheDir == "" || r.cfg.CacheDir == "-" {
+		return ""
+	}
+	return filepath.Join(r.cfg.CacheDir, fmt.Sprint(r.chainID), strings.ToLower(address.Hex())+".json")
+}
+
+func (r *ExplorerABIResolver) readCache(address common.Address) (*explorerABI, error) {
+	path := r.cachePath(address)
+	if path == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	var cached explorerABI
+	if err := OpenJsonFileAsStruct(path, &cached); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
+
+func (r *ExplorerABIResolver) writeCache(address common.Address, cached *explorerABI) {
+	path := r.cachePath(address)
+	if path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(cached, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0600)
+	}
+	if err != nil {
+		L.Warn().Err(err).Str("Address", address.Hex()).Msg("Failed to cache ABI downloaded from explorer")
+	}
+}
+
+func redactURLQuery(s, secret string) string {
+	if secret == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, secret, redactedValue)
+}
+
+// resolveABI downloads ABI of the contract at the address with the resolver and adds it to the contract store and the
+// contract map. If the store already has an ABI with the same name, address is appended to the name.
+func (a *ABIFinder) resolveABI(address string) (string, *abi.ABI, bool) {
+	if a.Resolver == nil || !common.IsHexAddress(address) {
+		return "", nil, false
+	}
+	name, contractABI, err := a.Resolver.ResolveABI(context.Background(), common.HexToAddress(address))
+	if err != nil {
+		L.Debug().Err(err).Str("Address", address).Msg("Failed to resolve ABI of unknown contract")
+		return "", nil, false
+	}
+	if existing, ok := a.ContractStore.GetABI(name); ok && !sameABI(existing, contractABI) {
+		name = fmt.Sprintf("%s_%s", name, strings.ToLower(address[2:10]))
+	}
+	a.ContractStore.AddABI(name, *contractABI)
+	a.ContractMap.AddContract(address, name)
+	return name, contractABI, true
+}
+
+func sameABI(a, b *abi.ABI) bool {
+	if len(a.Methods) != len(b.Methods) || len(a.Events) != len(b.Events) {
+		return false
+	}
+	for _, m := range b.Methods {
+		if _, err := a.MethodById(m.ID); err != nil {
+			return false
+		}
+	}
+	return true
+}
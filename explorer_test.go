@@ -0,0 +1,94 @@
+package seth_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	verifiedAddress   = "0x1111111111111111111111111111111111111111"
+	unverifiedAddress = "0x2222222222222222222222222222222222222222"
+)
+
+func newExplorerServer(t *testing.T, requests *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		require.Equal(t, "getsourcecode", r.URL.Query().Get("action"), "incorrect action")
+		require.Equal(t, "secret", r.URL.Query().Get("apikey"), "API key should be sent")
+		if r.URL.Query().Get("address") == common.HexToAddress(verifiedAddress).Hex() {
+			_, _ = fmt.Fprint(w, `{"status":"1","message":"OK","result":[{"ContractName":"ThirdParty","ABI":"[{\"type\":\"function\",\"name\":\"swap\",\"stateMutability\":\"nonpayable\",\"inputs\":[{\"name\":\"amount\",\"type\":\"uint256\"}],\"outputs\":[]}]"}]}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"status":"1","message":"OK","result":[{"ContractName":"","ABI":"Contract source code not verified"}]}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestContractStoreExplorerABIResolver(t *testing.T) {
+	var requests int32
+	server := newExplorerServer(t, &requests)
+	cacheDir := t.TempDir()
+	cfg := &seth.ExplorerCfg{URL: server.URL, APIKey: "secret", CacheDir: cacheDir}
+
+	cs, err := seth.NewContractStore("./contracts/abi", "")
+	require.NoError(t, err, "failed to create contract store")
+	contractMap := seth.NewEmptyContractMap()
+	finder := seth.NewABIFinder(contractMap, cs)
+	finder.Resolver = seth.NewExplorerABIResolver(cfg, 1337)
+
+	swap := crypto.Keccak256([]byte("swap(uint256)"))[:4]
+	result, err := finder.FindABIByMethod(verifiedAddress, swap)
+	require.NoError(t, err, "ABI should be resolved with explorer")
+	require.Equal(t, "ThirdParty", result.ContractName(), "contract name should be read from explorer")
+	require.Equal(t, "swap", result.Method.Name, "incorrect method")
+	_, ok := cs.GetABI("ThirdParty")
+	require.True(t, ok, "ABI should be added to contract store")
+	require.Equal(t, "ThirdParty", contractMap.GetContractName(verifiedAddress), "contract should be added to contract map")
+
+	// unverified contracts are queried only once
+	unknown := crypto.Keccak256([]byte("unknown()"))[:4]
+	for i := 0; i < 2; i++ {
+		_, err = finder.FindABIByMethod(unverifiedAddress, unknown)
+		require.ErrorIs(t, err, seth.ErrNoABIMethod, "unverified contract should not be decoded")
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests), "failed query should not be repeated")
+
+	// downloaded ABIs are cached, so that the next run doesn't query the explorer
+	cs.RemoveABI("ThirdParty")
+	finder = seth.NewABIFinder(contractMap, cs)
+	finder.Resolver = seth.NewExplorerABIResolver(cfg, 1337)
+	result, err = finder.FindABIByMethod(verifiedAddress, swap)
+	require.NoError(t, err, "ABI should be resolved from cache for known address")
+	require.Equal(t, "swap", result.Method.Name, "incorrect method")
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests), "cached ABI should not be downloaded again")
+
+	// ABI with name of a different contract from the store is added under unique name
+	cs.AddABI("ThirdParty", abi.ABI{})
+	finder = seth.NewABIFinder(seth.NewEmptyContractMap(), cs)
+	finder.Resolver = seth.NewExplorerABIResolver(cfg, 1337)
+	result, err = finder.FindABIByMethod(verifiedAddress, swap)
+	require.NoError(t, err, "ABI should be resolved")
+	require.Equal(t, "ThirdParty_11111111", result.ContractName(), "name should be made unique")
+}
+
+func TestConfigExplorerValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	cfg.Network.Explorer = &seth.ExplorerCfg{URL: "api.etherscan.io"}
+	require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrExplorerURL, "URL should be validated")
+
+	cfg.Network.Explorer = &seth.ExplorerCfg{URL: "https://api.etherscan.io/api"}
+	require.NoError(t, seth.ValidateConfig(cfg), "config should be valid")
+	require.Equal(t, seth.DefaultExplorerCacheDir, cfg.Network.Explorer.CacheDir, "default cache dir should be set")
+}
@@ -0,0 +1,224 @@
+package seth
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// NativeToken is used as token name for native value transfers in funds flow report
+	NativeToken = "native"
+
+	FundsFlowReportName = "funds_flow"
+)
+
+// erc20TransferEventID is the topic of ERC-20 `Transfer(address,address,uint256)` event
+var erc20TransferEventID = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// FundsFlowEdge is an aggregated value sent from one address to another in a single token (native or ERC-20)
+type FundsFlowEdge struct {
+	FromAddress string   `json:"from_address"`
+	ToAddress   string   `json:"to_address"`
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	Token       string   `json:"token"`
+	TokenName   string   `json:"token_name"`
+	Amount      *big.Int `json:"amount"`
+	Transfers   int      `json:"transfers"`
+}
+
+// FundsFlow aggregates native and ERC-20 value transfers across all decoded transactions. Native transfers are taken from transaction
+// value and, if `trace_internal_transfers` is enabled and transaction was traced, from internal transfers. ERC-20 transfers are read from
+// `Transfer` events in transaction receipts. Only successful transactions are taken into account. It's safe for concurrent use.
+type FundsFlow struct {
+	mu                       *sync.Mutex
+	edges                    map[string]*FundsFlowEdge
+	contractAddressToNameMap ContractMap
+	addresses                []common.Address
+}
+
+// NewFundsFlow creates new empty funds flow, contract map and own addresses are used to give addresses human-readable names
+func NewFundsFlow(contractAddressToNameMap ContractMap, addresses []common.Address) *FundsFlow {
+	return &FundsFlow{
+		mu:                       &sync.Mutex{},
+		edges:                    make(map[string]*FundsFlowEdge),
+		contractAddressToNameMap: contractAddressToNameMap,
+		addresses:                addresses,
+	}
+}
+
+// AddTransaction adds all value transfers from a mined transaction to the funds flow
+func (f *FundsFlow) AddTransaction(tx *types.Transaction, receipt *types.Receipt, internalTransfers []InternalTransfer) error {
+	if receipt == nil || receipt.Status != types.ReceiptStatusSuccessful {
+		return nil
+	}
+
+	if tx.Value() != nil && tx.Value().Sign() > 0 && tx.To() != nil {
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			return err
+		}
+		f.AddTransfer(from.Hex(), tx.To().Hex(), NativeToken, tx.Value())
+	}
+
+	for _, it := range internalTransfers {
+		f.AddTransfer(it.FromAddress, it.ToAddress, NativeToken, it.Amount)
+	}
+
+	for _, log := range receipt.Logs {
+		// ERC-721 Transfer event has the same signature, but 4 topics and no data
+		if len(log.Topics) != 3 || log.Topics[0] != erc20TransferEventID || len(log.Data) != 32 {
+			continue
+		}
+		from := common.BytesToAddress(log.Topics[1].Bytes())
+		to := common.BytesToAddress(log.Topics[2].Bytes())
+		f.AddTransfer(from.Hex(), to.Hex(), log.Address.Hex(), new(big.Int).SetBytes(log.Data))
+	}
+
+	return nil
+}
+
+// AddTransfer adds a single transfer of given token (NativeToken or ERC-20 address) to the funds flow
+func (f *FundsFlow) AddTransfer(from, to, token string, amount *big.Int) {
+	if amount == nil || amount.Sign() <= 0 {
+		return
+	}
+	from, to = strings.ToLower(from), strings.ToLower(to)
+	if token != NativeToken {
+		token = strings.ToLower(token)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := fmt.Sprintf("%s-%s-%s", from, to, token)
+	edge, ok := f.edges[key]
+	if !ok {
+		edge = &FundsFlowEdge{
+			FromAddress: from,
+			ToAddress:   to,
+			From:        f.addressName(from),
+			To:          f.addressName(to),
+			Token:       token,
+			TokenName:   NativeToken,
+			Amount:      big.NewInt(0),
+		}
+		if token != NativeToken {
+			edge.TokenName = f.addressName(token)
+		}
+		f.edges[key] = edge
+	}
+	edge.Amount.Add(edge.Amount, amount)
+	edge.Transfers++
+}
+
+// Edges returns all aggregated transfers sorted by token, sender and receiver
+func (f *FundsFlow) Edges() []FundsFlowEdge {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	edges := make([]FundsFlowEdge, 0, len(f.edges))
+	for _, e := range f.edges {
+		edge := *e
+		edge.Amount = new(big.Int).Set(e.Amount)
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Token != edges[j].Token {
+			return edges[i].Token < edges[j].Token
+		}
+		if edges[i].FromAddress != edges[j].FromAddress {
+			return edges[i].FromAddress < edges[j].FromAddress
+		}
+		return edges[i].ToAddress < edges[j].ToAddress
+	})
+
+	return edges
+}
+
+// ToDOT renders the funds flow as a graphviz digraph, native amounts are shown in ether, ERC-20 amounts in token's base units
+func (f *FundsFlow) ToDOT() string {
+	edges := f.Edges()
+
+	nodes := make(map[string]string)
+	for _, e := range edges {
+		nodes[e.FromAddress] = e.From
+		nodes[e.ToAddress] = e.To
+	}
+	nodeAddresses := make([]string, 0, len(nodes))
+	for addr := range nodes {
+		nodeAddresses = append(nodeAddresses, addr)
+	}
+	sort.Strings(nodeAddresses)
+
+	var sb strings.Builder
+	sb.WriteString("digraph funds_flow {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box];\n")
+	for _, addr := range nodeAddresses {
+		sb.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\\n%s\"];\n", addr, nodes[addr], addr))
+	}
+	for _, e := range edges {
+		var amount string
+		if e.Token == NativeToken {
+			amount = fmt.Sprintf("%s ether", WeiToEther(e.Amount).Text('f', -1))
+		} else {
+			amount = fmt.Sprintf("%s %s", e.Amount.String(), e.TokenName)
+		}
+		sb.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [label=\"%s (%d tx)\"];\n", e.FromAddress, e.ToAddress, amount, e.Transfers))
+	}
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
+
+// SaveReport saves the funds flow as both JSON and graphviz DOT files in given directory, it returns paths to both files
+func (f *FundsFlow) SaveReport(dirName string) (jsonPath string, dotPath string, err error) {
+	jsonPath, err = saveAsJson(f.Edges(), dirName, FundsFlowReportName)
+	if err != nil {
+		return
+	}
+	dotPath = filepath.Join(filepath.Dir(jsonPath), FundsFlowReportName+".dot")
+	err = os.WriteFile(dotPath, []byte(f.ToDOT()), 0600)
+	return
+}
+
+func (f *FundsFlow) addressName(address string) string {
+	if f.contractAddressToNameMap.IsKnownAddress(address) {
+		return f.contractAddressToNameMap.GetContractName(address)
+	}
+	for i, a := range f.addresses {
+		if strings.EqualFold(a.Hex(), address) {
+			if i == 0 {
+				return "root key"
+			}
+			return fmt.Sprintf("key %d", i)
+		}
+	}
+	return UNKNOWN
+}
+
+// recordFundsFlow adds mined transaction to funds flow, using internal transfers from its trace, if it was traced
+func (m *Client) recordFundsFlow(tx *types.Transaction, receipt *types.Receipt) {
+	var internalTransfers []InternalTransfer
+	if m.Tracer != nil {
+		if calls, ok := m.Tracer.GetDecodedCalls(tx.Hash().Hex()); ok && len(calls) > 0 {
+			internalTransfers = calls[0].InternalTransfers
+		}
+	}
+	if err := m.FundsFlow.AddTransaction(tx, receipt, internalTransfers); err != nil {
+		m.logger().Warn().
+			Err(err).
+			Str("Transaction", tx.Hash().Hex()).
+			Msg("Failed to add transaction to funds flow")
+	}
+}
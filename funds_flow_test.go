@@ -0,0 +1,92 @@
+package seth_test
+
+import (
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIFundsFlow(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.TrackFundsFlow = true
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	require.NotNil(t, c.FundsFlow, "funds flow should be initialised")
+
+	c.ContractAddressToNameMap.AddContract(TestEnv.DebugContractAddress.Hex(), "NetworkDebugContract")
+	c.ContractAddressToNameMap.AddContract(TestEnv.LinkTokenContract.Address().Hex(), "LinkToken")
+
+	for i := 0; i < 2; i++ {
+		_, err = c.Decode(TestEnv.DebugContract.Pay(c.NewTXOpts(seth.WithValue(big.NewInt(1000)))))
+		require.NoError(t, err, "failed to send value to contract")
+	}
+
+	receiver, _, err := seth.NewAddress()
+	require.NoError(t, err, "failed to generate new address")
+	_, err = c.Decode(TestEnv.LinkTokenContract.GrantMintRole(c.NewTXOpts(), c.Addresses[0]))
+	require.NoError(t, err, "failed to grant mint role")
+	// minting emits Transfer event from zero address
+	_, err = c.Decode(TestEnv.LinkTokenContract.Mint(c.NewTXOpts(), common.HexToAddress(receiver), big.NewInt(5)))
+	require.NoError(t, err, "failed to mint tokens")
+
+	// reverted transactions don't move any funds
+	_, _ = c.Decode(TestEnv.DebugContract.AlwaysRevertsRequire(c.NewTXOpts(seth.WithGasLimit(1_000_000))))
+
+	rootAddress := strings.ToLower(c.Addresses[0].Hex())
+
+	edges := c.FundsFlow.Edges()
+	require.Len(t, edges, 2, "expected 2 edges")
+
+	var native, erc20 seth.FundsFlowEdge
+	for _, e := range edges {
+		if e.Token == seth.NativeToken {
+			native = e
+		} else {
+			erc20 = e
+		}
+	}
+
+	require.Equal(t, seth.FundsFlowEdge{
+		FromAddress: rootAddress,
+		ToAddress:   strings.ToLower(TestEnv.DebugContractAddress.Hex()),
+		From:        "root key",
+		To:          "NetworkDebugContract",
+		Token:       seth.NativeToken,
+		TokenName:   seth.NativeToken,
+		Amount:      big.NewInt(2000),
+		Transfers:   2,
+	}, native, "native edge does not match")
+
+	require.Equal(t, seth.FundsFlowEdge{
+		FromAddress: strings.ToLower(common.Address{}.Hex()),
+		ToAddress:   strings.ToLower(receiver),
+		From:        seth.UNKNOWN,
+		To:          seth.UNKNOWN,
+		Token:       strings.ToLower(TestEnv.LinkTokenContract.Address().Hex()),
+		TokenName:   "LinkToken",
+		Amount:      big.NewInt(5),
+		Transfers:   1,
+	}, erc20, "ERC-20 edge does not match")
+
+	dir := "funds_flow_test_report"
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	jsonPath, dotPath, err := c.FundsFlow.SaveReport(dir)
+	require.NoError(t, err, "failed to save funds flow report")
+
+	dot, err := os.ReadFile(dotPath)
+	require.NoError(t, err, "failed to read DOT report")
+	require.Contains(t, string(dot), "0.000000000000002 ether (2 tx)", "DOT report should contain native transfers")
+	require.Contains(t, string(dot), "5 LinkToken (1 tx)", "DOT report should contain token transfers")
+
+	_, err = os.Stat(jsonPath)
+	require.NoError(t, err, "JSON report should exist")
+}
@@ -1,9 +1,10 @@
 package seth
 
 import (
-	"context"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/montanaflynn/stats"
 )
 
@@ -21,14 +22,23 @@ func NewGasEstimator(c *Client) *GasEstimator {
 
 // Stats prints gas stats
 func (m *GasEstimator) Stats(fromNumber uint64, priorityPerc float64) (GasSuggestions, error) {
-	bn, err := m.Client.Client.BlockNumber(context.Background())
-	if err != nil {
-		return GasSuggestions{}, err
+	// fee history and current suggestions are fetched in a single batch request
+	var history feeHistoryResult
+	var suggestedGasPrice, suggestedGasTipCap hexutil.Big
+	batch := []BatchElem{
+		{Method: "eth_feeHistory", Args: []interface{}{hexutil.Uint64(fromNumber), "latest", []float64{priorityPerc}}, Result: &history},
+		{Method: "eth_gasPrice", Result: &suggestedGasPrice},
+		{Method: "eth_maxPriorityFeePerGas", Result: &suggestedGasTipCap},
 	}
-	hist, err := m.Client.Client.FeeHistory(context.Background(), fromNumber, big.NewInt(int64(bn)), []float64{priorityPerc})
-	if err != nil {
+	if err := m.Client.BatchCall(batch); err != nil {
 		return GasSuggestions{}, err
 	}
+	for _, elem := range batch {
+		if elem.Error != nil {
+			return GasSuggestions{}, elem.Error
+		}
+	}
+	hist := history.toFeeHistory()
 	baseFees := make([]float64, 0)
 	for _, bf := range hist.BaseFee {
 		if bf == nil {
@@ -58,25 +68,45 @@ func (m *GasEstimator) Stats(fromNumber uint64, priorityPerc float64) (GasSugges
 	if err != nil {
 		return GasSuggestions{}, err
 	}
-	suggestedGasPrice, err := m.Client.Client.SuggestGasPrice(context.Background())
-	if err != nil {
-		return GasSuggestions{}, err
-	}
-	suggestedGasTipCap, err := m.Client.Client.SuggestGasTipCap(context.Background())
-	if err != nil {
-		return GasSuggestions{}, err
-	}
 	L.Trace().
 		Interface("History", hist).
 		Msg("Fee history")
 	return GasSuggestions{
 		GasPrice:           gasPercs,
 		TipCap:             tipPercs,
-		SuggestedGasPrice:  suggestedGasPrice,
-		SuggestedGasTipCap: suggestedGasTipCap,
+		SuggestedGasPrice:  suggestedGasPrice.ToInt(),
+		SuggestedGasTipCap: suggestedGasTipCap.ToInt(),
 	}, nil
 }
 
+// feeHistoryResult is eth_feeHistory response, the same as ethclient unmarshals it
+type feeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	BaseFee      []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+func (r feeHistoryResult) toFeeHistory() *ethereum.FeeHistory {
+	reward := make([][]*big.Int, len(r.Reward))
+	for i, rewards := range r.Reward {
+		reward[i] = make([]*big.Int, len(rewards))
+		for j, rw := range rewards {
+			reward[i][j] = (*big.Int)(rw)
+		}
+	}
+	baseFee := make([]*big.Int, len(r.BaseFee))
+	for i, b := range r.BaseFee {
+		baseFee[i] = (*big.Int)(b)
+	}
+	return &ethereum.FeeHistory{
+		OldestBlock:  (*big.Int)(r.OldestBlock),
+		Reward:       reward,
+		BaseFee:      baseFee,
+		GasUsedRatio: r.GasUsedRatio,
+	}
+}
+
 // GasPercentiles contains gas percentiles
 type GasPercentiles struct {
 	Max    float64
@@ -6,8 +6,6 @@ import (
 	"math"
 	"math/big"
 	"slices"
-	"strings"
-	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/core/types"
@@ -34,8 +32,8 @@ const (
 )
 
 var (
-	ZeroGasSuggestedErr = "either base fee or suggested tip is 0"
-	BlockFetchingErr    = "failed to fetch enough block headers for congestion calculation"
+	ZeroGasSuggestedErr = errors.New("either base fee or suggested tip is 0")
+	BlockFetchingErr    = errors.New("failed to fetch enough block headers for congestion calculation")
 )
 
 // CalculateNetworkCongestionMetric calculates a simple congestion metric based on the last N blocks
@@ -44,15 +42,46 @@ func (m *Client) CalculateNetworkCongestionMetric(blocksNumber uint64, strategy
 	if m.HeaderCache == nil {
 		return 0, fmt.Errorf("header cache is nil")
 	}
-	var getHeaderData = func(bn *big.Int) (*types.Header, error) {
-		if bn == nil {
-			return nil, fmt.Errorf("block number is nil")
+	var lastBlockNumber uint64
+	if head := m.latestSubscribedHead(); head != nil {
+		lastBlockNumber = head.Number.Uint64()
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(2*time.Second))
+		defer cancel()
+		var err error
+		lastBlockNumber, err = m.Client.BlockNumber(ctx)
+		if err != nil {
+			return 0, err
 		}
-		cachedHeader, ok := m.HeaderCache.Get(bn.Int64())
-		if ok {
-			return cachedHeader, nil
+	}
+
+	m.logger().Trace().Msgf("Block range for gas calculation: %d - %d", lastBlockNumber-blocksNumber, lastBlockNumber)
+
+	// the last block is followed by the whole range (which starts with it again)
+	numbers := []int64{int64(lastBlockNumber)}
+	for i := lastBlockNumber; i > lastBlockNumber-blocksNumber; i-- {
+		// better safe than sorry (might happen for brand-new chains)
+		if i <= 1 {
+			break
 		}
+		numbers = append(numbers, int64(i))
+	}
 
+	// headers, that aren't cached, are fetched in batch requests
+	headers := make([]*types.Header, len(numbers))
+	var missing []*big.Int
+	var missingIdx []int
+	for i, bn := range numbers {
+		if cachedHeader, ok := m.HeaderCache.Get(bn); ok {
+			headers[i] = cachedHeader
+			continue
+		}
+		missing = append(missing, big.NewInt(bn))
+		missingIdx = append(missingIdx, i)
+	}
+
+	startTime := time.Now()
+	if len(missing) > 0 {
 		timeout := blocksNumber / 100
 		if timeout < 3 {
 			timeout = 3
@@ -62,74 +91,31 @@ func (m *Client) CalculateNetworkCongestionMetric(blocksNumber uint64, strategy
 
 		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
 		defer cancel()
-		header, err := m.Client.HeaderByNumber(ctx, bn)
+		fetched, errs, err := m.headersByNumber(ctx, missing)
 		if err != nil {
-			return nil, err
+			return 0, err
 		}
-		// ignore the error here as at this points is very improbable that block is nil and there's no error
-		_ = m.HeaderCache.Set(header)
-		return header, nil
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(2*time.Second))
-	defer cancel()
-	lastBlockNumber, err := m.Client.BlockNumber(ctx)
-	if err != nil {
-		return 0, err
-	}
-
-	L.Trace().Msgf("Block range for gas calculation: %d - %d", lastBlockNumber-blocksNumber, lastBlockNumber)
-
-	lastBlock, err := getHeaderData(big.NewInt(int64(lastBlockNumber)))
-	if err != nil {
-		return 0, err
-	}
-
-	var headers []*types.Header
-	headers = append(headers, lastBlock)
-
-	channelSize := blocksNumber
-	if blocksNumber > 20 {
-		channelSize = 20
-	}
-
-	var wg sync.WaitGroup
-	dataCh := make(chan *types.Header, channelSize)
-
-	go func() {
-		for header := range dataCh {
-			headers = append(headers, header)
-		}
-	}()
-
-	startTime := time.Now()
-	for i := lastBlockNumber; i > lastBlockNumber-blocksNumber; i-- {
-		// better safe than sorry (might happen for brand-new chains)
-		if i <= 1 {
-			break
-		}
-
-		wg.Add(1)
-		go func(bn *big.Int) {
-			defer wg.Done()
-			header, err := getHeaderData(bn)
-			if err != nil {
-				L.Error().Err(err).Msgf("Failed to get block %d header", bn.Int64())
-				return
+		for i, header := range fetched {
+			if header == nil && missingIdx[i] == 0 {
+				return 0, errs[i]
+			}
+			if header == nil {
+				m.logger().Error().Err(errs[i]).Msgf("Failed to get block %d header", missing[i].Int64())
+				continue
 			}
-			dataCh <- header
-		}(big.NewInt(int64(i)))
+			// ignore the error here as at this points is very improbable that block is nil and there's no error
+			_ = m.HeaderCache.Set(header)
+			headers[missingIdx[i]] = header
+		}
 	}
-
-	wg.Wait()
-	close(dataCh)
+	headers = slices.DeleteFunc(headers, func(h *types.Header) bool { return h == nil })
 
 	endTime := time.Now()
-	L.Debug().Msgf("Time to fetch %d block headers: %v", blocksNumber, endTime.Sub(startTime))
+	m.logger().Debug().Msgf("Time to fetch %d block headers: %v", blocksNumber, endTime.Sub(startTime))
 
 	minBlockCount := int(float64(blocksNumber) * 0.8)
 	if len(headers) < minBlockCount {
-		return 0, fmt.Errorf("%s. Wanted at least %d, got %d", BlockFetchingErr, minBlockCount, len(headers))
+		return 0, errors.Wrapf(BlockFetchingErr, "wanted at least %d, got %d", minBlockCount, len(headers))
 	}
 
 	switch strategy {
@@ -177,15 +163,15 @@ func calculateNewestFirstNetworkCongestionMetric(headers []*types.Header) float6
 
 // GetSuggestedEIP1559Fees returns suggested tip/fee cap calculated based on historical data, current congestion, and priority.
 func (m *Client) GetSuggestedEIP1559Fees(ctx context.Context, priority string) (maxFeeCap *big.Int, adjustedTipCap *big.Int, err error) {
-	L.Info().Msg("Calculating suggested EIP-1559 fees")
+	m.logger().Info().Msg("Calculating suggested EIP-1559 fees")
 	var suggestedGasTip *big.Int
 	suggestedGasTip, err = m.Client.SuggestGasTipCap(ctx)
 	if err != nil {
 		return
 	}
 
-	L.Debug().
-		Str("CurrentGasTip", fmt.Sprintf("%s wei / %s ether", suggestedGasTip.String(), WeiToEther(suggestedGasTip).Text('f', -1))).
+	m.logger().Debug().
+		Str("CurrentGasTip", FormatWei(suggestedGasTip)).
 		Msg("Current suggested gas tip")
 
 	// Fetch the baseline historical base fee and tip for the selected priority
@@ -195,51 +181,51 @@ func (m *Client) GetSuggestedEIP1559Fees(ctx context.Context, priority string) (
 		return
 	}
 
-	L.Debug().
-		Str("HistoricalBaseFee", fmt.Sprintf("%.0f wei / %s ether", baseFee64, WeiToEther(big.NewInt(int64(baseFee64))).Text('f', -1))).
-		Str("HistoricalSuggestedTip", fmt.Sprintf("%.0f wei / %s ether", historicalSuggestedTip64, WeiToEther(big.NewInt(int64(historicalSuggestedTip64))).Text('f', -1))).
+	m.logger().Debug().
+		Str("HistoricalBaseFee", FormatWei(big.NewInt(int64(baseFee64)))).
+		Str("HistoricalSuggestedTip", FormatWei(big.NewInt(int64(historicalSuggestedTip64)))).
 		Str("Priority", priority).
 		Msg("Historical fee data")
 
 	_, tipMagnitudeDiffText := calculateMagnitudeDifference(big.NewFloat(historicalSuggestedTip64), new(big.Float).SetInt(suggestedGasTip))
 
-	L.Debug().
+	m.logger().Debug().
 		Msgf("Historical tip is %s than suggested tip", tipMagnitudeDiffText)
 
 	currentGasTip := suggestedGasTip
 	if big.NewInt(int64(historicalSuggestedTip64)).Cmp(currentGasTip) > 0 {
-		L.Debug().Msg("Historical suggested tip is higher than current suggested tip. Will use it instead.")
+		m.logger().Debug().Msg("Historical suggested tip is higher than current suggested tip. Will use it instead.")
 		currentGasTip = big.NewInt(int64(historicalSuggestedTip64))
 	} else {
-		L.Debug().Msg("Suggested tip is higher than historical tip. Will use suggested tip.")
+		m.logger().Debug().Msg("Suggested tip is higher than historical tip. Will use suggested tip.")
 	}
 
 	if m.Cfg.IsExperimentEnabled(Experiment_Eip1559FeeEqualier) {
-		L.Debug().Msg("FeeEqualier experiment is enabled. Will adjust base fee and tip to be of the same order of magnitude.")
+		m.logger().Debug().Msg("FeeEqualier experiment is enabled. Will adjust base fee and tip to be of the same order of magnitude.")
 		baseFeeTipMagnitudeDiff, _ := calculateMagnitudeDifference(big.NewFloat(baseFee64), new(big.Float).SetInt(currentGasTip))
 
 		//one of values is 0, inifite order of magnitude smaller or larger
 		if baseFeeTipMagnitudeDiff == -0 {
 			if baseFee64 == 0.0 {
-				L.Debug().Msg("Historical base fee is 0.0. Will use suggested tip as base fee.")
+				m.logger().Debug().Msg("Historical base fee is 0.0. Will use suggested tip as base fee.")
 				baseFee64 = float64(currentGasTip.Int64())
 			} else {
-				L.Debug().Msg("Suggested tip is 0.0. Will use historical base fee as tip.")
+				m.logger().Debug().Msg("Suggested tip is 0.0. Will use historical base fee as tip.")
 				currentGasTip = big.NewInt(int64(baseFee64))
 			}
 		} else if baseFeeTipMagnitudeDiff < 3 {
-			L.Debug().Msg("Historical base fee is 3 orders of magnitude lower than suggested tip. Will use suggested tip as base fee.")
+			m.logger().Debug().Msg("Historical base fee is 3 orders of magnitude lower than suggested tip. Will use suggested tip as base fee.")
 			baseFee64 = float64(currentGasTip.Int64())
 		} else if baseFeeTipMagnitudeDiff > 3 {
-			L.Debug().Msg("Suggested tip is 3 orders of magnitude lower than historical base fee. Will use historical base fee as tip.")
+			m.logger().Debug().Msg("Suggested tip is 3 orders of magnitude lower than historical base fee. Will use historical base fee as tip.")
 			currentGasTip = big.NewInt(int64(baseFee64))
 		}
 	}
 
 	if baseFee64 == 0.0 {
-		err = errors.New(ZeroGasSuggestedErr)
+		err = ZeroGasSuggestedErr
 
-		L.Error().
+		m.logger().Error().
 			Err(err).
 			Float64("BaseFee", baseFee64).
 			Int64("SuggestedTip", currentGasTip.Int64()).
@@ -248,7 +234,7 @@ func (m *Client) GetSuggestedEIP1559Fees(ctx context.Context, priority string) (
 	}
 
 	if currentGasTip.Int64() == 0 {
-		L.Warn().
+		m.logger().Warn().
 			Msg("Suggested tip is 0.0. Although not strictly incorrect, it is unusual. Transaction might take much longer to confirm.")
 	}
 
@@ -274,7 +260,7 @@ func (m *Client) GetSuggestedEIP1559Fees(ctx context.Context, priority string) (
 	if err == nil {
 		congestionClassification := classifyCongestion(congestionMetric)
 
-		L.Debug().
+		m.logger().Debug().
 			Str("CongestionMetric", fmt.Sprintf("%.4f", congestionMetric)).
 			Str("CongestionClassification", congestionClassification).
 			Float64("AdjustmentFactor", adjustmentFactor).
@@ -295,10 +281,10 @@ func (m *Client) GetSuggestedEIP1559Fees(ctx context.Context, priority string) (
 		// Apply buffer also to the tip
 		bufferedTipCapFloat := new(big.Float).Mul(new(big.Float).SetInt(adjustedTipCap), big.NewFloat(bufferAdjustment))
 		adjustedTipCap, _ = bufferedTipCapFloat.Int(nil)
-	} else if !strings.Contains(err.Error(), BlockFetchingErr) {
+	} else if !errors.Is(err, BlockFetchingErr) {
 		return
 	} else {
-		L.Warn().
+		m.logger().Warn().
 			Err(err).
 			Msg("Failed to calculate congestion metric. Skipping congestion buffer adjustment")
 
@@ -313,27 +299,27 @@ func (m *Client) GetSuggestedEIP1559Fees(ctx context.Context, priority string) (
 	gasTipDiff := big.NewInt(0).Sub(adjustedTipCap, currentGasTip)
 	gasCapDiff := big.NewInt(0).Sub(maxFeeCap, initialFeeCap)
 
-	L.Debug().
-		Str("Diff (Wei/Ether)", fmt.Sprintf("%s wei / %s ether", gasTipDiff.String(), WeiToEther(gasTipDiff).Text('f', -1))).
-		Str("Initial Tip", fmt.Sprintf("%s wei / %s ether", currentGasTip.String(), WeiToEther(currentGasTip).Text('f', -1))).
-		Str("Final Tip", fmt.Sprintf("%s wei / %s ether", adjustedTipCap.String(), WeiToEther(adjustedTipCap).Text('f', -1))).
+	m.logger().Debug().
+		Str("Diff (Wei/Ether)", FormatWei(gasTipDiff)).
+		Str("Initial Tip", FormatWei(currentGasTip)).
+		Str("Final Tip", FormatWei(adjustedTipCap)).
 		Msg("Tip adjustment")
 
-	L.Debug().
-		Str("Diff (Wei/Ether)", fmt.Sprintf("%s wei / %s ether", baseFeeDiff.String(), WeiToEther(baseFeeDiff).Text('f', -1))).
-		Str("Initial Base Fee", fmt.Sprintf("%s wei / %s ether", big.NewInt(int64(baseFee64)).String(), WeiToEther(big.NewInt(int64(baseFee64))).Text('f', -1))).
-		Str("Final Base Fee", fmt.Sprintf("%s wei / %s ether", adjustedBaseFee.String(), WeiToEther(adjustedBaseFee).Text('f', -1))).
+	m.logger().Debug().
+		Str("Diff (Wei/Ether)", FormatWei(baseFeeDiff)).
+		Str("Initial Base Fee", FormatWei(big.NewInt(int64(baseFee64)))).
+		Str("Final Base Fee", FormatWei(adjustedBaseFee)).
 		Msg("Base Fee adjustment")
 
-	L.Debug().
-		Str("Diff (Wei/Ether)", fmt.Sprintf("%s wei / %s ether", gasCapDiff.String(), WeiToEther(gasCapDiff).Text('f', -1))).
-		Str("Initial Fee Cap", fmt.Sprintf("%s wei / %s ether", initialFeeCap.String(), WeiToEther(initialFeeCap).Text('f', -1))).
-		Str("Final Fee Cap", fmt.Sprintf("%s wei / %s ether", maxFeeCap.String(), WeiToEther(maxFeeCap).Text('f', -1))).
+	m.logger().Debug().
+		Str("Diff (Wei/Ether)", FormatWei(gasCapDiff)).
+		Str("Initial Fee Cap", FormatWei(initialFeeCap)).
+		Str("Final Fee Cap", FormatWei(maxFeeCap)).
 		Msg("Fee Cap adjustment")
 
-	L.Info().
-		Str("GasTipCap", fmt.Sprintf("%s wei / %s ether", adjustedTipCap.String(), WeiToEther(adjustedTipCap).Text('f', -1))).
-		Str("GasFeeCap", fmt.Sprintf("%s wei / %s ether", maxFeeCap.String(), WeiToEther(maxFeeCap).Text('f', -1))).
+	m.logger().Info().
+		Str("GasTipCap", FormatWei(adjustedTipCap)).
+		Str("GasFeeCap", FormatWei(maxFeeCap)).
 		Msg("Calculated suggested EIP-1559 fees")
 
 	return
@@ -341,7 +327,7 @@ func (m *Client) GetSuggestedEIP1559Fees(ctx context.Context, priority string) (
 
 // GetSuggestedLegacyFees calculates the suggested gas price based on historical data, current congestion, and priority.
 func (m *Client) GetSuggestedLegacyFees(ctx context.Context, priority string) (adjustedGasPrice *big.Int, err error) {
-	L.Info().
+	m.logger().Info().
 		Msg("Calculating suggested Legacy fees")
 
 	var suggestedGasPrice *big.Int
@@ -352,7 +338,7 @@ func (m *Client) GetSuggestedLegacyFees(ctx context.Context, priority string) (a
 
 	if suggestedGasPrice.Int64() == 0 {
 		err = fmt.Errorf("suggested gas price is 0")
-		L.Error().
+		m.logger().Error().
 			Err(err).
 			Msg("Incorrect gas data received from node. Skipping automation gas estimation")
 		return
@@ -374,7 +360,7 @@ func (m *Client) GetSuggestedLegacyFees(ctx context.Context, priority string) (a
 	if err == nil {
 		congestionClassification := classifyCongestion(congestionMetric)
 
-		L.Debug().
+		m.logger().Debug().
 			Str("CongestionMetric", fmt.Sprintf("%.4f", congestionMetric)).
 			Str("CongestionClassification", congestionClassification).
 			Float64("AdjustmentFactor", adjustmentFactor).
@@ -391,10 +377,10 @@ func (m *Client) GetSuggestedLegacyFees(ctx context.Context, priority string) (a
 		// Calculate and apply the buffer.
 		bufferedGasPriceFloat := new(big.Float).Mul(new(big.Float).SetInt(adjustedGasPrice), big.NewFloat(bufferAdjustment))
 		adjustedGasPrice, _ = bufferedGasPriceFloat.Int(nil)
-	} else if !strings.Contains(err.Error(), BlockFetchingErr) {
+	} else if !errors.Is(err, BlockFetchingErr) {
 		return
 	} else {
-		L.Warn().
+		m.logger().Warn().
 			Err(err).
 			Msg("Failed to calculate congestion metric. Skipping congestion buffer adjustment")
 
@@ -403,14 +389,14 @@ func (m *Client) GetSuggestedLegacyFees(ctx context.Context, priority string) (a
 		err = nil
 	}
 
-	L.Debug().
-		Str("Diff (Wei/Ether)", fmt.Sprintf("%s/%s", big.NewInt(0).Sub(adjustedGasPrice, suggestedGasPrice).String(), WeiToEther(big.NewInt(0).Sub(adjustedGasPrice, suggestedGasPrice)).Text('f', -1))).
-		Str("Initial GasPrice (Wei/Ether)", fmt.Sprintf("%s/%s", suggestedGasPrice.String(), WeiToEther(suggestedGasPrice).Text('f', -1))).
-		Str("Final GasPrice (Wei/Ether)", fmt.Sprintf("%s/%s", adjustedGasPrice.String(), WeiToEther(adjustedGasPrice).Text('f', -1))).
+	m.logger().Debug().
+		Str("Diff (Wei/Ether)", FormatWei(big.NewInt(0).Sub(adjustedGasPrice, suggestedGasPrice))).
+		Str("Initial GasPrice (Wei/Ether)", FormatWei(suggestedGasPrice)).
+		Str("Final GasPrice (Wei/Ether)", FormatWei(adjustedGasPrice)).
 		Msg("Suggested Legacy fees")
 
-	L.Info().
-		Str("GasPrice", fmt.Sprintf("%s wei / %s ether", adjustedGasPrice.String(), WeiToEther(adjustedGasPrice).Text('f', -1))).
+	m.logger().Info().
+		Str("GasPrice", FormatWei(adjustedGasPrice)).
 		Msg("Calculated suggested Legacy fees")
 
 	return
@@ -463,7 +449,7 @@ func (m *Client) HistoricalFeeData(priority string) (baseFee float64, historical
 	estimator := NewGasEstimator(m)
 	stats, err := estimator.Stats(m.Cfg.Network.GasPriceEstimationBlocks, 99)
 	if err != nil {
-		L.Error().
+		m.logger().Error().
 			Err(err).
 			Msg("Failed to get fee history. Skipping automation gas estimation")
 
@@ -484,7 +470,7 @@ func (m *Client) HistoricalFeeData(priority string) (baseFee float64, historical
 			historicalGasTipCap = stats.TipCap.Perc25
 		default:
 			err = fmt.Errorf("unknown priority: %s", priority)
-			L.Error().
+			m.logger().Error().
 				Str("Priority", priority).
 				Msg("Unknown priority. Skipping automation gas estimation")
 
@@ -0,0 +1,298 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultGasSpikeMultiplier    = 3.0
+	DefaultGasSpikeBaselineSize  = 20
+	DefaultGasSpikeMinSamples    = 3
+	DefaultGasSpikeCheckInterval = 5 * time.Second
+	DefaultGasSpikeMaxPause      = 10 * time.Minute
+)
+
+var (
+	ErrGasSpikeBreakerMaxPause    = errors.New("gas spike breaker paused transaction submission for longer than maximum pause")
+	ErrGasSpikeBreakerFeeFetching = errors.New("failed to fetch base fee for gas spike breaker")
+)
+
+// GasSpikeBreakerCfg configures circuit breaker, which pauses creation of transaction options (and thus submission of
+// transactions) when base fee spikes above a multiple of its rolling baseline
+type GasSpikeBreakerCfg struct {
+	// Multiplier of baseline above which the breaker trips, default 3
+	Multiplier float64 `toml:"multiplier"`
+	// ResumeMultiplier of baseline at or below which the breaker resumes, defaults to Multiplier
+	ResumeMultiplier float64 `toml:"resume_multiplier"`
+	// BaselineSize is number of base fee samples used to calculate rolling baseline (median), default 20
+	BaselineSize int `toml:"baseline_size"`
+	// CheckInterval is how often base fee is sampled, default 5s
+	CheckInterval *Duration `toml:"check_interval"`
+	// MaxPause is how long transaction submission can be paused before transaction options get an error, default 10m
+	MaxPause *Duration `toml:"max_pause"`
+}
+
+// BaseFeeSource returns current base fee (or gas price for networks without EIP-1559)
+type BaseFeeSource func(ctx context.Context) (*big.Int, error)
+
+// GasSpikeEvent is passed to breaker hooks, when it trips or resumes
+type GasSpikeEvent struct {
+	BaseFee  *big.Int
+	Baseline *big.Int
+	Ratio    float64
+	// PausedFor is set only when breaker resumes
+	PausedFor time.Duration
+}
+
+// GasSpikeBreakerStats are metrics of the breaker
+type GasSpikeBreakerStats struct {
+	Tripped     bool
+	Trips       int
+	TotalPaused time.Duration
+	Baseline    *big.Int
+	LastBaseFee *big.Int
+}
+
+// GasSpikeBreaker is a circuit breaker that pauses transaction submission during base fee spikes. It compares sampled base
+// fee with a rolling baseline (median of samples taken while it wasn't tripped), so that spike itself doesn't raise the baseline.
+type GasSpikeBreaker struct {
+	mu          *sync.Mutex
+	cfg         GasSpikeBreakerCfg
+	source      BaseFeeSource
+	samples     []*big.Int
+	lastCheck   time.Time
+	tripped     bool
+	trippedAt   time.Time
+	lastBaseFee *big.Int
+	stats       GasSpikeBreakerStats
+	onTrip      []func(GasSpikeEvent)
+	onResume    []func(GasSpikeEvent)
+}
+
+// NewGasSpikeBreaker creates a new gas spike breaker, zero values in config are replaced with defaults
+func NewGasSpikeBreaker(cfg GasSpikeBreakerCfg, source BaseFeeSource) *GasSpikeBreaker {
+	if cfg.Multiplier <= 0 {
+		cfg.Multiplier = DefaultGasSpikeMultiplier
+	}
+	if cfg.ResumeMultiplier <= 0 {
+		cfg.ResumeMultiplier = cfg.Multiplier
+	}
+	if cfg.BaselineSize <= 0 {
+		cfg.BaselineSize = DefaultGasSpikeBaselineSize
+	}
+	if cfg.CheckInterval == nil || cfg.CheckInterval.Duration() <= 0 {
+		cfg.CheckInterval = &Duration{D: DefaultGasSpikeCheckInterval}
+	}
+	if cfg.MaxPause == nil || cfg.MaxPause.Duration() <= 0 {
+		cfg.MaxPause = &Duration{D: DefaultGasSpikeMaxPause}
+	}
+	return &GasSpikeBreaker{
+		mu:     &sync.Mutex{},
+		cfg:    cfg,
+		source: source,
+	}
+}
+
+// OnTrip registers a hook called when the breaker trips
+func (b *GasSpikeBreaker) OnTrip(fn func(GasSpikeEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onTrip = append(b.onTrip, fn)
+}
+
+// OnResume registers a hook called when the breaker resumes
+func (b *GasSpikeBreaker) OnResume(fn func(GasSpikeEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onResume = append(b.onResume, fn)
+}
+
+// Stats returns breaker metrics
+func (b *GasSpikeBreaker) Stats() GasSpikeBreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := b.stats
+	stats.Tripped = b.tripped
+	stats.Baseline = b.baseline()
+	stats.LastBaseFee = b.lastBaseFee
+	if b.tripped {
+		stats.TotalPaused += time.Since(b.trippedAt)
+	}
+	return stats
+}
+
+// Check samples base fee, unless it was sampled less than check interval ago, and returns true if the breaker is tripped
+func (b *GasSpikeBreaker) Check(ctx context.Context) (bool, error) {
+	b.mu.Lock()
+	if !b.lastCheck.IsZero() && time.Since(b.lastCheck) < b.cfg.CheckInterval.Duration() {
+		tripped := b.tripped
+		b.mu.Unlock()
+		return tripped, nil
+	}
+	b.mu.Unlock()
+
+	baseFee, err := b.source(ctx)
+	if err != nil {
+		return false, wrapError(err, ErrGasSpikeBreakerFeeFetching)
+	}
+
+	b.mu.Lock()
+	b.lastCheck = time.Now()
+	b.lastBaseFee = baseFee
+
+	if len(b.samples) < DefaultGasSpikeMinSamples {
+		b.addSample(baseFee)
+		b.mu.Unlock()
+		return false, nil
+	}
+
+	baseline := b.baseline()
+	ratio := feeRatio(baseFee, baseline)
+	var hooks []func(GasSpikeEvent)
+	event := GasSpikeEvent{BaseFee: baseFee, Baseline: baseline, Ratio: ratio}
+
+	switch {
+	case !b.tripped && ratio > b.cfg.Multiplier:
+		b.tripped = true
+		b.trippedAt = time.Now()
+		b.stats.Trips++
+		hooks = b.onTrip
+		L.Warn().
+			Str("Base fee", FormatWei(baseFee)).
+			Str("Baseline", FormatWei(baseline)).
+			Float64("Ratio", ratio).
+			Msg("Gas spike detected, pausing transaction submission")
+	case b.tripped && ratio <= b.cfg.ResumeMultiplier:
+		b.tripped = false
+		event.PausedFor = time.Since(b.trippedAt)
+		b.stats.TotalPaused += event.PausedFor
+		b.addSample(baseFee)
+		hooks = b.onResume
+		L.Info().
+			Str("Base fee", FormatWei(baseFee)).
+			Str("Baseline", FormatWei(baseline)).
+			Str("Paused for", event.PausedFor.String()).
+			Msg("Gas spike is over, resuming transaction submission")
+	case !b.tripped:
+		b.addSample(baseFee)
+	}
+	tripped := b.tripped
+	b.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(event)
+	}
+
+	return tripped, nil
+}
+
+// Wait blocks until the breaker isn't tripped. It returns an error if it's tripped for longer than max pause or context is done.
+// Errors of base fee fetching are logged and ignored, so that RPC issues don't block transaction submission.
+func (b *GasSpikeBreaker) Wait(ctx context.Context) error {
+	started := time.Now()
+	for {
+		tripped, err := b.Check(ctx)
+		if err != nil {
+			L.Debug().Err(err).Msg("Gas spike breaker check failed, not pausing transaction submission")
+			return nil
+		}
+		if !tripped {
+			return nil
+		}
+		if time.Since(started) >= b.cfg.MaxPause.Duration() {
+			stats := b.Stats()
+			threshold := new(big.Float).Mul(new(big.Float).SetInt(stats.Baseline), big.NewFloat(b.cfg.ResumeMultiplier))
+			return errors.Wrapf(ErrGasSpikeBreakerMaxPause, "max pause: %s, base fee %s is still above %s (%.2fx of baseline %s)", b.cfg.MaxPause.Duration(), stats.LastBaseFee, threshold.Text('f', 0), feeRatio(stats.LastBaseFee, stats.Baseline), stats.Baseline)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(b.cfg.CheckInterval.Duration()):
+		}
+	}
+}
+
+func (b *GasSpikeBreaker) addSample(fee *big.Int) {
+	b.samples = append(b.samples, fee)
+	if len(b.samples) > b.cfg.BaselineSize {
+		b.samples = b.samples[len(b.samples)-b.cfg.BaselineSize:]
+	}
+}
+
+// baseline returns median of samples
+func (b *GasSpikeBreaker) baseline() *big.Int {
+	if len(b.samples) == 0 {
+		return nil
+	}
+	sorted := make([]*big.Int, len(b.samples))
+	copy(sorted, b.samples)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Cmp(sorted[j]) < 0
+	})
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return new(big.Int).Set(sorted[mid])
+	}
+	sum := new(big.Int).Add(sorted[mid-1], sorted[mid])
+	return sum.Div(sum, big.NewInt(2))
+}
+
+func feeRatio(fee, baseline *big.Int) float64 {
+	if fee == nil || baseline == nil || baseline.Sign() == 0 {
+		return 0
+	}
+	ratio, _ := new(big.Float).Quo(new(big.Float).SetInt(fee), new(big.Float).SetInt(baseline)).Float64()
+	return ratio
+}
+
+// latestBaseFee returns base fee of the latest block or suggested gas price for networks without EIP-1559
+func (m *Client) latestBaseFee(ctx context.Context) (*big.Int, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.Cfg.Network.TxnTimeout.Duration())
+	defer cancel()
+	header, err := m.Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if header.BaseFee != nil {
+		return header.BaseFee, nil
+	}
+	return m.Client.SuggestGasPrice(ctx)
+}
+
+// waitForGasSpikeBreaker blocks while gas spike breaker is tripped, it does nothing if breaker isn't configured
+func (m *Client) waitForGasSpikeBreaker(ctx context.Context) error {
+	if m.GasSpikeBreaker == nil {
+		return nil
+	}
+	return m.GasSpikeBreaker.Wait(ctx)
+}
+
+func validateGasSpikeBreaker(cfg *GasSpikeBreakerCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Multiplier < 0 || cfg.ResumeMultiplier < 0 {
+		return errors.New("gas spike breaker 'multiplier' and 'resume_multiplier' must be positive")
+	}
+	if cfg.Multiplier != 0 && cfg.Multiplier <= 1 {
+		return fmt.Errorf("gas spike breaker 'multiplier' must be greater than 1, got %.2f", cfg.Multiplier)
+	}
+	multiplier := cfg.Multiplier
+	if multiplier == 0 {
+		multiplier = DefaultGasSpikeMultiplier
+	}
+	if cfg.ResumeMultiplier > multiplier {
+		return fmt.Errorf("gas spike breaker 'resume_multiplier' (%.2f) must not be greater than 'multiplier' (%.2f)", cfg.ResumeMultiplier, multiplier)
+	}
+	if cfg.BaselineSize < 0 {
+		return errors.New("gas spike breaker 'baseline_size' must not be negative")
+	}
+	return nil
+}
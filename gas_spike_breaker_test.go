@@ -0,0 +1,119 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+// feeSequence returns base fees from the sequence, repeating the last one once it's exhausted
+func feeSequence(fees ...int64) seth.BaseFeeSource {
+	mu := &sync.Mutex{}
+	idx := 0
+	return func(_ context.Context) (*big.Int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fee := fees[idx]
+		if idx < len(fees)-1 {
+			idx++
+		}
+		return big.NewInt(fee), nil
+	}
+}
+
+func TestUtilGasSpikeBreaker(t *testing.T) {
+	breaker := seth.NewGasSpikeBreaker(seth.GasSpikeBreakerCfg{
+		Multiplier:       3,
+		ResumeMultiplier: 2,
+		CheckInterval:    &seth.Duration{D: time.Millisecond},
+	}, feeSequence(10, 10, 12, 40, 25, 20, 11))
+
+	var trips, resumes []seth.GasSpikeEvent
+	breaker.OnTrip(func(e seth.GasSpikeEvent) { trips = append(trips, e) })
+	breaker.OnResume(func(e seth.GasSpikeEvent) { resumes = append(resumes, e) })
+
+	check := func() bool {
+		time.Sleep(2 * time.Millisecond)
+		tripped, err := breaker.Check(context.Background())
+		require.NoError(t, err, "check should not fail")
+		return tripped
+	}
+
+	for i := 0; i < 3; i++ {
+		require.False(t, check(), "breaker should not trip while collecting baseline")
+	}
+	require.True(t, check(), "breaker should trip when fee is above 3x of baseline")
+	require.Equal(t, 1, len(trips), "trip hook should be called once")
+	require.Equal(t, int64(40), trips[0].BaseFee.Int64(), "incorrect base fee in trip event")
+	require.Equal(t, int64(10), trips[0].Baseline.Int64(), "incorrect baseline in trip event")
+
+	require.True(t, check(), "breaker should stay tripped above resume multiplier")
+	require.False(t, check(), "breaker should resume at resume multiplier")
+	require.Equal(t, 1, len(resumes), "resume hook should be called once")
+	require.True(t, resumes[0].PausedFor > 0, "resume event should have pause duration")
+
+	require.False(t, check(), "breaker should stay resumed")
+	stats := breaker.Stats()
+	require.False(t, stats.Tripped, "breaker should not be tripped")
+	require.Equal(t, 1, stats.Trips, "incorrect number of trips")
+	require.True(t, stats.TotalPaused > 0, "total pause should be recorded")
+	require.Equal(t, int64(11), stats.LastBaseFee.Int64(), "incorrect last base fee")
+	require.Equal(t, int64(11), stats.Baseline.Int64(), "spike samples should not be part of baseline")
+}
+
+func TestAPIGasSpikeBreakerPausesTransactOpts(t *testing.T) {
+	c := newClient(t)
+
+	c.GasSpikeBreaker = seth.NewGasSpikeBreaker(seth.GasSpikeBreakerCfg{
+		CheckInterval: &seth.Duration{D: time.Millisecond},
+	}, feeSequence(10, 10, 10, 100, 100, 100, 10))
+	resumed := false
+	c.GasSpikeBreaker.OnResume(func(_ seth.GasSpikeEvent) { resumed = true })
+
+	for i := 0; i < 3; i++ {
+		_, err := c.GasSpikeBreaker.Check(context.Background())
+		require.NoError(t, err, "check should not fail")
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	opts := c.NewTXOpts()
+	require.NoError(t, seth.CheckTransactOpts(opts), "options should be created once spike is over")
+	require.True(t, resumed, "options should be created only after breaker resumed")
+	_, err := c.Decode(TestEnv.DebugContract.Set(opts, big.NewInt(1)))
+	require.NoError(t, err, "transaction should be sent after spike")
+
+	c.GasSpikeBreaker = seth.NewGasSpikeBreaker(seth.GasSpikeBreakerCfg{
+		CheckInterval: &seth.Duration{D: time.Millisecond},
+		MaxPause:      &seth.Duration{D: 20 * time.Millisecond},
+	}, feeSequence(10, 10, 10, 100))
+	for i := 0; i < 3; i++ {
+		_, err := c.GasSpikeBreaker.Check(context.Background())
+		require.NoError(t, err, "check should not fail")
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	err = seth.CheckTransactOpts(c.NewTXOpts())
+	require.Error(t, err, "options should have an error when spike lasts longer than max pause")
+	require.Contains(t, err.Error(), "gas spike breaker paused transaction submission", "incorrect error")
+}
+
+func TestConfigGasSpikeBreakerValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	cfg.GasSpikeBreaker = &seth.GasSpikeBreakerCfg{Multiplier: 2, ResumeMultiplier: 3}
+	err = seth.ValidateConfig(cfg)
+	require.Error(t, err, "resume multiplier above multiplier should be invalid")
+	require.Contains(t, err.Error(), "must not be greater than 'multiplier'", "incorrect error")
+
+	cfg.GasSpikeBreaker = &seth.GasSpikeBreakerCfg{Multiplier: 0.5}
+	require.Error(t, seth.ValidateConfig(cfg), "multiplier below 1 should be invalid")
+
+	cfg.GasSpikeBreaker = &seth.GasSpikeBreakerCfg{}
+	require.NoError(t, seth.ValidateConfig(cfg), "empty config should use defaults")
+}
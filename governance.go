@@ -0,0 +1,398 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TimelockControllerABIName is the name under which TimelockController ABI is added to the contract store
+	TimelockControllerABIName = "TimelockController"
+	// GovernorABIName is the name under which Governor ABI is added to the contract store
+	GovernorABIName = "Governor"
+
+	timelockControllerABIJSON = `[
+		{"type":"function","name":"schedule","stateMutability":"nonpayable","inputs":[{"name":"target","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"},{"name":"predecessor","type":"bytes32"},{"name":"salt","type":"bytes32"},{"name":"delay","type":"uint256"}],"outputs":[]},
+		{"type":"function","name":"scheduleBatch","stateMutability":"nonpayable","inputs":[{"name":"targets","type":"address[]"},{"name":"values","type":"uint256[]"},{"name":"payloads","type":"bytes[]"},{"name":"predecessor","type":"bytes32"},{"name":"salt","type":"bytes32"},{"name":"delay","type":"uint256"}],"outputs":[]},
+		{"type":"function","name":"execute","stateMutability":"payable","inputs":[{"name":"target","type":"address"},{"name":"value","type":"uint256"},{"name":"payload","type":"bytes"},{"name":"predecessor","type":"bytes32"},{"name":"salt","type":"bytes32"}],"outputs":[]},
+		{"type":"function","name":"executeBatch","stateMutability":"payable","inputs":[{"name":"targets","type":"address[]"},{"name":"values","type":"uint256[]"},{"name":"payloads","type":"bytes[]"},{"name":"predecessor","type":"bytes32"},{"name":"salt","type":"bytes32"}],"outputs":[]},
+		{"type":"function","name":"cancel","stateMutability":"nonpayable","inputs":[{"name":"id","type":"bytes32"}],"outputs":[]},
+		{"type":"function","name":"getMinDelay","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+		{"type":"function","name":"isOperationReady","stateMutability":"view","inputs":[{"name":"id","type":"bytes32"}],"outputs":[{"name":"","type":"bool"}]},
+		{"type":"function","name":"isOperationDone","stateMutability":"view","inputs":[{"name":"id","type":"bytes32"}],"outputs":[{"name":"","type":"bool"}]},
+		{"type":"event","name":"CallScheduled","anonymous":false,"inputs":[{"name":"id","type":"bytes32","indexed":true},{"name":"index","type":"uint256","indexed":true},{"name":"target","type":"address","indexed":false},{"name":"value","type":"uint256","indexed":false},{"name":"data","type":"bytes","indexed":false},{"name":"predecessor","type":"bytes32","indexed":false},{"name":"delay","type":"uint256","indexed":false}]},
+		{"type":"event","name":"CallExecuted","anonymous":false,"inputs":[{"name":"id","type":"bytes32","indexed":true},{"name":"index","type":"uint256","indexed":true},{"name":"target","type":"address","indexed":false},{"name":"value","type":"uint256","indexed":false},{"name":"data","type":"bytes","indexed":false}]},
+		{"type":"event","name":"Cancelled","anonymous":false,"inputs":[{"name":"id","type":"bytes32","indexed":true}]}
+	]`
+	governorABIJSON = `[
+		{"type":"function","name":"propose","stateMutability":"nonpayable","inputs":[{"name":"targets","type":"address[]"},{"name":"values","type":"uint256[]"},{"name":"calldatas","type":"bytes[]"},{"name":"description","type":"string"}],"outputs":[{"name":"","type":"uint256"}]},
+		{"type":"function","name":"queue","stateMutability":"nonpayable","inputs":[{"name":"targets","type":"address[]"},{"name":"values","type":"uint256[]"},{"name":"calldatas","type":"bytes[]"},{"name":"descriptionHash","type":"bytes32"}],"outputs":[{"name":"","type":"uint256"}]},
+		{"type":"function","name":"execute","stateMutability":"payable","inputs":[{"name":"targets","type":"address[]"},{"name":"values","type":"uint256[]"},{"name":"calldatas","type":"bytes[]"},{"name":"descriptionHash","type":"bytes32"}],"outputs":[{"name":"","type":"uint256"}]},
+		{"type":"function","name":"castVote","stateMutability":"nonpayable","inputs":[{"name":"proposalId","type":"uint256"},{"name":"support","type":"uint8"}],"outputs":[{"name":"","type":"uint256"}]},
+		{"type":"function","name":"castVoteWithReason","stateMutability":"nonpayable","inputs":[{"name":"proposalId","type":"uint256"},{"name":"support","type":"uint8"},{"name":"reason","type":"string"}],"outputs":[{"name":"","type":"uint256"}]},
+		{"type":"function","name":"state","stateMutability":"view","inputs":[{"name":"proposalId","type":"uint256"}],"outputs":[{"name":"","type":"uint8"}]},
+		{"type":"function","name":"proposalSnapshot","stateMutability":"view","inputs":[{"name":"proposalId","type":"uint256"}],"outputs":[{"name":"","type":"uint256"}]},
+		{"type":"function","name":"proposalDeadline","stateMutability":"view","inputs":[{"name":"proposalId","type":"uint256"}],"outputs":[{"name":"","type":"uint256"}]},
+		{"type":"event","name":"ProposalCreated","anonymous":false,"inputs":[{"name":"proposalId","type":"uint256","indexed":false},{"name":"proposer","type":"address","indexed":false},{"name":"targets","type":"address[]","indexed":false},{"name":"values","type":"uint256[]","indexed":false},{"name":"signatures","type":"string[]","indexed":false},{"name":"calldatas","type":"bytes[]","indexed":false},{"name":"voteStart","type":"uint256","indexed":false},{"name":"voteEnd","type":"uint256","indexed":false},{"name":"description","type":"string","indexed":false}]},
+		{"type":"event","name":"VoteCast","anonymous":false,"inputs":[{"name":"voter","type":"address","indexed":true},{"name":"proposalId","type":"uint256","indexed":false},{"name":"support","type":"uint8","indexed":false},{"name":"weight","type":"uint256","indexed":false},{"name":"reason","type":"string","indexed":false}]}
+	]`
+)
+
+var (
+	ErrEmptyTimelockOperation = errors.New("timelock operation has no calls")
+	ErrInvalidProposal        = errors.New("proposal must have the same number of targets, values and calldatas and at least one call")
+	ErrOperationNotReady      = errors.New("timelock operation is not ready for execution before timeout")
+)
+
+// Vote types of OpenZeppelin's GovernorCountingSimple
+const (
+	VoteAgainst uint8 = iota
+	VoteFor
+	VoteAbstain
+)
+
+// ProposalStates are names of OpenZeppelin Governor proposal states, indexed by value returned by state()
+var ProposalStates = []string{"Pending", "Active", "Canceled", "Defeated", "Succeeded", "Queued", "Expired", "Executed"}
+
+var (
+	timelockControllerABI = mustParseABI(timelockControllerABIJSON)
+	governorABI           = mustParseABI(governorABIJSON)
+)
+
+// GovernanceCall is a single call executed by a timelock or governor
+type GovernanceCall struct {
+	Target common.Address
+	Value  *big.Int
+	Data   []byte
+}
+
+// NewGovernanceCall packs method call with given ABI into a governance call without value
+func NewGovernanceCall(target common.Address, contractABI abi.ABI, method string, params ...interface{}) (GovernanceCall, error) {
+	data, err := contractABI.Pack(method, params...)
+	if err != nil {
+		return GovernanceCall{}, errors.Wrapf(err, "failed to pack %s call", method)
+	}
+	return GovernanceCall{Target: target, Value: big.NewInt(0), Data: data}, nil
+}
+
+// TimelockOperation is an OpenZeppelin TimelockController operation, with more than one call it's scheduled
+// and executed as a batch
+type TimelockOperation struct {
+	Calls       []GovernanceCall
+	Predecessor common.Hash
+	Salt        common.Hash
+}
+
+func (o TimelockOperation) isBatch() bool {
+	return len(o.Calls) > 1
+}
+
+func (o TimelockOperation) split() ([]common.Address, []*big.Int, [][]byte) {
+	return splitGovernanceCalls(o.Calls)
+}
+
+// ID returns operation id, the same as TimelockController's hashOperation() or hashOperationBatch()
+func (o TimelockOperation) ID() (common.Hash, error) {
+	if len(o.Calls) == 0 {
+		return common.Hash{}, ErrEmptyTimelockOperation
+	}
+
+	var args abi.Arguments
+	var values []interface{}
+	if o.isBatch() {
+		targets, vals, payloads := o.split()
+		args = abi.Arguments{{Type: mustNewType("address[]")}, {Type: mustNewType("uint256[]")}, {Type: mustNewType("bytes[]")}, {Type: mustNewType("bytes32")}, {Type: mustNewType("bytes32")}}
+		values = []interface{}{targets, vals, payloads, o.Predecessor, o.Salt}
+	} else {
+		c := o.Calls[0]
+		args = abi.Arguments{{Type: mustNewType("address")}, {Type: mustNewType("uint256")}, {Type: mustNewType("bytes")}, {Type: mustNewType("bytes32")}, {Type: mustNewType("bytes32")}}
+		values = []interface{}{c.Target, valueOrZero(c.Value), c.Data, o.Predecessor, o.Salt}
+	}
+
+	encoded, err := args.Pack(values...)
+	if err != nil {
+		return common.Hash{}, errors.Wrap(err, "failed to encode timelock operation")
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// ScheduleCalldata returns calldata of schedule() or scheduleBatch(), e.g. to be proposed to a governor or sent by a multisig
+func (o TimelockOperation) ScheduleCalldata(delay *big.Int) ([]byte, error) {
+	if len(o.Calls) == 0 {
+		return nil, ErrEmptyTimelockOperation
+	}
+	if o.isBatch() {
+		targets, values, payloads := o.split()
+		return timelockControllerABI.Pack("scheduleBatch", targets, values, payloads, o.Predecessor, o.Salt, delay)
+	}
+	c := o.Calls[0]
+	return timelockControllerABI.Pack("schedule", c.Target, valueOrZero(c.Value), c.Data, o.Predecessor, o.Salt, delay)
+}
+
+// ExecuteCalldata returns calldata of execute() or executeBatch()
+func (o TimelockOperation) ExecuteCalldata() ([]byte, error) {
+	if len(o.Calls) == 0 {
+		return nil, ErrEmptyTimelockOperation
+	}
+	if o.isBatch() {
+		targets, values, payloads := o.split()
+		return timelockControllerABI.Pack("executeBatch", targets, values, payloads, o.Predecessor, o.Salt)
+	}
+	c := o.Calls[0]
+	return timelockControllerABI.Pack("execute", c.Target, valueOrZero(c.Value), c.Data, o.Predecessor, o.Salt)
+}
+
+// Value returns sum of values of all calls, that has to be sent with execution
+func (o TimelockOperation) Value() *big.Int {
+	return sumGovernanceCallValues(o.Calls)
+}
+
+// Timelock sends OpenZeppelin TimelockController transactions with Seth keys. Its ABI is added to the contract store and
+// its address to the contract map, so scheduled and executed calls are decoded (also in traces) as nested calls.
+type Timelock struct {
+	Address  common.Address
+	client   *Client
+	contract *bind.BoundContract
+}
+
+// NewTimelock returns helper for TimelockController deployed at the address
+func (m *Client) NewTimelock(address common.Address) *Timelock {
+	m.registerGovernanceContract(address, TimelockControllerABIName, timelockControllerABI)
+	return &Timelock{
+		Address:  address,
+		client:   m,
+		contract: bind.NewBoundContract(address, timelockControllerABI, m.Client, m.Client, m.Client),
+	}
+}
+
+// Schedule schedules the operation with given delay (it has to be at least timelock's minimum delay)
+func (t *Timelock) Schedule(opts *bind.TransactOpts, op TimelockOperation, delay *big.Int) (*DecodedTransaction, error) {
+	data, err := op.ScheduleCalldata(delay)
+	if err != nil {
+		return nil, err
+	}
+	return t.client.Decode(t.contract.RawTransact(opts, data))
+}
+
+// Execute executes the operation, value of transaction options is set to the sum of values of operation's calls
+func (t *Timelock) Execute(opts *bind.TransactOpts, op TimelockOperation) (*DecodedTransaction, error) {
+	data, err := op.ExecuteCalldata()
+	if err != nil {
+		return nil, err
+	}
+	opts.Value = op.Value()
+	return t.client.Decode(t.contract.RawTransact(opts, data))
+}
+
+// Cancel cancels pending operation
+func (t *Timelock) Cancel(opts *bind.TransactOpts, op TimelockOperation) (*DecodedTransaction, error) {
+	id, err := op.ID()
+	if err != nil {
+		return nil, err
+	}
+	return t.client.Decode(t.contract.Transact(opts, "cancel", id))
+}
+
+// MinDelay returns minimum delay of operations
+func (t *Timelock) MinDelay(ctx context.Context) (*big.Int, error) {
+	var out []interface{}
+	if err := t.contract.Call(&bind.CallOpts{Context: ctx}, &out, "getMinDelay"); err != nil {
+		return nil, err
+	}
+	return *abi.ConvertType(out[0], new(*big.Int)).(**big.Int), nil
+}
+
+// IsOperationReady returns true if the operation was scheduled and its delay has passed
+func (t *Timelock) IsOperationReady(ctx context.Context, op TimelockOperation) (bool, error) {
+	return t.operationStatus(ctx, "isOperationReady", op)
+}
+
+// IsOperationDone returns true if the operation was executed
+func (t *Timelock) IsOperationDone(ctx context.Context, op TimelockOperation) (bool, error) {
+	return t.operationStatus(ctx, "isOperationDone", op)
+}
+
+// WaitUntilReady waits until the operation is ready for execution. Timelock uses block timestamps, so on simulated
+// networks blocks have to be produced in the meantime.
+func (t *Timelock) WaitUntilReady(ctx context.Context, op TimelockOperation, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		ready, err := t.IsOperationReady(ctx, op)
+		if err == nil && ready {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			id, _ := op.ID()
+			return errors.Wrapf(ErrOperationNotReady, "operation: %s", id.Hex())
+		case <-time.After(t.client.Cfg.Network.ReceiptPollingDelay(0)):
+		}
+	}
+}
+
+func (t *Timelock) operationStatus(ctx context.Context, method string, op TimelockOperation) (bool, error) {
+	id, err := op.ID()
+	if err != nil {
+		return false, err
+	}
+	var out []interface{}
+	if err := t.contract.Call(&bind.CallOpts{Context: ctx}, &out, method, id); err != nil {
+		return false, err
+	}
+	return *abi.ConvertType(out[0], new(bool)).(*bool), nil
+}
+
+// GovernorProposal is an OpenZeppelin Governor proposal
+type GovernorProposal struct {
+	Calls       []GovernanceCall
+	Description string
+}
+
+// DescriptionHash returns keccak256 of proposal's description
+func (p GovernorProposal) DescriptionHash() common.Hash {
+	return crypto.Keccak256Hash([]byte(p.Description))
+}
+
+// ID returns proposal id, the same as Governor's hashProposal()
+func (p GovernorProposal) ID() (*big.Int, error) {
+	if len(p.Calls) == 0 {
+		return nil, ErrInvalidProposal
+	}
+	targets, values, calldatas := splitGovernanceCalls(p.Calls)
+	args := abi.Arguments{{Type: mustNewType("address[]")}, {Type: mustNewType("uint256[]")}, {Type: mustNewType("bytes[]")}, {Type: mustNewType("bytes32")}}
+	encoded, err := args.Pack(targets, values, calldatas, p.DescriptionHash())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode proposal")
+	}
+	return new(big.Int).SetBytes(crypto.Keccak256(encoded)), nil
+}
+
+// Governor sends OpenZeppelin Governor transactions with Seth keys. Its ABI is added to the contract store and its
+// address to the contract map, so proposed calls are decoded (also in traces) as nested calls.
+type Governor struct {
+	Address  common.Address
+	client   *Client
+	contract *bind.BoundContract
+}
+
+// NewGovernor returns helper for Governor deployed at the address
+func (m *Client) NewGovernor(address common.Address) *Governor {
+	m.registerGovernanceContract(address, GovernorABIName, governorABI)
+	return &Governor{
+		Address:  address,
+		client:   m,
+		contract: bind.NewBoundContract(address, governorABI, m.Client, m.Client, m.Client),
+	}
+}
+
+// Propose creates the proposal
+func (g *Governor) Propose(opts *bind.TransactOpts, p GovernorProposal) (*DecodedTransaction, error) {
+	if len(p.Calls) == 0 {
+		return nil, ErrInvalidProposal
+	}
+	targets, values, calldatas := splitGovernanceCalls(p.Calls)
+	return g.client.Decode(g.contract.Transact(opts, "propose", targets, values, calldatas, p.Description))
+}
+
+// CastVote votes on the proposal, support is one of VoteAgainst, VoteFor or VoteAbstain
+func (g *Governor) CastVote(opts *bind.TransactOpts, p GovernorProposal, support uint8) (*DecodedTransaction, error) {
+	id, err := p.ID()
+	if err != nil {
+		return nil, err
+	}
+	return g.client.Decode(g.contract.Transact(opts, "castVote", id, support))
+}
+
+// Queue queues succeeded proposal in governor's timelock
+func (g *Governor) Queue(opts *bind.TransactOpts, p GovernorProposal) (*DecodedTransaction, error) {
+	if len(p.Calls) == 0 {
+		return nil, ErrInvalidProposal
+	}
+	targets, values, calldatas := splitGovernanceCalls(p.Calls)
+	return g.client.Decode(g.contract.Transact(opts, "queue", targets, values, calldatas, p.DescriptionHash()))
+}
+
+// Execute executes the proposal, value of transaction options is set to the sum of values of proposal's calls
+func (g *Governor) Execute(opts *bind.TransactOpts, p GovernorProposal) (*DecodedTransaction, error) {
+	if len(p.Calls) == 0 {
+		return nil, ErrInvalidProposal
+	}
+	targets, values, calldatas := splitGovernanceCalls(p.Calls)
+	opts.Value = sumGovernanceCallValues(p.Calls)
+	return g.client.Decode(g.contract.Transact(opts, "execute", targets, values, calldatas, p.DescriptionHash()))
+}
+
+// State returns proposal's state and its name (see ProposalStates)
+func (g *Governor) State(ctx context.Context, p GovernorProposal) (uint8, string, error) {
+	id, err := p.ID()
+	if err != nil {
+		return 0, "", err
+	}
+	var out []interface{}
+	if err := g.contract.Call(&bind.CallOpts{Context: ctx}, &out, "state", id); err != nil {
+		return 0, "", err
+	}
+	state := *abi.ConvertType(out[0], new(uint8)).(*uint8)
+	name := UNKNOWN
+	if int(state) < len(ProposalStates) {
+		name = ProposalStates[state]
+	}
+	return state, name, nil
+}
+
+// registerGovernanceContract adds governance ABI to the contract store and address to the contract map, unless ABI
+// of the contract deployed at the address is already known
+func (m *Client) registerGovernanceContract(address common.Address, name string, contractABI abi.ABI) {
+	if m.ContractStore == nil {
+		return
+	}
+	if _, ok := m.ContractStore.GetABI(name); !ok {
+		m.ContractStore.AddABI(name, contractABI)
+	}
+	if !m.ContractAddressToNameMap.IsKnownAddress(address.Hex()) {
+		m.ContractAddressToNameMap.AddContract(address.Hex(), name)
+	}
+}
+
+func splitGovernanceCalls(calls []GovernanceCall) ([]common.Address, []*big.Int, [][]byte) {
+	targets := make([]common.Address, len(calls))
+	values := make([]*big.Int, len(calls))
+	payloads := make([][]byte, len(calls))
+	for i, c := range calls {
+		targets[i] = c.Target
+		values[i] = valueOrZero(c.Value)
+		payloads[i] = c.Data
+	}
+	return targets, values, payloads
+}
+
+func sumGovernanceCallValues(calls []GovernanceCall) *big.Int {
+	sum := big.NewInt(0)
+	for _, c := range calls {
+		sum.Add(sum, valueOrZero(c.Value))
+	}
+	return sum
+}
+
+func valueOrZero(v *big.Int) *big.Int {
+	if v == nil {
+		return big.NewInt(0)
+	}
+	return v
+}
+
+func mustNewType(t string) abi.Type {
+	typ, err := abi.NewType(t, "", nil)
+	if err != nil {
+		panic(err)
+	}
+	return typ
+}
@@ -0,0 +1,96 @@
+package seth_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func word(v int64) []byte {
+	return common.LeftPadBytes(big.NewInt(v).Bytes(), 32)
+}
+
+func concatBytes(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func TestUtilGovernanceIDs(t *testing.T) {
+	target := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	data := []byte{0x12, 0x34, 0x56, 0x78}
+	predecessor := common.HexToHash("0x01")
+	salt := common.HexToHash("0x02")
+	paddedData := common.RightPadBytes(data, 32)
+
+	op := seth.TimelockOperation{
+		Calls:       []seth.GovernanceCall{{Target: target, Value: big.NewInt(5), Data: data}},
+		Predecessor: predecessor,
+		Salt:        salt,
+	}
+	id, err := op.ID()
+	require.NoError(t, err, "failed to hash operation")
+	// abi.encode(target, value, data, predecessor, salt)
+	expected := crypto.Keccak256Hash(concatBytes(
+		common.LeftPadBytes(target.Bytes(), 32), word(5), word(0xa0), predecessor.Bytes(), salt.Bytes(), word(4), paddedData,
+	))
+	require.Equal(t, expected, id, "operation id should match TimelockController's hashOperation")
+
+	proposal := seth.GovernorProposal{
+		Calls:       []seth.GovernanceCall{{Target: target, Data: data}},
+		Description: "Proposal #1",
+	}
+	proposalID, err := proposal.ID()
+	require.NoError(t, err, "failed to hash proposal")
+	// abi.encode(targets, values, calldatas, keccak256(description))
+	expectedProposalID := crypto.Keccak256(concatBytes(
+		word(0x80), word(0xc0), word(0x100), crypto.Keccak256([]byte("Proposal #1")),
+		word(1), common.LeftPadBytes(target.Bytes(), 32),
+		word(1), word(0),
+		word(1), word(0x20), word(4), paddedData,
+	))
+	require.Equal(t, new(big.Int).SetBytes(expectedProposalID), proposalID, "proposal id should match Governor's hashProposal")
+
+	_, err = seth.TimelockOperation{}.ID()
+	require.EqualError(t, err, "timelock operation has no calls")
+}
+
+func TestAPITimelockNestedCallDecoding(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	debugAbi, ok := c.ContractStore.GetABI("NetworkDebugContract")
+	require.True(t, ok, "ABI should be loaded")
+
+	call, err := seth.NewGovernanceCall(TestEnv.DebugContractAddress, *debugAbi, "set", big.NewInt(7))
+	require.NoError(t, err, "failed to create call")
+	other, err := seth.NewGovernanceCall(TestEnv.DebugContractAddress, *debugAbi, "addCounter", big.NewInt(1), big.NewInt(2))
+	require.NoError(t, err, "failed to create call")
+
+	// there's no timelock deployed, so calls are sent to an address without code, which only has to be decoded
+	tl := c.NewTimelock(common.HexToAddress("0x00000000000000000000000000000000000071e1"))
+
+	decoded, err := tl.Schedule(c.NewTXOpts(), seth.TimelockOperation{Calls: []seth.GovernanceCall{call}}, big.NewInt(60))
+	require.NoError(t, err, "failed to schedule operation")
+	require.Equal(t, "schedule(address,uint256,bytes,bytes32,bytes32,uint256)", decoded.Method, "incorrect method")
+	require.Equal(t, "set(int256)", decoded.NestedCalls["data"].Method, "scheduled call should be decoded")
+	require.Equal(t, TestEnv.DebugContractAddress.Hex(), decoded.NestedCalls["data"].To, "incorrect target of scheduled call")
+
+	batch := seth.TimelockOperation{Calls: []seth.GovernanceCall{call, other}, Salt: common.HexToHash("0x1")}
+	decoded, err = tl.Execute(c.NewTXOpts(), batch)
+	require.NoError(t, err, "failed to execute operation")
+	require.Equal(t, "executeBatch(address[],uint256[],bytes[],bytes32,bytes32)", decoded.Method, "incorrect method")
+	require.Equal(t, "set(int256)", decoded.NestedCalls["payloads[0]"].Method, "first call should be decoded")
+	require.Equal(t, "addCounter(int256,int256)", decoded.NestedCalls["payloads[1]"].Method, "second call should be decoded")
+
+	governor := c.NewGovernor(common.HexToAddress("0x0000000000000000000000000000000000006071"))
+	decoded, err = governor.Propose(c.NewTXOpts(), seth.GovernorProposal{Calls: []seth.GovernanceCall{other}, Description: "add counter"})
+	require.NoError(t, err, "failed to propose")
+	require.Equal(t, "propose(address[],uint256[],bytes[],string)", decoded.Method, "incorrect method")
+	require.Equal(t, "addCounter(int256,int256)", decoded.NestedCalls["calldatas[0]"].Method, "proposed call should be decoded")
+	require.Equal(t, "NetworkDebugContract", decoded.NestedCalls["calldatas[0]"].Contract, "incorrect contract of proposed call")
+}
@@ -82,3 +82,11 @@ func (c *LFUHeaderCache) evict() {
 	L.Trace().Msgf("Evicted header %d from cache", evictKey)
 	delete(c.cache, evictKey)
 }
+
+// Remove removes a header from the cache, e.g. when it was replaced by a chain reorg.
+func (c *LFUHeaderCache) Remove(blockNumber int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.cache, blockNumber)
+}
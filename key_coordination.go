@@ -0,0 +1,224 @@
+package seth
+
+import (
+	"context"
+	verr "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gofrs/flock"
+	"github.com/pkg/errors"
+)
+
+const (
+	KeyCoordinationBackend_File  = "file"
+	KeyCoordinationBackend_Redis = "redis"
+
+	DefaultKeyCoordinationDir       = "key_locks"
+	DefaultKeyCoordinationPrefix    = "seth"
+	DefaultKeyCoordinationLockTTL   = time.Minute
+	DefaultKeyCoordinationRetryTime = 100 * time.Millisecond
+)
+
+var (
+	ErrKeyCoordinationBackend        = errors.New("'backend' of key coordination must be either 'file' or 'redis'")
+	ErrKeyCoordinationRedisURL       = errors.New("'redis_url' of key coordination must be set, when 'redis' backend is used")
+	ErrKeyCoordinationWithProtection = errors.New("key coordination can't be used together with pending_nonce_protection_enabled, since keys can have pending transactions sent by other processes")
+	ErrKeyCoordinationNonce          = errors.New("failed to allocate coordinated nonce")
+	ErrKeyCoordinationLock           = errors.New("failed to lock key")
+)
+
+// KeyCoordinator serializes usage of keys and assignment of nonces across processes, which share the same keys (e.g. test
+// pods using the same keyfile). Keys locked by one process can't be acquired with AcquireKey() by others and nonces are
+// allocated from a counter shared by all processes.
+type KeyCoordinator interface {
+	// TryLockKey locks the key for this process, returns false if it's locked by another process
+	TryLockKey(ctx context.Context, address common.Address) (bool, error)
+	// UnlockKey unlocks the key locked by this process
+	UnlockKey(ctx context.Context, address common.Address) error
+	// AllocateNonce returns next nonce of the key from the shared counter and increments it. Pending nonce fetched from
+	// the node is the lower bound of returned nonce, so that transactions sent without coordination are taken into account.
+	AllocateNonce(ctx context.Context, address common.Address, pendingNonce uint64) (uint64, error)
+	// SetNonce sets the shared counter of the key, e.g. to the pending nonce after a transaction wasn't sent
+	SetNonce(ctx context.Context, address common.Address, nonce uint64) error
+	// Close unlocks all keys locked by this process and releases backend's resources
+	Close() error
+}
+
+// KeyCoordinationCfg configures coordination of keys between processes
+type KeyCoordinationCfg struct {
+	// Backend is either "file" (lock files in a shared directory) or "redis"
+	Backend string `toml:"backend"`
+	// Dir is the directory of lock files, it has to be shared by all processes, default "key_locks"
+	Dir string `toml:"dir"`
+	// RedisURL is the URL of redis server, e.g. "redis://:password@localhost:6379/0", "rediss://" uses TLS
+	RedisURL string `toml:"redis_url"`
+	// KeyPrefix is the prefix of redis keys, default "seth"
+	KeyPrefix string `toml:"key_prefix"`
+	// LockTTL is the time after which redis lock of a key held by a process, that died, expires, default 1m
+	LockTTL *Duration `toml:"lock_ttl"`
+	// RetryInterval is the pause between attempts to lock a key in AcquireKey(), when all keys are locked, default 100ms
+	RetryInterval *Duration `toml:"retry_interval"`
+}
+
+func validateKeyCoordinationCfg(cfg *Config) error {
+	kc := cfg.KeyCoordination
+	if kc == nil {
+		return nil
+	}
+	switch kc.Backend {
+	case KeyCoordinationBackend_File:
+		if kc.Dir == "" {
+			kc.Dir = DefaultKeyCoordinationDir
+		}
+	case KeyCoordinationBackend_Redis:
+		if kc.RedisURL == "" {
+			return ErrKeyCoordinationRedisURL
+		}
+		if kc.KeyPrefix == "" {
+			kc.KeyPrefix = DefaultKeyCoordinationPrefix
+		}
+	default:
+		return ErrKeyCoordinationBackend
+	}
+	if kc.LockTTL == nil || kc.LockTTL.Duration() <= 0 {
+		kc.LockTTL = &Duration{D: DefaultKeyCoordinationLockTTL}
+	}
+	if kc.RetryInterval == nil || kc.RetryInterval.Duration() <= 0 {
+		kc.RetryInterval = &Duration{D: DefaultKeyCoordinationRetryTime}
+	}
+	if cfg.PendingNonceProtectionEnabled {
+		return ErrKeyCoordinationWithProtection
+	}
+	return nil
+}
+
+// NewKeyCoordinator creates key coordinator of configured backend. Keys of different chains are coordinated separately.
+func NewKeyCoordinator(cfg *KeyCoordinationCfg, chainID int64) (KeyCoordinator, error) {
+	switch cfg.Backend {
+	case KeyCoordinationBackend_File:
+		return NewFileKeyCoordinator(filepath.Join(cfg.Dir, strconv.FormatInt(chainID, 10)), cfg.RetryInterval.Duration())
+	case KeyCoordinationBackend_Redis:
+		return NewRedisKeyCoordinator(cfg.RedisURL, fmt.Sprintf("%s:%d", cfg.KeyPrefix, chainID), cfg.LockTTL.Duration())
+	default:
+		return nil, ErrKeyCoordinationBackend
+	}
+}
+
+// FileKeyCoordinator coordinates keys with advisory locks of files in a directory shared by all processes (e.g. a volume
+// mounted to all pods). Locks are released by the OS, when the process dies, so they never have to expire.
+type FileKeyCoordinator struct {
+	mu            *sync.Mutex
+	dir           string
+	retryInterval time.Duration
+	locks         map[common.Address]*flock.Flock
+}
+
+// NewFileKeyCoordinator creates file key coordinator, which keeps lock and nonce files in the directory
+func NewFileKeyCoordinator(dir string, retryInterval time.Duration) (*FileKeyCoordinator, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, errors.Wrapf(err, "failed to create key coordination directory %s", dir)
+	}
+	return &FileKeyCoordinator{
+		mu:            &sync.Mutex{},
+		dir:           dir,
+		retryInterval: retryInterval,
+		locks:         make(map[common.Address]*flock.Flock),
+	}, nil
+}
+
+func (f *FileKeyCoordinator) path(address common.Address, suffix string) string {
+	return filepath.Join(f.dir, address.Hex()+suffix)
+}
+
+func (f *FileKeyCoordinator) TryLockKey(_ context.Context, address common.Address) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.locks[address]; ok {
+		return true, nil
+	}
+	lock := flock.New(f.path(address, ".lock"))
+	locked, err := lock.TryLock()
+	if err != nil {
+		return false, errors.Wrapf(wrapError(err, ErrKeyCoordinationLock), "address: %s", address.Hex())
+	}
+	if locked {
+		f.locks[address] = lock
+	}
+	return locked, nil
+}
+
+func (f *FileKeyCoordinator) UnlockKey(_ context.Context, address common.Address) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lock, ok := f.locks[address]
+	if !ok {
+		return nil
+	}
+	delete(f.locks, address)
+	return lock.Unlock()
+}
+
+// withNonceFile calls fn with the value of key's nonce counter (0, if it doesn't exist yet) holding the lock of the nonce
+// file and writes the value returned by fn
+func (f *FileKeyCoordinator) withNonceFile(ctx context.Context, address common.Address, fn func(current uint64) uint64) error {
+	lock := flock.New(f.path(address, ".nonce.lock"))
+	if _, err := lock.TryLockContext(ctx, f.retryInterval); err != nil {
+		return err
+	}
+	defer func() {
+		_ = lock.Unlock()
+	}()
+
+	var current uint64
+	data, err := os.ReadFile(f.path(address, ".nonce"))
+	switch {
+	case err == nil:
+		if current, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err != nil {
+			return err
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+	return os.WriteFile(f.path(address, ".nonce"), []byte(strconv.FormatUint(fn(current), 10)), 0600)
+}
+
+func (f *FileKeyCoordinator) AllocateNonce(ctx context.Context, address common.Address, pendingNonce uint64) (uint64, error) {
+	var nonce uint64
+	err := f.withNonceFile(ctx, address, func(current uint64) uint64 {
+		nonce = current
+		if pendingNonce > nonce {
+			nonce = pendingNonce
+		}
+		return nonce + 1
+	})
+	if err != nil {
+		return 0, errors.Wrapf(wrapError(err, ErrKeyCoordinationNonce), "address: %s", address.Hex())
+	}
+	return nonce, nil
+}
+
+func (f *FileKeyCoordinator) SetNonce(ctx context.Context, address common.Address, nonce uint64) error {
+	return f.withNonceFile(ctx, address, func(uint64) uint64 {
+		return nonce
+	})
+}
+
+func (f *FileKeyCoordinator) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var errs []error
+	for address, lock := range f.locks {
+		if err := lock.Unlock(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(f.locks, address)
+	}
+	return verr.Join(errs...)
+}
@@ -0,0 +1,165 @@
+package seth
+
+import (
+	"context"
+	verr "errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisAllocateNonceScript atomically returns max(counter, pending nonce) and stores it incremented by one
+	redisAllocateNonceScript = `local n = tonumber(redis.call('GET', KEYS[1]) or '0')
+local p = tonumber(ARGV[1])
+if p > n then n = p end
+redis.call('SET', KEYS[1], n + 1)
+return n`
+	// redisUnlockScript deletes the lock only if it's still held by the same process
+	redisUnlockScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end
+return 0`
+	// redisRefreshScript extends the lock only if it's still held by the same process
+	redisRefreshScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('PEXPIRE', KEYS[1], ARGV[2]) end
+return 0`
+)
+
+var (
+	ErrRedisURL = errors.New("invalid redis URL")
+
+	allocateNonceScript = redis.NewScript(redisAllocateNonceScript)
+	unlockScript        = redis.NewScript(redisUnlockScript)
+	refreshScript       = redis.NewScript(redisRefreshScript)
+)
+
+// RedisKeyCoordinator coordinates keys with locks and nonce counters kept in redis. Locks expire after TTL, so that keys of
+// processes, that died, are freed, and they are refreshed in the background as long as they are held.
+type RedisKeyCoordinator struct {
+	mu     *sync.Mutex
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+	// token identifies locks of this process
+	token string
+	locks map[common.Address]context.CancelFunc
+}
+
+// NewRedisKeyCoordinator creates redis key coordinator, all its keys start with the prefix
+func NewRedisKeyCoordinator(redisURL, prefix string, ttl time.Duration) (*RedisKeyCoordinator, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, errors.Wrapf(ErrRedisURL, "URL: '%s'", redactedRedisURL(redisURL))
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		_ = client.Close()
+		return nil, errors.Wrap(err, "failed to connect to redis")
+	}
+	return &RedisKeyCoordinator{
+		mu:     &sync.Mutex{},
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+		token:  uuid.NewString(),
+		locks:  make(map[common.Address]context.CancelFunc),
+	}, nil
+}
+
+// redactedRedisURL returns redis URL without user info, so that password doesn't end up in errors and logs
+func redactedRedisURL(redisURL string) string {
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		return redactedValue
+	}
+	u.User = nil
+	return u.String()
+}
+
+func (r *RedisKeyCoordinator) key(address common.Address, kind string) string {
+	return fmt.Sprintf("%s:%s:%s", r.prefix, address.Hex(), kind)
+}
+
+func (r *RedisKeyCoordinator) TryLockKey(ctx context.Context, address common.Address) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.locks[address]; ok {
+		return true, nil
+	}
+	locked, err := r.client.SetNX(ctx, r.key(address, "lock"), r.token, r.ttl).Result()
+	if err != nil {
+		return false, errors.Wrapf(wrapError(err, ErrKeyCoordinationLock), "address: %s", address.Hex())
+	}
+	if !locked {
+		return false, nil
+	}
+	refreshCtx, cancel := context.WithCancel(context.Background())
+	r.locks[address] = cancel
+	go r.refreshLock(refreshCtx, address)
+	return true, nil
+}
+
+// refreshLock extends the lock every third of its TTL until it's unlocked
+func (r *RedisKeyCoordinator) refreshLock(ctx context.Context, address common.Address) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.ttl / 3):
+		}
+		err := refreshScript.Run(ctx, r.client, []string{r.key(address, "lock")}, r.token, r.ttl.Milliseconds()).Err()
+		if err != nil && ctx.Err() == nil {
+			L.Warn().Err(err).Str("Address", address.Hex()).Msg("Failed to refresh redis lock of the key")
+		}
+	}
+}
+
+func (r *RedisKeyCoordinator) UnlockKey(ctx context.Context, address common.Address) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.unlock(ctx, address)
+}
+
+func (r *RedisKeyCoordinator) unlock(ctx context.Context, address common.Address) error {
+	stopRefresh, ok := r.locks[address]
+	if !ok {
+		return nil
+	}
+	stopRefresh()
+	delete(r.locks, address)
+	return unlockScript.Run(ctx, r.client, []string{r.key(address, "lock")}, r.token).Err()
+}
+
+func (r *RedisKeyCoordinator) AllocateNonce(ctx context.Context, address common.Address, pendingNonce uint64) (uint64, error) {
+	nonce, err := allocateNonceScript.Run(ctx, r.client, []string{r.key(address, "nonce")}, pendingNonce).Int64()
+	if err != nil {
+		return 0, errors.Wrapf(wrapError(err, ErrKeyCoordinationNonce), "address: %s", address.Hex())
+	}
+	if nonce < 0 {
+		return 0, errors.Wrapf(wrapError(fmt.Errorf("unexpected reply: %d", nonce), ErrKeyCoordinationNonce), "address: %s", address.Hex())
+	}
+	return uint64(nonce), nil
+}
+
+func (r *RedisKeyCoordinator) SetNonce(ctx context.Context, address common.Address, nonce uint64) error {
+	return r.client.Set(ctx, r.key(address, "nonce"), nonce, 0).Err()
+}
+
+func (r *RedisKeyCoordinator) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var errs []error
+	for address := range r.locks {
+		if err := r.unlock(context.Background(), address); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := r.client.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return verr.Join(errs...)
+}
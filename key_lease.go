@@ -0,0 +1,195 @@
+package seth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	// leasedKeyRequeueDelay is the pause after a leased key was taken from synced keys and put back, so that
+	// AnySyncedKey() doesn't spin, when all synced keys are leased
+	leasedKeyRequeueDelay = 10 * time.Millisecond
+)
+
+var (
+	ErrAcquireKey = errors.New("failed to acquire a key")
+	ErrKeyLeasing = errors.New("nonce manager is not set, keys can't be leased")
+)
+
+// KeyLease is a key handed out exclusively to a single holder until it's released. While key is leased no other lease is
+// given for it and AnySyncedKey() doesn't return it.
+type KeyLease struct {
+	KeyNum  int
+	Address common.Address
+	client  *Client
+	once    sync.Once
+}
+
+// keyPool holds keys, which can be leased: all keys except the root key or just the root key, if it's the only one
+type keyPool struct {
+	mu     *sync.Mutex
+	size   int
+	free   chan int
+	leased map[int]struct{}
+}
+
+func newKeyPool(addrs []common.Address) *keyPool {
+	first := 1
+	if len(addrs) == 1 {
+		first = 0
+	}
+	p := &keyPool{
+		mu:     &sync.Mutex{},
+		size:   len(addrs) - first,
+		free:   make(chan int, len(addrs)),
+		leased: make(map[int]struct{}),
+	}
+	for keyNum := first; keyNum < len(addrs); keyNum++ {
+		p.free <- keyNum
+	}
+	return p
+}
+
+func (p *keyPool) lease(keyNum int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.leased[keyNum] = struct{}{}
+}
+
+func (p *keyPool) release(keyNum int) {
+	p.mu.Lock()
+	delete(p.leased, keyNum)
+	p.mu.Unlock()
+	p.free <- keyNum
+}
+
+func (p *keyPool) isLeased(keyNum int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.leased[keyNum]
+	return ok
+}
+
+// AcquireKey leases a key exclusively to the caller, blocking until one is released, if all are leased, or until
+// the context is done. If key coordinator is set, keys locked by other processes are skipped and lease locks the key for
+// this process. Lease has to be released with Release(), when it's no longer needed.
+func (m *Client) AcquireKey(ctx context.Context) (*KeyLease, error) {
+	return m.acquireKey(ctx, true)
+}
+
+// TryAcquireKey leases a key exclusively to the caller or returns an error immediately, if all keys are leased (or locked
+// by other processes)
+func (m *Client) TryAcquireKey() (*KeyLease, error) {
+	return m.acquireKey(context.Background(), false)
+}
+
+func (m *Client) acquireKey(ctx context.Context, wait bool) (*KeyLease, error) {
+	if m.NonceManager == nil {
+		return nil, ErrKeyLeasing
+	}
+	pool := m.NonceManager.keys
+	// number of keys locked by other processes, that were tried since the last pause
+	tried := 0
+	for {
+		var keyNum int
+		if wait {
+			select {
+			case <-ctx.Done():
+				return nil, wrapError(ctx.Err(), ErrAcquireKey)
+			case keyNum = <-pool.free:
+			}
+		} else {
+			select {
+			case keyNum = <-pool.free:
+			default:
+				return nil, ErrNoKeyAvailable
+			}
+		}
+		if m.KeyCoordinator == nil {
+			return m.newKeyLease(keyNum), nil
+		}
+
+		locked, err := m.KeyCoordinator.TryLockKey(ctx, m.Addresses[keyNum])
+		if err != nil {
+			pool.free <- keyNum
+			return nil, wrapError(err, ErrAcquireKey)
+		}
+		if locked {
+			return m.newKeyLease(keyNum), nil
+		}
+		// key is used by another process, try the next one and pause, when all were tried
+		pool.free <- keyNum
+		tried++
+		if tried < pool.size {
+			continue
+		}
+		if !wait {
+			return nil, ErrNoKeyAvailable
+		}
+		tried = 0
+		select {
+		case <-ctx.Done():
+			return nil, wrapError(ctx.Err(), ErrAcquireKey)
+		case <-time.After(m.keyLockRetryInterval()):
+		}
+	}
+}
+
+func (m *Client) keyLockRetryInterval() time.Duration {
+	if m.Cfg.KeyCoordination != nil && m.Cfg.KeyCoordination.RetryInterval != nil {
+		return m.Cfg.KeyCoordination.RetryInterval.Duration()
+	}
+	return DefaultKeyCoordinationRetryTime
+}
+
+func (m *Client) newKeyLease(keyNum int) *KeyLease {
+	m.NonceManager.keys.lease(keyNum)
+	m.logger().Debug().
+		Int("KeyNum", keyNum).
+		Str("Address", m.Addresses[keyNum].Hex()).
+		Msg("Key leased")
+	return &KeyLease{
+		KeyNum:  keyNum,
+		Address: m.Addresses[keyNum],
+		client:  m,
+	}
+}
+
+// NewTXOpts returns transaction options of the leased key
+func (k *KeyLease) NewTXOpts(o ...TransactOpt) *bind.TransactOpts {
+	return k.client.NewTXKeyOpts(k.KeyNum, o...)
+}
+
+// NewTXOptsCtx returns transaction options of the leased key, see Client.NewTXOptsCtx()
+func (k *KeyLease) NewTXOptsCtx(ctx context.Context, o ...TransactOpt) *bind.TransactOpts {
+	return k.client.NewTXKeyOptsCtx(ctx, k.KeyNum, o...)
+}
+
+// Release returns the key to the pool (and unlocks it for other processes), so that it can be leased again. If nonces of the key were allocated from local
+// counter, it's reconciled first, so that the next holder doesn't inherit a gap left by unsent transactions. Calling it
+// more than once is a no-op.
+func (k *KeyLease) Release() {
+	k.once.Do(func() {
+		nm := k.client.NonceManager
+		if nm.hasAllocated(k.Address) {
+			if err := nm.ReconcileNonce(context.Background(), k.Address); err != nil {
+				k.client.logger().Warn().Err(err).Int("KeyNum", k.KeyNum).Msg("Failed to reconcile nonce of released key")
+			}
+		}
+		if k.client.KeyCoordinator != nil {
+			if err := k.client.KeyCoordinator.UnlockKey(context.Background(), k.Address); err != nil {
+				k.client.logger().Warn().Err(err).Int("KeyNum", k.KeyNum).Msg("Failed to unlock released key")
+			}
+		}
+		nm.keys.release(k.KeyNum)
+		k.client.logger().Debug().
+			Int("KeyNum", k.KeyNum).
+			Str("Address", k.Address.Hex()).
+			Msg("Key released")
+	})
+}
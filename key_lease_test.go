@@ -0,0 +1,55 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyLeasing(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	var two int64 = 2
+	cfg.EphemeralAddrs = &two
+	limitEphemeralFunds(t, cfg, seth.EtherToWei(big.NewFloat(1)))
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	t.Cleanup(func() {
+		_ = seth.ReturnFunds(c, c.Addresses[0].Hex())
+	})
+
+	first, err := c.AcquireKey(context.Background())
+	require.NoError(t, err, "failed to acquire key")
+	second, err := c.TryAcquireKey()
+	require.NoError(t, err, "failed to acquire key")
+	require.ElementsMatch(t, []int{1, 2}, []int{first.KeyNum, second.KeyNum}, "each lease should get a different non-root key")
+	require.Equal(t, c.Addresses[first.KeyNum], first.Address, "incorrect address of leased key")
+
+	_, err = c.TryAcquireKey()
+	require.ErrorIs(t, err, seth.ErrNoKeyAvailable, "no key should be available")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = c.AcquireKey(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded, "acquiring key should time out")
+
+	acquired := make(chan *seth.KeyLease, 1)
+	go func() {
+		lease, _ := c.AcquireKey(context.Background())
+		acquired <- lease
+	}()
+	_, err = c.Decode(TestEnv.DebugContract.Set(first.NewTXOpts(), big.NewInt(1)))
+	require.NoError(t, err, "failed to send transaction from leased key")
+	first.Release()
+	first.Release()
+	third := <-acquired
+	require.NotNil(t, third, "failed to acquire key")
+	require.Equal(t, first.KeyNum, third.KeyNum, "released key should be acquired by waiting caller")
+
+	third.Release()
+	require.Equal(t, third.KeyNum, c.AnySyncedKey(), "only key, which isn't leased, should be synced")
+	second.Release()
+}
@@ -11,6 +11,7 @@ import (
 	"os"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -42,37 +43,37 @@ func UpdateAndSplitFunds(c *Client, opts *FundKeyFileCmdOpts) error {
 		return err
 	}
 
-	gasPrice, err := c.GetSuggestedLegacyFees(context.Background(), Priority_Standard)
-	if err != nil {
-		gasPrice = big.NewInt(c.Cfg.Network.GasPrice)
-	}
+	fees := c.SuggestedTransferFees(context.Background())
 
-	bd, err := c.CalculateSubKeyFunding(opts.Addrs, gasPrice.Int64(), opts.RootKeyBuffer)
+	rootKeyBuffer := new(big.Int).Mul(big.NewInt(opts.RootKeyBuffer), big.NewInt(params.Ether))
+	bd, err := c.calculateSubKeyFunding(opts.Addrs, fees.MaxGasPrice(), rootKeyBuffer)
 	if err != nil {
 		return err
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	if err := c.checkTokenBalances(ctx, int64(len(keyFile.Keys))); err != nil {
+		return err
+	}
 	eg, egCtx := errgroup.WithContext(ctx)
+	addresses := make([]common.Address, 0, len(keyFile.Keys))
 	for _, kfd := range keyFile.Keys {
 		kfd := kfd
+		addresses = append(addresses, common.HexToAddress(kfd.Address))
 		eg.Go(func() error {
-			err := c.TransferETHFromKey(egCtx, 0, kfd.Address, bd.AddrFunding, gasPrice)
-			if err != nil {
-				return err
-			}
-			bal, err := c.Client.BalanceAt(egCtx, common.HexToAddress(kfd.Address), nil)
-			if err != nil {
-				return err
-			}
-			kfd.Funds = bal.String()
-			return nil
+			return c.TransferETHFromKeyWithFees(egCtx, 0, kfd.Address, bd.AddrFunding, fees)
 		})
 	}
 	if err := eg.Wait(); err != nil {
 		return err
 	}
+	if err := c.fundKeysWithTokens(ctx, addresses); err != nil {
+		return err
+	}
+	if err := c.updateKeyFileBalances(ctx, keyFile); err != nil {
+		return err
+	}
 	b, err := toml.Marshal(keyFile)
 	if err != nil {
 		return err
@@ -95,17 +96,13 @@ func UpdateAndSplitFunds(c *Client, opts *FundKeyFileCmdOpts) error {
 	return nil
 }
 
-// ReturnFunds returns funds to the root key from all other keys
+// ReturnFunds returns funds (and network's 'ephemeral_tokens', if any are configured) to the root key from all other keys.
+// Native funds are swept with SweepKey(), so that keys end with zero balance.
 func ReturnFunds(c *Client, toAddr string) error {
 	if toAddr == "" {
 		toAddr = c.Addresses[0].Hex()
 	}
 
-	gasPrice, err := c.GetSuggestedLegacyFees(context.Background(), Priority_Standard)
-	if err != nil {
-		gasPrice = big.NewInt(c.Cfg.Network.GasPrice)
-	}
-
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	eg, egCtx := errgroup.WithContext(ctx)
@@ -117,49 +114,19 @@ func ReturnFunds(c *Client, toAddr string) error {
 	for i := 1; i < len(c.Addresses); i++ {
 		idx := i
 		eg.Go(func() error {
-			balance, err := c.Client.BalanceAt(context.Background(), c.Addresses[idx], nil)
-			if err != nil {
-				L.Error().Err(err).Msg("Error getting balance")
+			// tokens are returned first, because their transfers are paid with native funds
+			if err := c.returnTokens(egCtx, idx, common.HexToAddress(toAddr)); err != nil {
 				return err
 			}
-
-			var gasLimit int64
-			gasLimitRaw, err := c.EstimateGasLimitForFundTransfer(c.Addresses[idx], common.HexToAddress(toAddr), balance)
-			if err != nil {
-				gasLimit = c.Cfg.Network.TransferGasFee
-			} else {
-				gasLimit = int64(gasLimitRaw)
-			}
-
-			networkTransferFee := gasPrice.Int64() * gasLimit
-			fundsToReturn := new(big.Int).Sub(balance, big.NewInt(networkTransferFee))
-
-			if fundsToReturn.Cmp(big.NewInt(0)) == -1 {
+			_, err := c.SweepKey(egCtx, idx, common.HexToAddress(toAddr))
+			if errors.Is(err, ErrNothingToSweep) {
 				L.Warn().
+					Err(err).
 					Str("Key", c.Addresses[idx].Hex()).
-					Interface("Balance", balance).
-					Interface("NetworkFee", networkTransferFee).
-					Interface("FundsToReturn", fundsToReturn).
 					Msg("Insufficient funds to return. Skipping.")
 				return nil
 			}
-
-			L.Info().
-				Str("Key", c.Addresses[idx].Hex()).
-				Interface("Balance", balance).
-				Interface("NetworkFee", c.Cfg.Network.GasPrice*gasLimit).
-				Interface("GasLimit", gasLimit).
-				Interface("GasPrice", gasPrice).
-				Interface("FundsToReturn", fundsToReturn).
-				Msg("KeyFile key balance")
-
-			return c.TransferETHFromKey(
-				egCtx,
-				idx,
-				toAddr,
-				fundsToReturn,
-				gasPrice,
-			)
+			return err
 		})
 	}
 	if err := eg.Wait(); err != nil {
@@ -197,19 +164,7 @@ func ReturnFundsFromKeyFileAndUpdateIt(c *Client, toAddr string, opts *FundKeyFi
 		return err
 	}
 
-	eg, egCtx := errgroup.WithContext(context.Background())
-	for _, kfd := range keyFile.Keys {
-		kfd := kfd
-		eg.Go(func() error {
-			balance, err := newClient.Client.BalanceAt(egCtx, common.HexToAddress(kfd.Address), nil)
-			if err != nil {
-				return err
-			}
-			kfd.Funds = balance.String()
-			return nil
-		})
-	}
-	if err := eg.Wait(); err != nil {
+	if err := newClient.updateKeyFileBalances(context.Background(), keyFile); err != nil {
 		return err
 	}
 	b, err := toml.Marshal(keyFile)
@@ -241,21 +196,7 @@ func UpdateKeyFileBalances(c *Client, opts *FundKeyFileCmdOpts) error {
 		return errors.New("did not find any keys in the keyfile or keyfile did not exist")
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	eg, egCtx := errgroup.WithContext(ctx)
-	for _, kfd := range keyFile.Keys {
-		kfd := kfd
-		eg.Go(func() error {
-			balance, err := c.Client.BalanceAt(egCtx, common.HexToAddress(kfd.Address), nil)
-			if err != nil {
-				return err
-			}
-			kfd.Funds = balance.String()
-			return nil
-		})
-	}
-	if err := eg.Wait(); err != nil {
+	if err := c.updateKeyFileBalances(context.Background(), keyFile); err != nil {
 		return err
 	}
 	b, err := toml.Marshal(keyFile)
@@ -275,3 +216,26 @@ func UpdateKeyFileBalances(c *Client, opts *FundKeyFileCmdOpts) error {
 
 	return nil
 }
+
+// updateKeyFileBalances updates native and token balances of all keys in the keyfile
+func (m *Client) updateKeyFileBalances(ctx context.Context, keyFile *KeyFile) error {
+	eg, egCtx := errgroup.WithContext(ctx)
+	for _, kfd := range keyFile.Keys {
+		kfd := kfd
+		eg.Go(func() error {
+			address := common.HexToAddress(kfd.Address)
+			balance, err := m.Client.BalanceAt(egCtx, address, nil)
+			if err != nil {
+				return err
+			}
+			tokenFunds, err := m.tokenBalances(egCtx, address)
+			if err != nil {
+				return err
+			}
+			kfd.Funds = balance.String()
+			kfd.TokenFunds = tokenFunds
+			return nil
+		})
+	}
+	return eg.Wait()
+}
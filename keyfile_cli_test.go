@@ -2,7 +2,6 @@ package seth_test
 
 import (
 	"context"
-	"fmt"
 	"math/big"
 	"os"
 	"testing"
@@ -79,5 +78,5 @@ func TestCLINoVaultPassed(t *testing.T) {
 	require.NoError(t, err)
 	err = sethcmd.RunCLI([]string{"seth", "-n", os.Getenv(seth.NETWORK_ENV_VAR), "keys", "fund", "-a", "2", "-b", "10"})
 	require.Error(t, err, "No error when splitting keys without vault id")
-	require.Equal(t, fmt.Sprintf(sethcmd.ErrNo1PassVault, seth.ONE_PASS_VAULT_ENV_VAR), err.Error(), "Error message is incorrect")
+	require.ErrorIs(t, err, sethcmd.ErrNo1PassVault, "Error message is incorrect")
 }
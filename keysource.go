@@ -0,0 +1,208 @@
+package seth
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+	"github.com/tyler-smith/go-bip39"
+)
+
+const (
+	KEYSTORE_PASSWORD_ENV_VAR = "SETH_KEYSTORE_PASSWORD"
+	MNEMONIC_ENV_VAR          = "SETH_MNEMONIC"
+
+	// DefaultMnemonicDerivationPath derives only the first Ethereum account of the mnemonic
+	DefaultMnemonicDerivationPath = "m/44'/60'/0'/0/0"
+)
+
+var (
+	ErrReadKeystore          = errors.New("failed to read keystore directory")
+	ErrDecryptKeystoreFile   = errors.New("failed to decrypt keystore file")
+	ErrEmptyKeystore         = errors.New("no keystore files found")
+	ErrNoKeystorePassword    = errors.New("keystore password is not set")
+	ErrEmptyMnemonic         = errors.New("mnemonic is not set")
+	ErrInvalidMnemonic       = errors.New("mnemonic is invalid")
+	ErrInvalidDerivationPath = errors.New("invalid mnemonic derivation path")
+	ErrDeriveKeyFromMnemonic = errors.New("failed to derive key from mnemonic")
+)
+
+const maxMnemonicDerivedKeysNum = 10000
+
+// ReadKeystoreKeys decrypts all geth keystore (JSON V3) files from the directory with the same password and returns their
+// private keys as hex strings. Hidden files and subdirectories are skipped.
+func ReadKeystoreKeys(dir, password string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrReadKeystore), "directory: '%s'", dir)
+	}
+	var keys []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		keyJSON, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(wrapError(err, ErrReadKeystore), "directory: '%s'", dir)
+		}
+		key, err := keystore.DecryptKey(keyJSON, password)
+		if err != nil {
+			return nil, errors.Wrapf(wrapError(err, ErrDecryptKeystoreFile), "file: '%s'", path)
+		}
+		L.Debug().Str("Address", key.Address.Hex()).Str("File", path).Msg("Decrypted keystore file")
+		keys = append(keys, common.Bytes2Hex(crypto.FromECDSA(key.PrivateKey)))
+	}
+	if len(keys) == 0 {
+		return nil, errors.Wrapf(ErrEmptyKeystore, "directory: '%s'", dir)
+	}
+	return keys, nil
+}
+
+// keystorePassword reads keystore password from the password file, if it's set, or from environment variable
+func keystorePassword(cfg *Config) (string, error) {
+	if cfg.KeystorePasswordFile != "" {
+		password, err := os.ReadFile(cfg.KeystorePasswordFile)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read keystore password file")
+		}
+		return strings.TrimRight(string(password), "\r\n"), nil
+	}
+	password, isSet := os.LookupEnv(KEYSTORE_PASSWORD_ENV_VAR)
+	if !isSet {
+		return "", errors.Wrapf(ErrNoKeystorePassword, "set %s=... or 'keystore_password_file'", KEYSTORE_PASSWORD_ENV_VAR)
+	}
+	return password, nil
+}
+
+// DeriveKeysFromMnemonic derives private keys (as hex strings) from BIP-39 mnemonic for derivation path, whose last
+// component can be a range of indexes, e.g. "m/44'/60'/0'/0/0-9" derives first 10 Ethereum accounts of the mnemonic.
+func DeriveKeysFromMnemonic(mnemonic, derivationPath string) ([]string, error) {
+	paths, err := expandDerivationPath(derivationPath)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := bip39.NewSeedWithErrorChecking(strings.Join(strings.Fields(mnemonic), " "), "")
+	if err != nil {
+		return nil, wrapError(err, ErrInvalidMnemonic)
+	}
+
+	keys := make([]string, 0, len(paths))
+	for _, path := range paths {
+		key, err := deriveKey(seed, path)
+		if err != nil {
+			return nil, errors.Wrapf(wrapError(err, ErrDeriveKeyFromMnemonic), "path: '%s'", path.String())
+		}
+		keys = append(keys, common.Bytes2Hex(key))
+	}
+	return keys, nil
+}
+
+// expandDerivationPath parses derivation path, whose last component can be a range "from-to" (inclusive)
+func expandDerivationPath(derivationPath string) ([]accounts.DerivationPath, error) {
+	base, last := "", derivationPath
+	if i := strings.LastIndex(derivationPath, "/"); i >= 0 {
+		base, last = derivationPath[:i+1], derivationPath[i+1:]
+	}
+	from, to, isRange := strings.Cut(last, "-")
+	if !isRange {
+		path, err := accounts.ParseDerivationPath(derivationPath)
+		if err != nil {
+			return nil, errors.Wrapf(wrapError(err, ErrInvalidDerivationPath), "path: '%s'", derivationPath)
+		}
+		return []accounts.DerivationPath{path}, nil
+	}
+
+	first, err := strconv.ParseUint(strings.TrimSpace(from), 10, 31)
+	if err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrInvalidDerivationPath), "path: '%s'", derivationPath)
+	}
+	lastIndex, err := strconv.ParseUint(strings.TrimSpace(to), 10, 31)
+	if err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrInvalidDerivationPath), "path: '%s'", derivationPath)
+	}
+	if lastIndex < first || lastIndex-first >= maxMnemonicDerivedKeysNum {
+		return nil, errors.Wrapf(wrapError(fmt.Errorf("range must be ascending and have at most %d indexes", maxMnemonicDerivedKeysNum), ErrInvalidDerivationPath), "path: '%s'", derivationPath)
+	}
+
+	paths := make([]accounts.DerivationPath, 0, lastIndex-first+1)
+	for i := first; i <= lastIndex; i++ {
+		path, err := accounts.ParseDerivationPath(fmt.Sprintf("%s%d", base, i))
+		if err != nil {
+			return nil, errors.Wrapf(wrapError(err, ErrInvalidDerivationPath), "path: '%s'", derivationPath)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// deriveKey derives BIP-32 private key of the path from the seed
+func deriveKey(seed []byte, path accounts.DerivationPath) ([]byte, error) {
+	key, chainCode := hmacSHA512([]byte("Bitcoin seed"), seed)
+	n := crypto.S256().Params().N
+	if new(big.Int).SetBytes(key).Cmp(n) >= 0 || new(big.Int).SetBytes(key).Sign() == 0 {
+		return nil, errors.New("invalid master key")
+	}
+
+	for _, index := range path {
+		data := make([]byte, 0, 37)
+		if index >= 0x80000000 {
+			data = append(append(data, 0), key...)
+		} else {
+			privateKey, err := crypto.ToECDSA(key)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, crypto.CompressPubkey(&privateKey.PublicKey)...)
+		}
+		data = binary.BigEndian.AppendUint32(data, index)
+
+		tweak, childChainCode := hmacSHA512(chainCode, data)
+		tweakInt := new(big.Int).SetBytes(tweak)
+		childKey := tweakInt.Add(tweakInt, new(big.Int).SetBytes(key))
+		childKey.Mod(childKey, n)
+		// probability of these is lower than 1 in 2^127, BIP-32 says to proceed with the next index, we just fail
+		if new(big.Int).SetBytes(tweak).Cmp(n) >= 0 || childKey.Sign() == 0 {
+			return nil, fmt.Errorf("invalid child key at index %d", index)
+		}
+		key, chainCode = common.LeftPadBytes(childKey.Bytes(), 32), childChainCode
+	}
+	return key, nil
+}
+
+func hmacSHA512(key, data []byte) ([]byte, []byte) {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	return sum[:32], sum[32:]
+}
+
+// readKeystoreOrMnemonicKeys returns private keys from keystore directory or derived from mnemonic, depending on the key file source
+func readKeystoreOrMnemonicKeys(cfg *Config) ([]string, error) {
+	if cfg.KeyFileSource == KeyFileSourceKeystore {
+		password, err := keystorePassword(cfg)
+		if err != nil {
+			return nil, err
+		}
+		L.Debug().Msgf("Reading keys from keystore directory '%s'", cfg.KeyFilePath)
+		return ReadKeystoreKeys(cfg.KeyFilePath, password)
+	}
+
+	mnemonic := os.Getenv(MNEMONIC_ENV_VAR)
+	if strings.TrimSpace(mnemonic) == "" {
+		return nil, errors.Wrapf(ErrEmptyMnemonic, "set %s=...", MNEMONIC_ENV_VAR)
+	}
+	L.Debug().Msgf("Deriving keys from mnemonic for path '%s'", cfg.MnemonicDerivationPath)
+	return DeriveKeysFromMnemonic(mnemonic, cfg.MnemonicDerivationPath)
+}
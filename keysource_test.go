@@ -0,0 +1,99 @@
+package seth_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+const testMnemonic = "test test test test test test test test test test test junk"
+
+func TestUtilDeriveKeysFromMnemonic(t *testing.T) {
+	keys, err := seth.DeriveKeysFromMnemonic(testMnemonic, seth.DefaultMnemonicDerivationPath)
+	require.NoError(t, err, "failed to derive keys")
+	require.Equal(t, []string{"ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"}, keys, "incorrect key")
+
+	keys, err = seth.DeriveKeysFromMnemonic(testMnemonic, "m/44'/60'/0'/0/1-2")
+	require.NoError(t, err, "failed to derive keys")
+	require.Equal(t, []string{
+		"59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d",
+		"5de4111afa1a4b94908f83103eb1f1706367c2e68ca870fc3fb9a804cdab365a",
+	}, keys, "incorrect keys")
+
+	_, err = seth.DeriveKeysFromMnemonic("test test test", seth.DefaultMnemonicDerivationPath)
+	require.ErrorIs(t, err, seth.ErrInvalidMnemonic, "mnemonic should be validated")
+
+	for _, path := range []string{"m/44'/60'/0'/0/x", "m/44'/60'/0'/0/2-1", "m/44'/60'/0'/0/0-x"} {
+		_, err = seth.DeriveKeysFromMnemonic(testMnemonic, path)
+		require.Error(t, err, "path %s should be invalid", path)
+	}
+}
+
+func TestUtilReadKeystoreKeys(t *testing.T) {
+	dir := t.TempDir()
+	accounts := make(map[common.Address]bool)
+	for i := 0; i < 2; i++ {
+		account, err := keystore.StoreKey(dir, "password", keystore.LightScryptN, keystore.LightScryptP)
+		require.NoError(t, err, "failed to create keystore file")
+		accounts[account.Address] = true
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0700), "failed to create subdirectory")
+
+	keys, err := seth.ReadKeystoreKeys(dir, "password")
+	require.NoError(t, err, "failed to read keystore")
+	cfg := &seth.Config{Network: &seth.Network{PrivateKeys: keys}}
+	addresses, _, err := cfg.ParseKeys()
+	require.NoError(t, err, "failed to parse keys")
+	require.Len(t, addresses, 2, "all keystore files should be read")
+	for _, address := range addresses {
+		require.True(t, accounts[address], "unexpected address %s", address.Hex())
+	}
+
+	_, err = seth.ReadKeystoreKeys(dir, "wrong")
+	require.ErrorContains(t, err, "could not decrypt key with given password", "incorrect password should fail")
+	_, err = seth.ReadKeystoreKeys(t.TempDir(), "password")
+	require.Error(t, err, "empty keystore should fail")
+}
+
+func TestConfigMnemonicKeyFileSource(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.EphemeralAddrs = &seth.ZeroInt64
+	cfg.KeyFileSource = seth.KeyFileSourceMnemonic
+	cfg.MnemonicDerivationPath = "m/44'/60'/0'/0/1-3"
+	t.Setenv(seth.MNEMONIC_ENV_VAR, testMnemonic)
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to create client")
+	// root key from SETH_ROOT_PRIVATE_KEY is followed by derived keys
+	require.Len(t, c.Addresses, 4, "3 keys should be derived")
+	require.Equal(t, common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"), c.Addresses[1], "incorrect first derived address")
+
+	cfg.MnemonicDerivationPath = "m/44'/60'/0'/0/x"
+	require.ErrorContains(t, seth.ValidateConfig(cfg), "invalid mnemonic derivation path", "path should be validated")
+}
+
+func TestConfigKeystoreKeyFileSource(t *testing.T) {
+	dir := t.TempDir()
+	account, err := keystore.StoreKey(dir, "password", keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err, "failed to create keystore file")
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("password\n"), 0600), "failed to write password file")
+
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.EphemeralAddrs = &seth.ZeroInt64
+	cfg.KeyFileSource = seth.KeyFileSourceKeystore
+	cfg.KeyFilePath = dir
+	cfg.KeystorePasswordFile = passwordFile
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to create client")
+	require.Len(t, c.Addresses, 2, "keystore key should be read")
+	require.Equal(t, account.Address, c.Addresses[1], "incorrect keystore address")
+}
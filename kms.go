@@ -0,0 +1,439 @@
+package seth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+const (
+	KMSProviderAWS = "aws"
+	KMSProviderGCP = "gcp"
+
+	DefaultGCPKMSEndpoint = "https://cloudkms.googleapis.com/v1/"
+	// GCP_ACCESS_TOKEN_ENV_VAR is OAuth access token used with GCP KMS, e.g. from 'gcloud auth print-access-token'.
+	// If it's not set, token of the service account is read from GCE metadata server.
+	GCP_ACCESS_TOKEN_ENV_VAR = "GOOGLE_OAUTH_ACCESS_TOKEN"
+	gcpMetadataTokenURL      = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	// DefaultKMSTimeout is timeout of a single KMS request
+	DefaultKMSTimeout = 30 * time.Second
+)
+
+var (
+	ErrKMSProvider         = errors.New("unknown KMS provider")
+	ErrKMSKeyID            = errors.New("KMS key has no 'key_id'")
+	ErrKMSGCPKeyID         = errors.New("GCP KMS key must be a crypto key version resource name")
+	ErrKMSEndpoint         = errors.New("KMS key has invalid 'endpoint', it must be a valid http(s) URL")
+	ErrKMSPublicKey        = errors.New("failed to read public key of KMS key")
+	ErrKMSKeyNotSecp256k1  = errors.New("KMS key isn't a secp256k1 key")
+	ErrKMSSign             = errors.New("KMS key failed to sign transaction")
+	ErrKMSInvalidSignature = errors.New("KMS key returned invalid signature")
+)
+
+var (
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidSecp256k1      = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// KMSKeyCfg configures secp256k1 signing key stored in AWS KMS or GCP Cloud KMS. Address of the key is derived from its
+// public key, private key never leaves the KMS.
+type KMSKeyCfg struct {
+	// Provider is either "aws" or "gcp"
+	Provider string `toml:"provider"`
+	// KeyID is AWS key ID, ARN or alias, or GCP crypto key version resource name
+	KeyID string `toml:"key_id"`
+	// Region is AWS region, if not set it's read from AWS environment or shared config
+	Region string `toml:"region"`
+	// Endpoint overrides KMS API endpoint, e.g. for a VPC endpoint or a local KMS emulator
+	Endpoint string `toml:"endpoint"`
+}
+
+func validateKMSKeys(n *Network) error {
+	for _, key := range n.KMSKeys {
+		if key.KeyID == "" {
+			return errors.Wrapf(ErrKMSKeyID, "network: '%s'", n.Name)
+		}
+		switch key.Provider {
+		case KMSProviderAWS:
+		case KMSProviderGCP:
+			if !strings.HasPrefix(key.KeyID, "projects/") || !strings.Contains(key.KeyID, "/cryptoKeyVersions/") {
+				return errors.Wrapf(ErrKMSGCPKeyID, "key: '%s', network: '%s', expected: projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*", key.KeyID, n.Name)
+			}
+		default:
+			return errors.Wrapf(ErrKMSProvider, "key: '%s', network: '%s', provider: '%s', use '%s' or '%s'", key.KeyID, n.Name, key.Provider, KMSProviderAWS, KMSProviderGCP)
+		}
+		if key.Endpoint != "" {
+			u, err := url.Parse(key.Endpoint)
+			if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+				return errors.Wrapf(ErrKMSEndpoint, "key: '%s', network: '%s'", key.KeyID, n.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// KMSClient is a single asymmetric secp256k1 key in a KMS
+type KMSClient interface {
+	// KeyID returns identifier of the key used in logs and errors
+	KeyID() string
+	// PublicKey returns DER encoded SubjectPublicKeyInfo of the key
+	PublicKey(ctx context.Context) ([]byte, error)
+	// SignDigest signs the 32 bytes digest and returns DER encoded ECDSA signature
+	SignDigest(ctx context.Context, digest []byte) ([]byte, error)
+}
+
+// NewKMSClient creates client of the KMS key for its provider
+func NewKMSClient(ctx context.Context, cfg *KMSKeyCfg) (KMSClient, error) {
+	switch cfg.Provider {
+	case KMSProviderAWS:
+		return NewAWSKMSClient(ctx, cfg)
+	case KMSProviderGCP:
+		return NewGCPKMSClient(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unknown KMS provider '%s'", cfg.Provider)
+	}
+}
+
+// AWSKMSClient signs with AWS KMS key of ECC_SECG_P256K1 spec. Credentials are loaded with AWS default credentials
+// chain (environment, shared config, IAM role, etc.).
+type AWSKMSClient struct {
+	client *kms.Client
+	keyID  string
+}
+
+// NewAWSKMSClient creates AWS KMS client of the key
+func NewAWSKMSClient(ctx context.Context, cfg *KMSKeyCfg) (*AWSKMSClient, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if cfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load AWS config")
+	}
+	client := kms.NewFromConfig(awsCfg, func(o *kms.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	})
+	return &AWSKMSClient{client: client, keyID: cfg.KeyID}, nil
+}
+
+func (c *AWSKMSClient) KeyID() string {
+	return c.keyID
+}
+
+func (c *AWSKMSClient) PublicKey(ctx context.Context) ([]byte, error) {
+	out, err := c.client.GetPublicKey(ctx, &kms.GetPublicKeyInput{KeyId: aws.String(c.keyID)})
+	if err != nil {
+		return nil, err
+	}
+	return out.PublicKey, nil
+}
+
+func (c *AWSKMSClient) SignDigest(ctx context.Context, digest []byte) ([]byte, error) {
+	out, err := c.client.Sign(ctx, &kms.SignInput{
+		KeyId:            aws.String(c.keyID),
+		Message:          digest,
+		MessageType:      kmstypes.MessageTypeDigest,
+		SigningAlgorithm: kmstypes.SigningAlgorithmSpecEcdsaSha256,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Signature, nil
+}
+
+// GCPKMSClient signs with GCP Cloud KMS key version of EC_SIGN_SECP256K1_SHA256 algorithm using KMS REST API.
+// Requests are authorized with access token from GOOGLE_OAUTH_ACCESS_TOKEN environment variable or with token of
+// the service account of the GCE instance (or GKE workload), when it's not set.
+type GCPKMSClient struct {
+	client   *http.Client
+	endpoint string
+	keyName  string
+
+	// withAccessToken is set, when requests are authorized with access token instead of authorized HTTP client
+	withAccessToken bool
+	mu              sync.Mutex
+	token           string
+	tokenExpiry     time.Time
+}
+
+// NewGCPKMSClient creates GCP Cloud KMS client of the key version
+func NewGCPKMSClient(_ context.Context, cfg *KMSKeyCfg) (*GCPKMSClient, error) {
+	c := NewGCPKMSClientWithHTTPClient(cfg, http.DefaultClient)
+	c.withAccessToken = true
+	if token := os.Getenv(GCP_ACCESS_TOKEN_ENV_VAR); token != "" {
+		c.token = token
+		c.tokenExpiry = time.Now().AddDate(100, 0, 0)
+	}
+	return c, nil
+}
+
+// NewGCPKMSClientWithHTTPClient creates GCP Cloud KMS client, which sends requests with the HTTP client, that's expected
+// to authorize them (e.g. created by golang.org/x/oauth2/google)
+func NewGCPKMSClientWithHTTPClient(cfg *KMSKeyCfg, client *http.Client) *GCPKMSClient {
+	endpoint := DefaultGCPKMSEndpoint
+	if cfg.Endpoint != "" {
+		endpoint = strings.TrimSuffix(cfg.Endpoint, "/") + "/"
+	}
+	return &GCPKMSClient{client: client, endpoint: endpoint, keyName: cfg.KeyID}
+}
+
+func (c *GCPKMSClient) KeyID() string {
+	return c.keyName
+}
+
+func (c *GCPKMSClient) PublicKey(ctx context.Context) ([]byte, error) {
+	var out struct {
+		Pem string `json:"pem"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.keyName+"/publicKey", nil, &out); err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode([]byte(out.Pem))
+	if block == nil {
+		return nil, errors.New("public key isn't PEM encoded")
+	}
+	return block.Bytes, nil
+}
+
+func (c *GCPKMSClient) SignDigest(ctx context.Context, digest []byte) ([]byte, error) {
+	in := map[string]interface{}{"digest": map[string][]byte{"sha256": digest}}
+	var out struct {
+		Signature []byte `json:"signature"`
+	}
+	if err := c.do(ctx, http.MethodPost, c.keyName+":asymmetricSign", in, &out); err != nil {
+		return nil, err
+	}
+	return out.Signature, nil
+}
+
+func (c *GCPKMSClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.withAccessToken {
+		token, err := c.accessToken(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GCP KMS returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, out)
+}
+
+// accessToken returns token set in environment or reads it from GCE metadata server, until it expires
+func (c *GCPKMSClient) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.tokenExpiry) {
+		return c.token, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read access token from GCE metadata server, set %s=...", GCP_ACCESS_TOKEN_ENV_VAR)
+	}
+	defer resp.Body.Close()
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GCE metadata server returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	c.token = token.AccessToken
+	// refresh token a minute before it expires
+	c.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+// KMSSigner signs transactions with keys stored in KMS. Signatures returned by KMS are normalized to Ethereum ones:
+// S is made canonical (lower half of the curve order, as required by EIP-2) and recovery ID is found by recovering
+// the public key; chain ID is added to V by the transaction signer (EIP-155).
+type KMSSigner struct {
+	keys   map[common.Address]kmsKey
+	signer types.Signer
+}
+
+type kmsKey struct {
+	client    KMSClient
+	publicKey []byte
+}
+
+// NewKMSSigner reads public keys of KMS keys and derives their addresses
+func NewKMSSigner(ctx context.Context, chainID *big.Int, clients ...KMSClient) (*KMSSigner, error) {
+	s := &KMSSigner{
+		keys:   make(map[common.Address]kmsKey),
+		signer: types.LatestSignerForChainID(chainID),
+	}
+	for _, client := range clients {
+		address, key, err := newKMSKey(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		s.keys[address] = key
+	}
+	return s, nil
+}
+
+// newKMSKey reads public key of KMS key and derives its address
+func newKMSKey(ctx context.Context, client KMSClient) (common.Address, kmsKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultKMSTimeout)
+	defer cancel()
+	der, err := client.PublicKey(ctx)
+	if err != nil {
+		return common.Address{}, kmsKey{}, errors.Wrapf(wrapError(err, ErrKMSPublicKey), "key: '%s'", client.KeyID())
+	}
+	publicKey, err := ParseKMSPublicKey(der)
+	if err != nil {
+		return common.Address{}, kmsKey{}, errors.Wrapf(wrapError(err, ErrKMSKeyNotSecp256k1), "key: '%s'", client.KeyID())
+	}
+	address := crypto.PubkeyToAddress(*publicKey)
+	L.Debug().Str("Key", client.KeyID()).Str("Address", address.Hex()).Msg("Read address of KMS key")
+	return address, kmsKey{client: client, publicKey: crypto.FromECDSAPub(publicKey)}, nil
+}
+
+// ParseKMSPublicKey parses DER encoded SubjectPublicKeyInfo of secp256k1 key, which isn't supported by x509 package
+func ParseKMSPublicKey(der []byte) (*ecdsa.PublicKey, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, errors.New("trailing data after public key")
+	}
+	var curve asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(spki.Algorithm.Parameters.FullBytes, &curve); err != nil {
+		return nil, errors.Wrap(err, "failed to parse curve of public key")
+	}
+	if !spki.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) || !curve.Equal(oidSecp256k1) {
+		return nil, fmt.Errorf("unsupported public key algorithm %s and curve %s", spki.Algorithm.Algorithm, curve)
+	}
+	return crypto.UnmarshalPubkey(spki.PublicKey.Bytes)
+}
+
+// Addresses returns addresses of KMS keys
+func (s *KMSSigner) Addresses() []common.Address {
+	addresses := make([]common.Address, 0, len(s.keys))
+	for address := range s.keys {
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// HasKey returns true if signer has KMS key of the address
+func (s *KMSSigner) HasKey(address common.Address) bool {
+	_, ok := s.keys[address]
+	return ok
+}
+
+// SignTx signs transaction hash with KMS key of the address
+func (s *KMSSigner) SignTx(ctx context.Context, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	key, ok := s.keys[address]
+	if !ok {
+		return nil, errors.Wrapf(ErrNoSignerForAddress, "address: %s", address.Hex())
+	}
+	hash := s.signer.Hash(tx)
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultKMSTimeout)
+	defer cancel()
+	der, err := key.client.SignDigest(ctx, hash.Bytes())
+	if err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrKMSSign), "key: '%s', address: %s", key.client.KeyID(), address.Hex())
+	}
+	signature, err := EthereumSignature(hash.Bytes(), der, key.publicKey)
+	if err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrKMSInvalidSignature), "key: '%s'", key.client.KeyID())
+	}
+	return tx.WithSignature(s.signer, signature)
+}
+
+// EthereumSignature converts DER encoded ECDSA signature of the digest to 65 bytes [R || S || V] signature with canonical
+// S and V being recovery ID (0 or 1), which can be used with types.Transaction.WithSignature
+func EthereumSignature(digest, der, publicKey []byte) ([]byte, error) {
+	var sig struct {
+		R, S *big.Int
+	}
+	if rest, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, errors.New("trailing data after signature")
+	}
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 || sig.R.Cmp(secp256k1N) >= 0 || sig.S.Cmp(secp256k1N) >= 0 {
+		return nil, errors.New("signature values are out of range")
+	}
+	// (r, s) and (r, n - s) are both valid, but only the lower S is accepted by Ethereum
+	if sig.S.Cmp(secp256k1HalfN) > 0 {
+		sig.S = new(big.Int).Sub(secp256k1N, sig.S)
+	}
+
+	signature := make([]byte, crypto.SignatureLength)
+	sig.R.FillBytes(signature[:32])
+	sig.S.FillBytes(signature[32:64])
+	for v := byte(0); v < 2; v++ {
+		signature[64] = v
+		recovered, err := crypto.Ecrecover(digest, signature)
+		if err == nil && bytes.Equal(recovered, publicKey) {
+			return signature, nil
+		}
+	}
+	return nil, errors.New("signature doesn't match public key")
+}
@@ -0,0 +1,197 @@
+package seth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+// kmsPublicKeyDER encodes public key as SubjectPublicKeyInfo, like AWS and GCP KMS do
+func kmsPublicKeyDER(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	curve, err := asn1.Marshal(asn1.ObjectIdentifier{1, 3, 132, 0, 10})
+	require.NoError(t, err, "failed to marshal curve")
+	publicKey := crypto.FromECDSAPub(&key.PublicKey)
+	der, err := asn1.Marshal(struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}, Parameters: asn1.RawValue{FullBytes: curve}},
+		PublicKey: asn1.BitString{Bytes: publicKey, BitLength: len(publicKey) * 8},
+	})
+	require.NoError(t, err, "failed to marshal public key")
+	return der
+}
+
+// kmsSignatureDER signs the digest and returns DER signature with S from the upper half of the curve order, which KMS
+// returns for about half of the signatures and which has to be normalized
+func kmsSignatureDER(t *testing.T, key *ecdsa.PrivateKey, digest []byte) []byte {
+	signature, err := crypto.Sign(digest, key)
+	require.NoError(t, err, "failed to sign digest")
+	n := crypto.S256().Params().N
+	der, err := asn1.Marshal(struct{ R, S *big.Int }{
+		R: new(big.Int).SetBytes(signature[:32]),
+		S: new(big.Int).Sub(n, new(big.Int).SetBytes(signature[32:64])),
+	})
+	require.NoError(t, err, "failed to marshal signature")
+	return der
+}
+
+// newKMSServer simulates AWS KMS JSON API or GCP Cloud KMS REST API with a single key
+func newKMSServer(t *testing.T, key *ecdsa.PrivateKey, gcp bool, signRequests *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		if gcp {
+			require.Equal(t, "Bearer test-token", r.Header.Get("Authorization"), "request should be authorized")
+			switch {
+			case strings.HasSuffix(r.URL.Path, "/publicKey"):
+				response = map[string]string{"pem": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: kmsPublicKeyDER(t, key)}))}
+			case strings.HasSuffix(r.URL.Path, ":asymmetricSign"):
+				atomic.AddInt32(signRequests, 1)
+				var req struct {
+					Digest struct {
+						Sha256 []byte `json:"sha256"`
+					} `json:"digest"`
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req), "failed to decode request")
+				response = map[string][]byte{"signature": kmsSignatureDER(t, key, req.Digest.Sha256)}
+			default:
+				t.Errorf("unexpected request %s", r.URL.Path)
+			}
+		} else {
+			var req struct {
+				KeyId            string
+				Message          []byte
+				MessageType      string
+				SigningAlgorithm string
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req), "failed to decode request")
+			switch r.Header.Get("X-Amz-Target") {
+			case "TrentService.GetPublicKey":
+				response = map[string]interface{}{"KeyId": req.KeyId, "KeySpec": "ECC_SECG_P256K1", "PublicKey": kmsPublicKeyDER(t, key)}
+			case "TrentService.Sign":
+				atomic.AddInt32(signRequests, 1)
+				require.Equal(t, "DIGEST", req.MessageType, "incorrect message type")
+				require.Equal(t, "ECDSA_SHA_256", req.SigningAlgorithm, "incorrect signing algorithm")
+				response = map[string]interface{}{"KeyId": req.KeyId, "Signature": kmsSignatureDER(t, key, req.Message)}
+			default:
+				t.Errorf("unexpected request %s", r.Header.Get("X-Amz-Target"))
+			}
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(response), "failed to encode response")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestUtilKMSSignatureNormalization(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err, "failed to generate key")
+	digest := crypto.Keccak256([]byte("seth"))
+
+	signature, err := seth.EthereumSignature(digest, kmsSignatureDER(t, key, digest), crypto.FromECDSAPub(&key.PublicKey))
+	require.NoError(t, err, "failed to convert signature")
+	require.True(t, crypto.ValidateSignatureValues(signature[64], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:64]), true), "signature should be canonical")
+	recovered, err := crypto.SigToPub(digest, signature)
+	require.NoError(t, err, "failed to recover public key")
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), crypto.PubkeyToAddress(*recovered), "incorrect recovered address")
+
+	otherKey, err := crypto.GenerateKey()
+	require.NoError(t, err, "failed to generate key")
+	_, err = seth.EthereumSignature(digest, kmsSignatureDER(t, otherKey, digest), crypto.FromECDSAPub(&key.PublicKey))
+	require.Error(t, err, "signature of other key should be rejected")
+}
+
+func TestUtilKMSSignerGCP(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err, "failed to generate key")
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	var signRequests int32
+	server := newKMSServer(t, key, true, &signRequests)
+	t.Setenv(seth.GCP_ACCESS_TOKEN_ENV_VAR, "test-token")
+
+	client, err := seth.NewGCPKMSClient(context.Background(), &seth.KMSKeyCfg{
+		Provider: seth.KMSProviderGCP,
+		KeyID:    "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1",
+		Endpoint: server.URL,
+	})
+	require.NoError(t, err, "failed to create GCP KMS client")
+	chainID := big.NewInt(1337)
+	signer, err := seth.NewKMSSigner(context.Background(), chainID, client)
+	require.NoError(t, err, "failed to create KMS signer")
+	require.Equal(t, []common.Address{address}, signer.Addresses(), "address should be derived from public key")
+
+	to := common.HexToAddress("0x01")
+	for _, tx := range []*types.Transaction{
+		types.NewTx(&types.LegacyTx{To: &to, Gas: 21000, GasPrice: big.NewInt(1), Value: big.NewInt(1)}),
+		types.NewTx(&types.DynamicFeeTx{ChainID: chainID, To: &to, Gas: 21000, GasFeeCap: big.NewInt(2), GasTipCap: big.NewInt(1), Value: big.NewInt(1)}),
+	} {
+		signed, err := signer.SignTx(context.Background(), address, tx)
+		require.NoError(t, err, "failed to sign transaction")
+		sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+		require.NoError(t, err, "failed to recover sender")
+		require.Equal(t, address, sender, "incorrect sender")
+		require.Equal(t, chainID, signed.ChainId(), "signature should be EIP-155 protected")
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&signRequests), "all transactions should be signed by KMS")
+}
+
+func TestAPIKMSSignerAWS(t *testing.T) {
+	rootKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	require.NoError(t, err, "failed to parse key")
+	rootAddress := crypto.PubkeyToAddress(rootKey.PublicKey)
+	var signRequests int32
+	server := newKMSServer(t, rootKey, false, &signRequests)
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.Network.PrivateKeys = nil
+	cfg.Network.KMSKeys = []*seth.KMSKeyCfg{{Provider: seth.KMSProviderAWS, KeyID: "alias/seth", Region: "us-east-1", Endpoint: server.URL}}
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to create client")
+	require.Equal(t, []common.Address{rootAddress}, c.Addresses, "KMS key's address should be the root key")
+	_, err = c.GetRootPrivateKey()
+	require.ErrorIs(t, err, seth.ErrNoPrivateKey, "private key should not be available")
+
+	err = c.TransferETHFromKey(context.Background(), 0, common.HexToAddress("0x01").Hex(), big.NewInt(1), nil)
+	require.NoError(t, err, "failed to transfer funds")
+
+	_, err = c.DeployContractFromContractStore(c.NewTXOpts(), "NetworkDebugSubContract.abi")
+	require.NoError(t, err, "failed to deploy contract")
+	require.Equal(t, int32(2), atomic.LoadInt32(&signRequests), "all transactions should be signed by KMS")
+}
+
+func TestConfigKMSKeysValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	cfg.Network.KMSKeys = []*seth.KMSKeyCfg{{Provider: "azure", KeyID: "key"}}
+	require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrKMSProvider, "provider should be validated")
+
+	cfg.Network.KMSKeys = []*seth.KMSKeyCfg{{Provider: seth.KMSProviderGCP, KeyID: "key"}}
+	require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrKMSGCPKeyID, "GCP key name should be validated")
+
+	cfg.Network.KMSKeys = []*seth.KMSKeyCfg{{Provider: seth.KMSProviderAWS}}
+	require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrKMSKeyID, "key ID should be required")
+
+	cfg.Network.KMSKeys = []*seth.KMSKeyCfg{{Provider: seth.KMSProviderAWS, KeyID: "alias/seth", Endpoint: "localhost:4566"}}
+	require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrKMSEndpoint, "endpoint should be validated")
+}
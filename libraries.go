@@ -0,0 +1,273 @@
+package seth
+
+import (
+	"bufio"
+	"context"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+const (
+	// libraryPlaceholderLength is the length of library placeholder in hex bytecode, both "__$<hash>$__" (solc >= 0.5)
+	// and "__<name>___" (older solc) ones
+	libraryPlaceholderLength = 40
+)
+
+var (
+	ErrUnlinkedLibraries      = errors.New("bytecode has unlinked libraries")
+	ErrMissingLibraries       = errors.New("libraries of contract aren't in the contract store")
+	ErrInvalidLibraryBytecode = errors.New("invalid library placeholder in bytecode")
+)
+
+// linkReferenceRegexp matches comments with fully qualified names of libraries, which solc appends to unlinked bytecode
+var linkReferenceRegexp = regexp.MustCompile(`^//\s*(__\$[0-9a-fA-F]{34}\$__|\$[0-9a-fA-F]{34}\$)\s*->\s*(\S+)`)
+
+// UnlinkedBytecode is hex bytecode with library placeholders, that have to be replaced with library addresses before it
+// can be deployed. LinkReferences map placeholders to fully qualified names of libraries (e.g. "contracts/Math.sol:Math").
+type UnlinkedBytecode struct {
+	Hex            string
+	LinkReferences map[string]string
+}
+
+// ParseUnlinkedBytecode parses hex bytecode, optionally followed by solc's "// $<hash>$ -> <library>" comments
+func ParseUnlinkedBytecode(s string) (*UnlinkedBytecode, error) {
+	u := &UnlinkedBytecode{LinkReferences: make(map[string]string)}
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(s)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "//"):
+			if match := linkReferenceRegexp.FindStringSubmatch(line); match != nil {
+				u.LinkReferences["__$"+strings.Trim(match[1], "_$")+"$__"] = match[2]
+			}
+		case u.Hex == "":
+			u.Hex = strings.TrimPrefix(line, "0x")
+		}
+	}
+	if _, err := u.placeholders(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// IsUnlinkedBytecode returns true if hex bytecode contains library placeholders
+func IsUnlinkedBytecode(s string) bool {
+	return strings.Contains(s, "__")
+}
+
+// Placeholders returns library placeholders found in the bytecode in order of their first occurrence
+func (u *UnlinkedBytecode) Placeholders() []string {
+	placeholders, _ := u.placeholders()
+	return placeholders
+}
+
+func (u *UnlinkedBytecode) placeholders() ([]string, error) {
+	var placeholders []string
+	seen := make(map[string]struct{})
+	for pos := 0; ; {
+		i := strings.Index(u.Hex[pos:], "__")
+		if i == -1 {
+			return placeholders, nil
+		}
+		start := pos + i
+		if start+libraryPlaceholderLength > len(u.Hex) {
+			return nil, errors.Wrapf(ErrInvalidLibraryBytecode, "position: %d", start)
+		}
+		placeholder := u.Hex[start : start+libraryPlaceholderLength]
+		if _, ok := seen[placeholder]; !ok {
+			seen[placeholder] = struct{}{}
+			placeholders = append(placeholders, placeholder)
+		}
+		pos = start + libraryPlaceholderLength
+	}
+}
+
+// LibraryName returns fully qualified name of the library replaced by the placeholder or an empty string, if it's unknown
+// (solc >= 0.5 placeholders contain only hash of the name, which is known only if bytecode was followed by link references)
+func (u *UnlinkedBytecode) LibraryName(placeholder string) string {
+	if name, ok := u.LinkReferences[placeholder]; ok {
+		return name
+	}
+	if !strings.HasPrefix(placeholder, "__$") {
+		return strings.Trim(placeholder, "_")
+	}
+	return ""
+}
+
+// Link replaces placeholders with library addresses and returns deployable bytecode. Libraries are keyed by fully qualified
+// name (e.g. "contracts/Math.sol:Math") or, if the name can be read from placeholders or link references, by library name.
+// Error lists all libraries, that couldn't be linked.
+func (u *UnlinkedBytecode) Link(libraries map[string]common.Address) ([]byte, error) {
+	linked := u.Hex
+	var missing []string
+	for _, placeholder := range u.Placeholders() {
+		address, ok := u.libraryAddress(placeholder, libraries)
+		if !ok {
+			missing = append(missing, u.describePlaceholder(placeholder))
+			continue
+		}
+		linked = strings.ReplaceAll(linked, placeholder, strings.ToLower(address.Hex()[2:]))
+	}
+	if len(missing) > 0 {
+		return nil, errors.Wrapf(ErrUnlinkedLibraries, "libraries: %s", strings.Join(missing, ", "))
+	}
+	return common.FromHex(linked), nil
+}
+
+func (u *UnlinkedBytecode) libraryAddress(placeholder string, libraries map[string]common.Address) (common.Address, bool) {
+	name := u.LibraryName(placeholder)
+	for key, address := range libraries {
+		if key == name || (name != "" && key == shortLibraryName(name)) || libraryPlaceholder(key, placeholder) == placeholder {
+			return address, true
+		}
+	}
+	return common.Address{}, false
+}
+
+func (u *UnlinkedBytecode) describePlaceholder(placeholder string) string {
+	if name := u.LibraryName(placeholder); name != "" {
+		return name
+	}
+	return placeholder
+}
+
+// LinkBytecode replaces library placeholders in hex bytecode with library addresses, see UnlinkedBytecode.Link()
+func LinkBytecode(bytecode string, libraries map[string]common.Address) ([]byte, error) {
+	u, err := ParseUnlinkedBytecode(bytecode)
+	if err != nil {
+		return nil, err
+	}
+	return u.Link(libraries)
+}
+
+// libraryPlaceholder returns placeholder of the library in the same format as the existing one
+func libraryPlaceholder(fullyQualifiedName, existing string) string {
+	if strings.HasPrefix(existing, "__$") {
+		return "__$" + common.Bytes2Hex(crypto.Keccak256([]byte(fullyQualifiedName)))[:34] + "$__"
+	}
+	name := fullyQualifiedName
+	if len(name) > libraryPlaceholderLength-4 {
+		name = name[:libraryPlaceholderLength-4]
+	}
+	return "__" + name + strings.Repeat("_", libraryPlaceholderLength-2-len(name))
+}
+
+// shortLibraryName returns library name without source file
+func shortLibraryName(fullyQualifiedName string) string {
+	if i := strings.LastIndex(fullyQualifiedName, ":"); i != -1 {
+		return fullyQualifiedName[i+1:]
+	}
+	return fullyQualifiedName
+}
+
+// librariesKey is the context key of libraries set with transaction options
+type librariesKey struct{}
+
+// WithLibraries sets addresses of libraries linked into bytecode deployed with DeployContractFromContractStore(). Libraries
+// are keyed by fully qualified name (e.g. "contracts/Math.sol:Math") or library name, libraries not set here are deployed
+// from the contract store first.
+func WithLibraries(libraries map[string]common.Address) TransactOpt {
+	return func(o *bind.TransactOpts) {
+		o.Context = context.WithValue(contextOrBackground(o.Context), librariesKey{}, libraries)
+	}
+}
+
+func librariesFromOpts(auth *bind.TransactOpts) map[string]common.Address {
+	libraries, _ := contextOrBackground(auth.Context).Value(librariesKey{}).(map[string]common.Address)
+	return libraries
+}
+
+// deployLinkedContract links libraries into the bytecode and deploys it. Libraries, whose addresses weren't set with
+// WithLibraries(), are deployed from the contract store (and linked themselves) first, each with the next nonce of the key.
+func (m *Client) deployLinkedContract(ctx context.Context, auth *bind.TransactOpts, name string, unlinked *UnlinkedBytecode, params ...interface{}) (DeploymentData, error) {
+	contractABI, ok := m.ContractStore.GetABI(name)
+	if !ok {
+		return DeploymentData{}, errors.New("ABI not found")
+	}
+	if err := m.checkTransactOpts(auth); err != nil {
+		return DeploymentData{}, errors.Wrapf(err, "aborted contract deployment for %s", name)
+	}
+
+	libraries := make(map[string]common.Address)
+	for k, v := range librariesFromOpts(auth) {
+		libraries[k] = v
+	}
+
+	var missing []string
+	for _, placeholder := range unlinked.Placeholders() {
+		if _, ok := unlinked.libraryAddress(placeholder, libraries); ok {
+			continue
+		}
+		libraryName := unlinked.LibraryName(placeholder)
+		if libraryName == "" {
+			missing = append(missing, placeholder)
+			continue
+		}
+		if !m.hasContractBytecode(shortLibraryName(libraryName)) {
+			missing = append(missing, libraryName)
+			continue
+		}
+
+		m.logger().Info().
+			Str("Library", libraryName).
+			Msgf("Deploying library linked into %s contract", name)
+		library, err := m.DeployContractFromContractStoreCtx(ctx, auth, shortLibraryName(libraryName))
+		if err != nil {
+			return DeploymentData{}, errors.Wrapf(err, "failed to deploy library %s", libraryName)
+		}
+		libraries[libraryName] = library.Address
+		if library.Transaction != nil {
+			auth = m.withNextNonce(auth, library.Transaction.Nonce())
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return DeploymentData{}, errors.Wrapf(ErrMissingLibraries, "contract: %s, libraries: %s, pass their addresses with WithLibraries()", name, strings.Join(missing, ", "))
+	}
+
+	if err := m.ContractStore.VerifyUnlinkedBIN(name, unlinked); err != nil {
+		return DeploymentData{}, err
+	}
+	bytecode, err := unlinked.Link(libraries)
+	if err != nil {
+		return DeploymentData{}, err
+	}
+
+	m.logger().Info().
+		Msgf("Started deploying %s contract", name)
+
+	return m.deployVerifiedContract(ctx, auth, name, *contractABI, bytecode, params...)
+}
+
+func (m *Client) hasContractBytecode(name string) bool {
+	if _, ok := m.ContractStore.GetBIN(name); ok {
+		return true
+	}
+	_, ok := m.ContractStore.GetUnlinkedBIN(name)
+	return ok
+}
+
+// withNextNonce returns copy of transaction options with nonce following the last used one, if nonce was set explicitly
+// (otherwise pending nonce is used anyway)
+func (m *Client) withNextNonce(auth *bind.TransactOpts, lastNonce uint64) *bind.TransactOpts {
+	next := *auth
+	if auth.Nonce == nil || hasNoncePlaceholder(auth) {
+		// nonce will be allocated, when transaction is signed
+		return &next
+	}
+	if m.localNonceAllocationEnabled() {
+		next.Nonce = new(big.Int).SetUint64(m.NonceManager.AllocateNonce(auth.From))
+	} else {
+		next.Nonce = new(big.Int).SetUint64(lastNonce + 1)
+	}
+	return &next
+}
@@ -0,0 +1,107 @@
+package seth_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	libraryFQN = "contracts/Lib.sol:Lib"
+	// libraryBytecode deploys contract with a single STOP instruction
+	libraryBytecode = "600180600b6000396000f300"
+	// consumerBytecodeTemplate deploys contract with runtime code PUSH20 <library address> POP STOP
+	consumerBytecodeTemplate = "601780600b6000396000f373%s5000"
+)
+
+func libraryHashPlaceholder(fqn string) string {
+	return "__$" + common.Bytes2Hex(crypto.Keccak256([]byte(fqn)))[:34] + "$__"
+}
+
+func newLinkingContractStore(t *testing.T) *seth.ContractStore {
+	abiDir, binDir := t.TempDir(), t.TempDir()
+	for _, name := range []string{"Lib", "Consumer"} {
+		require.NoError(t, os.WriteFile(filepath.Join(abiDir, name+".abi"), []byte("[]"), 0600), "failed to write ABI")
+	}
+	placeholder := libraryHashPlaceholder(libraryFQN)
+	consumer := fmt.Sprintf(consumerBytecodeTemplate, placeholder) + "\n\n// " + strings.Trim(placeholder, "_") + " -> " + libraryFQN + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "Consumer.bin"), []byte(consumer), 0600), "failed to write BIN")
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "Lib.bin"), []byte(libraryBytecode), 0600), "failed to write BIN")
+
+	cs, err := seth.NewContractStore(abiDir, binDir)
+	require.NoError(t, err, "failed to create contract store")
+	return cs
+}
+
+func TestContractStoreUnlinkedBIN(t *testing.T) {
+	cs := newLinkingContractStore(t)
+
+	_, ok := cs.GetBIN("Consumer")
+	require.False(t, ok, "unlinked bytecode should not be returned as deployable one")
+	unlinked, ok := cs.GetUnlinkedBIN("Consumer")
+	require.True(t, ok, "unlinked bytecode should be loaded")
+	require.Equal(t, []string{libraryHashPlaceholder(libraryFQN)}, unlinked.Placeholders(), "incorrect placeholders")
+	require.Equal(t, libraryFQN, unlinked.LibraryName(unlinked.Placeholders()[0]), "library name should be read from link references")
+}
+
+func TestUtilLinkBytecode(t *testing.T) {
+	library := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	expected := common.FromHex(fmt.Sprintf(consumerBytecodeTemplate, strings.ToLower(library.Hex()[2:])))
+
+	// solc >= 0.5 placeholders can be linked by fully qualified name
+	linked, err := seth.LinkBytecode(fmt.Sprintf(consumerBytecodeTemplate, libraryHashPlaceholder(libraryFQN)), map[string]common.Address{libraryFQN: library})
+	require.NoError(t, err, "failed to link bytecode")
+	require.Equal(t, expected, linked, "incorrect linked bytecode")
+
+	// older ones also by library name
+	legacy := "__Lib" + strings.Repeat("_", 35)
+	linked, err = seth.LinkBytecode(fmt.Sprintf(consumerBytecodeTemplate, legacy), map[string]common.Address{"Lib": library})
+	require.NoError(t, err, "failed to link bytecode")
+	require.Equal(t, expected, linked, "incorrect linked bytecode")
+
+	_, err = seth.LinkBytecode(fmt.Sprintf(consumerBytecodeTemplate, legacy), map[string]common.Address{"Other": library})
+	require.ErrorIs(t, err, seth.ErrUnlinkedLibraries, "unlinked library should be reported")
+
+	_, err = seth.LinkBytecode("6001__$abc", nil)
+	require.Error(t, err, "truncated placeholder should be rejected")
+}
+
+func TestAPIDeployContractWithLibraries(t *testing.T) {
+	c := newClient(t)
+	c.ContractStore = newLinkingContractStore(t)
+
+	// library is deployed from the contract store first
+	consumer, err := c.DeployContractFromContractStore(c.NewTXOpts(), "Consumer")
+	require.NoError(t, err, "failed to deploy contract with library")
+	library := c.ContractAddressToNameMap.GetContractAddress("Lib")
+	require.NotEqual(t, seth.UNKNOWN, library, "library should be added to contract map")
+
+	code, err := c.Client.CodeAt(context.Background(), consumer.Address, nil)
+	require.NoError(t, err, "failed to get code")
+	require.Contains(t, common.Bytes2Hex(code), strings.ToLower(library[2:]), "library address should be linked")
+
+	// or linked with given address
+	given := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	consumer, err = c.DeployContractFromContractStore(c.NewTXOpts(seth.WithLibraries(map[string]common.Address{"Lib": given})), "Consumer")
+	require.NoError(t, err, "failed to deploy contract with given library")
+	code, err = c.Client.CodeAt(context.Background(), consumer.Address, nil)
+	require.NoError(t, err, "failed to get code")
+	require.Contains(t, common.Bytes2Hex(code), strings.ToLower(given.Hex()[2:]), "given library address should be linked")
+
+	// name of the library can't be read from placeholder without link references
+	unlinked, err := seth.ParseUnlinkedBytecode(fmt.Sprintf(consumerBytecodeTemplate, libraryHashPlaceholder("contracts/Other.sol:Other")))
+	require.NoError(t, err, "failed to parse bytecode")
+	c.ContractStore.AddUnlinkedBIN("Other", unlinked)
+	c.ContractStore.AddABI("Other", abi.ABI{})
+	_, err = c.DeployContractFromContractStore(c.NewTXOpts(), "Other")
+	require.ErrorIs(t, err, seth.ErrMissingLibraries, "library of unknown name can't be deployed")
+}
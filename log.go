@@ -1,32 +1,206 @@
 package seth
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
+	"github.com/natefinch/lumberjack"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 const (
 	LogLevelEnvVar = "SETH_LOG_LEVEL"
+
+	// LogTarget_Stderr writes logs only to stderr (default)
+	LogTarget_Stderr = "stderr"
+	// LogTarget_File writes logs only to a file
+	LogTarget_File = "file"
+	// LogTarget_Both writes logs both to stderr and a file
+	LogTarget_Both = "both"
+
+	DefaultLogDir       = "logs"
+	LogFilePattern      = "seth_%s.log"
+	DefaultLogMaxSizeMB = 100
 )
 
 var (
+	ErrInvalidLogTarget = errors.New("invalid log target")
+)
+
+// LogCfg configures where logs are written to. Log file is rotated, when it exceeds max size.
+type LogCfg struct {
+	// Target is one of "stderr" (default), "file" or "both"
+	Target string `toml:"target"`
+	// Dir is the directory of the log file, "logs" by default
+	Dir string `toml:"dir"`
+	// FileName is the name of the log file, by default it's "seth_<run id>.log", so that each run has its own file
+	FileName string `toml:"file_name"`
+	// MaxSizeMB is the size of log file after which it is rotated, 100 MB by default
+	MaxSizeMB int `toml:"max_size_mb"`
+	// MaxBackups is the number of rotated files to keep, all are kept by default
+	MaxBackups int `toml:"max_backups"`
+}
+
+// Logger is the logger used by the Client. It's implemented by *zerolog.Logger, so any zerolog logger (e.g. with different
+// output, with client's name in context or zerolog.Nop() to silence the client) can be passed with WithLogger.
+type Logger interface {
+	Trace() *zerolog.Event
+	Debug() *zerolog.Event
+	Info() *zerolog.Event
+	Warn() *zerolog.Event
+	Error() *zerolog.Event
+	Err(err error) *zerolog.Event
+	With() zerolog.Context
+}
+
+var (
+	// L is the global logger used by default by all clients and by code, which isn't bound to a client
 	L zerolog.Logger
+
+	runID = time.Now().Format("20060102_150405") + fmt.Sprintf("_%d", os.Getpid())
+
+	logMu    = &sync.Mutex{}
+	logState *loggingState
+	logFile  *lumberjack.Logger
 )
 
+// loggingState is what the global logger was last configured with, if it doesn't change logger isn't replaced
+type loggingState struct {
+	level    zerolog.Level
+	target   string
+	filePath string
+}
+
 func init() {
 	initDefaultLogging()
 }
 
+// RunID returns identifier of the current run (process), which is used to name log files
+func RunID() string {
+	return runID
+}
+
+// LogFilePath returns path of the file logs are written to or empty string if logs are written only to stderr
+func LogFilePath() string {
+	logMu.Lock()
+	defer logMu.Unlock()
+	if logState == nil {
+		return ""
+	}
+	return logState.filePath
+}
+
+// logger returns client's logger or the global one, if it wasn't set
+func (m *Client) logger() Logger {
+	if m.Logger == nil {
+		return &L
+	}
+	return m.Logger
+}
+
 func initDefaultLogging() {
+	if err := initLogging(nil); err != nil {
+		panic(err)
+	}
+}
+
+// initLogging configures global logger. It's safe to call it concurrently and it's idempotent: logger is replaced only if
+// log level (read from SETH_LOG_LEVEL) or log targets changed, so clients created in parallel with the same config don't
+// race on the global logger.
+func initLogging(cfg *LogCfg) error {
 	lvlStr := os.Getenv(LogLevelEnvVar)
 	if lvlStr == "" {
 		lvlStr = "info"
 	}
 	lvl, err := zerolog.ParseLevel(lvlStr)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if err := validateLogCfg(cfg); err != nil {
+		return err
+	}
+
+	state := &loggingState{level: lvl, target: LogTarget_Stderr}
+	if cfg != nil && cfg.Target != "" {
+		state.target = cfg.Target
+	}
+	if state.target != LogTarget_Stderr {
+		state.filePath = cfg.filePath()
+	}
+
+	logMu.Lock()
+	defer logMu.Unlock()
+	if logState != nil && *logState == *state {
+		return nil
+	}
+
+	var writers []io.Writer
+	if state.target != LogTarget_File {
+		writers = append(writers, zerolog.ConsoleWriter{Out: os.Stderr})
+	}
+	if state.filePath != "" {
+		if logFile == nil || logFile.Filename != state.filePath {
+			if err := os.MkdirAll(filepath.Dir(state.filePath), os.ModePerm); err != nil {
+				return errors.Wrap(err, "failed to create log directory")
+			}
+			if logFile != nil {
+				_ = logFile.Close()
+			}
+			logFile = &lumberjack.Logger{
+				Filename:   state.filePath,
+				MaxSize:    cfg.maxSizeMB(),
+				MaxBackups: cfg.MaxBackups,
+			}
+		}
+		writers = append(writers, zerolog.ConsoleWriter{Out: logFile, NoColor: true})
+	}
+
+	L = log.Output(zerolog.MultiLevelWriter(writers...)).Level(lvl)
+	logState = state
+
+	if state.filePath != "" {
+		L.Debug().Str("Path", state.filePath).Str("Run ID", runID).Msg("Writing logs to file")
+	}
+
+	return nil
+}
+
+func validateLogCfg(cfg *LogCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	switch cfg.Target {
+	case "", LogTarget_Stderr, LogTarget_File, LogTarget_Both:
+	default:
+		return errors.Wrapf(ErrInvalidLogTarget, "log target must be one of '%s', '%s' or '%s', got '%s'", LogTarget_Stderr, LogTarget_File, LogTarget_Both, cfg.Target)
+	}
+	if cfg.MaxSizeMB < 0 || cfg.MaxBackups < 0 {
+		return errors.New("log 'max_size_mb' and 'max_backups' must not be negative")
+	}
+	return nil
+}
+
+func (c *LogCfg) filePath() string {
+	dir := c.Dir
+	if dir == "" {
+		dir = DefaultLogDir
+	}
+	name := c.FileName
+	if name == "" {
+		name = fmt.Sprintf(LogFilePattern, runID)
+	}
+	return filepath.Join(dir, name)
+}
+
+func (c *LogCfg) maxSizeMB() int {
+	if c.MaxSizeMB == 0 {
+		return DefaultLogMaxSizeMB
 	}
-	L = log.Output(zerolog.ConsoleWriter{Out: os.Stderr}).Level(lvl)
+	return c.MaxSizeMB
 }
@@ -0,0 +1,122 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultLogsChunkSize is the number of blocks queried with a single eth_getLogs call, ranges are split further, when
+	// provider rejects them as too large
+	DefaultLogsChunkSize = 2000
+)
+
+var (
+	ErrFilterLogs = errors.New("failed to get logs")
+)
+
+// logsRangeTooLargeErrors are parts of errors returned by RPC providers, when eth_getLogs range or result is too large
+var logsRangeTooLargeErrors = []string{
+	"query returned more than",
+	"block range",
+	"range is too large",
+	"range too large",
+	"exceed maximum block range",
+	"exceeds the range",
+	"too many blocks",
+	"too many results",
+	"response size exceeded",
+	"response size should not",
+	"limit exceeded",
+	"log response size",
+	"query timeout exceeded",
+	"is limited to",
+}
+
+// IsLogsRangeTooLargeError returns true if error means, that eth_getLogs query should be split into smaller ranges
+func IsLogsRangeTooLargeError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, e := range logsRangeTooLargeErrors {
+		if strings.Contains(msg, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterDecodedLogs returns all events of given name (or signature) emitted by all contracts with the name from contract
+// map between fromBlock and toBlock (inclusive, 0 means latest block), decoded with contract's ABI and ordered by block
+// and log index. Logs are queried in chunks of DefaultLogsChunkSize blocks and chunks, that the provider rejects as too
+// large (too many results or too wide range), are split in halves, until they are accepted.
+func (m *Client) FilterDecodedLogs(ctx context.Context, contractName, eventName string, fromBlock, toBlock uint64) ([]DecodedTransactionLog, error) {
+	contractABI, event, addresses, err := m.contractEventFilter(contractName, eventName)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := m.FilterLogsChunked(ctx, ethereum.FilterQuery{Addresses: addresses, Topics: [][]common.Hash{{event.ID}}}, fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	return m.decodeContractLogs(m.logger().With().Logger(), logs, *contractABI)
+}
+
+// FilterLogsChunked returns logs matching the query between fromBlock and toBlock (inclusive, 0 means latest block)
+// ordered by block and log index. Query's block range is ignored. Range is queried in chunks, which are split, when
+// the provider rejects them as too large.
+func (m *Client) FilterLogsChunked(ctx context.Context, q ethereum.FilterQuery, fromBlock, toBlock uint64) ([]types.Log, error) {
+	if toBlock == 0 {
+		latest, err := m.Client.BlockNumber(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get latest block number")
+		}
+		toBlock = latest
+	}
+	if fromBlock > toBlock {
+		return nil, errors.Errorf("from block %d is greater than to block %d", fromBlock, toBlock)
+	}
+
+	var logs []types.Log
+	chunkSize := uint64(DefaultLogsChunkSize)
+	for from := fromBlock; from <= toBlock; {
+		to := from + chunkSize - 1
+		if to > toBlock || to < from {
+			to = toBlock
+		}
+		q.BlockHash = nil
+		q.FromBlock = new(big.Int).SetUint64(from)
+		q.ToBlock = new(big.Int).SetUint64(to)
+		chunk, err := m.Client.FilterLogs(ctx, q)
+		if err != nil {
+			if IsLogsRangeTooLargeError(err) && to > from {
+				chunkSize = (to - from + 1) / 2
+				m.logger().Debug().Err(err).Uint64("From", from).Uint64("To", to).Uint64("New chunk size", chunkSize).Msg("Logs range is too large, splitting it")
+				continue
+			}
+			return nil, errors.Wrapf(wrapError(err, ErrFilterLogs), "blocks: %d-%d", from, to)
+		}
+		m.logger().Trace().Uint64("From", from).Uint64("To", to).Int("Logs", len(chunk)).Msg("Fetched logs")
+		logs = append(logs, chunk...)
+		if to == toBlock {
+			break
+		}
+		from = to + 1
+	}
+
+	sort.SliceStable(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber < logs[j].BlockNumber
+		}
+		return logs[i].Index < logs[j].Index
+	})
+	return logs, nil
+}
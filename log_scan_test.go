@@ -0,0 +1,91 @@
+package seth_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+// newLogsLimitingProxy forwards requests to the node, but rejects eth_getLogs queries wider than maxRange blocks, like
+// public RPC providers do
+func newLogsLimitingProxy(t *testing.T, target string, maxRange uint64, getLogsCalls *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err, "failed to read request")
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []struct {
+				FromBlock hexutil.Uint64 `json:"fromBlock"`
+				ToBlock   hexutil.Uint64 `json:"toBlock"`
+			} `json:"params"`
+		}
+		if json.Unmarshal(body, &req) == nil && req.Method == "eth_getLogs" {
+			atomic.AddInt32(getLogsCalls, 1)
+			if uint64(req.Params[0].ToBlock-req.Params[0].FromBlock)+1 > maxRange {
+				_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":-32005,"message":"query returned more than 10000 results"}}`, req.ID)
+				return
+			}
+		}
+		forwardRPCRequest(t, w, target, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAPIFilterDecodedLogs(t *testing.T) {
+	c := newClient(t)
+	httpURL := nodeHTTPURL(t, c)
+
+	fromBlock, err := c.Client.BlockNumber(context.Background())
+	require.NoError(t, err, "failed to get block number")
+	var hashes []string
+	for i := 0; i < 3; i++ {
+		_, err = c.Decode(TestEnv.DebugContract.EmitNoIndexEvent(c.NewTXOpts()))
+		require.NoError(t, err, "failed to emit event")
+		decoded, err := c.Decode(TestEnv.DebugContract.EmitOneIndexEvent(c.NewTXOpts()))
+		require.NoError(t, err, "failed to emit event")
+		hashes = append(hashes, decoded.Hash)
+	}
+
+	var getLogsCalls int32
+	proxy := newLogsLimitingProxy(t, httpURL, 2, &getLogsCalls)
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.Network.URLs = []string{proxy.URL}
+	client, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to create client")
+	client.ContractAddressToNameMap.AddContract(TestEnv.DebugContractAddress.Hex(), "NetworkDebugContract")
+
+	logs, err := client.FilterDecodedLogs(context.Background(), "NetworkDebugContract", "OneIndexEvent", fromBlock+1, 0)
+	require.NoError(t, err, "failed to filter logs")
+	require.Len(t, logs, len(hashes), "all events should be found")
+	for i, lo := range logs {
+		require.Equal(t, hashes[i], lo.TXHash, "logs should be ordered")
+		require.Equal(t, "OneIndexEvent(uint256)", lo.Signature, "incorrect event")
+		require.NotNil(t, lo.EventData["a"], "event should be decoded")
+	}
+	require.Greater(t, atomic.LoadInt32(&getLogsCalls), int32(1), "range should be split")
+}
+
+func TestUtilIsLogsRangeTooLargeError(t *testing.T) {
+	for _, msg := range []string{
+		"query returned more than 10000 results",
+		"exceed maximum block range: 50000",
+		"Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range",
+		"eth_getLogs is limited to a 10,000 range",
+	} {
+		require.True(t, seth.IsLogsRangeTooLargeError(errors.New(msg)), fmt.Sprintf("'%s' should be range error", msg))
+	}
+	require.False(t, seth.IsLogsRangeTooLargeError(errors.New("connection refused")), "connection error isn't range error")
+}
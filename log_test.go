@@ -0,0 +1,113 @@
+package seth_test
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigLogToFile(t *testing.T) {
+	t.Cleanup(func() {
+		// switch back to stderr for other tests
+		_ = newClient(t)
+	})
+
+	dir := t.TempDir()
+
+	// clients created in parallel with the same config should share the same logger and log file
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cfg, err := seth.ReadConfig()
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			cfg.Log = &seth.LogCfg{Target: seth.LogTarget_Both, Dir: dir}
+			_, errs[i] = seth.NewClientWithConfig(cfg)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err, "failed to initalise seth")
+	}
+
+	expectedPath := filepath.Join(dir, "seth_"+seth.RunID()+".log")
+	require.Equal(t, expectedPath, seth.LogFilePath(), "incorrect log file path")
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err, "failed to read log dir")
+	require.Len(t, files, 1, "all clients should write to the same log file")
+
+	content, err := os.ReadFile(expectedPath)
+	require.NoError(t, err, "failed to read log file")
+	require.True(t, len(content) > 0, "log file should not be empty")
+	require.False(t, strings.Contains(string(content), "\x1b["), "log file should not contain colors")
+}
+
+func TestConfigLogInvalidTarget(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.Log = &seth.LogCfg{Target: "syslog"}
+
+	_, err = seth.NewClientWithConfig(cfg)
+	require.Error(t, err, "invalid log target should fail")
+	require.Contains(t, err.Error(), "log target must be one of", "incorrect error")
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes from client's goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestAPIWithLogger(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	out := &syncBuffer{}
+	logger := zerolog.New(out).With().Str("Client", "first").Logger()
+	c, err := seth.NewClientWithConfig(cfg, seth.WithLogger(&logger))
+	require.NoError(t, err, "failed to initalise seth")
+	require.Equal(t, &logger, c.Logger, "logger should be set")
+
+	decoded, err := c.Decode(TestEnv.DebugContract.Pay(c.NewTXOpts(seth.WithValue(big.NewInt(1)))))
+	require.NoError(t, err, "failed to send transaction")
+	require.Contains(t, out.String(), `"Client":"first"`, "client's logs should be written to its logger")
+	require.Contains(t, out.String(), decoded.Hash, "transaction should be logged by client's logger")
+
+	require.NoError(t, c.Close(), "failed to close client")
+	require.Contains(t, out.String(), "Run spending report", "spending report should be logged by client's logger")
+	logged := out.String()
+	cfg, err = seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	nop := zerolog.Nop()
+	silent, err := seth.NewClientWithConfig(cfg, seth.WithLogger(&nop))
+	require.NoError(t, err, "failed to initalise seth")
+	_, err = silent.Decode(TestEnv.DebugContract.Pay(silent.NewTXOpts(seth.WithValue(big.NewInt(1)))))
+	require.NoError(t, err, "failed to send transaction")
+	require.Equal(t, logged, out.String(), "other client shouldn't write to the logger")
+}
@@ -0,0 +1,239 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// Multicall3Address is the address of Multicall3 contract deployed on most of the chains
+	Multicall3Address = "0xcA11bde05977b3631167028862bE2a173976CA11"
+	// DefaultMulticallBatchSize is the maximum number of calls aggregated into a single Multicall3 call
+	DefaultMulticallBatchSize = 500
+	// multicallFallbackConcurrency is the number of concurrent eth_calls, when Multicall3 isn't deployed
+	multicallFallbackConcurrency = 16
+
+	multicall3ABIJSON = `[
+		{"type":"function","name":"aggregate3","stateMutability":"payable","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}],"outputs":[{"name":"returnData","type":"tuple[]","components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}]}
+	]`
+)
+
+var (
+	ErrMulticallNoABI      = errors.New("no ABI found for contract called with multicall")
+	ErrMulticallPack       = errors.New("failed to pack multicall call")
+	ErrMulticallCallFailed = errors.New("multicall call failed")
+)
+
+var multicall3ABI = mustParseABI(multicall3ABIJSON)
+
+// multicall3Call is a Multicall3.Call3 struct
+type multicall3Call struct {
+	Target       common.Address `abi:"target"`
+	AllowFailure bool           `abi:"allowFailure"`
+	CallData     []byte         `abi:"callData"`
+}
+
+// multicall3Result is a Multicall3.Result struct
+type multicall3Result struct {
+	Success    bool   `json:"success"`
+	ReturnData []byte `json:"returnData"`
+}
+
+// MulticallResult is a result of a single call, Values are decoded outputs of the method, Err is set if the call failed
+type MulticallResult struct {
+	Target common.Address
+	Method string
+	Values []interface{}
+	Err    error
+}
+
+type multicallCall struct {
+	target common.Address
+	method abi.Method
+	data   []byte
+}
+
+// Multicall aggregates many view calls into Multicall3 aggregate3() calls, each with at most BatchSize calls. Failure of
+// a single call doesn't fail the others. If Multicall3 isn't deployed at Address, calls are executed one by one concurrently.
+type Multicall struct {
+	Address   common.Address
+	BatchSize int
+	caller    bind.ContractCaller
+	client    *Client
+	calls     []multicallCall
+	err       error
+}
+
+// NewMulticall creates a new multicall, that uses Multicall3 deployed at the address. Only calls with ABI can be added.
+func NewMulticall(caller bind.ContractCaller, address common.Address) *Multicall {
+	return &Multicall{
+		Address:   address,
+		BatchSize: DefaultMulticallBatchSize,
+		caller:    caller,
+	}
+}
+
+// Multicall creates a new multicall, that uses network's 'multicall_address' (or canonical Multicall3 address) and resolves
+// ABIs of called contracts with the contract map
+func (m *Client) Multicall() *Multicall {
+	address := Multicall3Address
+	if m.Cfg.Network.MulticallAddress != "" {
+		address = m.Cfg.Network.MulticallAddress
+	}
+	mc := NewMulticall(m.Client, common.HexToAddress(address))
+	mc.client = m
+	return mc
+}
+
+// Add adds a call of the method of the contract, whose ABI is found using the contract map and the contract store
+func (mc *Multicall) Add(contract common.Address, method string, args ...interface{}) *Multicall {
+	if mc.client == nil || !mc.client.ContractAddressToNameMap.IsKnownAddress(contract.Hex()) {
+		mc.setErr(errors.Wrapf(ErrMulticallNoABI, "contract: %s, add it to the contract map or use AddWithABI()", contract.Hex()))
+		return mc
+	}
+	contractABI, ok := mc.client.ContractStore.GetABI(mc.client.ContractAddressToNameMap.GetContractName(contract.Hex()))
+	if !ok {
+		mc.setErr(errors.Wrapf(ErrMulticallNoABI, "contract: %s, add it to the contract map or use AddWithABI()", contract.Hex()))
+		return mc
+	}
+	return mc.AddWithABI(contract, *contractABI, method, args...)
+}
+
+// AddWithABI adds a call of the method of the contract with given ABI
+func (mc *Multicall) AddWithABI(contract common.Address, contractABI abi.ABI, method string, args ...interface{}) *Multicall {
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		mc.setErr(errors.Wrapf(wrapError(err, ErrMulticallPack), "call: %d (%s)", len(mc.calls), method))
+		return mc
+	}
+	mc.calls = append(mc.calls, multicallCall{target: contract, method: contractABI.Methods[method], data: data})
+	return mc
+}
+
+// Len returns number of added calls
+func (mc *Multicall) Len() int {
+	return len(mc.calls)
+}
+
+// Execute executes all added calls and returns their results in the same order, in which they were added. Error is
+// returned only if calls couldn't be added or executed at all, failures of single calls are returned in their results.
+func (mc *Multicall) Execute(ctx context.Context) ([]MulticallResult, error) {
+	if mc.err != nil {
+		return nil, mc.err
+	}
+	results := make([]MulticallResult, len(mc.calls))
+	if len(mc.calls) == 0 {
+		return results, nil
+	}
+
+	code, err := mc.caller.CodeAt(ctx, mc.Address, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to check Multicall3 deployment")
+	}
+	if len(code) == 0 {
+		L.Debug().Str("Address", mc.Address.Hex()).Msg("Multicall3 is not deployed, executing calls one by one")
+		return results, mc.executeOneByOne(ctx, results)
+	}
+
+	batchSize := mc.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultMulticallBatchSize
+	}
+	for start := 0; start < len(mc.calls); start += batchSize {
+		end := min(start+batchSize, len(mc.calls))
+		if err := mc.executeBatch(ctx, start, end, results); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+func (mc *Multicall) executeBatch(ctx context.Context, start, end int, results []MulticallResult) error {
+	calls := make([]multicall3Call, 0, end-start)
+	for _, c := range mc.calls[start:end] {
+		calls = append(calls, multicall3Call{Target: c.target, AllowFailure: true, CallData: c.data})
+	}
+	data, err := multicall3ABI.Pack("aggregate3", calls)
+	if err != nil {
+		return errors.Wrap(err, "failed to pack aggregate3 call")
+	}
+
+	out, err := mc.caller.CallContract(ctx, ethereum.CallMsg{To: &mc.Address, Data: data}, nil)
+	if err != nil {
+		return errors.Wrapf(err, "aggregate3 call of calls %d-%d failed", start, end-1)
+	}
+	unpacked, err := multicall3ABI.Unpack("aggregate3", out)
+	if err != nil {
+		return errors.Wrap(err, "failed to unpack aggregate3 results")
+	}
+	returned := *abi.ConvertType(unpacked[0], new([]multicall3Result)).(*[]multicall3Result)
+	if len(returned) != end-start {
+		return fmt.Errorf("aggregate3 returned %d results for %d calls", len(returned), end-start)
+	}
+
+	for i, r := range returned {
+		var callErr error
+		if !r.Success {
+			callErr = mc.callError(mc.calls[start+i], r.ReturnData)
+		}
+		results[start+i] = mc.result(mc.calls[start+i], r.ReturnData, callErr)
+	}
+	return nil
+}
+
+func (mc *Multicall) executeOneByOne(ctx context.Context, results []MulticallResult) error {
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(multicallFallbackConcurrency)
+	for i, c := range mc.calls {
+		i, c := i, c
+		eg.Go(func() error {
+			out, err := mc.caller.CallContract(egCtx, ethereum.CallMsg{To: &c.target, Data: c.data}, nil)
+			if err != nil {
+				err = errors.Wrapf(wrapError(err, ErrMulticallCallFailed), "method: %s, target: %s", c.method.Sig, c.target.Hex())
+			}
+			results[i] = mc.result(c, out, err)
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+func (mc *Multicall) result(c multicallCall, out []byte, callErr error) MulticallResult {
+	result := MulticallResult{Target: c.target, Method: c.method.Sig, Err: callErr}
+	if callErr != nil {
+		return result
+	}
+	values, err := c.method.Outputs.Unpack(out)
+	if err != nil {
+		result.Err = errors.Wrapf(err, "failed to unpack output of %s", c.method.Sig)
+		return result
+	}
+	result.Values = values
+	return result
+}
+
+// callError returns error of failed call with decoded revert reason, if revert data could be decoded
+func (mc *Multicall) callError(c multicallCall, revertData []byte) error {
+	var store *ContractStore
+	if mc.client != nil {
+		store = mc.client.ContractStore
+	}
+	if reason := decodeRevertReason(store, revertData); reason != nil {
+		return errors.Wrapf(ErrMulticallCallFailed, "method: %s, target: %s, reason: %s", c.method.Sig, c.target.Hex(), reason.Message)
+	}
+	return errors.Wrapf(ErrMulticallCallFailed, "method: %s, target: %s", c.method.Sig, c.target.Hex())
+}
+
+func (mc *Multicall) setErr(err error) {
+	if mc.err == nil {
+		mc.err = err
+	}
+}
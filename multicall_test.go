@@ -0,0 +1,133 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+const testMulticall3ABI = `[{"type":"function","name":"aggregate3","stateMutability":"payable","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}],"outputs":[{"name":"returnData","type":"tuple[]","components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}]}]`
+
+// fakeMulticall3 executes aggregate3() calls like Multicall3 would, by calling each target separately
+type fakeMulticall3 struct {
+	*seth.Client
+	address    common.Address
+	aggregates int
+}
+
+func (f *fakeMulticall3) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	if contract == f.address {
+		return []byte{0x1}, nil
+	}
+	return f.Client.Client.CodeAt(ctx, contract, blockNumber)
+}
+
+func (f *fakeMulticall3) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if *call.To != f.address {
+		return f.Client.Client.CallContract(ctx, call, blockNumber)
+	}
+	f.aggregates++
+
+	a, err := abi.JSON(strings.NewReader(testMulticall3ABI))
+	if err != nil {
+		return nil, err
+	}
+	in, err := a.Methods["aggregate3"].Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	calls := *abi.ConvertType(in[0], new([]struct {
+		Target       common.Address
+		AllowFailure bool
+		CallData     []byte
+	})).(*[]struct {
+		Target       common.Address
+		AllowFailure bool
+		CallData     []byte
+	})
+
+	type result struct {
+		Success    bool
+		ReturnData []byte
+	}
+	var results []result
+	for _, c := range calls {
+		target := c.Target
+		out, err := f.Client.Client.CallContract(ctx, ethereum.CallMsg{To: &target, Data: c.CallData}, blockNumber)
+		if err != nil {
+			// Multicall3 returns revert data of failed calls
+			var data []byte
+			if dataErr, ok := err.(interface{ ErrorData() interface{} }); ok {
+				if hexData, ok := dataErr.ErrorData().(string); ok {
+					data = common.FromHex(hexData)
+				}
+			}
+			results = append(results, result{ReturnData: data})
+			continue
+		}
+		results = append(results, result{Success: true, ReturnData: out})
+	}
+	return a.Methods["aggregate3"].Outputs.Pack(results)
+}
+
+func requireMulticallResults(t *testing.T, results []seth.MulticallResult, stored int64) {
+	require.Len(t, results, 3, "expected result of every call")
+	require.NoError(t, results[0].Err, "get() should succeed")
+	require.Equal(t, "get()", results[0].Method, "incorrect method")
+	require.Len(t, results[0].Values, 1, "get() has a single output")
+	require.Equal(t, stored, results[0].Values[0].(*big.Int).Int64(), "incorrect get() output")
+	require.NoError(t, results[1].Err, "getCounter() should succeed")
+	require.Equal(t, 1, results[1].Values[0].(*big.Int).Sign(), "counter should be set")
+	require.ErrorIs(t, results[2].Err, seth.ErrMulticallCallFailed, "reverting call should fail")
+	require.Contains(t, results[2].Err.Error(), "method: alwaysRevertsRequire(), target: "+TestEnv.DebugContractAddress.Hex(), "incorrect error")
+	require.Contains(t, results[2].Err.Error(), "always revert error", "revert reason should be decoded")
+}
+
+func TestAPIMulticall(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	// debug contract is shared by all tests, so the value returned by get() is set here
+	stored := int64(4242)
+	_, err := c.Decode(TestEnv.DebugContract.Set(c.NewTXOpts(), big.NewInt(stored)))
+	require.NoError(t, err, "failed to set value")
+	_, err = c.Decode(TestEnv.DebugContract.AddCounter(c.NewTXOpts(), big.NewInt(77), big.NewInt(5)))
+	require.NoError(t, err, "failed to set counter")
+
+	t.Run("Multicall3 not deployed", func(t *testing.T) {
+		results, err := c.Multicall().
+			Add(TestEnv.DebugContractAddress, "get").
+			Add(TestEnv.DebugContractAddress, "getCounter", big.NewInt(77)).
+			Add(TestEnv.DebugContractAddress, "alwaysRevertsRequire").
+			Execute(context.Background())
+		require.NoError(t, err, "failed to execute calls")
+		requireMulticallResults(t, results, stored)
+	})
+
+	t.Run("aggregated in batches", func(t *testing.T) {
+		debugABI, ok := c.ContractStore.GetABI("NetworkDebugContract")
+		require.True(t, ok, "ABI should be loaded")
+		fake := &fakeMulticall3{Client: c, address: common.HexToAddress(seth.Multicall3Address)}
+
+		mc := seth.NewMulticall(fake, fake.address)
+		mc.BatchSize = 2
+		results, err := mc.
+			AddWithABI(TestEnv.DebugContractAddress, *debugABI, "get").
+			AddWithABI(TestEnv.DebugContractAddress, *debugABI, "getCounter", big.NewInt(77)).
+			AddWithABI(TestEnv.DebugContractAddress, *debugABI, "alwaysRevertsRequire").
+			Execute(context.Background())
+		require.NoError(t, err, "failed to execute calls")
+		require.Equal(t, 2, fake.aggregates, "calls should be aggregated into 2 batches")
+		requireMulticallResults(t, results, stored)
+	})
+
+	t.Run("unknown contract", func(t *testing.T) {
+		_, err := c.Multicall().Add(common.HexToAddress("0x1"), "get").Execute(context.Background())
+		require.EqualError(t, err, "contract: 0x0000000000000000000000000000000000000001, add it to the contract map or use AddWithABI(): no ABI found for contract called with multicall")
+	})
+}
@@ -0,0 +1,161 @@
+package seth
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MaxNestedCallsDepth is the maximum depth of calldata nested in bytes arguments that will be decoded
+const MaxNestedCallsDepth = 5
+
+// NestedCall is calldata passed as bytes argument (e.g. to forwarders, multisigs, timelocks or governance proposals)
+// decoded with one of known ABIs
+type NestedCall struct {
+	CommonData
+	// Contract is the name of ABI used to decode the call
+	Contract string `json:"contract"`
+	// To is the target of the call, if it was passed as an address argument next to calldata
+	To string `json:"to,omitempty"`
+}
+
+// DecodeNestedCalls finds bytes arguments (also inside arrays and structs) of the call that look like ABI-encoded calldata
+// and decodes them recursively. Returns map of argument path (e.g. "data", "calldatas[1]" or "request.data") to decoded
+// call or nil if no argument could be decoded.
+func (a *ABIFinder) DecodeNestedCalls(method *abi.Method, input map[string]interface{}) map[string]*NestedCall {
+	return a.decodeNestedCalls(method, input, 1)
+}
+
+func (a *ABIFinder) decodeNestedCalls(method *abi.Method, input map[string]interface{}, depth int) map[string]*NestedCall {
+	if a == nil || a.ContractStore == nil || method == nil || len(input) == 0 || depth > MaxNestedCallsDepth {
+		return nil
+	}
+
+	nested := make(map[string]*NestedCall)
+	for _, arg := range method.Inputs {
+		value, ok := input[arg.Name]
+		if !ok {
+			continue
+		}
+		a.walkBytes(arg.Name, reflect.ValueOf(value), -1, func(path string, data []byte, index int) {
+			if call := a.decodeCalldata(data, nestedCallTarget(method, input, index), depth); call != nil {
+				nested[path] = call
+			}
+		})
+	}
+	if len(nested) == 0 {
+		return nil
+	}
+
+	return nested
+}
+
+// walkBytes calls fn for every byte slice in value, index is set for elements of top-level arrays
+func (a *ABIFinder) walkBytes(path string, value reflect.Value, index int, fn func(path string, data []byte, index int)) {
+	switch value.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if !value.IsNil() {
+			a.walkBytes(path, value.Elem(), index, fn)
+		}
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			if value.Kind() == reflect.Slice {
+				fn(path, value.Bytes(), index)
+			}
+			return
+		}
+		for i := 0; i < value.Len(); i++ {
+			elemIndex := index
+			if elemIndex < 0 {
+				elemIndex = i
+			}
+			a.walkBytes(fmt.Sprintf("%s[%d]", path, i), value.Index(i), elemIndex, fn)
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Tag.Get("json")
+			if name == "" {
+				name = strings.ToLower(field.Name[:1]) + field.Name[1:]
+			}
+			a.walkBytes(path+"."+name, value.Field(i), index, fn)
+		}
+	}
+}
+
+// decodeCalldata decodes data, if it has a selector of a method from one of known ABIs and its arguments can be unpacked.
+// ABI of the contract at target address is used, if it's known, otherwise all ABIs are searched.
+func (a *ABIFinder) decodeCalldata(data []byte, target string, depth int) *NestedCall {
+	// ABI-encoded arguments are always a multiple of 32 bytes
+	if len(data) < 4 || (len(data)-4)%32 != 0 {
+		return nil
+	}
+
+	decode := func(name string, contractABI abi.ABI) *NestedCall {
+		method, err := contractABI.MethodById(data[:4])
+		if err != nil {
+			return nil
+		}
+		input, err := decodeTxInputs(L, data, method)
+		if err != nil {
+			return nil
+		}
+		return &NestedCall{
+			CommonData: CommonData{
+				Signature:   common.Bytes2Hex(method.ID),
+				Method:      method.Sig,
+				Input:       input,
+				NestedCalls: a.decodeNestedCalls(method, input, depth+1),
+			},
+			Contract: strings.TrimSuffix(name, ".abi"),
+			To:       target,
+		}
+	}
+
+	if target != "" && a.ContractMap.IsKnownAddress(target) {
+		name := a.ContractMap.GetContractName(target)
+		if contractABI, ok := a.ContractStore.GetABI(name); ok {
+			if call := decode(name, *contractABI); call != nil {
+				return call
+			}
+		}
+	}
+
+	var result *NestedCall
+	a.ContractStore.RangeABIs(func(name string, contractABI abi.ABI) bool {
+		result = decode(name, contractABI)
+		return result == nil
+	})
+
+	return result
+}
+
+// nestedCallTargetArgs are names of address arguments, that usually hold the target of calldata passed next to them
+var nestedCallTargetArgs = []string{"target", "targets", "to", "dest", "destination", "destinations"}
+
+// nestedCallTarget returns address from the target argument (see nestedCallTargetArgs) of the method, for calldata being
+// an element of array it's the address with the same index (e.g. Governor's propose(targets, values, calldatas, ...))
+func nestedCallTarget(method *abi.Method, input map[string]interface{}, index int) string {
+	for _, arg := range method.Inputs {
+		if !slices.Contains(nestedCallTargetArgs, strings.TrimPrefix(strings.ToLower(arg.Name), "_")) {
+			continue
+		}
+		switch v := input[arg.Name].(type) {
+		case common.Address:
+			return v.Hex()
+		case []common.Address:
+			if index >= 0 && index < len(v) {
+				return v[index].Hex()
+			}
+		}
+	}
+
+	return ""
+}
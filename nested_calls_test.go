@@ -0,0 +1,91 @@
+package seth_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+const forwarderABI = `[
+	{"type":"function","name":"execute","inputs":[{"name":"target","type":"address"},{"name":"data","type":"bytes"}],"outputs":[]},
+	{"type":"function","name":"scheduleBatch","inputs":[{"name":"targets","type":"address[]"},{"name":"payloads","type":"bytes[]"}],"outputs":[]},
+	{"type":"function","name":"forward","inputs":[{"name":"req","type":"tuple","components":[{"name":"from","type":"address"},{"name":"data","type":"bytes"}]}],"outputs":[]}
+]`
+
+func TestUtilDecodeNestedCalls(t *testing.T) {
+	cs, err := seth.NewContractStore("./contracts/abi", "./contracts/bin")
+	require.NoError(t, err, "failed to create contract store")
+	debugAbi, ok := cs.GetABI("NetworkDebugContract")
+	require.True(t, ok, "ABI should be loaded")
+	fwdAbi, err := abi.JSON(strings.NewReader(forwarderABI))
+	require.NoError(t, err, "failed to parse ABI")
+
+	target := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	contractMap := seth.NewEmptyContractMap()
+	contractMap.AddContract(target.Hex(), "NetworkDebugContract")
+	finder := seth.NewABIFinder(contractMap, cs)
+
+	setData, err := debugAbi.Pack("set", big.NewInt(7))
+	require.NoError(t, err, "failed to pack call")
+	// calldata nested twice: onTokenTransfer(sender, amount, set(7))
+	transferData, err := debugAbi.Pack("onTokenTransfer", target, big.NewInt(1), setData)
+	require.NoError(t, err, "failed to pack call")
+
+	decode := func(method string, args ...interface{}) map[string]*seth.NestedCall {
+		data, err := fwdAbi.Pack(method, args...)
+		require.NoError(t, err, "failed to pack call")
+		input := make(map[string]interface{})
+		require.NoError(t, fwdAbi.Methods[method].Inputs.UnpackIntoMap(input, data[4:]), "failed to unpack call")
+		m := fwdAbi.Methods[method]
+		return finder.DecodeNestedCalls(&m, input)
+	}
+
+	nested := decode("execute", target, transferData)
+	require.Len(t, nested, 1, "expected one nested call")
+	require.Equal(t, "onTokenTransfer(address,uint256,bytes)", nested["data"].Method, "incorrect nested method")
+	require.Equal(t, "NetworkDebugContract", nested["data"].Contract, "incorrect nested contract")
+	require.Equal(t, target.Hex(), nested["data"].To, "incorrect nested call target")
+	require.Equal(t, big.NewInt(1), nested["data"].Input["amount"], "incorrect nested input")
+	require.Equal(t, "set(int256)", nested["data"].NestedCalls["data"].Method, "calldata should be decoded recursively")
+	require.Equal(t, big.NewInt(7), nested["data"].NestedCalls["data"].Input["x"], "incorrect doubly nested input")
+	require.Empty(t, nested["data"].NestedCalls["data"].To, "sender is not a target of nested call")
+
+	other := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	nested = decode("scheduleBatch", []common.Address{other, target}, [][]byte{{0x1, 0x2}, setData})
+	require.Len(t, nested, 1, "bytes that aren't calldata should be skipped")
+	require.Equal(t, "set(int256)", nested["payloads[1]"].Method, "incorrect nested method")
+	require.Equal(t, target.Hex(), nested["payloads[1]"].To, "target should be matched by index")
+
+	nested = decode("forward", struct {
+		From common.Address `json:"from"`
+		Data []byte         `json:"data"`
+	}{From: other, Data: setData})
+	require.Equal(t, "set(int256)", nested["req.data"].Method, "calldata in struct should be decoded")
+
+	require.Nil(t, decode("execute", target, []byte{0xde, 0xad, 0xbe, 0xef}), "unknown calldata should not be decoded")
+}
+
+func TestTraceNestedCalldata(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	SkipAnvil(t, c)
+
+	debugAbi, ok := c.ContractStore.GetABI("NetworkDebugContract")
+	require.True(t, ok, "ABI should be loaded")
+	setData, err := debugAbi.Pack("set", big.NewInt(7))
+	require.NoError(t, err, "failed to pack call")
+	tx, txErr := TestEnv.DebugContract.OnTokenTransfer(c.NewTXOpts(), c.Addresses[0], big.NewInt(1), setData)
+	_, decodeErr := c.Decode(tx, txErr)
+	require.Error(t, decodeErr, "transaction should have reverted")
+
+	require.Len(t, c.Tracer.DecodedCalls[tx.Hash().Hex()], 1, "expected 1 decoded call")
+	nested := c.Tracer.DecodedCalls[tx.Hash().Hex()][0].NestedCalls
+	require.Len(t, nested, 1, "expected 1 nested call")
+	require.Equal(t, "set(int256)", nested["data"].Method, "incorrect nested method")
+	require.Equal(t, "NetworkDebugContract", nested["data"].Contract, "incorrect nested contract")
+	require.Equal(t, map[string]interface{}{"x": big.NewInt(7)}, nested["data"].Input, "incorrect nested input")
+}
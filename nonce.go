@@ -3,22 +3,28 @@ package seth
 import (
 	"context"
 	"crypto/ecdsa"
-	"errors"
 	"time"
 
 	"math/big"
+	"sort"
 	"sync"
 
 	"github.com/avast/retry-go"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
 	"go.uber.org/ratelimit"
 )
 
 const (
-	ErrKeySyncTimeout = "key sync timeout, consider increasing key_sync_timeout in seth.toml, or increasing the number of keys"
-	ErrKeySync        = "failed to sync the key"
-	ErrNonce          = "failed to get nonce"
-	TimeoutKeyNum     = -80001
+	TimeoutKeyNum = -80001
+)
+
+var (
+	ErrKeySyncTimeout = errors.New("key sync timeout, consider increasing key_sync_timeout in seth.toml, or increasing the number of keys")
+	ErrKeySync        = errors.New("failed to sync the key")
+	ErrNonce          = errors.New("failed to get nonce")
+	ErrNonceReconcile = errors.New("failed to reconcile nonce")
 )
 
 // NonceManager tracks nonce for each address
@@ -32,6 +38,16 @@ type NonceManager struct {
 	Addresses   []common.Address
 	PrivateKeys []*ecdsa.PrivateKey
 	Nonces      map[common.Address]int64
+	// addresses that allocated nonces from local counter since their last reconciliation
+	allocated map[common.Address]struct{}
+	// transactions signed with allocated nonces, which aren't known to be sent yet, by address and nonce
+	signed map[common.Address]map[uint64]common.Hash
+	// nonces of transactions, which weren't sent, by address, they are allocated again before any new one
+	released map[common.Address][]unsentTransaction
+	// keys, which can be leased exclusively with AcquireKey()
+	keys *keyPool
+	// Journal persists nonces of signed transactions, it's optional
+	Journal NonceJournal
 }
 
 type KeyNonce struct {
@@ -53,14 +69,25 @@ func NewNonceManager(cfg *Config, addrs []common.Address, privKeys []*ecdsa.Priv
 		Addresses:   addrs,
 		PrivateKeys: privKeys,
 		SyncedKeys:  make(chan *KeyNonce, len(addrs)),
+		allocated:   make(map[common.Address]struct{}),
+		signed:      make(map[common.Address]map[uint64]common.Hash),
+		released:    make(map[common.Address][]unsentTransaction),
+		keys:        newKeyPool(addrs),
 	}, nil
 }
 
-// UpdateNonces syncs nonces for addresses
+// UpdateNonces syncs nonces for addresses. If nonces are allocated locally, counters start at the pending nonce, so that
+// transactions already in the mempool aren't replaced, otherwise at the nonce of the latest block.
 func (m *NonceManager) UpdateNonces() error {
 	L.Debug().Interface("Addrs", m.Addresses).Msg("Updating nonces for addresses")
 	for addr := range m.Nonces {
-		nonce, err := m.Client.Client.NonceAt(context.Background(), addr, nil)
+		var nonce uint64
+		var err error
+		if m.cfg != nil && m.cfg.LocalNonceAllocation {
+			nonce, err = m.Client.Client.PendingNonceAt(context.Background(), addr)
+		} else {
+			nonce, err = m.Client.Client.NonceAt(context.Background(), addr, nil)
+		}
 		if err != nil {
 			return err
 		}
@@ -77,6 +104,29 @@ func (m *NonceManager) UpdateNonces() error {
 	return nil
 }
 
+// snapshotNonces returns a copy of local nonce counters
+func (m *NonceManager) snapshotNonces() map[common.Address]int64 {
+	m.Lock()
+	defer m.Unlock()
+	nonces := make(map[common.Address]int64, len(m.Nonces))
+	for addr, nonce := range m.Nonces {
+		nonces[addr] = nonce
+	}
+	return nonces
+}
+
+// restoreNonces sets local nonce counters to values from the snapshot, e.g. after chain was reverted to a snapshot
+func (m *NonceManager) restoreNonces(nonces map[common.Address]int64) {
+	m.Lock()
+	defer m.Unlock()
+	for addr, nonce := range nonces {
+		m.Nonces[addr] = nonce
+	}
+	m.allocated = make(map[common.Address]struct{})
+	m.signed = make(map[common.Address]map[uint64]common.Hash)
+	m.released = make(map[common.Address][]unsentTransaction)
+}
+
 // NextNonce returns new nonce for addr
 // this method is external for module testing, but you should not use it
 // since handling nonces on the client is unpredictable
@@ -88,60 +138,200 @@ func (m *NonceManager) NextNonce(addr common.Address) *big.Int {
 	return nextNonce
 }
 
+// AllocateNonce atomically allocates next nonce for addr from local counter, without querying the node. It allows to have
+// multiple transactions from the same key in flight at the same time. If transaction using allocated nonce is never sent
+// the counter has to be reconciled with ReconcileNonce, otherwise all further transactions from that key will get stuck.
+func (m *NonceManager) AllocateNonce(addr common.Address) uint64 {
+	m.Lock()
+	defer m.Unlock()
+	nonce := uint64(m.Nonces[addr])
+	m.Nonces[addr]++
+	m.allocated[addr] = struct{}{}
+	return nonce
+}
+
+// markAllocated marks addr as one that allocated nonce, which has to be reconciled, if transaction isn't sent
+func (m *NonceManager) markAllocated(addr common.Address) {
+	m.Lock()
+	defer m.Unlock()
+	m.allocated[addr] = struct{}{}
+}
+
+// hasAllocated returns true if addr allocated nonces from local counter since its last reconciliation
+func (m *NonceManager) hasAllocated(addr common.Address) bool {
+	m.Lock()
+	defer m.Unlock()
+	_, ok := m.allocated[addr]
+	return ok
+}
+
+// unsentTransaction is a transaction signed with nonce allocated from local counter, which isn't known to be sent yet
+type unsentTransaction struct {
+	address common.Address
+	nonce   uint64
+	// hash is empty, if transaction failed to be signed
+	hash common.Hash
+}
+
+// peekNonce returns nonce, which will be allocated next for addr, without allocating it
+func (m *NonceManager) peekNonce(addr common.Address) uint64 {
+	m.Lock()
+	defer m.Unlock()
+	if released := m.released[addr]; len(released) > 0 {
+		return released[0].nonce
+	}
+	return uint64(m.Nonces[addr])
+}
+
+// markSigned marks transaction signed with allocated nonce as one, that might have to be released, if it isn't sent
+func (m *NonceManager) markSigned(addr common.Address, tx *types.Transaction) {
+	m.Lock()
+	defer m.Unlock()
+	if m.signed[addr] == nil {
+		m.signed[addr] = make(map[uint64]common.Hash)
+	}
+	m.signed[addr][tx.Nonce()] = tx.Hash()
+}
+
+// markSent marks transaction with given nonce as sent, so its nonce is never released
+func (m *NonceManager) markSent(addr common.Address, nonce uint64) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.signed[addr], nonce)
+}
+
+// signedTransactions returns transactions signed with allocated nonces, which aren't known to be sent yet
+func (m *NonceManager) signedTransactions() []unsentTransaction {
+	m.Lock()
+	defer m.Unlock()
+	var txs []unsentTransaction
+	for addr, nonces := range m.signed {
+		for nonce, hash := range nonces {
+			txs = append(txs, unsentTransaction{address: addr, nonce: nonce, hash: hash})
+		}
+	}
+	return txs
+}
+
+// releaseNonce releases nonce of transaction, which wasn't sent, so that it's allocated again before any new one
+func (m *NonceManager) releaseNonce(tx unsentTransaction) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.signed[tx.address], tx.nonce)
+	released := append(m.released[tx.address], tx)
+	sort.Slice(released, func(i, j int) bool {
+		return released[i].nonce < released[j].nonce
+	})
+	m.released[tx.address] = released
+}
+
+// takeReleasedNonce removes the lowest released nonce of addr and returns it
+func (m *NonceManager) takeReleasedNonce(addr common.Address) (unsentTransaction, bool) {
+	m.Lock()
+	defer m.Unlock()
+	released := m.released[addr]
+	if len(released) == 0 {
+		return unsentTransaction{}, false
+	}
+	m.released[addr] = released[1:]
+	return released[0], true
+}
+
+// ReconcileNonce sets local counter for addr (and the counter shared with other processes, if key coordinator is set) to
+// its pending nonce fetched from the node. Bear in mind that nonces allocated, but not yet sent, by other goroutines using
+// the same key will be allocated once again.
+func (m *NonceManager) ReconcileNonce(ctx context.Context, addr common.Address) error {
+	pendingNonce, err := m.Client.Client.PendingNonceAt(ctx, addr)
+	if err != nil {
+		return errors.Wrapf(wrapError(err, ErrNonceReconcile), "address: %s", addr.Hex())
+	}
+
+	m.Lock()
+	if m.Nonces[addr] != int64(pendingNonce) {
+		L.Debug().
+			Str("Address", addr.Hex()).
+			Int64("Local nonce", m.Nonces[addr]).
+			Uint64("Pending nonce", pendingNonce).
+			Msg("Reconciled local nonce counter")
+	}
+	m.Nonces[addr] = int64(pendingNonce)
+	delete(m.allocated, addr)
+	delete(m.signed, addr)
+	delete(m.released, addr)
+	m.Unlock()
+
+	// shared counter is updated without holding the lock, so that allocations for other keys aren't blocked by it
+	if m.Client.KeyCoordinator != nil {
+		if err := m.Client.KeyCoordinator.SetNonce(ctx, addr, pendingNonce); err != nil {
+			return errors.Wrapf(wrapError(err, ErrNonceReconcile), "address: %s", addr.Hex())
+		}
+	}
+	return nil
+}
+
 func (m *NonceManager) anySyncedKey() int {
 	ctx, cancel := context.WithTimeout(context.Background(), m.cfg.KeySyncTimeout.Duration())
 	defer cancel()
-	select {
-	case <-ctx.Done():
-		L.Error().Msg(ErrKeySyncTimeout)
-		m.Client.Errors = append(m.Client.Errors, errors.New(ErrKeySync))
-		return TimeoutKeyNum //so that it's pretty uniqe number of invalid key
-	case keyData := <-m.SyncedKeys:
-		L.Trace().
-			Interface("KeyNum", keyData.KeyNum).
-			Uint64("Nonce", keyData.Nonce).
-			Interface("Address", m.Addresses[keyData.KeyNum]).
-			Msg("Key selected")
-		go func() {
-			err := retry.Do(
-				func() error {
-					m.rl.Take()
+	var keyData *KeyNonce
+	for keyData == nil {
+		select {
+		case <-ctx.Done():
+			// error is returned to the caller with transaction options for TimeoutKeyNum
+			L.Error().Msg(ErrKeySyncTimeout.Error())
+			return TimeoutKeyNum //so that it's pretty uniqe number of invalid key
+		case keyData = <-m.SyncedKeys:
+			if m.keys.isLeased(keyData.KeyNum) {
+				// leased keys stay synced, but are given only to their holders
+				m.SyncedKeys <- keyData
+				keyData = nil
+				time.Sleep(leasedKeyRequeueDelay)
+			}
+		}
+	}
+	L.Trace().
+		Interface("KeyNum", keyData.KeyNum).
+		Uint64("Nonce", keyData.Nonce).
+		Interface("Address", m.Addresses[keyData.KeyNum]).
+		Msg("Key selected")
+	go func() {
+		err := retry.Do(
+			func() error {
+				m.rl.Take()
+				L.Trace().
+					Interface("KeyNum", keyData.KeyNum).
+					Interface("Address", m.Addresses[keyData.KeyNum]).
+					Msg("Key is syncing")
+				nonce, err := m.Client.Client.NonceAt(context.Background(), m.Addresses[keyData.KeyNum], nil)
+				if err != nil {
+					return ErrNonce
+				}
+				if nonce == keyData.Nonce+1 {
 					L.Trace().
 						Interface("KeyNum", keyData.KeyNum).
+						Uint64("Nonce", nonce).
 						Interface("Address", m.Addresses[keyData.KeyNum]).
-						Msg("Key is syncing")
-					nonce, err := m.Client.Client.NonceAt(context.Background(), m.Addresses[keyData.KeyNum], nil)
-					if err != nil {
-						return errors.New(ErrNonce)
-					}
-					if nonce == keyData.Nonce+1 {
-						L.Trace().
-							Interface("KeyNum", keyData.KeyNum).
-							Uint64("Nonce", nonce).
-							Interface("Address", m.Addresses[keyData.KeyNum]).
-							Msg("Key synced")
-						m.SyncedKeys <- &KeyNonce{
-							KeyNum: keyData.KeyNum,
-							Nonce:  nonce,
-						}
-						return nil
-					} else {
-						L.Trace().
-							Interface("KeyNum", keyData.KeyNum).
-							Uint64("Nonce", nonce).
-							Int("Expected nonce", int(keyData.Nonce+1)).
-							Interface("Address", m.Addresses[keyData.KeyNum]).
-							Msg("Key NOT synced")
+						Msg("Key synced")
+					m.SyncedKeys <- &KeyNonce{
+						KeyNum: keyData.KeyNum,
+						Nonce:  nonce,
 					}
-					return errors.New(ErrKeySync)
-				},
-				retry.Attempts(m.cfg.KeySyncRetries),
-				retry.Delay(m.cfg.KeySyncRetryDelay.Duration()),
-			)
-			if err != nil {
-				m.Client.Errors = append(m.Client.Errors, errors.New(ErrKeySync))
-			}
-		}()
-		return keyData.KeyNum
-	}
+					return nil
+				} else {
+					L.Trace().
+						Interface("KeyNum", keyData.KeyNum).
+						Uint64("Nonce", nonce).
+						Int("Expected nonce", int(keyData.Nonce+1)).
+						Interface("Address", m.Addresses[keyData.KeyNum]).
+						Msg("Key NOT synced")
+				}
+				return ErrKeySync
+			},
+			retry.Attempts(m.cfg.KeySyncRetries),
+			retry.Delay(m.cfg.KeySyncRetryDelay.Duration()),
+		)
+		if err != nil {
+			m.Client.addError(KeyErrorScope(m.Addresses[keyData.KeyNum]), ErrKeySync)
+		}
+	}()
+	return keyData.KeyNum
 }
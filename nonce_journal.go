@@ -0,0 +1,248 @@
+package seth
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	selfTransferGasLimit  = 21_000
+	healGasPriceBumpRatio = 2
+)
+
+var (
+	ErrOpenNonceJournal  = errors.New("failed to open nonce journal")
+	ErrWriteNonceJournal = errors.New("failed to write nonce journal")
+	ErrHealNonceGap      = errors.New("failed to heal nonce gap")
+	ErrNoKeyToHeal       = errors.New("key to heal doesn't exist")
+)
+
+// NonceJournalEntry is a nonce assigned to a signed transaction
+type NonceJournalEntry struct {
+	Time    time.Time      `json:"time"`
+	Address common.Address `json:"address"`
+	Nonce   uint64         `json:"nonce"`
+	TxHash  common.Hash    `json:"tx_hash"`
+}
+
+// NonceJournal persists nonces assigned to transactions of all keys, so that gaps left by transactions, which were never
+// sent or got stuck, can be found after a crash
+type NonceJournal interface {
+	// Record persists the entry
+	Record(entry NonceJournalEntry) error
+	// LastNonce returns the highest nonce recorded for the address
+	LastNonce(address common.Address) (uint64, bool)
+	// Entries returns all entries of the address in the order they were recorded
+	Entries(address common.Address) []NonceJournalEntry
+	Close() error
+}
+
+// FileNonceJournal is a nonce journal appending entries as JSON lines to a file. Entries already present in the file are
+// loaded, when it's opened, so that the journal survives restarts.
+type FileNonceJournal struct {
+	mu      *sync.Mutex
+	file    *os.File
+	entries map[common.Address][]NonceJournalEntry
+	last    map[common.Address]uint64
+}
+
+// OpenFileNonceJournal opens (or creates) the journal file and loads its entries
+func OpenFileNonceJournal(path string) (*FileNonceJournal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, wrapError(err, ErrOpenNonceJournal)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, wrapError(err, ErrOpenNonceJournal)
+	}
+	j := &FileNonceJournal{
+		mu:      &sync.Mutex{},
+		file:    file,
+		entries: make(map[common.Address][]NonceJournalEntry),
+		last:    make(map[common.Address]uint64),
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry NonceJournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// last line could have been written partially, when process crashed
+			L.Warn().Err(err).Str("Path", path).Msg("Skipping invalid nonce journal entry")
+			continue
+		}
+		j.add(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		_ = file.Close()
+		return nil, wrapError(err, ErrOpenNonceJournal)
+	}
+	return j, nil
+}
+
+func (j *FileNonceJournal) add(entry NonceJournalEntry) {
+	j.entries[entry.Address] = append(j.entries[entry.Address], entry)
+	if last, ok := j.last[entry.Address]; !ok || entry.Nonce > last {
+		j.last[entry.Address] = entry.Nonce
+	}
+}
+
+func (j *FileNonceJournal) Record(entry NonceJournalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return wrapError(err, ErrWriteNonceJournal)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return wrapError(err, ErrWriteNonceJournal)
+	}
+	j.add(entry)
+	return nil
+}
+
+func (j *FileNonceJournal) LastNonce(address common.Address) (uint64, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	last, ok := j.last[address]
+	return last, ok
+}
+
+func (j *FileNonceJournal) Entries(address common.Address) []NonceJournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]NonceJournalEntry{}, j.entries[address]...)
+}
+
+func (j *FileNonceJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// journalSigner records nonce and hash of every signed transaction in nonce manager's journal
+type journalSigner struct {
+	Signer
+	nonceManager *NonceManager
+}
+
+func (s *journalSigner) SignTx(ctx context.Context, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	signed, err := s.Signer.SignTx(ctx, address, tx)
+	if err != nil || s.nonceManager.Journal == nil {
+		return signed, err
+	}
+	entry := NonceJournalEntry{Time: time.Now(), Address: address, Nonce: signed.Nonce(), TxHash: signed.Hash()}
+	if err := s.nonceManager.Journal.Record(entry); err != nil {
+		L.Warn().Err(err).Str("Address", address.Hex()).Uint64("Nonce", signed.Nonce()).Msg("Failed to record nonce in the journal")
+	}
+	return signed, nil
+}
+
+// HealNonceGaps fills gaps in nonces of the key, which block its later transactions from being mined, with zero-value
+// self-transfers and waits until they are mined. Gaps are nonces from the pending nonce up to the highest nonce recorded in
+// nonce journal, which the node doesn't have any transaction for (e.g. because they were assigned, but never sent, when the
+// process crashed). Transactions, which node already has, are never replaced, so healing requires nonce journal and it's a
+// no-op without it. Fillers use twice the suggested gas price. Returns healed nonces.
+func (m *Client) HealNonceGaps(ctx context.Context, keyNum int) ([]uint64, error) {
+	if keyNum < 0 || keyNum >= len(m.Addresses) {
+		return nil, errors.Wrapf(ErrNoKeyToHeal, "key: %d", keyNum)
+	}
+	address := m.Addresses[keyNum]
+	healed := make([]uint64, 0)
+	if m.NonceManager == nil || m.NonceManager.Journal == nil {
+		return healed, nil
+	}
+	journalNonce, ok := m.NonceManager.Journal.LastNonce(address)
+	if !ok {
+		return healed, nil
+	}
+	pendingNonce, err := m.Client.PendingNonceAt(ctx, address)
+	if err != nil {
+		return nil, wrapError(err, ErrNonce)
+	}
+	if pendingNonce > journalNonce {
+		return healed, nil
+	}
+	journaled := make(map[uint64][]common.Hash)
+	for _, entry := range m.NonceManager.Journal.Entries(address) {
+		journaled[entry.Nonce] = append(journaled[entry.Nonce], entry.TxHash)
+	}
+	gaps := make([]uint64, 0)
+	for nonce := pendingNonce; nonce <= journalNonce; nonce++ {
+		known, err := m.hasAnyTransaction(ctx, journaled[nonce])
+		if err != nil {
+			return healed, errors.Wrapf(wrapError(err, ErrHealNonceGap), "key: %d, nonce: %d", keyNum, nonce)
+		}
+		if !known {
+			gaps = append(gaps, nonce)
+		}
+	}
+	if len(gaps) == 0 {
+		return healed, nil
+	}
+
+	gasPrice, err := m.GetSuggestedLegacyFees(ctx, Priority_Fast)
+	if err != nil {
+		gasPrice = big.NewInt(m.Cfg.Network.GasPrice)
+	}
+	gasPrice = new(big.Int).Mul(gasPrice, big.NewInt(healGasPriceBumpRatio))
+
+	m.logger().Warn().
+		Int("KeyNum", keyNum).
+		Str("Address", address.Hex()).
+		Uint64("PendingNonce", pendingNonce).
+		Uint64("JournalNonce", journalNonce).
+		Interface("Gaps", gaps).
+		Msg("Healing nonce gaps with self-transfers")
+
+	var lastTx *types.Transaction
+	for _, nonce := range gaps {
+		tx, err := m.Signer.SignTx(ctx, address, types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			To:       &address,
+			Value:    big.NewInt(0),
+			Gas:      selfTransferGasLimit,
+			GasPrice: gasPrice,
+		}))
+		if err != nil {
+			return healed, errors.Wrapf(wrapError(err, ErrHealNonceGap), "key: %d, nonce: %d", keyNum, nonce)
+		}
+		if err := m.Client.SendTransaction(ctx, tx); err != nil {
+			return healed, errors.Wrapf(wrapError(err, ErrHealNonceGap), "key: %d, nonce: %d", keyNum, nonce)
+		}
+		healed = append(healed, nonce)
+		lastTx = tx
+	}
+
+	l := m.logger().With().Str("Transaction", lastTx.Hash().Hex()).Logger()
+	if _, err := m.WaitMined(ctx, l, m.Client, lastTx); err != nil {
+		return healed, errors.Wrapf(wrapError(err, ErrHealNonceGap), "key: %d, nonce: %d", keyNum, lastTx.Nonce())
+	}
+	if err := m.NonceManager.ReconcileNonce(ctx, address); err != nil {
+		return healed, err
+	}
+	return healed, nil
+}
+
+// hasAnyTransaction returns true if node has (pending or mined) any of the transactions
+func (m *Client) hasAnyTransaction(ctx context.Context, hashes []common.Hash) (bool, error) {
+	for _, hash := range hashes {
+		_, _, err := m.Client.TransactionByHash(ctx, hash)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return false, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,87 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIHealNonceGaps(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	journalPath := filepath.Join(t.TempDir(), "nonce_journal.jsonl")
+	cfg.NonceManager.JournalPath = journalPath
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	t.Cleanup(func() {
+		_ = c.Close()
+	})
+
+	ctx := context.Background()
+	address := c.Addresses[0]
+	pendingNonce, err := c.Client.PendingNonceAt(ctx, address)
+	require.NoError(t, err, "failed to get pending nonce")
+
+	// skipping one nonce leaves the transaction queued forever
+	gapNonce := pendingNonce + 1
+	tx, err := TestEnv.DebugContract.Set(c.NewTXOpts(seth.WithNonce(new(big.Int).SetUint64(gapNonce))), big.NewInt(1))
+	require.NoError(t, err, "failed to send transaction with a gap")
+
+	entries := c.NonceManager.Journal.Entries(address)
+	require.NotEmpty(t, entries, "signed transaction should be journaled")
+	require.Equal(t, gapNonce, entries[len(entries)-1].Nonce, "journaled nonce should match")
+	require.Equal(t, tx.Hash(), entries[len(entries)-1].TxHash, "journaled hash should match")
+
+	healed, err := c.HealNonceGaps(ctx, 0)
+	require.NoError(t, err, "failed to heal nonce gaps")
+	require.Equal(t, []uint64{pendingNonce}, healed, "only the gap should be healed")
+	// queued transaction isn't replaced, it's mined once the gap is filled
+	_, err = c.WaitMined(ctx, seth.L, c.Client, tx)
+	require.NoError(t, err, "queued transaction should be mined")
+
+	healed, err = c.HealNonceGaps(ctx, 0)
+	require.NoError(t, err, "failed to heal nonce gaps")
+	require.Empty(t, healed, "there should be no gaps left")
+
+	// journal survives restarts
+	require.NoError(t, c.Close(), "failed to close client")
+	journal, err := seth.OpenFileNonceJournal(journalPath)
+	require.NoError(t, err, "failed to open nonce journal")
+	defer func() {
+		_ = journal.Close()
+	}()
+	lastNonce, ok := journal.LastNonce(address)
+	require.True(t, ok, "journal should contain nonces of the key")
+	require.Equal(t, gapNonce, lastNonce, "last nonce should be the one of queued transaction")
+
+	_, err = c.HealNonceGaps(ctx, len(c.Addresses))
+	require.Error(t, err, "key number should be validated")
+}
+
+func TestAPIHealNonceGapsKeepsPendingTransactions(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.NonceManager.JournalPath = filepath.Join(t.TempDir(), "nonce_journal.jsonl")
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	t.Cleanup(func() {
+		_ = c.Close()
+	})
+
+	ctx := context.Background()
+	// transaction isn't awaited, so it's still pending, there's no gap before it
+	tx, err := TestEnv.DebugContract.Set(c.NewTXOpts(), big.NewInt(1))
+	require.NoError(t, err, "failed to send transaction")
+
+	healed, err := c.HealNonceGaps(ctx, 0)
+	require.NoError(t, err, "failed to heal nonce gaps")
+	require.Empty(t, healed, "nothing should be sent, when there's no gap")
+
+	receipt, err := c.WaitMined(ctx, seth.L, c.Client, tx)
+	require.NoError(t, err, "pending transaction should be mined")
+	require.Equal(t, tx.Hash(), receipt.TxHash, "pending transaction shouldn't be replaced")
+}
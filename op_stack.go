@@ -0,0 +1,194 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	// L1FeeCacheSize is the number of mined transactions, whose L1 data fee is cached, fees of the oldest ones are dropped
+	L1FeeCacheSize = 1024
+
+	gasPriceOracleABIJSON = `[
+		{"type":"function","name":"getL1Fee","stateMutability":"view","inputs":[{"name":"_data","type":"bytes"}],"outputs":[{"name":"","type":"uint256"}]}
+	]`
+)
+
+var (
+	ErrL1Fee = errors.New("failed to get L1 data fee from GasPriceOracle")
+)
+
+// GasPriceOracleAddress is the address of GasPriceOracle predeploy of OP-stack chains (Optimism, Base), which returns L1
+// data fee of a transaction
+var GasPriceOracleAddress = common.HexToAddress("0x420000000000000000000000000000000000000F")
+
+var gasPriceOracleABI = mustParseABI(gasPriceOracleABIJSON)
+
+// L1FeeOracle queries L1 data fee, which OP-stack chains charge on top of L2 execution fee for posting transaction's data
+// to L1. Fees of the latest L1FeeCacheSize mined transactions are cached by hash, so that the fee isn't queried again, when
+// the same receipt is decoded, traced and counted as spent. It's safe for concurrent use.
+type L1FeeOracle struct {
+	caller ethereum.ContractCaller
+	mu     *sync.Mutex
+	fees   map[common.Hash]*big.Int
+	// order are hashes from the oldest cached fee, used for eviction
+	order []common.Hash
+}
+
+// NewL1FeeOracle creates a new L1 fee oracle
+func NewL1FeeOracle(caller ethereum.ContractCaller) *L1FeeOracle {
+	return &L1FeeOracle{
+		caller: caller,
+		mu:     &sync.Mutex{},
+		fees:   make(map[common.Hash]*big.Int),
+	}
+}
+
+// L1Fee returns L1 data fee of unsigned serialized transaction at given block, nil block number means the latest block
+func (o *L1FeeOracle) L1Fee(ctx context.Context, unsignedTx []byte, blockNumber *big.Int) (*big.Int, error) {
+	data, err := gasPriceOracleABI.Pack("getL1Fee", unsignedTx)
+	if err != nil {
+		return nil, err
+	}
+	out, err := o.caller.CallContract(ctx, ethereum.CallMsg{To: &GasPriceOracleAddress, Data: data}, blockNumber)
+	if err != nil {
+		return nil, wrapError(err, ErrL1Fee)
+	}
+	unpacked, err := gasPriceOracleABI.Unpack("getL1Fee", out)
+	if err != nil {
+		return nil, wrapError(err, ErrL1Fee)
+	}
+	return unpacked[0].(*big.Int), nil
+}
+
+// TransactionL1Fee returns L1 data fee of the transaction. Fee of mined transaction is queried at the block it was mined
+// in and cached, fee of transaction that isn't mined yet (nil receipt) is estimated at the latest block.
+func (o *L1FeeOracle) TransactionL1Fee(ctx context.Context, tx *types.Transaction, receipt *types.Receipt) (*big.Int, error) {
+	if receipt == nil {
+		return o.l1Fee(ctx, tx, nil)
+	}
+	o.mu.Lock()
+	fee, ok := o.fees[tx.Hash()]
+	o.mu.Unlock()
+	if ok {
+		return new(big.Int).Set(fee), nil
+	}
+
+	fee, err := o.l1Fee(ctx, tx, receipt.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	o.cacheFee(tx.Hash(), fee)
+	return new(big.Int).Set(fee), nil
+}
+
+// cacheFee caches fee of mined transaction and evicts the oldest ones, when the cache is full
+func (o *L1FeeOracle) cacheFee(hash common.Hash, fee *big.Int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.fees[hash]; !ok {
+		o.order = append(o.order, hash)
+	}
+	o.fees[hash] = fee
+	for len(o.fees) > L1FeeCacheSize {
+		delete(o.fees, o.order[0])
+		o.order = o.order[1:]
+	}
+}
+
+func (o *L1FeeOracle) l1Fee(ctx context.Context, tx *types.Transaction, blockNumber *big.Int) (*big.Int, error) {
+	unsignedTx, err := UnsignedTransactionBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+	return o.L1Fee(ctx, unsignedTx, blockNumber)
+}
+
+// UnsignedTransactionBytes returns transaction serialized without signature, which is what GasPriceOracle expects (it
+// adds the size of the signature itself)
+func UnsignedTransactionBytes(tx *types.Transaction) ([]byte, error) {
+	var unsigned types.TxData
+	switch tx.Type() {
+	case types.LegacyTxType:
+		unsigned = &types.LegacyTx{
+			Nonce:    tx.Nonce(),
+			GasPrice: tx.GasPrice(),
+			Gas:      tx.Gas(),
+			To:       tx.To(),
+			Value:    tx.Value(),
+			Data:     tx.Data(),
+		}
+	case types.AccessListTxType:
+		unsigned = &types.AccessListTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      tx.Nonce(),
+			GasPrice:   tx.GasPrice(),
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		}
+	case types.DynamicFeeTxType:
+		unsigned = &types.DynamicFeeTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      tx.Nonce(),
+			GasTipCap:  tx.GasTipCap(),
+			GasFeeCap:  tx.GasFeeCap(),
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		}
+	default:
+		// other types aren't sent to OP-stack chains, signed transaction slightly overestimates the fee
+		return tx.MarshalBinary()
+	}
+	return types.NewTx(unsigned).MarshalBinary()
+}
+
+// l1Fee returns L1 data fee of the transaction or nil, if network isn't an OP-stack chain or the fee couldn't be queried
+func (m *Client) l1Fee(ctx context.Context, tx *types.Transaction, receipt *types.Receipt) *big.Int {
+	if m.L1FeeOracle == nil || tx == nil {
+		return nil
+	}
+	fee, err := m.L1FeeOracle.TransactionL1Fee(ctx, tx, receipt)
+	if err != nil {
+		m.logger().Warn().
+			Err(err).
+			Str("Transaction", tx.Hash().Hex()).
+			Msg("Failed to get L1 data fee of transaction, cost won't include it")
+		return nil
+	}
+	return fee
+}
+
+// estimateL1Fee returns L1 data fee of a transaction with given fields and gas prices from the config, which isn't signed
+// yet, or 0, if network isn't an OP-stack chain
+func (m *Client) estimateL1Fee(ctx context.Context, to *common.Address, value *big.Int, data []byte, gasLimit uint64) (*big.Int, error) {
+	if m.L1FeeOracle == nil {
+		return big.NewInt(0), nil
+	}
+	var tx *types.Transaction
+	if m.Cfg.Network.EIP1559DynamicFees {
+		tx = types.NewTx(&types.DynamicFeeTx{
+			ChainID:   big.NewInt(m.ChainID),
+			GasTipCap: big.NewInt(m.Cfg.Network.GasTipCap),
+			GasFeeCap: big.NewInt(m.Cfg.Network.GasFeeCap),
+			Gas:       gasLimit,
+			To:        to,
+			Value:     value,
+			Data:      data,
+		})
+	} else {
+		tx = types.NewTx(&types.LegacyTx{GasPrice: big.NewInt(m.Cfg.Network.GasPrice), Gas: gasLimit, To: to, Value: value, Data: data})
+	}
+	return m.L1FeeOracle.l1Fee(ctx, tx, nil)
+}
@@ -0,0 +1,86 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+// gasPriceOracleStub returns the same L1 fee for every call and counts calls
+type gasPriceOracleStub struct {
+	fee   *big.Int
+	calls int
+}
+
+func (s *gasPriceOracleStub) CallContract(_ context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	s.calls++
+	if msg.To == nil || *msg.To != seth.GasPriceOracleAddress {
+		return nil, nil
+	}
+	return math.U256Bytes(new(big.Int).Set(s.fee)), nil
+}
+
+func TestUtilL1FeeOracle(t *testing.T) {
+	pk, err := crypto.GenerateKey()
+	require.NoError(t, err, "failed to generate key")
+	to := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	signer := types.LatestSignerForChainID(big.NewInt(10))
+	tx, err := types.SignNewTx(pk, signer, &types.DynamicFeeTx{
+		ChainID:   big.NewInt(10),
+		Nonce:     3,
+		GasTipCap: big.NewInt(1_000_000),
+		GasFeeCap: big.NewInt(100_000_000),
+		Gas:       21_000,
+		To:        &to,
+		Value:     big.NewInt(1000),
+	})
+	require.NoError(t, err, "failed to sign transaction")
+
+	unsigned, err := seth.UnsignedTransactionBytes(tx)
+	require.NoError(t, err, "failed to serialize transaction")
+	signed, err := tx.MarshalBinary()
+	require.NoError(t, err, "failed to serialize transaction")
+	require.Less(t, len(unsigned), len(signed), "signature shouldn't be serialized")
+	var decoded types.Transaction
+	require.NoError(t, decoded.UnmarshalBinary(unsigned), "unsigned transaction should be decodable")
+	require.Equal(t, tx.Nonce(), decoded.Nonce(), "nonce should be kept")
+	require.Equal(t, tx.Value(), decoded.Value(), "value should be kept")
+
+	stub := &gasPriceOracleStub{fee: big.NewInt(5000)}
+	oracle := seth.NewL1FeeOracle(stub)
+	receipt := &types.Receipt{
+		Status:            types.ReceiptStatusSuccessful,
+		GasUsed:           21_000,
+		EffectiveGasPrice: big.NewInt(10),
+		BlockNumber:       big.NewInt(100),
+	}
+	for i := 0; i < 2; i++ {
+		fee, err := oracle.TransactionL1Fee(context.Background(), tx, receipt)
+		require.NoError(t, err, "failed to get L1 fee")
+		require.Equal(t, "5000", fee.String(), "incorrect L1 fee")
+	}
+	require.Equal(t, 1, stub.calls, "fee of mined transaction should be cached")
+
+	for nonce := uint64(0); nonce < seth.L1FeeCacheSize; nonce++ {
+		_, err := oracle.TransactionL1Fee(context.Background(), types.NewTx(&types.LegacyTx{Nonce: nonce, To: &to}), receipt)
+		require.NoError(t, err, "failed to get L1 fee")
+	}
+	_, err = oracle.TransactionL1Fee(context.Background(), tx, receipt)
+	require.NoError(t, err, "failed to get L1 fee")
+	require.Equal(t, seth.L1FeeCacheSize+2, stub.calls, "fee of the oldest transaction should be dropped from the cache")
+
+	tracker := seth.NewSpendingTracker()
+	require.NoError(t, tracker.AddTransactionWithL1Fee(tx, receipt, big.NewInt(5000)), "failed to add transaction")
+	report := tracker.Report()
+	require.Equal(t, "215000", report.Fees.String(), "fees should include L1 fee")
+	require.Equal(t, "5000", report.L1Fees.String(), "incorrect L1 fees")
+	require.Equal(t, "216000", report.Total.String(), "total should include L1 fee")
+}
@@ -0,0 +1,504 @@
+package seth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultEntryPoint is the address of ERC-4337 EntryPoint v0.6, which is deployed at the same address on all chains
+	DefaultEntryPoint = "0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"
+
+	DefaultUserOperationPollingInterval = 1 * time.Second
+
+	entryPointABIJSON = `[
+		{"type":"function","name":"getNonce","stateMutability":"view","inputs":[{"name":"sender","type":"address"},{"name":"key","type":"uint192"}],"outputs":[{"name":"nonce","type":"uint256"}]},
+		{"type":"function","name":"handleOps","stateMutability":"nonpayable","inputs":[{"name":"ops","type":"tuple[]","components":[
+			{"name":"sender","type":"address"},{"name":"nonce","type":"uint256"},{"name":"initCode","type":"bytes"},{"name":"callData","type":"bytes"},
+			{"name":"callGasLimit","type":"uint256"},{"name":"verificationGasLimit","type":"uint256"},{"name":"preVerificationGas","type":"uint256"},
+			{"name":"maxFeePerGas","type":"uint256"},{"name":"maxPriorityFeePerGas","type":"uint256"},{"name":"paymasterAndData","type":"bytes"},
+			{"name":"signature","type":"bytes"}]},{"name":"beneficiary","type":"address"}],"outputs":[]}
+	]`
+	smartAccountABIJSON = `[
+		{"type":"function","name":"execute","stateMutability":"nonpayable","inputs":[{"name":"dest","type":"address"},{"name":"value","type":"uint256"},{"name":"func","type":"bytes"}],"outputs":[]},
+		{"type":"function","name":"getAddress","stateMutability":"view","inputs":[{"name":"owner","type":"address"},{"name":"salt","type":"uint256"}],"outputs":[{"name":"","type":"address"}]},
+		{"type":"function","name":"createAccount","stateMutability":"nonpayable","inputs":[{"name":"owner","type":"address"},{"name":"salt","type":"uint256"}],"outputs":[{"name":"ret","type":"address"}]}
+	]`
+)
+
+var (
+	ErrPaymasterNotConfigured   = errors.New("paymaster is not configured, set [network.paymaster] in config")
+	ErrUserOperationNotIncluded = errors.New("user operation was not included before timeout")
+	ErrUserOperationFailed      = errors.New("user operation failed")
+	ErrPaymasterContractCreate  = errors.New("contract creation can't be sent through paymaster")
+	ErrNoSmartAccount           = errors.New("no smart account for key")
+)
+
+var (
+	entryPointABI   = mustParseABI(entryPointABIJSON)
+	smartAccountABI = mustParseABI(smartAccountABIJSON)
+
+	// dummySignature is used for gas estimation and sponsoring, before user operation is signed
+	dummySignature = hexutil.MustDecode("0xfffffffffffffffffffffffffffffff0000000000000000000000000000000007aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c")
+)
+
+// PaymasterCfg configures sending of transactions as ERC-4337 user operations, whose gas is paid by a paymaster, so that
+// keys don't need native tokens. Each key owns a smart account (e.g. SimpleAccount), which executes the calls.
+type PaymasterCfg struct {
+	// BundlerURL is the URL of ERC-4337 bundler RPC
+	BundlerURL string `toml:"bundler_url_secret"`
+	// EntryPoint is the address of EntryPoint v0.6 contract, default is the canonical deployment
+	EntryPoint string `toml:"entry_point"`
+	// Paymaster and PaymasterData are used as paymasterAndData of user operations, unless SponsorWithRPC is enabled
+	Paymaster     string `toml:"paymaster"`
+	PaymasterData string `toml:"paymaster_data"`
+	// SponsorWithRPC enables fetching paymasterAndData and gas limits with pm_sponsorUserOperation from the bundler RPC
+	SponsorWithRPC bool `toml:"sponsor_with_rpc"`
+	// SponsorContext is passed as the last param of pm_sponsorUserOperation (e.g. sponsorship policy)
+	SponsorContext map[string]interface{} `toml:"sponsor_context"`
+	// Accounts are smart account addresses, one per key (in the same order as private keys)
+	Accounts []string `toml:"accounts"`
+	// AccountFactory is used to compute smart account addresses (getAddress(owner, salt)) of keys without an account in
+	// Accounts and to deploy them (createAccount(owner, salt)) with the first user operation
+	AccountFactory string `toml:"account_factory"`
+	AccountSalt    int64  `toml:"account_salt"`
+}
+
+func validatePaymasterCfg(cfg *PaymasterCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.BundlerURL == "" {
+		return errors.New("paymaster 'bundler_url_secret' must be set")
+	}
+	for name, addr := range map[string]string{"entry_point": cfg.EntryPoint, "paymaster": cfg.Paymaster, "account_factory": cfg.AccountFactory} {
+		if addr != "" && !common.IsHexAddress(addr) {
+			return fmt.Errorf("paymaster '%s' is not a valid address: %s", name, addr)
+		}
+	}
+	for _, addr := range cfg.Accounts {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("paymaster account is not a valid address: %s", addr)
+		}
+	}
+	if cfg.PaymasterData != "" {
+		if cfg.Paymaster == "" {
+			return errors.New("paymaster 'paymaster_data' is set, but 'paymaster' is not")
+		}
+		if _, err := hexutil.Decode(cfg.PaymasterData); err != nil {
+			return fmt.Errorf("paymaster 'paymaster_data' is not valid 0x-prefixed hex: %s", cfg.PaymasterData)
+		}
+	}
+	if cfg.Paymaster == "" && !cfg.SponsorWithRPC {
+		return errors.New("either paymaster 'paymaster' must be set or 'sponsor_with_rpc' enabled")
+	}
+	if len(cfg.Accounts) == 0 && cfg.AccountFactory == "" {
+		return errors.New("either paymaster 'accounts' or 'account_factory' must be set")
+	}
+	return nil
+}
+
+// UserOperation is ERC-4337 (EntryPoint v0.6) user operation
+type UserOperation struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+// Paymaster returns address of the paymaster, that pays for the operation, or zero address if it's self-funded
+func (u *UserOperation) Paymaster() common.Address {
+	if len(u.PaymasterAndData) < common.AddressLength {
+		return common.Address{}
+	}
+	return common.BytesToAddress(u.PaymasterAndData[:common.AddressLength])
+}
+
+// PaymasterData returns paymaster-specific data (e.g. validity window and paymaster's signature)
+func (u *UserOperation) PaymasterData() []byte {
+	if len(u.PaymasterAndData) <= common.AddressLength {
+		return nil
+	}
+	return u.PaymasterAndData[common.AddressLength:]
+}
+
+// Hash returns user operation hash, which is signed by smart account's owner
+func (u *UserOperation) Hash(entryPoint common.Address, chainID *big.Int) common.Hash {
+	uint256Ty, _ := abi.NewType("uint256", "", nil)
+	addressTy, _ := abi.NewType("address", "", nil)
+	bytes32Ty, _ := abi.NewType("bytes32", "", nil)
+
+	packed, _ := abi.Arguments{
+		{Type: addressTy}, {Type: uint256Ty}, {Type: bytes32Ty}, {Type: bytes32Ty}, {Type: uint256Ty},
+		{Type: uint256Ty}, {Type: uint256Ty}, {Type: uint256Ty}, {Type: uint256Ty}, {Type: bytes32Ty},
+	}.Pack(
+		u.Sender, u.Nonce, crypto.Keccak256Hash(u.InitCode), crypto.Keccak256Hash(u.CallData), u.CallGasLimit,
+		u.VerificationGasLimit, u.PreVerificationGas, u.MaxFeePerGas, u.MaxPriorityFeePerGas, crypto.Keccak256Hash(u.PaymasterAndData),
+	)
+	encoded, _ := abi.Arguments{{Type: bytes32Ty}, {Type: addressTy}, {Type: uint256Ty}}.Pack(crypto.Keccak256Hash(packed), entryPoint, chainID)
+	return crypto.Keccak256Hash(encoded)
+}
+
+// Sign signs user operation hash as EIP-191 personal message, which is what SimpleAccount and most other accounts expect
+func (u *UserOperation) Sign(key *ecdsa.PrivateKey, entryPoint common.Address, chainID *big.Int) error {
+	hash := u.Hash(entryPoint, chainID)
+	sig, err := crypto.Sign(accounts.TextHash(hash.Bytes()), key)
+	if err != nil {
+		return err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	u.Signature = sig
+	return nil
+}
+
+// toRPC returns user operation in the format used by bundler RPC (all fields hex encoded)
+func (u *UserOperation) toRPC() map[string]interface{} {
+	hexBig := func(v *big.Int) *hexutil.Big {
+		if v == nil {
+			return (*hexutil.Big)(big.NewInt(0))
+		}
+		return (*hexutil.Big)(v)
+	}
+	return map[string]interface{}{
+		"sender":               u.Sender,
+		"nonce":                hexBig(u.Nonce),
+		"initCode":             hexutil.Bytes(u.InitCode),
+		"callData":             hexutil.Bytes(u.CallData),
+		"callGasLimit":         hexBig(u.CallGasLimit),
+		"verificationGasLimit": hexBig(u.VerificationGasLimit),
+		"preVerificationGas":   hexBig(u.PreVerificationGas),
+		"maxFeePerGas":         hexBig(u.MaxFeePerGas),
+		"maxPriorityFeePerGas": hexBig(u.MaxPriorityFeePerGas),
+		"paymasterAndData":     hexutil.Bytes(u.PaymasterAndData),
+		"signature":            hexutil.Bytes(u.Signature),
+	}
+}
+
+// userOperationGas is returned by eth_estimateUserOperationGas and pm_sponsorUserOperation
+type userOperationGas struct {
+	PaymasterAndData     *hexutil.Bytes `json:"paymasterAndData"`
+	CallGasLimit         *hexutil.Big   `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big   `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big   `json:"preVerificationGas"`
+}
+
+func (g userOperationGas) apply(u *UserOperation) {
+	if g.PaymasterAndData != nil {
+		u.PaymasterAndData = *g.PaymasterAndData
+	}
+	if g.CallGasLimit != nil {
+		u.CallGasLimit = g.CallGasLimit.ToInt()
+	}
+	if g.VerificationGasLimit != nil {
+		u.VerificationGasLimit = g.VerificationGasLimit.ToInt()
+	}
+	if g.PreVerificationGas != nil {
+		u.PreVerificationGas = g.PreVerificationGas.ToInt()
+	}
+}
+
+// UserOperationReceipt is the result of user operation returned by the bundler
+type UserOperationReceipt struct {
+	UserOpHash    common.Hash    `json:"userOpHash"`
+	Sender        common.Address `json:"sender"`
+	Paymaster     common.Address `json:"paymaster"`
+	ActualGasCost *hexutil.Big   `json:"actualGasCost"`
+	ActualGasUsed *hexutil.Big   `json:"actualGasUsed"`
+	Success       bool           `json:"success"`
+	Reason        string         `json:"reason"`
+	Receipt       struct {
+		TransactionHash common.Hash `json:"transactionHash"`
+	} `json:"receipt"`
+	// UserOperation is the operation that was sent
+	UserOperation *UserOperation `json:"-"`
+}
+
+// PaymasterBackend is what paymaster client needs from the node
+type PaymasterBackend interface {
+	bind.ContractCaller
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+}
+
+// PaymasterClient sends calls as user operations through ERC-4337 bundler, with gas paid by a paymaster
+type PaymasterClient struct {
+	Cfg        *PaymasterCfg
+	EntryPoint common.Address
+	ChainID    *big.Int
+	backend    PaymasterBackend
+	bundler    *rpc.Client
+}
+
+// NewPaymasterClient creates a new paymaster client, backend is used to read nonces and smart account addresses and to
+// estimate fees, bundler to estimate gas, sponsor and send user operations
+func NewPaymasterClient(cfg *PaymasterCfg, chainID int64, backend PaymasterBackend, bundler *rpc.Client) (*PaymasterClient, error) {
+	if err := validatePaymasterCfg(cfg); err != nil {
+		return nil, err
+	}
+	entryPoint := common.HexToAddress(DefaultEntryPoint)
+	if cfg.EntryPoint != "" {
+		entryPoint = common.HexToAddress(cfg.EntryPoint)
+	}
+	return &PaymasterClient{
+		Cfg:        cfg,
+		EntryPoint: entryPoint,
+		ChainID:    big.NewInt(chainID),
+		backend:    backend,
+		bundler:    bundler,
+	}, nil
+}
+
+// Close closes connection to the bundler
+func (p *PaymasterClient) Close() {
+	if p.bundler != nil {
+		p.bundler.Close()
+	}
+}
+
+// AccountAddress returns address of smart account owned by the key, either from config or computed by the account factory
+func (p *PaymasterClient) AccountAddress(ctx context.Context, owner common.Address, keyNum int) (common.Address, error) {
+	if keyNum < len(p.Cfg.Accounts) {
+		return common.HexToAddress(p.Cfg.Accounts[keyNum]), nil
+	}
+	if p.Cfg.AccountFactory == "" {
+		return common.Address{}, errors.Wrapf(ErrNoSmartAccount, "key: %d, set 'accounts' or 'account_factory' in paymaster config", keyNum)
+	}
+	var out []interface{}
+	factory := bind.NewBoundContract(common.HexToAddress(p.Cfg.AccountFactory), smartAccountABI, p.backend, nil, nil)
+	if err := factory.Call(&bind.CallOpts{Context: ctx}, &out, "getAddress", owner, big.NewInt(p.Cfg.AccountSalt)); err != nil {
+		return common.Address{}, errors.Wrap(err, "failed to get smart account address from factory")
+	}
+	return *abi.ConvertType(out[0], new(common.Address)).(*common.Address), nil
+}
+
+// BuildUserOperation builds unsigned user operation executing the call from key's smart account, with paymasterAndData and
+// gas limits set either by the sponsor RPC or from config and bundler's estimation
+func (p *PaymasterClient) BuildUserOperation(ctx context.Context, key *ecdsa.PrivateKey, keyNum int, to common.Address, value *big.Int, data []byte) (*UserOperation, error) {
+	owner := crypto.PubkeyToAddress(key.PublicKey)
+	sender, err := p.AccountAddress(ctx, owner, keyNum)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	callData, err := smartAccountABI.Pack("execute", to, value, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to pack smart account call")
+	}
+
+	op := &UserOperation{
+		Sender:    sender,
+		CallData:  callData,
+		Signature: dummySignature,
+	}
+
+	var out []interface{}
+	entryPoint := bind.NewBoundContract(p.EntryPoint, entryPointABI, p.backend, nil, nil)
+	if err := entryPoint.Call(&bind.CallOpts{Context: ctx}, &out, "getNonce", sender, big.NewInt(0)); err != nil {
+		return nil, errors.Wrap(err, "failed to get smart account nonce from entry point")
+	}
+	op.Nonce = *abi.ConvertType(out[0], new(*big.Int)).(**big.Int)
+
+	code, err := p.backend.CodeAt(ctx, sender, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get smart account code")
+	}
+	if len(code) == 0 {
+		if p.Cfg.AccountFactory == "" {
+			return nil, fmt.Errorf("smart account %s is not deployed and no 'account_factory' is set", sender.Hex())
+		}
+		createData, err := smartAccountABI.Pack("createAccount", owner, big.NewInt(p.Cfg.AccountSalt))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to pack account creation call")
+		}
+		op.InitCode = append(common.HexToAddress(p.Cfg.AccountFactory).Bytes(), createData...)
+	}
+
+	header, err := p.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get latest header")
+	}
+	op.MaxPriorityFeePerGas, err = p.backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get suggested gas tip cap")
+	}
+	op.MaxFeePerGas = new(big.Int).Set(op.MaxPriorityFeePerGas)
+	if header.BaseFee != nil {
+		op.MaxFeePerGas.Add(op.MaxFeePerGas, new(big.Int).Mul(header.BaseFee, big.NewInt(2)))
+	}
+
+	var gas userOperationGas
+	if p.Cfg.SponsorWithRPC {
+		if err := p.bundler.CallContext(ctx, &gas, "pm_sponsorUserOperation", op.toRPC(), p.EntryPoint, p.Cfg.SponsorContext); err != nil {
+			return nil, errors.Wrap(err, "failed to sponsor user operation")
+		}
+		gas.apply(op)
+	} else {
+		op.PaymasterAndData = append(common.HexToAddress(p.Cfg.Paymaster).Bytes(), common.FromHex(p.Cfg.PaymasterData)...)
+	}
+	if op.CallGasLimit == nil || op.VerificationGasLimit == nil || op.PreVerificationGas == nil {
+		gas = userOperationGas{}
+		if err := p.bundler.CallContext(ctx, &gas, "eth_estimateUserOperationGas", op.toRPC(), p.EntryPoint); err != nil {
+			return nil, errors.Wrap(err, "failed to estimate user operation gas")
+		}
+		gas.PaymasterAndData = nil
+		gas.apply(op)
+	}
+
+	return op, nil
+}
+
+// SendUserOperation builds, signs and sends user operation executing the call from key's smart account and waits for its
+// receipt. Gas is paid by the paymaster, so neither the key, nor its smart account need native tokens.
+func (p *PaymasterClient) SendUserOperation(ctx context.Context, key *ecdsa.PrivateKey, keyNum int, to common.Address, value *big.Int, data []byte) (*UserOperationReceipt, error) {
+	op, err := p.BuildUserOperation(ctx, key, keyNum, to, value, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := op.Sign(key, p.EntryPoint, p.ChainID); err != nil {
+		return nil, errors.Wrap(err, "failed to sign user operation")
+	}
+
+	var opHash common.Hash
+	if err := p.bundler.CallContext(ctx, &opHash, "eth_sendUserOperation", op.toRPC(), p.EntryPoint); err != nil {
+		return nil, errors.Wrap(err, "failed to send user operation")
+	}
+	L.Debug().
+		Str("User operation", opHash.Hex()).
+		Str("Sender", op.Sender.Hex()).
+		Str("Paymaster", op.Paymaster().Hex()).
+		Msg("Sent user operation")
+
+	receipt, err := p.WaitForUserOperation(ctx, opHash)
+	if err != nil {
+		return nil, err
+	}
+	receipt.UserOperation = op
+	if !receipt.Success {
+		return receipt, errors.Wrapf(ErrUserOperationFailed, "user operation: %s, reason: %s", opHash.Hex(), receipt.Reason)
+	}
+	return receipt, nil
+}
+
+// WaitForUserOperation polls the bundler for user operation receipt, until it's included or context is done
+func (p *PaymasterClient) WaitForUserOperation(ctx context.Context, opHash common.Hash) (*UserOperationReceipt, error) {
+	for {
+		var receipt *UserOperationReceipt
+		if err := p.bundler.CallContext(ctx, &receipt, "eth_getUserOperationReceipt", opHash); err != nil && ctx.Err() == nil {
+			L.Debug().Err(err).Str("User operation", opHash.Hex()).Msg("Failed to get user operation receipt")
+		}
+		if receipt != nil {
+			L.Debug().
+				Str("User operation", opHash.Hex()).
+				Str("Transaction", receipt.Receipt.TransactionHash.Hex()).
+				Bool("Success", receipt.Success).
+				Msg("User operation included")
+			return receipt, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ErrUserOperationNotIncluded, "user operation: %s", opHash.Hex())
+		case <-time.After(DefaultUserOperationPollingInterval):
+		}
+	}
+}
+
+// DecodeHandleOps decodes calldata of EntryPoint's handleOps() call, so that user operations included in a bundle
+// transaction (together with their paymasters) can be inspected
+func DecodeHandleOps(data []byte) ([]UserOperation, common.Address, error) {
+	method, ok := entryPointABI.Methods["handleOps"]
+	if len(data) < 4 || !ok || !bytes.Equal(data[:4], method.ID) {
+		return nil, common.Address{}, errors.New("calldata is not a handleOps() call")
+	}
+	values, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, common.Address{}, errors.Wrap(err, "failed to unpack handleOps() calldata")
+	}
+	ops := *abi.ConvertType(values[0], new([]UserOperation)).(*[]UserOperation)
+	return ops, values[1].(common.Address), nil
+}
+
+// SendUserOperation sends the call as a user operation from smart account of the key, with gas paid by configured paymaster
+func (m *Client) SendUserOperation(ctx context.Context, keyNum int, to common.Address, value *big.Int, data []byte) (*UserOperationReceipt, error) {
+	if m.Paymaster == nil {
+		return nil, ErrPaymasterNotConfigured
+	}
+	if keyNum < 0 || keyNum >= len(m.PrivateKeys) {
+		return nil, errors.Wrap(ErrNoKeyLoaded, fmt.Sprintf("requested key: %d", keyNum))
+	}
+	if m.PrivateKeys[keyNum] == nil {
+		return nil, errors.Wrapf(ErrNoPrivateKey, "key: %d", keyNum)
+	}
+	return m.Paymaster.SendUserOperation(ctx, m.PrivateKeys[keyNum], keyNum, to, value, data)
+}
+
+// SendWithPaymaster calls contract wrapper's method with transaction options, that don't send the transaction, and sends
+// its call as a user operation through the paymaster instead, e.g.:
+// client.SendWithPaymaster(ctx, 0, func(o *bind.TransactOpts) (*types.Transaction, error) { return contract.Set(o, big.NewInt(1)) })
+// Keep in mind, that msg.sender of the call is key's smart account, not the key itself.
+func (m *Client) SendWithPaymaster(ctx context.Context, keyNum int, call func(opts *bind.TransactOpts) (*types.Transaction, error)) (*UserOperationReceipt, error) {
+	if keyNum < 0 || keyNum >= len(m.PrivateKeys) {
+		return nil, errors.Wrap(ErrNoKeyLoaded, fmt.Sprintf("requested key: %d", keyNum))
+	}
+	if m.PrivateKeys[keyNum] == nil {
+		return nil, errors.Wrapf(ErrNoPrivateKey, "key: %d", keyNum)
+	}
+	// nonce, gas price and gas limit are set, so that wrapper doesn't query the node, which would fail for keys without funds
+	opts, err := bind.NewKeyedTransactorWithChainID(m.PrivateKeys[keyNum], big.NewInt(m.ChainID))
+	if err != nil {
+		return nil, err
+	}
+	opts.Context = ctx
+	opts.NoSend = true
+	opts.Nonce = big.NewInt(0)
+	opts.GasPrice = big.NewInt(0)
+	opts.GasLimit = 1
+
+	tx, err := call(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build call")
+	}
+	if tx.To() == nil {
+		return nil, ErrPaymasterContractCreate
+	}
+	return m.SendUserOperation(ctx, keyNum, *tx.To(), tx.Value(), tx.Data())
+}
+
+func newPaymasterClient(ctx context.Context, cfg *PaymasterCfg, chainID int64, backend PaymasterBackend) (*PaymasterClient, error) {
+	bundler, err := rpc.DialContext(ctx, cfg.BundlerURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to bundler")
+	}
+	return NewPaymasterClient(cfg, chainID, backend, bundler)
+}
+
+func mustParseABI(s string) abi.ABI {
+	a, err := abi.JSON(strings.NewReader(s))
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
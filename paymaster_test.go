@@ -0,0 +1,247 @@
+package seth_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+const handleOpsABI = `[{"type":"function","name":"handleOps","inputs":[{"name":"ops","type":"tuple[]","components":[
+	{"name":"sender","type":"address"},{"name":"nonce","type":"uint256"},{"name":"initCode","type":"bytes"},{"name":"callData","type":"bytes"},
+	{"name":"callGasLimit","type":"uint256"},{"name":"verificationGasLimit","type":"uint256"},{"name":"preVerificationGas","type":"uint256"},
+	{"name":"maxFeePerGas","type":"uint256"},{"name":"maxPriorityFeePerGas","type":"uint256"},{"name":"paymasterAndData","type":"bytes"},
+	{"name":"signature","type":"bytes"}]},{"name":"beneficiary","type":"address"}],"outputs":[]}]`
+
+var (
+	testEntryPoint   = common.HexToAddress(seth.DefaultEntryPoint)
+	testSmartAccount = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	testPaymaster    = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+)
+
+// paymasterBackend returns nonce 5 for any account, which is always deployed
+type paymasterBackend struct{}
+
+func (paymasterBackend) CodeAt(_ context.Context, _ common.Address, _ *big.Int) ([]byte, error) {
+	return []byte{0x1}, nil
+}
+
+func (paymasterBackend) CallContract(_ context.Context, _ ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	return common.LeftPadBytes(big.NewInt(5).Bytes(), 32), nil
+}
+
+func (paymasterBackend) HeaderByNumber(_ context.Context, _ *big.Int) (*types.Header, error) {
+	return &types.Header{BaseFee: big.NewInt(100)}, nil
+}
+
+func (paymasterBackend) SuggestGasTipCap(_ context.Context) (*big.Int, error) {
+	return big.NewInt(10), nil
+}
+
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcUserOperation struct {
+	Sender               common.Address `json:"sender"`
+	Nonce                *hexutil.Big   `json:"nonce"`
+	InitCode             hexutil.Bytes  `json:"initCode"`
+	CallData             hexutil.Bytes  `json:"callData"`
+	CallGasLimit         *hexutil.Big   `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big   `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big   `json:"preVerificationGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes  `json:"paymasterAndData"`
+	Signature            hexutil.Bytes  `json:"signature"`
+}
+
+func (o rpcUserOperation) toUserOperation() seth.UserOperation {
+	return seth.UserOperation{
+		Sender: o.Sender, Nonce: o.Nonce.ToInt(), InitCode: o.InitCode, CallData: o.CallData,
+		CallGasLimit: o.CallGasLimit.ToInt(), VerificationGasLimit: o.VerificationGasLimit.ToInt(),
+		PreVerificationGas: o.PreVerificationGas.ToInt(), MaxFeePerGas: o.MaxFeePerGas.ToInt(),
+		MaxPriorityFeePerGas: o.MaxPriorityFeePerGas.ToInt(), PaymasterAndData: o.PaymasterAndData, Signature: o.Signature,
+	}
+}
+
+// newMockBundler returns bundler RPC server, which records received methods and sent user operation
+func newMockBundler(t *testing.T) (*httptest.Server, *[]string, *seth.UserOperation) {
+	mu := &sync.Mutex{}
+	methods := []string{}
+	sent := &seth.UserOperation{}
+	receiptPolls := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req), "invalid RPC request")
+		mu.Lock()
+		defer mu.Unlock()
+		methods = append(methods, req.Method)
+
+		var result interface{}
+		switch req.Method {
+		case "pm_sponsorUserOperation":
+			result = map[string]string{
+				"paymasterAndData":     hexutil.Encode(append(testPaymaster.Bytes(), 0xca, 0xfe)),
+				"callGasLimit":         "0x1000",
+				"verificationGasLimit": "0x2000",
+				"preVerificationGas":   "0x3000",
+			}
+		case "eth_estimateUserOperationGas":
+			result = map[string]string{"callGasLimit": "0x100", "verificationGasLimit": "0x200", "preVerificationGas": "0x300"}
+		case "eth_sendUserOperation":
+			var op rpcUserOperation
+			require.NoError(t, json.Unmarshal(req.Params[0], &op), "invalid user operation")
+			*sent = op.toUserOperation()
+			result = sent.Hash(testEntryPoint, big.NewInt(1337))
+		case "eth_getUserOperationReceipt":
+			receiptPolls++
+			if receiptPolls > 1 {
+				result = map[string]interface{}{
+					"userOpHash":    sent.Hash(testEntryPoint, big.NewInt(1337)),
+					"sender":        sent.Sender,
+					"paymaster":     sent.Paymaster(),
+					"actualGasCost": "0x64",
+					"actualGasUsed": "0x10",
+					"success":       true,
+					"receipt":       map[string]string{"transactionHash": common.HexToHash("0x01").Hex()},
+				}
+			}
+		default:
+			t.Errorf("unexpected RPC method: %s", req.Method)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &methods, sent
+}
+
+func TestUtilPaymasterSendUserOperation(t *testing.T) {
+	type test struct {
+		name             string
+		cfg              seth.PaymasterCfg
+		expectedMethods  []string
+		expectedGasLimit int64
+		expectedData     []byte
+	}
+
+	tests := []test{
+		{
+			name:             "sponsored by RPC",
+			cfg:              seth.PaymasterCfg{SponsorWithRPC: true, Accounts: []string{testSmartAccount.Hex()}},
+			expectedMethods:  []string{"pm_sponsorUserOperation", "eth_sendUserOperation", "eth_getUserOperationReceipt", "eth_getUserOperationReceipt"},
+			expectedGasLimit: 0x1000,
+			expectedData:     []byte{0xca, 0xfe},
+		},
+		{
+			name:             "static paymaster",
+			cfg:              seth.PaymasterCfg{Paymaster: testPaymaster.Hex(), PaymasterData: "0xbeef", Accounts: []string{testSmartAccount.Hex()}},
+			expectedMethods:  []string{"eth_estimateUserOperationGas", "eth_sendUserOperation", "eth_getUserOperationReceipt", "eth_getUserOperationReceipt"},
+			expectedGasLimit: 0x100,
+			expectedData:     []byte{0xbe, 0xef},
+		},
+	}
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err, "failed to generate key")
+	to := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, methods, sent := newMockBundler(t)
+			bundler, err := rpc.Dial(srv.URL)
+			require.NoError(t, err, "failed to connect to bundler")
+
+			tc.cfg.BundlerURL = srv.URL
+			p, err := seth.NewPaymasterClient(&tc.cfg, 1337, paymasterBackend{}, bundler)
+			require.NoError(t, err, "failed to create paymaster client")
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			receipt, err := p.SendUserOperation(ctx, key, 0, to, big.NewInt(7), []byte{0x12, 0x34})
+			require.NoError(t, err, "failed to send user operation")
+
+			require.Equal(t, tc.expectedMethods, *methods, "incorrect bundler calls")
+			require.True(t, receipt.Success, "user operation should be successful")
+			require.Equal(t, testPaymaster, receipt.Paymaster, "incorrect paymaster in receipt")
+			require.Equal(t, int64(100), receipt.ActualGasCost.ToInt().Int64(), "incorrect gas cost")
+
+			require.Equal(t, testSmartAccount, sent.Sender, "incorrect sender")
+			require.Equal(t, int64(5), sent.Nonce.Int64(), "nonce should be read from entry point")
+			require.Equal(t, int64(210), sent.MaxFeePerGas.Int64(), "max fee should be 2x base fee plus tip")
+			require.Equal(t, tc.expectedGasLimit, sent.CallGasLimit.Int64(), "incorrect call gas limit")
+			require.Equal(t, testPaymaster, sent.Paymaster(), "incorrect paymaster")
+			require.Equal(t, tc.expectedData, sent.PaymasterData(), "incorrect paymaster data")
+			require.Empty(t, sent.InitCode, "deployed account should have no init code")
+
+			hash := sent.Hash(testEntryPoint, big.NewInt(1337))
+			sig := append([]byte{}, sent.Signature...)
+			sig[crypto.RecoveryIDOffset] -= 27
+			pub, err := crypto.SigToPub(accounts.TextHash(hash.Bytes()), sig)
+			require.NoError(t, err, "failed to recover signer")
+			require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), crypto.PubkeyToAddress(*pub), "user operation should be signed by the key")
+		})
+	}
+}
+
+func TestUtilPaymasterDecodeHandleOps(t *testing.T) {
+	entryPointAbi, err := abi.JSON(strings.NewReader(handleOpsABI))
+	require.NoError(t, err, "failed to parse ABI")
+
+	op := seth.UserOperation{
+		Sender: testSmartAccount, Nonce: big.NewInt(1), InitCode: []byte{}, CallData: []byte{0x1},
+		CallGasLimit: big.NewInt(2), VerificationGasLimit: big.NewInt(3), PreVerificationGas: big.NewInt(4),
+		MaxFeePerGas: big.NewInt(5), MaxPriorityFeePerGas: big.NewInt(6),
+		PaymasterAndData: append(testPaymaster.Bytes(), 0xaa), Signature: []byte{0x2},
+	}
+	beneficiary := common.HexToAddress("0x00000000000000000000000000000000000000dd")
+	data, err := entryPointAbi.Pack("handleOps", []seth.UserOperation{op}, beneficiary)
+	require.NoError(t, err, "failed to pack handleOps")
+
+	ops, decodedBeneficiary, err := seth.DecodeHandleOps(data)
+	require.NoError(t, err, "failed to decode handleOps")
+	require.Equal(t, beneficiary, decodedBeneficiary, "incorrect beneficiary")
+	require.Len(t, ops, 1, "incorrect number of operations")
+	require.Equal(t, op, ops[0], "incorrect operation")
+	require.Equal(t, testPaymaster, ops[0].Paymaster(), "incorrect paymaster")
+	require.Equal(t, []byte{0xaa}, ops[0].PaymasterData(), "incorrect paymaster data")
+
+	_, _, err = seth.DecodeHandleOps([]byte{0x1, 0x2, 0x3, 0x4})
+	require.Error(t, err, "other calldata should not be decoded")
+}
+
+func TestConfigPaymasterValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	cfg.Network.Paymaster = &seth.PaymasterCfg{BundlerURL: "http://localhost:4337", Paymaster: testPaymaster.Hex()}
+	err = seth.ValidateConfig(cfg)
+	require.Error(t, err, "paymaster without accounts should be invalid")
+	require.Contains(t, err.Error(), "'accounts' or 'account_factory'", "incorrect error")
+
+	cfg.Network.Paymaster = &seth.PaymasterCfg{BundlerURL: "http://localhost:4337", Accounts: []string{testSmartAccount.Hex()}}
+	require.Error(t, seth.ValidateConfig(cfg), "paymaster without paymaster address or sponsor RPC should be invalid")
+
+	cfg.Network.Paymaster = &seth.PaymasterCfg{BundlerURL: "http://localhost:4337", SponsorWithRPC: true, AccountFactory: testSmartAccount.Hex()}
+	require.NoError(t, seth.ValidateConfig(cfg), "paymaster config should be valid")
+}
@@ -0,0 +1,160 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+var (
+	// EIP1967ImplementationSlot is bytes32(uint256(keccak256('eip1967.proxy.implementation')) - 1)
+	EIP1967ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	// EIP1967BeaconSlot is bytes32(uint256(keccak256('eip1967.proxy.beacon')) - 1)
+	EIP1967BeaconSlot = common.HexToHash("0xa3f0ad74e5423aebfd80d3ef4346578335a9a72aeaee59ff6cb3582b35133d50")
+
+	// beaconImplementationSelector is selector of beacon's implementation() method
+	beaconImplementationSelector = common.FromHex("0x5c60da1b")
+)
+
+var (
+	ErrReadProxySlot            = errors.New("failed to read EIP-1967 slot")
+	ErrReadBeaconImplementation = errors.New("failed to read implementation from beacon")
+)
+
+// ProxyBackend is the part of the RPC client needed to detect proxies, it's implemented by ethclient.Client
+type ProxyBackend interface {
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// ProxyDetector finds implementations of EIP-1967 proxies, either read directly from the implementation slot or from
+// the beacon, whose address is in the beacon slot, and facets of EIP-2535 diamonds. Addresses, which aren't proxies,
+// are remembered and not queried again, but implementations of proxies are read every time, as proxies might be upgraded.
+type ProxyDetector struct {
+	backend     ProxyBackend
+	mu          sync.Mutex
+	notProxies  map[common.Address]struct{}
+	notDiamonds map[common.Address]struct{}
+	facets      map[common.Address]map[[4]byte]common.Address
+}
+
+// NewProxyDetector creates proxy detector, which reads proxies' storage with the backend
+func NewProxyDetector(backend ProxyBackend) *ProxyDetector {
+	return &ProxyDetector{
+		backend:     backend,
+		notProxies:  make(map[common.Address]struct{}),
+		notDiamonds: make(map[common.Address]struct{}),
+		facets:      make(map[common.Address]map[[4]byte]common.Address),
+	}
+}
+
+// Implementation returns address of the current implementation of the proxy or zero address, if the contract isn't
+// an EIP-1967 proxy
+func (p *ProxyDetector) Implementation(ctx context.Context, proxy common.Address) (common.Address, error) {
+	p.mu.Lock()
+	_, notProxy := p.notProxies[proxy]
+	p.mu.Unlock()
+	if notProxy {
+		return common.Address{}, nil
+	}
+
+	implementation, err := p.readAddressSlot(ctx, proxy, EIP1967ImplementationSlot)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if implementation == (common.Address{}) {
+		beacon, err := p.readAddressSlot(ctx, proxy, EIP1967BeaconSlot)
+		if err != nil {
+			return common.Address{}, err
+		}
+		if beacon != (common.Address{}) {
+			implementation, err = p.beaconImplementation(ctx, beacon)
+			if err != nil {
+				return common.Address{}, err
+			}
+		}
+	}
+
+	if implementation == (common.Address{}) {
+		p.mu.Lock()
+		p.notProxies[proxy] = struct{}{}
+		p.mu.Unlock()
+	}
+
+	return implementation, nil
+}
+
+func (p *ProxyDetector) readAddressSlot(ctx context.Context, account common.Address, slot common.Hash) (common.Address, error) {
+	value, err := p.backend.StorageAt(ctx, account, slot, nil)
+	if err != nil {
+		return common.Address{}, errors.Wrapf(wrapError(err, ErrReadProxySlot), "account: %s", account.Hex())
+	}
+	return common.BytesToAddress(value), nil
+}
+
+func (p *ProxyDetector) beaconImplementation(ctx context.Context, beacon common.Address) (common.Address, error) {
+	output, err := p.backend.CallContract(ctx, ethereum.CallMsg{To: &beacon, Data: beaconImplementationSelector}, nil)
+	if err != nil {
+		return common.Address{}, errors.Wrapf(wrapError(err, ErrReadBeaconImplementation), "beacon: %s", beacon.Hex())
+	}
+	if len(output) != common.HashLength {
+		return common.Address{}, errors.Wrapf(wrapError(errors.Errorf("unexpected output length %d", len(output)), ErrReadBeaconImplementation), "beacon: %s", beacon.Hex())
+	}
+	return common.BytesToAddress(output), nil
+}
+
+// findProxiedABIByMethod finds the method in the ABI of the implementation (or the facet), if the contract at the address
+// is a proxy (or a diamond). Proxy's address is never mapped to the name of the implementation in the contract map.
+func (a *ABIFinder) findProxiedABIByMethod(address string, signature []byte) (ABIFinderResult, bool) {
+	if a.Proxies == nil || !common.IsHexAddress(address) {
+		return ABIFinderResult{}, false
+	}
+	implementation, err := a.Proxies.Target(context.Background(), common.HexToAddress(address), signature)
+	if err != nil {
+		L.Debug().Err(err).Str("Address", address).Msg("Failed to check if contract is a proxy")
+		return ABIFinderResult{}, false
+	}
+	if implementation == (common.Address{}) || strings.EqualFold(implementation.Hex(), address) {
+		return ABIFinderResult{}, false
+	}
+
+	// implementation itself isn't checked for being a proxy, which also prevents loops
+	result, err := a.findABIByMethod(implementation.Hex(), signature, false)
+	if err != nil {
+		return ABIFinderResult{}, false
+	}
+
+	L.Debug().
+		Str("Proxy", address).
+		Str("Implementation", implementation.Hex()).
+		Str("Contract", result.ContractName()).
+		Msg("Found method in ABI of proxy's implementation")
+
+	result.ProxyAddress = address
+	result.Implementation = implementation
+	return result, true
+}
+
+// attributeProxyCall marks call decoded with ABI of the implementation of a proxy (or diamond's facet). Call to the proxy
+// is kept under proxy's name and delegatecall made by the proxy to its implementation is attributed to the proxy, since it
+// executes in its context.
+func (t *Tracer) attributeProxyCall(call *DecodedCall, rawCall Call, abiResult ABIFinderResult) {
+	if abiResult.ProxyAddress != "" {
+		call.Implementation = t.getHumanReadableAddressName(abiResult.Implementation.Hex())
+		return
+	}
+	if rawCall.Type != "DELEGATECALL" || t.ABIFinder == nil || t.ABIFinder.Proxies == nil || !common.IsHexAddress(rawCall.From) {
+		return
+	}
+	implementation, err := t.ABIFinder.Proxies.Target(context.Background(), common.HexToAddress(rawCall.From), abiResult.Method.ID)
+	if err != nil || !strings.EqualFold(implementation.Hex(), rawCall.To) {
+		return
+	}
+	call.Implementation = call.To
+	call.To = t.getHumanReadableAddressName(rawCall.From)
+}
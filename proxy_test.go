@@ -0,0 +1,79 @@
+package seth_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+type proxyBackendMock struct {
+	storage map[common.Address]map[common.Hash]common.Address
+	beacons map[common.Address]common.Address
+	facets  map[common.Address][]seth.DiamondFacet
+	reads   int
+	calls   int
+}
+
+func (p *proxyBackendMock) StorageAt(_ context.Context, account common.Address, key common.Hash, _ *big.Int) ([]byte, error) {
+	p.reads++
+	return common.LeftPadBytes(p.storage[account][key].Bytes(), 32), nil
+}
+
+func (p *proxyBackendMock) CallContract(_ context.Context, call ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	p.calls++
+	if facets, ok := p.facets[*call.To]; ok && bytes.Equal(call.Data, diamondLoupeABI.Methods["facets"].ID) {
+		return diamondLoupeABI.Methods["facets"].Outputs.Pack(facets)
+	}
+	implementation, ok := p.beacons[*call.To]
+	if !ok {
+		return nil, errors.New("execution reverted")
+	}
+	return common.LeftPadBytes(implementation.Bytes(), 32), nil
+}
+
+func TestUtilProxyDetector(t *testing.T) {
+	require.Equal(t, common.BigToHash(new(big.Int).Sub(new(big.Int).SetBytes(crypto.Keccak256([]byte("eip1967.proxy.implementation"))), big.NewInt(1))), seth.EIP1967ImplementationSlot, "incorrect implementation slot")
+	require.Equal(t, common.BigToHash(new(big.Int).Sub(new(big.Int).SetBytes(crypto.Keccak256([]byte("eip1967.proxy.beacon"))), big.NewInt(1))), seth.EIP1967BeaconSlot, "incorrect beacon slot")
+
+	proxy := common.HexToAddress("0x01")
+	beaconProxy := common.HexToAddress("0x02")
+	beacon := common.HexToAddress("0x03")
+	implementation := common.HexToAddress("0x04")
+	notProxy := common.HexToAddress("0x05")
+
+	backend := &proxyBackendMock{
+		storage: map[common.Address]map[common.Hash]common.Address{
+			proxy:       {seth.EIP1967ImplementationSlot: implementation},
+			beaconProxy: {seth.EIP1967BeaconSlot: beacon},
+		},
+		beacons: map[common.Address]common.Address{beacon: implementation},
+	}
+	detector := seth.NewProxyDetector(backend)
+
+	found, err := detector.Implementation(context.Background(), proxy)
+	require.NoError(t, err, "failed to read implementation")
+	require.Equal(t, implementation, found, "implementation should be read from implementation slot")
+
+	found, err = detector.Implementation(context.Background(), beaconProxy)
+	require.NoError(t, err, "failed to read implementation")
+	require.Equal(t, implementation, found, "implementation should be read from beacon")
+
+	for i := 0; i < 2; i++ {
+		found, err = detector.Implementation(context.Background(), notProxy)
+		require.NoError(t, err, "failed to read implementation")
+		require.Equal(t, common.Address{}, found, "contract isn't a proxy")
+	}
+	require.Equal(t, 5, backend.reads, "contract, which isn't a proxy, should be checked only once")
+
+	backend.storage[beaconProxy][seth.EIP1967BeaconSlot] = common.HexToAddress("0x06")
+	_, err = detector.Implementation(context.Background(), beaconProxy)
+	require.Error(t, err, "broken beacon should return an error")
+}
@@ -0,0 +1,202 @@
+package seth
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/ratelimit"
+)
+
+const (
+	DefaultRateLimitMaxRetries   = 3
+	DefaultRateLimitMaxRetryWait = 30 * time.Second
+
+	// rateLimitDefaultRetryWait is used, when 429 response has no valid Retry-After header
+	rateLimitDefaultRetryWait = 1 * time.Second
+)
+
+// RateLimitCfg limits rate of RPC requests sent over HTTP to a single endpoint and configures retries of requests rejected by
+// the provider with 429 Too Many Requests
+type RateLimitCfg struct {
+	// RequestsPerSecond is the maximum number of requests per second, 0 means requests aren't limited, only 429 responses are retried
+	RequestsPerSecond int `toml:"requests_per_second"`
+	// Burst is the number of requests, that can be sent at once after a period of inactivity, 0 means requests are evenly spaced
+	Burst int `toml:"burst"`
+	// MaxRetries is the number of times request rejected with 429 is retried, default 3
+	MaxRetries *int `toml:"max_retries"`
+	// MaxRetryWait is the longest wait before a retry, longer Retry-After is capped to it, default 30s
+	MaxRetryWait *Duration `toml:"max_retry_wait"`
+}
+
+func validateRateLimitCfg(cfg *RateLimitCfg, name string) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.RequestsPerSecond < 0 {
+		return fmt.Errorf("'requests_per_second' of %s must be greater than or equal to 0", name)
+	}
+	if cfg.Burst < 0 {
+		return fmt.Errorf("'burst' of %s must be greater than or equal to 0", name)
+	}
+	if cfg.MaxRetries != nil && *cfg.MaxRetries < 0 {
+		return fmt.Errorf("'max_retries' of %s must be greater than or equal to 0", name)
+	}
+	if cfg.MaxRetryWait != nil && cfg.MaxRetryWait.Duration() < 0 {
+		return fmt.Errorf("'max_retry_wait' of %s must be greater than or equal to 0", name)
+	}
+	return nil
+}
+
+func validateRateLimits(n *Network) error {
+	if err := validateRateLimitCfg(n.RateLimit, fmt.Sprintf("rate limit of network '%s'", n.Name)); err != nil {
+		return err
+	}
+	for i, e := range n.Endpoints {
+		if e == nil {
+			continue
+		}
+		if err := validateRateLimitCfg(e.RateLimit, fmt.Sprintf("rate limit of endpoint %d of network '%s'", i, n.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rateLimitFor returns rate limit of the HTTP URL, endpoint's one takes precedence over network's one
+func (n *Network) rateLimitFor(rawURL string) *RateLimitCfg {
+	for _, e := range n.Endpoints {
+		if e != nil && e.HTTP == rawURL && e.RateLimit != nil {
+			return e.RateLimit
+		}
+	}
+	return n.RateLimit
+}
+
+// rateLimitersMu guards lazy creation of network's rate limiting transport
+var rateLimitersMu sync.Mutex
+
+// rpcHTTPTransport returns transport of HTTP RPC requests sent to network's nodes. If rate limits are configured, the same
+// transport is returned for every connection (e.g. of the client and of its tracer), so that they share the limit.
+func (n *Network) rpcHTTPTransport() http.RoundTripper {
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+	if n.rateLimiter != nil {
+		return n.rateLimiter
+	}
+	limits := make(map[string]*RateLimitCfg)
+	for _, u := range n.RPCURLs() {
+		if cfg := n.rateLimitFor(u); cfg != nil && isHTTPURL(u) {
+			limits[u] = cfg
+		}
+	}
+	if len(limits) == 0 {
+		return n.rpcBaseTransport()
+	}
+	n.rateLimiter = newRateLimitTransport(limits, n.rpcBaseTransport())
+	return n.rateLimiter
+}
+
+type endpointRateLimit struct {
+	limiter      ratelimit.Limiter
+	maxRetries   int
+	maxRetryWait time.Duration
+}
+
+// rateLimitTransport waits for the limiter of request's URL before sending it and retries requests rejected with 429 Too
+// Many Requests after the time from Retry-After header. Requests to URLs without configured limit are sent right away.
+type rateLimitTransport struct {
+	limits map[string]*endpointRateLimit
+	base   http.RoundTripper
+}
+
+func newRateLimitTransport(limits map[string]*RateLimitCfg, base http.RoundTripper) *rateLimitTransport {
+	t := &rateLimitTransport{limits: make(map[string]*endpointRateLimit), base: base}
+	for raw, cfg := range limits {
+		// requests' URLs are parsed, so they're compared with parsed ones
+		u, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		l := &endpointRateLimit{maxRetries: DefaultRateLimitMaxRetries, maxRetryWait: DefaultRateLimitMaxRetryWait}
+		if cfg.RequestsPerSecond > 0 {
+			slack := ratelimit.WithoutSlack
+			if cfg.Burst > 0 {
+				slack = ratelimit.WithSlack(cfg.Burst)
+			}
+			l.limiter = ratelimit.New(cfg.RequestsPerSecond, slack)
+		}
+		if cfg.MaxRetries != nil {
+			l.maxRetries = *cfg.MaxRetries
+		}
+		if cfg.MaxRetryWait != nil && cfg.MaxRetryWait.Duration() > 0 {
+			l.maxRetryWait = cfg.MaxRetryWait.Duration()
+		}
+		t.limits[u.String()] = l
+	}
+	return t
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	l, ok := t.limits[req.URL.String()]
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+	for attempt := 0; ; attempt++ {
+		if l.limiter != nil {
+			l.limiter.Take()
+		}
+		r := req
+		if attempt > 0 {
+			r = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				r.Body = body
+			}
+		}
+		resp, err := t.base.RoundTrip(r)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt >= l.maxRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		wait := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if wait > l.maxRetryWait {
+			wait = l.maxRetryWait
+		}
+		_ = resp.Body.Close()
+		L.Warn().
+			Str("Host", req.URL.Host).
+			Int("Attempt", attempt+1).
+			Str("RetryAfter", wait.String()).
+			Msg("RPC provider rate limited the request, retrying")
+		select {
+		case <-req.Context().Done():
+			return nil, errors.Wrap(req.Context().Err(), "request was rate limited and cancelled while waiting for retry")
+		case <-time.After(wait):
+		}
+	}
+}
+
+// retryAfter parses Retry-After header, which is either a number of seconds or an HTTP date
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return rateLimitDefaultRetryWait
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait
+		}
+		return 0
+	}
+	return rateLimitDefaultRetryWait
+}
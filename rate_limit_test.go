@@ -0,0 +1,94 @@
+package seth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIRateLimit(t *testing.T) {
+	c := newClient(t)
+	if !strings.HasPrefix(c.URL, "ws://") {
+		t.Skip("test requires a websocket RPC URL")
+	}
+	httpURL := strings.Replace(strings.Replace(c.URL, "ws://", "http://", 1), ":8546", ":8545", 1)
+	if _, err := http.Post(httpURL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`)); err != nil {
+		t.Skipf("HTTP RPC endpoint %s is not available", httpURL)
+	}
+	target, err := url.Parse(httpURL)
+	require.NoError(t, err, "failed to parse HTTP URL")
+
+	// once limited, provider rejects every third request with 429
+	var limited atomic.Bool
+	var requests, rejected atomic.Int32
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1)%3 == 0 && limited.Load() {
+			rejected.Add(1)
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(provider.Close)
+
+	newRateLimitedClient := func(t *testing.T, limit *seth.RateLimitCfg) *seth.Client {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Network.URLs = nil
+		cfg.Network.Endpoints = []*seth.Endpoint{{HTTP: provider.URL}}
+		cfg.Network.RateLimit = limit
+		cfg.TracingLevel = seth.TracingLevel_None
+		require.NoError(t, seth.ValidateConfig(cfg), "rate limit should be valid")
+		client, err := seth.NewClientWithConfig(cfg)
+		require.NoError(t, err, "failed to create client")
+		t.Cleanup(func() {
+			limited.Store(false)
+			_ = client.Close()
+		})
+		limited.Store(true)
+		return client
+	}
+
+	t.Run("requests are limited and 429 is retried", func(t *testing.T) {
+		client := newRateLimitedClient(t, &seth.RateLimitCfg{RequestsPerSecond: 10})
+		requests.Store(0)
+		rejected.Store(0)
+		start := time.Now()
+		for i := 0; i < 20; i++ {
+			_, err := client.Client.BlockNumber(context.Background())
+			require.NoError(t, err, "rate limited request should be retried")
+		}
+		require.NotZero(t, rejected.Load(), "provider should reject some requests")
+		// 20 requests and retries at 10 per second
+		require.GreaterOrEqual(t, time.Since(start), 2*time.Second, "requests should be rate limited")
+	})
+
+	t.Run("request fails once retries are exhausted", func(t *testing.T) {
+		retries := 0
+		client := newRateLimitedClient(t, &seth.RateLimitCfg{MaxRetries: &retries})
+		var err error
+		for i := 0; i < 3 && err == nil; i++ {
+			_, err = client.Client.BlockNumber(context.Background())
+		}
+		require.Error(t, err, "429 shouldn't be retried")
+		require.Contains(t, err.Error(), "429", "error should contain status of the response")
+	})
+}
+
+func TestConfigRateLimit(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.Network.Endpoints = []*seth.Endpoint{{HTTP: "http://localhost:8545", RateLimit: &seth.RateLimitCfg{Burst: -1}}}
+	require.Error(t, seth.ValidateConfig(cfg), "negative burst should be rejected")
+}
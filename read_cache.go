@@ -0,0 +1,279 @@
+package seth
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultReadCacheTTL        = 10 * time.Second
+	DefaultReadCacheMaxEntries = 10_000
+)
+
+// DefaultReadCacheMethods are methods, whose results are cached, unless 'methods' are configured
+var DefaultReadCacheMethods = []string{"eth_call", "eth_getBalance", "eth_getCode", "eth_getBlockByNumber", "eth_getBlockByHash"}
+
+// ReadCacheCfg configures cache of results of read-only RPC calls sent over HTTP
+type ReadCacheCfg struct {
+	// TTL is how long results are cached, default 10s
+	TTL *Duration `toml:"ttl"`
+	// MaxEntries is the maximum number of cached results, the oldest ones are evicted first, default 10000
+	MaxEntries int `toml:"max_entries"`
+	// Methods are JSON-RPC methods, whose results are cached, default eth_call, eth_getBalance, eth_getCode,
+	// eth_getBlockByNumber and eth_getBlockByHash
+	Methods []string `toml:"methods"`
+}
+
+func validateReadCacheCfg(cfg *ReadCacheCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.TTL != nil && cfg.TTL.Duration() < 0 {
+		return errors.New("read cache 'ttl' must be greater than or equal to 0")
+	}
+	if cfg.MaxEntries < 0 {
+		return errors.New("read cache 'max_entries' must be greater than or equal to 0")
+	}
+	for _, m := range cfg.Methods {
+		if strings.HasPrefix(m, "eth_send") {
+			return errors.Errorf("read cache can't cache '%s', only read-only methods can be cached", m)
+		}
+	}
+	return nil
+}
+
+// ReadCacheStats are numbers of calls answered from the cache and sent to the node
+type ReadCacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+}
+
+type readCacheEntry struct {
+	result  json.RawMessage
+	expires time.Time
+	// volatile entries depend on the state of the latest or pending block, so they're invalidated by transactions
+	volatile bool
+}
+
+// ReadCache caches results of read-only RPC calls keyed by method and params (including block tag) for a configured
+// time. Results of calls at 'latest' or 'pending' block are invalidated, whenever a transaction is sent, the whole
+// cache can be invalidated with Invalidate. It's shared by all connections of the client, including tracer's one.
+type ReadCache struct {
+	mu      *sync.Mutex
+	ttl     time.Duration
+	max     int
+	methods map[string]struct{}
+	entries map[string]*readCacheEntry
+	// order are keys from the oldest entry, used for eviction
+	order  []string
+	hits   uint64
+	misses uint64
+}
+
+// NewReadCache creates a new read cache, zero values in config are replaced with defaults
+func NewReadCache(cfg ReadCacheCfg) *ReadCache {
+	c := &ReadCache{
+		mu:      &sync.Mutex{},
+		ttl:     DefaultReadCacheTTL,
+		max:     DefaultReadCacheMaxEntries,
+		methods: make(map[string]struct{}),
+		entries: make(map[string]*readCacheEntry),
+	}
+	if cfg.TTL != nil && cfg.TTL.Duration() > 0 {
+		c.ttl = cfg.TTL.Duration()
+	}
+	if cfg.MaxEntries > 0 {
+		c.max = cfg.MaxEntries
+	}
+	methods := cfg.Methods
+	if len(methods) == 0 {
+		methods = DefaultReadCacheMethods
+	}
+	for _, m := range methods {
+		c.methods[m] = struct{}{}
+	}
+	return c
+}
+
+// Invalidate removes all cached results
+func (c *ReadCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*readCacheEntry)
+	c.order = nil
+}
+
+// InvalidateMethod removes cached results of the method
+func (c *ReadCache) InvalidateMethod(method string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, method+"[") || key == method {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Stats returns number of cache hits, misses and cached results
+func (c *ReadCache) Stats() ReadCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ReadCacheStats{Hits: c.hits, Misses: c.misses, Entries: len(c.entries)}
+}
+
+func (c *ReadCache) cacheable(method string) bool {
+	_, ok := c.methods[method]
+	return ok
+}
+
+func (c *ReadCache) get(key string) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if ok && time.Now().Before(e.expires) {
+		c.hits++
+		return e.result, true
+	}
+	if ok {
+		delete(c.entries, key)
+	}
+	c.misses++
+	return nil, false
+}
+
+func (c *ReadCache) put(key string, result json.RawMessage, volatile bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = &readCacheEntry{result: result, expires: time.Now().Add(c.ttl), volatile: volatile}
+	// keys of removed entries stay in order, until they're evicted
+	for len(c.entries) > c.max && len(c.order) > 0 {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	if len(c.order) > 2*c.max {
+		c.compactOrder()
+	}
+}
+
+// compactOrder removes keys of entries, that were already removed, from eviction order
+func (c *ReadCache) compactOrder() {
+	order := make([]string, 0, len(c.entries))
+	seen := make(map[string]struct{}, len(c.entries))
+	for _, key := range c.order {
+		if _, ok := c.entries[key]; !ok {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		order = append(order, key)
+	}
+	c.order = order
+}
+
+// invalidateVolatile removes results, which depend on the state of the latest or pending block
+func (c *ReadCache) invalidateVolatile() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		if e.volatile {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// isVolatileCall returns true if params don't pin the call to a block number or hash
+func isVolatileCall(method string, params json.RawMessage) bool {
+	if method == "eth_getBlockByHash" {
+		return false
+	}
+	p := string(params)
+	for _, tag := range []string{`"latest"`, `"pending"`, `"safe"`, `"finalized"`} {
+		if strings.Contains(p, tag) {
+			return true
+		}
+	}
+	// eth_getBalance and eth_getCode at a block number have it as the last param
+	var list []json.RawMessage
+	if err := json.Unmarshal(params, &list); err != nil || len(list) == 0 {
+		return true
+	}
+	return method != "eth_getBlockByNumber" && len(list) < 2
+}
+
+// wrap returns transport answering cacheable calls from the cache and sending other ones with base
+func (c *ReadCache) wrap(base http.RoundTripper) http.RoundTripper {
+	return &readCacheTransport{cache: c, base: base}
+}
+
+type readCacheTransport struct {
+	cache *ReadCache
+	base  http.RoundTripper
+}
+
+func (t *readCacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil {
+		return t.base.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	msgs, batch, err := parseJsonrpcMessages(body)
+	if err != nil {
+		return t.base.RoundTrip(req)
+	}
+	for _, msg := range msgs {
+		if strings.HasPrefix(msg.Method, "eth_send") {
+			t.cache.invalidateVolatile()
+		}
+	}
+	// batches are sent as they are
+	if batch || len(msgs) != 1 || !t.cache.cacheable(msgs[0].Method) {
+		return t.base.RoundTrip(req)
+	}
+
+	msg := msgs[0]
+	key := interactionKey(msg.Method, msg.Params)
+	if result, ok := t.cache.get(key); ok {
+		return jsonrpcResponse(req, jsonrpcMessage{Version: "2.0", ID: msg.ID, Result: result})
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	var out jsonrpcMessage
+	if err := json.Unmarshal(respBody, &out); err == nil && out.Error == nil && out.Result != nil && string(out.Result) != "null" {
+		t.cache.put(key, out.Result, isVolatileCall(msg.Method, msg.Params))
+	}
+	return resp, nil
+}
+
+// InvalidateReadCache removes all cached results of read-only calls, it does nothing, if 'read_cache' isn't set
+func (m *Client) InvalidateReadCache() {
+	if m.ReadCache != nil {
+		m.ReadCache.Invalidate()
+	}
+}
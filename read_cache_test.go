@@ -0,0 +1,81 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIReadCache(t *testing.T) {
+	c := newClient(t)
+	if !strings.HasPrefix(c.URL, "ws://") {
+		t.Skip("test requires a websocket RPC URL")
+	}
+	httpURL := strings.Replace(strings.Replace(c.URL, "ws://", "http://", 1), ":8546", ":8545", 1)
+	if _, err := http.Post(httpURL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`)); err != nil {
+		t.Skipf("HTTP RPC endpoint %s is not available", httpURL)
+	}
+
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.Network.URLs = []string{httpURL}
+	cfg.Network.Endpoints = nil
+	cfg.TracingLevel = seth.TracingLevel_None
+	cfg.ReadCache = &seth.ReadCacheCfg{TTL: &seth.Duration{D: time.Minute}}
+	require.NoError(t, seth.ValidateConfig(cfg), "read cache config should be valid")
+
+	transport := &countingTransport{}
+	client, err := seth.NewClientWithConfig(cfg, seth.WithHTTPClient(&http.Client{Transport: transport}))
+	require.NoError(t, err, "failed to create client")
+	t.Cleanup(func() { _ = client.Close() })
+
+	ctx := context.Background()
+	balanceAt := func(t *testing.T, block *big.Int) *big.Int {
+		balance, err := client.Client.BalanceAt(ctx, client.Addresses[0], block)
+		require.NoError(t, err, "failed to get balance")
+		return balance
+	}
+
+	t.Run("repeated calls are answered from the cache", func(t *testing.T) {
+		client.InvalidateReadCache()
+		before := transport.requests.Load()
+		first := balanceAt(t, nil)
+		for i := 0; i < 10; i++ {
+			require.Equal(t, first.String(), balanceAt(t, nil).String(), "cached balance should be returned")
+		}
+		require.Equal(t, int32(1), transport.requests.Load()-before, "only the first call should be sent")
+		require.GreaterOrEqual(t, client.ReadCache.Stats().Hits, uint64(10), "cache hits should be counted")
+	})
+
+	t.Run("transaction invalidates calls at latest block", func(t *testing.T) {
+		block, err := client.Client.BlockNumber(ctx)
+		require.NoError(t, err, "failed to get block number")
+		pinned := balanceAt(t, big.NewInt(int64(block)))
+		latest := balanceAt(t, nil)
+
+		err = client.TransferETHFromKey(ctx, 0, "0x0000000000000000000000000000000000000001", big.NewInt(1), nil)
+		require.NoError(t, err, "failed to transfer ETH")
+		require.NotEqual(t, latest.String(), balanceAt(t, nil).String(), "balance at latest block should be fetched again")
+
+		before := transport.requests.Load()
+		require.Equal(t, pinned.String(), balanceAt(t, big.NewInt(int64(block))).String(), "balance at pinned block shouldn't change")
+		require.Equal(t, int32(0), transport.requests.Load()-before, "balance at pinned block should stay cached")
+
+		client.InvalidateReadCache()
+		_ = balanceAt(t, big.NewInt(int64(block)))
+		require.Equal(t, int32(1), transport.requests.Load()-before, "call should be sent after explicit invalidation")
+	})
+}
+
+func TestConfigReadCache(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.ReadCache = &seth.ReadCacheCfg{Methods: []string{"eth_sendRawTransaction"}}
+	require.Error(t, seth.ValidateConfig(cfg), "methods sending transactions can't be cached")
+}
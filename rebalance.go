@@ -0,0 +1,237 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	ErrNothingToRebalance = errors.New("at least 2 non-root keys are required to rebalance funds")
+)
+
+// RebalanceTransfer is a single transfer planned during funds rebalancing
+type RebalanceTransfer struct {
+	FromKeyNum int
+	ToKeyNum   int
+	Amount     *big.Int
+}
+
+// PlanRebalance calculates transfers required to even out balances of all keys passed to it. Balances are indexed by key number.
+// It greedily matches the richest key with the poorest one, which results in at most len(balances)-1 transfers. Each transfer
+// costs the sender transferFee, so the sender never sends more than its surplus reduced by that fee. Transfers smaller than
+// minTransfer (or transferFee, if minTransfer is nil) are skipped, as it makes no sense to pay a fee to move dust around.
+func PlanRebalance(balances map[int]*big.Int, transferFee, minTransfer *big.Int) []RebalanceTransfer {
+	if len(balances) < 2 {
+		return []RebalanceTransfer{}
+	}
+
+	total := big.NewInt(0)
+	for _, b := range balances {
+		total.Add(total, b)
+	}
+	target := new(big.Int).Div(total, big.NewInt(int64(len(balances))))
+
+	if minTransfer == nil {
+		minTransfer = transferFee
+	}
+
+	type keyDelta struct {
+		keyNum int
+		delta  *big.Int
+	}
+
+	var donors, receivers []*keyDelta
+	for keyNum, b := range balances {
+		delta := new(big.Int).Sub(b, target)
+		switch delta.Sign() {
+		case 1:
+			// sender has to pay for the transfer, so that's the maximum it can give away
+			delta.Sub(delta, transferFee)
+			if delta.Sign() > 0 {
+				donors = append(donors, &keyDelta{keyNum: keyNum, delta: delta})
+			}
+		case -1:
+			receivers = append(receivers, &keyDelta{keyNum: keyNum, delta: delta.Neg(delta)})
+		}
+	}
+
+	var sortDesc = func(deltas []*keyDelta) {
+		sort.SliceStable(deltas, func(i, j int) bool {
+			if c := deltas[i].delta.Cmp(deltas[j].delta); c != 0 {
+				return c > 0
+			}
+			return deltas[i].keyNum < deltas[j].keyNum
+		})
+	}
+	sortDesc(donors)
+	sortDesc(receivers)
+
+	transfers := make([]RebalanceTransfer, 0)
+	d, r := 0, 0
+	for d < len(donors) && r < len(receivers) {
+		donor, receiver := donors[d], receivers[r]
+		amount := new(big.Int).Set(donor.delta)
+		if receiver.delta.Cmp(amount) < 0 {
+			amount.Set(receiver.delta)
+		}
+
+		if amount.Cmp(minTransfer) >= 0 {
+			transfers = append(transfers, RebalanceTransfer{
+				FromKeyNum: donor.keyNum,
+				ToKeyNum:   receiver.keyNum,
+				Amount:     amount,
+			})
+			// every additional transfer costs the donor one more fee
+			donor.delta.Sub(donor.delta, transferFee)
+		}
+
+		donor.delta.Sub(donor.delta, amount)
+		receiver.delta.Sub(receiver.delta, amount)
+
+		if donor.delta.Sign() <= 0 {
+			d++
+		}
+		if receiver.delta.Sign() <= 0 {
+			r++
+		}
+	}
+
+	return transfers
+}
+
+// RebalanceKeys evens out native token balances of all keys except the root key (key 0), by moving funds from the richest keys
+// to the poorest ones. It's useful during long-running tests, when traffic is skewed towards a few keys and they start to run out of
+// funds, while others still have plenty. Transfers sent by the same key are sent sequentially, different keys send in parallel.
+// Transfers smaller than minTransfer are skipped (if it's nil, transfers smaller than the transfer fee are skipped). Returns executed transfers.
+func (m *Client) RebalanceKeys(ctx context.Context, minTransfer *big.Int) ([]RebalanceTransfer, error) {
+	if len(m.Addresses) < 3 {
+		return nil, ErrNothingToRebalance
+	}
+
+	fees := m.SuggestedTransferFees(ctx)
+
+	balances := make(map[int]*big.Int)
+	balancesMu := &sync.Mutex{}
+	eg, egCtx := errgroup.WithContext(ctx)
+	for keyNum := 1; keyNum < len(m.Addresses); keyNum++ {
+		keyNum := keyNum
+		eg.Go(func() error {
+			balance, err := m.Client.BalanceAt(egCtx, m.Addresses[keyNum], nil)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get balance of key %d", keyNum)
+			}
+			balancesMu.Lock()
+			balances[keyNum] = balance
+			balancesMu.Unlock()
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	transferFee := new(big.Int).Mul(fees.MaxGasPrice(), big.NewInt(m.Cfg.Network.TransferGasFee))
+	// on OP-stack chains every transfer also pays L1 data fee, the highest balance as value makes it an upper bound
+	richest := big.NewInt(0)
+	for _, balance := range balances {
+		if balance.Cmp(richest) > 0 {
+			richest = balance
+		}
+	}
+	l1TransferFee, err := m.estimateL1Fee(ctx, &m.Addresses[1], richest, nil, uint64(m.Cfg.Network.TransferGasFee))
+	if err != nil {
+		return nil, err
+	}
+	transferFee.Add(transferFee, l1TransferFee)
+	transfers := PlanRebalance(balances, transferFee, minTransfer)
+
+	m.logger().Info().
+		Int("Keys", len(balances)).
+		Int("Transfers", len(transfers)).
+		Str("TransferFee", transferFee.String()).
+		Msg("Rebalancing funds between keys")
+
+	transfersByKey := make(map[int][]RebalanceTransfer)
+	for _, tr := range transfers {
+		transfersByKey[tr.FromKeyNum] = append(transfersByKey[tr.FromKeyNum], tr)
+	}
+
+	eg, egCtx = errgroup.WithContext(ctx)
+	for _, keyTransfers := range transfersByKey {
+		keyTransfers := keyTransfers
+		eg.Go(func() error {
+			for _, tr := range keyTransfers {
+				m.logger().Debug().
+					Int("FromKeyNum", tr.FromKeyNum).
+					Int("ToKeyNum", tr.ToKeyNum).
+					Str("Amount", tr.Amount.String()).
+					Msg("Rebalancing transfer")
+				if err := m.TransferETHFromKeyWithFees(egCtx, tr.FromKeyNum, m.Addresses[tr.ToKeyNum].Hex(), tr.Amount, fees); err != nil {
+					return errors.Wrapf(err, "failed to transfer funds from key %d to key %d", tr.FromKeyNum, tr.ToKeyNum)
+				}
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+// RebalanceKeyFileAndUpdateIt evens out balances of all the test keys in keyfile (local or loaded from 1password) and updates
+// the keyfile with the new balances
+func RebalanceKeyFileAndUpdateIt(c *Client, opts *FundKeyFileCmdOpts) error {
+	keyFile, wasNewKeyfileCreated, err := c.CreateOrUnmarshalKeyFile(opts)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create or unmarshal keyfile")
+	}
+
+	if wasNewKeyfileCreated {
+		return errors.New("did not find any keys in the keyfile or keyfile did not exist. Nothing to rebalance")
+	}
+
+	cfg := *c.Cfg
+	cfg.KeyFileSource = ""
+	cfg.Network.PrivateKeys = cfg.Network.PrivateKeys[:1] //take only root key
+	for _, kfd := range keyFile.Keys {
+		cfg.Network.PrivateKeys = append(cfg.Network.PrivateKeys, kfd.PrivateKey)
+	}
+
+	newClient, err := NewClientWithConfig(&cfg)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create new client")
+	}
+
+	if _, err := newClient.RebalanceKeys(context.Background(), nil); err != nil {
+		return err
+	}
+
+	if err := newClient.updateKeyFileBalances(context.Background(), keyFile); err != nil {
+		return err
+	}
+	b, err := toml.Marshal(keyFile)
+	if err != nil {
+		return err
+	}
+
+	if opts.LocalKeyfile {
+		return os.WriteFile(newClient.Cfg.KeyFilePath, b, os.ModePerm)
+	}
+
+	err = ReplaceIn1Pass(newClient, string(b), opts.VaultId)
+	if err != nil {
+		L.Error().Err(err).Msg("Error saving keyfile to 1Password. Will save to local file to avoid data loss")
+		return os.WriteFile(newClient.Cfg.KeyFilePath, b, os.ModePerm)
+	}
+
+	return nil
+}
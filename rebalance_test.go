@@ -0,0 +1,114 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUtilRebalancePlan(t *testing.T) {
+	fee := big.NewInt(10)
+
+	type tc struct {
+		name     string
+		balances map[int]*big.Int
+		expected []seth.RebalanceTransfer
+	}
+
+	tcs := []tc{
+		{
+			name:     "already balanced",
+			balances: map[int]*big.Int{1: big.NewInt(1000), 2: big.NewInt(1000), 3: big.NewInt(1000)},
+			expected: []seth.RebalanceTransfer{},
+		},
+		{
+			name:     "one rich key, two poor ones",
+			balances: map[int]*big.Int{1: big.NewInt(2800), 2: big.NewInt(100), 3: big.NewInt(100)},
+			expected: []seth.RebalanceTransfer{
+				{FromKeyNum: 1, ToKeyNum: 2, Amount: big.NewInt(900)},
+				{FromKeyNum: 1, ToKeyNum: 3, Amount: big.NewInt(880)},
+			},
+		},
+		{
+			name:     "dust is not moved",
+			balances: map[int]*big.Int{1: big.NewInt(1015), 2: big.NewInt(985)},
+			expected: []seth.RebalanceTransfer{},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			transfers := seth.PlanRebalance(tc.balances, fee, nil)
+			require.Equal(t, tc.expected, transfers, "incorrect transfers")
+		})
+	}
+}
+
+func TestAPIRebalanceKeys(t *testing.T) {
+	_ = os.Unsetenv(seth.KEYFILE_PATH_ENV_VAR)
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	var three int64 = 3
+	cfg.EphemeralAddrs = &three
+	limitEphemeralFunds(t, cfg, seth.EtherToWei(big.NewFloat(1)))
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+
+	t.Cleanup(func() {
+		_ = seth.ReturnFunds(c, c.Addresses[0].Hex())
+	})
+
+	balance, err := c.Client.BalanceAt(context.Background(), c.Addresses[1], nil)
+	require.NoError(t, err, "failed to get balance")
+
+	// skew balances, so that key 2 has much more than key 1 and 3
+	err = c.TransferETHFromKey(context.Background(), 1, c.Addresses[2].Hex(), new(big.Int).Div(balance, big.NewInt(2)), nil)
+	require.NoError(t, err, "failed to transfer funds")
+
+	fees := c.SuggestedTransferFees(context.Background())
+	transferFee := new(big.Int).Mul(fees.MaxGasPrice(), big.NewInt(c.Cfg.Network.TransferGasFee))
+	if c.L1FeeOracle != nil {
+		// on OP-stack chains every transfer also pays L1 data fee, no key has more than twice the initial balance
+		l1Fee, err := c.L1FeeOracle.TransactionL1Fee(context.Background(), types.NewTx(&types.LegacyTx{
+			GasPrice: fees.MaxGasPrice(),
+			Gas:      uint64(c.Cfg.Network.TransferGasFee),
+			To:       &c.Addresses[1],
+			Value:    new(big.Int).Mul(balance, big.NewInt(2)),
+		}), nil)
+		require.NoError(t, err, "failed to estimate L1 data fee")
+		transferFee.Add(transferFee, l1Fee)
+	}
+
+	transfers, err := c.RebalanceKeys(context.Background(), nil)
+	require.NoError(t, err, "failed to rebalance keys")
+	require.NotEmpty(t, transfers, "expected at least one transfer")
+
+	for _, tr := range transfers {
+		require.Equal(t, 2, tr.FromKeyNum, "only the richest key should send funds")
+	}
+
+	var minBalance, maxBalance *big.Int
+	for _, addr := range c.Addresses[1:] {
+		b, err := c.Client.BalanceAt(context.Background(), addr, nil)
+		require.NoError(t, err, "failed to get balance")
+		if minBalance == nil || b.Cmp(minBalance) < 0 {
+			minBalance = b
+		}
+		if maxBalance == nil || b.Cmp(maxBalance) > 0 {
+			maxBalance = b
+		}
+	}
+
+	// each key either paid a transfer fee or was left with a surplus smaller than one
+	keys := int64(len(c.Addresses) - 1)
+	spread := new(big.Int).Sub(maxBalance, minBalance)
+	maxSpread := new(big.Int).Mul(big.NewInt(keys), transferFee)
+	require.True(t, spread.Cmp(maxSpread) <= 0, "balances should differ by at most %s wei after rebalancing, but differ by %s wei", maxSpread, spread)
+}
@@ -0,0 +1,298 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultReorgMonitorDepth = 64
+)
+
+var (
+	ErrReorgMonitorHeader = errors.New("reorg monitor failed to fetch header")
+)
+
+// ReorgMonitorCfg configures monitor, which detects replaced blocks, when new heads arrive
+type ReorgMonitorCfg struct {
+	// Depth is the number of latest blocks, whose hashes are tracked, default 64
+	Depth uint64 `toml:"depth"`
+	// RecheckReceipts makes monitor fetch receipts of transactions mined in replaced blocks, transactions, which are no
+	// longer mined, are invalidated
+	RecheckReceipts bool `toml:"recheck_receipts"`
+}
+
+func validateReorgMonitorCfg(cfg *ReorgMonitorCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Depth == 0 {
+		cfg.Depth = DefaultReorgMonitorDepth
+	}
+	return nil
+}
+
+// ReorgBackend is the part of the RPC client needed to detect reorgs, it's implemented by ethclient.Client
+type ReorgBackend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// ReorgEvent describes blocks replaced by a reorg
+type ReorgEvent struct {
+	// CommonAncestor is number of the latest block, which wasn't replaced
+	CommonAncestor uint64
+	// Depth is the number of replaced blocks
+	Depth uint64
+	// ReplacedBlocks are hashes of replaced blocks ordered by block number
+	ReplacedBlocks []common.Hash
+	// NewHead is the head of the new chain
+	NewHead *types.Header
+	// AffectedTransactions are tracked transactions, that were mined in replaced blocks
+	AffectedTransactions []common.Hash
+	// InvalidatedTransactions are affected transactions, that aren't mined in the new chain, set only when receipts are rechecked
+	InvalidatedTransactions []common.Hash
+}
+
+// minedTransaction is transaction, whose receipt was received
+type minedTransaction struct {
+	blockNumber uint64
+	blockHash   common.Hash
+}
+
+// ReorgMonitor detects reorgs by comparing hashes of new heads and their ancestors with headers of recent blocks, that it
+// has seen, kept in LFU header cache. It tracks transactions, whose receipts were received, and reports ones mined in
+// replaced blocks. Optionally it rechecks their receipts and invalidates transactions, that were dropped from the chain.
+type ReorgMonitor struct {
+	mu          *sync.Mutex
+	cfg         ReorgMonitorCfg
+	backend     ReorgBackend
+	headers     *LFUHeaderCache
+	latest      uint64
+	firstSeen   uint64
+	started     bool
+	mined       map[common.Hash]minedTransaction
+	invalidated map[common.Hash]struct{}
+	reorgs      []ReorgEvent
+	onReorg     []func(ReorgEvent)
+}
+
+// NewReorgMonitor creates a new reorg monitor, zero values in config are replaced with defaults
+func NewReorgMonitor(cfg ReorgMonitorCfg, backend ReorgBackend) *ReorgMonitor {
+	if cfg.Depth == 0 {
+		cfg.Depth = DefaultReorgMonitorDepth
+	}
+	return &ReorgMonitor{
+		mu:          &sync.Mutex{},
+		cfg:         cfg,
+		backend:     backend,
+		headers:     NewLFUBlockCache(cfg.Depth),
+		mined:       make(map[common.Hash]minedTransaction),
+		invalidated: make(map[common.Hash]struct{}),
+	}
+}
+
+// OnReorg registers a hook called with every detected reorg
+func (r *ReorgMonitor) OnReorg(fn func(ReorgEvent)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onReorg = append(r.onReorg, fn)
+}
+
+// Reorgs returns all detected reorgs
+func (r *ReorgMonitor) Reorgs() []ReorgEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ReorgEvent{}, r.reorgs...)
+}
+
+// TrackTransaction remembers block of mined transaction, so that it's reported, if the block is replaced
+func (r *ReorgMonitor) TrackTransaction(receipt *types.Receipt) {
+	if receipt == nil || receipt.BlockNumber == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mined[receipt.TxHash] = minedTransaction{blockNumber: receipt.BlockNumber.Uint64(), blockHash: receipt.BlockHash}
+	delete(r.invalidated, receipt.TxHash)
+}
+
+// IsInvalidated returns true if transaction was mined in a block replaced by a reorg and it's not mined in the new chain
+func (r *ReorgMonitor) IsInvalidated(txHash common.Hash) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.invalidated[txHash]
+	return ok
+}
+
+// ProcessHead checks if the new head replaced any block seen before and returns the reorg, if it did. Ancestors of
+// the head, that weren't seen, are fetched, so that reorgs are detected even if some heads were skipped.
+func (r *ReorgMonitor) ProcessHead(ctx context.Context, head *types.Header) (*ReorgEvent, error) {
+	r.mu.Lock()
+	event, err := r.processHead(ctx, head)
+	hooks := r.onReorg
+	r.mu.Unlock()
+	if event != nil {
+		// hooks are called without holding the lock, so that they can use the monitor
+		for _, fn := range hooks {
+			fn(*event)
+		}
+	}
+	return event, err
+}
+
+func (r *ReorgMonitor) processHead(ctx context.Context, head *types.Header) (*ReorgEvent, error) {
+
+	if !r.started {
+		r.started = true
+		r.firstSeen = head.Number.Uint64()
+		r.latest = head.Number.Uint64()
+		_ = r.headers.Set(head)
+		return nil, nil
+	}
+
+	var replaced []*types.Header
+	canonical := []*types.Header{head}
+	current := head
+	ancestor := head.Number.Uint64()
+	for depth := uint64(0); ; depth++ {
+		number := current.Number.Uint64()
+		if cached, ok := r.headers.Get(int64(number)); ok {
+			if cached.Hash() == current.Hash() {
+				ancestor = number
+				break
+			}
+			replaced = append(replaced, cached)
+		}
+		// nothing older than the first seen block can be replaced
+		if number == 0 || number <= r.firstSeen || depth+1 >= r.cfg.Depth {
+			ancestor = number
+			if number > 0 {
+				ancestor = number - 1
+			}
+			break
+		}
+		if parent, ok := r.headers.Get(int64(number - 1)); ok && parent.Hash() == current.ParentHash {
+			ancestor = number - 1
+			break
+		}
+		parent, err := r.backend.HeaderByHash(ctx, current.ParentHash)
+		if err != nil {
+			return nil, errors.Wrapf(wrapError(err, ErrReorgMonitorHeader), "header: %s", current.ParentHash.Hex())
+		}
+		canonical = append(canonical, parent)
+		current = parent
+	}
+
+	// blocks above the new head, which were seen before, are replaced too, when the new chain is shorter
+	for number := r.latest; number > head.Number.Uint64(); number-- {
+		if cached, ok := r.headers.Get(int64(number)); ok {
+			replaced = append(replaced, cached)
+			r.headers.Remove(int64(number))
+		}
+	}
+	for _, h := range canonical {
+		_ = r.headers.Set(h)
+	}
+	r.latest = head.Number.Uint64()
+	r.pruneTransactions()
+
+	if len(replaced) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(replaced, func(i, j int) bool {
+		return replaced[i].Number.Uint64() < replaced[j].Number.Uint64()
+	})
+	event := ReorgEvent{CommonAncestor: ancestor, Depth: uint64(len(replaced)), NewHead: head}
+	replacedBlocks := make(map[common.Hash]struct{})
+	for _, h := range replaced {
+		replacedBlocks[h.Hash()] = struct{}{}
+		event.ReplacedBlocks = append(event.ReplacedBlocks, h.Hash())
+	}
+
+	for txHash, tx := range r.mined {
+		if _, ok := replacedBlocks[tx.blockHash]; ok {
+			event.AffectedTransactions = append(event.AffectedTransactions, txHash)
+		}
+	}
+	if r.cfg.RecheckReceipts {
+		for _, txHash := range event.AffectedTransactions {
+			receipt, err := r.backend.TransactionReceipt(ctx, txHash)
+			if errors.Is(err, ethereum.NotFound) {
+				event.InvalidatedTransactions = append(event.InvalidatedTransactions, txHash)
+				r.invalidated[txHash] = struct{}{}
+				delete(r.mined, txHash)
+				continue
+			}
+			if err != nil {
+				L.Warn().Err(err).Str("TxHash", txHash.Hex()).Msg("Failed to recheck receipt of transaction from replaced block")
+				continue
+			}
+			r.mined[txHash] = minedTransaction{blockNumber: receipt.BlockNumber.Uint64(), blockHash: receipt.BlockHash}
+		}
+	}
+
+	L.Warn().
+		Uint64("Common ancestor", event.CommonAncestor).
+		Uint64("Depth", event.Depth).
+		Uint64("New head", head.Number.Uint64()).
+		Int("Affected transactions", len(event.AffectedTransactions)).
+		Int("Invalidated transactions", len(event.InvalidatedTransactions)).
+		Msg("Chain reorg detected")
+
+	r.reorgs = append(r.reorgs, event)
+	return &event, nil
+}
+
+// pruneTransactions forgets transactions mined in blocks, that are deeper than tracked depth
+func (r *ReorgMonitor) pruneTransactions() {
+	for txHash, tx := range r.mined {
+		if tx.blockNumber+r.cfg.Depth < r.latest {
+			delete(r.mined, txHash)
+		}
+	}
+}
+
+// Start processes new heads received by subscription or polls the node, if subscriptions aren't available, until context
+// is done
+func (r *ReorgMonitor) Start(ctx context.Context, subscriptions *SubscriptionManager, pollInterval time.Duration) {
+	process := func(head *types.Header) {
+		if _, err := r.ProcessHead(ctx, head); err != nil {
+			L.Debug().Err(err).Msg("Failed to check new head for reorg")
+		}
+	}
+	if head, err := r.backend.HeaderByNumber(ctx, nil); err == nil {
+		process(head)
+	}
+	if subscriptions != nil {
+		subscriptions.OnHead(process)
+		if subscriptions.NextHead() != nil {
+			return
+		}
+		L.Debug().Msg("New heads subscription isn't active, reorg monitor falls back to polling")
+	}
+
+	go func() {
+		for {
+			head, err := r.backend.HeaderByNumber(ctx, nil)
+			if err == nil {
+				process(head)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollInterval):
+			}
+		}
+	}()
+}
@@ -0,0 +1,191 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChain is an in-memory chain, whose blocks can be replaced to simulate reorgs
+type fakeChain struct {
+	mu        sync.Mutex
+	canonical []*types.Header
+	byHash    map[common.Hash]*types.Header
+	receipts  map[common.Hash]*types.Receipt
+}
+
+func newFakeChain(length int) *fakeChain {
+	c := &fakeChain{byHash: make(map[common.Hash]*types.Header), receipts: make(map[common.Hash]*types.Receipt)}
+	c.extend(length, 0)
+	return c
+}
+
+// extend appends blocks to canonical chain, fork makes hashes of the blocks different from blocks of other forks
+func (c *fakeChain) extend(blocks int, fork uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := 0; i < blocks; i++ {
+		h := &types.Header{Number: big.NewInt(int64(len(c.canonical))), Difficulty: big.NewInt(0), Extra: big.NewInt(int64(fork)).Bytes()}
+		if len(c.canonical) > 0 {
+			h.ParentHash = c.canonical[len(c.canonical)-1].Hash()
+		}
+		c.canonical = append(c.canonical, h)
+		c.byHash[h.Hash()] = h
+	}
+}
+
+// reorg replaces all blocks after the ancestor with new ones
+func (c *fakeChain) reorg(ancestor uint64, blocks int, fork uint64) {
+	c.mu.Lock()
+	c.canonical = c.canonical[:ancestor+1]
+	c.mu.Unlock()
+	c.extend(blocks, fork)
+}
+
+func (c *fakeChain) mine(txHash common.Hash, number uint64) *types.Receipt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := &types.Receipt{TxHash: txHash, BlockNumber: new(big.Int).SetUint64(number), BlockHash: c.canonical[number].Hash()}
+	c.receipts[txHash] = r
+	return r
+}
+
+func (c *fakeChain) head() *types.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.canonical[len(c.canonical)-1]
+}
+
+func (c *fakeChain) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		return c.head(), nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if number.Uint64() >= uint64(len(c.canonical)) {
+		return nil, ethereum.NotFound
+	}
+	return c.canonical[number.Uint64()], nil
+}
+
+func (c *fakeChain) HeaderByHash(_ context.Context, hash common.Hash) (*types.Header, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h, ok := c.byHash[hash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return h, nil
+}
+
+func (c *fakeChain) TransactionReceipt(_ context.Context, txHash common.Hash) (*types.Receipt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return r, nil
+}
+
+func TestUtilReorgMonitor(t *testing.T) {
+	ctx := context.Background()
+	chain := newFakeChain(10)
+	monitor := seth.NewReorgMonitor(seth.ReorgMonitorCfg{Depth: 16, RecheckReceipts: true}, chain)
+	var hooked []seth.ReorgEvent
+	monitor.OnReorg(func(e seth.ReorgEvent) {
+		hooked = append(hooked, e)
+	})
+
+	event, err := monitor.ProcessHead(ctx, chain.head())
+	require.NoError(t, err, "failed to process head")
+	require.Nil(t, event, "first head can't be a reorg")
+
+	dropped := common.HexToHash("0x01")
+	moved := common.HexToHash("0x02")
+	stable := common.HexToHash("0x03")
+	chain.extend(3, 0)
+	monitor.TrackTransaction(chain.mine(dropped, 12))
+	monitor.TrackTransaction(chain.mine(moved, 11))
+	monitor.TrackTransaction(chain.mine(stable, 10))
+	// heads are skipped, ancestors are fetched
+	event, err = monitor.ProcessHead(ctx, chain.head())
+	require.NoError(t, err, "failed to process head")
+	require.Nil(t, event, "chain was only extended")
+	event, err = monitor.ProcessHead(ctx, chain.head())
+	require.NoError(t, err, "failed to process head")
+	require.Nil(t, event, "same head isn't a reorg")
+
+	replaced := []common.Hash{}
+	for number := int64(11); number <= 12; number++ {
+		h, err := chain.HeaderByNumber(ctx, big.NewInt(number))
+		require.NoError(t, err, "failed to get header")
+		replaced = append(replaced, h.Hash())
+	}
+	chain.reorg(10, 3, 1)
+	delete(chain.receipts, dropped)
+	chain.mine(moved, 13)
+
+	event, err = monitor.ProcessHead(ctx, chain.head())
+	require.NoError(t, err, "failed to process head")
+	require.NotNil(t, event, "reorg should be detected")
+	require.Equal(t, uint64(10), event.CommonAncestor, "incorrect common ancestor")
+	require.Equal(t, uint64(2), event.Depth, "incorrect depth")
+	require.Equal(t, replaced, event.ReplacedBlocks, "incorrect replaced blocks")
+	require.Equal(t, chain.head().Hash(), event.NewHead.Hash(), "incorrect new head")
+	require.ElementsMatch(t, []common.Hash{dropped, moved}, event.AffectedTransactions, "incorrect affected transactions")
+	require.Equal(t, []common.Hash{dropped}, event.InvalidatedTransactions, "incorrect invalidated transactions")
+	require.True(t, monitor.IsInvalidated(dropped), "dropped transaction should be invalidated")
+	require.False(t, monitor.IsInvalidated(moved), "moved transaction shouldn't be invalidated")
+	require.False(t, monitor.IsInvalidated(stable), "transaction from common ancestor shouldn't be invalidated")
+
+	// new chain is shorter than the old one
+	chain.reorg(11, 1, 2)
+	event, err = monitor.ProcessHead(ctx, chain.head())
+	require.NoError(t, err, "failed to process head")
+	require.NotNil(t, event, "reorg should be detected")
+	require.Equal(t, uint64(11), event.CommonAncestor, "incorrect common ancestor")
+	require.Equal(t, uint64(2), event.Depth, "replaced blocks above new head should be counted")
+	require.Equal(t, []common.Hash{moved}, event.AffectedTransactions, "moved transaction should be affected again")
+
+	require.Len(t, monitor.Reorgs(), 2, "all reorgs should be remembered")
+	require.Len(t, hooked, 2, "hook should be called for every reorg")
+}
+
+func TestUtilReorgMonitorPolling(t *testing.T) {
+	chain := newFakeChain(5)
+	monitor := seth.NewReorgMonitor(seth.ReorgMonitorCfg{}, chain)
+	reorgs := make(chan seth.ReorgEvent, 1)
+	monitor.OnReorg(func(e seth.ReorgEvent) {
+		reorgs <- e
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	monitor.Start(ctx, nil, 10*time.Millisecond)
+
+	chain.reorg(2, 3, 1)
+	select {
+	case e := <-reorgs:
+		// blocks before the first seen head are unknown to the monitor
+		require.Equal(t, uint64(3), e.CommonAncestor, "incorrect common ancestor")
+		require.Equal(t, uint64(1), e.Depth, "incorrect depth")
+	case <-time.After(5 * time.Second):
+		t.Fatal("reorg wasn't detected")
+	}
+}
+
+func TestConfigReorgMonitorValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.ReorgMonitor = &seth.ReorgMonitorCfg{}
+	require.NoError(t, seth.ValidateConfig(cfg), "failed to validate config")
+	require.Equal(t, uint64(seth.DefaultReorgMonitorDepth), cfg.ReorgMonitor.Depth, "default depth should be set")
+}
@@ -0,0 +1,266 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	ReplayReportDir = "replays"
+
+	// ReplayTxStatusNotSent is the status of transaction, that couldn't be sent during the replay
+	ReplayTxStatusNotSent = "not_sent"
+)
+
+var (
+	ErrReplayNoTransactionData = errors.New("transaction has no sender or input recorded, it can't be replayed")
+	ErrReplayTooManySenders    = errors.New("run manifest has transactions sent from more addresses than client has keys")
+)
+
+// ReplayedTransaction is a transaction from run manifest and its replayed counterpart
+type ReplayedTransaction struct {
+	OriginalHash    string `json:"original_hash"`
+	Hash            string `json:"hash,omitempty"`
+	From            string `json:"from"`
+	To              string `json:"to,omitempty"`
+	ContractAddress string `json:"contract_address,omitempty"`
+	OriginalStatus  string `json:"original_status"`
+	Status          string `json:"status"`
+	Error           string `json:"error,omitempty"`
+}
+
+// ReplayReport describes replay of a run manifest, AddressMap maps addresses of the recorded run (senders and deployed
+// contracts) to their replayed counterparts
+type ReplayReport struct {
+	SourceNetwork string                `json:"source_network"`
+	SourceChainID int64                 `json:"source_chain_id"`
+	Network       string                `json:"network"`
+	ChainID       int64                 `json:"chain_id"`
+	StartedAt     time.Time             `json:"started_at"`
+	Duration      string                `json:"duration"`
+	AddressMap    map[string]string     `json:"address_map"`
+	Transactions  []ReplayedTransaction `json:"transactions"`
+	// Mismatches is the number of transactions, whose status differs from the recorded one
+	Mismatches int `json:"mismatches"`
+}
+
+// LoadRunManifest loads run manifest saved with SaveRunManifest()
+func LoadRunManifest(path string) (RunManifestReport, error) {
+	var report RunManifestReport
+	if err := OpenJsonFileAsStruct(path, &report); err != nil {
+		return RunManifestReport{}, errors.Wrapf(err, "failed to read run manifest %s", path)
+	}
+	return report, nil
+}
+
+// Replay sends transactions recorded in the run manifest again, in the same order and one by one, so that a run from
+// an ephemeral environment can be reproduced on a fresh chain. Recorded senders are mapped to client's keys (addresses,
+// that client has, are kept) and nonces are assigned anew. Addresses of contracts deployed during the run are remapped
+// to addresses of their replayed deployments, also inside calldata and constructor parameters. Transactions, that weren't
+// mined, are skipped and reverted ones are replayed with their recorded gas limit, so that they revert again. Replay
+// continues after failed transactions, each one's status is compared with the recorded one.
+func (m *Client) Replay(ctx context.Context, manifest RunManifestReport) (*ReplayReport, error) {
+	var toReplay []ManifestTransaction
+	for _, tx := range manifest.Transactions {
+		if tx.Status == ManifestTxStatusNotMined {
+			continue
+		}
+		if tx.From == "" || tx.Input == "" {
+			return nil, errors.Wrapf(ErrReplayNoTransactionData, "transaction: %s, run manifest was saved by an older version of Seth", tx.Hash)
+		}
+		toReplay = append(toReplay, tx)
+	}
+
+	senders, err := m.replaySenders(toReplay)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReplayReport{
+		SourceNetwork: manifest.Network,
+		SourceChainID: manifest.ChainID,
+		Network:       m.Cfg.Network.Name,
+		ChainID:       m.ChainID,
+		StartedAt:     time.Now(),
+		AddressMap:    make(map[string]string),
+		Transactions:  []ReplayedTransaction{},
+	}
+	addressMap := make(map[common.Address]common.Address)
+	for original, keyNum := range senders {
+		if original != m.Addresses[keyNum] {
+			addressMap[original] = m.Addresses[keyNum]
+		}
+	}
+
+	for _, recorded := range toReplay {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		replayed := m.replayTransaction(recorded, senders[common.HexToAddress(recorded.From)], addressMap)
+		if recorded.ContractAddress != "" && replayed.ContractAddress != "" {
+			original := common.HexToAddress(recorded.ContractAddress)
+			addressMap[original] = common.HexToAddress(replayed.ContractAddress)
+			if name, ok := manifest.ContractMapDelta[strings.ToLower(original.Hex())]; ok {
+				m.ContractAddressToNameMap.AddContract(replayed.ContractAddress, name)
+			}
+		}
+		if replayed.Status != recorded.Status {
+			report.Mismatches++
+			m.logger().Warn().
+				Str("Original hash", recorded.Hash).
+				Str("Hash", replayed.Hash).
+				Str("Original status", recorded.Status).
+				Str("Status", replayed.Status).
+				Msg("Replayed transaction has different status than the recorded one")
+		}
+		report.Transactions = append(report.Transactions, replayed)
+	}
+
+	for original, replayed := range addressMap {
+		report.AddressMap[original.Hex()] = replayed.Hex()
+	}
+	report.Duration = time.Since(report.StartedAt).String()
+
+	return report, nil
+}
+
+// SaveReplayReport saves replay report as JSON and returns path to it
+func SaveReplayReport(report *ReplayReport, dirName string) (string, error) {
+	return saveAsJson(report, dirName, fmt.Sprintf("replay_%s_%s", report.Network, report.StartedAt.Format("2006-01-02-15-04-05")))
+}
+
+// replaySenders maps recorded senders to client's keys, senders that client has keep their keys and the rest get unused
+// keys in order of their first transaction
+func (m *Client) replaySenders(txs []ManifestTransaction) (map[common.Address]int, error) {
+	keys := make(map[common.Address]int)
+	for i, addr := range m.Addresses {
+		keys[addr] = i
+	}
+
+	senders := make(map[common.Address]int)
+	used := make(map[int]bool)
+	var unknown []common.Address
+	for _, tx := range txs {
+		from := common.HexToAddress(tx.From)
+		if _, ok := senders[from]; ok {
+			continue
+		}
+		if keyNum, ok := keys[from]; ok {
+			senders[from] = keyNum
+			used[keyNum] = true
+			continue
+		}
+		senders[from] = -1
+		unknown = append(unknown, from)
+	}
+
+	next := 0
+	for _, from := range unknown {
+		for next < len(m.Addresses) && used[next] {
+			next++
+		}
+		if next == len(m.Addresses) {
+			return nil, errors.Wrapf(ErrReplayTooManySenders, "senders: %d, keys: %d", len(senders), len(m.Addresses))
+		}
+		senders[from] = next
+		used[next] = true
+	}
+
+	return senders, nil
+}
+
+func (m *Client) replayTransaction(recorded ManifestTransaction, keyNum int, addressMap map[common.Address]common.Address) ReplayedTransaction {
+	replayed := ReplayedTransaction{
+		OriginalHash:   recorded.Hash,
+		From:           m.Addresses[keyNum].Hex(),
+		OriginalStatus: recorded.Status,
+		Status:         ReplayTxStatusNotSent,
+	}
+	input := remapAddresses(common.FromHex(recorded.Input), addressMap)
+
+	opts := m.NewTXKeyOpts(keyNum, WithValue(recorded.Value))
+	if recorded.Status == ManifestTxStatusReverted {
+		// gas estimation would fail
+		opts.GasLimit = recorded.GasLimit
+	}
+	if err := m.checkTransactOpts(opts); err != nil {
+		replayed.Error = err.Error()
+		return replayed
+	}
+
+	var tx *types.Transaction
+	var err error
+	if recorded.To == "" {
+		// constructor parameters are already part of the input
+		_, tx, _, err = bind.DeployContract(opts, abi.ABI{}, input, m.Client)
+	} else {
+		to := remapAddress(common.HexToAddress(recorded.To), addressMap)
+		replayed.To = to.Hex()
+		tx, err = bind.NewBoundContract(to, abi.ABI{}, m.Client, m.Client, m.Client).RawTransact(opts, input)
+	}
+	if err != nil {
+		if m.RunManifest != nil {
+			m.RunManifest.AddSendError()
+		}
+		replayed.Error = err.Error()
+		return replayed
+	}
+	replayed.Hash = tx.Hash().Hex()
+
+	if _, decodeErr := m.Decode(tx, nil); decodeErr != nil {
+		replayed.Error = decodeErr.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
+	defer cancel()
+	receipt, err := m.Client.TransactionReceipt(ctx, tx.Hash())
+	switch {
+	case err != nil:
+		replayed.Status = ManifestTxStatusNotMined
+	case receipt.Status == types.ReceiptStatusSuccessful:
+		replayed.Status = ManifestTxStatusSuccess
+		if tx.To() == nil {
+			replayed.ContractAddress = receipt.ContractAddress.Hex()
+		}
+	default:
+		replayed.Status = ManifestTxStatusReverted
+	}
+
+	return replayed
+}
+
+func remapAddress(addr common.Address, addressMap map[common.Address]common.Address) common.Address {
+	if remapped, ok := addressMap[addr]; ok {
+		return remapped
+	}
+	return addr
+}
+
+// remapAddresses replaces every occurrence of recorded addresses in the data (ABI encoded or packed) with their replayed
+// counterparts, data is scanned once, so that remapped addresses aren't remapped again
+func remapAddresses(data []byte, addressMap map[common.Address]common.Address) []byte {
+	if len(addressMap) == 0 {
+		return data
+	}
+	remapped := make([]byte, 0, len(data))
+	for i := 0; i < len(data); {
+		if i+common.AddressLength <= len(data) {
+			if replacement, ok := addressMap[common.BytesToAddress(data[i:i+common.AddressLength])]; ok {
+				remapped = append(remapped, replacement.Bytes()...)
+				i += common.AddressLength
+				continue
+			}
+		}
+		remapped = append(remapped, data[i])
+		i++
+	}
+	return remapped
+}
@@ -0,0 +1,80 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/smartcontractkit/seth"
+	sethcmd "github.com/smartcontractkit/seth/cmd"
+	network_debug_contract "github.com/smartcontractkit/seth/contracts/bind/debug"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIReplay(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.RunManifest = true
+
+	recorded, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	t.Cleanup(func() {
+		_ = os.RemoveAll(seth.RunManifestDir)
+		_ = os.RemoveAll(seth.ReplayReportDir)
+	})
+
+	sub, err := recorded.DeployContractFromContractStore(recorded.NewTXOpts(), "NetworkDebugSubContract")
+	require.NoError(t, err, "failed to deploy sub contract")
+	debug, err := recorded.DeployContractFromContractStore(recorded.NewTXOpts(), "NetworkDebugContract", sub.Address)
+	require.NoError(t, err, "failed to deploy contract")
+	debugContract, err := network_debug_contract.NewNetworkDebugContract(debug.Address, recorded.Client)
+	require.NoError(t, err, "failed to bind contract")
+	_, err = recorded.Decode(debugContract.Set(recorded.NewTXOpts(), big.NewInt(5)))
+	require.NoError(t, err, "failed to send transaction")
+	_, err = recorded.Decode(debugContract.AlwaysRevertsRequire(recorded.NewTXOpts(seth.WithGasLimit(1_000_000))))
+	require.Error(t, err, "transaction should revert")
+
+	path, err := recorded.SaveRunManifest()
+	require.NoError(t, err, "failed to save run manifest")
+	manifest, err := seth.LoadRunManifest(path)
+	require.NoError(t, err, "failed to load run manifest")
+
+	replayer := newClient(t)
+	report, err := replayer.Replay(context.Background(), manifest)
+	require.NoError(t, err, "failed to replay run")
+	require.Len(t, report.Transactions, 4, "all transactions should be replayed")
+	require.Equal(t, 0, report.Mismatches, "replayed transactions should have the same statuses")
+	require.Equal(t, seth.ManifestTxStatusReverted, report.Transactions[3].Status, "reverted transaction should revert again")
+
+	replayedSub := common.HexToAddress(report.AddressMap[sub.Address.Hex()])
+	replayedDebug := common.HexToAddress(report.AddressMap[debug.Address.Hex()])
+	require.NotEqual(t, sub.Address, replayedSub, "sub contract should be deployed again")
+	require.NotEqual(t, debug.Address, replayedDebug, "contract should be deployed again")
+	require.Equal(t, replayedDebug.Hex(), report.Transactions[2].To, "call should be sent to replayed contract")
+
+	replayedContract, err := network_debug_contract.NewNetworkDebugContract(replayedDebug, replayer.Client)
+	require.NoError(t, err, "failed to bind replayed contract")
+	subAddress, err := replayedContract.SubContract(replayer.NewCallOpts())
+	require.NoError(t, err, "failed to call replayed contract")
+	require.Equal(t, replayedSub, subAddress, "constructor parameter should be remapped")
+	value, err := replayedContract.Get(replayer.NewCallOpts())
+	require.NoError(t, err, "failed to call replayed contract")
+	require.Equal(t, int64(5), value.Int64(), "state should be replayed")
+
+	t.Run("CLI", func(t *testing.T) {
+		err := sethcmd.RunCLI([]string{"seth", "-n", os.Getenv(seth.NETWORK_ENV_VAR), "replay", path})
+		require.NoError(t, err, "failed to replay run with CLI")
+		reports, err := os.ReadDir(seth.ReplayReportDir)
+		require.NoError(t, err, "failed to read reports dir")
+		require.Len(t, reports, 1, "report should be saved")
+	})
+
+	t.Run("manifest without transaction data", func(t *testing.T) {
+		_, err := replayer.Replay(context.Background(), seth.RunManifestReport{
+			Transactions: []seth.ManifestTransaction{{Hash: "0x01", Status: seth.ManifestTxStatusSuccess}},
+		})
+		require.EqualError(t, err, "transaction: 0x01, run manifest was saved by an older version of Seth: transaction has no sender or input recorded, it can't be replayed")
+	})
+}
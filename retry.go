@@ -1,10 +1,15 @@
 package seth
 
 import (
+	"bytes"
+	"context"
+	"math/big"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/avast/retry-go"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/pkg/errors"
 )
@@ -12,11 +17,20 @@ import (
 /* these are the common errors of RPCs */
 
 const (
-	ErrRPCConnectionRefused = "connection refused"
+	rpcConnectionRefused = "connection refused"
+	rpcNonceTooLow       = "nonce too low"
+	rpcAlreadyKnown      = "already known"
+	rpcUnderpriced       = "replacement transaction underpriced"
 )
 
 const (
-	ErrRetryTimeout = "retry timeout"
+	// replacementFeeBumpPercent is the fee increase of a transaction resent after 'replacement transaction underpriced',
+	// nodes require at least 10%
+	replacementFeeBumpPercent = 20
+)
+
+var (
+	ErrSendRetry = errors.New("failed to send transaction after retries")
 )
 
 // RetryTxAndDecode executes transaction several times, retries if connection is lost and decodes all the data
@@ -28,16 +42,16 @@ func (m *Client) RetryTxAndDecode(f func() (*types.Transaction, error)) (*Decode
 			tx, err = f()
 			return err
 		}, retry.OnRetry(func(i uint, _ error) {
-			L.Debug().Uint("Attempt", i).Msg("Retrying transaction...")
+			m.logger().Debug().Uint("Attempt", i).Msg("Retrying transaction...")
 		}),
 		retry.DelayType(retry.FixedDelay),
 		retry.Attempts(10), retry.Delay(time.Duration(1)*time.Second), retry.RetryIf(func(err error) bool {
-			return strings.Contains(err.Error(), ErrRPCConnectionRefused)
+			return strings.Contains(err.Error(), rpcConnectionRefused)
 		}),
 	)
 
 	if err != nil {
-		return &DecodedTransaction{}, errors.New(ErrRetryTimeout)
+		return &DecodedTransaction{}, ErrRetryTimeout
 	}
 
 	dt, err := m.Decode(tx, nil)
@@ -47,3 +61,224 @@ func (m *Client) RetryTxAndDecode(f func() (*types.Transaction, error)) (*Decode
 
 	return dt, nil
 }
+
+// sendRetries returns how many times transaction, that failed with a nonce error, is resent
+func (m *Client) sendRetries() uint {
+	if m.Cfg.NonceManager == nil {
+		return 0
+	}
+	return m.Cfg.NonceManager.SendRetries
+}
+
+// SendTransaction sends signed transaction and returns the transaction, that was actually sent. If 'send_retries' of nonce
+// manager is set and node rejects the transaction with 'nonce too low', the nonce is resynced from the node and transaction
+// is signed again with the new one. If it's rejected with 'replacement transaction underpriced' and the transaction holding
+// the nonce is an earlier attempt of the same send (it was sent by this client, has the same recipient, value and data and
+// is still pending), its fees are bumped by 20%. Otherwise a different transaction (e.g. a concurrent send) holds the nonce
+// and it's resynced same as with 'nonce too low'. 'already known' means that the very same transaction is already in the
+// mempool, so it's treated as sent, same as transaction rejected with 'nonce too low', that was already mined.
+// ETH transfers and contract deployments are sent with it. To get the same behaviour for your gethwrappers create transaction
+// options with WithNoSend(true) and send the returned transaction with this function.
+func (m *Client) SendTransaction(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	from, err := types.Sender(types.LatestSignerForChainID(big.NewInt(m.ChainID)), tx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to recover transaction sender")
+	}
+	retries := m.sendRetries()
+	for attempt := uint(0); ; attempt++ {
+		sendErr := m.Client.SendTransaction(ctx, tx)
+		if sendErr == nil {
+			m.sendAttempts.add(from, tx)
+			return tx, nil
+		}
+		msg := strings.ToLower(sendErr.Error())
+		if strings.Contains(msg, rpcAlreadyKnown) {
+			m.sendAttempts.add(from, tx)
+			return tx, nil
+		}
+		nonceTooLow := strings.Contains(msg, rpcNonceTooLow)
+		if nonceTooLow {
+			// transaction could have been mined already, e.g. when it was sent again after a timeout
+			if _, _, err := m.Client.TransactionByHash(ctx, tx.Hash()); err == nil {
+				return tx, nil
+			}
+		}
+		underpriced := strings.Contains(msg, rpcUnderpriced)
+		if !nonceTooLow && !underpriced {
+			m.releaseBudget(tx)
+			return nil, sendErr
+		}
+		if attempt >= retries {
+			m.releaseBudget(tx)
+			if retries == 0 {
+				return nil, sendErr
+			}
+			return nil, errors.Wrapf(wrapError(sendErr, ErrSendRetry), "retries: %d", retries)
+		}
+
+		nonce, bump, base := tx.Nonce(), 0, tx
+		if underpriced {
+			if earlier := m.pendingEarlierAttempt(ctx, from, tx); earlier != nil {
+				bump = replacementFeeBumpPercent
+				// replacement has to outbid the pending attempt, whose fees could be higher
+				if earlier.GasFeeCap().Cmp(tx.GasFeeCap()) > 0 {
+					base = earlier
+				}
+			}
+		}
+		if bump == 0 {
+			if nonce, err = m.resyncNonce(ctx, from); err != nil {
+				return nil, err
+			}
+		}
+		m.logger().Warn().
+			Str("Transaction", tx.Hash().Hex()).
+			Str("From", from.Hex()).
+			Uint64("Nonce", tx.Nonce()).
+			Uint64("NewNonce", nonce).
+			Int("FeeBumpPercent", bump).
+			Uint("Attempt", attempt+1).
+			Str("Reason", sendErr.Error()).
+			Msg("Resending rejected transaction")
+		if nonce != tx.Nonce() {
+			m.releaseBudget(tx)
+		}
+		if tx, err = m.Signer.SignTx(ctx, from, replacementTx(base, nonce, bump)); err != nil {
+			return nil, errors.Wrap(err, "failed to sign tx")
+		}
+	}
+}
+
+// pendingEarlierAttempt returns pending transaction with the same nonce, recipient, value and data, that was sent by
+// SendTransaction before, or nil if there's none
+func (m *Client) pendingEarlierAttempt(ctx context.Context, from common.Address, tx *types.Transaction) *types.Transaction {
+	for _, hash := range m.sendAttempts.hashes(from, tx.Nonce()) {
+		if hash == tx.Hash() {
+			continue
+		}
+		earlier, isPending, err := m.Client.TransactionByHash(ctx, hash)
+		if err != nil || !isPending || !sameSend(earlier, tx) {
+			continue
+		}
+		return earlier
+	}
+	return nil
+}
+
+// sameSend returns true if transactions have the same recipient, value and data, so they differ only in gas settings
+func sameSend(a, b *types.Transaction) bool {
+	if (a.To() == nil) != (b.To() == nil) || (a.To() != nil && *a.To() != *b.To()) {
+		return false
+	}
+	return a.Value().Cmp(b.Value()) == 0 && bytes.Equal(a.Data(), b.Data())
+}
+
+// sendAttempts remembers hashes of transactions sent by SendTransaction by sender and nonce, so that replacement of
+// an earlier attempt can be told apart from a different transaction holding the same nonce. Nonces are forgotten, once
+// a transaction with that nonce is mined. It's safe for concurrent use.
+type sendAttempts struct {
+	mu   *sync.Mutex
+	sent map[common.Address]map[uint64][]common.Hash
+}
+
+func newSendAttempts() *sendAttempts {
+	return &sendAttempts{mu: &sync.Mutex{}, sent: make(map[common.Address]map[uint64][]common.Hash)}
+}
+
+func (a *sendAttempts) add(from common.Address, tx *types.Transaction) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.sent[from]; !ok {
+		a.sent[from] = make(map[uint64][]common.Hash)
+	}
+	a.sent[from][tx.Nonce()] = append(a.sent[from][tx.Nonce()], tx.Hash())
+}
+
+func (a *sendAttempts) hashes(from common.Address, nonce uint64) []common.Hash {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]common.Hash(nil), a.sent[from][nonce]...)
+}
+
+// forget removes attempts of the transaction's nonce, it's called when the transaction is mined
+func (a *sendAttempts) forget(tx *types.Transaction) {
+	if a == nil {
+		return
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sent[from], tx.Nonce())
+}
+
+// resyncNonce returns next nonce of the address fetched from the node. Local counter (and the one shared with other
+// processes) is reconciled first, if nonces are allocated from it.
+func (m *Client) resyncNonce(ctx context.Context, address common.Address) (uint64, error) {
+	if m.NonceManager != nil && (m.localNonceAllocationEnabled() || m.KeyCoordinator != nil) {
+		if err := m.NonceManager.ReconcileNonce(ctx, address); err != nil {
+			return 0, err
+		}
+		if m.KeyCoordinator == nil {
+			return m.NonceManager.AllocateNonce(address), nil
+		}
+	}
+	pendingNonce, err := m.Client.PendingNonceAt(ctx, address)
+	if err != nil {
+		return 0, wrapError(err, ErrNonce)
+	}
+	if m.KeyCoordinator != nil {
+		return m.KeyCoordinator.AllocateNonce(ctx, address, pendingNonce)
+	}
+	return pendingNonce, nil
+}
+
+// replacementTx returns unsigned copy of the transaction with given nonce and fees bumped by given percentage
+func replacementTx(tx *types.Transaction, nonce uint64, bumpPercent int) *types.Transaction {
+	bumpFee := func(fee *big.Int) *big.Int {
+		bumped := new(big.Int).Mul(fee, big.NewInt(int64(100+bumpPercent)))
+		return bumped.Div(bumped, big.NewInt(100))
+	}
+	switch tx.Type() {
+	case types.DynamicFeeTxType:
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      nonce,
+			GasTipCap:  bumpFee(tx.GasTipCap()),
+			GasFeeCap:  bumpFee(tx.GasFeeCap()),
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		})
+	case types.AccessListTxType:
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      nonce,
+			GasPrice:   bumpFee(tx.GasPrice()),
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		})
+	default:
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: bumpFee(tx.GasPrice()),
+			Gas:      tx.Gas(),
+			To:       tx.To(),
+			Value:    tx.Value(),
+			Data:     tx.Data(),
+		})
+	}
+}
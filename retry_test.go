@@ -0,0 +1,103 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func newClientWithSendRetries(t *testing.T, retries uint) *seth.Client {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.NonceManager.SendRetries = retries
+	// nonce gaps left by queued transactions can be healed only with nonce journal
+	cfg.NonceManager.JournalPath = filepath.Join(t.TempDir(), "nonce_journal.jsonl")
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	t.Cleanup(func() {
+		_ = c.Close()
+	})
+	return c
+}
+
+func TestAPISendTransactionRetries(t *testing.T) {
+	ctx := context.Background()
+	c := newClientWithSendRetries(t, 2)
+	pendingNonce, err := c.Client.PendingNonceAt(ctx, c.Addresses[0])
+	require.NoError(t, err, "failed to get pending nonce")
+	require.NotZero(t, pendingNonce, "root key should have sent transactions")
+
+	t.Run("nonce too low is resynced", func(t *testing.T) {
+		stale := new(big.Int).SetUint64(pendingNonce - 1)
+		// value differs from other tests, so that transaction isn't the same as the mined one with that nonce
+		tx, err := TestEnv.DebugContract.Set(c.NewTXOpts(seth.WithNoSend(true), seth.WithNonce(stale)), big.NewInt(101))
+		require.NoError(t, err, "failed to create transaction")
+		sent, err := c.SendTransaction(ctx, tx)
+		require.NoError(t, err, "transaction should be resent with resynced nonce")
+		require.Equal(t, pendingNonce, sent.Nonce(), "nonce should be resynced from the node")
+		_, err = c.Decode(sent, nil)
+		require.NoError(t, err, "failed to decode resent transaction")
+
+		// sending the same transaction again is fine
+		again, err := c.SendTransaction(ctx, sent)
+		require.NoError(t, err, "known transaction should be treated as sent")
+		require.Equal(t, sent.Hash(), again.Hash(), "known transaction shouldn't be resent")
+	})
+
+	t.Run("underpriced earlier attempt is bumped", func(t *testing.T) {
+		pendingNonce, err := c.Client.PendingNonceAt(ctx, c.Addresses[0])
+		require.NoError(t, err, "failed to get pending nonce")
+		// transaction after a gap stays in the mempool, so it can be replaced
+		queued := seth.WithNonce(new(big.Int).SetUint64(pendingNonce + 1))
+		first, err := TestEnv.DebugContract.Set(c.NewTXOpts(queued, seth.WithNoSend(true)), big.NewInt(1))
+		require.NoError(t, err, "failed to create queued transaction")
+		first, err = c.SendTransaction(ctx, first)
+		require.NoError(t, err, "failed to send queued transaction")
+		// the same call with different gas limit is another attempt of the same send
+		second, err := TestEnv.DebugContract.Set(c.NewTXOpts(queued, seth.WithNoSend(true), seth.WithGasLimit(first.Gas()+1)), big.NewInt(1))
+		require.NoError(t, err, "failed to create replacement transaction")
+		replacement, err := c.SendTransaction(ctx, second)
+		require.NoError(t, err, "replacement should be resent with bumped fees")
+		require.Equal(t, first.Nonce(), replacement.Nonce(), "replacement should keep the nonce")
+		require.Equal(t, 1, replacement.GasPrice().Cmp(second.GasPrice()), "replacement's gas price should be bumped")
+
+		healed, err := c.HealNonceGaps(ctx, 0)
+		require.NoError(t, err, "failed to heal nonce gap")
+		require.Equal(t, []uint64{pendingNonce}, healed, "gap before queued transaction should be healed")
+	})
+
+	t.Run("underpriced different transaction is resynced", func(t *testing.T) {
+		pendingNonce, err := c.Client.PendingNonceAt(ctx, c.Addresses[0])
+		require.NoError(t, err, "failed to get pending nonce")
+		queued := seth.WithNonce(new(big.Int).SetUint64(pendingNonce + 1))
+		first, err := TestEnv.DebugContract.Set(c.NewTXOpts(queued), big.NewInt(1))
+		require.NoError(t, err, "failed to send queued transaction")
+		// different call holding the nonce is e.g. a concurrent send, which mustn't be replaced
+		second, err := TestEnv.DebugContract.Set(c.NewTXOpts(queued, seth.WithNoSend(true)), big.NewInt(2))
+		require.NoError(t, err, "failed to create transaction")
+		sent, err := c.SendTransaction(ctx, second)
+		require.NoError(t, err, "transaction should be resent with resynced nonce")
+		require.NotEqual(t, first.Nonce(), sent.Nonce(), "nonce should be resynced from the node")
+		require.Equal(t, second.GasPrice().String(), sent.GasPrice().String(), "fees shouldn't be bumped")
+		require.Equal(t, pendingNonce, sent.Nonce(), "resynced nonce should fill the gap before queued transaction")
+		_, err = c.Decode(sent, nil)
+		require.NoError(t, err, "failed to decode resent transaction")
+
+		healed, err := c.HealNonceGaps(ctx, 0)
+		require.NoError(t, err, "failed to heal nonce gap")
+		require.Empty(t, healed, "there should be no gap left")
+	})
+
+	t.Run("retries are disabled by default", func(t *testing.T) {
+		c := newClientWithSendRetries(t, 0)
+		stale := new(big.Int).SetUint64(pendingNonce - 1)
+		tx, err := TestEnv.DebugContract.Set(c.NewTXOpts(seth.WithNoSend(true), seth.WithNonce(stale)), big.NewInt(102))
+		require.NoError(t, err, "failed to create transaction")
+		_, err = c.SendTransaction(ctx, tx)
+		require.ErrorContains(t, err, "nonce too low", "transaction with stale nonce shouldn't be resent")
+	})
+}
@@ -0,0 +1,225 @@
+package seth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// RevertFrameOrigin is the deepest reverting frame, where the revert actually happened
+	RevertFrameOrigin = "origin"
+	// RevertFramePropagated is a frame that bubbled up revert of its sub-call without changing revert data
+	RevertFramePropagated = "propagated"
+	// RevertFrameRewrapped is a frame that reverted with different revert data than its reverting sub-call
+	RevertFrameRewrapped = "rewrapped"
+	// RevertFrameSwallowed is a reverted call, whose revert was caught by the caller, which continued execution
+	RevertFrameSwallowed = "swallowed"
+)
+
+var (
+	revertErrorSelector = []byte{0x08, 0xc3, 0x79, 0xa0} // Error(string)
+	revertPanicSelector = []byte{0x4e, 0x48, 0x7b, 0x71} // Panic(uint256)
+)
+
+// RevertFrame is a single call frame taking part in a revert
+type RevertFrame struct {
+	Kind        string `json:"kind"`
+	Depth       int    `json:"depth"`
+	Type        string `json:"type"`
+	FromAddress string `json:"from_address"`
+	ToAddress   string `json:"to_address"`
+	From        string `json:"from,omitempty"`
+	To          string `json:"to,omitempty"`
+	Method      string `json:"method"`
+	Error       string `json:"error"`
+	Reason      string `json:"reason,omitempty"`
+	RevertData  string `json:"revert_data,omitempty"`
+}
+
+// RevertChain describes where a revert originated and how it propagated to the top-level call. Propagation starts with the
+// origin and ends with the top-level call. Swallowed contains reverted calls that were caught by their callers, they are
+// present also in transactions that didn't revert.
+type RevertChain struct {
+	Origin      *RevertFrame  `json:"origin,omitempty"`
+	Propagation []RevertFrame `json:"propagation,omitempty"`
+	Swallowed   []RevertFrame `json:"swallowed,omitempty"`
+}
+
+// RevertChain finds the original revert site (deepest reverting frame) and propagation chain of the revert, together with
+// all reverted calls that were swallowed by their callers. It returns nil if no call in the trace reverted. Method is set to
+// the 4-byte selector, revert reason only if it was provided by the node.
+func (t *TXCallTraceOutput) RevertChain() *RevertChain {
+	root := t.AsCall()
+	root.Calls = t.Calls
+
+	chain := &RevertChain{}
+	collectSwallowedReverts(root, 0, chain)
+
+	if root.Error != "" {
+		path := []Call{root}
+		for current := root; ; {
+			child, ok := revertingSubCall(current)
+			if !ok {
+				break
+			}
+			path = append(path, child)
+			current = child
+		}
+
+		for i := len(path) - 1; i >= 0; i-- {
+			frame := newRevertFrame(path[i], i)
+			switch {
+			case i == len(path)-1:
+				frame.Kind = RevertFrameOrigin
+			case path[i].Output == path[i+1].Output:
+				frame.Kind = RevertFramePropagated
+			default:
+				frame.Kind = RevertFrameRewrapped
+			}
+			chain.Propagation = append(chain.Propagation, frame)
+		}
+		chain.Origin = &chain.Propagation[0]
+	}
+
+	if chain.Origin == nil && len(chain.Swallowed) == 0 {
+		return nil
+	}
+
+	return chain
+}
+
+// revertingSubCall returns sub-call, whose revert made the call revert, that's the last reverted sub-call, since execution stops
+// at the revert
+func revertingSubCall(call Call) (Call, bool) {
+	for i := len(call.Calls) - 1; i >= 0; i-- {
+		if call.Calls[i].Error != "" {
+			return call.Calls[i], true
+		}
+	}
+	return Call{}, false
+}
+
+func collectSwallowedReverts(call Call, depth int, chain *RevertChain) {
+	reverting := -1
+	if call.Error != "" {
+		for i := len(call.Calls) - 1; i >= 0; i-- {
+			if call.Calls[i].Error != "" {
+				reverting = i
+				break
+			}
+		}
+	}
+
+	for i, sub := range call.Calls {
+		if sub.Error != "" && i != reverting {
+			frame := newRevertFrame(sub, depth+1)
+			frame.Kind = RevertFrameSwallowed
+			chain.Swallowed = append(chain.Swallowed, frame)
+		}
+		collectSwallowedReverts(sub, depth+1, chain)
+	}
+}
+
+func newRevertFrame(call Call, depth int) RevertFrame {
+	method := UNKNOWN
+	if len(call.Input) >= 10 {
+		method = call.Input[2:10]
+	}
+	return RevertFrame{
+		Depth:       depth,
+		Type:        strings.ToUpper(call.Type),
+		FromAddress: call.From,
+		ToAddress:   call.To,
+		Method:      method,
+		Error:       call.Error,
+		Reason:      call.RevertReason,
+		RevertData:  call.Output,
+	}
+}
+
+// decodeRevertChain builds revert chain of the trace with human-readable names of contracts, methods and revert reasons
+func (t *Tracer) decodeRevertChain(trace Trace) *RevertChain {
+	chain := trace.CallTrace.RevertChain()
+	if chain == nil {
+		return nil
+	}
+
+	enrich := func(frame *RevertFrame) {
+		frame.From = t.getHumanReadableAddressName(frame.FromAddress)
+		frame.To = t.getHumanReadableAddressName(frame.ToAddress)
+		if frame.Method != UNKNOWN && t.ABIFinder != nil {
+			if abiResult, err := t.ABIFinder.FindABIByMethod(frame.ToAddress, common.Hex2Bytes(frame.Method)); err == nil {
+				frame.Method = abiResult.Method.Sig
+			}
+		}
+		if reason := t.decodeRevertData(frame.RevertData); reason != "" {
+			frame.Reason = reason
+		}
+	}
+	for i := range chain.Propagation {
+		enrich(&chain.Propagation[i])
+	}
+	for i := range chain.Swallowed {
+		enrich(&chain.Swallowed[i])
+	}
+
+	return chain
+}
+
+// decodeRevertData decodes revert data as Error(string), Panic(uint256) or custom error from any ABI in the contract store
+func (t *Tracer) decodeRevertData(revertData string) string {
+	if revertData == "" {
+		return ""
+	}
+	data, err := hexutil.Decode(revertData)
+	if err != nil {
+		return ""
+	}
+	if reason := decodeRevertReason(t.ContractStore, data); reason != nil {
+		return reason.Message
+	}
+	return ""
+}
+
+// printRevertChain prints where the revert originated and how it propagated, flagging frames that rewrapped or swallowed reverts
+func (t *Tracer) printRevertChain(l zerolog.Logger, chain *RevertChain) {
+	if chain.Origin != nil {
+		steps := make([]string, 0, len(chain.Propagation))
+		for _, f := range chain.Propagation {
+			steps = append(steps, fmt.Sprintf("%s.%s", f.To, f.Method))
+		}
+		l.Info().
+			Str("Contract", chain.Origin.To).
+			Str("Address", chain.Origin.ToAddress).
+			Str("Method", chain.Origin.Method).
+			Int("Depth", chain.Origin.Depth).
+			Str("Reason", chain.Origin.Reason).
+			Str("Propagation", strings.Join(steps, " -> ")).
+			Msg("Revert origin")
+
+		for _, f := range chain.Propagation[1:] {
+			if f.Kind == RevertFrameRewrapped {
+				l.Warn().
+					Str("Contract", f.To).
+					Str("Method", f.Method).
+					Int("Depth", f.Depth).
+					Str("Reason", f.Reason).
+					Msg("Revert was rewrapped, reverted with different data than its sub-call")
+			}
+		}
+	}
+
+	for _, f := range chain.Swallowed {
+		l.Warn().
+			Str("Caller", f.From).
+			Str("Contract", f.To).
+			Str("Method", f.Method).
+			Int("Depth", f.Depth).
+			Str("Reason", f.Reason).
+			Msg("Revert was swallowed by the caller")
+	}
+}
@@ -0,0 +1,151 @@
+package seth
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// RevertReasonError is the name of the built-in Error(string) error used by require() and revert("...")
+	RevertReasonError = "Error"
+	// RevertReasonPanic is the name of the built-in Panic(uint256) error used by assert(), division by zero, overflows, etc.
+	RevertReasonPanic = "Panic"
+)
+
+// RevertReasonParam is a single decoded parameter of revert error
+type RevertReasonParam struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// RevertReason is a decoded revert error. Name is either "Error", "Panic" or name of the custom error, Contract is the name of
+// the ABI that declares the custom error or, for built-in errors, name of the called contract if it's known.
+type RevertReason struct {
+	Name     string              `json:"name"`
+	Selector string              `json:"selector"`
+	Params   []RevertReasonParam `json:"params,omitempty"`
+	Contract string              `json:"contract,omitempty"`
+	Address  string              `json:"address,omitempty"`
+	Message  string              `json:"message"`
+	RawData  string              `json:"raw_data"`
+}
+
+// Param returns value of parameter with given name
+func (r *RevertReason) Param(name string) (interface{}, bool) {
+	for _, p := range r.Params {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+// IsCustomError returns true if revert reason is a custom Solidity error, not Error(string) or Panic(uint256)
+func (r *RevertReason) IsCustomError() bool {
+	return r.Name != RevertReasonError && r.Name != RevertReasonPanic
+}
+
+// revertDataFromErr returns revert data from error returned by eth_call or eth_estimateGas
+func revertDataFromErr(err error) ([]byte, bool) {
+	dataErr, ok := err.(rpc.DataError)
+	if !ok || dataErr.ErrorData() == nil {
+		return nil, false
+	}
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil, false
+	}
+	data, decodeErr := hexutil.Decode(hexData)
+	if decodeErr != nil || len(data) < 4 {
+		return nil, false
+	}
+	return data, true
+}
+
+// decodeRevertReason decodes revert data as Error(string), Panic(uint256) or custom error from any ABI in the contract store,
+// it returns nil if data doesn't match any of them
+func decodeRevertReason(cs *ContractStore, data []byte) *RevertReason {
+	return decodeRevertReasonOf(cs, data, "")
+}
+
+// decodeRevertReasonOf works like decodeRevertReason, but if the same custom error is declared by multiple ABIs, the one of
+// the called contract is preferred, other ABIs are tried in the order of their names
+func decodeRevertReasonOf(cs *ContractStore, data []byte, calledContract string) *RevertReason {
+	if len(data) < 4 {
+		return nil
+	}
+	reason := &RevertReason{
+		Selector: hexutil.Encode(data[:4]),
+		RawData:  hexutil.Encode(data),
+	}
+
+	switch {
+	case bytes.Equal(data[:4], revertErrorSelector):
+		message, err := abi.UnpackRevert(data)
+		if err != nil {
+			return nil
+		}
+		reason.Name = RevertReasonError
+		reason.Params = []RevertReasonParam{{Name: "message", Type: "string", Value: message}}
+		reason.Message = message
+		return reason
+	case bytes.Equal(data[:4], revertPanicSelector):
+		if len(data) != 36 {
+			return nil
+		}
+		code := new(big.Int).SetBytes(data[4:])
+		reason.Name = RevertReasonPanic
+		reason.Params = []RevertReasonParam{{Name: "code", Type: "uint256", Value: code}}
+		reason.Message = fmt.Sprintf("panic code: 0x%x", code)
+		return reason
+	}
+
+	if cs == nil {
+		return nil
+	}
+	abis := cs.ListABIs()
+	names := make([]string, 0, len(abis))
+	for abiName := range abis {
+		names = append(names, abiName)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if iCalled, jCalled := names[i] == calledContract+".abi", names[j] == calledContract+".abi"; iCalled != jCalled {
+			return iCalled
+		}
+		return names[i] < names[j]
+	})
+	for _, abiName := range names {
+		a := abis[abiName]
+		for name, abiError := range a.Errors {
+			if !bytes.Equal(data[:4], abiError.ID.Bytes()[:4]) {
+				continue
+			}
+			v, err := abiError.Unpack(data)
+			if err != nil {
+				continue
+			}
+			values, _ := v.([]interface{})
+			reason.Name = name
+			reason.Contract = strings.TrimSuffix(abiName, ".abi")
+			for i, input := range abiError.Inputs {
+				param := RevertReasonParam{Name: input.Name, Type: input.Type.String()}
+				if i < len(values) {
+					param.Value = values[i]
+				}
+				reason.Params = append(reason.Params, param)
+			}
+			reason.Message = fmt.Sprintf("error type: %s, error values: %v", name, v)
+			return reason
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrRPCNamespaceRegistered = errors.New("RPC namespace is already registered")
+	ErrRPCNamespaceEmpty      = errors.New("RPC namespace prefix cannot be empty")
+	ErrRPCCall                = errors.New("RPC call failed")
+)
+
+// RPCCaller executes any JSON-RPC method, Client implements it, so typed wrappers of chain-specific methods can reuse its connection
+type RPCCaller interface {
+	CallRPC(result interface{}, method string, params ...interface{}) error
+}
+
+// RPCNamespace describes a group of chain-specific JSON-RPC methods sharing the same prefix, e.g. `zks` for `zks_L1ChainId`
+type RPCNamespace struct {
+	Prefix      string
+	Description string
+	Methods     []string
+}
+
+var (
+	rpcNamespacesMu = &sync.RWMutex{}
+	rpcNamespaces   = map[string]RPCNamespace{}
+)
+
+func init() {
+	for _, ns := range []RPCNamespace{
+		{
+			Prefix:      "zks",
+			Description: "zkSync Era",
+			Methods:     []string{"zks_L1ChainId", "zks_getMainContract", "zks_getBridgeContracts", "zks_L1BatchNumber"},
+		},
+		{
+			Prefix:      "arbtrace",
+			Description: "Arbitrum Classic tracing (pre-Nitro blocks)",
+			Methods:     []string{"arbtrace_transaction", "arbtrace_block", "arbtrace_call"},
+		},
+		{
+			Prefix:      "optimism",
+			Description: "OP Stack rollup node",
+			Methods:     []string{"optimism_syncStatus", "optimism_outputAtBlock", "optimism_rollupConfig"},
+		},
+	} {
+		if err := RegisterRPCNamespace(ns); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterRPCNamespace registers a namespace of chain-specific RPC methods, extensions should call it from their init() function
+func RegisterRPCNamespace(ns RPCNamespace) error {
+	if ns.Prefix == "" {
+		return ErrRPCNamespaceEmpty
+	}
+	ns.Prefix = strings.TrimSuffix(ns.Prefix, "_")
+
+	rpcNamespacesMu.Lock()
+	defer rpcNamespacesMu.Unlock()
+	if _, ok := rpcNamespaces[ns.Prefix]; ok {
+		return errors.Wrapf(ErrRPCNamespaceRegistered, "namespace: '%s'", ns.Prefix)
+	}
+	rpcNamespaces[ns.Prefix] = ns
+	return nil
+}
+
+// RegisteredRPCNamespaces returns all registered namespaces sorted by prefix
+func RegisteredRPCNamespaces() []RPCNamespace {
+	rpcNamespacesMu.RLock()
+	defer rpcNamespacesMu.RUnlock()
+	namespaces := make([]RPCNamespace, 0, len(rpcNamespaces))
+	for _, ns := range rpcNamespaces {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Prefix < namespaces[j].Prefix
+	})
+	return namespaces
+}
+
+// RPCNamespaceForMethod returns registered namespace the method belongs to
+func RPCNamespaceForMethod(method string) (RPCNamespace, bool) {
+	prefix, _, found := strings.Cut(method, "_")
+	if !found {
+		return RPCNamespace{}, false
+	}
+	rpcNamespacesMu.RLock()
+	defer rpcNamespacesMu.RUnlock()
+	ns, ok := rpcNamespaces[prefix]
+	return ns, ok
+}
+
+// CallRPC executes any JSON-RPC method using client's connection and unmarshals response into result, which should be a pointer.
+// It's meant for methods not supported by ethclient, like chain-specific ones (see RegisterRPCNamespace).
+func (m *Client) CallRPC(result interface{}, method string, params ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
+	defer cancel()
+
+	l := m.logger().Debug().Str("Method", method)
+	if ns, ok := RPCNamespaceForMethod(method); ok {
+		l = l.Str("Namespace", ns.Description)
+	}
+	l.Interface("Params", params).Msg("Calling RPC method")
+
+	if err := m.Client.Client().CallContext(ctx, result, method, params...); err != nil {
+		return errors.Wrapf(wrapError(err, ErrRPCCall), "method: %s", method)
+	}
+	return nil
+}
+
+// ZkSyncRPC is a typed wrapper of zkSync Era `zks_` RPC methods
+type ZkSyncRPC struct {
+	c RPCCaller
+}
+
+// NewZkSyncRPC creates a new zkSync RPC wrapper
+func NewZkSyncRPC(c RPCCaller) *ZkSyncRPC {
+	return &ZkSyncRPC{c: c}
+}
+
+// L1ChainID returns chain ID of the L1 network
+func (z *ZkSyncRPC) L1ChainID() (*big.Int, error) {
+	var result hexutil.Big
+	if err := z.c.CallRPC(&result, "zks_L1ChainId"); err != nil {
+		return nil, err
+	}
+	return result.ToInt(), nil
+}
+
+// MainContract returns address of the zkSync main contract on L1
+func (z *ZkSyncRPC) MainContract() (common.Address, error) {
+	var result common.Address
+	err := z.c.CallRPC(&result, "zks_getMainContract")
+	return result, err
+}
+
+// L1BatchNumber returns number of the latest L1 batch
+func (z *ZkSyncRPC) L1BatchNumber() (uint64, error) {
+	var result hexutil.Uint64
+	err := z.c.CallRPC(&result, "zks_L1BatchNumber")
+	return uint64(result), err
+}
+
+// ArbTraceRPC is a typed wrapper of Arbitrum `arbtrace_` RPC methods, available only for pre-Nitro blocks
+type ArbTraceRPC struct {
+	c RPCCaller
+}
+
+// NewArbTraceRPC creates a new Arbitrum trace RPC wrapper
+func NewArbTraceRPC(c RPCCaller) *ArbTraceRPC {
+	return &ArbTraceRPC{c: c}
+}
+
+// Transaction returns parity-style traces of the transaction
+func (a *ArbTraceRPC) Transaction(txHash common.Hash) ([]map[string]interface{}, error) {
+	var result []map[string]interface{}
+	err := a.c.CallRPC(&result, "arbtrace_transaction", txHash)
+	return result, err
+}
+
+// OptimismRPC is a typed wrapper of OP Stack rollup node `optimism_` RPC methods, client has to be connected to the rollup node
+type OptimismRPC struct {
+	c RPCCaller
+}
+
+// NewOptimismRPC creates a new OP Stack rollup node RPC wrapper
+func NewOptimismRPC(c RPCCaller) *OptimismRPC {
+	return &OptimismRPC{c: c}
+}
+
+// SyncStatus returns sync status of the rollup node (current L1 block, unsafe/safe/finalized L2 blocks)
+func (o *OptimismRPC) SyncStatus() (map[string]interface{}, error) {
+	var result map[string]interface{}
+	err := o.c.CallRPC(&result, "optimism_syncStatus")
+	return result, err
+}
+
+// OutputAtBlock returns output root of given L2 block
+func (o *OptimismRPC) OutputAtBlock(blockNumber uint64) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	err := o.c.CallRPC(&result, "optimism_outputAtBlock", hexutil.Uint64(blockNumber))
+	return result, err
+}
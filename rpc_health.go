@@ -0,0 +1,228 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+)
+
+const (
+	// RPCHealthCheckMode_Transaction sends 10k wei from root key to root key and waits for it to be mined
+	RPCHealthCheckMode_Transaction = "transaction"
+	// RPCHealthCheckMode_ReadOnly only reads from the node, so it doesn't cost anything and works without funded keys
+	RPCHealthCheckMode_ReadOnly = "read_only"
+
+	DefaultRPCHealthCheckBlockProgressionTimeout = 1 * time.Minute
+)
+
+var (
+	ErrRpcHealthCheckNodeSyncing = errors.New("node is syncing")
+	ErrRpcHealthCheckNoNewBlock  = errors.New("no new block was produced")
+	ErrRpcHealthCheckChainID     = errors.New("node returned unexpected chain ID")
+	ErrRpcHealthCheckStaleBlock  = errors.New("latest block is too old")
+	ErrRpcHealthCheckGasPrice    = errors.New("suggested gas price is too high")
+)
+
+// RPCHealthCheckCfg configures RPC health check executed on start, if `check_rpc_health_on_start` is enabled
+type RPCHealthCheckCfg struct {
+	// Mode is either "transaction" (default) or "read_only"
+	Mode string `toml:"mode"`
+	// BlockProgressionTimeout is how long read-only check waits for a new block, by default it waits 1 minute on live networks
+	// and doesn't wait on simulated ones, which might produce blocks only when transactions are sent
+	BlockProgressionTimeout *Duration `toml:"block_progression_timeout"`
+	// CallTo and CallData define optional eth_call executed by read-only check
+	CallTo   string `toml:"call_to"`
+	CallData string `toml:"call_data"`
+	// Timeout of the check (excluding waiting for a new block in read-only mode), by default network's 'transaction_timeout'
+	Timeout *Duration `toml:"timeout"`
+	// ChainID is the chain ID, which node has to return in read-only mode, 0 means any
+	ChainID int64 `toml:"chain_id"`
+	// MaxBlockAge is the maximum age of the latest block in read-only mode, 0 means it's not checked
+	MaxBlockAge *Duration `toml:"max_block_age"`
+	// MaxGasPrice is the maximum gas price suggested by the node in read-only mode, 0 means any
+	MaxGasPrice Amount `toml:"max_gas_price"`
+}
+
+func (c *Config) rpcHealthCheckMode() string {
+	if c.RPCHealthCheck == nil || c.RPCHealthCheck.Mode == "" {
+		return RPCHealthCheckMode_Transaction
+	}
+	return c.RPCHealthCheck.Mode
+}
+
+// rpcHealthCheckTimeout returns timeout of the health check, which is network's transaction timeout, unless it's configured
+func (c *Config) rpcHealthCheckTimeout() time.Duration {
+	if c.RPCHealthCheck != nil && c.RPCHealthCheck.Timeout != nil && c.RPCHealthCheck.Timeout.Duration() > 0 {
+		return c.RPCHealthCheck.Timeout.Duration()
+	}
+	return c.Network.TxnTimeout.Duration()
+}
+
+func validateRPCHealthCheck(cfg *RPCHealthCheckCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	switch cfg.Mode {
+	case "", RPCHealthCheckMode_Transaction, RPCHealthCheckMode_ReadOnly:
+	default:
+		return fmt.Errorf("RPC health check mode must be either '%s' or '%s', got '%s'", RPCHealthCheckMode_Transaction, RPCHealthCheckMode_ReadOnly, cfg.Mode)
+	}
+	if cfg.CallTo != "" && !common.IsHexAddress(cfg.CallTo) {
+		return fmt.Errorf("RPC health check 'call_to' is not a valid address: %s", cfg.CallTo)
+	}
+	if cfg.CallData != "" {
+		if cfg.CallTo == "" {
+			return errors.New("RPC health check 'call_data' is set, but 'call_to' is not")
+		}
+		if _, err := hexutil.Decode(cfg.CallData); err != nil {
+			return fmt.Errorf("RPC health check 'call_data' is not valid 0x-prefixed hex: %s", cfg.CallData)
+		}
+	}
+	if cfg.Timeout != nil && cfg.Timeout.Duration() < 0 {
+		return errors.New("RPC health check 'timeout' must be greater than or equal to 0")
+	}
+	if cfg.MaxBlockAge != nil && cfg.MaxBlockAge.Duration() < 0 {
+		return errors.New("RPC health check 'max_block_age' must be greater than or equal to 0")
+	}
+	if cfg.ChainID < 0 {
+		return errors.New("RPC health check 'chain_id' must be greater than or equal to 0")
+	}
+	if cfg.MaxGasPrice.Wei().Sign() < 0 {
+		return errors.New("RPC health check 'max_gas_price' must be greater than or equal to 0")
+	}
+	return nil
+}
+
+func (m *Client) checkRPCHealth() error {
+	m.logger().Info().Str("RPC node", m.URL).Msg("---------------- !!!!! ----------------> Checking RPC health")
+	ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.rpcHealthCheckTimeout())
+	defer cancel()
+
+	err := m.TransferETHFromKeyWithFees(ctx, 0, m.Addresses[0].Hex(), big.NewInt(10_000), m.SuggestedTransferFees(ctx))
+	if err != nil {
+		return wrapError(err, ErrRpcHealthCheckFailed)
+	}
+
+	m.logger().Info().Msg("RPC health check passed <---------------- !!!!! ----------------")
+	return nil
+}
+
+// checkRPCHealthReadOnly checks chain ID, that node isn't syncing, produces new blocks, suggests sane gas price and returns
+// pending nonce of root key without sending any transaction. If configured it also checks age of the latest block and
+// executes an eth_call.
+func (m *Client) checkRPCHealthReadOnly() error {
+	m.logger().Info().Str("RPC node", m.URL).Msg("---------------- !!!!! ----------------> Checking RPC health (read-only)")
+	hcCfg := m.Cfg.RPCHealthCheck
+
+	progressionTimeout := time.Duration(0)
+	if hcCfg.BlockProgressionTimeout != nil {
+		progressionTimeout = hcCfg.BlockProgressionTimeout.Duration()
+	} else if !m.Cfg.IsSimulatedNetwork() {
+		progressionTimeout = DefaultRPCHealthCheckBlockProgressionTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.rpcHealthCheckTimeout()+progressionTimeout)
+	defer cancel()
+
+	chainID, err := m.Client.ChainID(ctx)
+	if err != nil {
+		return wrapError(errors.Wrap(err, "failed to get chain ID"), ErrRpcHealthCheckFailed)
+	}
+	expectedChainID := m.ChainID
+	if hcCfg.ChainID != 0 {
+		expectedChainID = hcCfg.ChainID
+	}
+	if chainID.Int64() != expectedChainID {
+		return wrapError(errors.Wrapf(ErrRpcHealthCheckChainID, "chain ID: %d, expected: %d", chainID.Int64(), expectedChainID), ErrRpcHealthCheckFailed)
+	}
+
+	progress, err := m.Client.SyncProgress(ctx)
+	if err != nil {
+		return wrapError(errors.Wrap(err, "failed to get sync status"), ErrRpcHealthCheckFailed)
+	}
+	if progress != nil {
+		return wrapError(errors.Wrapf(ErrRpcHealthCheckNodeSyncing, "current block: %d, highest block: %d", progress.CurrentBlock, progress.HighestBlock), ErrRpcHealthCheckFailed)
+	}
+
+	startBlock, err := m.Client.BlockNumber(ctx)
+	if err != nil {
+		return wrapError(errors.Wrap(err, "failed to get latest block number"), ErrRpcHealthCheckFailed)
+	}
+	if hcCfg.MaxBlockAge != nil && hcCfg.MaxBlockAge.Duration() > 0 {
+		header, err := m.Client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return wrapError(errors.Wrap(err, "failed to get latest block header"), ErrRpcHealthCheckFailed)
+		}
+		age := time.Since(time.Unix(int64(header.Time), 0))
+		if age > hcCfg.MaxBlockAge.Duration() {
+			return wrapError(errors.Wrapf(ErrRpcHealthCheckStaleBlock, "block: %d, age: %s, max age: %s", header.Number.Uint64(), age.Round(time.Second), hcCfg.MaxBlockAge.Duration()), ErrRpcHealthCheckFailed)
+		}
+	}
+	if progressionTimeout > 0 {
+		if err := m.waitForNewBlock(ctx, startBlock, progressionTimeout); err != nil {
+			return wrapError(err, ErrRpcHealthCheckFailed)
+		}
+	}
+
+	gasPrice, err := m.Client.SuggestGasPrice(ctx)
+	if err != nil {
+		return wrapError(errors.Wrap(err, "failed to get suggested gas price"), ErrRpcHealthCheckFailed)
+	}
+	if gasPrice.Sign() == 0 {
+		m.logger().Warn().Msg("Node suggests 0 gas price, gas price estimations might not work")
+	}
+	if !hcCfg.MaxGasPrice.IsZero() && gasPrice.Cmp(hcCfg.MaxGasPrice.Wei()) > 0 {
+		return wrapError(errors.Wrapf(ErrRpcHealthCheckGasPrice, "gas price: %s wei, max gas price: %d wei", gasPrice.String(), hcCfg.MaxGasPrice.Wei()), ErrRpcHealthCheckFailed)
+	}
+
+	if len(m.Addresses) > 0 {
+		nonce, err := m.Client.PendingNonceAt(ctx, m.Addresses[0])
+		if err != nil {
+			return wrapError(errors.Wrap(err, "failed to get pending nonce of root key"), ErrRpcHealthCheckFailed)
+		}
+		m.logger().Debug().Str("Address", m.Addresses[0].Hex()).Uint64("Nonce", nonce).Msg("Fetched pending nonce of root key")
+	}
+
+	if hcCfg.CallTo != "" {
+		to := common.HexToAddress(hcCfg.CallTo)
+		msg := ethereum.CallMsg{To: &to}
+		if hcCfg.CallData != "" {
+			msg.Data = hexutil.MustDecode(hcCfg.CallData)
+		}
+		if _, err := m.Client.CallContract(ctx, msg, nil); err != nil {
+			return wrapError(errors.Wrapf(err, "eth_call to %s failed", hcCfg.CallTo), ErrRpcHealthCheckFailed)
+		}
+	}
+
+	m.logger().Info().Msg("RPC health check passed <---------------- !!!!! ----------------")
+	return nil
+}
+
+func (m *Client) waitForNewBlock(ctx context.Context, startBlock uint64, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case <-deadline:
+			return errors.Wrapf(ErrRpcHealthCheckNoNewBlock, "timeout: %s, latest block: %d", timeout, startBlock)
+		case <-ctx.Done():
+			return errors.Wrapf(ErrRpcHealthCheckNoNewBlock, "timeout: %s, latest block: %d", timeout, startBlock)
+		case <-time.After(m.Cfg.Network.ReceiptPollingDelay(0)):
+		}
+
+		latest, err := m.Client.BlockNumber(ctx)
+		if err != nil {
+			m.logger().Debug().Err(err).Msg("Failed to get latest block number, while waiting for new block")
+			continue
+		}
+		if latest > startBlock {
+			m.logger().Debug().Uint64("Start block", startBlock).Uint64("Latest block", latest).Msg("Node is producing new blocks")
+			return nil
+		}
+	}
+}
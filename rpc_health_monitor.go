@@ -0,0 +1,384 @@
+package seth
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultRPCHealthMonitorInterval = 10 * time.Second
+	DefaultRPCHealthMonitorTimeout  = 5 * time.Second
+)
+
+var (
+	ErrRpcHealthMonitorLatency  = errors.New("latency is too high")
+	ErrRpcHealthMonitorBlockLag = errors.New("endpoint is too many blocks behind the best one")
+)
+
+// RPCHealthMonitorCfg configures monitor, which periodically checks liveness, latency and block lag of every RPC endpoint
+type RPCHealthMonitorCfg struct {
+	// Interval between checks, default 10s
+	Interval *Duration `toml:"interval"`
+	// Timeout of a single check of an endpoint, default 5s
+	Timeout *Duration `toml:"timeout"`
+	// MaxLatency is the maximum latency of eth_blockNumber call, 0 means it's not checked
+	MaxLatency *Duration `toml:"max_latency"`
+	// MaxBlockLag is the maximum number of blocks endpoint can be behind the best endpoint, 0 means it's not checked
+	MaxBlockLag uint64 `toml:"max_block_lag"`
+	// FailureThreshold is the number of consecutive failed checks, after which endpoint is degraded, default 1
+	FailureThreshold int `toml:"failure_threshold"`
+}
+
+func validateRPCHealthMonitorCfg(cfg *RPCHealthMonitorCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Interval != nil && cfg.Interval.Duration() < 0 {
+		return errors.New("RPC health monitor 'interval' must be greater than or equal to 0")
+	}
+	if cfg.Timeout != nil && cfg.Timeout.Duration() < 0 {
+		return errors.New("RPC health monitor 'timeout' must be greater than or equal to 0")
+	}
+	if cfg.MaxLatency != nil && cfg.MaxLatency.Duration() < 0 {
+		return errors.New("RPC health monitor 'max_latency' must be greater than or equal to 0")
+	}
+	if cfg.FailureThreshold < 0 {
+		return errors.New("RPC health monitor 'failure_threshold' must be greater than or equal to 0")
+	}
+	return nil
+}
+
+// EndpointHealth is the result of the latest checks of an RPC endpoint
+type EndpointHealth struct {
+	// Index of the endpoint in network's RPC URLs
+	Index int
+	// Host of the endpoint, full URL isn't exposed, because it might contain secrets
+	Host    string
+	Healthy bool
+	// Latency of the last successful eth_blockNumber call
+	Latency     time.Duration
+	BlockNumber uint64
+	// BlockLag is the number of blocks the endpoint is behind the best endpoint
+	BlockLag uint64
+	// Error of the last check, empty if it passed
+	Error               string
+	ConsecutiveFailures int
+	CheckedAt           time.Time
+}
+
+// RPCHealth is the health of all RPC endpoints
+type RPCHealth struct {
+	// Healthy is true if the endpoint currently used for RPC calls is healthy
+	Healthy bool
+	// Current is the index of the endpoint currently used for RPC calls
+	Current   int
+	Endpoints []EndpointHealth
+	CheckedAt time.Time
+}
+
+// endpointSelector chooses endpoint used for RPC calls, it's implemented by failoverTransport
+type endpointSelector interface {
+	selected() int
+	selectEndpoint(idx int)
+}
+
+// RPCHealthMonitor periodically calls eth_blockNumber on every RPC endpoint and marks endpoints, that fail, respond
+// slowly or fall behind the best endpoint, as degraded. If RPC calls fail over between multiple HTTP endpoints, they're
+// sent to the first healthy endpoint in the order of failover, so they move away from degraded endpoints and come
+// back to preferred ones, when they recover.
+type RPCHealthMonitor struct {
+	mu          *sync.Mutex
+	cfg         RPCHealthMonitorCfg
+	urls        []string
+	clients     []*rpc.Client
+	endpoints   []EndpointHealth
+	checkedAt   time.Time
+	selectors   []endpointSelector
+	dialOptions func(url string) []rpc.ClientOption
+	started     bool
+	onDegraded  []func(EndpointHealth)
+	onRecovered []func(EndpointHealth)
+}
+
+// NewRPCHealthMonitor creates a new RPC health monitor, zero values in config are replaced with defaults
+func NewRPCHealthMonitor(cfg RPCHealthMonitorCfg) *RPCHealthMonitor {
+	if cfg.Interval == nil || cfg.Interval.Duration() == 0 {
+		cfg.Interval = MustMakeDuration(DefaultRPCHealthMonitorInterval)
+	}
+	if cfg.Timeout == nil || cfg.Timeout.Duration() == 0 {
+		cfg.Timeout = MustMakeDuration(DefaultRPCHealthMonitorTimeout)
+	}
+	if cfg.FailureThreshold == 0 {
+		cfg.FailureThreshold = 1
+	}
+	return &RPCHealthMonitor{
+		mu:  &sync.Mutex{},
+		cfg: cfg,
+	}
+}
+
+// OnDegraded registers a hook called, when an endpoint becomes degraded
+func (h *RPCHealthMonitor) OnDegraded(fn func(EndpointHealth)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onDegraded = append(h.onDegraded, fn)
+}
+
+// OnRecovered registers a hook called, when a degraded endpoint becomes healthy again
+func (h *RPCHealthMonitor) OnRecovered(fn func(EndpointHealth)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onRecovered = append(h.onRecovered, fn)
+}
+
+// Health returns the result of the latest check of all endpoints
+func (h *RPCHealthMonitor) Health() RPCHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	health := RPCHealth{
+		Endpoints: append([]EndpointHealth{}, h.endpoints...),
+		CheckedAt: h.checkedAt,
+	}
+	if len(h.selectors) > 0 {
+		health.Current = h.selectors[0].selected()
+	}
+	if health.Current < len(health.Endpoints) {
+		health.Healthy = health.Endpoints[health.Current].Healthy
+	}
+	return health
+}
+
+// Start checks endpoints once and then keeps checking them in the background until the context is cancelled
+func (h *RPCHealthMonitor) Start(ctx context.Context, urls []string) {
+	h.start(ctx, urls, nil, nil)
+}
+
+func (h *RPCHealthMonitor) start(ctx context.Context, urls []string, selector endpointSelector, dialOptions func(url string) []rpc.ClientOption) {
+	h.mu.Lock()
+	if h.started {
+		h.mu.Unlock()
+		return
+	}
+	h.started = true
+	h.urls = urls
+	h.dialOptions = dialOptions
+	h.clients = make([]*rpc.Client, len(urls))
+	h.endpoints = make([]EndpointHealth, len(urls))
+	for i, u := range urls {
+		// endpoints are healthy until they fail a check
+		h.endpoints[i] = EndpointHealth{Index: i, Host: endpointHost(u), Healthy: true}
+	}
+	if selector != nil {
+		h.selectors = append(h.selectors, selector)
+	}
+	h.mu.Unlock()
+
+	h.Check(ctx)
+	go func() {
+		defer h.closeClients()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(h.cfg.Interval.Duration()):
+				h.Check(ctx)
+			}
+		}
+	}()
+}
+
+// addSelector makes the monitor choose endpoint used by another failover transport, e.g. tracer's one
+func (h *RPCHealthMonitor) addSelector(selector endpointSelector) {
+	h.mu.Lock()
+	h.selectors = append(h.selectors, selector)
+	h.mu.Unlock()
+	h.selectHealthyEndpoint()
+}
+
+// Check checks all endpoints concurrently, updates their health, calls hooks of endpoints, whose health changed, and
+// chooses endpoint used for RPC calls
+func (h *RPCHealthMonitor) Check(ctx context.Context) {
+	h.mu.Lock()
+	urls := h.urls
+	h.mu.Unlock()
+
+	type result struct {
+		block   uint64
+		latency time.Duration
+		err     error
+	}
+	results := make([]result, len(urls))
+	wg := &sync.WaitGroup{}
+	for i := range urls {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i].block, results[i].latency, results[i].err = h.checkEndpoint(ctx, i)
+		}(i)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+
+	var best uint64
+	for _, r := range results {
+		if r.err == nil && r.block > best {
+			best = r.block
+		}
+	}
+
+	now := time.Now()
+	var degraded, recovered []EndpointHealth
+	h.mu.Lock()
+	for i, r := range results {
+		e := h.endpoints[i]
+		e.CheckedAt = now
+		err := r.err
+		if err == nil {
+			e.Latency = r.latency
+			e.BlockNumber = r.block
+			e.BlockLag = best - r.block
+			if h.cfg.MaxLatency != nil && h.cfg.MaxLatency.Duration() > 0 && r.latency > h.cfg.MaxLatency.Duration() {
+				err = errors.Wrapf(ErrRpcHealthMonitorLatency, "latency: %s, max latency: %s", r.latency, h.cfg.MaxLatency.Duration())
+			} else if h.cfg.MaxBlockLag > 0 && e.BlockLag > h.cfg.MaxBlockLag {
+				err = errors.Wrapf(ErrRpcHealthMonitorBlockLag, "block lag: %d, max block lag: %d", e.BlockLag, h.cfg.MaxBlockLag)
+			}
+		}
+
+		wasHealthy := e.Healthy
+		if err != nil {
+			e.Error = err.Error()
+			e.ConsecutiveFailures++
+			e.Healthy = wasHealthy && e.ConsecutiveFailures < h.cfg.FailureThreshold
+		} else {
+			e.Error = ""
+			e.ConsecutiveFailures = 0
+			e.Healthy = true
+		}
+		h.endpoints[i] = e
+
+		switch {
+		case wasHealthy && !e.Healthy:
+			degraded = append(degraded, e)
+		case !wasHealthy && e.Healthy:
+			recovered = append(recovered, e)
+		}
+	}
+	h.checkedAt = now
+	onDegraded, onRecovered := h.onDegraded, h.onRecovered
+	h.mu.Unlock()
+
+	for _, e := range degraded {
+		L.Warn().
+			Int("Endpoint", e.Index).
+			Str("Host", e.Host).
+			Str("Error", e.Error).
+			Msg("RPC endpoint is degraded")
+		for _, fn := range onDegraded {
+			fn(e)
+		}
+	}
+	for _, e := range recovered {
+		L.Info().
+			Int("Endpoint", e.Index).
+			Str("Host", e.Host).
+			Msg("RPC endpoint recovered")
+		for _, fn := range onRecovered {
+			fn(e)
+		}
+	}
+	h.selectHealthyEndpoint()
+}
+
+// checkEndpoint returns the latest block number of the endpoint and latency of the call
+func (h *RPCHealthMonitor) checkEndpoint(ctx context.Context, idx int) (uint64, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.Timeout.Duration())
+	defer cancel()
+
+	h.mu.Lock()
+	client := h.clients[idx]
+	dialOptions := h.dialOptions
+	h.mu.Unlock()
+	if client == nil {
+		var opts []rpc.ClientOption
+		if dialOptions != nil {
+			opts = dialOptions(h.urls[idx])
+		}
+		var err error
+		// each endpoint is dialled separately, so that calls don't fail over to other endpoints
+		client, err = rpc.DialOptions(ctx, h.urls[idx], opts...)
+		if err != nil {
+			return 0, 0, err
+		}
+		h.mu.Lock()
+		h.clients[idx] = client
+		h.mu.Unlock()
+	}
+
+	var block hexutil.Uint64
+	start := time.Now()
+	if err := client.CallContext(ctx, &block, "eth_blockNumber"); err != nil {
+		return 0, 0, err
+	}
+	return uint64(block), time.Since(start), nil
+}
+
+// selectHealthyEndpoint sends RPC calls to the first healthy endpoint, calls aren't redirected if all endpoints are degraded
+func (h *RPCHealthMonitor) selectHealthyEndpoint() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	preferred := -1
+	for _, e := range h.endpoints {
+		if e.Healthy {
+			preferred = e.Index
+			break
+		}
+	}
+	if preferred == -1 {
+		return
+	}
+	for _, s := range h.selectors {
+		if current := s.selected(); current != preferred {
+			s.selectEndpoint(preferred)
+			L.Info().
+				Int("From", current).
+				Int("To", preferred).
+				Msg("Switched RPC endpoint based on its health")
+		}
+	}
+}
+
+func (h *RPCHealthMonitor) closeClients() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, c := range h.clients {
+		if c != nil {
+			c.Close()
+			h.clients[i] = nil
+		}
+	}
+}
+
+func endpointHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// RPCHealth returns the result of the latest check of RPC endpoints done by RPC health monitor, it's empty if the monitor
+// isn't enabled
+func (m *Client) RPCHealth() RPCHealth {
+	if m.RPCHealthMonitor == nil {
+		return RPCHealth{}
+	}
+	return m.RPCHealthMonitor.Health()
+}
@@ -0,0 +1,114 @@
+package seth
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrRPCAuthConflict = errors.New("network can use either 'basic_auth' or 'bearer_token_secret', not both")
+)
+
+// BasicAuthCfg are credentials sent with every RPC request in Authorization header
+type BasicAuthCfg struct {
+	Username string `toml:"username"`
+	Password string `toml:"password_secret"`
+}
+
+func validateRPCAuth(n *Network) error {
+	if n.BasicAuth != nil && n.BearerToken != "" {
+		return errors.Wrapf(ErrRPCAuthConflict, "network: '%s'", n.Name)
+	}
+	if n.BasicAuth != nil && n.BasicAuth.Username == "" {
+		return errors.New("'basic_auth' requires 'username'")
+	}
+	return nil
+}
+
+// rpcHeaderOptions returns options setting static headers and authentication of RPC requests and websocket handshakes
+func (n *Network) rpcHeaderOptions() []rpc.ClientOption {
+	var opts []rpc.ClientOption
+	if len(n.HTTPHeaders) > 0 {
+		headers := http.Header{}
+		for k, v := range n.HTTPHeaders {
+			headers.Set(k, v)
+		}
+		opts = append(opts, rpc.WithHeaders(headers))
+	}
+	if n.BasicAuth == nil && n.BearerToken == "" && n.rpcHeaders == nil {
+		return opts
+	}
+	basicAuth, bearerToken, dynamic := n.BasicAuth, n.BearerToken, n.rpcHeaders
+	return append(opts, rpc.WithHTTPAuth(func(h http.Header) error {
+		if basicAuth != nil {
+			credentials := base64.StdEncoding.EncodeToString([]byte(basicAuth.Username + ":" + basicAuth.Password))
+			h.Set("Authorization", "Basic "+credentials)
+		}
+		if bearerToken != "" {
+			h.Set("Authorization", "Bearer "+bearerToken)
+		}
+		if dynamic != nil {
+			return dynamic(h)
+		}
+		return nil
+	}))
+}
+
+// rpcDialOptions returns options of connection to the URL, HTTP requests are sent with network's HTTP client and transport
+func (n *Network) rpcDialOptions(rawURL string) []rpc.ClientOption {
+	opts := n.rpcHeaderOptions()
+	if isHTTPURL(rawURL) {
+		opts = append(opts, rpc.WithHTTPClient(n.rpcHTTPClient(n.rpcHTTPTransport())))
+	}
+	return opts
+}
+
+// rpcHTTPClient returns a copy of HTTP client set with WithHTTPClient (or a new one), which sends requests with the transport,
+// cached results of read-only calls are returned without sending them, if 'read_cache' is set
+func (n *Network) rpcHTTPClient(transport http.RoundTripper) *http.Client {
+	client := &http.Client{}
+	if n.httpClient != nil {
+		*client = *n.httpClient
+	}
+	if n.readCache != nil {
+		transport = n.readCache.wrap(transport)
+	}
+	client.Transport = transport
+	return client
+}
+
+// rpcBaseTransport returns transport recording or replaying requests, if 'rpc_recording' is set, otherwise transport of
+// HTTP client set with WithHTTPClient or the default one
+func (n *Network) rpcBaseTransport() http.RoundTripper {
+	if n.rpcRecording != nil {
+		return n.rpcRecording
+	}
+	return n.rpcClientTransport()
+}
+
+// rpcClientTransport returns transport of HTTP client set with WithHTTPClient or the default one
+func (n *Network) rpcClientTransport() http.RoundTripper {
+	if n.httpClient != nil && n.httpClient.Transport != nil {
+		return n.httpClient.Transport
+	}
+	return http.DefaultTransport
+}
+
+// WithHTTPClient makes all RPC connections of the network send HTTP requests with the client, e.g. with custom TLS config,
+// proxy or timeout. Seth wraps its transport to fail over between URLs and to limit rate of requests.
+func WithHTTPClient(client *http.Client) ClientOpt {
+	return func(c *Client) {
+		c.Cfg.Network.httpClient = client
+	}
+}
+
+// WithRPCHeaders sets function called before every HTTP RPC request and websocket handshake, which can set headers of
+// the request, e.g. a refreshed JWT token. It's called after static headers and credentials from the config are set.
+func WithRPCHeaders(fn func(h http.Header) error) ClientOpt {
+	return func(c *Client) {
+		c.Cfg.Network.rpcHeaders = fn
+	}
+}
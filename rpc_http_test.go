@@ -0,0 +1,101 @@
+package seth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+type countingTransport struct {
+	requests atomic.Int32
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestAPIRPCHeaders(t *testing.T) {
+	c := newClient(t)
+	if !strings.HasPrefix(c.URL, "ws://") {
+		t.Skip("test requires a websocket RPC URL")
+	}
+	httpURL := strings.Replace(strings.Replace(c.URL, "ws://", "http://", 1), ":8546", ":8545", 1)
+	if _, err := http.Post(httpURL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`)); err != nil {
+		t.Skipf("HTTP RPC endpoint %s is not available", httpURL)
+	}
+	target, err := url.Parse(httpURL)
+	require.NoError(t, err, "failed to parse HTTP URL")
+
+	// authenticating proxy in front of the node
+	var token atomic.Value
+	token.Store("token-1")
+	var unauthorized atomic.Int32
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	authProxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if r.Header.Get("X-API-Key") != "key" || r.Header.Get("X-Token") != token.Load().(string) || !ok || user != "seth" || password != "secret" {
+			unauthorized.Add(1)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(authProxy.Close)
+
+	newConfig := func(t *testing.T) *seth.Config {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Network.URLs = []string{authProxy.URL}
+		cfg.Network.Endpoints = nil
+		return cfg
+	}
+
+	t.Run("requests without credentials are rejected", func(t *testing.T) {
+		_, err := seth.NewClientWithConfig(newConfig(t))
+		require.Error(t, err, "client shouldn't connect without credentials")
+	})
+
+	t.Run("static and dynamic headers are sent", func(t *testing.T) {
+		cfg := newConfig(t)
+		cfg.Network.HTTPHeaders = map[string]string{"X-API-Key": "key"}
+		cfg.Network.BasicAuth = &seth.BasicAuthCfg{Username: "seth", Password: "secret"}
+		require.NoError(t, seth.ValidateConfig(cfg), "auth config should be valid")
+
+		transport := &countingTransport{}
+		client, err := seth.NewClientWithConfig(cfg,
+			seth.WithHTTPClient(&http.Client{Transport: transport}),
+			seth.WithRPCHeaders(func(h http.Header) error {
+				h.Set("X-Token", token.Load().(string))
+				return nil
+			}),
+		)
+		require.NoError(t, err, "failed to create client")
+		t.Cleanup(func() { _ = client.Close() })
+		unauthorized.Store(0)
+
+		token.Store("token-2")
+		_, err = client.Client.BlockNumber(context.Background())
+		require.NoError(t, err, "refreshed token should be sent")
+		// transaction doesn't exist, but the request has to reach the node
+		_ = client.Tracer.TraceGethTXCtx(context.Background(), "0x0000000000000000000000000000000000000000000000000000000000000001")
+		require.Equal(t, int32(0), unauthorized.Load(), "all requests, including tracer's ones, should be authorized")
+		require.NotZero(t, transport.requests.Load(), "requests should be sent with the custom HTTP client")
+	})
+}
+
+func TestConfigRPCAuth(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.Network.BasicAuth = &seth.BasicAuthCfg{Username: "seth", Password: "secret"}
+	cfg.Network.BearerToken = "token"
+	require.Error(t, seth.ValidateConfig(cfg), "basic auth and bearer token can't be used together")
+}
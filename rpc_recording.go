@@ -0,0 +1,325 @@
+package seth
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// RPCRecordingMode_Record sends RPC requests to the node and saves them with their responses to the file
+	RPCRecordingMode_Record = "record"
+	// RPCRecordingMode_Replay serves RPC requests with responses from the file without connecting to the node
+	RPCRecordingMode_Replay = "replay"
+
+	DefaultRPCRecordingFile = "rpc_recording.jsonl"
+)
+
+var (
+	ErrRPCRecordingMode   = errors.New("invalid RPC recording mode")
+	ErrReadRPCRecording   = errors.New("failed to read RPC recording")
+	ErrWriteRPCRecording  = errors.New("failed to write RPC recording")
+	ErrNoRecordedResponse = errors.New("no recorded response")
+)
+
+// RPCRecordingCfg configures recording of HTTP RPC requests and their replaying in tests
+type RPCRecordingCfg struct {
+	// Mode is either "record" or "replay"
+	Mode string `toml:"mode"`
+	// File is the path of the recording (relative to working directory), default 'rpc_recording.jsonl'
+	File string `toml:"file"`
+}
+
+func validateRPCRecordingCfg(cfg *RPCRecordingCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	switch cfg.Mode {
+	case RPCRecordingMode_Record, RPCRecordingMode_Replay:
+	default:
+		return errors.Wrapf(ErrRPCRecordingMode, "mode must be either '%s' or '%s', got '%s'", RPCRecordingMode_Record, RPCRecordingMode_Replay, cfg.Mode)
+	}
+	if cfg.File == "" {
+		cfg.File = DefaultRPCRecordingFile
+	}
+	return nil
+}
+
+// RPCInteraction is a single JSON-RPC call and its result or error, it's a line of the recording
+type RPCInteraction struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  json.RawMessage `json:"error,omitempty"`
+}
+
+type jsonrpcMessage struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+// parseJsonrpcMessages parses single JSON-RPC message or a batch of them, it returns true if it was a batch
+func parseJsonrpcMessages(body []byte) ([]jsonrpcMessage, bool, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var msgs []jsonrpcMessage
+		err := json.Unmarshal(body, &msgs)
+		return msgs, true, err
+	}
+	var msg jsonrpcMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, false, err
+	}
+	return []jsonrpcMessage{msg}, false, nil
+}
+
+// jsonrpcResponse returns HTTP response with JSON-RPC message as its body
+func jsonrpcResponse(req *http.Request, msg interface{}) (*http.Response, error) {
+	out, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(out)),
+		ContentLength: int64(len(out)),
+		Request:       req,
+	}, nil
+}
+
+// interactionKey identifies the call by method and compacted params, request IDs are ignored
+func interactionKey(method string, params json.RawMessage) string {
+	compacted := &bytes.Buffer{}
+	if err := json.Compact(compacted, params); err != nil {
+		return method + string(params)
+	}
+	return method + compacted.String()
+}
+
+// RPCRecorder is an HTTP transport, which sends JSON-RPC requests with the base transport and appends every call and
+// its response to the recording file. Use it as transport of HTTP client passed with WithHTTPClient or enable it with
+// 'rpc_recording' config.
+type RPCRecorder struct {
+	mu   *sync.Mutex
+	base http.RoundTripper
+	file *os.File
+	w    *bufio.Writer
+}
+
+// NewRPCRecorder creates a new recorder writing to the file, the file is truncated
+func NewRPCRecorder(path string, base http.RoundTripper) (*RPCRecorder, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return nil, wrapError(err, ErrWriteRPCRecording)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, wrapError(err, ErrWriteRPCRecording)
+	}
+	return &RPCRecorder{mu: &sync.Mutex{}, base: base, file: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (r *RPCRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	resp, err := r.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	if err := r.record(reqBody, respBody); err != nil {
+		L.Warn().Err(err).Msg("Failed to record RPC call")
+	}
+	return resp, nil
+}
+
+// record matches requests with responses by ID and appends them to the recording
+func (r *RPCRecorder) record(reqBody, respBody []byte) error {
+	requests, _, err := parseJsonrpcMessages(reqBody)
+	if err != nil {
+		return err
+	}
+	responses, _, err := parseJsonrpcMessages(respBody)
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]jsonrpcMessage, len(responses))
+	for _, resp := range responses {
+		byID[string(resp.ID)] = resp
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, req := range requests {
+		resp, ok := byID[string(req.ID)]
+		if !ok {
+			continue
+		}
+		line, err := json.Marshal(RPCInteraction{Method: req.Method, Params: req.Params, Result: resp.Result, Error: resp.Error})
+		if err != nil {
+			return err
+		}
+		if _, err := r.w.Write(append(line, '\n')); err != nil {
+			return wrapError(err, ErrWriteRPCRecording)
+		}
+	}
+	return r.w.Flush()
+}
+
+// Close flushes and closes the recording file
+func (r *RPCRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); err != nil {
+		_ = r.file.Close()
+		return wrapError(err, ErrWriteRPCRecording)
+	}
+	return r.file.Close()
+}
+
+// RPCReplayer is an HTTP transport, which answers JSON-RPC requests with responses from the recording without sending
+// them anywhere. Calls are matched by method and params. Repeated calls get recorded responses in the order, in which
+// they were recorded, and once they run out, the last one is repeated. Calls, that weren't recorded, fail with an error.
+type RPCReplayer struct {
+	mu       *sync.Mutex
+	recorded map[string][]RPCInteraction
+	served   map[string]int
+}
+
+// NewRPCReplayer creates a new replayer of the recording
+func NewRPCReplayer(path string) (*RPCReplayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, wrapError(err, ErrReadRPCRecording)
+	}
+	defer func() { _ = f.Close() }()
+
+	r := &RPCReplayer{mu: &sync.Mutex{}, recorded: make(map[string][]RPCInteraction), served: make(map[string]int)}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var i RPCInteraction
+		if err := json.Unmarshal(scanner.Bytes(), &i); err != nil {
+			return nil, errors.Wrapf(wrapError(err, ErrReadRPCRecording), "line: %d", line)
+		}
+		key := interactionKey(i.Method, i.Params)
+		r.recorded[key] = append(r.recorded[key], i)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, wrapError(err, ErrReadRPCRecording)
+	}
+	return r, nil
+}
+
+func (r *RPCReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	requests, batch, err := parseJsonrpcMessages(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse JSON-RPC request")
+	}
+
+	responses := make([]jsonrpcMessage, 0, len(requests))
+	for _, msg := range requests {
+		resp := jsonrpcMessage{Version: "2.0", ID: msg.ID}
+		if i, ok := r.next(msg.Method, msg.Params); ok {
+			resp.Result, resp.Error = i.Result, i.Error
+		} else {
+			resp.Error, _ = json.Marshal(map[string]interface{}{
+				"code":    -32000,
+				"message": fmt.Sprintf("%s for %s with params %s", ErrNoRecordedResponse, msg.Method, string(msg.Params)),
+			})
+		}
+		if resp.Result == nil && resp.Error == nil {
+			resp.Result = json.RawMessage("null")
+		}
+		responses = append(responses, resp)
+	}
+
+	if batch {
+		return jsonrpcResponse(req, responses)
+	}
+	return jsonrpcResponse(req, responses[0])
+}
+
+// next returns the next recorded response of the call
+func (r *RPCReplayer) next(method string, params json.RawMessage) (RPCInteraction, bool) {
+	key := interactionKey(method, params)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	calls := r.recorded[key]
+	if len(calls) == 0 {
+		return RPCInteraction{}, false
+	}
+	idx := r.served[key]
+	if idx >= len(calls) {
+		idx = len(calls) - 1
+	}
+	r.served[key]++
+	return calls[idx], true
+}
+
+// Close does nothing, it's there, so that replayer can be closed the same way as recorder
+func (r *RPCReplayer) Close() error {
+	return nil
+}
+
+// rpcRecordingTransport is either RPCRecorder or RPCReplayer
+type rpcRecordingTransport interface {
+	http.RoundTripper
+	io.Closer
+}
+
+// newRPCRecordingTransport creates recorder or replayer configured with 'rpc_recording', recorder sends requests with base
+func newRPCRecordingTransport(cfg *RPCRecordingCfg, base http.RoundTripper) (rpcRecordingTransport, error) {
+	if cfg.Mode == RPCRecordingMode_Replay {
+		return NewRPCReplayer(cfg.File)
+	}
+	return NewRPCRecorder(cfg.File, base)
+}
@@ -0,0 +1,72 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIRPCRecordAndReplay(t *testing.T) {
+	c := newClient(t)
+	if !strings.HasPrefix(c.URL, "ws://") {
+		t.Skip("test requires a websocket RPC URL")
+	}
+	httpURL := strings.Replace(strings.Replace(c.URL, "ws://", "http://", 1), ":8546", ":8545", 1)
+	if _, err := http.Post(httpURL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`)); err != nil {
+		t.Skipf("HTTP RPC endpoint %s is not available", httpURL)
+	}
+	recording := filepath.Join(t.TempDir(), "recording.jsonl")
+
+	newConfig := func(t *testing.T, url, mode string) *seth.Config {
+		cfg, err := seth.ReadConfig()
+		require.NoError(t, err, "failed to read config")
+		cfg.Network.URLs = []string{url}
+		cfg.Network.Endpoints = nil
+		cfg.RPCRecording = &seth.RPCRecordingCfg{Mode: mode, File: recording}
+		require.NoError(t, seth.ValidateConfig(cfg), "recording config should be valid")
+		return cfg
+	}
+
+	client, err := seth.NewClientWithConfig(newConfig(t, httpURL, seth.RPCRecordingMode_Record))
+	require.NoError(t, err, "failed to create recording client")
+	block, err := client.Client.BlockNumber(context.Background())
+	require.NoError(t, err, "failed to get block number")
+	balance, err := client.Client.BalanceAt(context.Background(), client.Addresses[0], big.NewInt(int64(block)))
+	require.NoError(t, err, "failed to get balance")
+	require.NoError(t, client.Close(), "failed to close recording client")
+
+	data, err := os.ReadFile(recording)
+	require.NoError(t, err, "failed to read recording")
+	require.Contains(t, string(data), `"method":"eth_getBalance"`, "calls should be recorded")
+
+	// nothing listens on the port, so all responses have to come from the recording
+	replaying, err := seth.NewClientWithConfig(newConfig(t, "http://127.0.0.1:1", seth.RPCRecordingMode_Replay))
+	require.NoError(t, err, "failed to create replaying client")
+	defer func() { _ = replaying.Close() }()
+	require.Equal(t, client.ChainID, replaying.ChainID, "chain ID should be replayed")
+
+	replayedBlock, err := replaying.Client.BlockNumber(context.Background())
+	require.NoError(t, err, "failed to replay block number")
+	require.Equal(t, block, replayedBlock, "block number should be replayed")
+	replayedBalance, err := replaying.Client.BalanceAt(context.Background(), replaying.Addresses[0], big.NewInt(int64(block)))
+	require.NoError(t, err, "failed to replay balance")
+	require.Equal(t, balance.String(), replayedBalance.String(), "balance should be replayed")
+
+	_, err = replaying.Client.BalanceAt(context.Background(), replaying.Addresses[0], big.NewInt(int64(block)+1000))
+	require.Error(t, err, "call, that wasn't recorded, should fail")
+	require.Contains(t, err.Error(), "no recorded response for eth_getBalance", "error should explain that call wasn't recorded")
+}
+
+func TestConfigRPCRecording(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.RPCRecording = &seth.RPCRecordingCfg{Mode: "rewind"}
+	require.Error(t, seth.ValidateConfig(cfg), "unknown mode should be rejected")
+}
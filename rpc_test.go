@@ -0,0 +1,78 @@
+package seth_test
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPICallRPC(t *testing.T) {
+	c := newClient(t)
+
+	var chainID hexutil.Big
+	err := c.CallRPC(&chainID, "eth_chainId")
+	require.NoError(t, err, "failed to call RPC method")
+	require.Equal(t, c.ChainID, chainID.ToInt().Int64(), "incorrect chain ID")
+
+	var balance hexutil.Big
+	err = c.CallRPC(&balance, "eth_getBalance", c.Addresses[0], "latest")
+	require.NoError(t, err, "failed to call RPC method with params")
+	require.Equal(t, 1, balance.ToInt().Sign(), "root key should have positive balance")
+
+	_, err = seth.NewZkSyncRPC(c).L1ChainID()
+	require.Error(t, err, "zkSync method should not be available on Geth")
+}
+
+func TestAPIBatchCall(t *testing.T) {
+	c := newClient(t)
+
+	var chainID hexutil.Big
+	var balance hexutil.Big
+	var block hexutil.Uint64
+	var unknown interface{}
+	batch := []seth.BatchElem{
+		{Method: "eth_chainId", Result: &chainID},
+		{Method: "eth_getBalance", Args: []interface{}{c.Addresses[0], "latest"}, Result: &balance},
+		{Method: "eth_blockNumber", Result: &block},
+		{Method: "zks_L1ChainId", Result: &unknown},
+	}
+	require.NoError(t, c.BatchCall(batch), "failed to send batch")
+	require.NoError(t, batch[0].Error, "chain ID call shouldn't fail")
+	require.Equal(t, c.ChainID, chainID.ToInt().Int64(), "incorrect chain ID")
+	require.Equal(t, 1, balance.ToInt().Sign(), "root key should have positive balance")
+	require.NotZero(t, uint64(block), "block number should be returned")
+	require.Error(t, batch[3].Error, "error of single call should be set to its element")
+
+	// batches larger than the limit are split
+	large := make([]seth.BatchElem, seth.MaxBatchSize+1)
+	results := make([]hexutil.Big, len(large))
+	for i := range large {
+		large[i] = seth.BatchElem{Method: "eth_chainId", Result: &results[i]}
+	}
+	require.NoError(t, c.BatchCall(large), "failed to send large batch")
+	for i := range large {
+		require.NoError(t, large[i].Error, "call shouldn't fail")
+		require.Equal(t, c.ChainID, results[i].ToInt().Int64(), "incorrect chain ID")
+	}
+}
+
+func TestUtilRPCNamespaces(t *testing.T) {
+	ns, ok := seth.RPCNamespaceForMethod("zks_L1ChainId")
+	require.True(t, ok, "zks namespace should be registered")
+	require.Equal(t, "zks", ns.Prefix, "incorrect namespace")
+
+	_, ok = seth.RPCNamespaceForMethod("eth_chainId")
+	require.False(t, ok, "eth namespace should not be registered")
+
+	err := seth.RegisterRPCNamespace(seth.RPCNamespace{Prefix: "zks_"})
+	require.Error(t, err, "should not register the same namespace twice")
+	require.ErrorIs(t, err, seth.ErrRPCNamespaceRegistered, "incorrect error")
+
+	err = seth.RegisterRPCNamespace(seth.RPCNamespace{Prefix: "testchain", Methods: []string{"testchain_foo"}})
+	require.NoError(t, err, "failed to register namespace")
+	ns, ok = seth.RPCNamespaceForMethod("testchain_foo")
+	require.True(t, ok, "registered namespace should be found")
+	require.Equal(t, []string{"testchain_foo"}, ns.Methods, "incorrect methods")
+}
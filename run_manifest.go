@@ -0,0 +1,332 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	RunManifestDir         = "run_manifests"
+	RunManifestFilePattern = "run_manifest_%s_%s"
+
+	ManifestTxStatusSuccess  = "success"
+	ManifestTxStatusReverted = "reverted"
+	ManifestTxStatusNotMined = "not_mined"
+
+	redactedValue = "[redacted]"
+)
+
+// ManifestTransaction is a single transaction sent during the run
+type ManifestTransaction struct {
+	Hash        string   `json:"hash"`
+	From        string   `json:"from,omitempty"`
+	To          string   `json:"to,omitempty"`
+	Status      string   `json:"status"`
+	Error       string   `json:"error,omitempty"`
+	BlockNumber uint64   `json:"block_number,omitempty"`
+	GasUsed     uint64   `json:"gas_used,omitempty"`
+	Cost        *big.Int `json:"cost,omitempty"`
+	// L1Fee is the part of cost paid for posting data to L1 on OP-stack chains
+	L1Fee    *big.Int `json:"l1_fee,omitempty"`
+	Duration string   `json:"duration"`
+	// Value, Input and GasLimit are what's needed to replay the transaction, ContractAddress is set for mined deployments
+	Value           *big.Int `json:"value,omitempty"`
+	Input           string   `json:"input,omitempty"`
+	GasLimit        uint64   `json:"gas_limit,omitempty"`
+	ContractAddress string   `json:"contract_address,omitempty"`
+}
+
+// RunManifestReport is what's saved as JSON at Close
+type RunManifestReport struct {
+	Network          string                 `json:"network"`
+	ChainID          int64                  `json:"chain_id"`
+	StartedAt        time.Time              `json:"started_at"`
+	FinishedAt       time.Time              `json:"finished_at"`
+	Duration         string                 `json:"duration"`
+	Config           map[string]interface{} `json:"config"`
+	ContractMapDelta map[string]string      `json:"contract_map_delta"`
+	Transactions     []ManifestTransaction  `json:"transactions"`
+	SendErrors       int                    `json:"send_errors"`
+	Artifacts        []string               `json:"artifacts"`
+	TotalCost        *big.Int               `json:"total_cost"`
+	TotalCostEther   string                 `json:"total_cost_ether"`
+}
+
+// RunManifest collects everything that happened during the run (transactions, deployed contracts, saved files), so that it
+// can be saved as a single file at Close. It's safe for concurrent use.
+type RunManifest struct {
+	mu                 *sync.Mutex
+	startedAt          time.Time
+	initialContractMap map[string]string
+	transactions       []ManifestTransaction
+	sendErrors         int
+	artifacts          []string
+}
+
+// NewRunManifest creates a new run manifest, contracts already present in the contract map won't be included in the delta
+func NewRunManifest(contractMap ContractMap) *RunManifest {
+	return &RunManifest{
+		mu:                 &sync.Mutex{},
+		startedAt:          time.Now(),
+		initialContractMap: contractMap.Snapshot(),
+	}
+}
+
+// AddTransaction adds mined (or not) transaction to the manifest, receipt is nil if transaction wasn't mined
+func (r *RunManifest) AddTransaction(tx *types.Transaction, receipt *types.Receipt, mineErr error, duration time.Duration) {
+	r.AddTransactionWithL1Fee(tx, receipt, nil, mineErr, duration)
+}
+
+// AddTransactionWithL1Fee is the same as AddTransaction, but L1 data fee paid on OP-stack chains is added to the cost
+func (r *RunManifest) AddTransactionWithL1Fee(tx *types.Transaction, receipt *types.Receipt, l1Fee *big.Int, mineErr error, duration time.Duration) {
+	mtx := ManifestTransaction{
+		Hash:     tx.Hash().Hex(),
+		Duration: duration.String(),
+		Value:    tx.Value(),
+		Input:    hexutil.Encode(tx.Data()),
+		GasLimit: tx.Gas(),
+	}
+	if from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err == nil {
+		mtx.From = from.Hex()
+	}
+	if tx.To() != nil {
+		mtx.To = tx.To().Hex()
+	}
+	switch {
+	case receipt == nil:
+		mtx.Status = ManifestTxStatusNotMined
+		if mineErr != nil {
+			mtx.Error = mineErr.Error()
+		}
+	case receipt.Status == types.ReceiptStatusSuccessful:
+		mtx.Status = ManifestTxStatusSuccess
+	default:
+		mtx.Status = ManifestTxStatusReverted
+	}
+	if receipt != nil {
+		mtx.BlockNumber = receipt.BlockNumber.Uint64()
+		mtx.GasUsed = receipt.GasUsed
+		if tx.To() == nil {
+			mtx.ContractAddress = receipt.ContractAddress.Hex()
+		}
+		if receipt.EffectiveGasPrice != nil {
+			mtx.Cost = new(big.Int).Mul(receipt.EffectiveGasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+		}
+		if l1Fee != nil {
+			mtx.L1Fee = l1Fee
+			if mtx.Cost != nil {
+				mtx.Cost.Add(mtx.Cost, l1Fee)
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transactions = append(r.transactions, mtx)
+}
+
+// AddSendError counts transaction that failed to be sent, such transactions have no hash
+func (r *RunManifest) AddSendError() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sendErrors++
+}
+
+// AddArtifact adds path of a file produced during the run
+func (r *RunManifest) AddArtifact(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, a := range r.artifacts {
+		if a == path {
+			return
+		}
+	}
+	r.artifacts = append(r.artifacts, path)
+}
+
+// Report builds the manifest report, config secrets (RPC URLs and private keys) are redacted
+func (r *RunManifest) Report(cfg *Config, chainID int64, contractMap ContractMap) (RunManifestReport, error) {
+	configSnapshot, err := redactedConfigSnapshot(cfg)
+	if err != nil {
+		return RunManifestReport{}, err
+	}
+
+	delta := make(map[string]string)
+	for addr, name := range contractMap.Snapshot() {
+		if r.initialContractMap[addr] != name {
+			delta[addr] = name
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	totalCost := big.NewInt(0)
+	for _, tx := range r.transactions {
+		if tx.Cost != nil {
+			totalCost.Add(totalCost, tx.Cost)
+		}
+	}
+
+	finishedAt := time.Now()
+	return RunManifestReport{
+		Network:          cfg.Network.Name,
+		ChainID:          chainID,
+		StartedAt:        r.startedAt,
+		FinishedAt:       finishedAt,
+		Duration:         finishedAt.Sub(r.startedAt).String(),
+		Config:           configSnapshot,
+		ContractMapDelta: delta,
+		Transactions:     append([]ManifestTransaction{}, r.transactions...),
+		SendErrors:       r.sendErrors,
+		Artifacts:        append([]string{}, r.artifacts...),
+		TotalCost:        totalCost,
+		TotalCostEther:   WeiToEther(totalCost).Text('f', -1),
+	}, nil
+}
+
+// SaveRunManifest saves run manifest as JSON, together with funds flow report if funds flow is tracked. It returns path to the manifest.
+func (m *Client) SaveRunManifest() (string, error) {
+	if m.RunManifest == nil {
+		return "", errors.New("run manifest is not enabled, set 'run_manifest = true' in config")
+	}
+
+	// traces saved in the background are run's artifacts too
+	if err := m.FlushTraces(context.Background()); err != nil {
+		m.logger().Warn().Err(err).Msg("Failed to flush traces")
+	}
+
+	if m.FundsFlow != nil {
+		jsonPath, dotPath, err := m.FundsFlow.SaveReport(RunManifestDir)
+		if err != nil {
+			m.logger().Warn().Err(err).Msg("Failed to save funds flow report")
+		} else {
+			m.RunManifest.AddArtifact(jsonPath)
+			m.RunManifest.AddArtifact(dotPath)
+		}
+	}
+
+	report, err := m.RunManifest.Report(m.Cfg, m.ChainID, m.ContractAddressToNameMap)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create run manifest")
+	}
+
+	name := fmt.Sprintf(RunManifestFilePattern, m.Cfg.Network.Name, report.StartedAt.Format("2006-01-02-15-04-05"))
+	path, err := saveAsJson(report, RunManifestDir, name)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to save run manifest")
+	}
+
+	m.logger().Info().
+		Str("Path", path).
+		Int("Transactions", len(report.Transactions)).
+		Str("Total cost (ether)", report.TotalCostEther).
+		Msg("Saved run manifest")
+
+	return path, nil
+}
+
+func (m *Client) recordManifestTransaction(tx *types.Transaction, receipt *types.Receipt, mineErr error, startedAt time.Time) {
+	if m.RunManifest == nil {
+		return
+	}
+	var l1Fee *big.Int
+	if receipt != nil {
+		l1Fee = m.l1Fee(context.Background(), tx, receipt)
+	}
+	m.RunManifest.AddTransactionWithL1Fee(tx, receipt, l1Fee, mineErr, time.Since(startedAt))
+}
+
+func (m *Client) recordManifestArtifact(path string) {
+	if m.RunManifest == nil {
+		return
+	}
+	m.RunManifest.AddArtifact(path)
+}
+
+// redactedConfigSnapshot returns config as a generic map (with the same keys as in TOML) with RPC URLs, redis URL and private keys redacted
+func redactedConfigSnapshot(cfg *Config) (map[string]interface{}, error) {
+	cfgCopy := *cfg
+	if cfg.Network != nil {
+		cfgCopy.Network = redactedNetwork(cfg.Network)
+	}
+	if cfg.KeyCoordination != nil && cfg.KeyCoordination.RedisURL != "" {
+		keyCoordinationCopy := *cfg.KeyCoordination
+		keyCoordinationCopy.RedisURL = redactedValue
+		cfgCopy.KeyCoordination = &keyCoordinationCopy
+	}
+	cfgCopy.Networks = make([]*Network, 0, len(cfg.Networks))
+	for _, n := range cfg.Networks {
+		cfgCopy.Networks = append(cfgCopy.Networks, redactedNetwork(n))
+	}
+
+	b, err := toml.Marshal(cfgCopy)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make(map[string]interface{})
+	if err := toml.Unmarshal(b, &snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func redactedNetwork(n *Network) *Network {
+	nCopy := *n
+	nCopy.URLs = redactAll(n.URLs)
+	nCopy.PrivateKeys = redactAll(n.PrivateKeys)
+	nCopy.Endpoints = make([]*Endpoint, 0, len(n.Endpoints))
+	for _, e := range n.Endpoints {
+		redacted := redactAll([]string{e.HTTP, e.WS})
+		nCopy.Endpoints = append(nCopy.Endpoints, &Endpoint{HTTP: redacted[0], WS: redacted[1]})
+	}
+	if n.Paymaster != nil {
+		paymasterCopy := *n.Paymaster
+		paymasterCopy.BundlerURL = redactedValue
+		nCopy.Paymaster = &paymasterCopy
+	}
+	if n.RemoteSigner != nil {
+		remoteSignerCopy := *n.RemoteSigner
+		remoteSignerCopy.URL = redactedValue
+		nCopy.RemoteSigner = &remoteSignerCopy
+	}
+	if len(n.HTTPHeaders) > 0 {
+		nCopy.HTTPHeaders = make(map[string]string, len(n.HTTPHeaders))
+		for k := range n.HTTPHeaders {
+			nCopy.HTTPHeaders[k] = redactedValue
+		}
+	}
+	if n.BearerToken != "" {
+		nCopy.BearerToken = redactedValue
+	}
+	if n.BasicAuth != nil {
+		nCopy.BasicAuth = &BasicAuthCfg{Username: n.BasicAuth.Username, Password: redactedValue}
+	}
+	if n.Explorer != nil && n.Explorer.APIKey != "" {
+		explorerCopy := *n.Explorer
+		explorerCopy.APIKey = redactedValue
+		nCopy.Explorer = &explorerCopy
+	}
+	return &nCopy
+}
+
+func redactAll(values []string) []string {
+	redacted := make([]string, 0, len(values))
+	for _, v := range values {
+		if strings.TrimSpace(v) == "" {
+			redacted = append(redacted, v)
+			continue
+		}
+		redacted = append(redacted, redactedValue)
+	}
+	return redacted
+}
@@ -0,0 +1,59 @@
+package seth_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIRunManifest(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.RunManifest = true
+	cfg.TrackFundsFlow = true
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	t.Cleanup(func() {
+		_ = os.RemoveAll(seth.RunManifestDir)
+	})
+
+	data, err := c.DeployContractFromContractStore(c.NewTXOpts(), "NetworkDebugSubContract.abi")
+	require.NoError(t, err, "failed to deploy contract")
+
+	_, err = c.Decode(TestEnv.DebugContract.Pay(c.NewTXOpts(seth.WithValue(big.NewInt(1000)))))
+	require.NoError(t, err, "failed to send value to contract")
+	_, err = c.Decode(TestEnv.DebugContract.AlwaysRevertsRequire(c.NewTXOpts(seth.WithGasLimit(1_000_000))))
+	require.Error(t, err, "transaction should revert")
+	_, err = c.Decode(TestEnv.DebugContract.Set(c.NewTXOpts(seth.WithGasLimit(1)), big.NewInt(1)))
+	require.Error(t, err, "transaction with too low gas limit should fail")
+
+	path, err := c.SaveRunManifest()
+	require.NoError(t, err, "failed to save run manifest")
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err, "failed to read run manifest")
+	for _, secret := range append(cfg.Network.URLs, cfg.Network.PrivateKeys...) {
+		require.NotContains(t, string(b), strings.TrimPrefix(secret, "0x"), "secrets should be redacted")
+	}
+
+	var report seth.RunManifestReport
+	require.NoError(t, json.Unmarshal(b, &report), "failed to unmarshal run manifest")
+	require.Equal(t, c.ChainID, report.ChainID, "incorrect chain ID")
+	// contracts found by ABI finder when tracing are added to the map as well
+	require.Equal(t, "NetworkDebugSubContract", report.ContractMapDelta[strings.ToLower(data.Address.Hex())], "deployed contract should be in delta")
+	require.Len(t, report.Transactions, 3, "expected deployment, successful and reverted transaction")
+	require.Equal(t, seth.ManifestTxStatusSuccess, report.Transactions[0].Status, "deployment should be successful")
+	require.Equal(t, seth.ManifestTxStatusSuccess, report.Transactions[1].Status, "payment should be successful")
+	require.Equal(t, seth.ManifestTxStatusReverted, report.Transactions[2].Status, "transaction should be reverted")
+	require.Equal(t, 1, report.SendErrors, "expected one send error")
+	require.Len(t, report.Artifacts, 2, "funds flow report should be included in artifacts")
+	require.Equal(t, 1, report.TotalCost.Sign(), "total cost should be positive")
+
+	require.NoError(t, c.Close(), "failed to close client")
+}
@@ -0,0 +1,504 @@
+package seth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	ScenarioStepDeploy       = "deploy"
+	ScenarioStepSend         = "send"
+	ScenarioStepCall         = "call"
+	ScenarioStepWaitForEvent = "wait_for_event"
+	ScenarioStepAssert       = "assert"
+
+	ScenarioStepPassed  = "passed"
+	ScenarioStepFailed  = "failed"
+	ScenarioStepSkipped = "skipped"
+
+	ScenarioReportDir = "scenario_reports"
+
+	// DefaultScenarioStepTimeout is used by wait_for_event steps without timeout
+	DefaultScenarioStepTimeout = 30 * time.Second
+)
+
+var (
+	ErrUnknownScenarioStep   = errors.New("unknown type of scenario step")
+	ErrUnknownScenarioVar    = errors.New("unknown variable in scenario step")
+	ErrScenarioAssertion     = errors.New("assertion failed")
+	ErrDuplicateScenarioStep = errors.New("scenario step is defined more than once")
+	ErrScenarioFileExtension = errors.New("unsupported scenario file extension")
+	ErrNoEventInABI          = errors.New("event not found in ABI of contract")
+	ErrScenarioEventTimeout  = errors.New("event was not emitted before timeout")
+)
+
+var scenarioVarRegexp = regexp.MustCompile(`\$\{([^}]+)}`)
+
+// ScenarioStep is a single step of a scenario. Fields that are used depend on step type:
+//   - deploy: Contract, Args (constructor arguments), Value, KeyNum, GasLimit
+//   - send: Template (and optionally Args/Value to override it) or Contract, Address, Method, Args, Value, KeyNum, GasLimit
+//   - call: Contract, Address, Method, Args, KeyNum
+//   - wait_for_event: Contract, Address, Event, Timeout (first matching event emitted since the scenario started is used)
+//   - assert: Actual, Expect, Operator ("eq" (default), "ne", "gt", "gte", "lt", "lte"; all but eq/ne compare integers)
+//
+// Address, Args, Actual and Expect can reference results of previous steps with ${step.key}, where key is `address`
+// (deploy), `tx_hash` (deploy, send), `output.N` or `output.name` (call) and `event.name` (wait_for_event).
+// If Address is empty, contract address is read from the contract map.
+type ScenarioStep struct {
+	Name     string    `toml:"name" json:"name"`
+	Type     string    `toml:"type" json:"type"`
+	Template string    `toml:"template" json:"template,omitempty"`
+	Contract string    `toml:"contract" json:"contract,omitempty"`
+	Address  string    `toml:"address" json:"address,omitempty"`
+	Method   string    `toml:"method" json:"method,omitempty"`
+	Args     []string  `toml:"args" json:"args,omitempty"`
+	Value    Amount    `toml:"value" json:"value,omitempty"`
+	KeyNum   int       `toml:"key_num" json:"key_num,omitempty"`
+	GasLimit uint64    `toml:"gas_limit" json:"gas_limit,omitempty"`
+	Event    string    `toml:"event" json:"event,omitempty"`
+	Timeout  *Duration `toml:"timeout" json:"timeout,omitempty"`
+	Actual   string    `toml:"actual" json:"actual,omitempty"`
+	Expect   string    `toml:"expect" json:"expect,omitempty"`
+	Operator string    `toml:"operator" json:"operator,omitempty"`
+}
+
+// Scenario is a named list of steps executed one after another with shared variables
+type Scenario struct {
+	Name  string         `toml:"name" json:"name"`
+	Steps []ScenarioStep `toml:"steps" json:"steps"`
+}
+
+// ScenarioStepResult is a result of a single scenario step
+type ScenarioStepResult struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Status   string            `json:"status"`
+	Error    string            `json:"error,omitempty"`
+	Duration string            `json:"duration"`
+	Vars     map[string]string `json:"vars,omitempty"`
+}
+
+// ScenarioReport is a per-step report of the scenario run, Vars contains all variables produced by the steps
+type ScenarioReport struct {
+	Name     string               `json:"name"`
+	Passed   bool                 `json:"passed"`
+	Duration string               `json:"duration"`
+	Steps    []ScenarioStepResult `json:"steps"`
+	Vars     map[string]string    `json:"vars"`
+}
+
+// LoadScenario reads scenario from TOML or JSON file
+func LoadScenario(path string) (*Scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read scenario file %s", path)
+	}
+
+	s := &Scenario{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".toml":
+		err = toml.Unmarshal(b, s)
+	case ".json":
+		err = json.Unmarshal(b, s)
+	default:
+		return nil, errors.Wrapf(ErrScenarioFileExtension, "extension '%s', use .toml or .json", ext)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal scenario file %s", path)
+	}
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	return s, s.Validate()
+}
+
+// Validate checks that all steps have unique names and known types
+func (s *Scenario) Validate() error {
+	names := make(map[string]struct{})
+	for i, step := range s.Steps {
+		if step.Name == "" {
+			return fmt.Errorf("scenario step %d has no name", i)
+		}
+		if _, ok := names[step.Name]; ok {
+			return errors.Wrapf(ErrDuplicateScenarioStep, "step: '%s'", step.Name)
+		}
+		names[step.Name] = struct{}{}
+
+		switch step.Type {
+		case ScenarioStepDeploy, ScenarioStepCall, ScenarioStepWaitForEvent:
+			if step.Contract == "" {
+				return fmt.Errorf("scenario step '%s' of type %s requires contract", step.Name, step.Type)
+			}
+		case ScenarioStepSend:
+			if step.Template == "" && (step.Contract == "" || step.Method == "") {
+				return fmt.Errorf("scenario step '%s' requires either template or contract and method", step.Name)
+			}
+		case ScenarioStepAssert:
+		default:
+			return errors.Wrapf(ErrUnknownScenarioStep, "type: '%s', step: '%s'", step.Type, step.Name)
+		}
+	}
+	return nil
+}
+
+// RunScenario executes all steps of the scenario in order. Execution stops at the first failed step, all remaining steps
+// are marked as skipped. Returned error is nil only if all steps passed, report is always returned.
+func (m *Client) RunScenario(ctx context.Context, s *Scenario) (*ScenarioReport, error) {
+	report := &ScenarioReport{
+		Name: s.Name,
+		Vars: make(map[string]string),
+	}
+	if err := s.Validate(); err != nil {
+		return report, err
+	}
+
+	startBlock, err := m.Client.BlockNumber(ctx)
+	if err != nil {
+		return report, errors.Wrap(err, "failed to get current block number")
+	}
+
+	startedAt := time.Now()
+	var runErr error
+	for _, step := range s.Steps {
+		result := ScenarioStepResult{
+			Name:   step.Name,
+			Type:   step.Type,
+			Status: ScenarioStepSkipped,
+		}
+		if runErr != nil {
+			report.Steps = append(report.Steps, result)
+			continue
+		}
+
+		m.logger().Info().
+			Str("Scenario", s.Name).
+			Str("Step", step.Name).
+			Str("Type", step.Type).
+			Msg("Running scenario step")
+
+		stepStartedAt := time.Now()
+		vars, err := m.runScenarioStep(ctx, step, report.Vars, startBlock)
+		result.Duration = time.Since(stepStartedAt).String()
+		result.Vars = vars
+		for k, v := range vars {
+			report.Vars[fmt.Sprintf("%s.%s", step.Name, k)] = v
+		}
+
+		if err != nil {
+			result.Status = ScenarioStepFailed
+			result.Error = err.Error()
+			runErr = errors.Wrapf(err, "scenario step '%s' failed", step.Name)
+			m.logger().Error().
+				Err(err).
+				Str("Scenario", s.Name).
+				Str("Step", step.Name).
+				Msg("Scenario step failed")
+		} else {
+			result.Status = ScenarioStepPassed
+		}
+		report.Steps = append(report.Steps, result)
+	}
+
+	report.Passed = runErr == nil
+	report.Duration = time.Since(startedAt).String()
+
+	return report, runErr
+}
+
+// SaveScenarioReport saves scenario report as JSON in given directory and returns path to it
+func SaveScenarioReport(report *ScenarioReport, dirName string) (string, error) {
+	return saveAsJson(report, dirName, fmt.Sprintf("%s_%s", report.Name, time.Now().Format("2006-01-02-15-04-05")))
+}
+
+func (m *Client) runScenarioStep(ctx context.Context, step ScenarioStep, vars map[string]string, startBlock uint64) (map[string]string, error) {
+	var err error
+	if step, err = substituteScenarioVars(step, vars); err != nil {
+		return nil, err
+	}
+
+	switch step.Type {
+	case ScenarioStepDeploy:
+		return m.runScenarioDeploy(step)
+	case ScenarioStepSend:
+		return m.runScenarioSend(step)
+	case ScenarioStepCall:
+		return m.runScenarioCall(step)
+	case ScenarioStepWaitForEvent:
+		return m.runScenarioWaitForEvent(ctx, step, startBlock)
+	case ScenarioStepAssert:
+		return nil, assertScenarioValues(step.Actual, step.Expect, step.Operator)
+	default:
+		return nil, errors.Wrapf(ErrUnknownScenarioStep, "type: '%s', step: '%s'", step.Type, step.Name)
+	}
+}
+
+func (m *Client) runScenarioDeploy(step ScenarioStep) (map[string]string, error) {
+	contractAbi, ok := m.ContractStore.GetABI(step.Contract)
+	if !ok {
+		return nil, errors.Wrapf(ErrNoABIForContract, "contract: %s", step.Contract)
+	}
+	bytecode, ok := m.ContractStore.GetBIN(step.Contract)
+	if !ok {
+		return nil, fmt.Errorf("BIN for contract %s not found", step.Contract)
+	}
+	params, err := ParseMethodArgs(contractAbi.Constructor, step.Args)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse constructor arguments")
+	}
+	txOpts, err := scenarioTransactOpts(step)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := m.DeployContract(m.NewTXKeyOpts(step.KeyNum, txOpts...), step.Contract, *contractAbi, bytecode, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"address": data.Address.Hex(),
+		"tx_hash": data.Transaction.Hash().Hex(),
+	}, nil
+}
+
+func (m *Client) runScenarioSend(step ScenarioStep) (map[string]string, error) {
+	tmpl := TransactionTemplate{
+		Name:     step.Name,
+		Contract: step.Contract,
+		To:       step.Address,
+		Method:   step.Method,
+		Args:     step.Args,
+		Value:    step.Value,
+		KeyNum:   step.KeyNum,
+		GasLimit: step.GasLimit,
+	}
+	if step.Template != "" {
+		var ok bool
+		tmpl, ok = m.Cfg.GetTemplate(step.Template)
+		if !ok {
+			return nil, errors.Wrapf(ErrNoTemplate, "template: '%s'", step.Template)
+		}
+		if step.Args != nil {
+			tmpl.Args = step.Args
+		}
+		if !step.Value.IsZero() {
+			tmpl.Value = step.Value
+		}
+		if step.Address != "" {
+			tmpl.To = step.Address
+		}
+	}
+
+	decoded, err := m.Decode(m.sendTransactionTemplate(tmpl))
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{"tx_hash": decoded.Hash}, nil
+}
+
+func (m *Client) runScenarioCall(step ScenarioStep) (map[string]string, error) {
+	contractAbi, method, address, err := m.resolveScenarioContract(step)
+	if err != nil {
+		return nil, err
+	}
+	args, err := ParseMethodArgs(method, step.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []interface{}
+	contract := bind.NewBoundContract(address, *contractAbi, m.Client, m.Client, m.Client)
+	if err := contract.Call(m.NewCallKeyOpts(step.KeyNum), &out, method.Name, args...); err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string)
+	for i, o := range out {
+		value := formatScenarioValue(o)
+		vars[fmt.Sprintf("output.%d", i)] = value
+		if i < len(method.Outputs) && method.Outputs[i].Name != "" {
+			vars["output."+method.Outputs[i].Name] = value
+		}
+	}
+
+	return vars, nil
+}
+
+func (m *Client) runScenarioWaitForEvent(ctx context.Context, step ScenarioStep, startBlock uint64) (map[string]string, error) {
+	contractAbi, ok := m.ContractStore.GetABI(step.Contract)
+	if !ok {
+		return nil, errors.Wrapf(ErrNoABIForContract, "contract: %s", step.Contract)
+	}
+	var event *abi.Event
+	for _, e := range contractAbi.Events {
+		if e.Name == step.Event || e.Sig == strings.ReplaceAll(step.Event, " ", "") {
+			e := e
+			event = &e
+			break
+		}
+	}
+	if event == nil {
+		return nil, errors.Wrapf(ErrNoEventInABI, "event: %s, contract: %s", step.Event, step.Contract)
+	}
+	address, err := m.scenarioContractAddress(step)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := DefaultScenarioStepTimeout
+	if step.Timeout != nil {
+		timeout = step.Timeout.Duration()
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log, err := m.WaitForEvent(ctx, address, event.ID, startBlock)
+	if err != nil {
+		return nil, errors.Wrapf(ErrScenarioEventTimeout, "event: %s, emitter: %s", event.Name, address.Hex())
+	}
+
+	eventData := make(map[string]interface{})
+	if err := contractAbi.UnpackIntoMap(eventData, event.Name, log.Data); err != nil {
+		return nil, errors.Wrapf(err, "failed to unpack event %s", event.Name)
+	}
+	var indexed abi.Arguments
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(eventData, indexed, log.Topics[1:]); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse topics of event %s", event.Name)
+	}
+
+	vars := map[string]string{"tx_hash": log.TxHash.Hex()}
+	for k, v := range eventData {
+		vars["event."+k] = formatScenarioValue(v)
+	}
+	return vars, nil
+}
+
+func (m *Client) resolveScenarioContract(step ScenarioStep) (*abi.ABI, abi.Method, common.Address, error) {
+	contractAbi, ok := m.ContractStore.GetABI(step.Contract)
+	if !ok {
+		return nil, abi.Method{}, common.Address{}, errors.Wrapf(ErrNoABIForContract, "contract: %s", step.Contract)
+	}
+	method, err := findMethodBySignature(contractAbi, step.Contract, step.Method)
+	if err != nil {
+		return nil, abi.Method{}, common.Address{}, err
+	}
+	address, err := m.scenarioContractAddress(step)
+	return contractAbi, method, address, err
+}
+
+func (m *Client) scenarioContractAddress(step ScenarioStep) (common.Address, error) {
+	address := step.Address
+	if address == "" {
+		address = m.ContractAddressToNameMap.GetContractAddress(step.Contract)
+		if address == UNKNOWN {
+			return common.Address{}, fmt.Errorf("contract %s not found in the contract map, set address of step '%s'", step.Contract, step.Name)
+		}
+	}
+	if !common.IsHexAddress(address) {
+		return common.Address{}, fmt.Errorf("invalid address of step '%s': %s", step.Name, address)
+	}
+	return common.HexToAddress(address), nil
+}
+
+func scenarioTransactOpts(step ScenarioStep) ([]TransactOpt, error) {
+	var opts []TransactOpt
+	if !step.Value.IsZero() {
+		opts = append(opts, WithValue(step.Value.Wei()))
+	}
+	if step.GasLimit != 0 {
+		opts = append(opts, WithGasLimit(step.GasLimit))
+	}
+	return opts, nil
+}
+
+// substituteScenarioVars replaces all ${step.key} references with values produced by previous steps
+func substituteScenarioVars(step ScenarioStep, vars map[string]string) (ScenarioStep, error) {
+	var err error
+	substitute := func(s string) string {
+		return scenarioVarRegexp.ReplaceAllStringFunc(s, func(match string) string {
+			key := scenarioVarRegexp.FindStringSubmatch(match)[1]
+			v, ok := vars[key]
+			if !ok && err == nil {
+				err = errors.Wrapf(ErrUnknownScenarioVar, "variable: '%s', step: '%s'", key, step.Name)
+			}
+			return v
+		})
+	}
+
+	step.Address = substitute(step.Address)
+	step.Actual = substitute(step.Actual)
+	step.Expect = substitute(step.Expect)
+	if step.Args != nil {
+		args := make([]string, len(step.Args))
+		for i, a := range step.Args {
+			args[i] = substitute(a)
+		}
+		step.Args = args
+	}
+
+	return step, err
+}
+
+func assertScenarioValues(actual, expected, operator string) error {
+	if operator == "" {
+		operator = "eq"
+	}
+
+	var ok bool
+	switch operator {
+	case "eq":
+		ok = actual == expected
+	case "ne":
+		ok = actual != expected
+	case "gt", "gte", "lt", "lte":
+		a, aOk := new(big.Int).SetString(actual, 0)
+		e, eOk := new(big.Int).SetString(expected, 0)
+		if !aOk || !eOk {
+			return fmt.Errorf("operator %s requires integers, got '%s' and '%s'", operator, actual, expected)
+		}
+		cmp := a.Cmp(e)
+		ok = (operator == "gt" && cmp > 0) || (operator == "gte" && cmp >= 0) || (operator == "lt" && cmp < 0) || (operator == "lte" && cmp <= 0)
+	default:
+		return fmt.Errorf("unknown assert operator '%s'", operator)
+	}
+
+	if !ok {
+		return errors.Wrapf(ErrScenarioAssertion, "expected %s %s %s", actual, operator, expected)
+	}
+	return nil
+}
+
+func formatScenarioValue(v interface{}) string {
+	switch t := v.(type) {
+	case common.Address:
+		return t.Hex()
+	case common.Hash:
+		return t.Hex()
+	case []byte:
+		return "0x" + common.Bytes2Hex(t)
+	case [32]byte:
+		return "0x" + common.Bytes2Hex(t[:])
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,97 @@
+package seth_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	sethcmd "github.com/smartcontractkit/seth/cmd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIRunScenario(t *testing.T) {
+	c := newClient(t)
+
+	scenario := &seth.Scenario{
+		Name: "debug_contract",
+		Steps: []seth.ScenarioStep{
+			{Name: "sub", Type: seth.ScenarioStepDeploy, Contract: "NetworkDebugSubContract"},
+			{Name: "debug", Type: seth.ScenarioStepDeploy, Contract: "NetworkDebugContract", Args: []string{"${sub.address}"}},
+			{Name: "set", Type: seth.ScenarioStepSend, Contract: "NetworkDebugContract", Address: "${debug.address}", Method: "set", Args: []string{"42"}},
+			{Name: "get", Type: seth.ScenarioStepCall, Contract: "NetworkDebugContract", Address: "${debug.address}", Method: "get"},
+			{Name: "check_get", Type: seth.ScenarioStepAssert, Actual: "${get.output.data}", Expect: "42"},
+			{Name: "emit", Type: seth.ScenarioStepSend, Contract: "NetworkDebugContract", Address: "${debug.address}", Method: "emitOneIndexEvent"},
+			{Name: "event", Type: seth.ScenarioStepWaitForEvent, Contract: "NetworkDebugContract", Address: "${debug.address}", Event: "OneIndexEvent"},
+			{Name: "check_event", Type: seth.ScenarioStepAssert, Actual: "${event.tx_hash}", Expect: "${emit.tx_hash}"},
+		},
+	}
+
+	report, err := c.RunScenario(context.Background(), scenario)
+	require.NoError(t, err, "scenario should pass")
+	require.True(t, report.Passed, "scenario should pass")
+	require.Len(t, report.Steps, len(scenario.Steps), "each step should have a result")
+	for _, step := range report.Steps {
+		require.Equal(t, seth.ScenarioStepPassed, step.Status, "step %s should pass", step.Name)
+	}
+	require.Equal(t, "42", report.Vars["get.output.0"], "call output should be saved by index")
+
+	scenario.Steps = []seth.ScenarioStep{
+		{Name: "get", Type: seth.ScenarioStepCall, Contract: "NetworkDebugContract", Address: report.Vars["debug.address"], Method: "get"},
+		{Name: "check", Type: seth.ScenarioStepAssert, Actual: "${get.output.data}", Expect: "43", Operator: "gte"},
+		{Name: "never", Type: seth.ScenarioStepAssert, Actual: "${unknown.var}", Expect: "1"},
+	}
+	report, err = c.RunScenario(context.Background(), scenario)
+	require.Error(t, err, "scenario should fail")
+	require.False(t, report.Passed, "scenario should fail")
+	require.Equal(t, seth.ScenarioStepPassed, report.Steps[0].Status, "first step should pass")
+	require.Equal(t, seth.ScenarioStepFailed, report.Steps[1].Status, "assertion should fail")
+	require.Equal(t, "expected 42 gte 43: assertion failed", report.Steps[1].Error, "incorrect assertion error")
+	require.Equal(t, seth.ScenarioStepSkipped, report.Steps[2].Status, "steps after failure should be skipped")
+}
+
+func TestCLIRunScenario(t *testing.T) {
+	dir := t.TempDir()
+	scenarioFile := filepath.Join(dir, "scenario.toml")
+	err := os.WriteFile(scenarioFile, []byte(`
+name = "cli_scenario"
+
+[[steps]]
+name = "sub"
+type = "deploy"
+contract = "NetworkDebugSubContract"
+
+[[steps]]
+name = "debug"
+type = "deploy"
+contract = "NetworkDebugContract"
+args = ["${sub.address}"]
+
+[[steps]]
+name = "get"
+type = "call"
+contract = "NetworkDebugContract"
+address = "${debug.address}"
+method = "get"
+
+[[steps]]
+name = "check"
+type = "assert"
+actual = "${get.output.data}"
+expect = "0"
+`), 0600)
+	require.NoError(t, err, "failed to write scenario file")
+
+	reportDir := "scenario_test_reports"
+	t.Cleanup(func() {
+		_ = os.RemoveAll(reportDir)
+	})
+
+	err = sethcmd.RunCLI([]string{"seth", "-n", os.Getenv(seth.NETWORK_ENV_VAR), "run", "--report", reportDir, scenarioFile})
+	require.NoError(t, err, "scenario should pass")
+
+	reports, err := os.ReadDir(reportDir)
+	require.NoError(t, err, "failed to read reports dir")
+	require.Len(t, reports, 1, "report should be saved")
+}
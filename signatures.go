@@ -0,0 +1,321 @@
+package seth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultSignatureDatabaseURL       = "https://www.4byte.directory/api/v1/signatures/"
+	DefaultSignatureDatabaseCacheFile = "4byte_signatures.json"
+	DefaultSignatureDatabaseTimeout   = 5 * time.Second
+
+	// CommentSignatureFromDatabase is added to calls decoded with a signature from the signature database instead of an ABI
+	CommentSignatureFromDatabase = "Call decoded with signature from 4byte database, argument names are unknown"
+)
+
+var (
+	ErrSignatureDatabaseURL     = errors.New("signature database 'url' must be a valid http(s) URL")
+	ErrSignatureDatabaseRequest = errors.New("failed to query signature database")
+	ErrInvalidTextSignature     = errors.New("invalid text signature")
+)
+
+// SignatureDatabaseCfg configures lookup of function signatures in 4byte.directory (or compatible) database, which is used
+// by the tracer to show candidate signatures and decoded arguments of calls, that don't match any ABI
+type SignatureDatabaseCfg struct {
+	URL string `toml:"url"`
+	// CacheFile keeps signatures of looked up selectors (also of the unknown ones) between runs
+	CacheFile string    `toml:"cache_file"`
+	Timeout   *Duration `toml:"timeout"`
+	// Offline disables queries, only signatures from the cache file are used
+	Offline bool `toml:"offline"`
+}
+
+func validateSignatureDatabaseCfg(cfg *SignatureDatabaseCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.URL == "" {
+		cfg.URL = DefaultSignatureDatabaseURL
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrSignatureDatabaseURL
+	}
+	if cfg.CacheFile == "" {
+		cfg.CacheFile = DefaultSignatureDatabaseCacheFile
+	}
+	if cfg.Timeout == nil {
+		cfg.Timeout = MustMakeDuration(DefaultSignatureDatabaseTimeout)
+	}
+	return nil
+}
+
+// SignatureDatabase finds text signatures (e.g. "transfer(address,uint256)") of function selectors. Results are cached
+// in memory and in the cache file, selectors that failed to be queried are not queried again by the same instance.
+type SignatureDatabase struct {
+	cfg    *SignatureDatabaseCfg
+	client *http.Client
+	mu     sync.Mutex
+	cache  map[string][]string
+	failed map[string]struct{}
+}
+
+// NewSignatureDatabase creates signature database and loads its cache file, if it exists
+func NewSignatureDatabase(cfg *SignatureDatabaseCfg) *SignatureDatabase {
+	timeout := DefaultSignatureDatabaseTimeout
+	if cfg.Timeout != nil {
+		timeout = cfg.Timeout.Duration()
+	}
+	db := &SignatureDatabase{
+		cfg:    cfg,
+		client: &http.Client{Timeout: timeout},
+		cache:  make(map[string][]string),
+		failed: make(map[string]struct{}),
+	}
+	if cfg.CacheFile != "" {
+		if _, err := os.Stat(cfg.CacheFile); err == nil {
+			if err := OpenJsonFileAsStruct(cfg.CacheFile, &db.cache); err != nil {
+				L.Warn().Err(err).Str("File", cfg.CacheFile).Msg("Failed to load signature database cache")
+			}
+		}
+	}
+	return db
+}
+
+// Lookup returns candidate text signatures of the selector, the oldest registered first (it's the most likely to be the
+// genuine one, as collisions are usually registered later). Empty list means the selector is unknown.
+func (d *SignatureDatabase) Lookup(ctx context.Context, selector []byte) ([]string, error) {
+	key := "0x" + common.Bytes2Hex(selector)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if signatures, ok := d.cache[key]; ok {
+		return signatures, nil
+	}
+	if _, failed := d.failed[key]; failed || d.cfg.Offline {
+		return nil, nil
+	}
+
+	signatures, err := d.query(ctx, key)
+	if err != nil {
+		d.failed[key] = struct{}{}
+		return nil, err
+	}
+	d.cache[key] = signatures
+	d.saveCache()
+	return signatures, nil
+}
+
+func (d *SignatureDatabase) query(ctx context.Context, selector string) ([]string, error) {
+	u, err := url.Parse(d.cfg.URL)
+	if err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrSignatureDatabaseRequest), "selector: %s", selector)
+	}
+	query := u.Query()
+	query.Set("hex_signature", selector)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrSignatureDatabaseRequest), "selector: %s", selector)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrSignatureDatabaseRequest), "selector: %s", selector)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrapf(wrapError(errors.New(resp.Status), ErrSignatureDatabaseRequest), "selector: %s", selector)
+	}
+
+	var body struct {
+		Results []struct {
+			ID            int64  `json:"id"`
+			TextSignature string `json:"text_signature"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrSignatureDatabaseRequest), "selector: %s", selector)
+	}
+	sort.Slice(body.Results, func(i, j int) bool {
+		return body.Results[i].ID < body.Results[j].ID
+	})
+	signatures := make([]string, 0, len(body.Results))
+	for _, r := range body.Results {
+		signatures = append(signatures, r.TextSignature)
+	}
+	return signatures, nil
+}
+
+func (d *SignatureDatabase) saveCache() {
+	if d.cfg.CacheFile == "" {
+		return
+	}
+	data, err := json.MarshalIndent(d.cache, "", "  ")
+	if err == nil && filepath.Dir(d.cfg.CacheFile) != "." {
+		err = os.MkdirAll(filepath.Dir(d.cfg.CacheFile), os.ModePerm)
+	}
+	if err == nil {
+		err = os.WriteFile(d.cfg.CacheFile, data, 0600)
+	}
+	if err != nil {
+		L.Warn().Err(err).Str("File", d.cfg.CacheFile).Msg("Failed to save signature database cache")
+	}
+}
+
+// MethodFromTextSignature creates ABI method from text signature (e.g. "swap(uint256,(address,bytes)[])"), whose
+// arguments are named "arg0", "arg1", etc.
+func MethodFromTextSignature(signature string) (*abi.Method, error) {
+	open := strings.Index(signature, "(")
+	if open <= 0 || !strings.HasSuffix(signature, ")") {
+		return nil, errors.Wrapf(ErrInvalidTextSignature, "signature: %s", signature)
+	}
+	types, err := splitSignatureTypes(signature[open+1 : len(signature)-1])
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidTextSignature, "signature: %s", signature)
+	}
+	inputs := make(abi.Arguments, 0, len(types))
+	for i, t := range types {
+		typ, err := abiTypeFromSignature(t)
+		if err != nil {
+			return nil, errors.Wrapf(wrapError(err, ErrInvalidTextSignature), "signature: %s", signature)
+		}
+		inputs = append(inputs, abi.Argument{Name: fmt.Sprintf("arg%d", i), Type: typ})
+	}
+	method := abi.NewMethod(signature[:open], signature[:open], abi.Function, "", false, false, inputs, nil)
+	return &method, nil
+}
+
+// splitSignatureTypes splits comma separated types, that can contain tuples
+func splitSignatureTypes(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var types []string
+	depth, start := 0, 0
+	for i, ch := range s {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, errors.New("unbalanced parentheses")
+			}
+		case ',':
+			if depth == 0 {
+				types = append(types, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, errors.New("unbalanced parentheses")
+	}
+	return append(types, s[start:]), nil
+}
+
+// abiTypeFromSignature creates ABI type from its canonical name, tuples are written as "(type1,type2)" with optional
+// array suffixes
+func abiTypeFromSignature(t string) (abi.Type, error) {
+	if !strings.HasPrefix(t, "(") {
+		return abi.NewType(t, "", nil)
+	}
+	components, suffix, err := tupleComponents(t)
+	if err != nil {
+		return abi.Type{}, err
+	}
+	return abi.NewType("tuple"+suffix, "", components)
+}
+
+func tupleComponents(t string) ([]abi.ArgumentMarshaling, string, error) {
+	closing := strings.LastIndex(t, ")")
+	types, err := splitSignatureTypes(t[1:closing])
+	if err != nil {
+		return nil, "", err
+	}
+	components := make([]abi.ArgumentMarshaling, 0, len(types))
+	for i, ct := range types {
+		component := abi.ArgumentMarshaling{Name: fmt.Sprintf("field%d", i), Type: ct}
+		if strings.HasPrefix(ct, "(") {
+			nested, suffix, err := tupleComponents(ct)
+			if err != nil {
+				return nil, "", err
+			}
+			component.Type, component.Components = "tuple"+suffix, nested
+		}
+		components = append(components, component)
+	}
+	return components, t[closing+1:], nil
+}
+
+// decodeWithSignatureDatabase fills the call with the first candidate signature from the database, which decodes its
+// input, or lists all candidates in the comment, if none does
+func (t *Tracer) decodeWithSignatureDatabase(call *DecodedCall, selector []byte, input string) bool {
+	candidates, err := t.SignatureDatabase.Lookup(context.Background(), selector)
+	if err != nil {
+		L.Debug().Err(err).Str("Selector", common.Bytes2Hex(selector)).Msg("Failed to look up signature")
+		return false
+	}
+	if len(candidates) == 0 {
+		return false
+	}
+	data := common.FromHex(input)
+	for _, candidate := range candidates {
+		method, err := MethodFromTextSignature(candidate)
+		// database might return signatures of other selectors
+		if err != nil || !bytes.Equal(method.ID, selector) {
+			continue
+		}
+		if len(data) < 4 {
+			return false
+		}
+		// arguments must use whole calldata, otherwise it's most likely a collision
+		values, err := method.Inputs.UnpackValues(data[4:])
+		if err != nil {
+			continue
+		}
+		if packed, err := method.Inputs.Pack(values...); err != nil || len(packed) != len(data)-4 {
+			continue
+		}
+		decoded, err := decodeTxInputs(L, data, method)
+		if err != nil {
+			continue
+		}
+		call.Method = candidate
+		call.Input = decoded
+		call.Comment = CommentSignatureFromDatabase
+		if len(candidates) > 1 {
+			call.Comment = fmt.Sprintf("%s; other candidates: %s", call.Comment, strings.Join(without(candidates, candidate), ", "))
+		}
+		return true
+	}
+	call.Comment = fmt.Sprintf("%s; candidate signatures from 4byte database: %s", call.Comment, strings.Join(candidates, ", "))
+	return false
+}
+
+func without(values []string, value string) []string {
+	filtered := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != value {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
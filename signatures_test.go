@@ -0,0 +1,52 @@
+package seth_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUtilMethodFromTextSignature(t *testing.T) {
+	signature := "swap(uint256,(address,(bytes32,bool)[])[],string)"
+	method, err := seth.MethodFromTextSignature(signature)
+	require.NoError(t, err, "failed to parse signature")
+	require.Equal(t, "swap", method.Name, "incorrect name")
+	require.Equal(t, signature, method.Sig, "signature should be canonical")
+	require.Equal(t, crypto.Keccak256([]byte(signature))[:4], method.ID, "incorrect selector")
+	require.Len(t, method.Inputs, 3, "incorrect number of inputs")
+	require.Equal(t, "arg1", method.Inputs[1].Name, "arguments should be named by position")
+
+	data, err := method.Inputs.Pack(common.Big1, []struct {
+		Field0 common.Address
+		Field1 []struct {
+			Field0 [32]byte
+			Field1 bool
+		}
+	}{{Field0: common.HexToAddress("0x01")}}, "text")
+	require.NoError(t, err, "tuple arguments should be packable")
+	values, err := method.Inputs.UnpackValues(data)
+	require.NoError(t, err, "failed to unpack arguments")
+	require.Equal(t, "text", values[2], "incorrect value")
+
+	for _, invalid := range []string{"swap", "(uint256)", "swap(uint256", "swap((uint256)", "swap(unknown)"} {
+		_, err = seth.MethodFromTextSignature(invalid)
+		require.Error(t, err, fmt.Sprintf("signature %s should be invalid", invalid))
+	}
+}
+
+func TestConfigSignatureDatabaseValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	cfg.SignatureDatabase = &seth.SignatureDatabaseCfg{URL: "www.4byte.directory"}
+	require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrSignatureDatabaseURL, "URL should be validated")
+
+	cfg.SignatureDatabase = &seth.SignatureDatabaseCfg{}
+	require.NoError(t, seth.ValidateConfig(cfg), "config should be valid")
+	require.Equal(t, seth.DefaultSignatureDatabaseURL, cfg.SignatureDatabase.URL, "default URL should be set")
+	require.Equal(t, seth.DefaultSignatureDatabaseCacheFile, cfg.SignatureDatabase.CacheFile, "default cache file should be set")
+}
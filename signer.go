@@ -0,0 +1,243 @@
+package seth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultRemoteSignerTimeout = 30 * time.Second
+)
+
+var (
+	ErrRemoteSignerURL      = errors.New("remote signer 'url_secret' must be a valid http(s) or ws(s) URL")
+	ErrRemoteSignerAddress  = errors.New("remote signer address is not a valid address")
+	ErrNoRemoteSignerAddr   = errors.New("remote signer has no 'addresses'")
+	ErrNoSignerForAddress   = errors.New("no signer for address")
+	ErrRemoteSign           = errors.New("remote signer failed to sign transaction")
+	ErrRemoteSignerResponse = errors.New("remote signer returned invalid signed transaction")
+	ErrNoPrivateKey         = errors.New("private key isn't available, it's signed by remote signer or KMS")
+)
+
+// Signer signs transactions sent from the address. It's used by transactors returned by NewTXOpts, contract deployments
+// and transfers, so that private keys don't have to be in seth's process, when a remote signing service is used.
+type Signer interface {
+	SignTx(ctx context.Context, address common.Address, tx *types.Transaction) (*types.Transaction, error)
+}
+
+// PrivateKeySigner signs transactions with in-process private keys
+type PrivateKeySigner struct {
+	keys   map[common.Address]*ecdsa.PrivateKey
+	signer types.Signer
+}
+
+// NewPrivateKeySigner creates signer for private keys, nil keys are skipped
+func NewPrivateKeySigner(chainID *big.Int, keys ...*ecdsa.PrivateKey) *PrivateKeySigner {
+	s := &PrivateKeySigner{
+		keys:   make(map[common.Address]*ecdsa.PrivateKey),
+		signer: types.LatestSignerForChainID(chainID),
+	}
+	for _, key := range keys {
+		if key != nil {
+			s.keys[crypto.PubkeyToAddress(key.PublicKey)] = key
+		}
+	}
+	return s
+}
+
+// SignTx signs transaction with the private key of the address
+func (s *PrivateKeySigner) SignTx(_ context.Context, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	key, ok := s.keys[address]
+	if !ok {
+		return nil, errors.Wrapf(ErrNoSignerForAddress, "address: %s", address.Hex())
+	}
+	return types.SignTx(tx, s.signer, key)
+}
+
+// HasKey returns true if signer has the private key of the address
+func (s *PrivateKeySigner) HasKey(address common.Address) bool {
+	_, ok := s.keys[address]
+	return ok
+}
+
+// RemoteSignerCfg configures signing service with 'eth_signTransaction' JSON-RPC method (e.g. web3signer or Clef), which
+// signs transactions of its addresses
+type RemoteSignerCfg struct {
+	URL       string    `toml:"url_secret"`
+	Addresses []string  `toml:"addresses"`
+	Timeout   *Duration `toml:"timeout"`
+}
+
+func validateRemoteSignerCfg(n *Network) error {
+	if n.RemoteSigner == nil {
+		return nil
+	}
+	u, err := url.Parse(n.RemoteSigner.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "ws" && u.Scheme != "wss") {
+		return errors.Wrapf(ErrRemoteSignerURL, "network: '%s'", n.Name)
+	}
+	if len(n.RemoteSigner.Addresses) == 0 {
+		return errors.Wrapf(ErrNoRemoteSignerAddr, "network: '%s'", n.Name)
+	}
+	for _, address := range n.RemoteSigner.Addresses {
+		if !common.IsHexAddress(address) {
+			return errors.Wrapf(ErrRemoteSignerAddress, "address: '%s', network: '%s'", address, n.Name)
+		}
+	}
+	if n.RemoteSigner.Timeout == nil {
+		n.RemoteSigner.Timeout = MustMakeDuration(DefaultRemoteSignerTimeout)
+	}
+	return nil
+}
+
+// RemoteSigner signs transactions with 'eth_signTransaction' of a remote signing service
+type RemoteSigner struct {
+	client  *rpc.Client
+	signer  types.Signer
+	timeout time.Duration
+}
+
+// NewRemoteSigner connects to remote signing service
+func NewRemoteSigner(cfg *RemoteSignerCfg, chainID *big.Int) (*RemoteSigner, error) {
+	client, err := rpc.DialContext(context.Background(), cfg.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to remote signer")
+	}
+	timeout := DefaultRemoteSignerTimeout
+	if cfg.Timeout != nil {
+		timeout = cfg.Timeout.Duration()
+	}
+	return &RemoteSigner{
+		client:  client,
+		signer:  types.LatestSignerForChainID(chainID),
+		timeout: timeout,
+	}, nil
+}
+
+// remoteSignerTxArgs are arguments of 'eth_signTransaction'
+type remoteSignerTxArgs struct {
+	From                 common.Address    `json:"from"`
+	To                   *common.Address   `json:"to,omitempty"`
+	Gas                  hexutil.Uint64    `json:"gas"`
+	GasPrice             *hexutil.Big      `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big      `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big      `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big      `json:"value"`
+	Nonce                hexutil.Uint64    `json:"nonce"`
+	Data                 hexutil.Bytes     `json:"data"`
+	ChainID              *hexutil.Big      `json:"chainId"`
+	AccessList           *types.AccessList `json:"accessList,omitempty"`
+}
+
+// SignTx sends transaction to remote signer and verifies, that the signed transaction is the same and signed by the address
+func (s *RemoteSigner) SignTx(ctx context.Context, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	args := remoteSignerTxArgs{
+		From:    address,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(s.signer.ChainID()),
+	}
+	switch tx.Type() {
+	case types.LegacyTxType:
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	case types.AccessListTxType:
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+		accessList := tx.AccessList()
+		args.AccessList = &accessList
+	default:
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+		accessList := tx.AccessList()
+		args.AccessList = &accessList
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	var result json.RawMessage
+	if err := s.client.CallContext(ctx, &result, "eth_signTransaction", args); err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrRemoteSign), "address: %s", address.Hex())
+	}
+
+	// web3signer returns raw transaction, geth and Clef return object with raw transaction and its fields
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(result, &raw); err != nil {
+		var signed struct {
+			Raw hexutil.Bytes `json:"raw"`
+		}
+		if err := json.Unmarshal(result, &signed); err != nil {
+			return nil, errors.Wrapf(wrapError(err, ErrRemoteSignerResponse), "address: %s", address.Hex())
+		}
+		raw = signed.Raw
+	}
+	signedTx := new(types.Transaction)
+	if err := signedTx.UnmarshalBinary(raw); err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrRemoteSignerResponse), "address: %s", address.Hex())
+	}
+
+	if s.signer.Hash(signedTx) != s.signer.Hash(tx) {
+		return nil, errors.Wrapf(wrapError(errors.New("signed transaction differs from the requested one"), ErrRemoteSignerResponse), "address: %s", address.Hex())
+	}
+	sender, err := types.Sender(s.signer, signedTx)
+	if err != nil || sender != address {
+		return nil, errors.Wrapf(wrapError(fmt.Errorf("transaction was not signed by %s", address.Hex()), ErrRemoteSignerResponse), "address: %s", address.Hex())
+	}
+	return signedTx, nil
+}
+
+// Close closes connection to the remote signer
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
+
+// keySigner signs transactions with in-process private keys or KMS keys and falls back to the remote signer for other
+// addresses
+type keySigner struct {
+	local  *PrivateKeySigner
+	kms    *KMSSigner
+	remote Signer
+}
+
+func (s *keySigner) SignTx(ctx context.Context, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	if s.kms != nil && s.kms.HasKey(address) {
+		return s.kms.SignTx(ctx, address, tx)
+	}
+	if s.local.HasKey(address) || s.remote == nil {
+		return s.local.SignTx(ctx, address, tx)
+	}
+	return s.remote.SignTx(ctx, address, tx)
+}
+
+// newSigningTransactor creates transactor, which signs with client's Signer, the context is used both for signing and as
+// transactor's context
+func (m *Client) newSigningTransactor(ctx context.Context, keyNum int) (*bind.TransactOpts, error) {
+	if keyNum >= len(m.Addresses) {
+		return nil, errors.Wrap(ErrNoKeyLoaded, fmt.Sprintf("requested key: %d", keyNum))
+	}
+	from := m.Addresses[keyNum]
+	return &bind.TransactOpts{
+		From: from,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != from {
+				return nil, bind.ErrNotAuthorized
+			}
+			return m.Signer.SignTx(ctx, address, tx)
+		},
+		Context: ctx,
+	}, nil
+}
@@ -0,0 +1,124 @@
+package seth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+// newRemoteSignerServer simulates signing service, which signs with the key and returns raw transaction (like web3signer)
+// or an object with it (like geth and Clef)
+func newRemoteSignerServer(t *testing.T, key *ecdsa.PrivateKey, objectResponse bool, requests *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []struct {
+				From                 common.Address  `json:"from"`
+				To                   *common.Address `json:"to"`
+				Gas                  hexutil.Uint64  `json:"gas"`
+				GasPrice             *hexutil.Big    `json:"gasPrice"`
+				MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas"`
+				MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
+				Value                *hexutil.Big    `json:"value"`
+				Nonce                hexutil.Uint64  `json:"nonce"`
+				Data                 hexutil.Bytes   `json:"data"`
+				ChainID              *hexutil.Big    `json:"chainId"`
+			} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req), "failed to decode request")
+		require.Equal(t, "eth_signTransaction", req.Method, "incorrect method")
+		args := req.Params[0]
+
+		var tx *types.Transaction
+		if args.GasPrice != nil {
+			tx = types.NewTx(&types.LegacyTx{Nonce: uint64(args.Nonce), To: args.To, Gas: uint64(args.Gas), GasPrice: args.GasPrice.ToInt(), Value: args.Value.ToInt(), Data: args.Data})
+		} else {
+			tx = types.NewTx(&types.DynamicFeeTx{ChainID: args.ChainID.ToInt(), Nonce: uint64(args.Nonce), To: args.To, Gas: uint64(args.Gas), GasFeeCap: args.MaxFeePerGas.ToInt(), GasTipCap: args.MaxPriorityFeePerGas.ToInt(), Value: args.Value.ToInt(), Data: args.Data})
+		}
+		signed, err := types.SignTx(tx, types.LatestSignerForChainID(args.ChainID.ToInt()), key)
+		require.NoError(t, err, "failed to sign transaction")
+		raw, err := signed.MarshalBinary()
+		require.NoError(t, err, "failed to marshal transaction")
+
+		result := fmt.Sprintf("%q", hexutil.Encode(raw))
+		if objectResponse {
+			result = fmt.Sprintf(`{"raw":%q,"tx":{}}`, hexutil.Encode(raw))
+		}
+		_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAPIRemoteSigner(t *testing.T) {
+	rootKey, err := crypto.HexToECDSA("ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80")
+	require.NoError(t, err, "failed to parse key")
+	rootAddress := crypto.PubkeyToAddress(rootKey.PublicKey)
+
+	for _, objectResponse := range []bool{false, true} {
+		t.Run(fmt.Sprintf("object response %v", objectResponse), func(t *testing.T) {
+			var requests int32
+			server := newRemoteSignerServer(t, rootKey, objectResponse, &requests)
+
+			cfg, err := seth.ReadConfig()
+			require.NoError(t, err, "failed to read config")
+			cfg.Network.PrivateKeys = nil
+			cfg.Network.RemoteSigner = &seth.RemoteSignerCfg{URL: server.URL, Addresses: []string{rootAddress.Hex()}}
+
+			c, err := seth.NewClientWithConfig(cfg)
+			require.NoError(t, err, "failed to create client")
+			require.Equal(t, []common.Address{rootAddress}, c.Addresses, "remote signer's address should be the root key")
+			_, err = c.GetRootPrivateKey()
+			require.ErrorIs(t, err, seth.ErrNoPrivateKey, "private key should not be available")
+
+			err = c.TransferETHFromKey(context.Background(), 0, common.HexToAddress("0x01").Hex(), big.NewInt(1), nil)
+			require.NoError(t, err, "failed to transfer funds")
+
+			_, err = c.DeployContractFromContractStore(c.NewTXOpts(), "NetworkDebugSubContract.abi")
+			require.NoError(t, err, "failed to deploy contract")
+			require.Equal(t, int32(2), atomic.LoadInt32(&requests), "all transactions should be signed remotely")
+		})
+	}
+}
+
+func TestAPIRemoteSignerVerifiesSignature(t *testing.T) {
+	otherKey, err := crypto.GenerateKey()
+	require.NoError(t, err, "failed to generate key")
+	var requests int32
+	server := newRemoteSignerServer(t, otherKey, false, &requests)
+
+	signer, err := seth.NewRemoteSigner(&seth.RemoteSignerCfg{URL: server.URL}, big.NewInt(1337))
+	require.NoError(t, err, "failed to create remote signer")
+	t.Cleanup(signer.Close)
+
+	address := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(1337), To: &address, Gas: 21000, GasFeeCap: big.NewInt(2), GasTipCap: big.NewInt(1), Value: big.NewInt(1)})
+	_, err = signer.SignTx(context.Background(), address, tx)
+	require.ErrorIs(t, err, seth.ErrRemoteSignerResponse, "transaction signed with other key should be rejected")
+}
+
+func TestConfigRemoteSignerValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	cfg.Network.RemoteSigner = &seth.RemoteSignerCfg{URL: "localhost:9000", Addresses: []string{"0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"}}
+	require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrRemoteSignerURL, "URL should be validated")
+
+	cfg.Network.RemoteSigner = &seth.RemoteSignerCfg{URL: "http://localhost:9000", Addresses: []string{"0x01"}}
+	require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrRemoteSignerAddress, "addresses should be validated")
+}
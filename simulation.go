@@ -0,0 +1,161 @@
+package seth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrSimulationReverted = errors.New("transaction simulation reverted")
+	ErrSimulationFailed   = errors.New("failed to simulate transaction")
+)
+
+// simulateFirstKey is the context key of pre-flight simulation requested with transaction options
+type simulateFirstKey struct{}
+
+// SimulationResult is the result of transaction executed with eth_call on top of the pending block
+type SimulationResult struct {
+	// ReturnData is the data returned by the call
+	ReturnData []byte
+	// Reverted is true if the transaction would revert
+	Reverted bool
+	// RevertReason is decoded revert error, if it could be decoded
+	RevertReason *RevertReason
+	// CallTrace is the trace of reverted transaction executed on top of the latest block, it's set only if node supports
+	// debug_traceCall
+	CallTrace *TXCallTraceOutput
+}
+
+// WithSimulateFirst makes transaction options run the transaction with eth_call on top of the pending block right after
+// it's signed and before it's sent. If it would revert, the transaction isn't sent and the error with decoded revert reason
+// is returned instead, so no gas is spent. It's useful, when gas limit is set explicitly and estimation, which would catch
+// the revert, is skipped.
+func WithSimulateFirst() TransactOpt {
+	return func(o *bind.TransactOpts) {
+		o.Context = context.WithValue(contextOrBackground(o.Context), simulateFirstKey{}, true)
+	}
+}
+
+// SimulateTransaction executes signed transaction with eth_call on top of the pending block without sending it. If it
+// reverts, result has decoded revert reason (and call trace, if node supports debug_traceCall) and error describing it
+// is returned.
+func (m *Client) SimulateTransaction(tx *types.Transaction) (*SimulationResult, error) {
+	msg, err := m.CallMsgFromTx(tx)
+	if err != nil {
+		return nil, wrapError(err, ErrSimulationFailed)
+	}
+	return m.simulateCall(msg)
+}
+
+// simulateCall executes the message on top of the pending block, it's separate from SimulateTransaction, so that signed
+// transaction doesn't have to be decoded again, when sender is already known
+func (m *Client) simulateCall(msg ethereum.CallMsg) (*SimulationResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
+	defer cancel()
+
+	result := &SimulationResult{}
+	returnData, callErr := m.Client.PendingCallContract(ctx, msg)
+	if callErr == nil {
+		result.ReturnData = returnData
+		return result, nil
+	}
+	data, isRevert := revertDataFromErr(callErr)
+	if !isRevert && !strings.Contains(callErr.Error(), "execution reverted") {
+		return nil, wrapError(callErr, ErrSimulationFailed)
+	}
+	result.Reverted = true
+
+	// trace shows which call reverted and it has revert data even if node's eth_call error doesn't
+	if m.Capabilities().DebugAPI {
+		trace, err := m.traceCall(ctx, msg)
+		if err != nil {
+			m.logger().Debug().Err(err).Msg("Failed to trace simulated transaction")
+		} else {
+			result.CallTrace = trace
+			if len(data) == 0 && trace.Output != "" {
+				data = common.FromHex(trace.Output)
+			}
+		}
+	}
+
+	reason := callErr.Error()
+	if len(data) > 0 {
+		var calledContract string
+		if msg.To != nil && m.ContractAddressToNameMap.IsKnownAddress(msg.To.Hex()) {
+			calledContract = m.ContractAddressToNameMap.GetContractName(msg.To.Hex())
+		}
+		result.RevertReason = decodeRevertReasonOf(m.ContractStore, data, calledContract)
+	}
+	if decoded, err := m.DecodeCustomABIErr(callErr); err == nil && decoded != "" {
+		reason = decoded
+	} else if result.RevertReason != nil && result.RevertReason.Message != "" {
+		reason = result.RevertReason.Message
+	}
+	if result.RevertReason != nil && msg.To != nil {
+		result.RevertReason.Address = msg.To.Hex()
+		if result.RevertReason.Contract == "" && m.ContractAddressToNameMap.IsKnownAddress(msg.To.Hex()) {
+			result.RevertReason.Contract = m.ContractAddressToNameMap.GetContractName(msg.To.Hex())
+		}
+	}
+
+	m.logger().Warn().
+		Str("Reason", reason).
+		Msg("Transaction simulation reverted")
+
+	return result, errors.Wrapf(ErrSimulationReverted, "reason: %s", reason)
+}
+
+// traceCall traces the message with debug_traceCall and callTracer on top of the latest block, since Geth doesn't support
+// tracing on top of the pending one
+func (m *Client) traceCall(ctx context.Context, msg ethereum.CallMsg) (*TXCallTraceOutput, error) {
+	var trace *TXCallTraceOutput
+	if err := m.Client.Client().CallContext(ctx, &trace, "debug_traceCall", toCallArg(msg), "latest", map[string]interface{}{
+		"tracer": "callTracer",
+		"tracerConfig": map[string]interface{}{
+			"withLog": true,
+		},
+	}); err != nil {
+		return nil, err
+	}
+	if trace == nil {
+		return nil, errors.New("empty trace")
+	}
+	return trace, nil
+}
+
+// attachSimulation wraps signer of transaction options, so that signed transaction is simulated and isn't sent, if it
+// would revert. It's a no-op, if simulation wasn't requested.
+func (m *Client) attachSimulation(opts *bind.TransactOpts) {
+	if opts.Signer == nil {
+		return
+	}
+	if simulate, ok := contextOrBackground(opts.Context).Value(simulateFirstKey{}).(bool); !ok || !simulate {
+		return
+	}
+
+	sign := opts.Signer
+	opts.Signer = func(from common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		signed, err := sign(from, tx)
+		if err != nil {
+			return nil, err
+		}
+		// bind doesn't send transactions, that failed to be signed
+		if _, err := m.simulateCall(ethereum.CallMsg{
+			From:       from,
+			To:         signed.To(),
+			Gas:        signed.Gas(),
+			Value:      signed.Value(),
+			Data:       signed.Data(),
+			AccessList: signed.AccessList(),
+		}); err != nil {
+			return nil, err
+		}
+		return signed, nil
+	}
+}
@@ -0,0 +1,48 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPISimulateFirst(t *testing.T) {
+	c := newClient(t)
+
+	nonce, err := c.Client.PendingNonceAt(context.Background(), c.Addresses[0])
+	require.NoError(t, err, "failed to get nonce")
+	// gas limit is set, so that gas estimation doesn't catch the revert
+	_, err = TestEnv.DebugContract.AlwaysRevertsCustomError(c.NewTXOpts(seth.WithGasLimit(1_000_000), seth.WithSimulateFirst()))
+	require.ErrorIs(t, err, seth.ErrSimulationReverted, "simulation should return decoded revert reason")
+	after, err := c.Client.PendingNonceAt(context.Background(), c.Addresses[0])
+	require.NoError(t, err, "failed to get nonce")
+	require.Equal(t, nonce, after, "reverting transaction shouldn't be sent")
+
+	_, err = c.Decode(TestEnv.DebugContract.AddCounter(c.NewTXOpts(seth.WithSimulateFirst()), big.NewInt(0), big.NewInt(1)))
+	require.NoError(t, err, "successful transaction should be sent")
+}
+
+func TestAPISimulateTransaction(t *testing.T) {
+	c := newClient(t)
+
+	tx, err := TestEnv.DebugContract.AlwaysRevertsCustomError(c.NewTXOpts(seth.WithGasLimit(1_000_000), seth.WithNoSend(true)))
+	require.NoError(t, err, "failed to sign transaction")
+	result, err := c.SimulateTransaction(tx)
+	require.Error(t, err, "simulation should revert")
+	require.True(t, result.Reverted, "result should be reverted")
+	require.NotNil(t, result.RevertReason, "revert reason should be decoded")
+	require.Equal(t, "CustomErr", result.RevertReason.Name, "incorrect revert reason")
+	require.Equal(t, TestEnv.DebugContractAddress.Hex(), result.RevertReason.Address, "incorrect address")
+	require.NotNil(t, result.CallTrace, "call trace should be set")
+	require.NotEmpty(t, result.CallTrace.Error, "call trace should have an error")
+
+	tx, err = TestEnv.DebugContract.AddCounter(c.NewTXOpts(seth.WithNoSend(true)), big.NewInt(0), big.NewInt(1))
+	require.NoError(t, err, "failed to sign transaction")
+	result, err = c.SimulateTransaction(tx)
+	require.NoError(t, err, "simulation shouldn't revert")
+	require.False(t, result.Reverted, "result shouldn't be reverted")
+	require.NotEmpty(t, result.ReturnData, "return data should be set")
+}
@@ -0,0 +1,80 @@
+package seth
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ChainSnapshot is a handle of chain state snapshot taken on a simulated network together with client's local state, that
+// has to be restored with it: nonces tracked by NonceManager and contract map
+type ChainSnapshot struct {
+	// ID is the ID of snapshot returned by evm_snapshot
+	ID        string
+	nonces    map[common.Address]int64
+	contracts map[string]string
+}
+
+// TestingT is the part of testing.TB used by SnapshotTest
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Fatalf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// Snapshot snapshots state of the simulated network (see DevNode) and client's nonces and contract map, it returns an error
+// on any other network
+func (m *Client) Snapshot() (*ChainSnapshot, error) {
+	node, err := m.DevNode()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
+	defer cancel()
+	id, err := node.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &ChainSnapshot{ID: id, contracts: m.ContractAddressToNameMap.Snapshot()}
+	if m.NonceManager != nil {
+		snapshot.nonces = m.NonceManager.snapshotNonces()
+	}
+	return snapshot, nil
+}
+
+// RevertTo reverts state of the simulated network to the snapshot and restores client's nonces and contract map. Snapshot
+// can be reverted to only once.
+func (m *Client) RevertTo(snapshot *ChainSnapshot) error {
+	node, err := m.DevNode()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
+	defer cancel()
+	if err := node.Revert(ctx, snapshot.ID); err != nil {
+		return err
+	}
+	m.ContractAddressToNameMap.Restore(snapshot.contracts)
+	if m.NonceManager != nil && snapshot.nonces != nil {
+		m.NonceManager.restoreNonces(snapshot.nonces)
+	}
+	return nil
+}
+
+// SnapshotTest snapshots the simulated network at the start of the test and reverts to the snapshot when the test
+// finishes, so that each test starts with the same state without redeploying contracts. Test fails, if network isn't
+// a simulated one.
+func (m *Client) SnapshotTest(t TestingT) {
+	t.Helper()
+	snapshot, err := m.Snapshot()
+	if err != nil {
+		t.Fatalf("failed to snapshot chain: %v", err)
+		return
+	}
+	t.Cleanup(func() {
+		if err := m.RevertTo(snapshot); err != nil {
+			t.Errorf("failed to revert chain to snapshot %s: %v", snapshot.ID, err)
+		}
+	})
+}
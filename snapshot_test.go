@@ -0,0 +1,93 @@
+package seth_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeAnvilProxy forwards requests to the node, but pretends to be Anvil and records snapshot cheat codes instead of
+// forwarding them
+func newFakeAnvilProxy(t *testing.T, target string, reverted *[]string) *httptest.Server {
+	var mu sync.Mutex
+	snapshots := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err, "failed to read request")
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []string        `json:"params"`
+		}
+		if json.Unmarshal(body, &req) == nil {
+			var result string
+			switch req.Method {
+			case "web3_clientVersion":
+				result = `"anvil/v0.2.0"`
+			case "evm_snapshot":
+				mu.Lock()
+				snapshots++
+				result = fmt.Sprintf(`"0x%x"`, snapshots)
+				mu.Unlock()
+			case "evm_revert":
+				mu.Lock()
+				*reverted = append(*reverted, req.Params[0])
+				mu.Unlock()
+				result = "true"
+			}
+			if result != "" {
+				_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+				return
+			}
+		}
+		forwardRPCRequest(t, w, target, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAPISnapshotRevert(t *testing.T) {
+	c := newClient(t)
+	httpURL := nodeHTTPURL(t, c)
+
+	var reverted []string
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.Network.URLs = []string{newFakeAnvilProxy(t, httpURL, &reverted).URL}
+	client, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to create client")
+	require.True(t, client.IsSimulatedNetwork(), "proxy should be detected as Anvil")
+	require.NotNil(t, client.NonceManager, "nonce manager should be initialised")
+
+	address := common.HexToAddress("0x00000000000000000000000000000000000000a1").Hex()
+	contracts := client.ContractAddressToNameMap.Size()
+	nonce := client.NonceManager.Nonces[client.Addresses[0]]
+
+	t.Run("isolated test", func(t *testing.T) {
+		client.SnapshotTest(t)
+		client.ContractAddressToNameMap.AddContract(address, "Deployed")
+		client.NonceManager.NextNonce(client.Addresses[0])
+	})
+	require.Equal(t, []string{"0x1"}, reverted, "chain should be reverted after the test")
+	require.False(t, client.ContractAddressToNameMap.IsKnownAddress(address), "contract map should be restored")
+	require.Equal(t, contracts, client.ContractAddressToNameMap.Size(), "contract map should be restored")
+	require.Equal(t, nonce, client.NonceManager.Nonces[client.Addresses[0]], "nonces should be restored")
+
+	snapshot, err := client.Snapshot()
+	require.NoError(t, err, "failed to snapshot")
+	require.Equal(t, "0x2", snapshot.ID, "incorrect snapshot ID")
+	client.ContractAddressToNameMap.AddContract(address, "Deployed")
+	require.NoError(t, client.RevertTo(snapshot), "failed to revert")
+	require.False(t, client.ContractAddressToNameMap.IsKnownAddress(address), "contract map should be restored")
+
+	_, err = c.Snapshot()
+	require.Error(t, err, "real network can't be snapshotted")
+}
@@ -0,0 +1,335 @@
+package seth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// SourceMapFileSuffix is the suffix of solc combined JSON files with runtime source maps, that are loaded from BIN dir
+	SourceMapFileSuffix = ".srcmap.json"
+	// SourceSnippetContextLines is the number of lines printed before and after the line, where revert happened
+	SourceSnippetContextLines = 2
+)
+
+var (
+	ErrParseSourceMap      = errors.New("failed to parse source map")
+	ErrOpenSourceMapFile   = errors.New("failed to open source map file")
+	ErrNoSourceForPC       = errors.New("no source mapped to program counter")
+	ErrNoRevertInTrace     = errors.New("no REVERT or INVALID opcode found in transaction trace")
+	ErrNoSourceMapForTrace = errors.New("none of the reverting contracts has a source map")
+)
+
+// SourceMapEntry is a decompressed entry of solc source map, File is -1 for code generated by the compiler
+type SourceMapEntry struct {
+	Start  int
+	Length int
+	File   int
+	Jump   string
+}
+
+// SourceMap is a solc runtime source map together with list of source files indexed by entries
+type SourceMap struct {
+	Entries []SourceMapEntry
+	Sources []string
+}
+
+// SourceLocation is a place in Solidity source, to which program counter of a contract is mapped
+type SourceLocation struct {
+	Contract string `json:"contract,omitempty"`
+	Address  string `json:"address,omitempty"`
+	PC       uint64 `json:"pc"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Snippet  string `json:"snippet,omitempty"`
+}
+
+// String returns location as "file:line", or just file if line is unknown
+func (l *SourceLocation) String() string {
+	if l.Line == 0 {
+		return l.File
+	}
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+// solcCombinedJSON is output of "solc --combined-json srcmap-runtime"
+type solcCombinedJSON struct {
+	Contracts map[string]struct {
+		SrcMapRuntime string `json:"srcmap-runtime"`
+	} `json:"contracts"`
+	SourceList []string `json:"sourceList"`
+}
+
+// ParseSourceMap decompresses solc source map ("s:l:f:j:m" entries separated with ";", where empty fields are copied
+// from the previous entry). Sources are source file paths indexed by "f" field.
+func ParseSourceMap(srcMap string, sources []string) (*SourceMap, error) {
+	sm := &SourceMap{Sources: sources}
+	if srcMap == "" {
+		return sm, nil
+	}
+
+	prev := SourceMapEntry{File: -1}
+	for i, raw := range strings.Split(srcMap, ";") {
+		entry := prev
+		for j, field := range strings.Split(raw, ":") {
+			if field == "" {
+				continue
+			}
+			var err error
+			switch j {
+			case 0:
+				entry.Start, err = strconv.Atoi(field)
+			case 1:
+				entry.Length, err = strconv.Atoi(field)
+			case 2:
+				entry.File, err = strconv.Atoi(field)
+			case 3:
+				entry.Jump = field
+			}
+			if err != nil {
+				return nil, errors.Wrapf(wrapError(err, ErrParseSourceMap), "entry %d: '%s'", i, raw)
+			}
+		}
+		sm.Entries = append(sm.Entries, entry)
+		prev = entry
+	}
+
+	return sm, nil
+}
+
+// loadSourceMaps loads runtime source maps of all contracts from solc combined JSON file, keyed by contract name
+func loadSourceMaps(fsys fs.FS, path string) (map[string]*SourceMap, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, wrapError(err, ErrOpenSourceMapFile)
+	}
+	var combined solcCombinedJSON
+	if err := json.Unmarshal(data, &combined); err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrParseSourceMap), "file: %s", path)
+	}
+
+	sourceMaps := make(map[string]*SourceMap)
+	for fullName, contract := range combined.Contracts {
+		if contract.SrcMapRuntime == "" {
+			continue
+		}
+		sm, err := ParseSourceMap(contract.SrcMapRuntime, combined.SourceList)
+		if err != nil {
+			return nil, errors.Wrapf(err, "contract %s in %s", fullName, path)
+		}
+		// solc uses "path/to/Contract.sol:Contract" keys
+		sourceMaps[fullName[strings.LastIndex(fullName, ":")+1:]] = sm
+	}
+
+	return sourceMaps, nil
+}
+
+// Location returns source location of the instruction at program counter of the runtime code. Snippet contains
+// a few lines around it, if the source file can be read.
+func (s *SourceMap) Location(code []byte, pc uint64) (*SourceLocation, error) {
+	idx, ok := instructionIndex(code, pc)
+	if !ok || idx >= len(s.Entries) {
+		return nil, errors.Wrapf(ErrNoSourceForPC, "pc %d", pc)
+	}
+	entry := s.Entries[idx]
+	if entry.File < 0 || entry.File >= len(s.Sources) {
+		return nil, errors.Wrapf(ErrNoSourceForPC, "pc %d", pc)
+	}
+
+	loc := &SourceLocation{PC: pc, File: s.Sources[entry.File]}
+	source, err := os.ReadFile(filepath.Clean(loc.File))
+	if err != nil {
+		L.Debug().Err(err).Str("File", loc.File).Msg("Failed to read source file, source location won't have line number")
+		return loc, nil
+	}
+	if entry.Start > len(source) {
+		return nil, fmt.Errorf("source map entry of program counter %d is out of bounds of %s, is it outdated?", pc, loc.File)
+	}
+
+	before := source[:entry.Start]
+	loc.Line = strings.Count(string(before), "\n") + 1
+	loc.Column = entry.Start - strings.LastIndex(string(before), "\n")
+	loc.Snippet = sourceSnippet(strings.Split(string(source), "\n"), loc.Line)
+
+	return loc, nil
+}
+
+// instructionIndex returns index of instruction starting at program counter, source map has an entry per instruction
+func instructionIndex(code []byte, pc uint64) (int, bool) {
+	idx := 0
+	for i := uint64(0); i < uint64(len(code)); i++ {
+		if i == pc {
+			return idx, true
+		}
+		// PUSH1-PUSH32 are followed by their data
+		if op := code[i]; op >= 0x60 && op <= 0x7f {
+			i += uint64(op - 0x5f)
+		}
+		idx++
+	}
+	return 0, false
+}
+
+func sourceSnippet(lines []string, line int) string {
+	from := max(line-SourceSnippetContextLines, 1)
+	to := min(line+SourceSnippetContextLines, len(lines))
+	width := len(strconv.Itoa(to))
+
+	var sb strings.Builder
+	for i := from; i <= to; i++ {
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+		sb.WriteString(fmt.Sprintf("%s %*d | %s\n", marker, width, i, lines[i-1]))
+	}
+	return sb.String()
+}
+
+// structLog is a single step of debug_traceTransaction default (struct logger) tracer
+type structLog struct {
+	PC    uint64   `json:"pc"`
+	Op    string   `json:"op"`
+	Depth int      `json:"depth"`
+	Stack []string `json:"stack"`
+}
+
+// revertSite is a REVERT or INVALID opcode executed by the contract
+type revertSite struct {
+	address common.Address
+	pc      uint64
+}
+
+// RevertLocation maps the revert of the transaction to Solidity source, using runtime source maps from the contract store.
+// It traces the transaction with opcode level tracer to find program counter of the revert and, if the reverting contract
+// has no source map, it tries contracts that propagated the revert. Contract creation reverts are not supported.
+func (m *Client) RevertLocation(ctx context.Context, txHash common.Hash) (*SourceLocation, error) {
+	tx, _, err := m.Client.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	if tx.To() == nil {
+		return nil, errors.New("reverts of contract creation transactions can't be mapped to source")
+	}
+
+	var trace struct {
+		StructLogs []structLog `json:"structLogs"`
+	}
+	if err := m.Client.Client().CallContext(ctx, &trace, "debug_traceTransaction", txHash.Hex(), map[string]interface{}{
+		"disableStorage": true,
+		"disableMemory":  true,
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to trace transaction")
+	}
+
+	sites := revertSites(*tx.To(), trace.StructLogs)
+	if len(sites) == 0 {
+		return nil, ErrNoRevertInTrace
+	}
+
+	for _, site := range sites {
+		name := m.ContractAddressToNameMap.GetContractName(site.address.Hex())
+		sm, ok := m.ContractStore.GetSourceMap(name)
+		if !ok {
+			continue
+		}
+		code, err := m.Client.CodeAt(ctx, site.address, nil)
+		if err != nil {
+			return nil, err
+		}
+		loc, err := sm.Location(code, site.pc)
+		if err != nil {
+			return nil, err
+		}
+		loc.Contract = name
+		loc.Address = site.address.Hex()
+		return loc, nil
+	}
+
+	return nil, ErrNoSourceMapForTrace
+}
+
+// revertSites returns REVERT/INVALID opcodes, that reverted the transaction, starting from the deepest one and ending with
+// the top-level one. Revert of a sub-call is treated as the origin of its caller's revert, unless the caller made another
+// call in between.
+func revertSites(to common.Address, logs []structLog) []revertSite {
+	type frame struct {
+		address common.Address
+		// reverts are reverts of the last sub-call, if it reverted
+		reverts []revertSite
+	}
+	frames := []*frame{{address: to}}
+	var callee common.Address
+
+	for _, l := range logs {
+		if l.Depth < 1 {
+			continue
+		}
+		for len(frames) < l.Depth {
+			frames = append(frames, &frame{address: callee})
+		}
+		frames = frames[:l.Depth]
+		current := frames[l.Depth-1]
+
+		switch l.Op {
+		case "CALL", "CALLCODE", "DELEGATECALL", "STATICCALL":
+			current.reverts = nil
+			// address is the second item from the top of the stack
+			if len(l.Stack) >= 2 {
+				callee = common.HexToAddress(l.Stack[len(l.Stack)-2])
+			}
+		case "CREATE", "CREATE2":
+			current.reverts = nil
+			// init code has no runtime source map
+			callee = common.Address{}
+		case "REVERT", "INVALID":
+			sites := append(current.reverts, revertSite{address: current.address, pc: l.PC})
+			if l.Depth == 1 {
+				return sites
+			}
+			frames[l.Depth-2].reverts = sites
+		}
+	}
+
+	return nil
+}
+
+// attachRevertLocation maps revert of the transaction to source, if any source maps are loaded. Location is printed
+// together with source snippet and added to revert error.
+func (m *Client) attachRevertLocation(l zerolog.Logger, decoded *DecodedTransaction, tx *types.Transaction, revertErr error) error {
+	if m.ContractStore == nil || !m.ContractStore.HasSourceMaps() {
+		return revertErr
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.Cfg.Network.TxnTimeout.Duration())
+	defer cancel()
+	loc, err := m.RevertLocation(ctx, tx.Hash())
+	if err != nil {
+		l.Debug().Err(err).Msg("Failed to map revert to source")
+		return revertErr
+	}
+
+	if decoded != nil {
+		decoded.RevertLocation = loc
+	}
+	l.Info().
+		Str("Contract", loc.Contract).
+		Uint64("PC", loc.PC).
+		Msgf("Transaction reverted at %s\n%s", loc, loc.Snippet)
+
+	if revertErr == nil {
+		return nil
+	}
+	return errors.Wrapf(revertErr, "reverted at %s", loc)
+}
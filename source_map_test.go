@@ -0,0 +1,122 @@
+package seth_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+const reverterSource = `contract Reverter {
+    fallback() external {
+        revert();
+    }
+}
+`
+
+var (
+	// PUSH1 0 PUSH1 0 REVERT
+	reverterRuntime = common.FromHex("0x60006000fd")
+	// returns runtime code
+	reverterCreation = common.FromHex("0x6460006000fd6000526005601bf3")
+)
+
+// writeReverterArtifacts writes Reverter source and solc combined JSON with its runtime source map, all instructions
+// are mapped to revert() statement
+func writeReverterArtifacts(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "Reverter.sol")
+	require.NoError(t, os.WriteFile(sourcePath, []byte(reverterSource), 0600), "failed to write source")
+
+	combined := map[string]interface{}{
+		"contracts": map[string]interface{}{
+			sourcePath + ":Reverter": map[string]string{
+				"srcmap-runtime": fmt.Sprintf("%d:8:0:-:0;;", strings.Index(reverterSource, "revert()")),
+			},
+		},
+		"sourceList": []string{sourcePath},
+	}
+	data, err := json.Marshal(combined)
+	require.NoError(t, err, "failed to marshal combined JSON")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Reverter"+seth.SourceMapFileSuffix), data, 0600), "failed to write source map")
+
+	return dir, sourcePath
+}
+
+func TestUtilSourceMap(t *testing.T) {
+	sm, err := seth.ParseSourceMap("1:2:0:-;:3;5::1:i;;:::o", []string{"A.sol", "B.sol"})
+	require.NoError(t, err, "failed to parse source map")
+	require.Equal(t, []seth.SourceMapEntry{
+		{Start: 1, Length: 2, File: 0, Jump: "-"},
+		{Start: 1, Length: 3, File: 0, Jump: "-"},
+		{Start: 5, Length: 3, File: 1, Jump: "i"},
+		{Start: 5, Length: 3, File: 1, Jump: "i"},
+		{Start: 5, Length: 3, File: 1, Jump: "o"},
+	}, sm.Entries, "incorrect entries")
+
+	_, err = seth.ParseSourceMap("1:x:0", nil)
+	require.Error(t, err, "invalid source map should not be parsed")
+
+	dir, sourcePath := writeReverterArtifacts(t)
+	cs, err := seth.NewContractStore("", dir)
+	require.NoError(t, err, "failed to create contract store")
+	reverterMap, ok := cs.GetSourceMap("Reverter")
+	require.True(t, ok, "source map should be loaded")
+
+	// REVERT is the third instruction at PC 4
+	loc, err := reverterMap.Location(reverterRuntime, 4)
+	require.NoError(t, err, "failed to map PC to source")
+	require.Equal(t, sourcePath+":3", loc.String(), "incorrect location")
+	require.Equal(t, 9, loc.Column, "incorrect column")
+	require.Equal(t, "  1 | contract Reverter {\n  2 |     fallback() external {\n> 3 |         revert();\n  4 |     }\n  5 | }\n", loc.Snippet, "incorrect snippet")
+
+	_, err = reverterMap.Location(reverterRuntime, 1)
+	require.EqualError(t, err, "pc 1: no source mapped to program counter", "PUSH data should not be mapped")
+}
+
+func TestAPIRevertSourceLocation(t *testing.T) {
+	c := newClient(t)
+	dir, sourcePath := writeReverterArtifacts(t)
+	cs, err := seth.NewContractStore("", dir)
+	require.NoError(t, err, "failed to create contract store")
+	sm, _ := cs.GetSourceMap("Reverter")
+	c.ContractStore.AddSourceMap("Reverter", sm)
+
+	data, err := c.DeployContract(c.NewTXOpts(), "Reverter", abi.ABI{}, reverterCreation)
+	require.NoError(t, err, "failed to deploy contract")
+
+	t.Run("direct revert", func(t *testing.T) {
+		decoded, err := c.Decode(data.BoundContract.RawTransact(c.NewTXOpts(seth.WithGasLimit(100_000)), nil))
+		require.Error(t, err, "transaction should revert")
+		require.Contains(t, err.Error(), "reverted at "+sourcePath+":3", "error should contain revert location")
+		require.NotNil(t, decoded.RevertLocation, "revert location should be set")
+		require.Equal(t, "Reverter", decoded.RevertLocation.Contract, "incorrect contract")
+		require.Equal(t, uint64(4), decoded.RevertLocation.PC, "incorrect program counter")
+		require.Contains(t, decoded.RevertLocation.Snippet, "> 3 |         revert();", "snippet should point to revert")
+	})
+
+	t.Run("revert propagated by contract without source map", func(t *testing.T) {
+		// calls Reverter and reverts, if the call failed: PUSH1 0 (x5) PUSH20 reverter GAS CALL PUSH1 0 DUP1 REVERT
+		runtime := append(common.FromHex("0x60006000600060006000"), 0x73)
+		runtime = append(runtime, data.Address.Bytes()...)
+		runtime = append(runtime, common.FromHex("0x5af1600080fd")...)
+		// CODECOPY runtime appended to creation code and return it
+		creation := append([]byte{0x60, byte(len(runtime)), 0x60, 0x0c, 0x60, 0x00, 0x39, 0x60, byte(len(runtime)), 0x60, 0x00, 0xf3}, runtime...)
+
+		caller, err := c.DeployContract(c.NewTXOpts(), "RevertCaller", abi.ABI{}, creation)
+		require.NoError(t, err, "failed to deploy contract")
+
+		decoded, err := c.Decode(caller.BoundContract.RawTransact(c.NewTXOpts(seth.WithGasLimit(200_000)), nil))
+		require.Error(t, err, "transaction should revert")
+		require.NotNil(t, decoded.RevertLocation, "revert location should be set")
+		require.Equal(t, "Reverter", decoded.RevertLocation.Contract, "revert should be mapped to the origin")
+		require.Equal(t, data.Address.Hex(), decoded.RevertLocation.Address, "incorrect address")
+	})
+}
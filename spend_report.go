@@ -0,0 +1,220 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	SpendReportDir = "spend_reports"
+)
+
+var (
+	ErrInvalidSpendBlockRange = errors.New("invalid block range, from block is greater than to block")
+	ErrNoManagedAddresses     = errors.New("client has no addresses, there's nothing to report")
+)
+
+// SpendTransaction is a single transaction sent from one of managed addresses
+type SpendTransaction struct {
+	Hash        string   `json:"hash"`
+	From        string   `json:"from"`
+	To          string   `json:"to,omitempty"`
+	BlockNumber uint64   `json:"block_number"`
+	Status      string   `json:"status"`
+	Value       *big.Int `json:"value"`
+	Fee         *big.Int `json:"fee"`
+	// L1Fee is the part of fee paid for posting data to L1 on OP-stack chains
+	L1Fee *big.Int `json:"l1_fee,omitempty"`
+	// InAuditLog is true if transaction was recorded in one of run manifests
+	InAuditLog bool `json:"in_audit_log"`
+}
+
+// AddressSpend sums fees and value sent from a single address
+type AddressSpend struct {
+	Transactions int      `json:"transactions"`
+	Fees         *big.Int `json:"fees"`
+	Value        *big.Int `json:"value"`
+}
+
+// SpendReport is a summary of all transactions sent from managed addresses in a block range, reconciled with run manifests
+type SpendReport struct {
+	Network      string                   `json:"network"`
+	ChainID      int64                    `json:"chain_id"`
+	FromBlock    uint64                   `json:"from_block"`
+	ToBlock      uint64                   `json:"to_block"`
+	Addresses    map[string]*AddressSpend `json:"addresses"`
+	Transactions []SpendTransaction       `json:"transactions"`
+	TotalFees    *big.Int                 `json:"total_fees"`
+	TotalValue   *big.Int                 `json:"total_value"`
+	// UntrackedTransactions are hashes of transactions, which aren't in any run manifest (e.g. sent by crashed or external runs)
+	UntrackedTransactions []string `json:"untracked_transactions"`
+	UntrackedFees         *big.Int `json:"untracked_fees"`
+	UntrackedValue        *big.Int `json:"untracked_value"`
+	// MissingTransactions are hashes of transactions from run manifests mined in the range, that weren't found on chain (e.g. reorged out)
+	MissingTransactions []string `json:"missing_transactions"`
+	TotalFeesEther      string   `json:"total_fees_ether"`
+	TotalValueEther     string   `json:"total_value_ether"`
+	UntrackedFeesEther  string   `json:"untracked_fees_ether"`
+}
+
+// LoadRunManifests loads all run manifests saved in the directory, it returns no manifests if directory doesn't exist
+func LoadRunManifests(dir string) ([]RunManifestReport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, strings.Split(RunManifestFilePattern, "%s")[0]+"*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	manifests := make([]RunManifestReport, 0, len(files))
+	for _, f := range files {
+		var report RunManifestReport
+		if err := OpenJsonFileAsStruct(f, &report); err != nil {
+			return nil, errors.Wrapf(err, "failed to read run manifest %s", f)
+		}
+		manifests = append(manifests, report)
+	}
+
+	return manifests, nil
+}
+
+// SpendReport scans blocks in the range (inclusive) for all transactions sent from client's addresses, no matter which
+// process sent them, and sums their fees and value. Transactions are reconciled with transactions from run manifests of
+// the same chain, so that spend of crashed runs (which didn't save the manifest) or external ones can be found.
+// Only value sent directly with transactions is included, internal transfers made by contracts are not.
+func (m *Client) SpendReport(ctx context.Context, fromBlock, toBlock uint64, manifests []RunManifestReport) (*SpendReport, error) {
+	if fromBlock > toBlock {
+		return nil, errors.Wrapf(ErrInvalidSpendBlockRange, "from block: %d, to block: %d", fromBlock, toBlock)
+	}
+	if len(m.Addresses) == 0 {
+		return nil, ErrNoManagedAddresses
+	}
+
+	report := &SpendReport{
+		Network:               m.Cfg.Network.Name,
+		ChainID:               m.ChainID,
+		FromBlock:             fromBlock,
+		ToBlock:               toBlock,
+		Addresses:             make(map[string]*AddressSpend),
+		Transactions:          []SpendTransaction{},
+		TotalFees:             big.NewInt(0),
+		TotalValue:            big.NewInt(0),
+		UntrackedTransactions: []string{},
+		UntrackedFees:         big.NewInt(0),
+		UntrackedValue:        big.NewInt(0),
+		MissingTransactions:   []string{},
+	}
+	for _, addr := range m.Addresses {
+		report.Addresses[addr.Hex()] = &AddressSpend{Fees: big.NewInt(0), Value: big.NewInt(0)}
+	}
+
+	auditLog := make(map[string]ManifestTransaction)
+	for _, manifest := range manifests {
+		if manifest.ChainID != m.ChainID {
+			continue
+		}
+		for _, tx := range manifest.Transactions {
+			auditLog[strings.ToLower(tx.Hash)] = tx
+		}
+	}
+
+	signer := types.LatestSignerForChainID(big.NewInt(m.ChainID))
+	found := make(map[string]bool)
+	for bn := fromBlock; bn <= toBlock; bn++ {
+		block, err := m.Client.BlockByNumber(ctx, new(big.Int).SetUint64(bn))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get block %d", bn)
+		}
+		for _, tx := range block.Transactions() {
+			from, err := types.Sender(signer, tx)
+			if err != nil {
+				m.logger().Debug().Err(err).Str("Hash", tx.Hash().Hex()).Msg("Failed to get sender of transaction, skipping it")
+				continue
+			}
+			spend, ok := report.Addresses[from.Hex()]
+			if !ok {
+				continue
+			}
+
+			receipt, err := m.Client.TransactionReceipt(ctx, tx.Hash())
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get receipt of transaction %s", tx.Hash().Hex())
+			}
+
+			stx := newSpendTransaction(from, tx, receipt, m.l1Fee(ctx, tx, receipt))
+			_, stx.InAuditLog = auditLog[strings.ToLower(stx.Hash)]
+			found[strings.ToLower(stx.Hash)] = true
+			report.Transactions = append(report.Transactions, stx)
+
+			spend.Transactions++
+			spend.Fees.Add(spend.Fees, stx.Fee)
+			report.TotalFees.Add(report.TotalFees, stx.Fee)
+			// value is transferred only if transaction was successful
+			if stx.Status == ManifestTxStatusSuccess {
+				spend.Value.Add(spend.Value, stx.Value)
+				report.TotalValue.Add(report.TotalValue, stx.Value)
+			}
+			if !stx.InAuditLog {
+				report.UntrackedTransactions = append(report.UntrackedTransactions, stx.Hash)
+				report.UntrackedFees.Add(report.UntrackedFees, stx.Fee)
+				if stx.Status == ManifestTxStatusSuccess {
+					report.UntrackedValue.Add(report.UntrackedValue, stx.Value)
+				}
+			}
+		}
+	}
+
+	for hash, tx := range auditLog {
+		if tx.Status == ManifestTxStatusNotMined || tx.BlockNumber < fromBlock || tx.BlockNumber > toBlock {
+			continue
+		}
+		if !found[hash] {
+			report.MissingTransactions = append(report.MissingTransactions, tx.Hash)
+		}
+	}
+	sort.Strings(report.MissingTransactions)
+
+	report.TotalFeesEther = WeiToEther(report.TotalFees).Text('f', -1)
+	report.TotalValueEther = WeiToEther(report.TotalValue).Text('f', -1)
+	report.UntrackedFeesEther = WeiToEther(report.UntrackedFees).Text('f', -1)
+
+	return report, nil
+}
+
+// SaveSpendReport saves spend report as JSON and returns path to it
+func SaveSpendReport(report *SpendReport, dirName string) (string, error) {
+	return saveAsJson(report, dirName, fmt.Sprintf("spend_%s_%d_%d_%s", report.Network, report.FromBlock, report.ToBlock, time.Now().Format("2006-01-02-15-04-05")))
+}
+
+func newSpendTransaction(from common.Address, tx *types.Transaction, receipt *types.Receipt, l1Fee *big.Int) SpendTransaction {
+	stx := SpendTransaction{
+		Hash:        tx.Hash().Hex(),
+		From:        from.Hex(),
+		BlockNumber: receipt.BlockNumber.Uint64(),
+		Status:      ManifestTxStatusSuccess,
+		Value:       tx.Value(),
+		Fee:         big.NewInt(0),
+	}
+	if tx.To() != nil {
+		stx.To = tx.To().Hex()
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		stx.Status = ManifestTxStatusReverted
+	}
+	if receipt.EffectiveGasPrice != nil {
+		stx.Fee = new(big.Int).Mul(receipt.EffectiveGasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+	}
+	if l1Fee != nil {
+		stx.L1Fee = l1Fee
+		stx.Fee.Add(stx.Fee, l1Fee)
+	}
+	return stx
+}
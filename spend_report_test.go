@@ -0,0 +1,73 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	sethcmd "github.com/smartcontractkit/seth/cmd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPISpendReport(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.RunManifest = true
+
+	tracked, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	t.Cleanup(func() {
+		_ = os.RemoveAll(seth.RunManifestDir)
+		_ = os.RemoveAll(seth.SpendReportDir)
+	})
+
+	fromBlock, err := tracked.Client.BlockNumber(context.Background())
+	require.NoError(t, err, "failed to get block number")
+	fromBlock++
+
+	trackedTx, err := tracked.Decode(TestEnv.DebugContract.Pay(tracked.NewTXOpts(seth.WithValue(big.NewInt(1000)))))
+	require.NoError(t, err, "failed to send value to contract")
+	_, err = tracked.SaveRunManifest()
+	require.NoError(t, err, "failed to save run manifest")
+
+	// simulates a run, which crashed before saving its manifest
+	untracked := newClient(t)
+	untrackedTx, err := untracked.Decode(TestEnv.DebugContract.Pay(untracked.NewTXOpts(seth.WithValue(big.NewInt(500)))))
+	require.NoError(t, err, "failed to send value to contract")
+
+	toBlock := untrackedTx.Receipt.BlockNumber.Uint64()
+	manifests, err := seth.LoadRunManifests(seth.RunManifestDir)
+	require.NoError(t, err, "failed to load run manifests")
+	require.Len(t, manifests, 1, "run manifest should be loaded")
+	// transaction, which was recorded in the manifest, but isn't on chain anymore
+	manifests[0].Transactions = append(manifests[0].Transactions, seth.ManifestTransaction{
+		Hash:        "0x0000000000000000000000000000000000000000000000000000000000000001",
+		Status:      seth.ManifestTxStatusSuccess,
+		BlockNumber: toBlock,
+	})
+
+	report, err := untracked.SpendReport(context.Background(), fromBlock, toBlock, manifests)
+	require.NoError(t, err, "failed to create spend report")
+	require.Len(t, report.Transactions, 2, "both transactions should be found")
+	require.Equal(t, trackedTx.Hash, report.Transactions[0].Hash, "incorrect first transaction")
+	require.True(t, report.Transactions[0].InAuditLog, "first transaction should be in audit log")
+	require.Equal(t, []string{untrackedTx.Hash}, report.UntrackedTransactions, "second transaction should be untracked")
+	require.Equal(t, big.NewInt(1500), report.TotalValue, "incorrect total value")
+	require.Equal(t, big.NewInt(500), report.UntrackedValue, "incorrect untracked value")
+	require.Equal(t, report.Transactions[1].Fee, report.UntrackedFees, "incorrect untracked fees")
+	require.Equal(t, new(big.Int).Add(report.Transactions[0].Fee, report.Transactions[1].Fee), report.TotalFees, "incorrect total fees")
+	require.Equal(t, 2, report.Addresses[untracked.Addresses[0].Hex()].Transactions, "incorrect number of root key transactions")
+	require.Equal(t, []string{"0x0000000000000000000000000000000000000000000000000000000000000001"}, report.MissingTransactions, "missing transaction should be reported")
+
+	_, err = untracked.SpendReport(context.Background(), toBlock, fromBlock, nil)
+	require.EqualError(t, err, "from block: "+strconv.FormatUint(toBlock, 10)+", to block: "+strconv.FormatUint(fromBlock, 10)+": invalid block range, from block is greater than to block")
+
+	err = sethcmd.RunCLI([]string{"seth", "-n", os.Getenv(seth.NETWORK_ENV_VAR), "report", "spend", "--from-block", strconv.FormatUint(fromBlock, 10), "--to-block", strconv.FormatUint(toBlock, 10)})
+	require.NoError(t, err, "failed to create spend report with CLI")
+	reports, err := os.ReadDir(seth.SpendReportDir)
+	require.NoError(t, err, "failed to read reports dir")
+	require.Len(t, reports, 1, "report should be saved")
+}
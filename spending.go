@@ -0,0 +1,227 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// KeySpending is the amount of value and fees spent by a single key
+type KeySpending struct {
+	Address      string   `json:"address"`
+	Transactions int      `json:"transactions"`
+	Reverted     int      `json:"reverted"`
+	GasUsed      uint64   `json:"gas_used"`
+	Fees         *big.Int `json:"fees"`
+	// L1Fees is the part of fees paid for posting data to L1 on OP-stack chains
+	L1Fees *big.Int `json:"l1_fees"`
+	Value  *big.Int `json:"value"`
+	Total  *big.Int `json:"total"`
+}
+
+// SpendingReport summarises value and fees spent by each key since the client was created together with the burn rate
+type SpendingReport struct {
+	Since    time.Time     `json:"since"`
+	Duration time.Duration `json:"duration"`
+	// Keys are sorted by total amount spent, from the highest
+	Keys         []KeySpending `json:"keys"`
+	Transactions int           `json:"transactions"`
+	Fees         *big.Int      `json:"fees"`
+	L1Fees       *big.Int      `json:"l1_fees"`
+	Value        *big.Int      `json:"value"`
+	Total        *big.Int      `json:"total"`
+	// BurnRatePerHour is the total amount spent (value and fees) extrapolated to one hour
+	BurnRatePerHour *big.Int `json:"burn_rate_per_hour"`
+}
+
+// SpendingTracker tracks value and fees spent by each key for the lifetime of the client. Each mined transaction is counted
+// once, including reverted ones, which still pay for gas (value of reverted transactions isn't counted). It's safe for
+// concurrent use.
+type SpendingTracker struct {
+	mu      *sync.Mutex
+	since   time.Time
+	keys    map[common.Address]*KeySpending
+	counted map[common.Hash]struct{}
+}
+
+// NewSpendingTracker creates a new empty spending tracker
+func NewSpendingTracker() *SpendingTracker {
+	return &SpendingTracker{
+		mu:      &sync.Mutex{},
+		since:   time.Now(),
+		keys:    make(map[common.Address]*KeySpending),
+		counted: make(map[common.Hash]struct{}),
+	}
+}
+
+// AddTransaction counts value and fees of a mined transaction, transactions that were already counted are ignored
+func (s *SpendingTracker) AddTransaction(tx *types.Transaction, receipt *types.Receipt) error {
+	return s.AddTransactionWithL1Fee(tx, receipt, nil)
+}
+
+// AddTransactionWithL1Fee is the same as AddTransaction, but L1 data fee paid on OP-stack chains is added to the fees
+func (s *SpendingTracker) AddTransactionWithL1Fee(tx *types.Transaction, receipt *types.Receipt, l1Fee *big.Int) error {
+	if tx == nil || receipt == nil {
+		return nil
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return err
+	}
+
+	gasPrice := receipt.EffectiveGasPrice
+	if gasPrice == nil {
+		gasPrice = tx.GasPrice()
+	}
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), gasPrice)
+	if l1Fee == nil {
+		l1Fee = big.NewInt(0)
+	}
+	fee.Add(fee, l1Fee)
+	value := big.NewInt(0)
+	if receipt.Status == types.ReceiptStatusSuccessful && tx.Value() != nil {
+		value.Set(tx.Value())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.counted[tx.Hash()]; ok {
+		return nil
+	}
+	s.counted[tx.Hash()] = struct{}{}
+	key, ok := s.keys[from]
+	if !ok {
+		key = &KeySpending{Address: from.Hex(), Fees: big.NewInt(0), L1Fees: big.NewInt(0), Value: big.NewInt(0), Total: big.NewInt(0)}
+		s.keys[from] = key
+	}
+	key.Transactions++
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		key.Reverted++
+	}
+	key.GasUsed += receipt.GasUsed
+	key.Fees.Add(key.Fees, fee)
+	key.L1Fees.Add(key.L1Fees, l1Fee)
+	key.Value.Add(key.Value, value)
+	key.Total.Add(key.Total, fee).Add(key.Total, value)
+	return nil
+}
+
+// Spent returns the total amount (value and fees) spent by the address
+func (s *SpendingTracker) Spent(address common.Address) *big.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[address]; ok {
+		return new(big.Int).Set(key.Total)
+	}
+	return big.NewInt(0)
+}
+
+// TotalSpent returns the total amount (value and fees) spent by all keys
+func (s *SpendingTracker) TotalSpent() *big.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := big.NewInt(0)
+	for _, key := range s.keys {
+		total.Add(total, key.Total)
+	}
+	return total
+}
+
+// Report returns spending of each key and totals since the tracker was created
+func (s *SpendingTracker) Report() *SpendingReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &SpendingReport{
+		Since:           s.since,
+		Duration:        time.Since(s.since),
+		Keys:            make([]KeySpending, 0, len(s.keys)),
+		Fees:            big.NewInt(0),
+		L1Fees:          big.NewInt(0),
+		Value:           big.NewInt(0),
+		Total:           big.NewInt(0),
+		BurnRatePerHour: big.NewInt(0),
+	}
+	for _, key := range s.keys {
+		report.Keys = append(report.Keys, KeySpending{
+			Address:      key.Address,
+			Transactions: key.Transactions,
+			Reverted:     key.Reverted,
+			GasUsed:      key.GasUsed,
+			Fees:         new(big.Int).Set(key.Fees),
+			L1Fees:       new(big.Int).Set(key.L1Fees),
+			Value:        new(big.Int).Set(key.Value),
+			Total:        new(big.Int).Set(key.Total),
+		})
+		report.Transactions += key.Transactions
+		report.Fees.Add(report.Fees, key.Fees)
+		report.L1Fees.Add(report.L1Fees, key.L1Fees)
+		report.Value.Add(report.Value, key.Value)
+		report.Total.Add(report.Total, key.Total)
+	}
+	sort.Slice(report.Keys, func(i, j int) bool {
+		if c := report.Keys[i].Total.Cmp(report.Keys[j].Total); c != 0 {
+			return c > 0
+		}
+		return report.Keys[i].Address < report.Keys[j].Address
+	})
+	if report.Duration > 0 {
+		report.BurnRatePerHour.Mul(report.Total, big.NewInt(int64(time.Hour)))
+		report.BurnRatePerHour.Div(report.BurnRatePerHour, big.NewInt(int64(report.Duration)))
+	}
+	return report
+}
+
+// SpendingReport returns value and fees spent by each key since the client was created together with the burn rate
+func (m *Client) SpendingReport() *SpendingReport {
+	if m.Spending == nil {
+		return NewSpendingTracker().Report()
+	}
+	return m.Spending.Report()
+}
+
+func (m *Client) recordSpending(tx *types.Transaction, receipt *types.Receipt) {
+	// spent amount replaces the reservation
+	defer m.releaseBudget(tx)
+	if m.Spending == nil {
+		return
+	}
+	if err := m.Spending.AddTransactionWithL1Fee(tx, receipt, m.l1Fee(context.Background(), tx, receipt)); err != nil {
+		m.logger().Warn().
+			Err(err).
+			Str("Transaction", tx.Hash().Hex()).
+			Msg("Failed to add transaction to spending report")
+	}
+}
+
+// logSpendingReport logs spending of each key and totals, it's called when client is closed
+func (m *Client) logSpendingReport() {
+	report := m.SpendingReport()
+	if report.Transactions == 0 {
+		return
+	}
+	for _, key := range report.Keys {
+		m.logger().Info().
+			Str("Address", key.Address).
+			Int("Transactions", key.Transactions).
+			Int("Reverted", key.Reverted).
+			Uint64("GasUsed", key.GasUsed).
+			Str("Fees", WeiToEther(key.Fees).Text('f', -1)).
+			Str("Value", WeiToEther(key.Value).Text('f', -1)).
+			Str("Total", WeiToEther(key.Total).Text('f', -1)).
+			Msg("Key spending")
+	}
+	m.logger().Info().
+		Dur("Duration", report.Duration).
+		Int("Transactions", report.Transactions).
+		Str("Fees", WeiToEther(report.Fees).Text('f', -1)).
+		Str("Value", WeiToEther(report.Value).Text('f', -1)).
+		Str("Total", WeiToEther(report.Total).Text('f', -1)).
+		Str("BurnRatePerHour", WeiToEther(report.BurnRatePerHour).Text('f', -1)).
+		Msg("Run spending report")
+}
@@ -0,0 +1,47 @@
+package seth_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPISpendingReport(t *testing.T) {
+	c := newClient(t)
+	require.NotNil(t, c.Spending, "spending tracker should be initialised")
+
+	before := c.SpendingReport()
+	require.Equal(t, 0, before.Transactions, "no transactions should be counted yet")
+
+	var fees, value big.Int
+	for i := 0; i < 2; i++ {
+		decoded, err := c.Decode(TestEnv.DebugContract.Pay(c.NewTXOpts(seth.WithValue(big.NewInt(1000)))))
+		require.NoError(t, err, "failed to send value to contract")
+		fees.Add(&fees, new(big.Int).Mul(big.NewInt(int64(decoded.Receipt.GasUsed)), decoded.Receipt.EffectiveGasPrice))
+		value.Add(&value, big.NewInt(1000))
+
+		// already counted transaction isn't counted again
+		_, err = c.WaitMined(c.Context, seth.L, c.Client, decoded.Transaction)
+		require.NoError(t, err, "failed to wait for transaction")
+	}
+
+	// reverted transactions pay only fees
+	decoded, _ := c.Decode(TestEnv.DebugContract.AlwaysRevertsRequire(c.NewTXOpts(seth.WithGasLimit(1_000_000))))
+	require.NotNil(t, decoded, "reverted transaction should be decoded")
+	require.Equal(t, types.ReceiptStatusFailed, decoded.Receipt.Status, "transaction should revert")
+	fees.Add(&fees, new(big.Int).Mul(big.NewInt(int64(decoded.Receipt.GasUsed)), decoded.Receipt.EffectiveGasPrice))
+
+	report := c.SpendingReport()
+	require.Equal(t, 3, report.Transactions, "incorrect number of transactions")
+	require.Len(t, report.Keys, 1, "only root key should spend funds")
+	require.Equal(t, c.Addresses[0].Hex(), report.Keys[0].Address, "incorrect key")
+	require.Equal(t, 1, report.Keys[0].Reverted, "incorrect number of reverted transactions")
+	require.Equal(t, fees.String(), report.Fees.String(), "incorrect fees")
+	require.Equal(t, value.String(), report.Value.String(), "incorrect value")
+	require.Equal(t, new(big.Int).Add(&fees, &value).String(), report.Total.String(), "incorrect total")
+	require.Equal(t, report.Total.String(), c.Spending.Spent(c.Addresses[0]).String(), "incorrect amount spent by root key")
+	require.Equal(t, 1, report.BurnRatePerHour.Cmp(report.Total), "burn rate per hour should be extrapolated from run duration")
+}
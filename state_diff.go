@@ -0,0 +1,159 @@
+package seth
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// prestateDiffOutput is the result of prestateTracer in diff mode. Pre contains only accounts and storage slots modified by
+// the transaction, post contains only fields that changed. Accounts missing in post were deleted, accounts missing in pre
+// were created and storage slots missing in post were cleared.
+type prestateDiffOutput struct {
+	Pre  map[string]*PrestateAccount `json:"pre"`
+	Post map[string]*PrestateAccount `json:"post"`
+}
+
+// AccountStateDiff is a change of account's state made by the transaction. Balance and nonce fields are set only if they changed.
+type AccountStateDiff struct {
+	Address       string        `json:"address"`
+	Name          string        `json:"name"`
+	Created       bool          `json:"created,omitempty"`
+	Deleted       bool          `json:"deleted,omitempty"`
+	BalanceBefore *big.Int      `json:"balance_before,omitempty"`
+	BalanceAfter  *big.Int      `json:"balance_after,omitempty"`
+	NonceBefore   uint64        `json:"nonce_before,omitempty"`
+	NonceAfter    uint64        `json:"nonce_after,omitempty"`
+	CodeChanged   bool          `json:"code_changed,omitempty"`
+	Storage       []StorageDiff `json:"storage,omitempty"`
+}
+
+// StorageDiff is a change of a single storage slot
+type StorageDiff struct {
+	Slot   string `json:"slot"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// StateDiff returns changes of accounts' state sorted by address, it returns nil, if the transaction wasn't traced with
+// 'state_diff' enabled
+func (t *Trace) StateDiff() ([]AccountStateDiff, error) {
+	if t.PoststateTrace == nil {
+		return nil, nil
+	}
+
+	addresses := make([]string, 0, len(t.PrestateTrace)+len(t.PoststateTrace))
+	for address := range t.PrestateTrace {
+		addresses = append(addresses, address)
+	}
+	for address := range t.PoststateTrace {
+		if _, ok := t.PrestateTrace[address]; !ok {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+
+	diffs := make([]AccountStateDiff, 0, len(addresses))
+	for _, address := range addresses {
+		diff, err := accountStateDiff(address, t.PrestateTrace[address], t.PoststateTrace[address])
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+func accountStateDiff(address string, pre, post *PrestateAccount) (AccountStateDiff, error) {
+	diff := AccountStateDiff{Address: address, Created: pre == nil, Deleted: post == nil}
+	if pre == nil {
+		pre = &PrestateAccount{}
+	}
+	if post == nil {
+		post = &PrestateAccount{}
+	}
+
+	if post.Balance != "" || diff.Deleted {
+		before, err := decodePrestateBalance(pre.Balance)
+		if err != nil {
+			return diff, errors.Wrapf(err, "failed to parse balance of %s before the transaction", address)
+		}
+		after, err := decodePrestateBalance(post.Balance)
+		if err != nil {
+			return diff, errors.Wrapf(err, "failed to parse balance of %s after the transaction", address)
+		}
+		if before.Cmp(after) != 0 {
+			diff.BalanceBefore, diff.BalanceAfter = before, after
+		}
+	}
+	if post.Nonce != 0 && post.Nonce != pre.Nonce {
+		diff.NonceBefore, diff.NonceAfter = pre.Nonce, post.Nonce
+	}
+	diff.CodeChanged = post.Code != "" && post.Code != pre.Code
+
+	slots := make([]string, 0, len(pre.Storage)+len(post.Storage))
+	for slot := range pre.Storage {
+		slots = append(slots, slot)
+	}
+	for slot := range post.Storage {
+		if _, ok := pre.Storage[slot]; !ok {
+			slots = append(slots, slot)
+		}
+	}
+	sort.Strings(slots)
+	for _, slot := range slots {
+		before, after := storageValue(pre.Storage, slot), storageValue(post.Storage, slot)
+		if before != after {
+			diff.Storage = append(diff.Storage, StorageDiff{Slot: slot, Before: before, After: after})
+		}
+	}
+	return diff, nil
+}
+
+func decodePrestateBalance(balance string) (*big.Int, error) {
+	if balance == "" {
+		return big.NewInt(0), nil
+	}
+	return hexutil.DecodeBig(balance)
+}
+
+// storageValue returns the value of the slot, slots missing in the storage are zero
+func storageValue(storage map[string]string, slot string) string {
+	return common.HexToHash(storage[slot]).Hex()
+}
+
+func (t *Tracer) printStateDiff(l zerolog.Logger, diffs []AccountStateDiff) {
+	for _, d := range diffs {
+		e := l.Debug().
+			Str("Account", d.Name).
+			Str("Address", d.Address)
+		switch {
+		case d.Created:
+			e = e.Bool("Created", true)
+		case d.Deleted:
+			e = e.Bool("Deleted", true)
+		}
+		if d.BalanceBefore != nil {
+			e = e.Str("Balance (wei/ether)", fmt.Sprintf("%s -> %s", FormatWei(d.BalanceBefore), FormatWei(d.BalanceAfter)))
+		}
+		if d.NonceAfter != 0 {
+			e = e.Str("Nonce", fmt.Sprintf("%d -> %d", d.NonceBefore, d.NonceAfter))
+		}
+		if d.CodeChanged {
+			e = e.Bool("Code changed", true)
+		}
+		if len(d.Storage) > 0 {
+			storage := make(map[string]string, len(d.Storage))
+			for _, s := range d.Storage {
+				storage[s.Slot] = fmt.Sprintf("%s -> %s", s.Before, s.After)
+			}
+			e = e.Interface("Storage", storage)
+		}
+		e.Msg("State diff")
+	}
+}
@@ -0,0 +1,626 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultSubscriptionReconnectDelay    = 1 * time.Second
+	DefaultSubscriptionMaxReconnectDelay = 30 * time.Second
+	// maxSubscriptionBackfill is the maximum number of blocks, whose headers or logs are fetched after reconnection
+	maxSubscriptionBackfill = 1000
+)
+
+var (
+	ErrSubscriptionsRequireWebsocket = errors.New("subscriptions require websocket RPC URL (ws:// or wss://)")
+	ErrSubscriptionReconnectFailed   = errors.New("failed to resubscribe")
+	ErrSubscriptionsClosed           = errors.New("subscription manager is closed")
+)
+
+// SubscriptionsCfg configures reconnection of subscriptions
+type SubscriptionsCfg struct {
+	// Disabled prevents Seth from using subscriptions instead of polling, when connected over websocket
+	Disabled bool `toml:"disabled"`
+	// ReconnectDelay is the initial delay between reconnection attempts, doubled after each failed one, default 1s
+	ReconnectDelay *Duration `toml:"reconnect_delay"`
+	// MaxReconnectDelay caps the delay between reconnection attempts, default 30s
+	MaxReconnectDelay *Duration `toml:"max_reconnect_delay"`
+	// MaxReconnectAttempts after which subscription fails with an error, 0 means retrying forever
+	MaxReconnectAttempts int `toml:"max_reconnect_attempts"`
+}
+
+// IsWebsocketURL returns true if URL uses ws:// or wss:// scheme
+func IsWebsocketURL(url string) bool {
+	url = strings.ToLower(url)
+	return strings.HasPrefix(url, "ws://") || strings.HasPrefix(url, "wss://")
+}
+
+// SubscriptionManager manages subscriptions over its own websocket connection. When connection drops it reconnects
+// with exponential backoff and resubscribes all active subscriptions, backfilling headers and logs from blocks
+// that were missed while it was disconnected.
+type SubscriptionManager struct {
+	URL    string
+	urls   []string
+	cfg    SubscriptionsCfg
+	ctx    context.Context
+	mu     *sync.Mutex
+	client *rpc.Client
+	// dialOptions are used to connect to websocket URLs, e.g. to set headers of the handshake
+	dialOptions []rpc.ClientOption
+	// current is the index of URL of current connection
+	current int
+	closed  bool
+	heads   *headFeed
+}
+
+// NewSubscriptionManager creates a new subscription manager, connection is established on first subscription.
+// All subscriptions end, when context is done.
+func NewSubscriptionManager(ctx context.Context, url string, cfg SubscriptionsCfg) (*SubscriptionManager, error) {
+	return NewSubscriptionManagerWithFailover(ctx, []string{url}, cfg)
+}
+
+// NewSubscriptionManagerWithFailover creates a new subscription manager, which connects to the first URL, that it can
+// connect to, trying them in order starting with URL of the last connection. URL field is set to the first URL.
+func NewSubscriptionManagerWithFailover(ctx context.Context, urls []string, cfg SubscriptionsCfg) (*SubscriptionManager, error) {
+	if len(urls) == 0 {
+		return nil, errors.Wrapf(ErrSubscriptionsRequireWebsocket, "URL: '%s'", "")
+	}
+	for _, url := range urls {
+		if !IsWebsocketURL(url) {
+			return nil, errors.Wrapf(ErrSubscriptionsRequireWebsocket, "URL: '%s'", url)
+		}
+	}
+	if cfg.ReconnectDelay == nil {
+		cfg.ReconnectDelay = &Duration{D: DefaultSubscriptionReconnectDelay}
+	}
+	if cfg.MaxReconnectDelay == nil {
+		cfg.MaxReconnectDelay = &Duration{D: DefaultSubscriptionMaxReconnectDelay}
+	}
+	return &SubscriptionManager{
+		URL:  urls[0],
+		urls: urls,
+		cfg:  cfg,
+		ctx:  ctx,
+		mu:   &sync.Mutex{},
+	}, nil
+}
+
+// Close closes the connection, active subscriptions fail with an error
+func (s *SubscriptionManager) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	if s.client != nil {
+		s.client.Close()
+		s.client = nil
+	}
+}
+
+// connection returns current connection, dialing a new one if there's none
+func (s *SubscriptionManager) connection(ctx context.Context) (*rpc.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrSubscriptionsClosed
+	}
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	var lastErr error
+	for i := 0; i < len(s.urls); i++ {
+		idx := (s.current + i) % len(s.urls)
+		c, err := rpc.DialOptions(ctx, s.urls[idx], s.dialOptions...)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if idx != s.current {
+			L.Warn().
+				Int("Endpoint", idx).
+				Err(lastErr).
+				Msg("Websocket RPC endpoint failed, switched subscriptions to the next one")
+		}
+		s.current = idx
+		s.client = c
+		return s.client, nil
+	}
+	return nil, lastErr
+}
+
+// dropConnection closes the connection, if it's still the current one, so that next subscription attempt redials
+func (s *SubscriptionManager) dropConnection(c *rpc.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c != nil && s.client == c {
+		s.client.Close()
+		s.client = nil
+	}
+}
+
+// ManagedSubscription is a subscription, that survives dropped connections. Err() returns an error only when it
+// couldn't be resubscribed within configured number of attempts or manager was closed.
+type ManagedSubscription struct {
+	name       string
+	err        chan error
+	unsub      chan struct{}
+	once       *sync.Once
+	mu         *sync.Mutex
+	reconnects int
+	connected  bool
+}
+
+// Unsubscribe stops the subscription
+func (ms *ManagedSubscription) Unsubscribe() {
+	ms.once.Do(func() { close(ms.unsub) })
+}
+
+// Err returns a channel, that receives an error if subscription fails permanently
+func (ms *ManagedSubscription) Err() <-chan error {
+	return ms.err
+}
+
+// Reconnects returns how many times the subscription was resubscribed after connection was lost
+func (ms *ManagedSubscription) Reconnects() int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.reconnects
+}
+
+// Connected returns true if the subscription is currently active
+func (ms *ManagedSubscription) Connected() bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.connected
+}
+
+func (ms *ManagedSubscription) setConnected(connected bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.connected = connected
+}
+
+// subscribeFn creates the subscription over given connection, resubscribed is true after reconnection
+type subscribeFn func(ctx context.Context, c *rpc.Client, resubscribed bool) (ethereum.Subscription, error)
+
+// subscribe creates the subscription and keeps resubscribing it until it's unsubscribed or context is done. First attempt
+// is synchronous, so that errors like unsupported subscription type are returned immediately.
+func (s *SubscriptionManager) subscribe(ctx context.Context, name string, fn subscribeFn) (*ManagedSubscription, error) {
+	ms := &ManagedSubscription{
+		name:  name,
+		err:   make(chan error, 1),
+		unsub: make(chan struct{}),
+		once:  &sync.Once{},
+		mu:    &sync.Mutex{},
+	}
+
+	c, err := s.connection(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to websocket RPC endpoint")
+	}
+	sub, err := fn(ctx, c, false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to subscribe to %s", name)
+	}
+	ms.setConnected(true)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				sub.Unsubscribe()
+				ms.setConnected(false)
+				return
+			case <-s.ctx.Done():
+				sub.Unsubscribe()
+				ms.setConnected(false)
+				return
+			case <-ms.unsub:
+				sub.Unsubscribe()
+				ms.setConnected(false)
+				return
+			case subErr := <-sub.Err():
+				ms.setConnected(false)
+				L.Warn().Err(subErr).Str("Subscription", name).Msg("Subscription dropped, resubscribing")
+				s.dropConnection(c)
+			}
+
+			c, sub, err = s.resubscribe(ctx, ms, fn)
+			if err != nil {
+				ms.err <- err
+				return
+			}
+			if sub == nil {
+				return
+			}
+			ms.mu.Lock()
+			ms.reconnects++
+			ms.connected = true
+			ms.mu.Unlock()
+			L.Info().Str("Subscription", name).Msg("Resubscribed")
+		}
+	}()
+
+	return ms, nil
+}
+
+// resubscribe retries subscribing with exponential backoff, returns nil subscription, if it was stopped in the meantime
+func (s *SubscriptionManager) resubscribe(ctx context.Context, ms *ManagedSubscription, fn subscribeFn) (*rpc.Client, ethereum.Subscription, error) {
+	delay := s.cfg.ReconnectDelay.Duration()
+	for attempt := 1; ; attempt++ {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, nil
+		case <-s.ctx.Done():
+			timer.Stop()
+			return nil, nil, nil
+		case <-ms.unsub:
+			timer.Stop()
+			return nil, nil, nil
+		case <-timer.C:
+		}
+
+		c, err := s.connection(ctx)
+		if err == nil {
+			var sub ethereum.Subscription
+			if sub, err = fn(ctx, c, true); err == nil {
+				return c, sub, nil
+			}
+			s.dropConnection(c)
+		}
+		if s.isClosed() {
+			return nil, nil, ErrSubscriptionsClosed
+		}
+		L.Debug().Err(err).Int("Attempt", attempt).Str("Subscription", ms.name).Msg("Failed to resubscribe")
+		if s.cfg.MaxReconnectAttempts > 0 && attempt >= s.cfg.MaxReconnectAttempts {
+			return nil, nil, errors.Wrapf(wrapError(err, ErrSubscriptionReconnectFailed), "subscription: %s, attempts: %d", ms.name, attempt)
+		}
+
+		delay *= 2
+		if delay > s.cfg.MaxReconnectDelay.Duration() {
+			delay = s.cfg.MaxReconnectDelay.Duration()
+		}
+	}
+}
+
+func validateSubscriptionsCfg(cfg *SubscriptionsCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.ReconnectDelay != nil && cfg.ReconnectDelay.Duration() <= 0 {
+		return errors.New("subscriptions 'reconnect_delay' must be greater than 0")
+	}
+	if cfg.MaxReconnectDelay != nil && cfg.MaxReconnectDelay.Duration() <= 0 {
+		return errors.New("subscriptions 'max_reconnect_delay' must be greater than 0")
+	}
+	if cfg.MaxReconnectAttempts < 0 {
+		return errors.New("subscriptions 'max_reconnect_attempts' must be greater than or equal to 0")
+	}
+	return nil
+}
+
+func (s *SubscriptionManager) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// SubscribeNewHeads subscribes to new block headers. Headers of blocks that were missed while connection was down (or
+// that node skipped) are fetched and delivered in order, so block numbers are consecutive unless there was a reorg.
+func (s *SubscriptionManager) SubscribeNewHeads(ctx context.Context, ch chan<- *types.Header) (*ManagedSubscription, error) {
+	internal := make(chan *types.Header, 16)
+	ms, err := s.subscribe(ctx, "newHeads", func(ctx context.Context, c *rpc.Client, _ bool) (ethereum.Subscription, error) {
+		return ethclient.NewClient(c).SubscribeNewHead(ctx, internal)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		var last *big.Int
+		for {
+			var head *types.Header
+			select {
+			case <-ctx.Done():
+				return
+			case <-ms.unsub:
+				return
+			case head = <-internal:
+			}
+
+			if last != nil && head.Number.Cmp(new(big.Int).Add(last, big.NewInt(1))) > 0 {
+				for _, missed := range s.missedHeaders(ctx, last, head.Number) {
+					if !forward(ctx, ms, ch, missed) {
+						return
+					}
+				}
+			}
+			if !forward(ctx, ms, ch, head) {
+				return
+			}
+			last = head.Number
+		}
+	}()
+
+	return ms, nil
+}
+
+// missedHeaders fetches headers of blocks between last and current (exclusive), at most maxSubscriptionBackfill latest
+func (s *SubscriptionManager) missedHeaders(ctx context.Context, last, current *big.Int) []*types.Header {
+	from := new(big.Int).Add(last, big.NewInt(1))
+	if new(big.Int).Sub(current, from).Cmp(big.NewInt(maxSubscriptionBackfill)) > 0 {
+		from = new(big.Int).Sub(current, big.NewInt(maxSubscriptionBackfill))
+	}
+	c, err := s.connection(ctx)
+	if err != nil {
+		return nil
+	}
+
+	var headers []*types.Header
+	for n := from; n.Cmp(current) < 0; n = new(big.Int).Add(n, big.NewInt(1)) {
+		h, err := ethclient.NewClient(c).HeaderByNumber(ctx, n)
+		if err != nil {
+			L.Warn().Err(err).Str("Block", n.String()).Msg("Failed to backfill missed block header")
+			continue
+		}
+		headers = append(headers, h)
+	}
+	return headers
+}
+
+// SubscribeLogs subscribes to logs matching the filter query. After reconnection logs from blocks that were missed are
+// fetched with eth_getLogs, logs that were already delivered are not delivered again.
+func (s *SubscriptionManager) SubscribeLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (*ManagedSubscription, error) {
+	internal := make(chan types.Log, 64)
+	mu := &sync.Mutex{}
+	var lastBlock uint64
+	seen := make(map[string]uint64)
+
+	var ms *ManagedSubscription
+	ms, err := s.subscribe(ctx, "logs", func(ctx context.Context, c *rpc.Client, resubscribed bool) (ethereum.Subscription, error) {
+		ec := ethclient.NewClient(c)
+		if !resubscribed {
+			latest, err := ec.BlockNumber(ctx)
+			if err != nil {
+				return nil, err
+			}
+			mu.Lock()
+			lastBlock = latest
+			mu.Unlock()
+		}
+		sub, err := ec.SubscribeFilterLogs(ctx, q, internal)
+		if err != nil || !resubscribed {
+			return sub, err
+		}
+
+		// subscription is already active, so no logs will be missed between backfill and new logs
+		mu.Lock()
+		from := lastBlock
+		mu.Unlock()
+		latest, err := ec.BlockNumber(ctx)
+		if err != nil {
+			sub.Unsubscribe()
+			return nil, err
+		}
+		if latest > from+maxSubscriptionBackfill {
+			from = latest - maxSubscriptionBackfill
+		}
+		backfillQuery := q
+		backfillQuery.FromBlock = new(big.Int).SetUint64(from)
+		backfillQuery.ToBlock = new(big.Int).SetUint64(latest)
+		logs, err := ec.FilterLogs(ctx, backfillQuery)
+		if err != nil {
+			sub.Unsubscribe()
+			return nil, err
+		}
+		go func() {
+			for _, lo := range logs {
+				select {
+				case internal <- lo:
+				case <-ms.unsub:
+					return
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return sub, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			var lo types.Log
+			select {
+			case <-ctx.Done():
+				return
+			case <-ms.unsub:
+				return
+			case lo = <-internal:
+			}
+
+			key := fmt.Sprintf("%s:%t", logKey(lo), lo.Removed)
+			mu.Lock()
+			_, duplicate := seen[key]
+			if !duplicate {
+				seen[key] = lo.BlockNumber
+				if lo.BlockNumber > lastBlock {
+					lastBlock = lo.BlockNumber
+					// keep only logs from blocks, that can be backfilled again
+					for k, bn := range seen {
+						if bn+maxSubscriptionBackfill < lastBlock {
+							delete(seen, k)
+						}
+					}
+				}
+			}
+			mu.Unlock()
+			if duplicate {
+				continue
+			}
+			if !forward(ctx, ms, ch, lo) {
+				return
+			}
+		}
+	}()
+
+	return ms, nil
+}
+
+// SubscribePendingTransactions subscribes to hashes of transactions entering node's transaction pool. Transactions
+// received while connection was down are not backfilled.
+func (s *SubscriptionManager) SubscribePendingTransactions(ctx context.Context, ch chan<- common.Hash) (*ManagedSubscription, error) {
+	internal := make(chan common.Hash, 64)
+	ms, err := s.subscribe(ctx, "newPendingTransactions", func(ctx context.Context, c *rpc.Client, _ bool) (ethereum.Subscription, error) {
+		return c.EthSubscribe(ctx, internal, "newPendingTransactions")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ms.unsub:
+				return
+			case hash := <-internal:
+				if !forward(ctx, ms, ch, hash) {
+					return
+				}
+			}
+		}
+	}()
+
+	return ms, nil
+}
+
+// forward sends value to the channel, returns false if subscription was stopped in the meantime
+func forward[T any](ctx context.Context, ms *ManagedSubscription, ch chan<- T, v T) bool {
+	select {
+	case ch <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-ms.unsub:
+		return false
+	}
+}
+
+// headFeed is a single new heads subscription shared by all internal consumers (e.g. waiting for receipts)
+type headFeed struct {
+	mu     *sync.Mutex
+	sub    *ManagedSubscription
+	next   chan struct{}
+	latest *types.Header
+	onHead []func(*types.Header)
+}
+
+// startHeadFeed starts shared new heads subscription, if it's not running yet. If it can't be started consumers
+// fall back to polling.
+func (s *SubscriptionManager) startHeadFeed() *headFeed {
+	s.mu.Lock()
+	if s.heads != nil {
+		s.mu.Unlock()
+		return s.heads
+	}
+	feed := &headFeed{mu: &sync.Mutex{}, next: make(chan struct{})}
+	s.heads = feed
+	s.mu.Unlock()
+
+	ch := make(chan *types.Header, 16)
+	sub, err := s.SubscribeNewHeads(s.ctx, ch)
+	if err != nil {
+		L.Debug().Err(err).Msg("Failed to subscribe to new heads, falling back to polling")
+		return feed
+	}
+
+	feed.mu.Lock()
+	feed.sub = sub
+	feed.mu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-sub.Err():
+				return
+			case head := <-ch:
+				feed.mu.Lock()
+				feed.latest = head
+				close(feed.next)
+				feed.next = make(chan struct{})
+				hooks := feed.onHead
+				feed.mu.Unlock()
+				for _, fn := range hooks {
+					fn(head)
+				}
+			}
+		}
+	}()
+
+	return feed
+}
+
+// NextHead returns a channel, that is closed when next block header is received, or nil channel (blocking forever) if
+// new heads subscription isn't active, so it can always be used in select next to polling timer
+func (s *SubscriptionManager) NextHead() <-chan struct{} {
+	feed := s.startHeadFeed()
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	if feed.sub == nil || !feed.sub.Connected() {
+		return nil
+	}
+	return feed.next
+}
+
+// LatestHead returns the latest block header received by the shared new heads subscription or nil if it's not active
+func (s *SubscriptionManager) LatestHead() *types.Header {
+	feed := s.startHeadFeed()
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	if feed.sub == nil || !feed.sub.Connected() {
+		return nil
+	}
+	return feed.latest
+}
+
+// OnHead registers a hook called with every block header received by the shared new heads subscription
+func (s *SubscriptionManager) OnHead(fn func(*types.Header)) {
+	feed := s.startHeadFeed()
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	feed.onHead = append(feed.onHead, fn)
+}
+
+// latestSubscribedHead returns the latest header received over websocket subscription or nil if client isn't subscribed
+func (m *Client) latestSubscribedHead() *types.Header {
+	if m.Subscriptions == nil {
+		return nil
+	}
+	return m.Subscriptions.LatestHead()
+}
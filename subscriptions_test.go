@@ -0,0 +1,203 @@
+package seth_test
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+// dropProxy is a TCP proxy, that can drop all connections and refuse new ones to simulate network failures
+type dropProxy struct {
+	ln     net.Listener
+	target string
+	mu     *sync.Mutex
+	conns  []net.Conn
+	refuse bool
+}
+
+func newDropProxy(t *testing.T, target string) *dropProxy {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "failed to start proxy")
+	p := &dropProxy{ln: ln, target: target, mu: &sync.Mutex{}}
+	t.Cleanup(func() {
+		_ = ln.Close()
+		p.drop()
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			p.mu.Lock()
+			refuse := p.refuse
+			p.mu.Unlock()
+			if refuse {
+				_ = conn.Close()
+				continue
+			}
+			upstream, err := net.Dial("tcp", target)
+			if err != nil {
+				_ = conn.Close()
+				continue
+			}
+			p.mu.Lock()
+			p.conns = append(p.conns, conn, upstream)
+			p.mu.Unlock()
+			go func() { _, _ = io.Copy(upstream, conn); _ = upstream.Close() }()
+			go func() { _, _ = io.Copy(conn, upstream); _ = conn.Close() }()
+		}
+	}()
+
+	return p
+}
+
+func (p *dropProxy) URL() string {
+	return "ws://" + p.ln.Addr().String()
+}
+
+func (p *dropProxy) setRefuse(refuse bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refuse = refuse
+}
+
+func (p *dropProxy) drop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.conns {
+		_ = c.Close()
+	}
+	p.conns = nil
+}
+
+func TestAPISubscriptionsReconnect(t *testing.T) {
+	c := newClient(t)
+	if !seth.IsWebsocketURL(c.URL) {
+		t.Skip("network isn't connected over websocket")
+	}
+
+	proxy := newDropProxy(t, strings.TrimPrefix(strings.TrimPrefix(c.URL, "ws://"), "wss://"))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	subs, err := seth.NewSubscriptionManager(ctx, proxy.URL(), seth.SubscriptionsCfg{ReconnectDelay: &seth.Duration{D: 100 * time.Millisecond}})
+	require.NoError(t, err, "failed to create subscription manager")
+	defer subs.Close()
+
+	heads := make(chan *types.Header, 100)
+	headsSub, err := subs.SubscribeNewHeads(ctx, heads)
+	require.NoError(t, err, "failed to subscribe to new heads")
+	logs := make(chan types.Log, 100)
+	logsSub, err := subs.SubscribeLogs(ctx, ethereum.FilterQuery{Addresses: []common.Address{TestEnv.DebugContractAddress}}, logs)
+	require.NoError(t, err, "failed to subscribe to logs")
+
+	var first *types.Header
+	select {
+	case first = <-heads:
+	case <-ctx.Done():
+		t.Fatal("no new head received")
+	}
+
+	// event is emitted while subscription is disconnected and node refuses new connections
+	proxy.setRefuse(true)
+	proxy.drop()
+	tx, err := c.Decode(TestEnv.DebugContract.EmitNoIndexEvent(c.NewTXOpts()))
+	require.NoError(t, err, "failed to emit event")
+	time.Sleep(2 * time.Second)
+	require.False(t, headsSub.Connected(), "subscription should be disconnected")
+	proxy.setRefuse(false)
+
+	select {
+	case lo := <-logs:
+		require.Equal(t, tx.Hash, lo.TxHash.Hex(), "missed log should be backfilled")
+	case <-ctx.Done():
+		t.Fatal("missed log wasn't backfilled")
+	}
+
+	prev := first
+	for prev.Number.Uint64() <= tx.Receipt.BlockNumber.Uint64()+1 {
+		select {
+		case head := <-heads:
+			require.Equal(t, prev.Number.Uint64()+1, head.Number.Uint64(), "missed headers should be backfilled")
+			prev = head
+		case <-ctx.Done():
+			t.Fatal("no new head received after reconnection")
+		}
+	}
+	require.GreaterOrEqual(t, headsSub.Reconnects(), 1, "new heads should be resubscribed")
+	require.GreaterOrEqual(t, logsSub.Reconnects(), 1, "logs should be resubscribed")
+	require.True(t, headsSub.Connected(), "subscription should be connected")
+	require.Empty(t, logs, "log should be delivered only once")
+}
+
+func TestAPISubscriptionsReconnectAttempts(t *testing.T) {
+	c := newClient(t)
+	if !seth.IsWebsocketURL(c.URL) {
+		t.Skip("network isn't connected over websocket")
+	}
+
+	proxy := newDropProxy(t, strings.TrimPrefix(strings.TrimPrefix(c.URL, "ws://"), "wss://"))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	subs, err := seth.NewSubscriptionManager(ctx, proxy.URL(), seth.SubscriptionsCfg{
+		ReconnectDelay:       &seth.Duration{D: 50 * time.Millisecond},
+		MaxReconnectAttempts: 2,
+	})
+	require.NoError(t, err, "failed to create subscription manager")
+	defer subs.Close()
+
+	hashes := make(chan common.Hash, 100)
+	sub, err := subs.SubscribePendingTransactions(ctx, hashes)
+	require.NoError(t, err, "failed to subscribe to pending transactions")
+
+	proxy.setRefuse(true)
+	proxy.drop()
+	select {
+	case err := <-sub.Err():
+		require.ErrorIs(t, err, seth.ErrSubscriptionReconnectFailed, "incorrect error")
+		require.Contains(t, err.Error(), "subscription: newPendingTransactions, attempts: 2", "error should contain subscription and attempts")
+	case <-ctx.Done():
+		t.Fatal("subscription should fail after max reconnect attempts")
+	}
+}
+
+func TestAPIWaitMinedWithNewHeads(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	// without subscription receipt would be checked only after 10s
+	cfg.Network.ReceiptPollingInterval = &seth.Duration{D: 10 * time.Second}
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to create client")
+	if c.Subscriptions == nil {
+		t.Skip("network isn't connected over websocket")
+	}
+
+	start := time.Now()
+	_, err = c.Decode(TestEnv.DebugContract.AddCounter(c.NewTXOpts(), big.NewInt(0), big.NewInt(1)))
+	require.NoError(t, err, "transaction should be mined")
+	require.Less(t, time.Since(start), 8*time.Second, "receipt should be fetched as soon as new block arrives")
+}
+
+func TestConfigSubscriptionsValidation(t *testing.T) {
+	_, err := seth.NewSubscriptionManager(context.Background(), "http://localhost:8545", seth.SubscriptionsCfg{})
+	require.EqualError(t, err, "URL: 'http://localhost:8545': subscriptions require websocket RPC URL (ws:// or wss://)")
+
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.Subscriptions = &seth.SubscriptionsCfg{MaxReconnectAttempts: -1}
+	require.EqualError(t, seth.ValidateConfig(cfg), "subscriptions 'max_reconnect_attempts' must be greater than or equal to 0")
+}
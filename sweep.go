@@ -0,0 +1,166 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	// sweepAttempts is the number of times sweep is sent, when it's rejected because base fee or balance changed
+	sweepAttempts = 3
+	// sweepBaseFeeHeadroomPercent is by how much base fee can grow, before sweep transaction can't be mined anymore
+	sweepBaseFeeHeadroomPercent = 25
+)
+
+var (
+	ErrSweepKey = errors.New("failed to sweep key")
+)
+
+// sweepRejections are errors of nodes rejecting sweep transaction, because base fee or balance changed since its cost was calculated
+var sweepRejections = []string{"less than block base fee", "insufficient funds"}
+
+// SweepKey sends the whole balance of the key to given address, so that the key ends with zero balance. The transfer is
+// sent with a fixed price per gas, so that its cost is known exactly and can be subtracted from the balance. On networks
+// with dynamic fees both fee cap and tip cap are set to the latest base fee increased by 25% plus suggested tip. If node
+// rejects the transfer, because base fee grew above that or balance changed in the meantime, the cost is calculated again
+// and the transfer is resent (3 attempts at most). If it's rejected with 'nonce too low', nonce is resynced from the node. Only if the recipient is a contract, that uses less gas than estimated,
+// unused gas is refunded to the key. On OP-stack chains L1 data fee increased by 25% is subtracted as well, so its
+// surplus stays on the key. Returns swept amount or ErrNothingToSweep, if balance doesn't cover the transfer fee.
+func (m *Client) SweepKey(ctx context.Context, keyNum int, toAddr common.Address) (*big.Int, error) {
+	if keyNum >= len(m.Addresses) {
+		return nil, errors.Wrap(ErrNoKeyLoaded, fmt.Sprintf("requested key: %d", keyNum))
+	}
+	if err := m.waitForGasSpikeBreaker(ctx); err != nil {
+		return nil, err
+	}
+	from := m.Addresses[keyNum]
+
+	var nonce *uint64
+	for attempt := 1; ; attempt++ {
+		amount, gasLimit, fees, err := m.sweepTransfer(ctx, from, toAddr)
+		if err != nil {
+			return nil, err
+		}
+		// nonce is taken only once, so that rejected attempts don't leave gaps
+		if nonce == nil {
+			n := m.NonceManager.NextNonce(from).Uint64()
+			nonce = &n
+		}
+		signedTx, err := m.Signer.SignTx(ctx, from, m.newTransferTx(*nonce, toAddr, amount, gasLimit, fees))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to sign tx")
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, m.Cfg.Network.TxnTimeout.Duration())
+		sendErr := m.Client.SendTransaction(sendCtx, signedTx)
+		cancel()
+		if sendErr == nil {
+			l := m.logger().With().Str("Transaction", signedTx.Hash().Hex()).Logger()
+			l.Info().
+				Int("KeyNum", keyNum).
+				Str("To", toAddr.Hex()).
+				Str("Amount", FormatWei(amount)).
+				Msg("Sweeping key")
+			if _, err := m.WaitMined(ctx, l, m.Client, signedTx); err != nil {
+				return nil, errors.Wrapf(wrapError(err, ErrSweepKey), "key: %d", keyNum)
+			}
+			return amount, nil
+		}
+		nonceTooLow := strings.Contains(strings.ToLower(sendErr.Error()), rpcNonceTooLow)
+		if (!nonceTooLow && !isSweepRejection(sendErr)) || attempt >= sweepAttempts {
+			m.releaseBudget(signedTx)
+			return nil, errors.Wrapf(wrapError(sendErr, ErrSweepKey), "key: %d", keyNum)
+		}
+		m.logger().Warn().
+			Err(sendErr).
+			Int("KeyNum", keyNum).
+			Int("Attempt", attempt).
+			Msg("Sweep was rejected, calculating its cost again")
+		if nonceTooLow {
+			// local counter is behind, e.g. when the key was used via synced keys pool
+			m.releaseBudget(signedTx)
+			n, err := m.resyncNonce(ctx, from)
+			if err != nil {
+				return nil, errors.Wrapf(wrapError(err, ErrSweepKey), "key: %d", keyNum)
+			}
+			nonce = &n
+		}
+		// balance could have been served from the read cache
+		m.InvalidateReadCache()
+	}
+}
+
+// sweepTransfer returns amount, gas limit and fees of the transfer of the whole balance
+func (m *Client) sweepTransfer(ctx context.Context, from, to common.Address) (*big.Int, uint64, TransferFees, error) {
+	balance, err := m.Client.BalanceAt(ctx, from, nil)
+	if err != nil {
+		return nil, 0, TransferFees{}, errors.Wrap(err, "failed to get balance")
+	}
+
+	gasLimit := uint64(m.Cfg.Network.TransferGasFee)
+	if estimated, err := m.EstimateGasLimitForFundTransfer(from, to, balance); err == nil {
+		gasLimit = estimated
+	}
+
+	gasPrice, err := m.sweepGasPrice(ctx)
+	if err != nil {
+		return nil, 0, TransferFees{}, err
+	}
+	fee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gasLimit))
+	// L1 data fee is charged from the balance on top of gas, it can grow with L1 base fee before the sweep is mined
+	l1Fee, err := m.estimateL1Fee(ctx, &to, balance, nil, gasLimit)
+	if err != nil {
+		return nil, 0, TransferFees{}, err
+	}
+	l1Fee.Mul(l1Fee, big.NewInt(100+sweepBaseFeeHeadroomPercent))
+	fee.Add(fee, l1Fee.Div(l1Fee, big.NewInt(100)))
+	amount := new(big.Int).Sub(balance, fee)
+	if amount.Sign() <= 0 {
+		return nil, 0, TransferFees{}, errors.Wrapf(ErrNothingToSweep, "address: %s, balance: %s, transfer fee: %s", from.Hex(), FormatWei(balance), FormatWei(fee))
+	}
+
+	fees := TransferFees{GasPrice: gasPrice}
+	if m.Cfg.Network.EIP1559DynamicFees {
+		fees = TransferFees{GasFeeCap: gasPrice, GasTipCap: gasPrice}
+	}
+	return amount, gasLimit, fees, nil
+}
+
+// sweepGasPrice returns the price per gas sweep pays, on networks with dynamic fees it's latest base fee with headroom plus
+// suggested tip, otherwise suggested gas price
+func (m *Client) sweepGasPrice(ctx context.Context) (*big.Int, error) {
+	if !m.Cfg.Network.EIP1559DynamicFees {
+		return m.SuggestedTransferFees(ctx).GasPrice, nil
+	}
+	header, err := m.Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get latest header")
+	}
+	if header.BaseFee == nil {
+		return nil, errors.New("latest block has no base fee, disable 'eip_1559_dynamic_fees' for this network")
+	}
+	tipCap, err := m.Client.SuggestGasTipCap(ctx)
+	if err != nil {
+		m.logger().Warn().Err(err).Msg("Failed to get suggested tip, using tip from config")
+		tipCap = big.NewInt(m.Cfg.Network.GasTipCap)
+	}
+	gasPrice := new(big.Int).Mul(header.BaseFee, big.NewInt(100+sweepBaseFeeHeadroomPercent))
+	gasPrice.Div(gasPrice, big.NewInt(100))
+	return gasPrice.Add(gasPrice, tipCap), nil
+}
+
+// isSweepRejection returns true if node rejected sweep, because its cost was calculated with outdated base fee or balance
+func isSweepRejection(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, r := range sweepRejections {
+		if strings.Contains(msg, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPISweepKey(t *testing.T) {
+	_ = os.Unsetenv(seth.KEYFILE_PATH_ENV_VAR)
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	var two int64 = 2
+	cfg.EphemeralAddrs = &two
+	limitEphemeralFunds(t, cfg, seth.EtherToWei(big.NewFloat(1)))
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	t.Cleanup(func() {
+		_ = seth.ReturnFunds(c, c.Addresses[0].Hex())
+	})
+
+	balance, err := c.Client.BalanceAt(context.Background(), c.Addresses[1], nil)
+	require.NoError(t, err, "failed to get balance")
+
+	swept, err := c.SweepKey(context.Background(), 1, c.Addresses[0])
+	require.NoError(t, err, "failed to sweep key")
+	require.Equal(t, 1, swept.Sign(), "swept amount should be positive")
+	require.Equal(t, -1, swept.Cmp(balance), "swept amount should be lower than balance by the fee")
+
+	balance, err = c.Client.BalanceAt(context.Background(), c.Addresses[1], nil)
+	require.NoError(t, err, "failed to get balance")
+	require.Zero(t, balance.Sign(), "key should have zero balance after sweep")
+
+	_, err = c.SweepKey(context.Background(), 1, c.Addresses[0])
+	require.ErrorIs(t, err, seth.ErrNothingToSweep, "empty key shouldn't be swept")
+}
+
+func TestAPISweepKeyResyncsNonce(t *testing.T) {
+	_ = os.Unsetenv(seth.KEYFILE_PATH_ENV_VAR)
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	var one int64 = 1
+	cfg.EphemeralAddrs = &one
+	limitEphemeralFunds(t, cfg, seth.EtherToWei(big.NewFloat(1)))
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	t.Cleanup(func() {
+		_ = seth.ReturnFunds(c, c.Addresses[0].Hex())
+	})
+
+	err = c.TransferETHFromKey(context.Background(), 1, c.Addresses[0].Hex(), big.NewInt(1), nil)
+	require.NoError(t, err, "failed to transfer ETH")
+	// key was used without local counter, e.g. via synced keys pool
+	c.NonceManager.Lock()
+	c.NonceManager.Nonces[c.Addresses[1]] = 0
+	c.NonceManager.Unlock()
+
+	_, err = c.SweepKey(context.Background(), 1, c.Addresses[0])
+	require.NoError(t, err, "failed to sweep key")
+
+	balance, err := c.Client.BalanceAt(context.Background(), c.Addresses[1], nil)
+	require.NoError(t, err, "failed to get balance")
+	require.Zero(t, balance.Sign(), "key should have zero balance after sweep")
+}
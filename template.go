@@ -0,0 +1,179 @@
+package seth
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNoTemplate            = errors.New("transaction template not found in config")
+	ErrTemplateNoAddress     = errors.New("transaction template has no 'to' address and contract was not found in the contract map")
+	ErrDuplicateTemplateName = errors.New("transaction template is defined more than once")
+)
+
+// TransactionTemplate is a named, reusable contract call defined in TOML config. Arguments are passed as strings and converted
+// to method's ABI types (see ParseMethodArgs), numbers without unit in value are in wei. Zero gas values mean that defaults from network config are used.
+type TransactionTemplate struct {
+	Name      string   `toml:"name"`
+	Contract  string   `toml:"contract"`
+	To        string   `toml:"to"`
+	Method    string   `toml:"method"`
+	Args      []string `toml:"args"`
+	Value     Amount   `toml:"value"`
+	KeyNum    int      `toml:"key_num"`
+	GasLimit  uint64   `toml:"gas_limit"`
+	GasPrice  Amount   `toml:"gas_price"`
+	GasFeeCap Amount   `toml:"gas_fee_cap"`
+	GasTipCap Amount   `toml:"gas_tip_cap"`
+}
+
+// TemplateOverride overrides a field of transaction template, before it's instantiated
+type TemplateOverride func(t *TransactionTemplate)
+
+// WithTemplateArgs overrides template's arguments
+func WithTemplateArgs(args ...string) TemplateOverride {
+	return func(t *TransactionTemplate) {
+		t.Args = args
+	}
+}
+
+// WithTemplateTo overrides template's target address
+func WithTemplateTo(to string) TemplateOverride {
+	return func(t *TransactionTemplate) {
+		t.To = to
+	}
+}
+
+// WithTemplateValue overrides template's value (in wei)
+func WithTemplateValue(value *big.Int) TemplateOverride {
+	return func(t *TransactionTemplate) {
+		t.Value = NewAmount(value)
+	}
+}
+
+// WithTemplateKeyNum overrides key used to send the transaction
+func WithTemplateKeyNum(keyNum int) TemplateOverride {
+	return func(t *TransactionTemplate) {
+		t.KeyNum = keyNum
+	}
+}
+
+// WithTemplateGasLimit overrides template's gas limit
+func WithTemplateGasLimit(gasLimit uint64) TemplateOverride {
+	return func(t *TransactionTemplate) {
+		t.GasLimit = gasLimit
+	}
+}
+
+// GetTemplate returns a copy of transaction template with given name
+func (c *Config) GetTemplate(name string) (TransactionTemplate, bool) {
+	for _, t := range c.TransactionTemplates {
+		if t.Name == name {
+			tmpl := *t
+			tmpl.Args = append([]string{}, t.Args...)
+			return tmpl, true
+		}
+	}
+	return TransactionTemplate{}, false
+}
+
+// FromTemplate sends a transaction defined by named template from the config, applying overrides first. If template has no
+// 'to' address set, contract address is read from the contract map. Use it together with Decode(), e.g.:
+// client.Decode(client.FromTemplate("mint", seth.WithTemplateArgs("0x...", "100")))
+func (m *Client) FromTemplate(name string, overrides ...TemplateOverride) (*types.Transaction, error) {
+	tmpl, ok := m.Cfg.GetTemplate(name)
+	if !ok {
+		return nil, errors.Wrapf(ErrNoTemplate, "template: '%s'", name)
+	}
+	for _, o := range overrides {
+		o(&tmpl)
+	}
+
+	return m.sendTransactionTemplate(tmpl)
+}
+
+// sendTransactionTemplate sends transaction described by the template, without waiting for it to be mined
+func (m *Client) sendTransactionTemplate(tmpl TransactionTemplate) (*types.Transaction, error) {
+	name := tmpl.Name
+	if m.ContractStore == nil {
+		return nil, errors.New("ABIStore is nil")
+	}
+	contractAbi, ok := m.ContractStore.GetABI(tmpl.Contract)
+	if !ok {
+		return nil, errors.Wrapf(ErrNoABIForContract, "contract: %s", tmpl.Contract)
+	}
+	method, err := findMethodBySignature(contractAbi, tmpl.Contract, tmpl.Method)
+	if err != nil {
+		return nil, err
+	}
+	args, err := ParseMethodArgs(method, tmpl.Args)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse arguments of transaction template '%s'", name)
+	}
+
+	to := tmpl.To
+	if to == "" {
+		to = m.ContractAddressToNameMap.GetContractAddress(tmpl.Contract)
+		if to == UNKNOWN {
+			return nil, errors.Wrapf(ErrTemplateNoAddress, "template: '%s', contract: %s", name, tmpl.Contract)
+		}
+	}
+	if !common.IsHexAddress(to) {
+		return nil, fmt.Errorf("transaction template '%s' has invalid 'to' address: %s", name, to)
+	}
+
+	txOpts := []TransactOpt{}
+	if !tmpl.Value.IsZero() {
+		txOpts = append(txOpts, WithValue(tmpl.Value.Wei()))
+	}
+	if tmpl.GasLimit != 0 {
+		txOpts = append(txOpts, WithGasLimit(tmpl.GasLimit))
+	}
+	if !tmpl.GasPrice.IsZero() {
+		txOpts = append(txOpts, WithGasPrice(tmpl.GasPrice.Wei()))
+	}
+	if !tmpl.GasFeeCap.IsZero() {
+		txOpts = append(txOpts, WithGasFeeCap(tmpl.GasFeeCap.Wei()))
+	}
+	if !tmpl.GasTipCap.IsZero() {
+		txOpts = append(txOpts, WithGasTipCap(tmpl.GasTipCap.Wei()))
+	}
+
+	opts := m.NewTXKeyOpts(tmpl.KeyNum, txOpts...)
+	if err := m.checkTransactOpts(opts); err != nil {
+		return nil, errors.Wrapf(err, "aborted sending transaction from template '%s'", name)
+	}
+
+	m.logger().Debug().
+		Str("Template", name).
+		Str("To", to).
+		Str("Method", method.Sig).
+		Strs("Args", tmpl.Args).
+		Msg("Sending transaction from template")
+
+	contract := bind.NewBoundContract(common.HexToAddress(to), *contractAbi, m.Client, m.Client, m.Client)
+	return contract.Transact(opts, method.Name, args...)
+}
+
+func validateTransactionTemplates(templates []*TransactionTemplate) error {
+	names := make(map[string]struct{})
+	for _, t := range templates {
+		if t.Name == "" {
+			return errors.New("transaction template name cannot be empty")
+		}
+		if _, ok := names[t.Name]; ok {
+			return errors.Wrapf(ErrDuplicateTemplateName, "template: '%s'", t.Name)
+		}
+		names[t.Name] = struct{}{}
+
+		if t.Contract == "" || t.Method == "" {
+			return fmt.Errorf("transaction template '%s' must have both contract and method set", t.Name)
+		}
+	}
+	return nil
+}
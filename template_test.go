@@ -0,0 +1,74 @@
+package seth_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIFromTemplate(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.TransactionTemplates = []*seth.TransactionTemplate{
+		{
+			Name:     "add_counter",
+			Contract: "NetworkDebugContract",
+			To:       TestEnv.DebugContractAddress.Hex(),
+			Method:   "addCounter(int256,int256)",
+			Args:     []string{"77", "2"},
+			GasLimit: 1_000_000,
+		},
+		{
+			Name:     "pay",
+			Contract: "NetworkDebugContract",
+			Method:   "pay",
+			Value:    seth.NewAmount(big.NewInt(1000)),
+		},
+	}
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+
+	counterBefore, err := TestEnv.DebugContract.GetCounter(c.NewCallOpts(), big.NewInt(77))
+	require.NoError(t, err, "failed to get counter")
+
+	decoded, err := c.Decode(c.FromTemplate("add_counter"))
+	require.NoError(t, err, "failed to send transaction from template")
+	require.Equal(t, uint64(1_000_000), decoded.Transaction.Gas(), "gas limit from template was not used")
+
+	_, err = c.Decode(c.FromTemplate("add_counter", seth.WithTemplateArgs("77", "3")))
+	require.NoError(t, err, "failed to send transaction from template with overridden args")
+
+	counterAfter, err := TestEnv.DebugContract.GetCounter(c.NewCallOpts(), big.NewInt(77))
+	require.NoError(t, err, "failed to get counter")
+	require.Equal(t, new(big.Int).Add(counterBefore, big.NewInt(5)), counterAfter, "counter should be increased by default and overridden args")
+
+	// address is taken from contract map, if it's not set in the template
+	c.ContractAddressToNameMap.AddContract(TestEnv.DebugContractAddress.Hex(), "NetworkDebugContract")
+	decoded, err = c.Decode(c.FromTemplate("pay", seth.WithTemplateValue(big.NewInt(2000))))
+	require.NoError(t, err, "failed to send transaction from template with overridden value")
+	require.Equal(t, big.NewInt(2000), decoded.Transaction.Value(), "value should be overridden")
+
+	_, err = c.FromTemplate("no_such_template")
+	require.Error(t, err, "should fail for unknown template")
+	require.ErrorIs(t, err, seth.ErrNoTemplate, "incorrect error")
+}
+
+func TestConfigTransactionTemplatesValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	cfg.TransactionTemplates = []*seth.TransactionTemplate{
+		{Name: "a", Contract: "NetworkDebugContract", Method: "set"},
+		{Name: "a", Contract: "NetworkDebugContract", Method: "get"},
+	}
+	err = seth.ValidateConfig(cfg)
+	require.Error(t, err, "should fail for duplicated template names")
+	require.ErrorIs(t, err, seth.ErrDuplicateTemplateName, "incorrect error")
+
+	err = toml.Unmarshal([]byte("[[transaction_templates]]\nname = \"a\"\nvalue = \"1 finney\""), cfg)
+	require.Error(t, err, "should fail for invalid value")
+}
@@ -0,0 +1,211 @@
+package seth
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	ErrTopUpNotConfigured  = errors.New("top up isn't configured, set [top_up] in the config")
+	ErrTopUpThresholds     = errors.New("'target_balance' of top up must be greater than 'min_balance'")
+	ErrTopUpKeyAddress     = errors.New("'address' of top up key must be a valid address")
+	ErrTopUpInterval       = errors.New("'interval' of top up must be positive")
+	ErrInsufficientTopUp   = errors.New("root key has insufficient balance to top up keys")
+	ErrTopUpTransferFailed = errors.New("failed to top up key")
+)
+
+// TopUpCfg configures top up of keys (all keys except the root key), whose balance dropped below the minimum, from the root
+// key to the target balance. Thresholds can be overridden for single keys. If interval is set, keys are checked periodically
+// in the background for the lifetime of the client.
+type TopUpCfg struct {
+	MinBalance    *Amount        `toml:"min_balance"`
+	TargetBalance *Amount        `toml:"target_balance"`
+	Interval      *Duration      `toml:"interval"`
+	Keys          []*KeyTopUpCfg `toml:"keys"`
+	thresholds    topUpThresholds
+	keys          map[common.Address]topUpThresholds
+}
+
+// KeyTopUpCfg overrides top up thresholds of a single key, empty thresholds are taken from TopUpCfg
+type KeyTopUpCfg struct {
+	Address       string  `toml:"address"`
+	MinBalance    *Amount `toml:"min_balance"`
+	TargetBalance *Amount `toml:"target_balance"`
+}
+
+type topUpThresholds struct {
+	min    *big.Int
+	target *big.Int
+}
+
+func validateTopUpCfg(cfg *TopUpCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	thresholds, err := topUpThresholdsOf(cfg.MinBalance, cfg.TargetBalance, topUpThresholds{})
+	if err != nil {
+		return err
+	}
+	cfg.thresholds = thresholds
+	cfg.keys = make(map[common.Address]topUpThresholds, len(cfg.Keys))
+	for i, k := range cfg.Keys {
+		if k == nil || !common.IsHexAddress(k.Address) {
+			return errors.Wrapf(ErrTopUpKeyAddress, "top up key: %d", i)
+		}
+		thresholds, err := topUpThresholdsOf(k.MinBalance, k.TargetBalance, cfg.thresholds)
+		if err != nil {
+			return errors.Wrapf(err, "key %s", k.Address)
+		}
+		cfg.keys[common.HexToAddress(k.Address)] = thresholds
+	}
+	if cfg.Interval != nil && cfg.Interval.Duration() <= 0 {
+		return ErrTopUpInterval
+	}
+	return nil
+}
+
+// topUpThresholdsOf returns thresholds of given amounts, unset ones are taken from defaults
+func topUpThresholdsOf(minBalance, targetBalance *Amount, defaults topUpThresholds) (topUpThresholds, error) {
+	thresholds := defaults
+	if minBalance != nil {
+		thresholds.min = minBalance.Wei()
+	}
+	if targetBalance != nil {
+		thresholds.target = targetBalance.Wei()
+	}
+	if thresholds.min == nil || thresholds.target == nil || thresholds.target.Cmp(thresholds.min) <= 0 {
+		return thresholds, ErrTopUpThresholds
+	}
+	return thresholds, nil
+}
+
+func (c *TopUpCfg) thresholdsOf(address common.Address) topUpThresholds {
+	if t, ok := c.keys[address]; ok {
+		return t
+	}
+	return c.thresholds
+}
+
+// TopUp is a single transfer from the root key to the key, whose balance dropped below the minimum
+type TopUp struct {
+	KeyNum  int
+	Address common.Address
+	// Balance is the balance of the key before the top up
+	Balance *big.Int
+	Amount  *big.Int
+}
+
+// EnsureFunded tops up all keys except the root key, whose balance is below the minimum of [top_up] config, from the root
+// key to the target balance. Root key's balance is checked before any funds are sent. Concurrent calls (also from
+// the background top up) are serialized, so that keys aren't topped up twice. Returns executed top ups.
+func (m *Client) EnsureFunded(ctx context.Context) ([]TopUp, error) {
+	if m.Cfg.TopUp == nil {
+		return nil, ErrTopUpNotConfigured
+	}
+	m.topUpMu.Lock()
+	defer m.topUpMu.Unlock()
+
+	topUps := make([]TopUp, 0)
+	topUpsMu := &sync.Mutex{}
+	eg, egCtx := errgroup.WithContext(ctx)
+	for keyNum := 1; keyNum < len(m.Addresses); keyNum++ {
+		keyNum := keyNum
+		eg.Go(func() error {
+			balance, err := m.Client.BalanceAt(egCtx, m.Addresses[keyNum], nil)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get balance of key %d", keyNum)
+			}
+			thresholds := m.Cfg.TopUp.thresholdsOf(m.Addresses[keyNum])
+			if balance.Cmp(thresholds.min) >= 0 {
+				return nil
+			}
+			topUpsMu.Lock()
+			topUps = append(topUps, TopUp{
+				KeyNum:  keyNum,
+				Address: m.Addresses[keyNum],
+				Balance: balance,
+				Amount:  new(big.Int).Sub(thresholds.target, balance),
+			})
+			topUpsMu.Unlock()
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	if len(topUps) == 0 {
+		return topUps, nil
+	}
+	sort.Slice(topUps, func(i, j int) bool {
+		return topUps[i].KeyNum < topUps[j].KeyNum
+	})
+
+	fees := m.SuggestedTransferFees(ctx)
+	needed := new(big.Int).Mul(fees.MaxGasPrice(), big.NewInt(m.Cfg.Network.TransferGasFee*int64(len(topUps))))
+	for _, t := range topUps {
+		needed.Add(needed, t.Amount)
+	}
+	rootBalance, err := m.Client.BalanceAt(ctx, m.Addresses[0], nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get balance of root key")
+	}
+	if rootBalance.Cmp(needed) < 0 {
+		return nil, errors.Wrapf(ErrInsufficientTopUp, "balance: %s, needed: %s, keys: %d", FormatWei(rootBalance), FormatWei(needed), len(topUps))
+	}
+
+	eg, egCtx = errgroup.WithContext(ctx)
+	for _, t := range topUps {
+		t := t
+		eg.Go(func() error {
+			m.logger().Info().
+				Int("KeyNum", t.KeyNum).
+				Str("Address", t.Address.Hex()).
+				Str("Balance", FormatWei(t.Balance)).
+				Str("Amount", FormatWei(t.Amount)).
+				Msg("Topping up key")
+			if err := m.TransferETHFromKeyWithFees(egCtx, 0, t.Address.Hex(), t.Amount, fees); err != nil {
+				return errors.Wrapf(wrapError(err, ErrTopUpTransferFailed), "key: %d", t.KeyNum)
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return topUps, nil
+}
+
+// startTopUp periodically tops up keys in the background until the context is cancelled or client is closed. Failed top
+// ups are logged and added to collected errors.
+func (m *Client) startTopUp(ctx context.Context, interval time.Duration) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	m.stopTopUp = func() {
+		cancel()
+		<-done
+	}
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+			if _, err := m.EnsureFunded(ctx); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				m.logger().Warn().Err(err).Msg("Failed to top up keys")
+				m.addError(GlobalErrorScope, err)
+			}
+		}
+	}()
+}
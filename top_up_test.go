@@ -0,0 +1,93 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func newClientWithTopUp(t *testing.T, topUp *seth.TopUpCfg) *seth.Client {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	var two int64 = 2
+	cfg.EphemeralAddrs = &two
+	// each key gets about 4 ether, above the minimum balance of the tests
+	limitEphemeralFunds(t, cfg, seth.EtherToWei(big.NewFloat(8)))
+	cfg.TopUp = topUp
+	cfg.ReturnFundsOnClose = true
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+	t.Cleanup(func() {
+		_ = c.Close()
+	})
+	return c
+}
+
+// amountOf returns pointer to parsed amount
+func amountOf(amount string) *seth.Amount {
+	a := seth.MustParseAmount(amount)
+	return &a
+}
+
+// drainKey sends funds of the key back to the root key, so that it's left with about one ether
+func drainKey(t *testing.T, c *seth.Client, keyNum int) {
+	balance, err := c.Client.BalanceAt(context.Background(), c.Addresses[keyNum], nil)
+	require.NoError(t, err, "failed to get balance")
+	amount := new(big.Int).Sub(balance, big.NewInt(1_000_000_000_000_000_000))
+	require.NoError(t, c.TransferETHFromKey(context.Background(), keyNum, c.Addresses[0].Hex(), amount, big.NewInt(c.Cfg.Network.GasPrice)), "failed to drain key")
+}
+
+func TestAPIEnsureFunded(t *testing.T) {
+	t.Run("on demand", func(t *testing.T) {
+		c := newClientWithTopUp(t, &seth.TopUpCfg{MinBalance: amountOf("2 ether"), TargetBalance: amountOf("5 ether")})
+
+		topUps, err := c.EnsureFunded(context.Background())
+		require.NoError(t, err, "failed to ensure keys are funded")
+		require.Empty(t, topUps, "funded keys shouldn't be topped up")
+
+		drainKey(t, c, 1)
+		topUps, err = c.EnsureFunded(context.Background())
+		require.NoError(t, err, "failed to ensure keys are funded")
+		require.Len(t, topUps, 1, "only drained key should be topped up")
+		require.Equal(t, 1, topUps[0].KeyNum, "incorrect key was topped up")
+
+		balance, err := c.Client.BalanceAt(context.Background(), c.Addresses[1], nil)
+		require.NoError(t, err, "failed to get balance")
+		require.Equal(t, "5000000000000000000", balance.String(), "key should be topped up to target balance")
+
+		c.Cfg.TopUp.Keys = []*seth.KeyTopUpCfg{{Address: c.Addresses[1].Hex(), MinBalance: amountOf("6 ether"), TargetBalance: amountOf("1000000 ether")}}
+		require.NoError(t, seth.ValidateConfig(c.Cfg), "config should be valid")
+		_, err = c.EnsureFunded(context.Background())
+		require.ErrorIs(t, err, seth.ErrInsufficientTopUp, "root key shouldn't be able to top up the key")
+		require.ErrorContains(t, err, "keys: 1", "error should contain number of keys")
+	})
+
+	t.Run("in the background", func(t *testing.T) {
+		c := newClientWithTopUp(t, &seth.TopUpCfg{MinBalance: amountOf("2 ether"), TargetBalance: amountOf("5 ether"), Interval: &seth.Duration{D: 200 * time.Millisecond}})
+
+		drainKey(t, c, 2)
+		require.Eventually(t, func() bool {
+			balance, err := c.Client.BalanceAt(context.Background(), c.Addresses[2], nil)
+			return err == nil && balance.String() == "5000000000000000000"
+		}, 10*time.Second, 200*time.Millisecond, "key should be topped up in the background")
+		require.False(t, c.HasErrors(), "background top up shouldn't fail")
+	})
+}
+
+func TestConfigTopUpValidation(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+
+	cfg.TopUp = &seth.TopUpCfg{MinBalance: amountOf("2 ether"), TargetBalance: amountOf("1 ether")}
+	require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrTopUpThresholds, "target should be greater than minimum")
+
+	cfg.TopUp = &seth.TopUpCfg{MinBalance: amountOf("1 ether"), TargetBalance: amountOf("2 ether"), Keys: []*seth.KeyTopUpCfg{{Address: "not an address"}}}
+	require.EqualError(t, seth.ValidateConfig(cfg), "top up key: 0: 'address' of top up key must be a valid address", "key address should be validated")
+
+	cfg.TopUp = &seth.TopUpCfg{MinBalance: amountOf("1 ether"), TargetBalance: amountOf("2 ether"), Interval: &seth.Duration{}}
+	require.ErrorIs(t, seth.ValidateConfig(cfg), seth.ErrTopUpInterval, "interval should be validated")
+}
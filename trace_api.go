@@ -0,0 +1,138 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// TracerBackend_Debug traces transactions with geth's debug API (debug_traceTransaction), it's the default one
+	TracerBackend_Debug = "debug"
+	// TracerBackend_TraceAPI traces transactions with Parity-style trace API (trace_transaction) supported by e.g. Erigon,
+	// Nethermind and Besu. It provides only call traces without logs.
+	TracerBackend_TraceAPI = "trace"
+)
+
+var (
+	ErrInvalidTraceAPIOutput = errors.New("invalid trace_transaction output")
+)
+
+// parityTrace is a single frame of trace_transaction output, frames are ordered depth-first and traceAddress is the path
+// of indexes of sub-calls leading to the frame
+type parityTrace struct {
+	Action struct {
+		CallType      string `json:"callType"`
+		From          string `json:"from"`
+		To            string `json:"to"`
+		Gas           string `json:"gas"`
+		Input         string `json:"input"`
+		Init          string `json:"init"`
+		Value         string `json:"value"`
+		Address       string `json:"address"`
+		RefundAddress string `json:"refundAddress"`
+		Balance       string `json:"balance"`
+	} `json:"action"`
+	Result *struct {
+		GasUsed string `json:"gasUsed"`
+		Output  string `json:"output"`
+		Address string `json:"address"`
+	} `json:"result"`
+	Error        string `json:"error"`
+	TraceAddress []int  `json:"traceAddress"`
+	Type         string `json:"type"`
+}
+
+// Backend returns the API used to trace transactions
+func (t *Tracer) Backend() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.backend == "" {
+		return TracerBackend_Debug
+	}
+	return t.backend
+}
+
+// SetBackend sets the API used to trace transactions, either TracerBackend_Debug or TracerBackend_TraceAPI
+func (t *Tracer) SetBackend(backend string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.backend = backend
+}
+
+// traceWithTraceAPI returns call trace of the transaction built from trace_transaction output
+func (t *Tracer) traceWithTraceAPI(ctx context.Context, txHash string) (*TXCallTraceOutput, error) {
+	var traces []parityTrace
+	if err := t.rpcClient.CallContext(ctx, &traces, "trace_transaction", txHash); err != nil {
+		return nil, err
+	}
+	return parityTracesToCallTrace(traces)
+}
+
+// parityTracesToCallTrace nests flat trace_transaction frames into a call tree in the format of geth's callTracer
+func parityTracesToCallTrace(traces []parityTrace) (*TXCallTraceOutput, error) {
+	if len(traces) == 0 {
+		return nil, ErrNoTrace
+	}
+	if len(traces[0].TraceAddress) != 0 {
+		return nil, wrapError(errors.New("first frame isn't the top-level call"), ErrInvalidTraceAPIOutput)
+	}
+
+	root := traces[0].asCall()
+	for _, trace := range traces[1:] {
+		if len(trace.TraceAddress) == 0 {
+			return nil, wrapError(errors.New("more than one top-level call"), ErrInvalidTraceAPIOutput)
+		}
+		parent := &root
+		for _, idx := range trace.TraceAddress[:len(trace.TraceAddress)-1] {
+			if idx >= len(parent.Calls) {
+				return nil, wrapError(fmt.Errorf("parent of frame %v is missing", trace.TraceAddress), ErrInvalidTraceAPIOutput)
+			}
+			parent = &parent.Calls[idx]
+		}
+		parent.Calls = append(parent.Calls, trace.asCall())
+	}
+
+	out := &TXCallTraceOutput{Call: root, Calls: root.Calls}
+	out.Call.Calls = nil
+	return out, nil
+}
+
+func (p parityTrace) asCall() Call {
+	call := Call{
+		From:  p.Action.From,
+		To:    p.Action.To,
+		Gas:   p.Action.Gas,
+		Input: p.Action.Input,
+		Value: p.Action.Value,
+		Error: p.Error,
+	}
+	if p.Result != nil {
+		call.GasUsed = p.Result.GasUsed
+		call.Output = p.Result.Output
+	}
+	// geth reports reverts as 'execution reverted', while Parity-style nodes report them as 'Reverted'
+	if p.Error == "Reverted" {
+		call.Error = "execution reverted"
+	}
+
+	switch p.Type {
+	case "create":
+		call.Type = "CREATE"
+		call.Input = p.Action.Init
+		if p.Result != nil {
+			call.To = p.Result.Address
+			call.Output = ""
+		}
+	case "suicide":
+		call.Type = "SELFDESTRUCT"
+		call.From = p.Action.Address
+		call.To = p.Action.RefundAddress
+		call.Value = p.Action.Balance
+	default:
+		call.Type = strings.ToUpper(p.Action.CallType)
+	}
+	return call
+}
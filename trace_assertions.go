@@ -0,0 +1,205 @@
+package seth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TraceAssertion is a query over decoded calls of a traced transaction, use it to check calls made by the transaction
+// in tests, e.g.:
+//
+//	trace := client.Tracer.AssertTrace(txHash)
+//	require.NoError(t, trace.ExpectCall("VRFCoordinator", "fulfillRandomWords").WithArg(0, subID).Times(1))
+type TraceAssertion struct {
+	tracer *Tracer
+	txHash string
+	calls  []*DecodedCall
+	err    error
+}
+
+// AssertTrace returns assertions over calls of any depth made by already traced transaction
+func (t *Tracer) AssertTrace(txHash string) *TraceAssertion {
+	a := &TraceAssertion{tracer: t, txHash: txHash}
+	if tree, err := t.DecodeCallTree(txHash); err == nil {
+		a.calls = flattenCallTree(tree, nil)
+		return a
+	}
+	calls, ok := t.GetDecodedCalls(txHash)
+	if !ok {
+		a.err = errors.Wrapf(ErrNoTrace, "transaction %s", txHash)
+	}
+	a.calls = calls
+	return a
+}
+
+func flattenCallTree(frame *CallTreeFrame, calls []*DecodedCall) []*DecodedCall {
+	calls = append(calls, frame.DecodedCall)
+	for _, sub := range frame.Calls {
+		calls = flattenCallTree(sub, calls)
+	}
+	return calls
+}
+
+// ExpectCall selects calls to the contract (name from the contract map or address) of the method (name or full signature)
+func (a *TraceAssertion) ExpectCall(contract, method string) *CallExpectation {
+	return &CallExpectation{assertion: a, contract: contract, method: method}
+}
+
+// CallExpectation is a set of conditions, that selected calls have to meet, it's checked with Times, AtLeast or Never
+type CallExpectation struct {
+	assertion  *TraceAssertion
+	contract   string
+	method     string
+	conditions []callCondition
+}
+
+type callCondition struct {
+	description string
+	matches     func(a *TraceAssertion, call *DecodedCall) bool
+}
+
+// WithArg requires argument at the index (in order of method's declaration) to be equal to the value. Values are compared
+// by their JSON encoding, so e.g. *big.Int and uint64 with the same value are equal.
+func (e *CallExpectation) WithArg(index int, value interface{}) *CallExpectation {
+	e.conditions = append(e.conditions, callCondition{
+		description: fmt.Sprintf("arg %d = %s", index, formatDiffValue(value)),
+		matches: func(a *TraceAssertion, call *DecodedCall) bool {
+			name, ok := a.argName(call, index)
+			if !ok {
+				return false
+			}
+			arg, ok := call.Input[name]
+			return ok && normalizedJson(arg) == normalizedJson(value)
+		},
+	})
+	return e
+}
+
+// WithNamedArg requires the argument with the name to be equal to the value, values are compared the same way as in WithArg
+func (e *CallExpectation) WithNamedArg(name string, value interface{}) *CallExpectation {
+	e.conditions = append(e.conditions, callCondition{
+		description: fmt.Sprintf("arg '%s' = %s", name, formatDiffValue(value)),
+		matches: func(_ *TraceAssertion, call *DecodedCall) bool {
+			arg, ok := call.Input[name]
+			return ok && normalizedJson(arg) == normalizedJson(value)
+		},
+	})
+	return e
+}
+
+// WithEvent requires the call to emit the event with given name or full signature
+func (e *CallExpectation) WithEvent(event string) *CallExpectation {
+	e.conditions = append(e.conditions, callCondition{
+		description: fmt.Sprintf("event %s", event),
+		matches: func(_ *TraceAssertion, call *DecodedCall) bool {
+			for _, ev := range call.Events {
+				if matchesSignature(ev.Signature, event) {
+					return true
+				}
+			}
+			return false
+		},
+	})
+	return e
+}
+
+// Times returns an error if matching calls weren't made exactly n times
+func (e *CallExpectation) Times(n int) error {
+	return e.check(func(count int) bool { return count == n }, fmt.Sprintf("exactly %d times", n))
+}
+
+// AtLeast returns an error if matching calls were made less than n times
+func (e *CallExpectation) AtLeast(n int) error {
+	return e.check(func(count int) bool { return count >= n }, fmt.Sprintf("at least %d times", n))
+}
+
+// Never returns an error if any matching call was made
+func (e *CallExpectation) Never() error {
+	return e.check(func(count int) bool { return count == 0 }, "never")
+}
+
+// Calls returns all calls matching the expectation
+func (e *CallExpectation) Calls() []*DecodedCall {
+	matching := []*DecodedCall{}
+	for _, call := range e.assertion.calls {
+		if e.matchesTarget(call) && e.matchesConditions(call) {
+			matching = append(matching, call)
+		}
+	}
+	return matching
+}
+
+func (e *CallExpectation) check(ok func(count int) bool, expected string) error {
+	if e.assertion.err != nil {
+		return e.assertion.err
+	}
+	count := len(e.Calls())
+	if ok(count) {
+		return nil
+	}
+
+	description := fmt.Sprintf("%s.%s", e.contract, e.method)
+	if len(e.conditions) > 0 {
+		conditions := make([]string, 0, len(e.conditions))
+		for _, c := range e.conditions {
+			conditions = append(conditions, c.description)
+		}
+		description = fmt.Sprintf("%s with %s", description, strings.Join(conditions, ", "))
+	}
+	targetCount := 0
+	made := make([]string, 0, len(e.assertion.calls))
+	for _, call := range e.assertion.calls {
+		if e.matchesTarget(call) {
+			targetCount++
+		}
+		made = append(made, fmt.Sprintf("%s.%s", call.To, call.Method))
+	}
+	return fmt.Errorf("expected %s to be called %s in transaction %s, but it was called %d times (%d times regardless of conditions), calls made: %s",
+		description, expected, e.assertion.txHash, count, targetCount, strings.Join(made, ", "))
+}
+
+func (e *CallExpectation) matchesTarget(call *DecodedCall) bool {
+	contractMatches := call.To == e.contract || call.Implementation == e.contract || strings.EqualFold(call.ToAddress, e.contract)
+	return contractMatches && matchesSignature(call.Method, e.method)
+}
+
+func (e *CallExpectation) matchesConditions(call *DecodedCall) bool {
+	for _, c := range e.conditions {
+		if !c.matches(e.assertion, call) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesSignature returns true if the signature (e.g. "transfer(address,uint256)") is equal to name or its name part is
+func matchesSignature(signature, name string) bool {
+	if signature == name {
+		return true
+	}
+	idx := strings.Index(signature, "(")
+	return idx != -1 && signature[:idx] == name
+}
+
+// argName returns name of call's argument at the index from ABI of the called contract, unnamed arguments are keyed by index
+func (a *TraceAssertion) argName(call *DecodedCall, index int) (string, bool) {
+	if a.tracer != nil && a.tracer.ContractStore != nil {
+		contract := call.To
+		if call.Implementation != "" {
+			contract = call.Implementation
+		}
+		if contractABI, ok := a.tracer.ContractStore.GetABI(contract); ok {
+			for _, m := range contractABI.Methods {
+				if m.Sig == call.Method && index < len(m.Inputs) && m.Inputs[index].Name != "" {
+					return m.Inputs[index].Name, true
+				}
+			}
+		}
+	}
+	name := strconv.Itoa(index)
+	_, ok := call.Input[name]
+	return name, ok
+}
@@ -0,0 +1,106 @@
+package seth
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+)
+
+const (
+	// CallTraceKeyPrefix is the prefix of keys, under which decoded traces of calls are stored in DecodedCalls
+	CallTraceKeyPrefix = "call_"
+)
+
+var (
+	ErrTraceCall = errors.New("failed to trace call")
+)
+
+// AccountOverride replaces account's state for a traced call. Only set fields are overridden, State replaces whole storage,
+// while StateDiff replaces only given slots.
+type AccountOverride struct {
+	Nonce     *hexutil.Uint64             `json:"nonce,omitempty"`
+	Code      hexutil.Bytes               `json:"code,omitempty"`
+	Balance   *hexutil.Big                `json:"balance,omitempty"`
+	State     map[common.Hash]common.Hash `json:"state,omitempty"`
+	StateDiff map[common.Hash]common.Hash `json:"stateDiff,omitempty"`
+}
+
+// StateOverrides are account overrides applied before the call is traced
+type StateOverrides map[common.Address]AccountOverride
+
+// TraceCall traces the call with debug_traceCall on top of the latest block with state overrides applied (e.g. different
+// sender balance or swapped contract code) and decodes it the same way as TraceGethTX does for transactions. Nothing is
+// sent to the network. Decoded calls are stored in DecodedCalls (and revert chain in RevertChains) under key returned
+// by CallTraceKey.
+func (t *Tracer) TraceCall(ctx context.Context, msg ethereum.CallMsg, overrides StateOverrides) ([]*DecodedCall, error) {
+	key, err := CallTraceKey(msg, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	var fourByteTrace map[string]int
+	if err := t.rpcClient.CallContext(ctx, &fourByteTrace, "debug_traceCall", toCallArg(msg), "latest", map[string]interface{}{
+		"tracer":         "4byteTracer",
+		"stateOverrides": overrides,
+	}); err != nil {
+		return nil, wrapError(err, ErrTraceCall)
+	}
+	fourByte, err := parseFourByteTrace(fourByteTrace)
+	if err != nil {
+		return nil, err
+	}
+
+	callTracerConfig := t.traceOpts(nil).tracerConfig(TracerType_Call)
+	callTracerConfig["stateOverrides"] = overrides
+	var callTrace *TXCallTraceOutput
+	if err := t.rpcClient.CallContext(ctx, &callTrace, "debug_traceCall", toCallArg(msg), "latest", callTracerConfig); err != nil {
+		return nil, wrapError(err, ErrTraceCall)
+	}
+	if callTrace == nil {
+		return nil, wrapError(ErrNoTrace, ErrTraceCall)
+	}
+
+	trace := &Trace{
+		TxHash:    key,
+		FourByte:  fourByte,
+		CallTrace: callTrace,
+	}
+	t.storeTrace(trace)
+	l := L.With().Str("Call", key).Logger()
+	l.Debug().Interface("CallTrace", t.Cfg.DecodedOutput.Limit(callTrace)).Msg("Full call trace with logs")
+
+	return t.DecodeTrace(l, *trace)
+}
+
+// CallTraceKey returns the key, under which decoded trace of the call with given overrides is stored, same calls have
+// the same key
+func CallTraceKey(msg ethereum.CallMsg, overrides StateOverrides) (string, error) {
+	encoded, err := json.Marshal([]interface{}{toCallArg(msg), overrides})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode call")
+	}
+	return CallTraceKeyPrefix + crypto.Keccak256Hash(encoded).Hex(), nil
+}
+
+// toCallArg converts call message to the JSON-RPC call object
+func toCallArg(msg ethereum.CallMsg) map[string]interface{} {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["input"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	return arg
+}
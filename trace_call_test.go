@@ -0,0 +1,67 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceCallWithOverrides(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	SkipAnvil(t, c)
+	require.NotNil(t, c.Tracer, "tracer should be initialised")
+
+	debugABI, ok := c.ContractStore.GetABI("NetworkDebugContract")
+	require.True(t, ok, "debug contract ABI should be in contract store")
+	code, err := c.Client.CodeAt(context.Background(), TestEnv.DebugContractAddress, nil)
+	require.NoError(t, err, "failed to get contract code")
+
+	t.Run("swapped code", func(t *testing.T) {
+		// contract doesn't exist at this address, its code is only overridden for the call
+		address := common.HexToAddress("0x00000000000000000000000000000000000c0de1")
+		c.ContractAddressToNameMap.AddContract(address.Hex(), "NetworkDebugContract")
+		data, err := debugABI.Pack("addCounter", big.NewInt(0), big.NewInt(5))
+		require.NoError(t, err, "failed to pack call data")
+		msg := ethereum.CallMsg{From: c.Addresses[0], To: &address, Data: data}
+
+		calls, err := c.Tracer.TraceCall(context.Background(), msg, seth.StateOverrides{
+			address: {Code: code},
+		})
+		require.NoError(t, err, "failed to trace call")
+		require.Len(t, calls, 1, "expected one decoded call")
+		require.Equal(t, "addCounter(int256,int256)", calls[0].Method, "incorrect method")
+		require.Equal(t, "NetworkDebugContract", calls[0].To, "incorrect contract")
+		require.Equal(t, big.NewInt(5), calls[0].Output["value"], "incorrect output")
+
+		key, err := seth.CallTraceKey(msg, seth.StateOverrides{address: {Code: code}})
+		require.NoError(t, err, "failed to get call trace key")
+		require.True(t, strings.HasPrefix(key, seth.CallTraceKeyPrefix), "incorrect key")
+		require.Equal(t, calls, c.Tracer.DecodedCalls[key], "decoded calls should be stored")
+	})
+
+	t.Run("sender balance", func(t *testing.T) {
+		// sender has no funds
+		sender := common.HexToAddress("0x00000000000000000000000000000000000b0b01")
+		data, err := debugABI.Pack("pay")
+		require.NoError(t, err, "failed to pack call data")
+		msg := ethereum.CallMsg{From: sender, To: &TestEnv.DebugContractAddress, Data: data, Value: big.NewInt(1e18)}
+
+		_, err = c.Tracer.TraceCall(context.Background(), msg, nil)
+		require.Error(t, err, "call without funds should fail")
+		require.Contains(t, err.Error(), "insufficient funds", "incorrect error")
+
+		calls, err := c.Tracer.TraceCall(context.Background(), msg, seth.StateOverrides{
+			sender: {Balance: (*hexutil.Big)(big.NewInt(2e18))},
+		})
+		require.NoError(t, err, "failed to trace call")
+		require.Len(t, calls, 1, "expected one decoded call")
+		require.Equal(t, "pay()", calls[0].Method, "incorrect method")
+	})
+}
@@ -0,0 +1,236 @@
+package seth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	TraceDiff_Target   = "target"
+	TraceDiff_Method   = "method"
+	TraceDiff_Argument = "argument"
+	TraceDiff_Output   = "output"
+	TraceDiff_Gas      = "gas"
+	TraceDiff_Event    = "event"
+	TraceDiff_Revert   = "revert"
+	// TraceDiff_MissingCall is a call present only in the first trace
+	TraceDiff_MissingCall = "missing_call"
+	// TraceDiff_ExtraCall is a call present only in the second trace
+	TraceDiff_ExtraCall = "extra_call"
+)
+
+var (
+	ErrReadCallTree = errors.New("failed to read call tree")
+)
+
+// TraceDifference is a single difference between two call trees. Path is the chain of calls leading to the frame, in which
+// the difference was found, Field is the name of argument, output or index of an event.
+type TraceDifference struct {
+	Path  string      `json:"path"`
+	Kind  string      `json:"kind"`
+	Field string      `json:"field,omitempty"`
+	A     interface{} `json:"a,omitempty"`
+	B     interface{} `json:"b,omitempty"`
+}
+
+func (d TraceDifference) String() string {
+	field := ""
+	if d.Field != "" {
+		field = fmt.Sprintf(" '%s'", d.Field)
+	}
+	return fmt.Sprintf("%s: %s%s differs: %v != %v", d.Path, d.Kind, field, formatDiffValue(d.A), formatDiffValue(d.B))
+}
+
+func formatDiffValue(v interface{}) string {
+	if v == nil {
+		return "<none>"
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return normalizedJson(v)
+}
+
+// CompareTraces aligns two decoded call trees and returns differences in call targets, methods, arguments, outputs, gas used,
+// emitted events and reverts. Sub-calls of aligned frames are aligned by their method signatures keeping the order of
+// calls, calls that couldn't be aligned are reported as missing (present only in traceA) or extra (present only in traceB).
+func CompareTraces(traceA, traceB *CallTreeFrame) []TraceDifference {
+	diffs := []TraceDifference{}
+	compareFrames(traceA, traceB, frameName(traceA), &diffs)
+	return diffs
+}
+
+func frameName(f *CallTreeFrame) string {
+	return fmt.Sprintf("%s.%s", f.To, f.Method)
+}
+
+func compareFrames(a, b *CallTreeFrame, path string, diffs *[]TraceDifference) {
+	add := func(kind, field string, va, vb interface{}) {
+		*diffs = append(*diffs, TraceDifference{Path: path, Kind: kind, Field: field, A: va, B: vb})
+	}
+
+	if a.To != b.To || !strings.EqualFold(a.ToAddress, b.ToAddress) {
+		add(TraceDiff_Target, "", fmt.Sprintf("%s (%s)", a.To, a.ToAddress), fmt.Sprintf("%s (%s)", b.To, b.ToAddress))
+	}
+	if a.Method != b.Method {
+		add(TraceDiff_Method, "", a.Method, b.Method)
+	}
+	compareValues(a.Input, b.Input, func(field string, va, vb interface{}) { add(TraceDiff_Argument, field, va, vb) })
+	compareValues(a.Output, b.Output, func(field string, va, vb interface{}) { add(TraceDiff_Output, field, va, vb) })
+	if a.GasUsed != b.GasUsed {
+		add(TraceDiff_Gas, "gas_used", a.GasUsed, b.GasUsed)
+	}
+	if a.Error != b.Error || a.RevertReason != b.RevertReason {
+		add(TraceDiff_Revert, "", revertDescription(a), revertDescription(b))
+	}
+	for i := 0; i < len(a.Events) || i < len(b.Events); i++ {
+		var ea, eb interface{}
+		if i < len(a.Events) {
+			ea = eventDescription(a.Events[i])
+		}
+		if i < len(b.Events) {
+			eb = eventDescription(b.Events[i])
+		}
+		if normalizedJson(ea) != normalizedJson(eb) {
+			add(TraceDiff_Event, fmt.Sprint(i), ea, eb)
+		}
+	}
+
+	for _, pair := range alignCalls(a.Calls, b.Calls) {
+		switch {
+		case pair[0] == nil:
+			*diffs = append(*diffs, TraceDifference{Path: path, Kind: TraceDiff_ExtraCall, B: frameName(pair[1])})
+		case pair[1] == nil:
+			*diffs = append(*diffs, TraceDifference{Path: path, Kind: TraceDiff_MissingCall, A: frameName(pair[0])})
+		default:
+			compareFrames(pair[0], pair[1], fmt.Sprintf("%s > %s", path, frameName(pair[0])), diffs)
+		}
+	}
+}
+
+func revertDescription(f *CallTreeFrame) interface{} {
+	if f.Error == "" {
+		return nil
+	}
+	if f.RevertReason == "" {
+		return f.Error
+	}
+	return fmt.Sprintf("%s: %s", f.Error, f.RevertReason)
+}
+
+func eventDescription(e DecodedCommonLog) map[string]interface{} {
+	return map[string]interface{}{"signature": e.Signature, "data": e.EventData}
+}
+
+// compareValues reports values of keys, which differ between the maps, in alphabetical order of keys
+func compareValues(a, b map[string]interface{}, report func(field string, va, vb interface{})) {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if normalizedJson(a[k]) != normalizedJson(b[k]) {
+			report(k, a[k], b[k])
+		}
+	}
+}
+
+// normalizedJson encodes the value as JSON, so that values decoded in memory (e.g. *big.Int) and read from JSON files can be compared
+func normalizedJson(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// alignCalls aligns calls by their method signatures using longest common subsequence, unaligned calls are paired with nil
+func alignCalls(a, b []*CallTreeFrame) [][2]*CallTreeFrame {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i].Signature == b[j].Signature {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	pairs := make([][2]*CallTreeFrame, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i].Signature == b[j].Signature:
+			pairs = append(pairs, [2]*CallTreeFrame{a[i], b[j]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			pairs = append(pairs, [2]*CallTreeFrame{a[i], nil})
+			i++
+		default:
+			pairs = append(pairs, [2]*CallTreeFrame{nil, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		pairs = append(pairs, [2]*CallTreeFrame{a[i], nil})
+	}
+	for ; j < len(b); j++ {
+		pairs = append(pairs, [2]*CallTreeFrame{nil, b[j]})
+	}
+	return pairs
+}
+
+// ReadCallTree reads decoded call tree from JSON file. It accepts both a call tree (as returned by Tracer.DecodeCallTree)
+// and decoded calls saved with 'trace_to_json', in which case the first call is the root and the others are its sub-calls.
+func ReadCallTree(path string) (*CallTreeFrame, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrReadCallTree), "file: %s", path)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// keeps big numbers intact
+	decoder.UseNumber()
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var calls []*DecodedCall
+		if err := decoder.Decode(&calls); err != nil {
+			return nil, errors.Wrapf(wrapError(err, ErrReadCallTree), "file: %s", path)
+		}
+		if len(calls) == 0 {
+			return nil, errors.Wrapf(wrapError(ErrNoTrace, ErrReadCallTree), "file: %s", path)
+		}
+		root := &CallTreeFrame{DecodedCall: calls[0]}
+		for _, call := range calls[1:] {
+			root.Calls = append(root.Calls, &CallTreeFrame{DecodedCall: call, Depth: 1})
+		}
+		return root, nil
+	}
+
+	tree := &CallTreeFrame{}
+	if err := decoder.Decode(tree); err != nil {
+		return nil, errors.Wrapf(wrapError(err, ErrReadCallTree), "file: %s", path)
+	}
+	if tree.DecodedCall == nil {
+		return nil, errors.Wrapf(wrapError(ErrNoTrace, ErrReadCallTree), "file: %s", path)
+	}
+	return tree, nil
+}
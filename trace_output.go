@@ -0,0 +1,247 @@
+package seth
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultTraceFileName is the default template of names (without extension) of files with traces
+	DefaultTraceFileName = "{tx_hash}"
+	// DefaultRevertedTransactionsFile is the default template of path of the file with hashes of transactions, which couldn't be decoded
+	DefaultRevertedTransactionsFile = "reverted_transactions_{network}_{timestamp}.json"
+
+	traceStatusReverted = "reverted"
+	traceStatusSuccess  = "success"
+)
+
+var (
+	ErrTraceOutputPlaceholder = errors.New("unknown placeholder in trace output")
+	ErrPruneTraces            = errors.New("failed to remove old trace files")
+)
+
+var tracePlaceholderRegexp = regexp.MustCompile(`\{[^{}]*\}`)
+
+// TraceOutputCfg configures where traces saved with 'trace_to_json' and 'trace_to_html' are written. Directory and file
+// templates can use {network}, {timestamp} (start of the run) and {run_id} placeholders, file name can also use {tx_hash}
+// and {status} ('reverted' or 'success'), while path of reverted transactions file can also use {dir} (directory of traces).
+type TraceOutputCfg struct {
+	// Dir is the root directory of traces (absolute or relative to working directory), default 'traces'
+	Dir string `toml:"dir"`
+	// RunSubdir is a template of a subdirectory of Dir created for each run, empty means traces are saved directly to Dir
+	RunSubdir string `toml:"run_subdir"`
+	// FileName is a template of names of trace files without extension, default '{tx_hash}'
+	FileName string `toml:"file_name"`
+	// RevertedTransactionsFile is a template of path (relative to working directory) of the file with hashes of transactions,
+	// which couldn't be decoded, default 'reverted_transactions_{network}_{timestamp}.json'
+	RevertedTransactionsFile string `toml:"reverted_transactions_file"`
+	// MaxFiles is the maximum number of trace files kept in Dir, the oldest ones are removed first, 0 means no limit
+	MaxFiles int `toml:"max_files"`
+	// MaxAge is the maximum age of trace files kept in Dir, 0 means no limit
+	MaxAge *Duration `toml:"max_age"`
+}
+
+func validateTraceOutputCfg(cfg *TraceOutputCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	runPlaceholders := []string{"{network}", "{timestamp}", "{run_id}"}
+	if err := checkTracePlaceholders(cfg.Dir, runPlaceholders); err != nil {
+		return err
+	}
+	if err := checkTracePlaceholders(cfg.RunSubdir, runPlaceholders); err != nil {
+		return err
+	}
+	if err := checkTracePlaceholders(cfg.FileName, append(runPlaceholders, "{tx_hash}", "{status}")); err != nil {
+		return err
+	}
+	if err := checkTracePlaceholders(cfg.RevertedTransactionsFile, append(runPlaceholders, "{dir}")); err != nil {
+		return err
+	}
+	if filepath.IsAbs(cfg.RunSubdir) || strings.HasPrefix(filepath.Clean(cfg.RunSubdir), "..") {
+		return fmt.Errorf("trace output 'run_subdir' must be a path inside 'dir', but it's '%s'", cfg.RunSubdir)
+	}
+	if cfg.MaxFiles < 0 {
+		return errors.New("trace output 'max_files' must be greater than or equal to 0")
+	}
+	if cfg.MaxAge != nil && cfg.MaxAge.Duration() < 0 {
+		return errors.New("trace output 'max_age' must be greater than or equal to 0")
+	}
+	return nil
+}
+
+func checkTracePlaceholders(template string, allowed []string) error {
+	for _, p := range tracePlaceholderRegexp.FindAllString(template, -1) {
+		known := false
+		for _, a := range allowed {
+			known = known || p == a
+		}
+		if !known {
+			return errors.Wrapf(ErrTraceOutputPlaceholder, "placeholder: '%s', trace output: '%s', use one of: %s", p, template, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// resolveTraceOutput sets directory of traces and path of reverted transactions file of the run started at the time
+func (c *Config) resolveTraceOutput(started time.Time) {
+	cfg := c.TraceOutput
+	if cfg == nil {
+		cfg = &TraceOutputCfg{}
+	}
+	c.traceOutputStarted = started
+	replacer := strings.NewReplacer(c.traceRunPlaceholders()...)
+
+	root := TracesDir
+	if cfg.Dir != "" {
+		root = replacer.Replace(cfg.Dir)
+	}
+	c.traceOutputRoot = root
+	c.traceOutputDir = filepath.Join(root, replacer.Replace(cfg.RunSubdir))
+
+	reverted := DefaultRevertedTransactionsFile
+	if cfg.RevertedTransactionsFile != "" {
+		reverted = cfg.RevertedTransactionsFile
+	}
+	c.RevertedTransactionsFile = strings.NewReplacer("{dir}", c.traceOutputDir).Replace(replacer.Replace(reverted))
+	if dir := filepath.Dir(c.RevertedTransactionsFile); dir != "." {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			L.Warn().Err(err).Str("Dir", dir).Msg("Failed to create directory of reverted transactions file")
+		}
+	}
+}
+
+// TraceOutputDir returns directory (absolute or relative to working directory), in which traces of this run are saved
+func (c *Config) TraceOutputDir() string {
+	if c.traceOutputDir == "" {
+		return TracesDir
+	}
+	return c.traceOutputDir
+}
+
+// traceFileName returns name (without extension) of the file with trace of the transaction
+func (c *Config) traceFileName(txHash string, reverted bool) string {
+	if c.TraceOutput == nil || c.TraceOutput.FileName == "" {
+		return txHash
+	}
+	status := traceStatusSuccess
+	if reverted {
+		status = traceStatusReverted
+	}
+	placeholders := append(c.traceRunPlaceholders(), "{tx_hash}", txHash, "{status}", status)
+	return strings.NewReplacer(placeholders...).Replace(c.TraceOutput.FileName)
+}
+
+// traceRunPlaceholders returns pairs of placeholders and their values, which are the same for the whole run
+func (c *Config) traceRunPlaceholders() []string {
+	networkName := ""
+	if c.Network != nil {
+		networkName = c.Network.Name
+	}
+	return []string{
+		"{network}", networkName,
+		"{timestamp}", c.traceOutputStarted.Format("2006-01-02-15-04-05"),
+		"{run_id}", RunID(),
+	}
+}
+
+// PruneTraces removes the oldest trace files (JSON and HTML ones) from 'trace_output.dir', which exceed 'max_files' or are
+// older than 'max_age'. Files of the current run may be removed too. It's called, when client is created, and after traces
+// are flushed.
+func (m *Client) PruneTraces() error {
+	cfg := m.Cfg.TraceOutput
+	if cfg == nil || (cfg.MaxFiles == 0 && (cfg.MaxAge == nil || cfg.MaxAge.Duration() == 0)) {
+		return nil
+	}
+	var maxAge time.Duration
+	if cfg.MaxAge != nil {
+		maxAge = cfg.MaxAge.Duration()
+	}
+	removed, err := pruneTraceFiles(m.Cfg.traceOutputRoot, cfg.MaxFiles, maxAge, m.Cfg.TraceOutputDir())
+	if err != nil {
+		return wrapError(err, ErrPruneTraces)
+	}
+	if removed > 0 {
+		m.logger().Debug().
+			Int("Removed", removed).
+			Str("Dir", m.Cfg.traceOutputRoot).
+			Msg("Removed old trace files")
+	}
+	return nil
+}
+
+type traceFile struct {
+	path    string
+	modTime time.Time
+}
+
+// pruneTraceFiles removes trace files older than maxAge and the oldest ones exceeding maxFiles, then it removes empty
+// directories except for root and keepDir. It returns number of removed files.
+func pruneTraceFiles(root string, maxFiles int, maxAge time.Duration, keepDir string) (int, error) {
+	if root == "" {
+		return 0, nil
+	}
+	var files []traceFile
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			if path != root {
+				dirs = append(dirs, path)
+			}
+			return nil
+		}
+		if ext := filepath.Ext(path); ext != ".json" && ext != ".html" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, traceFile{path: path, modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// newest files first
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+	removed := 0
+	for i, f := range files {
+		tooMany := maxFiles > 0 && i >= maxFiles
+		tooOld := maxAge > 0 && time.Since(f.modTime) > maxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, err
+		}
+		removed++
+	}
+
+	// nested directories go first, so that their parents are empty, when they're checked
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) > len(dirs[j]) })
+	for _, dir := range dirs {
+		if filepath.Clean(dir) == filepath.Clean(keepDir) {
+			continue
+		}
+		if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+			_ = os.Remove(dir)
+		}
+	}
+	return removed, nil
+}
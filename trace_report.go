@@ -0,0 +1,164 @@
+package seth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrSaveHTMLReport = errors.New("failed to save HTML trace report")
+)
+
+// CallTreeFrame is a decoded frame of transaction's call tree. Unlike DecodedCalls, which contain only the main call and
+// its direct sub-calls, call tree contains calls of any depth.
+type CallTreeFrame struct {
+	*DecodedCall
+	Type         string           `json:"type"`
+	Depth        int              `json:"depth"`
+	Error        string           `json:"error,omitempty"`
+	RevertReason string           `json:"revert_reason,omitempty"`
+	Calls        []*CallTreeFrame `json:"calls,omitempty"`
+}
+
+// DecodeCallTree decodes every frame of the call trace of already traced transaction
+func (t *Tracer) DecodeCallTree(txHash string) (*CallTreeFrame, error) {
+	trace, ok := t.GetTrace(txHash)
+	if !ok || trace.CallTrace == nil {
+		return nil, ErrNoTrace
+	}
+	root := trace.CallTrace.AsCall()
+	root.Calls = trace.CallTrace.Calls
+	return t.decodeCallTreeFrame(root, 0), nil
+}
+
+func (t *Tracer) decodeCallTreeFrame(call Call, depth int) *CallTreeFrame {
+	var signature []byte
+	if len(call.Input) >= 10 {
+		signature = common.Hex2Bytes(call.Input[2:10])
+	}
+	decoded, err := t.decodeCall(signature, call)
+	if err != nil {
+		if decoded.Comment != "" {
+			decoded.Comment = fmt.Sprintf("%s; %s", decoded.Comment, err.Error())
+		} else {
+			decoded.Comment = err.Error()
+		}
+	}
+	frame := &CallTreeFrame{
+		DecodedCall: decoded,
+		Type:        call.Type,
+		Depth:       depth,
+		Error:       call.Error,
+	}
+	if call.Error != "" {
+		frame.RevertReason = call.RevertReason
+		if reason := t.decodeRevertData(call.Output); reason != "" {
+			frame.RevertReason = reason
+		}
+	}
+	for _, sub := range call.Calls {
+		frame.Calls = append(frame.Calls, t.decodeCallTreeFrame(sub, depth+1))
+	}
+	return frame
+}
+
+// SaveHTMLReport saves decoded call tree of already traced transaction as an HTML report with collapsible frames to
+// '<dir>/<tx hash>.html' and returns its path
+func (t *Tracer) SaveHTMLReport(txHash, dir string) (string, error) {
+	return t.saveHTMLReport(txHash, dir, txHash)
+}
+
+// saveHTMLReport saves the report to '<dir>/<name>.html', name can contain subdirectories
+func (t *Tracer) saveHTMLReport(txHash, dir, name string) (string, error) {
+	tree, err := t.DecodeCallTree(txHash)
+	if err != nil {
+		return "", wrapError(err, ErrSaveHTMLReport)
+	}
+
+	revertChain, _ := t.GetRevertChain(txHash)
+	tmpl, err := traceReportTemplate.Clone()
+	if err != nil {
+		return "", wrapError(err, ErrSaveHTMLReport)
+	}
+	tmpl.Funcs(template.FuncMap{"json": func(v interface{}) string {
+		return toIndentedJson(t.Cfg.DecodedOutput.Limit(v))
+	}})
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, map[string]interface{}{
+		"TxHash":      txHash,
+		"RevertChain": revertChain,
+		"Root":        tree,
+	}); err != nil {
+		return "", wrapError(err, ErrSaveHTMLReport)
+	}
+
+	path := filepath.Join(dir, name+".html")
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", wrapError(err, ErrSaveHTMLReport)
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0600); err != nil {
+		return "", wrapError(err, ErrSaveHTMLReport)
+	}
+	return path, nil
+}
+
+func toIndentedJson(v interface{}) string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+// traceReportTemplate renders frames recursively as nested <details> elements, 'json' function limits the data with
+// 'decoded_output' config, when the report is saved
+var traceReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{"json": toIndentedJson}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Trace {{.TxHash}}</title>
+<style>
+body { font-family: monospace; font-size: 13px; margin: 16px; }
+details { margin-left: 16px; border-left: 1px solid #ccc; padding-left: 8px; }
+summary { cursor: pointer; padding: 2px 0; }
+summary.reverted { color: #b00020; }
+.type { color: #666; }
+.gas { color: #1a6fb0; }
+.frame { margin: 4px 0 8px 8px; }
+.frame table td:first-child { color: #666; padding-right: 12px; vertical-align: top; }
+pre { background: #f6f6f6; padding: 6px; margin: 2px 0; white-space: pre-wrap; word-break: break-all; }
+.revert { border: 1px solid #b00020; padding: 8px; margin-bottom: 12px; }
+</style>
+</head>
+<body>
+<h2>Transaction {{.TxHash}}</h2>
+{{with .RevertChain}}{{with .Origin}}<div class="revert">Reverted in <b>{{.To}}</b>.{{.Method}} at depth {{.Depth}}: {{.Error}}{{if .Reason}} ({{.Reason}}){{end}}</div>{{end}}{{end}}
+<button onclick="document.querySelectorAll('details').forEach(d => d.open = true)">Expand all</button>
+<button onclick="document.querySelectorAll('details').forEach(d => d.open = false)">Collapse all</button>
+{{template "frame" .Root}}
+</body>
+</html>
+{{define "frame"}}<details open>
+<summary{{if .Error}} class="reverted"{{end}}><span class="type">{{.Type}}</span> <b>{{.To}}</b>.{{.Method}} <span class="gas">gas {{.GasUsed}}/{{.GasLimit}}</span>{{if .Error}} {{.Error}}{{if .RevertReason}}: {{.RevertReason}}{{end}}{{end}}</summary>
+<div class="frame">
+<table>
+<tr><td>from</td><td>{{.From}} {{.FromAddress}}</td></tr>
+<tr><td>to</td><td>{{.To}} {{.ToAddress}}</td></tr>
+<tr><td>signature</td><td>{{.Signature}}</td></tr>
+{{if .Implementation}}<tr><td>implementation</td><td>{{.Implementation}}</td></tr>{{end}}
+{{if .Value}}<tr><td>value</td><td>{{.Value}}</td></tr>{{end}}
+{{if .Comment}}<tr><td>comment</td><td>{{.Comment}}</td></tr>{{end}}
+</table>
+{{if .Input}}<div>inputs</div><pre>{{json .Input}}</pre>{{end}}
+{{if .Output}}<div>outputs</div><pre>{{json .Output}}</pre>{{end}}
+{{range .Events}}<div>event {{.Signature}} emitted by {{.Address.Hex}}</div><pre>{{json .EventData}}</pre>{{end}}
+{{range .Calls}}{{template "frame" .}}{{end}}
+</div>
+</details>{{end}}`))
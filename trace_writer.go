@@ -0,0 +1,342 @@
+package seth
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultTraceWriterQueueSize is the number of traces waiting to be saved, after which new traces of successful transactions are dropped
+	DefaultTraceWriterQueueSize = 1000
+	// DefaultTraceWriterBatchSize is the maximum number of traces saved together, before queue is checked for flush requests
+	DefaultTraceWriterBatchSize = 50
+	// TracesDir is the default directory (relative to working directory), in which traces are saved, see 'trace_output.dir'
+	TracesDir = "traces"
+)
+
+var (
+	ErrTraceWriterClosed = errors.New("trace writer is closed")
+)
+
+// TraceWriterCfg configures writer, which saves traces and decoded transactions as JSON, when 'trace_to_json' is enabled
+type TraceWriterCfg struct {
+	// Synchronous saves traces on the goroutine that decodes the transaction, instead of in the background
+	Synchronous bool `toml:"synchronous"`
+	// QueueSize is the number of traces waiting to be saved, default 1000
+	QueueSize int `toml:"queue_size"`
+	// BatchSize is the maximum number of traces saved together, default 50
+	BatchSize int `toml:"batch_size"`
+	// MaxWritesPerSecond limits how many files are written per second, 0 means no limit
+	MaxWritesPerSecond int `toml:"max_writes_per_second"`
+	// Fsync makes writer sync each file and its directory, so that traces survive a crash of the machine
+	Fsync bool `toml:"fsync"`
+}
+
+// TraceWriterStats are counters of trace writer
+type TraceWriterStats struct {
+	Written int64
+	Dropped int64
+	Failed  int64
+	Queued  int
+}
+
+type traceWriteJob struct {
+	v       any
+	dirName string
+	name    string
+}
+
+// TraceWriter saves traces as JSON files in the background, so that decoding of transactions doesn't wait for disk writes.
+// Traces of reverted transactions have priority: they're saved first and never dropped (writing blocks, when their queue
+// is full), while traces of successful transactions are dropped, when the queue is full.
+type TraceWriter struct {
+	cfg       TraceWriterCfg
+	priority  chan traceWriteJob
+	normal    chan traceWriteJob
+	flushReqs chan chan struct{}
+	quit      chan struct{}
+	done      chan struct{}
+	mu        *sync.RWMutex
+	closed    bool
+	onSaved   func(path string)
+	nextWrite time.Time
+	written   atomic.Int64
+	dropped   atomic.Int64
+	failed    atomic.Int64
+}
+
+// NewTraceWriter creates a new trace writer and starts it, onSaved (if not nil) is called with path of each saved file
+func NewTraceWriter(cfg *TraceWriterCfg, onSaved func(path string)) *TraceWriter {
+	w := &TraceWriter{
+		flushReqs: make(chan chan struct{}),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+		mu:        &sync.RWMutex{},
+		onSaved:   onSaved,
+	}
+	if cfg != nil {
+		w.cfg = *cfg
+	}
+	if w.cfg.QueueSize <= 0 {
+		w.cfg.QueueSize = DefaultTraceWriterQueueSize
+	}
+	if w.cfg.BatchSize <= 0 {
+		w.cfg.BatchSize = DefaultTraceWriterBatchSize
+	}
+	w.priority = make(chan traceWriteJob, w.cfg.QueueSize)
+	w.normal = make(chan traceWriteJob, w.cfg.QueueSize)
+
+	if w.cfg.Synchronous {
+		close(w.done)
+	} else {
+		go w.run()
+	}
+
+	return w
+}
+
+// Write queues value to be saved as JSON file 'name.json' in the directory (absolute or relative to working directory). Value must not
+// be modified afterward, because it's marshalled in the background. Priority traces are never dropped.
+func (w *TraceWriter) Write(v any, dirName, name string, priority bool) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return ErrTraceWriterClosed
+	}
+
+	job := traceWriteJob{v: v, dirName: dirName, name: name}
+	if w.cfg.Synchronous {
+		w.writeBatch([]traceWriteJob{job})
+		return nil
+	}
+	if priority {
+		w.priority <- job
+		return nil
+	}
+
+	select {
+	case w.normal <- job:
+	default:
+		if dropped := w.dropped.Add(1); dropped == 1 || dropped%100 == 0 {
+			L.Warn().
+				Int64("Dropped", dropped).
+				Int("Queue size", w.cfg.QueueSize).
+				Msg("Trace writer queue is full, dropping traces of successful transactions. Increase 'queue_size' or lower tracing level")
+		}
+	}
+	return nil
+}
+
+// Flush waits until all traces queued before it was called are saved
+func (w *TraceWriter) Flush(ctx context.Context) error {
+	if w.cfg.Synchronous {
+		return nil
+	}
+	reply := make(chan struct{})
+	select {
+	case w.flushReqs <- reply:
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-reply:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close saves all queued traces and stops the writer, traces written afterward are rejected
+func (w *TraceWriter) Close() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		if !w.cfg.Synchronous {
+			close(w.quit)
+		}
+	}
+	w.mu.Unlock()
+	<-w.done
+}
+
+// Stats returns number of saved, dropped, failed and currently queued traces
+func (w *TraceWriter) Stats() TraceWriterStats {
+	return TraceWriterStats{
+		Written: w.written.Load(),
+		Dropped: w.dropped.Load(),
+		Failed:  w.failed.Load(),
+		Queued:  len(w.priority) + len(w.normal),
+	}
+}
+
+func (w *TraceWriter) run() {
+	defer close(w.done)
+	for {
+		// priority traces are always checked first
+		select {
+		case job := <-w.priority:
+			w.writeBatch(w.collect(job))
+			continue
+		default:
+		}
+
+		select {
+		case job := <-w.priority:
+			w.writeBatch(w.collect(job))
+		case job := <-w.normal:
+			w.writeBatch(w.collect(job))
+		case reply := <-w.flushReqs:
+			w.drain()
+			close(reply)
+		case <-w.quit:
+			w.drain()
+			return
+		}
+	}
+}
+
+// collect returns a batch of queued traces starting with the first one, priority ones go first
+func (w *TraceWriter) collect(first traceWriteJob) []traceWriteJob {
+	batch := []traceWriteJob{first}
+	for len(batch) < w.cfg.BatchSize {
+		select {
+		case job := <-w.priority:
+			batch = append(batch, job)
+			continue
+		default:
+		}
+		select {
+		case job := <-w.normal:
+			batch = append(batch, job)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// drain saves all queued traces
+func (w *TraceWriter) drain() {
+	for {
+		var job traceWriteJob
+		select {
+		case job = <-w.priority:
+		case job = <-w.normal:
+		default:
+			return
+		}
+		w.writeBatch(w.collect(job))
+	}
+}
+
+func (w *TraceWriter) writeBatch(batch []traceWriteJob) {
+	dirs := make(map[string]struct{})
+	for _, job := range batch {
+		w.waitForRateLimit()
+
+		data, err := json.MarshalIndent(job.v, "", "   ")
+		if err != nil {
+			w.failed.Add(1)
+			L.Warn().Err(err).Str("Name", job.name).Msg("Failed to marshal trace to JSON")
+			continue
+		}
+		path, err := writeJsonFile(data, job.dirName, job.name, w.cfg.Fsync)
+		if err != nil {
+			w.failed.Add(1)
+			L.Warn().Err(err).Str("Name", job.name).Msg("Failed to save trace as JSON")
+			continue
+		}
+		w.written.Add(1)
+		dirs[filepath.Dir(path)] = struct{}{}
+		if w.onSaved != nil {
+			w.onSaved(path)
+		}
+		L.Trace().
+			Str("Path", path).
+			Str("Tx hash", job.name).
+			Msg("Saved trace to JSON")
+	}
+
+	if !w.cfg.Fsync {
+		return
+	}
+	// entries of new files are persisted once per batch
+	for dirName := range dirs {
+		if err := syncDir(dirName); err != nil {
+			L.Warn().Err(err).Str("Dir", dirName).Msg("Failed to sync traces directory")
+		}
+	}
+}
+
+// waitForRateLimit spaces writes evenly, synchronous writes are not rate limited
+func (w *TraceWriter) waitForRateLimit() {
+	if w.cfg.MaxWritesPerSecond <= 0 || w.cfg.Synchronous {
+		return
+	}
+	if wait := time.Until(w.nextWrite); wait > 0 {
+		time.Sleep(wait)
+	}
+	w.nextWrite = time.Now().Add(time.Second / time.Duration(w.cfg.MaxWritesPerSecond))
+}
+
+func syncDir(dirName string) error {
+	d, err := os.Open(dirName)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func validateTraceWriterCfg(cfg *TraceWriterCfg) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.QueueSize < 0 {
+		return errors.New("trace writer 'queue_size' must be greater than or equal to 0")
+	}
+	if cfg.BatchSize < 0 {
+		return errors.New("trace writer 'batch_size' must be greater than or equal to 0")
+	}
+	if cfg.MaxWritesPerSecond < 0 {
+		return errors.New("trace writer 'max_writes_per_second' must be greater than or equal to 0")
+	}
+	return nil
+}
+
+// saveTraceAsJson saves trace with trace writer, reverted transactions' traces have priority
+func (m *Client) saveTraceAsJson(v any, txHash string, reverted bool) {
+	v = m.Cfg.DecodedOutput.Limit(v)
+	name := m.Cfg.traceFileName(txHash, reverted)
+	if m.TraceWriter == nil {
+		path, err := saveAsJson(v, m.Cfg.TraceOutputDir(), name)
+		if err != nil {
+			m.logger().Warn().Err(err).Msg("Failed to save decoded call as JSON")
+			return
+		}
+		m.recordManifestArtifact(path)
+		return
+	}
+	if err := m.TraceWriter.Write(v, m.Cfg.TraceOutputDir(), name, reverted); err != nil {
+		m.logger().Warn().Err(err).Str("Tx hash", txHash).Msg("Failed to queue trace to be saved as JSON")
+	}
+}
+
+// FlushTraces waits until all traces queued so far are saved as JSON, then it removes old trace files exceeding 'trace_output' retention
+func (m *Client) FlushTraces(ctx context.Context) error {
+	if m.TraceWriter != nil {
+		if err := m.TraceWriter.Flush(ctx); err != nil {
+			return err
+		}
+	}
+	return m.PruneTraces()
+}
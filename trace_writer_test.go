@@ -0,0 +1,77 @@
+package seth_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUtilTraceWriter(t *testing.T) {
+	dirName := "trace_writer_test"
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dirName)
+	})
+
+	requireSaved := func(t *testing.T, name string) {
+		_, err := os.Stat(filepath.Join(dirName, name+".json"))
+		require.NoError(t, err, "expected trace file to exist")
+	}
+
+	t.Run("saved in background", func(t *testing.T) {
+		var saved []string
+		mu := &sync.Mutex{}
+		w := seth.NewTraceWriter(&seth.TraceWriterCfg{Fsync: true, BatchSize: 3}, func(path string) {
+			mu.Lock()
+			defer mu.Unlock()
+			saved = append(saved, path)
+		})
+		defer w.Close()
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, w.Write(map[string]int{"i": i}, dirName, fmt.Sprintf("background_%d", i), i%2 == 0), "failed to queue trace")
+		}
+		require.NoError(t, w.Flush(context.Background()), "failed to flush traces")
+
+		for i := 0; i < 10; i++ {
+			requireSaved(t, fmt.Sprintf("background_%d", i))
+		}
+		mu.Lock()
+		require.Len(t, saved, 10, "expected callback for each saved trace")
+		mu.Unlock()
+		require.Equal(t, seth.TraceWriterStats{Written: 10}, w.Stats(), "incorrect stats")
+	})
+
+	t.Run("synchronous", func(t *testing.T) {
+		w := seth.NewTraceWriter(&seth.TraceWriterCfg{Synchronous: true}, nil)
+		require.NoError(t, w.Write("trace", dirName, "synchronous", false), "failed to save trace")
+		requireSaved(t, "synchronous")
+		w.Close()
+		require.ErrorIs(t, w.Write("trace", dirName, "synchronous", false), seth.ErrTraceWriterClosed)
+	})
+
+	t.Run("full queue drops only traces without priority", func(t *testing.T) {
+		// writer saves one trace at a time every 50ms, so that queue with a single slot fills up
+		w := seth.NewTraceWriter(&seth.TraceWriterCfg{QueueSize: 1, BatchSize: 1, MaxWritesPerSecond: 20}, nil)
+		for i := 0; i < 5; i++ {
+			require.NoError(t, w.Write("trace", dirName, fmt.Sprintf("normal_%d", i), false), "failed to queue trace")
+		}
+		for i := 0; i < 3; i++ {
+			require.NoError(t, w.Write("trace", dirName, fmt.Sprintf("priority_%d", i), true), "failed to queue trace")
+		}
+		w.Close()
+
+		for i := 0; i < 3; i++ {
+			requireSaved(t, fmt.Sprintf("priority_%d", i))
+		}
+		stats := w.Stats()
+		require.GreaterOrEqual(t, stats.Dropped, int64(1), "expected some traces to be dropped")
+		require.Equal(t, int64(8), stats.Written+stats.Dropped, "every trace should be either saved or dropped")
+		require.Equal(t, 0, stats.Queued, "queue should be empty after close")
+	})
+}
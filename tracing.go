@@ -1,9 +1,16 @@
 package seth
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
@@ -14,12 +21,7 @@ import (
 )
 
 const (
-	ErrNoTrace                = "no trace found"
-	ErrNoABIMethod            = "no ABI method found"
-	ErrNoAbiFound             = "no ABI found in Contract Store"
-	ErrNoFourByteFound        = "no method signatures found in tracing data"
-	ErrInvalidMethodSignature = "no method signature found or it's not 4 bytes long"
-	WrnMissingCallTrace       = "This call was missing from call trace, but it's signature was present in 4bytes trace. Most data is missing; Call order remains unknown"
+	WrnMissingCallTrace = "This call was missing from call trace, but it's signature was present in 4bytes trace. Most data is missing; Call order remains unknown"
 
 	FAILED_TO_DECODE = "failed to decode"
 	UNKNOWN          = "unknown"
@@ -28,15 +30,124 @@ const (
 	CommentMissingABI = "Call not decoded due to missing ABI instance"
 )
 
+var (
+	ErrNoTrace                = errors.New("no trace found")
+	ErrNoAbiFound             = errors.New("no ABI found in Contract Store")
+	ErrNoFourByteFound        = errors.New("no method signatures found in tracing data")
+	ErrInvalidMethodSignature = errors.New("no method signature found or it's not 4 bytes long")
+	ErrTracerType             = errors.New("unknown tracer")
+	ErrTracerTimeout          = errors.New("tracer timeout must be greater than 0")
+)
+
+const (
+	TracerType_FourByte = "4byteTracer"
+	TracerType_Call     = "callTracer"
+	TracerType_Prestate = "prestateTracer"
+	// TracerType_OpCodes is the default geth tracer logging every executed opcode
+	TracerType_OpCodes = "structLogger"
+)
+
+// defaultTracers are used, when TraceOpts don't select any tracers
+var defaultTracers = []string{TracerType_FourByte, TracerType_Call, TracerType_OpCodes}
+
+// TraceOpts select geth tracers used by TraceGethTX and configure them. Call tracer is always used, because the trace is
+// decoded from its output, other tracers are used only if they are selected. If no tracers are selected 4byte, call and
+// opcodes tracers are used.
+type TraceOpts struct {
+	// Tracers are names of native tracers: 4byteTracer, callTracer, prestateTracer or structLogger (opcodes)
+	Tracers []string `toml:"tracers"`
+	// JSTracer is code of a custom JavaScript tracer, its raw result is stored in Trace.CustomTrace
+	JSTracer string `toml:"js_tracer"`
+	// Timeout of each tracer, node's default is 5s
+	Timeout *Duration `toml:"timeout"`
+	// OnlyTopCall makes call tracer skip sub-calls
+	OnlyTopCall bool `toml:"only_top_call"`
+	// WithLog makes call tracer collect logs, defaults to true
+	WithLog *bool `toml:"with_log"`
+	// StateDiff makes prestate tracer (even if it's not selected) run in diff mode, capturing both pre and post state
+	// of modified accounts, which is decoded to storage and balance changes of each account
+	StateDiff bool `toml:"state_diff"`
+}
+
+// uses returns true if the tracer is selected
+func (o TraceOpts) uses(tracer string) bool {
+	tracers := o.Tracers
+	if len(tracers) == 0 {
+		tracers = defaultTracers
+	}
+	for _, t := range tracers {
+		if t == tracer {
+			return true
+		}
+	}
+	return false
+}
+
+// tracerConfig returns options of debug_traceTransaction for the tracer
+func (o TraceOpts) tracerConfig(tracer string) map[string]interface{} {
+	cfg := map[string]interface{}{}
+	if tracer != TracerType_OpCodes {
+		cfg["tracer"] = tracer
+	}
+	if o.Timeout != nil {
+		cfg["timeout"] = o.Timeout.Duration().String()
+	}
+	switch tracer {
+	case TracerType_Call:
+		cfg["tracerConfig"] = map[string]interface{}{
+			"withLog":     o.WithLog == nil || *o.WithLog,
+			"onlyTopCall": o.OnlyTopCall,
+		}
+	case TracerType_Prestate:
+		cfg["tracerConfig"] = map[string]interface{}{
+			"diffMode": o.StateDiff,
+		}
+	}
+	return cfg
+}
+
+func validateTraceOpts(opts *TraceOpts) error {
+	if opts == nil {
+		return nil
+	}
+	for _, tracer := range opts.Tracers {
+		switch tracer {
+		case TracerType_FourByte, TracerType_Call, TracerType_Prestate, TracerType_OpCodes:
+		default:
+			return errors.Wrapf(ErrTracerType, "tracer '%s', use one of: 4byteTracer, callTracer, prestateTracer, structLogger or set 'js_tracer'", tracer)
+		}
+	}
+	if opts.Timeout != nil && opts.Timeout.Duration() <= 0 {
+		return ErrTracerTimeout
+	}
+	return nil
+}
+
 type Tracer struct {
-	Cfg                      *Config
-	rpcClient                *rpc.Client
-	traces                   map[string]*Trace
+	Cfg       *Config
+	rpcClient *rpc.Client
+	// failover is set, when debug calls fail over between multiple HTTP URLs
+	failover *failoverTransport
+	// sharedConnection is set, when tracer uses connection of the client provided with WithEthClient or WithRPCClient
+	sharedConnection bool
+	// mu guards traces, DecodedCalls, RevertChains, retained and backend
+	mu     *sync.RWMutex
+	traces map[string]*Trace
+	// backend is the API used to trace transactions, empty means debug API
+	backend string
+	// retained are keys of traced transactions and calls from the oldest one, used to evict them, when 'trace_retention' is set
+	retained                 []string
 	Addresses                []common.Address
 	ContractStore            *ContractStore
 	ContractAddressToNameMap ContractMap
-	DecodedCalls             map[string][]*DecodedCall
-	ABIFinder                *ABIFinder
+	// DecodedCalls shouldn't be accessed directly, while transactions are decoded concurrently, use GetDecodedCalls instead
+	DecodedCalls map[string][]*DecodedCall
+	// RevertChains contains revert origin and propagation chain for traced transactions, in which any call reverted. It
+	// shouldn't be accessed directly, while transactions are decoded concurrently, use GetRevertChain instead.
+	RevertChains map[string]*RevertChain
+	ABIFinder    *ABIFinder
+	// SignatureDatabase is used to decode calls, that don't match any ABI, it's set if 'signature_database' is configured
+	SignatureDatabase *SignatureDatabase
 }
 
 type Trace struct {
@@ -44,6 +155,21 @@ type Trace struct {
 	FourByte     map[string]*TXFourByteMetadataOutput
 	CallTrace    *TXCallTraceOutput
 	OpCodesTrace map[string]interface{}
+	// PrestateTrace is the state of accounts touched by the transaction before it was executed, keyed by address. With
+	// 'state_diff' enabled it contains only modified accounts and storage slots.
+	PrestateTrace map[string]*PrestateAccount
+	// PoststateTrace is set only with 'state_diff' enabled and contains changed fields of modified accounts
+	PoststateTrace map[string]*PrestateAccount
+	// CustomTrace is the raw result of the JavaScript tracer
+	CustomTrace json.RawMessage
+}
+
+// PrestateAccount is the state of an account as returned by prestateTracer, storage contains only accessed slots
+type PrestateAccount struct {
+	Balance string            `json:"balance,omitempty"`
+	Nonce   uint64            `json:"nonce,omitempty"`
+	Code    string            `json:"code,omitempty"`
+	Storage map[string]string `json:"storage,omitempty"`
 }
 
 type TXFourByteMetadataOutput struct {
@@ -88,6 +214,130 @@ type Call struct {
 	To      string     `json:"to"`
 	Type    string     `json:"type"`
 	Value   string     `json:"value"`
+	Error   string     `json:"error,omitempty"`
+	// RevertReason is set by some nodes, when revert data can be decoded as Error(string)
+	RevertReason string `json:"revertReason,omitempty"`
+	Calls        []Call `json:"calls,omitempty"`
+}
+
+// InternalTransfer is a native value transfer that happened inside a transaction: an internal call or contract creation
+// with value or a selfdestruct sweeping contract's balance.
+type InternalTransfer struct {
+	Type        string   `json:"type"`
+	FromAddress string   `json:"from_address"`
+	ToAddress   string   `json:"to_address"`
+	From        string   `json:"from,omitempty"`
+	To          string   `json:"to,omitempty"`
+	Amount      *big.Int `json:"amount"`
+	Depth       int      `json:"depth"`
+}
+
+// InternalTransfers returns all native value transfers made by internal calls, contract creations and selfdestructs in the order
+// they were executed. Value of the transaction itself is not included. Transfers made by reverted calls are skipped, since they never
+// really happened.
+func (t *TXCallTraceOutput) InternalTransfers() ([]InternalTransfer, error) {
+	transfers := []InternalTransfer{}
+	if t.Error != "" {
+		return transfers, nil
+	}
+
+	err := collectInternalTransfers(t.Calls, 1, &transfers)
+	return transfers, err
+}
+
+// BalanceChange is the net change of address' native balance caused by value transfers made in a transaction
+type BalanceChange struct {
+	Address  string   `json:"address"`
+	Name     string   `json:"name,omitempty"`
+	Sent     *big.Int `json:"sent"`
+	Received *big.Int `json:"received"`
+	Change   *big.Int `json:"change"`
+}
+
+// BalanceChanges returns net changes of native balances of all addresses, that sent or received value in the transaction
+// (its value and internal transfers), sorted by address. Addresses, that forwarded all received value, are included with
+// zero change. Gas fees are not included.
+func (t *TXCallTraceOutput) BalanceChanges() ([]BalanceChange, error) {
+	changes := []BalanceChange{}
+	if t.Error != "" {
+		return changes, nil
+	}
+
+	transfers, err := t.InternalTransfers()
+	if err != nil {
+		return nil, err
+	}
+	if t.Value != "" {
+		value, err := hexutil.DecodeBig(t.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse value of transaction from %s to %s", t.From, t.To)
+		}
+		transfers = append(transfers, InternalTransfer{FromAddress: t.From, ToAddress: t.To, Amount: value})
+	}
+
+	byAddress := make(map[string]*BalanceChange)
+	get := func(address string) *BalanceChange {
+		address = strings.ToLower(address)
+		if change, ok := byAddress[address]; ok {
+			return change
+		}
+		change := &BalanceChange{Address: address, Sent: big.NewInt(0), Received: big.NewInt(0), Change: big.NewInt(0)}
+		byAddress[address] = change
+		return change
+	}
+	for _, transfer := range transfers {
+		if transfer.Amount.Sign() <= 0 {
+			continue
+		}
+		from, to := get(transfer.FromAddress), get(transfer.ToAddress)
+		from.Sent.Add(from.Sent, transfer.Amount)
+		from.Change.Sub(from.Change, transfer.Amount)
+		to.Received.Add(to.Received, transfer.Amount)
+		to.Change.Add(to.Change, transfer.Amount)
+	}
+
+	for _, change := range byAddress {
+		changes = append(changes, *change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Address < changes[j].Address
+	})
+	return changes, nil
+}
+
+func collectInternalTransfers(calls []Call, depth int, transfers *[]InternalTransfer) error {
+	for _, call := range calls {
+		if call.Error != "" {
+			continue
+		}
+
+		switch strings.ToUpper(call.Type) {
+		// these calls execute in the context of the caller, so no value is really moved
+		case "DELEGATECALL", "STATICCALL", "CALLCODE":
+		default:
+			if call.Value != "" {
+				amount, err := hexutil.DecodeBig(call.Value)
+				if err != nil {
+					return errors.Wrapf(err, "failed to parse value of %s call from %s to %s", call.Type, call.From, call.To)
+				}
+				if amount.Sign() > 0 {
+					*transfers = append(*transfers, InternalTransfer{
+						Type:        strings.ToUpper(call.Type),
+						FromAddress: call.From,
+						ToAddress:   call.To,
+						Amount:      amount,
+						Depth:       depth,
+					})
+				}
+			}
+		}
+
+		if err := collectInternalTransfers(call.Calls, depth+1, transfers); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func NewTracer(url string, cs *ContractStore, abiFinder *ABIFinder, cfg *Config, contractAddressToNameMap ContractMap, addresses []common.Address) (*Tracer, error) {
@@ -95,61 +345,232 @@ func NewTracer(url string, cs *ContractStore, abiFinder *ABIFinder, cfg *Config,
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to '%s' due to: %w", url, err)
 	}
+	return newTracer(c, cs, abiFinder, cfg, contractAddressToNameMap, addresses), nil
+}
+
+// newTracerWithFailover creates a new tracer, whose heavy debug calls fail over between network's RPC URLs
+func newTracerWithFailover(cs *ContractStore, abiFinder *ABIFinder, cfg *Config, contractAddressToNameMap ContractMap, addresses []common.Address) (*Tracer, error) {
+	t := newTracer(nil, cs, abiFinder, cfg, contractAddressToNameMap, addresses)
+	if err := t.connect(cfg.Network); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// connect connects tracer to network's RPC URLs, its heavy debug calls fail over between them
+func (t *Tracer) connect(n *Network) error {
+	c, failover, err := dialRPC(context.Background(), n)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to RPC node")
+	}
+	t.rpcClient = c
+	t.failover = failover
+	return nil
+}
+
+func newTracer(c *rpc.Client, cs *ContractStore, abiFinder *ABIFinder, cfg *Config, contractAddressToNameMap ContractMap, addresses []common.Address) *Tracer {
+	var signatureDatabase *SignatureDatabase
+	if cfg.SignatureDatabase != nil {
+		signatureDatabase = NewSignatureDatabase(cfg.SignatureDatabase)
+	}
 	return &Tracer{
 		Cfg:                      cfg,
 		rpcClient:                c,
+		mu:                       &sync.RWMutex{},
 		traces:                   make(map[string]*Trace),
 		Addresses:                addresses,
 		ContractStore:            cs,
 		ContractAddressToNameMap: contractAddressToNameMap,
 		DecodedCalls:             make(map[string][]*DecodedCall),
+		RevertChains:             make(map[string]*RevertChain),
 		ABIFinder:                abiFinder,
-	}, nil
+		SignatureDatabase:        signatureDatabase,
+	}
 }
 
-func (t *Tracer) TraceGethTX(txHash string) error {
-	fourByte, err := t.trace4Byte(txHash)
-	if err != nil {
-		return err
+// Close closes tracer's RPC connection
+func (t *Tracer) Close() {
+	if t.rpcClient != nil && !t.sharedConnection {
+		t.rpcClient.Close()
 	}
-	callTrace, err := t.traceCallTracer(txHash)
-	if err != nil {
+}
+
+// TraceGethTX traces the transaction with tracers selected in opts or, if they aren't passed, in 'tracer' config and decodes it
+func (t *Tracer) TraceGethTX(txHash string, opts ...TraceOpts) error {
+	return t.TraceGethTXCtx(context.Background(), txHash, opts...)
+}
+
+// TraceGethTXCtx is the same as TraceGethTX, but tracing calls can be cancelled with the context
+func (t *Tracer) TraceGethTXCtx(ctx context.Context, txHash string, opts ...TraceOpts) error {
+	o := t.traceOpts(opts)
+	trace := &Trace{TxHash: txHash}
+	var err error
+	if t.Backend() == TracerBackend_TraceAPI {
+		// trace API provides only call traces, so other tracers and options are ignored
+		if trace.CallTrace, err = t.traceWithTraceAPI(ctx, txHash); err != nil {
+			return err
+		}
+		return t.storeAndDecodeTrace(trace)
+	}
+	if o.uses(TracerType_FourByte) {
+		if trace.FourByte, err = t.trace4Byte(ctx, txHash, o); err != nil {
+			return err
+		}
+	}
+	if trace.CallTrace, err = t.traceCallTracer(ctx, txHash, o); err != nil {
 		return err
 	}
+	if o.uses(TracerType_OpCodes) {
+		if trace.OpCodesTrace, err = t.traceOpCodesTracer(ctx, txHash, o); err != nil {
+			return err
+		}
+	}
+	if o.StateDiff {
+		var diff prestateDiffOutput
+		if err := t.rpcClient.CallContext(ctx, &diff, "debug_traceTransaction", txHash, o.tracerConfig(TracerType_Prestate)); err != nil {
+			return err
+		}
+		trace.PrestateTrace, trace.PoststateTrace = diff.Pre, diff.Post
+	} else if o.uses(TracerType_Prestate) {
+		if err := t.rpcClient.CallContext(ctx, &trace.PrestateTrace, "debug_traceTransaction", txHash, o.tracerConfig(TracerType_Prestate)); err != nil {
+			return err
+		}
+	}
+	if o.JSTracer != "" {
+		if err := t.rpcClient.CallContext(ctx, &trace.CustomTrace, "debug_traceTransaction", txHash, o.tracerConfig(o.JSTracer)); err != nil {
+			return err
+		}
+	}
+	return t.storeAndDecodeTrace(trace)
+}
 
-	opCodesTrace, err := t.traceOpCodesTracer(txHash)
-	if err != nil {
+func (t *Tracer) storeAndDecodeTrace(trace *Trace) error {
+	t.storeTrace(trace)
+	if _, err := t.DecodeTrace(L, *trace); err != nil {
 		return err
 	}
-	t.traces[txHash] = &Trace{
-		TxHash:       txHash,
-		FourByte:     fourByte,
-		CallTrace:    callTrace,
-		OpCodesTrace: opCodesTrace,
+	return t.PrintTXTrace(trace.TxHash)
+}
+
+// traceOpts returns passed trace options or, if there are none, the configured ones
+func (t *Tracer) traceOpts(opts []TraceOpts) TraceOpts {
+	if len(opts) > 0 {
+		return opts[0]
 	}
-	_, err = t.DecodeTrace(L, *t.traces[txHash])
-	if err != nil {
-		return err
+	if t.Cfg != nil && t.Cfg.TraceOpts != nil {
+		return *t.Cfg.TraceOpts
 	}
-	return t.PrintTXTrace(txHash)
+	return TraceOpts{}
 }
 
-func (t *Tracer) PrintTXTrace(txHash string) error {
+// GetTrace returns raw outputs of tracers used to trace the transaction
+func (t *Tracer) GetTrace(txHash string) (*Trace, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	trace, ok := t.traces[txHash]
+	return trace, ok
+}
+
+// GetDecodedCalls returns decoded calls of the traced transaction
+func (t *Tracer) GetDecodedCalls(txHash string) ([]*DecodedCall, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	calls, ok := t.DecodedCalls[txHash]
+	return calls, ok
+}
+
+// GetRevertChain returns revert chain of the traced transaction, if any call reverted in it
+func (t *Tracer) GetRevertChain(txHash string) (*RevertChain, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	chain, ok := t.RevertChains[txHash]
+	return chain, ok
+}
+
+// storeTrace keeps raw trace of the transaction or call, evicting the oldest traced ones over 'trace_retention'
+func (t *Tracer) storeTrace(trace *Trace) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.retain(trace.TxHash)
+	t.traces[trace.TxHash] = trace
+}
+
+// retain marks the key as the newest one and evicts data of the oldest ones over 'trace_retention', it has to be called
+// with the lock held
+func (t *Tracer) retain(key string) {
+	_, traced := t.traces[key]
+	_, decoded := t.DecodedCalls[key]
+	if !traced && !decoded {
+		t.retained = append(t.retained, key)
+	}
+	if t.Cfg == nil || t.Cfg.TraceRetention <= 0 {
+		return
+	}
+	for len(t.retained) > t.Cfg.TraceRetention {
+		evicted := t.retained[0]
+		t.retained = t.retained[1:]
+		delete(t.traces, evicted)
+		delete(t.DecodedCalls, evicted)
+		delete(t.RevertChains, evicted)
+	}
+}
+
+// FlushTo saves decoded calls of all traced transactions as '<dir>/<tx hash>.json' files and removes them (together with
+// raw traces and revert chains) from memory
+func (t *Tracer) FlushTo(dir string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "failed to create traces directory")
+	}
+	for txHash, calls := range t.DecodedCalls {
+		data, err := json.MarshalIndent(t.Cfg.DecodedOutput.Limit(calls), "", "   ")
+		if err != nil {
+			return errors.Wrapf(err, "failed to encode decoded calls of %s", txHash)
+		}
+		if err := os.WriteFile(filepath.Join(dir, txHash+".json"), data, 0600); err != nil {
+			return errors.Wrapf(err, "failed to save decoded calls of %s", txHash)
+		}
+	}
+	t.reset()
+	return nil
+}
+
+// Reset removes raw traces, decoded calls and revert chains of all traced transactions from memory
+func (t *Tracer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reset()
+}
+
+func (t *Tracer) reset() {
+	t.traces = make(map[string]*Trace)
+	t.DecodedCalls = make(map[string][]*DecodedCall)
+	t.RevertChains = make(map[string]*RevertChain)
+	t.retained = nil
+}
+
+func (t *Tracer) PrintTXTrace(txHash string) error {
+	trace, ok := t.GetTrace(txHash)
 	if !ok {
-		return errors.New(ErrNoTrace)
+		return ErrNoTrace
 	}
 	l := L.With().Str("Transaction", txHash).Logger()
 	l.Debug().Interface("4Byte", trace.FourByte).Msg("Calls function signatures (names)")
-	l.Debug().Interface("CallTrace", trace.CallTrace).Msg("Full call trace with logs")
+	l.Debug().Interface("CallTrace", t.Cfg.DecodedOutput.Limit(trace.CallTrace)).Msg("Full call trace with logs")
 	return nil
 }
 
-func (t *Tracer) trace4Byte(txHash string) (map[string]*TXFourByteMetadataOutput, error) {
+func (t *Tracer) trace4Byte(ctx context.Context, txHash string, opts TraceOpts) (map[string]*TXFourByteMetadataOutput, error) {
 	var trace map[string]int
-	if err := t.rpcClient.Call(&trace, "debug_traceTransaction", txHash, map[string]interface{}{"tracer": "4byteTracer"}); err != nil {
+	if err := t.rpcClient.CallContext(ctx, &trace, "debug_traceTransaction", txHash, opts.tracerConfig(TracerType_FourByte)); err != nil {
 		return nil, err
 	}
+	return parseFourByteTrace(trace)
+}
+
+// parseFourByteTrace parses result of 4byteTracer, which maps "<signature>-<call data size>" to number of calls
+func parseFourByteTrace(trace map[string]int) (map[string]*TXFourByteMetadataOutput, error) {
 	out := make(map[string]*TXFourByteMetadataOutput)
 	for k, v := range trace {
 		d := strings.Split(k, "-")
@@ -162,26 +583,17 @@ func (t *Tracer) trace4Byte(txHash string) (map[string]*TXFourByteMetadataOutput
 	return out, nil
 }
 
-func (t *Tracer) traceCallTracer(txHash string) (*TXCallTraceOutput, error) {
+func (t *Tracer) traceCallTracer(ctx context.Context, txHash string, opts TraceOpts) (*TXCallTraceOutput, error) {
 	var trace *TXCallTraceOutput
-	if err := t.rpcClient.Call(
-		&trace,
-		"debug_traceTransaction",
-		txHash,
-		map[string]interface{}{
-			"tracer": "callTracer",
-			"tracerConfig": map[string]interface{}{
-				"withLog": true,
-			},
-		}); err != nil {
+	if err := t.rpcClient.CallContext(ctx, &trace, "debug_traceTransaction", txHash, opts.tracerConfig(TracerType_Call)); err != nil {
 		return nil, err
 	}
 	return trace, nil
 }
 
-func (t *Tracer) traceOpCodesTracer(txHash string) (map[string]interface{}, error) {
+func (t *Tracer) traceOpCodesTracer(ctx context.Context, txHash string, opts TraceOpts) (map[string]interface{}, error) {
 	var trace map[string]interface{}
-	if err := t.rpcClient.Call(&trace, "debug_traceTransaction", txHash); err != nil {
+	if err := t.rpcClient.CallContext(ctx, &trace, "debug_traceTransaction", txHash, opts.tracerConfig(TracerType_OpCodes)); err != nil {
 		return nil, err
 	}
 	return trace, nil
@@ -197,20 +609,20 @@ func (t *Tracer) DecodeTrace(l zerolog.Logger, trace Trace) ([]*DecodedCall, err
 		return []*DecodedCall{}, nil
 	}
 
-	// we can still decode the calls without 4byte signatures
-	if len(trace.FourByte) == 0 {
-		L.Warn().Msg(ErrNoFourByteFound)
+	// we can still decode the calls without 4byte signatures, nil means that 4byte tracer wasn't used
+	if trace.FourByte != nil && len(trace.FourByte) == 0 {
+		L.Warn().Msg(ErrNoFourByteFound.Error())
 	}
 
 	methods := make([]string, 0, len(trace.CallTrace.Calls)+1)
 
 	var getSignature = func(input string) (string, error) {
 		if len(input) < 10 {
-			err := errors.New(ErrInvalidMethodSignature)
+			err := ErrInvalidMethodSignature
 			l.Err(err).
 				Str("Input", input).
 				Send()
-			return "", errors.New(ErrInvalidMethodSignature)
+			return "", ErrInvalidMethodSignature
 		}
 
 		return input[2:10], nil
@@ -266,8 +678,46 @@ func (t *Tracer) DecodeTrace(l zerolog.Logger, trace Trace) ([]*DecodedCall, err
 		decodedCalls = append(decodedCalls, decodedSubCall)
 	}
 
-	missingCalls := t.checkForMissingCalls(trace)
-	decodedCalls = append(decodedCalls, missingCalls...)
+	if trace.FourByte != nil {
+		missingCalls := t.checkForMissingCalls(trace)
+		decodedCalls = append(decodedCalls, missingCalls...)
+	}
+
+	if t.Cfg.TraceInternalTransfers {
+		transfers, err := trace.CallTrace.InternalTransfers()
+		if err != nil {
+			l.Warn().
+				Err(err).
+				Msg("Failed to decode internal transfers")
+		}
+		for i := range transfers {
+			transfers[i].From = t.getHumanReadableAddressName(transfers[i].FromAddress)
+			transfers[i].To = t.getHumanReadableAddressName(transfers[i].ToAddress)
+		}
+		decodedMainCall.InternalTransfers = transfers
+
+		balanceChanges, err := trace.CallTrace.BalanceChanges()
+		if err != nil {
+			l.Warn().
+				Err(err).
+				Msg("Failed to calculate balance changes")
+		}
+		for i := range balanceChanges {
+			balanceChanges[i].Name = t.getHumanReadableAddressName(balanceChanges[i].Address)
+		}
+		decodedMainCall.BalanceChanges = balanceChanges
+	}
+
+	stateDiff, err := trace.StateDiff()
+	if err != nil {
+		l.Warn().
+			Err(err).
+			Msg("Failed to decode state diff")
+	}
+	for i := range stateDiff {
+		stateDiff[i].Name = t.getHumanReadableAddressName(stateDiff[i].Address)
+	}
+	decodedMainCall.StateDiff = stateDiff
 
 	if len(decodedCalls) != 0 {
 		l.Debug().
@@ -279,6 +729,17 @@ func (t *Tracer) DecodeTrace(l zerolog.Logger, trace Trace) ([]*DecodedCall, err
 			Msg("----------- Decoding transaction trace finished -----------")
 	}
 
+	revertChain := t.decodeRevertChain(trace)
+	if revertChain != nil {
+		t.printRevertChain(l, revertChain)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.retain(trace.TxHash)
+	if revertChain != nil {
+		t.RevertChains[trace.TxHash] = revertChain
+	}
 	t.DecodedCalls[trace.TxHash] = decodedCalls
 	return decodedCalls, nil
 }
@@ -350,6 +811,15 @@ func (t *Tracer) decodeCall(byteSignature []byte, rawCall Call) (*DecodedCall, e
 		} else {
 			defaultCall.Comment = CommentMissingABI
 		}
+		if t.SignatureDatabase != nil && t.decodeWithSignatureDatabase(defaultCall, byteSignature, rawCall.Input) {
+			L.Debug().
+				Str("Method signature", common.Bytes2Hex(byteSignature)).
+				Str("Method", defaultCall.Method).
+				Str("Contract", rawCall.To).
+				Msg("Method not found in any ABI instance, decoded it with signature from signature database")
+
+			return defaultCall, nil
+		}
 		L.Warn().
 			Err(err).
 			Str("Method signature", common.Bytes2Hex(byteSignature)).
@@ -362,22 +832,24 @@ func (t *Tracer) decodeCall(byteSignature []byte, rawCall Call) (*DecodedCall, e
 
 	defaultCall.Method = abiResult.Method.Sig
 	defaultCall.Signature = common.Bytes2Hex(abiResult.Method.ID)
+	t.attributeProxyCall(defaultCall, rawCall, abiResult)
 
 	txInput, err = decodeTxInputs(L, common.Hex2Bytes(strings.TrimPrefix(rawCall.Input, "0x")), abiResult.Method)
 	if err != nil {
-		return defaultCall, errors.Wrap(err, ErrDecodeInput)
+		return defaultCall, wrapError(err, ErrDecodeInput)
 	}
 
 	defaultCall.Input = txInput
+	defaultCall.NestedCalls = t.ABIFinder.DecodeNestedCalls(abiResult.Method, txInput)
 
 	if rawCall.Output != "" {
 		output, err := hexutil.Decode(rawCall.Output)
 		if err != nil {
-			return defaultCall, errors.Wrap(err, ErrDecodeOutput)
+			return defaultCall, wrapError(err, ErrDecodeOutput)
 		}
 		txOutput, err = decodeTxOutputs(L, output, abiResult.Method)
 		if err != nil {
-			return defaultCall, errors.Wrap(err, ErrDecodeOutput)
+			return defaultCall, wrapError(err, ErrDecodeOutput)
 		}
 
 		defaultCall.Output = txOutput
@@ -493,6 +965,8 @@ func (t *Tracer) checkForMissingCalls(trace Trace) []*DecodedCall {
 }
 
 func (t *Tracer) SaveDecodedCallsAsJson(dirname string) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	for txHash, calls := range t.DecodedCalls {
 		_, err := saveAsJson(calls, dirname, txHash)
 		if err != nil {
@@ -512,7 +986,7 @@ func (t *Tracer) decodeContractLogs(l zerolog.Logger, logs []TraceLog, a abi.ABI
 				l.Trace().Str("Name", evSpec.RawName).Str("Signature", evSpec.Sig).Msg("Unpacking event")
 				eventsMap, topicsMap, err := decodeEventFromLog(l, a, evSpec, lo)
 				if err != nil {
-					return nil, errors.Wrap(err, ErrDecodeLog)
+					return nil, wrapError(err, ErrDecodeLog)
 				}
 				parsedEvent := decodedLogFromMaps(&DecodedCommonLog{}, eventsMap, topicsMap)
 				if decodedLog, ok := parsedEvent.(*DecodedCommonLog); ok {
@@ -556,20 +1030,44 @@ func (t *Tracer) printDecodedCallData(l zerolog.Logger, dc *DecodedCall) {
 
 	l.Debug().Str("Method signature", dc.Signature).Send()
 	l.Debug().Str("Method name", dc.Method).Send()
+	if dc.Implementation != "" {
+		l.Debug().Str("Implementation", dc.Implementation).Msg("Decoded with ABI of proxy's implementation")
+	}
 	l.Debug().Str("Gas used/limit", fmt.Sprintf("%d/%d", dc.GasUsed, dc.GasLimit)).Send()
 	l.Debug().Str("Gas left", fmt.Sprintf("%d", dc.GasLimit-dc.GasUsed)).Send()
 	if dc.Comment != "" {
 		l.Debug().Str("Comment", dc.Comment).Send()
 	}
 	if dc.Input != nil {
-		l.Debug().Interface("Inputs", dc.Input).Send()
+		l.Debug().Interface("Inputs", t.Cfg.DecodedOutput.Limit(dc.Input)).Send()
+	}
+	for arg, nc := range dc.NestedCalls {
+		l.Debug().Str("Argument", arg).Str("Contract", nc.Contract).Str("Method", nc.Method).Interface("Inputs", t.Cfg.DecodedOutput.Limit(nc.Input)).Msg("Nested call")
 	}
 	if dc.Output != nil {
-		l.Debug().Interface("Outputs", dc.Output).Send()
+		l.Debug().Interface("Outputs", t.Cfg.DecodedOutput.Limit(dc.Output)).Send()
 	}
 	for _, e := range dc.Events {
 		l.Debug().
 			Str("Signature", e.Signature).
-			Interface("Log", e.EventData).Send()
+			Interface("Log", t.Cfg.DecodedOutput.Limit(e.EventData)).Send()
+	}
+	for _, it := range dc.InternalTransfers {
+		l.Debug().
+			Str("Type", it.Type).
+			Str("Transfer", fmt.Sprintf("%s -> %s", it.From, it.To)).
+			Str("Transfer address", fmt.Sprintf("%s -> %s", it.FromAddress, it.ToAddress)).
+			Str("Amount (wei/ether)", FormatWei(it.Amount)).
+			Msg("Internal transfer")
+	}
+	for _, bc := range dc.BalanceChanges {
+		l.Debug().
+			Str("Account", bc.Name).
+			Str("Address", bc.Address).
+			Str("Sent (wei/ether)", FormatWei(bc.Sent)).
+			Str("Received (wei/ether)", FormatWei(bc.Received)).
+			Str("Change (wei/ether)", FormatWei(bc.Change)).
+			Msg("Balance change")
 	}
+	t.printStateDiff(l, dc.StateDiff)
 }
@@ -2,7 +2,10 @@ package seth_test
 
 import (
 	"context"
+	"encoding/json"
+	"math/big"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/smartcontractkit/seth"
@@ -30,3 +33,39 @@ func TestCLITracing(t *testing.T) {
 	err = sethcmd.RunCLI([]string{"seth", "-n", "Geth", "trace", "-f", file.Name()})
 	require.NoError(t, err, "should have traced transactions")
 }
+
+func TestCLITraceDiff(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+	SkipAnvil(t, c)
+
+	c.Cfg.TracingLevel = seth.TracingLevel_All
+	tx, err := c.Decode(TestEnv.DebugContract.Trace(c.NewTXOpts(), big.NewInt(2), big.NewInt(4)))
+	require.NoError(t, err, "failed to decode transaction")
+	tree, err := c.Tracer.DecodeCallTree(tx.Hash)
+	require.NoError(t, err, "failed to decode call tree")
+
+	dir := t.TempDir()
+	writeJson := func(name string, v interface{}) string {
+		data, err := json.Marshal(v)
+		require.NoError(t, err, "failed to marshal trace")
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, data, 0600), "failed to write trace")
+		return path
+	}
+	// decoded calls saved with 'trace_to_json' and call tree of the same transaction
+	callsFile := writeJson("calls.json", c.Tracer.DecodedCalls[tx.Hash])
+	treeFile := writeJson("tree.json", tree)
+
+	traceA, err := seth.ReadCallTree(callsFile)
+	require.NoError(t, err, "failed to read decoded calls")
+	traceB, err := seth.ReadCallTree(treeFile)
+	require.NoError(t, err, "failed to read call tree")
+	require.Empty(t, seth.CompareTraces(traceA, traceB), "traces of the same transaction should be identical")
+
+	// network isn't needed to compare traces
+	err = sethcmd.RunCLI([]string{"seth", "trace", "diff", "-f", callsFile, "-f2", treeFile})
+	require.NoError(t, err, "should have compared traces")
+
+	_, err = seth.ReadCallTree(filepath.Join(dir, "missing.json"))
+	require.Error(t, err, "missing file should be reported")
+}
@@ -0,0 +1,91 @@
+package seth
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrTracingLevelOverride = errors.New("tracing level override must be one of: NONE, REVERTED, ALL")
+)
+
+func isValidTracingLevel(level string) bool {
+	switch level {
+	case TracingLevel_None, TracingLevel_Reverted, TracingLevel_All:
+		return true
+	default:
+		return false
+	}
+}
+
+// validateTracingLevelOverrides uppercases override levels and lowercases address keys, so that they can be looked up
+// without normalisation of each transaction's address
+func validateTracingLevelOverrides(cfg *Config) error {
+	if len(cfg.TracingLevelOverrides) == 0 {
+		return nil
+	}
+	overrides := make(map[string]string, len(cfg.TracingLevelOverrides))
+	for key, level := range cfg.TracingLevelOverrides {
+		level = strings.ToUpper(level)
+		if !isValidTracingLevel(level) {
+			return errors.Wrapf(ErrTracingLevelOverride, "contract: '%s'", key)
+		}
+		if common.IsHexAddress(key) {
+			key = strings.ToLower(key)
+		}
+		overrides[key] = level
+	}
+	cfg.TracingLevelOverrides = overrides
+	return nil
+}
+
+// TracingLevelFor returns tracing level of transactions sent to the contract. Override set for contract's address takes
+// precedence over the one set for its name, if there's none global 'tracing_level' is returned.
+func (c *Config) TracingLevelFor(address, contractName string) string {
+	if level, ok := c.TracingLevelOverrides[strings.ToLower(address)]; ok && address != "" {
+		return level
+	}
+	if level, ok := c.TracingLevelOverrides[contractName]; ok && contractName != "" {
+		return level
+	}
+	return c.TracingLevel
+}
+
+// tracingEnabled returns true if any transaction can be traced, either because of global level or one of overrides
+func (c *Config) tracingEnabled() bool {
+	if c.TracingLevel != TracingLevel_None {
+		return true
+	}
+	for _, level := range c.TracingLevelOverrides {
+		if level != TracingLevel_None {
+			return true
+		}
+	}
+	return false
+}
+
+// disableTracing turns off tracing of all transactions, including contracts with overridden tracing level
+func (c *Config) disableTracing() {
+	c.TracingLevel = TracingLevel_None
+	c.TracingLevelOverrides = nil
+}
+
+// tracingLevelForTx returns tracing level of the transaction based on the called or deployed contract
+func (m *Client) tracingLevelForTx(tx *types.Transaction, receipt *types.Receipt) string {
+	if len(m.Cfg.TracingLevelOverrides) == 0 {
+		return m.Cfg.TracingLevel
+	}
+	var address common.Address
+	switch {
+	case tx.To() != nil:
+		address = *tx.To()
+	case receipt != nil:
+		address = receipt.ContractAddress
+	default:
+		return m.Cfg.TracingLevel
+	}
+	return m.Cfg.TracingLevelFor(address.Hex(), m.ContractAddressToNameMap.GetContractName(address.Hex()))
+}
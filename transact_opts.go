@@ -0,0 +1,70 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNilTransactOpts = errors.New("transaction options are nil")
+)
+
+// CheckTransactOpts returns error set in transaction options' context (see ContextErrorKey). Seth never returns nil options,
+// because RPC wrappers would panic, instead it sets the error in the context and such options can't sign any transaction.
+// Once the error is returned, it's removed from client's error collector, so that Decode() doesn't return it again.
+func CheckTransactOpts(opts *bind.TransactOpts) error {
+	if opts == nil {
+		return ErrNilTransactOpts
+	}
+	if opts.Context == nil {
+		return nil
+	}
+	if err, ok := opts.Context.Value(ContextErrorKey{}).(error); ok {
+		if collector, ok := opts.Context.Value(errorCollectorKey{}).(*ErrorCollector); ok {
+			collector.discard(err)
+		}
+		return wrapError(err, ErrTransactOptsWithError)
+	}
+	return nil
+}
+
+// checkTransactOpts works like CheckTransactOpts, but panics if `strict_transact_opts` is enabled, so that usage of options
+// with an error fails loudly in tests
+func (m *Client) checkTransactOpts(opts *bind.TransactOpts) error {
+	err := CheckTransactOpts(opts)
+	if err != nil && m.Cfg.StrictTransactOpts {
+		panic(fmt.Sprintf("strict_transact_opts is enabled and %s", err.Error()))
+	}
+	return err
+}
+
+// guardTransactOpts replaces signer of transaction options with an error set in their context with one that returns that
+// error, so that transaction can't be sent with them and the error is returned by the contract wrapper. Nonce, gas price and
+// gas limit are set, so that wrapper doesn't query the node (e.g. estimate gas, which might fail with a different error)
+// before calling the signer.
+func (m *Client) guardTransactOpts(opts *bind.TransactOpts) *bind.TransactOpts {
+	if _, ok := contextOrBackground(opts.Context).Value(ContextErrorKey{}).(error); !ok {
+		return opts
+	}
+	// error is removed from the collector, once it's returned by CheckTransactOpts
+	opts.Context = context.WithValue(opts.Context, errorCollectorKey{}, m.ErrorCollector)
+	if opts.Nonce == nil {
+		opts.Nonce = big.NewInt(0)
+	}
+	if opts.GasLimit == 0 {
+		opts.GasLimit = 1
+	}
+	opts.GasPrice = big.NewInt(0)
+	opts.GasFeeCap = nil
+	opts.GasTipCap = nil
+	opts.Signer = func(_ common.Address, _ *types.Transaction) (*types.Transaction, error) {
+		return nil, m.checkTransactOpts(opts)
+	}
+	return opts
+}
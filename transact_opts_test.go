@@ -0,0 +1,50 @@
+package seth_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPICheckTransactOpts(t *testing.T) {
+	c := newClient(t)
+
+	require.NoError(t, seth.CheckTransactOpts(c.NewTXOpts()), "valid options should have no error")
+	require.ErrorIs(t, seth.CheckTransactOpts(nil), seth.ErrNilTransactOpts, "nil options should have an error")
+
+	opts := c.NewTXKeyOpts(len(c.Addresses) + 1)
+	err := seth.CheckTransactOpts(opts)
+	require.Error(t, err, "options for key out of range should have an error")
+	require.ErrorIs(t, err, seth.ErrTransactOptsWithError, "incorrect error")
+	require.Contains(t, err.Error(), "keyNum is out of range", "error should contain the original error")
+
+	_, err = TestEnv.DebugContract.Set(opts, big.NewInt(1))
+	require.Error(t, err, "options with an error should not sign the transaction")
+	require.ErrorIs(t, err, seth.ErrTransactOptsWithError, "incorrect error")
+
+	_, err = c.Decode(TestEnv.DebugContract.Set(opts, big.NewInt(1)))
+	require.Error(t, err, "decode should surface error of options")
+	require.Contains(t, err.Error(), "keyNum is out of range", "incorrect error")
+}
+
+func TestAPIStrictTransactOpts(t *testing.T) {
+	cfg, err := seth.ReadConfig()
+	require.NoError(t, err, "failed to read config")
+	cfg.StrictTransactOpts = true
+
+	c, err := seth.NewClientWithConfig(cfg)
+	require.NoError(t, err, "failed to initalise seth")
+
+	opts := c.NewTXKeyOpts(len(c.Addresses) + 1)
+	require.Panics(t, func() {
+		_, _ = TestEnv.DebugContract.Set(opts, big.NewInt(1))
+	}, "using options with an error should panic in strict mode")
+
+	abi, ok := c.ContractStore.GetABI("NetworkDebugSubContract")
+	require.True(t, ok, "ABI not found")
+	require.Panics(t, func() {
+		_, _ = c.DeployContract(opts, "NetworkDebugSubContract", *abi, []byte{})
+	}, "deploying with options with an error should panic in strict mode")
+}
@@ -0,0 +1,150 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+// TransferFees are fees of a native token transfer, GasPrice is used for legacy transactions, GasFeeCap and GasTipCap
+// for dynamic fee (EIP-1559) ones
+type TransferFees struct {
+	GasPrice  *big.Int
+	GasFeeCap *big.Int
+	GasTipCap *big.Int
+}
+
+// DynamicFees returns true if transfer is sent as dynamic fee transaction
+func (f TransferFees) DynamicFees() bool {
+	return f.GasFeeCap != nil
+}
+
+// MaxGasPrice returns the highest price per gas the transfer can pay, i.e. fee cap of dynamic fee transaction or gas price
+// of legacy one, so that cost calculated with it is never lower than the actual one
+func (f TransferFees) MaxGasPrice() *big.Int {
+	if f.DynamicFees() {
+		return f.GasFeeCap
+	}
+	return f.GasPrice
+}
+
+// SuggestedTransferFees returns fees of transfers suggested for standard priority, dynamic fees are used, if network
+// has them enabled. If suggestion fails, fees from network config are used.
+func (m *Client) SuggestedTransferFees(ctx context.Context) TransferFees {
+	if m.Cfg.Network.EIP1559DynamicFees {
+		feeCap, tipCap, err := m.GetSuggestedEIP1559Fees(ctx, Priority_Standard)
+		if err != nil {
+			m.logger().Warn().Err(err).Msg("Failed to get suggested EIP-1559 fees for transfer, using fees from config")
+			return m.configTransferFees()
+		}
+		return TransferFees{GasFeeCap: feeCap, GasTipCap: tipCap}
+	}
+	gasPrice, err := m.GetSuggestedLegacyFees(ctx, Priority_Standard)
+	if err != nil {
+		return m.configTransferFees()
+	}
+	return TransferFees{GasPrice: gasPrice}
+}
+
+// configTransferFees returns transfer fees from network config
+func (m *Client) configTransferFees() TransferFees {
+	if m.Cfg.Network.EIP1559DynamicFees {
+		return TransferFees{GasFeeCap: big.NewInt(m.Cfg.Network.GasFeeCap), GasTipCap: big.NewInt(m.Cfg.Network.GasTipCap)}
+	}
+	return TransferFees{GasPrice: big.NewInt(m.Cfg.Network.GasPrice)}
+}
+
+// transferFeesFromGasPrice returns fees of a transfer sent with given gas price, which is used as fee cap, if network
+// has dynamic fees enabled (tip is the configured one, but not higher than the cap). Nil means fees from network config.
+func (m *Client) transferFeesFromGasPrice(gasPrice *big.Int) TransferFees {
+	if gasPrice == nil {
+		return m.configTransferFees()
+	}
+	if !m.Cfg.Network.EIP1559DynamicFees {
+		return TransferFees{GasPrice: gasPrice}
+	}
+	tipCap := big.NewInt(m.Cfg.Network.GasTipCap)
+	if tipCap.Cmp(gasPrice) > 0 {
+		tipCap = new(big.Int).Set(gasPrice)
+	}
+	return TransferFees{GasFeeCap: gasPrice, GasTipCap: tipCap}
+}
+
+// newTransferTx returns unsigned legacy or dynamic fee transfer transaction, depending on fees
+func (m *Client) newTransferTx(nonce uint64, to common.Address, value *big.Int, gasLimit uint64, fees TransferFees) *types.Transaction {
+	if fees.DynamicFees() {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:   big.NewInt(m.ChainID),
+			Nonce:     nonce,
+			To:        &to,
+			Value:     value,
+			Gas:       gasLimit,
+			GasFeeCap: fees.GasFeeCap,
+			GasTipCap: fees.GasTipCap,
+		})
+	}
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		To:       &to,
+		Value:    value,
+		Gas:      gasLimit,
+		GasPrice: fees.GasPrice,
+	})
+}
+
+// TransferETHFromKeyWithFees is the same as TransferETHFromKey, but transfer is sent with given fees, as dynamic fee
+// transaction, if fee cap is set, or legacy one otherwise
+func (m *Client) TransferETHFromKeyWithFees(ctx context.Context, fromKeyNum int, to string, value *big.Int, fees TransferFees) error {
+	if fromKeyNum > len(m.PrivateKeys) || fromKeyNum > len(m.Addresses) {
+		return errors.Wrap(ErrNoKeyLoaded, fmt.Sprintf("requested key: %d", fromKeyNum))
+	}
+	if err := m.waitForGasSpikeBreaker(ctx); err != nil {
+		return err
+	}
+	if err := m.ensureKeyFunded(ctx, fromKeyNum); err != nil {
+		return err
+	}
+	toAddr := common.HexToAddress(to)
+
+	ctx, cancel := context.WithTimeout(ctx, m.Cfg.Network.TxnTimeout.Duration())
+	defer cancel()
+	signedTx, err := m.sendETHFromKey(ctx, fromKeyNum, toAddr, value, fees)
+	if err != nil {
+		return err
+	}
+	l := m.logger().With().Str("Transaction", signedTx.Hash().Hex()).Logger()
+	l.Info().
+		Int("FromKeyNum", fromKeyNum).
+		Str("To", to).
+		Interface("Value", value).
+		Msg("Send ETH")
+	_, err = m.WaitMined(ctx, l, m.Client, signedTx)
+	return err
+}
+
+// sendETHFromKey takes the next nonce of the key, signs and sends the transfer without waiting for it to be mined
+func (m *Client) sendETHFromKey(ctx context.Context, fromKeyNum int, to common.Address, value *big.Int, fees TransferFees) (*types.Transaction, error) {
+	var gasLimit int64
+	gasLimitRaw, err := m.EstimateGasLimitForFundTransfer(m.Addresses[fromKeyNum], to, value)
+	if err != nil {
+		gasLimit = m.Cfg.Network.TransferGasFee
+	} else {
+		gasLimit = int64(gasLimitRaw)
+	}
+
+	rawTx := m.newTransferTx(m.NonceManager.NextNonce(m.Addresses[fromKeyNum]).Uint64(), to, value, uint64(gasLimit), fees)
+	m.logger().Debug().Interface("TransferTx", rawTx).Send()
+	signedTx, err := m.Signer.SignTx(ctx, m.Addresses[fromKeyNum], rawTx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign tx")
+	}
+	signedTx, err = m.SendTransaction(ctx, signedTx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send transaction")
+	}
+	return signedTx, nil
+}
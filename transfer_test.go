@@ -0,0 +1,56 @@
+package seth_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUtilTransferFees(t *testing.T) {
+	legacy := seth.TransferFees{GasPrice: big.NewInt(10)}
+	require.False(t, legacy.DynamicFees(), "fees without fee cap are legacy")
+	require.Equal(t, big.NewInt(10), legacy.MaxGasPrice(), "gas price should be used for legacy fees")
+
+	dynamic := seth.TransferFees{GasFeeCap: big.NewInt(30), GasTipCap: big.NewInt(2)}
+	require.True(t, dynamic.DynamicFees(), "fees with fee cap are dynamic")
+	require.Equal(t, big.NewInt(30), dynamic.MaxGasPrice(), "fee cap should be used for dynamic fees")
+}
+
+func TestAPITransferETHFromKeyDynamicFees(t *testing.T) {
+	c := newClient(t)
+	if !c.Capabilities().EIP1559 {
+		t.Skip("network doesn't support EIP-1559")
+	}
+	c.Cfg.Network.EIP1559DynamicFees = true
+
+	fees := c.SuggestedTransferFees(context.Background())
+	require.True(t, fees.DynamicFees(), "dynamic fees should be suggested")
+
+	from, err := c.Client.BlockNumber(context.Background())
+	require.NoError(t, err, "failed to get block number")
+
+	to := common.HexToAddress("0x0000000000000000000000000000000000000123")
+	err = c.TransferETHFromKeyWithFees(context.Background(), 0, to.Hex(), big.NewInt(1), fees)
+	require.NoError(t, err, "failed to transfer funds")
+
+	latest, err := c.Client.BlockNumber(context.Background())
+	require.NoError(t, err, "failed to get block number")
+
+	var transfer *types.Transaction
+	for bn := from; bn <= latest && transfer == nil; bn++ {
+		block, err := c.Client.BlockByNumber(context.Background(), new(big.Int).SetUint64(bn))
+		require.NoError(t, err, "failed to get block")
+		for _, tx := range block.Transactions() {
+			if tx.To() != nil && *tx.To() == to {
+				transfer = tx
+			}
+		}
+	}
+	require.NotNil(t, transfer, "transfer should be mined")
+	require.Equal(t, uint8(types.DynamicFeeTxType), transfer.Type(), "transfer should be a dynamic fee transaction")
+}
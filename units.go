@@ -0,0 +1,229 @@
+package seth
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/pkg/errors"
+)
+
+const (
+	UnitWei   = "wei"
+	UnitGwei  = "gwei"
+	UnitEther = "ether"
+)
+
+var (
+	ErrInvalidAmount = errors.New("invalid amount")
+	ErrFractionalWei = errors.New("amount is not a whole number of wei")
+)
+
+// unitSuffixes are checked in order, so that longer suffixes are matched first ("gwei" before "wei", "ether" before "eth")
+var unitSuffixes = []struct {
+	suffix     string
+	multiplier *big.Int
+}{
+	{UnitGwei, big.NewInt(params.GWei)},
+	{UnitWei, big.NewInt(params.Wei)},
+	{UnitEther, big.NewInt(params.Ether)},
+	{"eth", big.NewInt(params.Ether)},
+}
+
+// ParseAmount parses human-readable amount into wei. Amount is a decimal number with optional unit suffix (wei, gwei, eth or ether),
+// separated by optional whitespace, e.g. "1.5eth", "10 gwei" or "1000". Amounts without unit are in wei and can also be hex
+// numbers prefixed with "0x". Digits can be separated with underscores, e.g. "1_000 gwei". Amounts that aren't whole numbers
+// of wei are rejected.
+func ParseAmount(amount string) (*big.Int, error) {
+	return parseAmount(amount, UnitWei)
+}
+
+// parseAmount is the same as ParseAmount, but amounts without unit are in the default unit. Hex numbers are accepted only
+// if the default unit is wei.
+func parseAmount(amount string, defaultUnit string) (*big.Int, error) {
+	s, ok := removeDigitSeparators(strings.ToLower(strings.TrimSpace(amount)))
+	if !ok || s == "" {
+		return nil, errors.Wrapf(ErrInvalidAmount, "amount: '%s', expected a non-negative number with optional unit (wei, gwei, eth or ether), e.g. '1.5eth', '10 gwei' or '1000'", amount)
+	}
+	if strings.HasPrefix(s, "0x") {
+		value, ok := new(big.Int).SetString(s, 0)
+		if !ok || defaultUnit != UnitWei {
+			return nil, errors.Wrapf(ErrInvalidAmount, "amount: '%s', expected a non-negative number with optional unit (wei, gwei, eth or ether), e.g. '1.5eth', '10 gwei' or '1000'", amount)
+		}
+		return value, nil
+	}
+
+	number, multiplier := s, unitMultiplier(defaultUnit)
+	for _, u := range unitSuffixes {
+		if strings.HasSuffix(s, u.suffix) {
+			number = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			multiplier = u.multiplier
+			break
+		}
+	}
+	if number == "" || strings.ContainsAny(number, "/+") {
+		return nil, errors.Wrapf(ErrInvalidAmount, "amount: '%s', expected a non-negative number with optional unit (wei, gwei, eth or ether), e.g. '1.5eth', '10 gwei' or '1000'", amount)
+	}
+	r, ok := new(big.Rat).SetString(number)
+	if !ok || r.Sign() < 0 {
+		return nil, errors.Wrapf(ErrInvalidAmount, "amount: '%s', expected a non-negative number with optional unit (wei, gwei, eth or ether), e.g. '1.5eth', '10 gwei' or '1000'", amount)
+	}
+	r.Mul(r, new(big.Rat).SetInt(multiplier))
+	if !r.IsInt() {
+		return nil, errors.Wrapf(ErrFractionalWei, "amount: '%s'", amount)
+	}
+	return new(big.Int).Set(r.Num()), nil
+}
+
+// unitMultiplier returns number of wei in one unit
+func unitMultiplier(unit string) *big.Int {
+	for _, u := range unitSuffixes {
+		if u.suffix == unit {
+			return u.multiplier
+		}
+	}
+	return big.NewInt(params.Wei)
+}
+
+// removeDigitSeparators removes underscores separating digits (as in TOML integers), returns false if an underscore isn't
+// surrounded by digits
+func removeDigitSeparators(s string) (string, bool) {
+	if !strings.Contains(s, "_") {
+		return s, true
+	}
+	isDigit := func(i int) bool {
+		return i >= 0 && i < len(s) && s[i] >= '0' && s[i] <= '9'
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '_' {
+			if !isDigit(i-1) || !isDigit(i+1) {
+				return "", false
+			}
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String(), true
+}
+
+// Amount is an amount of wei in TOML config, which can be set either as a number of wei (e.g. 1_000_000_000) or as a string
+// with unit (e.g. "3 gwei" or "1.5 ether"), see ParseAmount. Invalid amounts fail unmarshalling of the config. It's kept as
+// a decimal number of wei, so that configs can be deep copied. Zero value is 0 wei.
+type Amount string
+
+// NewAmount returns amount of given wei
+func NewAmount(wei *big.Int) Amount {
+	if wei == nil {
+		return ""
+	}
+	return Amount(wei.String())
+}
+
+// MustParseAmount returns parsed amount (see ParseAmount) and panics if it's invalid
+func MustParseAmount(amount string) Amount {
+	wei, err := ParseAmount(amount)
+	if err != nil {
+		panic(err)
+	}
+	return NewAmount(wei)
+}
+
+// Wei returns the amount in wei, returned value is a copy and can be modified. Amounts, which weren't created by NewAmount,
+// MustParseAmount or unmarshalled, are parsed with ParseAmount and invalid ones are 0 wei.
+func (a Amount) Wei() *big.Int {
+	if a == "" {
+		return big.NewInt(0)
+	}
+	wei, err := ParseAmount(string(a))
+	if err != nil {
+		return big.NewInt(0)
+	}
+	return wei
+}
+
+// IsZero returns true if amount is 0 wei
+func (a Amount) IsZero() bool {
+	return a.Wei().Sign() == 0
+}
+
+func (a Amount) String() string {
+	return FormatWei(a.Wei())
+}
+
+func (a *Amount) UnmarshalText(text []byte) error {
+	wei, err := ParseAmount(string(text))
+	if err != nil {
+		return err
+	}
+	*a = NewAmount(wei)
+	return nil
+}
+
+// MarshalText returns amount with unit, so that it can't be mistaken for an amount in another default unit
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.Wei().String() + " " + UnitWei), nil
+}
+
+// EtherAmount is the same as Amount, but numbers without unit are in ether, e.g. 10 or 0.5 in TOML config mean 10 or 0.5 ether
+type EtherAmount struct{ Amount }
+
+// NewEtherAmount returns amount of given wei
+func NewEtherAmount(wei *big.Int) EtherAmount {
+	return EtherAmount{NewAmount(wei)}
+}
+
+func (a *EtherAmount) UnmarshalText(text []byte) error {
+	wei, err := parseAmount(string(text), UnitEther)
+	if err != nil {
+		return err
+	}
+	a.Amount = NewAmount(wei)
+	return nil
+}
+
+// FormatWei formats wei amount for logs and reports as "<wei> wei / <ether> ether"
+func FormatWei(wei *big.Int) string {
+	if wei == nil {
+		wei = big.NewInt(0)
+	}
+	return fmt.Sprintf("%s wei / %s ether", wei.String(), WeiToEther(wei).Text('f', -1))
+}
+
+// EtherToWei converts an ETH float amount to wei
+func EtherToWei(eth *big.Float) *big.Int {
+	truncInt, _ := eth.Int(nil)
+	truncInt = new(big.Int).Mul(truncInt, big.NewInt(params.Ether))
+	fracStr := strings.Split(fmt.Sprintf("%.18f", eth), ".")[1]
+	fracStr += strings.Repeat("0", 18-len(fracStr))
+	fracInt, _ := new(big.Int).SetString(fracStr, 10)
+	wei := new(big.Int).Add(truncInt, fracInt)
+	return wei
+}
+
+// WeiToEther converts a wei amount to eth float
+func WeiToEther(wei *big.Int) *big.Float {
+	return weiToUnit(wei, params.Ether)
+}
+
+// GweiToWei converts a gwei float amount to wei, fractions of wei are truncated
+func GweiToWei(gwei *big.Float) *big.Int {
+	wei, _ := new(big.Float).SetPrec(236).Mul(gwei, big.NewFloat(params.GWei)).Int(nil)
+	return wei
+}
+
+// WeiToGwei converts a wei amount to gwei float
+func WeiToGwei(wei *big.Int) *big.Float {
+	return weiToUnit(wei, params.GWei)
+}
+
+func weiToUnit(wei *big.Int, unit float64) *big.Float {
+	f := new(big.Float)
+	f.SetPrec(236) //  IEEE 754 octuple-precision binary floating-point format: binary256
+	f.SetMode(big.ToNearestEven)
+	fWei := new(big.Float)
+	fWei.SetPrec(236) //  IEEE 754 octuple-precision binary floating-point format: binary256
+	fWei.SetMode(big.ToNearestEven)
+	return f.Quo(fWei.SetInt(wei), big.NewFloat(unit))
+}
@@ -0,0 +1,116 @@
+package seth_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/barkimedes/go-deepcopy"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/smartcontractkit/seth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUtilParseAmount(t *testing.T) {
+	type test struct {
+		name     string
+		amount   string
+		expected string
+		err      error
+	}
+
+	tests := []test{
+		{name: "wei without unit", amount: "1000", expected: "1000"},
+		{name: "hex wei", amount: "0x3e8", expected: "1000"},
+		{name: "wei", amount: "1000wei", expected: "1000"},
+		{name: "gwei", amount: "10gwei", expected: "10000000000"},
+		{name: "fractional gwei", amount: "1.5 gwei", expected: "1500000000"},
+		{name: "eth", amount: "1.5eth", expected: "1500000000000000000"},
+		{name: "ether with whitespace and uppercase", amount: " 0.1 ETHER ", expected: "100000000000000000"},
+		{name: "smallest ether fraction", amount: "0.000000000000000001eth", expected: "1"},
+		{name: "fractional wei", amount: "1.5wei", err: seth.ErrFractionalWei},
+		{name: "too precise ether", amount: "0.0000000000000000001eth", err: seth.ErrFractionalWei},
+		{name: "negative", amount: "-1eth", err: seth.ErrInvalidAmount},
+		{name: "empty", amount: "", err: seth.ErrInvalidAmount},
+		{name: "unit only", amount: "gwei", err: seth.ErrInvalidAmount},
+		{name: "unknown unit", amount: "1 finney", err: seth.ErrInvalidAmount},
+		{name: "fraction", amount: "1/2eth", err: seth.ErrInvalidAmount},
+		{name: "invalid hex", amount: "0xzz", err: seth.ErrInvalidAmount},
+		{name: "digit separators", amount: "1_000 gwei", expected: "1000000000000"},
+		{name: "misplaced separator", amount: "1__000", err: seth.ErrInvalidAmount},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			amount, err := seth.ParseAmount(tc.amount)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err, "amount should be invalid")
+				require.Contains(t, err.Error(), "amount: '"+tc.amount+"'", "error should contain the amount")
+				return
+			}
+			require.NoError(t, err, "amount should be valid")
+			require.Equal(t, tc.expected, amount.String(), "amount in wei should match")
+		})
+	}
+}
+
+func TestUtilUnitConversions(t *testing.T) {
+	oneAndHalfEther, _ := new(big.Int).SetString("1500000000000000000", 10)
+
+	require.Equal(t, oneAndHalfEther.String(), seth.EtherToWei(big.NewFloat(1.5)).String(), "ether should be converted to wei")
+	require.Equal(t, "1.5", seth.WeiToEther(oneAndHalfEther).Text('f', -1), "wei should be converted to ether")
+	require.Equal(t, "2500000000", seth.GweiToWei(big.NewFloat(2.5)).String(), "gwei should be converted to wei")
+	require.Equal(t, "2.5", seth.WeiToGwei(big.NewInt(2500000000)).Text('f', -1), "wei should be converted to gwei")
+	require.Equal(t, "1500000000000000000 wei / 1.5 ether", seth.FormatWei(oneAndHalfEther), "wei should be formatted")
+	require.Equal(t, "0 wei / 0 ether", seth.FormatWei(nil), "nil should be formatted as zero")
+}
+
+func TestConfigAmounts(t *testing.T) {
+	var cfg *seth.Config
+	err := toml.Unmarshal([]byte(`
+root_key_funds_buffer_amount = 0.5
+[network]
+gas_price = 1_000_000_000
+gas_fee_cap_amount = 30_000_000_000
+gas_tip_cap_amount = "3 gwei"
+`), &cfg)
+	require.NoError(t, err, "amounts should be valid")
+	require.Equal(t, "500000000000000000", cfg.RootKeyFundsBufferAmount.Wei().String(), "number should be in ether")
+	require.NoError(t, seth.ValidateConfig(cfg), "config should be valid")
+	require.Equal(t, int64(1_000_000_000), cfg.Network.GasPrice, "number should be in wei")
+	require.Equal(t, int64(30_000_000_000), cfg.Network.GasFeeCap, "number amount should be in wei")
+	require.Equal(t, int64(3_000_000_000), cfg.Network.GasTipCap, "amount with unit should be converted to wei")
+
+	copied := deepcopy.MustAnything(cfg).(*seth.Config)
+	require.Equal(t, cfg.RootKeyFundsBufferAmount.Wei().String(), copied.RootKeyFundsBufferAmount.Wei().String(), "copied amount should be the same")
+	require.Equal(t, cfg.Network.GasTipCapAmount, copied.Network.GasTipCapAmount, "copied amount should be the same")
+
+	cfg.Network.GasPriceAmount = seth.MustParseAmount("2 gwei")
+	require.Error(t, seth.ValidateConfig(cfg), "different gas prices should be rejected")
+	cfg.Network.GasPriceAmount = seth.MustParseAmount("1 gwei")
+	require.NoError(t, seth.ValidateConfig(cfg), "the same gas prices should be valid")
+	cfg.Network.GasPriceAmount = seth.MustParseAmount("10 ether")
+	require.Error(t, seth.ValidateConfig(cfg), "gas price, which doesn't fit into int64, should be rejected")
+
+	type amounts struct {
+		GasPrice seth.Amount       `toml:"gas_price"`
+		Buffer   *seth.EtherAmount `toml:"root_key_funds_buffer"`
+	}
+	var a amounts
+	err = toml.Unmarshal([]byte(`root_key_funds_buffer = "10 gwei"`), &a)
+	require.NoError(t, err, "ether amount with unit should be valid")
+	require.Equal(t, "10000000000", a.Buffer.Wei().String(), "unit should override ether")
+
+	marshalled, err := toml.Marshal(a)
+	require.NoError(t, err, "amounts should be marshalled")
+	var unmarshalled amounts
+	require.NoError(t, toml.Unmarshal(marshalled, &unmarshalled), "marshalled amounts should be valid")
+	require.Equal(t, a.Buffer.Wei().String(), unmarshalled.Buffer.Wei().String(), "marshalled amount should keep its unit")
+
+	for _, invalid := range []string{`gas_price = -1`, `gas_price = 1.5`, `gas_price = "1 finney"`, `root_key_funds_buffer = "0x10"`} {
+		require.Error(t, toml.Unmarshal([]byte(invalid), &a), "'%s' should be rejected", invalid)
+	}
+
+	var zero seth.Amount
+	require.True(t, zero.IsZero(), "zero value should be zero")
+	require.Equal(t, "0", zero.Wei().String(), "zero value should be 0 wei")
+}
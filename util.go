@@ -9,6 +9,7 @@ import (
 	"io"
 	"math/big"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -22,9 +23,9 @@ import (
 	network_sub_debug_contract "github.com/smartcontractkit/seth/contracts/bind/sub"
 )
 
-const (
-	ErrEmptyKeyFile               = "keyfile is empty"
-	ErrInsufficientRootKeyBalance = "insufficient root key balance: %s"
+var (
+	ErrEmptyKeyFile               = errors.New("keyfile is empty")
+	ErrInsufficientRootKeyBalance = errors.New("insufficient root key balance")
 )
 
 // KeyFile is a struct that holds all test keys data
@@ -37,6 +38,8 @@ type KeyData struct {
 	PrivateKey string `toml:"private_key"`
 	Address    string `toml:"address"`
 	Funds      string `toml:"funds"`
+	// TokenFunds are balances of network's 'ephemeral_tokens' keyed by token address
+	TokenFunds map[string]string `toml:"token_funds,omitempty"`
 }
 
 // FundKeyFileCmdOpts funding params for CLI
@@ -69,8 +72,14 @@ func NewEphemeralKeys(addrs int64) ([]string, error) {
 	return privKeys, nil
 }
 
-// CalculateSubKeyFunding calculates all required params to split funds from the root key to N test keys
+// CalculateSubKeyFunding calculates all required params to split funds from the root key to N test keys. Gas price is
+// the highest price per gas transfers can pay, i.e. fee cap on networks with dynamic fees (see TransferFees.MaxGasPrice)
 func (m *Client) CalculateSubKeyFunding(addrs, gasPrice, rooKeyBuffer int64) (*FundingDetails, error) {
+	return m.calculateSubKeyFunding(addrs, big.NewInt(gasPrice), new(big.Int).Mul(big.NewInt(rooKeyBuffer), big.NewInt(params.Ether)))
+}
+
+// calculateSubKeyFunding is the same as CalculateSubKeyFunding, but gas price and root key buffer are in wei
+func (m *Client) calculateSubKeyFunding(addrs int64, gasPrice, rootKeyBuffer *big.Int) (*FundingDetails, error) {
 	balance, err := m.Client.BalanceAt(context.Background(), m.Addresses[0], nil)
 	if err != nil {
 		return nil, err
@@ -85,33 +94,50 @@ func (m *Client) CalculateSubKeyFunding(addrs, gasPrice, rooKeyBuffer int64) (*F
 		}
 	}
 
-	networkTransferFee := gasPrice * gasLimit
-	totalFee := new(big.Int).Mul(big.NewInt(networkTransferFee), big.NewInt(addrs))
-	rootKeyBuffer := new(big.Int).Mul(big.NewInt(rooKeyBuffer), big.NewInt(1_000_000_000_000_000_000))
+	// on OP-stack chains every transfer also pays L1 data fee
+	to := common.HexToAddress(newAddress)
+	l1TransferFee, err := m.estimateL1Fee(context.Background(), &to, new(big.Int).Quo(balance, big.NewInt(addrs)), nil, uint64(gasLimit))
+	if err != nil {
+		return nil, err
+	}
+	networkTransferFee := new(big.Int).Mul(gasPrice, big.NewInt(gasLimit))
+	networkTransferFee.Add(networkTransferFee, l1TransferFee)
+	totalFee := new(big.Int).Mul(networkTransferFee, big.NewInt(addrs))
+	// each key also receives every configured token, fees of these transfers are paid by the root key
+	tokenTransfers := int64(len(m.Cfg.Network.EphemeralTokens)) * addrs
+	tokenTransferFee := new(big.Int).Mul(gasPrice, big.NewInt(erc20TransferGasLimit))
+	if tokenTransfers > 0 {
+		l1TokenTransferFee, err := m.estimateL1TokenTransferFee(to)
+		if err != nil {
+			return nil, err
+		}
+		tokenTransferFee.Add(tokenTransferFee, l1TokenTransferFee)
+	}
+	totalFee.Add(totalFee, new(big.Int).Mul(tokenTransferFee, big.NewInt(tokenTransfers)))
 	freeBalance := new(big.Int).Sub(balance, big.NewInt(0).Add(totalFee, rootKeyBuffer))
 
-	L.Info().
-		Str("Balance (wei/ether)", fmt.Sprintf("%s/%s", balance.String(), WeiToEther(balance).Text('f', -1))).
-		Str("Total fee (wei/ether)", fmt.Sprintf("%s/%s", totalFee.String(), WeiToEther(totalFee).Text('f', -1))).
-		Str("Free Balance (wei/ether)", fmt.Sprintf("%s/%s", freeBalance.String(), WeiToEther(freeBalance).Text('f', -1))).
-		Str("Buffer (wei/ether)", fmt.Sprintf("%s/%s", rootKeyBuffer.String(), WeiToEther(rootKeyBuffer).Text('f', -1))).
+	m.logger().Info().
+		Str("Balance (wei/ether)", FormatWei(balance)).
+		Str("Total fee (wei/ether)", FormatWei(totalFee)).
+		Str("Free Balance (wei/ether)", FormatWei(freeBalance)).
+		Str("Buffer (wei/ether)", FormatWei(rootKeyBuffer)).
 		Msg("Root key balance")
 
 	if freeBalance.Cmp(big.NewInt(0)) < 0 {
-		return nil, errors.New(fmt.Sprintf(ErrInsufficientRootKeyBalance, freeBalance.String()))
+		return nil, errors.Wrapf(ErrInsufficientRootKeyBalance, "free balance: %s", freeBalance.String())
 	}
 
 	addrFunding := new(big.Int).Div(freeBalance, big.NewInt(addrs))
 	requiredBalance := big.NewInt(0).Mul(addrFunding, big.NewInt(addrs))
 
-	L.Debug().
-		Str("Funding per ephemeral key (wei/ether)", fmt.Sprintf("%s/%s", addrFunding.String(), WeiToEther(addrFunding).Text('f', -1))).
-		Str("Available balance (wei/ether)", fmt.Sprintf("%s/%s", freeBalance.String(), WeiToEther(freeBalance).Text('f', -1))).
-		Interface("Required balance (wei/ether)", fmt.Sprintf("%s/%s", requiredBalance.String(), WeiToEther(requiredBalance).Text('f', -1))).
+	m.logger().Debug().
+		Str("Funding per ephemeral key (wei/ether)", FormatWei(addrFunding)).
+		Str("Available balance (wei/ether)", FormatWei(freeBalance)).
+		Interface("Required balance (wei/ether)", FormatWei(requiredBalance)).
 		Msg("Using hardcoded ephemeral funding")
 
 	if freeBalance.Cmp(requiredBalance) < 0 {
-		return nil, errors.New(fmt.Sprintf(ErrInsufficientRootKeyBalance, freeBalance.String()))
+		return nil, errors.Wrapf(ErrInsufficientRootKeyBalance, "free balance: %s", freeBalance.String())
 	}
 
 	bd := &FundingDetails{
@@ -119,13 +145,13 @@ func (m *Client) CalculateSubKeyFunding(addrs, gasPrice, rooKeyBuffer int64) (*F
 		TotalFee:           totalFee,
 		FreeBalance:        freeBalance,
 		AddrFunding:        addrFunding,
-		NetworkTransferFee: networkTransferFee,
+		NetworkTransferFee: networkTransferFee.Int64(),
 	}
-	L.Info().
+	m.logger().Info().
 		Interface("RootBalance", bd.RootBalance.String()).
 		Interface("RootKeyBuffer", rootKeyBuffer.String()).
 		Interface("TransferFeesTotal", bd.TotalFee.String()).
-		Interface("NetworkTransferFee", bd.NetworkTransferFee).
+		Interface("NetworkTransferFee", networkTransferFee.String()).
 		Interface("FreeBalance", bd.FreeBalance.String()).
 		Interface("EachAddrGets", bd.AddrFunding.String()).
 		Msg("Splitting funds from the root account")
@@ -143,7 +169,7 @@ const (
 func (m *Client) CreateOrUnmarshalKeyFile(opts *FundKeyFileCmdOpts) (*KeyFile, KeyfileStatus, error) {
 	if opts.LocalKeyfile {
 		if _, err := os.Stat(m.Cfg.KeyFilePath); os.IsNotExist(err) {
-			L.Info().
+			m.logger().Info().
 				Str("Path", m.Cfg.KeyFilePath).
 				Interface("Opts", opts).
 				Msg("Creating a new key file")
@@ -161,7 +187,7 @@ func (m *Client) CreateOrUnmarshalKeyFile(opts *FundKeyFileCmdOpts) (*KeyFile, K
 			}
 			return kf, NewKeyfile, nil
 		} else {
-			L.Info().
+			m.logger().Info().
 				Str("Path", m.Cfg.KeyFilePath).
 				Interface("Opts", opts).
 				Msg("Loading keyfile. Ignoring addresses-related opts")
@@ -176,14 +202,14 @@ func (m *Client) CreateOrUnmarshalKeyFile(opts *FundKeyFileCmdOpts) (*KeyFile, K
 			}
 
 			if kf == nil || len(kf.Keys) == 0 {
-				return nil, false, errors.New(ErrEmptyKeyFile)
+				return nil, false, ErrEmptyKeyFile
 			}
 			return kf, ExistingKeyfile, nil
 		}
 	} else {
 		existsIn1Pass, err := ExistsIn1Pass(m, opts.VaultId)
 		if err != nil {
-			L.Error().Err(err).Msg("error trying to check if keyfile exists in 1Password")
+			m.logger().Error().Err(err).Msg("error trying to check if keyfile exists in 1Password")
 			return nil, false, err
 		}
 
@@ -212,14 +238,14 @@ func (m *Client) DeployDebugSubContract() (*network_sub_debug_contract.NetworkDe
 	if err != nil {
 		return nil, common.Address{}, err
 	}
-	L.Info().
+	m.logger().Info().
 		Str("Address", address.Hex()).
 		Str("TXHash", tx.Hash().Hex()).
 		Msg("Deploying sub-debug contract")
 	if _, err := bind.WaitDeployed(context.Background(), m.Client, tx); err != nil {
 		return nil, common.Address{}, err
 	}
-	L.Info().
+	m.logger().Info().
 		Str("Address", address.Hex()).
 		Str("TXHash", tx.Hash().Hex()).
 		Msg("Sub-debug contract deployed")
@@ -231,14 +257,14 @@ func (m *Client) DeployDebugContract(subDbgAddr common.Address) (*network_debug_
 	if err != nil {
 		return nil, common.Address{}, err
 	}
-	L.Info().
+	m.logger().Info().
 		Str("Address", address.Hex()).
 		Str("TXHash", tx.Hash().Hex()).
 		Msg("Deploying debug contract")
 	if _, err := bind.WaitDeployed(context.Background(), m.Client, tx); err != nil {
 		return nil, common.Address{}, err
 	}
-	L.Info().
+	m.logger().Info().
 		Str("Address", address.Hex()).
 		Str("TXHash", tx.Hash().Hex()).
 		Msg("Debug contract deployed")
@@ -358,22 +384,42 @@ func (d *Duration) UnmarshalText(input []byte) error {
 }
 
 func saveAsJson(v any, dirName, name string) (string, error) {
-	pwd, err := os.Getwd()
-	if err != nil {
-		return "", err
-	}
-	dir := fmt.Sprintf("%s/%s", pwd, dirName)
-	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
-		err := os.Mkdir(dir, os.ModePerm)
+	f, _ := json.MarshalIndent(v, "", "   ")
+	return writeJsonFile(f, dirName, name, false)
+}
+
+// writeJsonFile writes data to 'name.json' file in the directory (absolute or relative to working directory), creating it
+// if needed. Name can contain subdirectories. If fsync is true file is synced before it's closed.
+func writeJsonFile(data []byte, dirName, name string, fsync bool) (string, error) {
+	dir := dirName
+	if !filepath.IsAbs(dir) {
+		pwd, err := os.Getwd()
 		if err != nil {
 			return "", err
 		}
+		dir = filepath.Join(pwd, dirName)
+	}
+	confPath := filepath.Join(dir, name+".json")
+	if err := os.MkdirAll(filepath.Dir(confPath), os.ModePerm); err != nil {
+		return "", err
+	}
+	if !fsync {
+		return confPath, os.WriteFile(confPath, data, 0600)
 	}
-	confPath := fmt.Sprintf("%s/%s.json", dir, name)
-	f, _ := json.MarshalIndent(v, "", "   ")
-	err = os.WriteFile(confPath, f, 0600)
 
-	return confPath, err
+	f, err := os.OpenFile(confPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	return confPath, f.Close()
 }
 
 func OpenJsonFileAsStruct(path string, v any) error {
@@ -422,28 +468,6 @@ func CreateOrAppendToJsonArray(filePath string, newItem any) error {
 	return err
 }
 
-// EtherToWei converts an ETH float amount to wei
-func EtherToWei(eth *big.Float) *big.Int {
-	truncInt, _ := eth.Int(nil)
-	truncInt = new(big.Int).Mul(truncInt, big.NewInt(params.Ether))
-	fracStr := strings.Split(fmt.Sprintf("%.18f", eth), ".")[1]
-	fracStr += strings.Repeat("0", 18-len(fracStr))
-	fracInt, _ := new(big.Int).SetString(fracStr, 10)
-	wei := new(big.Int).Add(truncInt, fracInt)
-	return wei
-}
-
-// WeiToEther converts a wei amount to eth float
-func WeiToEther(wei *big.Int) *big.Float {
-	f := new(big.Float)
-	f.SetPrec(236) //  IEEE 754 octuple-precision binary floating-point format: binary256
-	f.SetMode(big.ToNearestEven)
-	fWei := new(big.Float)
-	fWei.SetPrec(236) //  IEEE 754 octuple-precision binary floating-point format: binary256
-	fWei.SetMode(big.ToNearestEven)
-	return f.Quo(fWei.SetInt(wei), big.NewFloat(params.Ether))
-}
-
 const (
 	MetadataNotFoundErr       = "metadata section not found"
 	InvalidMetadataLengthErr  = "invalid metadata length"
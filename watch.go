@@ -0,0 +1,225 @@
+package seth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	// WatchActivity_Transaction is a transaction sent from or to the watched address
+	WatchActivity_Transaction = "transaction"
+	// WatchActivity_Event is an event emitted by the watched address or by a transaction sent from or to it
+	WatchActivity_Event = "event"
+)
+
+var (
+	ErrWatchFetchBlock = errors.New("failed to fetch block")
+)
+
+// WatchActivity is a single transaction or event involving watched address
+type WatchActivity struct {
+	Type        string
+	BlockNumber uint64
+	TxHash      string
+	// From, To, Value, Method and Status are set only for transactions
+	From  string
+	To    string
+	Value *big.Int
+	// Method is the signature of called method, if ABI of called contract is known
+	Method string
+	Status uint64
+	// Contract is the name of called contract or contract that emitted the event, if it's known
+	Contract string
+	// Event is set only for events, if it couldn't be decoded only its address and topics are set
+	Event *DecodedTransactionLog
+}
+
+// WatchAddress streams transactions sent from or to the address and events involving it, starting with fromBlock (or latest
+// block if it's 0), until context is done. New blocks are received via subscription (which is resubscribed after connection
+// drops), if client is connected over websocket, otherwise node is polled. Transactions are followed by their events.
+func (m *Client) WatchAddress(ctx context.Context, address common.Address, fromBlock uint64, fn func(WatchActivity)) error {
+	next := fromBlock
+	if next == 0 {
+		latest, err := m.Client.BlockNumber(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to get latest block number")
+		}
+		next = latest
+	}
+
+	m.logger().Info().Str("Address", address.Hex()).Uint64("From block", next).Msg("Watching address activity")
+
+	heads := make(chan *types.Header, 16)
+	var sub *ManagedSubscription
+	if m.Subscriptions != nil {
+		var err error
+		sub, err = m.Subscriptions.SubscribeNewHeads(ctx, heads)
+		if err != nil {
+			m.logger().Debug().Err(err).Msg("Failed to subscribe to new heads, falling back to polling")
+			sub = nil
+		} else {
+			defer sub.Unsubscribe()
+		}
+	}
+
+	for {
+		latest, err := m.Client.BlockNumber(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			m.logger().Debug().Err(err).Msg("Failed to get latest block number, while watching address")
+		}
+		for ; err == nil && next <= latest; next++ {
+			if err := m.watchBlock(ctx, address, next, fn); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+		}
+
+		if sub != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case subErr := <-sub.Err():
+				m.logger().Debug().Err(subErr).Msg("Failed to resubscribe to new heads, falling back to polling")
+				sub = nil
+			case <-heads:
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(m.Cfg.Network.ReceiptPollingDelay(0)):
+		}
+	}
+}
+
+// watchBlock calls fn for each transaction and event involving the address in the block
+func (m *Client) watchBlock(ctx context.Context, address common.Address, number uint64, fn func(WatchActivity)) error {
+	block, err := m.Client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return errors.Wrapf(wrapError(err, ErrWatchFetchBlock), "block: %d", number)
+	}
+
+	seenLogs := make(map[string]struct{})
+	signer := types.LatestSignerForChainID(big.NewInt(m.ChainID))
+	for _, tx := range block.Transactions() {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			m.logger().Debug().Err(err).Str("Transaction", tx.Hash().Hex()).Msg("Failed to get transaction sender")
+			continue
+		}
+		if from != address && (tx.To() == nil || *tx.To() != address) {
+			continue
+		}
+
+		activity := WatchActivity{
+			Type:        WatchActivity_Transaction,
+			BlockNumber: number,
+			TxHash:      tx.Hash().Hex(),
+			From:        from.Hex(),
+			Value:       tx.Value(),
+		}
+		if tx.To() != nil {
+			activity.To = tx.To().Hex()
+			activity.Contract = m.ContractAddressToNameMap.GetContractName(activity.To)
+			if len(tx.Data()) >= 4 && m.ABIFinder != nil {
+				if result, err := m.ABIFinder.FindABIByMethod(activity.To, tx.Data()[:4]); err == nil {
+					activity.Method = result.Method.Sig
+					activity.Contract = result.ContractName()
+				}
+			}
+		}
+
+		receipt, err := m.Client.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			m.logger().Debug().Err(err).Str("Transaction", tx.Hash().Hex()).Msg("Failed to get transaction receipt")
+			fn(activity)
+			continue
+		}
+		activity.Status = receipt.Status
+		fn(activity)
+
+		for _, lo := range receipt.Logs {
+			seenLogs[logKey(*lo)] = struct{}{}
+			fn(m.watchedEvent(*lo))
+		}
+	}
+
+	blockHash := block.Hash()
+	logs, err := m.Client.FilterLogs(ctx, ethereum.FilterQuery{BlockHash: &blockHash, Addresses: []common.Address{address}})
+	if err != nil {
+		return errors.Wrapf(wrapError(err, ErrWatchFetchBlock), "block: %d", number)
+	}
+	for _, lo := range logs {
+		if _, ok := seenLogs[logKey(lo)]; ok {
+			continue
+		}
+		fn(m.watchedEvent(lo))
+	}
+
+	return nil
+}
+
+func (m *Client) watchedEvent(lo types.Log) WatchActivity {
+	return WatchActivity{
+		Type:        WatchActivity_Event,
+		BlockNumber: lo.BlockNumber,
+		TxHash:      lo.TxHash.Hex(),
+		Contract:    m.ContractAddressToNameMap.GetContractName(lo.Address.Hex()),
+		Event:       m.decodeLogWithKnownABIs(lo),
+	}
+}
+
+// decodeLogWithKnownABIs decodes the log with ABI of the contract that emitted it, if it's known, or with the first ABI
+// from the contract store that has matching event. If it can't be decoded only its metadata is set.
+func (m *Client) decodeLogWithKnownABIs(lo types.Log) *DecodedTransactionLog {
+	undecoded := &DecodedTransactionLog{}
+	m.mergeLogMeta(undecoded, lo)
+	if len(lo.Topics) == 0 || m.ContractStore == nil {
+		return undecoded
+	}
+
+	if name := m.ContractAddressToNameMap.GetContractName(lo.Address.Hex()); name != "" {
+		if a, ok := m.ContractStore.GetABI(name); ok {
+			if decoded, err := m.decodeContractLogs(m.logger().With().Logger(), []types.Log{lo}, *a); err == nil && len(decoded) > 0 {
+				return &decoded[0]
+			}
+		}
+	}
+
+	var result *DecodedTransactionLog
+	m.ContractStore.RangeABIs(func(_ string, a abi.ABI) bool {
+		if _, err := a.EventByID(lo.Topics[0]); err != nil {
+			return true
+		}
+		decoded, err := m.decodeContractLogs(m.logger().With().Logger(), []types.Log{lo}, a)
+		if err != nil || len(decoded) == 0 {
+			return true
+		}
+		result = &decoded[0]
+		return false
+	})
+	if result != nil {
+		return result
+	}
+
+	return undecoded
+}
+
+func logKey(lo types.Log) string {
+	return fmt.Sprintf("%s:%d", lo.TxHash.Hex(), lo.Index)
+}
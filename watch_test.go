@@ -0,0 +1,77 @@
+package seth_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smartcontractkit/seth"
+	sethcmd "github.com/smartcontractkit/seth/cmd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIWatchAddress(t *testing.T) {
+	c := newClientWithContractMapFromEnv(t)
+
+	fromBlock, err := c.Client.BlockNumber(context.Background())
+	require.NoError(t, err, "failed to get block number")
+
+	decoded, err := c.Decode(TestEnv.DebugContract.EmitOneIndexEvent(c.NewTXOpts()))
+	require.NoError(t, err, "failed to emit event")
+
+	mu := &sync.Mutex{}
+	var activities []seth.WatchActivity
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.WatchAddress(ctx, TestEnv.DebugContractAddress, fromBlock+1, func(a seth.WatchActivity) {
+			mu.Lock()
+			defer mu.Unlock()
+			activities = append(activities, a)
+			if a.Type == seth.WatchActivity_Event && a.TxHash == decoded.Hash {
+				cancel()
+			}
+		})
+	}()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err, "watch should stop without error, when context is done")
+	case <-time.After(15 * time.Second):
+		t.Fatal("watch did not stop, when context was done")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var tx, event *seth.WatchActivity
+	for i := range activities {
+		if activities[i].TxHash != decoded.Hash {
+			continue
+		}
+		switch activities[i].Type {
+		case seth.WatchActivity_Transaction:
+			tx = &activities[i]
+		case seth.WatchActivity_Event:
+			event = &activities[i]
+		}
+	}
+	require.NotNil(t, tx, "transaction should be watched")
+	require.Equal(t, c.Addresses[0].Hex(), tx.From, "incorrect sender")
+	require.Equal(t, TestEnv.DebugContractAddress.Hex(), tx.To, "incorrect recipient")
+	require.Equal(t, "emitOneIndexEvent()", tx.Method, "method should be decoded")
+	require.Equal(t, uint64(1), tx.Status, "transaction should be successful")
+
+	require.NotNil(t, event, "event should be watched")
+	require.Equal(t, "NetworkDebugContract", event.Contract, "incorrect contract name")
+	require.Equal(t, "OneIndexEvent(uint256)", event.Event.Signature, "event should be decoded")
+}
+
+func TestCLIWatch(t *testing.T) {
+	err := sethcmd.RunCLI([]string{"seth", "-n", "Geth", "watch", "-a", TestEnv.DebugContractAddress.Hex(), "-d", "2s", "--no_color"})
+	require.NoError(t, err, "watch should stop after duration")
+
+	err = sethcmd.RunCLI([]string{"seth", "-n", "Geth", "watch", "-a", "0xinvalid"})
+	require.Error(t, err, "watch should fail with invalid address")
+}